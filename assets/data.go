@@ -1,9 +1,43 @@
 package assets
 
-import _ "embed"
+import (
+	"bytes"
+	"compress/gzip"
+	_ "embed"
+	"errors"
+	"io"
+	"sync"
+)
 
-//go:embed fingerprints_data.json
-var FingerprintsJSON string
+//go:generate go run ./gen
 
 //go:embed categories_data.json
-var CategoriesJSON string
\ No newline at end of file
+var CategoriesJSON string
+
+// EOLJSON is a snapshot of the release cycles of versioned technologies from
+// endoflife.date, refreshed with the eol subcommand of update-fingerprints
+//
+//go:embed eol_data.json
+var EOLJSON []byte
+
+// ErrNoData is returned by Fingerprints in builds with the kitsune_nodata tag,
+// which embed no fingerprints
+var ErrNoData = errors.New("no fingerprints are embedded in builds with the kitsune_nodata tag")
+
+// Fingerprints returns fingerprints_data.json in the binary format of the
+// profiler, with patterns validated, for fast loading. It is embedded gzip
+// compressed to keep binaries small and decompressed on first use.
+func Fingerprints() ([]byte, error) {
+	return decompressFingerprints()
+}
+
+var decompressFingerprints = sync.OnceValues(func() ([]byte, error) {
+	if fingerprintsCompressed == nil {
+		return nil, ErrNoData
+	}
+	reader, err := gzip.NewReader(bytes.NewReader(fingerprintsCompressed))
+	if err != nil {
+		return nil, err
+	}
+	return io.ReadAll(reader)
+})
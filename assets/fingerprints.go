@@ -0,0 +1,8 @@
+//go:build !kitsune_nodata
+
+package assets
+
+import _ "embed"
+
+//go:embed fingerprints_data.bin.gz
+var fingerprintsCompressed []byte
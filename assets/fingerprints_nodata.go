@@ -0,0 +1,7 @@
+//go:build kitsune_nodata
+
+package assets
+
+// Builds with the kitsune_nodata tag leave the fingerprints out, for users who
+// always load them from a file
+var fingerprintsCompressed []byte
@@ -0,0 +1,44 @@
+// Command gen regenerates fingerprints_data.bin.gz from fingerprints_data.json.
+// It is run by go generate from the assets package directory, after
+// update-fingerprints has refreshed the JSON.
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"log"
+	"os"
+
+	"github.com/kavinsood/kitsune/internal/profiler"
+)
+
+func main() {
+	data, err := os.ReadFile("fingerprints_data.json")
+	if err != nil {
+		log.Fatalf("Could not read fingerprints_data.json: %v", err)
+	}
+
+	encoded, dropped, err := profiler.EncodeFingerprints(data)
+	if err != nil {
+		log.Fatalf("Could not encode fingerprints: %v", err)
+	}
+
+	// The gzip header carries no name or modification time, so the same
+	// fingerprints always compress to the same file
+	var compressed bytes.Buffer
+	writer, err := gzip.NewWriterLevel(&compressed, gzip.BestCompression)
+	if err != nil {
+		log.Fatalf("Could not compress fingerprints: %v", err)
+	}
+	if _, err := writer.Write(encoded); err != nil {
+		log.Fatalf("Could not compress fingerprints: %v", err)
+	}
+	if err := writer.Close(); err != nil {
+		log.Fatalf("Could not compress fingerprints: %v", err)
+	}
+
+	if err := os.WriteFile("fingerprints_data.bin.gz", compressed.Bytes(), 0o644); err != nil {
+		log.Fatalf("Could not write fingerprints_data.bin.gz: %v", err)
+	}
+	log.Printf("Wrote %d bytes (%d uncompressed), dropped %d patterns that do not compile", compressed.Len(), len(encoded), dropped)
+}
@@ -1,116 +1,1349 @@
 package main
 
 import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
+	"flag"
 	"fmt"
 	"io"
-	"log"
+	"log/slog"
+	"math"
+	"net"
 	"net/http"
+	"net/netip"
+	"net/url"
 	"os"
+	"os/signal"
+	"reflect"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
 
+	"github.com/kavinsood/kitsune/internal/api"
+	"github.com/kavinsood/kitsune/internal/apikey"
+	"github.com/kavinsood/kitsune/internal/compress"
+	"github.com/kavinsood/kitsune/internal/config"
+	"github.com/kavinsood/kitsune/internal/export"
+	"github.com/kavinsood/kitsune/internal/icons"
+	"github.com/kavinsood/kitsune/internal/policy"
 	"github.com/kavinsood/kitsune/internal/profiler"
+	"github.com/kavinsood/kitsune/internal/queue"
+	"github.com/kavinsood/kitsune/internal/reqlog"
+	"github.com/kavinsood/kitsune/internal/webhook"
 )
 
-type AnalyzeRequest struct {
-	URL string `json:"url"`
-}
-
 func main() {
-	fmt.Println("Starting Kitsune API server...")
+	// Settings come from the config file, then the environment, then the flags
+	configPath := flag.String("config", os.Getenv("KITSUNE_CONFIG"), "YAML config file (env KITSUNE_CONFIG)")
+	printConfig := flag.Bool("print-config", false, "print the effective configuration, with API keys redacted, and exit")
+	applyFlags := config.RegisterFlags(flag.CommandLine)
+	flag.Parse()
 
-	// Get port from environment variable (for Render) or default to 8080
-	port := os.Getenv("PORT")
-	if port == "" {
-		port = "8080"
+	cfg, err := loadConfig(*configPath, applyFlags)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid configuration: %v\n", err)
+		os.Exit(1)
+	}
+	if *printConfig {
+		data, err := cfg.Marshal()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to print configuration: %v\n", err)
+			os.Exit(1)
+		}
+		os.Stdout.Write(data)
+		return
 	}
 
+	// Log as JSON by default, so deployments can ship logs with levels
+	logger, logLevel := configureLogger(cfg.Log)
+	slog.SetDefault(logger)
+	logger.Info("starting kitsune api server")
+
 	// Construct the listen address with "0.0.0.0" to accept external connections
-	listenAddr := "0.0.0.0:" + port
+	listenAddr := "0.0.0.0:" + strconv.Itoa(cfg.Server.Port)
+
+	// Build the engine and the catalogs it serves. Reloads build them anew and
+	// swap them in, while in-flight requests complete with the state they
+	// started with.
+	initial, err := newServerState(cfg, logger)
+	if err != nil {
+		fatal("failed to initialize profiler engine", err)
+	}
+	var state atomic.Pointer[serverState]
+	state.Store(initial)
 
-	// Initialize the profiler
-	engine, err := profiler.New()
+	// Configure the sinks completed analyses are published to, if any
+	sinks, err := configureSinks(cfg.Sinks)
 	if err != nil {
-		log.Fatalf("Failed to initialize profiler engine: %v", err)
+		fatal("failed to configure result sinks", err)
 	}
+	defer sinks.Close()
+
+	// Time allowed for in-flight analyses to finish on shutdown
+	drainTimeout := cfg.Server.DrainTimeout
+
+	// Analyses run under this context, which is canceled when draining times out
+	analysisCtx, cancelAnalyses := context.WithCancel(context.Background())
+	defer cancelAnalyses()
+
+	// Bound the analyses running at once, queueing the requests over the bound
+	analyses := queue.New(cfg.Server.MaxAnalyses, cfg.Server.QueueSize)
+
+	// Async analyses outlive their request, so they are tracked separately
+	var asyncAnalyses sync.WaitGroup
+
+	// Set while shutting down, so load balancers stop routing new requests
+	var draining atomic.Bool
 
 	// Set up HTTP routes
+	// Report the fingerprint data version along with the status, so operators
+	// know how stale the rulebase is
 	http.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
-		w.WriteHeader(http.StatusOK)
-		w.Write([]byte("OK"))
+		response := api.HealthResponse{Status: "ok", Data: state.Load().sets[config.DefaultSet].dataVersion}
+		if draining.Load() {
+			response.Status = "draining"
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusServiceUnavailable)
+			json.NewEncoder(w).Encode(response)
+			return
+		}
+		writeJSON(w, response)
 	})
 
-	http.HandleFunc("/analyze", func(w http.ResponseWriter, r *http.Request) {
-		if r.Method != "POST" {
-			http.Error(w, "Only POST method is allowed", http.StatusMethodNotAllowed)
+	// Serve the OpenAPI document the request and response types are generated from
+	http.HandleFunc("/openapi.json", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "GET" {
+			reqlog.Error(w, r, "Only GET method is allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(api.Spec)
+	})
+
+	// List the technologies the engine can detect, optionally filtered by category and tag
+	http.HandleFunc("/technologies", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "GET" {
+			reqlog.Error(w, r, "Only GET method is allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		set, err := state.Load().requestSet(r, "")
+		if err != nil {
+			reqlog.Error(w, r, err.Error(), http.StatusBadRequest)
 			return
 		}
+		technologies := set.technologies
 
-		var reqData AnalyzeRequest
-		// Decode the JSON body instead of using FormValue
-		if err := json.NewDecoder(r.Body).Decode(&reqData); err != nil {
-			http.Error(w, "Invalid JSON body", http.StatusBadRequest)
+		query := r.URL.Query()
+		page, err := queryInt(query, "page", 1, math.MaxInt32)
+		if err != nil {
+			reqlog.Error(w, r, err.Error(), http.StatusBadRequest)
+			return
+		}
+		perPage, err := queryInt(query, "per_page", 100, maxTechnologiesPerPage)
+		if err != nil {
+			reqlog.Error(w, r, err.Error(), http.StatusBadRequest)
 			return
 		}
 
-		targetURL := reqData.URL // Get URL from the decoded struct
-		if targetURL == "" {
-			http.Error(w, "URL parameter is required", http.StatusBadRequest)
+		matching := technologies
+		if category := query.Get("category"); category != "" {
+			matching = nil
+			for _, technology := range technologies {
+				if hasCategory(technology, category) {
+					matching = append(matching, technology)
+				}
+			}
+		}
+		if tag := query.Get("tag"); tag != "" {
+			tagged := []profiler.Technology{}
+			for _, technology := range matching {
+				if set.engine.HasTag(technology.Name, tag) {
+					tagged = append(tagged, technology)
+				}
+			}
+			matching = tagged
+		}
+
+		response := api.TechnologiesResponse{
+			Total:        len(matching),
+			Page:         page,
+			PerPage:      perPage,
+			Technologies: []profiler.Technology{},
+		}
+		if start := (page - 1) * perPage; start < len(matching) {
+			response.Technologies = matching[start:min(start+perPage, len(matching))]
+		}
+		w.Header().Add("Vary", setHeader)
+		writeCachedJSON(w, r, response)
+	})
+
+	// Serve technology icons from a local directory, or fetched from upstream and cached
+	http.HandleFunc("/icons/", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "GET" {
+			reqlog.Error(w, r, "Only GET method is allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		current := state.Load()
+		set, err := current.requestSet(r, "")
+		if err != nil {
+			reqlog.Error(w, r, err.Error(), http.StatusBadRequest)
+			return
+		}
+		file, ok := set.iconFiles[strings.ToLower(strings.TrimPrefix(r.URL.Path, "/icons/"))]
+		if !ok {
+			reqlog.Error(w, r, "Unknown technology", http.StatusNotFound)
+			return
+		}
+		icon, err := current.iconStore.Get(r.Context(), file)
+		if errors.Is(err, icons.ErrNotFound) {
+			reqlog.Error(w, r, "Icon not found", http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			reqlog.Error(w, r, fmt.Sprintf("Error loading icon: %v", err), http.StatusBadGateway)
 			return
 		}
 
-		// Make HTTP request to the target URL
-		resp, err := http.Get(targetURL)
+		w.Header().Set("Content-Type", icon.ContentType)
+		// Icons are third party files, so never let browsers run scripts in them
+		w.Header().Set("Content-Security-Policy", "default-src 'none'; style-src 'unsafe-inline'; sandbox")
+		w.Header().Add("Vary", setHeader)
+		writeCached(w, r, icon.Data, "public, max-age=86400")
+	})
+
+	http.HandleFunc("/categories", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "GET" {
+			reqlog.Error(w, r, "Only GET method is allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		set, err := state.Load().requestSet(r, "")
 		if err != nil {
-			http.Error(w, fmt.Sprintf("Error fetching URL: %v", err), http.StatusInternalServerError)
+			reqlog.Error(w, r, err.Error(), http.StatusBadRequest)
 			return
 		}
-		defer resp.Body.Close()
+		w.Header().Add("Vary", setHeader)
+		writeCachedJSON(w, r, api.CategoriesResponse{Categories: set.engine.Categories()})
+	})
 
-		// Read body with size limit
-		const maxBodySize = 5 * 1024 * 1024 // 5 MB
-		limitedReader := io.LimitReader(resp.Body, maxBodySize)
-		body, err := io.ReadAll(limitedReader)
+	http.HandleFunc("/analyze", func(w http.ResponseWriter, r *http.Request) {
+		current := state.Load()
+		var reqData api.AnalyzeRequest
+		switch r.Method {
+		case "POST":
+			// Decode the JSON body instead of using FormValue
+			if err := json.NewDecoder(r.Body).Decode(&reqData); err != nil {
+				reqlog.Error(w, r, "Invalid JSON body", http.StatusBadRequest)
+				return
+			}
+		case "GET":
+			// Tools that cannot send JSON pass the request as query parameters.
+			// Async analyses have side effects, so they are only requested by POST.
+			query := r.URL.Query()
+			reqData = api.AnalyzeRequest{
+				URL:         query.Get("url"),
+				Format:      query.Get("format"),
+				InlineIcons: query.Get("inline_icons") == "true",
+				Profile:     query.Get("profile"),
+				Tags:        requestTags(query.Get("tags")),
+				Set:         query.Get("set"),
+				Explain:     query.Get("explain") == "true",
+				Trace:       query.Get("trace") == "true",
+			}
+		default:
+			reqlog.Error(w, r, "Only GET and POST methods are allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		fields := api.ParseFields(r.URL.Query().Get("fields"))
+		// Explaining and tracing may be asked as parameters too, to keep the body unchanged
+		explain := reqData.Explain || r.URL.Query().Get("explain") == "true"
+		trace := reqData.Trace || r.URL.Query().Get("trace") == "true"
+
+		targetURL := reqData.URL // Get URL from the decoded struct
+		if targetURL == "" {
+			reqlog.Error(w, r, "URL parameter is required", http.StatusBadRequest)
+			return
+		}
+		reqlog.SetTarget(r.Context(), targetURL)
+		// The format may also be passed as a parameter, as on the stream
+		format := reqData.Format
+		if format == "" {
+			format = r.URL.Query().Get("format")
+		}
+		if format != "" && format != "wappalyzer" && format != "legacy" {
+			reqlog.Error(w, r, "Unsupported format, expected \"wappalyzer\" or \"legacy\"", http.StatusBadRequest)
+			return
+		}
+		profile, err := requestProfile(reqData.Profile)
+		if err != nil {
+			reqlog.Error(w, r, err.Error(), http.StatusBadRequest)
+			return
+		}
+		set, err := current.requestSet(r, reqData.Set)
 		if err != nil {
-			http.Error(w, fmt.Sprintf("Error reading response body: %v", err), http.StatusInternalServerError)
+			reqlog.Error(w, r, err.Error(), http.StatusBadRequest)
 			return
 		}
 
-		// Perform fingerprinting with detailed info
-		results := engine.FingerprintWithInfoAndURL(resp.Header, body, targetURL)
+		// Analyze in the background and publish the result to the sinks
+		if reqData.Async {
+			if len(sinks) == 0 {
+				reqlog.Error(w, r, "Async analysis requires a configured result sink", http.StatusBadRequest)
+				return
+			}
+			// Async analyses take a slot like the others, so under load the
+			// request waits in the queue before being accepted
+			release, ok := admit(w, r, analyses, cfg.Server.QueueTimeout)
+			if !ok {
+				return
+			}
+			// The analysis outlives the request, so its logs carry the request ID explicitly
+			asyncLogger := logger.With("request_id", reqlog.ID(r.Context()))
+			asyncAnalyses.Add(1)
+			go func() {
+				defer asyncAnalyses.Done()
+				defer release()
+
+				ctx, cancel := context.WithTimeout(analysisCtx, time.Minute)
+				defer cancel()
+				ctx = profiler.TagsContext(withProfile(ctx, profile), reqData.Tags...)
+				ctx = withTrace(withExplain(ctx, explain), trace)
+
+				result, err := set.engine.FingerprintURL(ctx, targetURL)
+				if err != nil && !errors.Is(err, profiler.ErrBlockedByTarget) && !errors.Is(err, profiler.ErrBodyTooLarge) {
+					asyncLogger.ErrorContext(ctx, "async analysis failed", "url", targetURL, "error", err)
+					return
+				}
+				if err := sinks.Publish(ctx, export.NewDocument(targetURL, time.Now(), result)); err != nil {
+					asyncLogger.ErrorContext(ctx, "failed to publish analysis", "url", targetURL, "error", err)
+				}
+			}()
+			w.WriteHeader(http.StatusAccepted)
+			return
+		}
 
-		// Create response struct
-		type Technology struct {
-			Name        string `json:"name"`
-			Description string `json:"description"`
-			Website     string `json:"website"`
+		release, ok := admit(w, r, analyses, cfg.Server.QueueTimeout)
+		if !ok {
+			return
 		}
+		defer release()
 
-		type Response struct {
-			Technologies []Technology `json:"technologies"`
+		// Fetch the target URL and perform fingerprinting with detailed info.
+		// Blocked and oversized pages are still analyzed from what was received.
+		ctx := profiler.TagsContext(withProfile(r.Context(), profile), reqData.Tags...)
+		ctx = withTrace(withExplain(ctx, explain), trace)
+		result, err := set.engine.FingerprintURL(ctx, targetURL)
+		if err != nil && !errors.Is(err, profiler.ErrBlockedByTarget) && !errors.Is(err, profiler.ErrBodyTooLarge) {
+			reqlog.Error(w, r, fmt.Sprintf("Error fetching URL: %v", err), fetchErrorStatus(err))
+			return
 		}
 
-		// Populate the response
-		response := Response{
-			Technologies: make([]Technology, 0, len(results)),
+		// Publish the analysis in addition to returning it
+		if len(sinks) > 0 {
+			if err := sinks.Publish(r.Context(), export.NewDocument(targetURL, time.Now(), result)); err != nil {
+				logger.ErrorContext(r.Context(), "failed to publish analysis", "url", targetURL, "error", err)
+			}
 		}
 
-		for tech, info := range results {
-			response.Technologies = append(response.Technologies, Technology{
-				Name:        tech,
-				Description: info.Description,
-				Website:     info.Website,
-			})
+		// Respond in the Wappalyzer CLI schema if requested
+		var body interface{}
+		if format == "wappalyzer" {
+			body = set.engine.Wappalyzer(result)
+		} else {
+			response := newAnalyzeResponse(result, set.categories)
+			if reqData.InlineIcons {
+				inlineIcons(r.Context(), current.iconStore, &response, result.GetAppInfo())
+			}
+			body = response
+			if format == "legacy" {
+				body = newLegacyAnalyzeResponse(response)
+			}
+		}
+		body, err = api.SelectFields(body, fields)
+		if err != nil {
+			reqlog.Error(w, r, fmt.Sprintf("Error encoding response: %v", err), http.StatusInternalServerError)
+			return
 		}
 
 		// Set content type and marshal to JSON
 		w.Header().Set("Content-Type", "application/json")
-		if err := json.NewEncoder(w).Encode(response); err != nil {
-			http.Error(w, fmt.Sprintf("Error encoding response: %v", err), http.StatusInternalServerError)
+		if err := json.NewEncoder(w).Encode(body); err != nil {
+			reqlog.Error(w, r, fmt.Sprintf("Error encoding response: %v", err), http.StatusInternalServerError)
 			return
 		}
 	})
 
+	// Stream detections as they are found, followed by the full result, as Server-Sent Events
+	http.HandleFunc("/analyze/stream", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "GET" {
+			reqlog.Error(w, r, "Only GET method is allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		current := state.Load()
+		targetURL := r.URL.Query().Get("url")
+		if targetURL == "" {
+			reqlog.Error(w, r, "URL parameter is required", http.StatusBadRequest)
+			return
+		}
+		reqlog.SetTarget(r.Context(), targetURL)
+		profile, err := requestProfile(r.URL.Query().Get("profile"))
+		if err != nil {
+			reqlog.Error(w, r, err.Error(), http.StatusBadRequest)
+			return
+		}
+		format := r.URL.Query().Get("format")
+		if format != "" && format != "legacy" {
+			reqlog.Error(w, r, "Unsupported format, expected \"legacy\"", http.StatusBadRequest)
+			return
+		}
+		set, err := current.requestSet(r, "")
+		if err != nil {
+			reqlog.Error(w, r, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		flusher, ok := w.(http.Flusher)
+		if !ok {
+			reqlog.Error(w, r, "Streaming is not supported", http.StatusInternalServerError)
+			return
+		}
+		release, ok := admit(w, r, analyses, cfg.Server.QueueTimeout)
+		if !ok {
+			return
+		}
+		defer release()
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Set("Cache-Control", "no-cache")
+		w.Header().Set("Connection", "keep-alive")
+		w.WriteHeader(http.StatusOK)
+		flusher.Flush()
+
+		// Progress is reported from the analysis goroutines, one event at a time
+		ctx := profiler.TagsContext(withProfile(r.Context(), profile), requestTags(r.URL.Query().Get("tags"))...)
+		ctx = withExplain(ctx, r.URL.Query().Get("explain") == "true")
+		ctx = withTrace(ctx, r.URL.Query().Get("trace") == "true")
+		ctx = profiler.WithProgress(ctx, func(event profiler.ProgressEvent) {
+			writeEvent(w, string(event.Type), event)
+			flusher.Flush()
+		})
+
+		result, err := set.engine.FingerprintURL(ctx, targetURL)
+		if err != nil && !errors.Is(err, profiler.ErrBlockedByTarget) && !errors.Is(err, profiler.ErrBodyTooLarge) {
+			writeEvent(w, "error", api.StreamError{Error: err.Error(), RequestID: reqlog.ID(r.Context())})
+			flusher.Flush()
+			return
+		}
+
+		if len(sinks) > 0 {
+			if err := sinks.Publish(r.Context(), export.NewDocument(targetURL, time.Now(), result)); err != nil {
+				logger.ErrorContext(r.Context(), "failed to publish analysis", "url", targetURL, "error", err)
+			}
+		}
+
+		response := newAnalyzeResponse(result, set.categories)
+		if r.URL.Query().Get("inline_icons") == "true" {
+			inlineIcons(r.Context(), current.iconStore, &response, result.GetAppInfo())
+		}
+		if format == "legacy" {
+			writeEvent(w, "result", newLegacyAnalyzeResponse(response))
+		} else {
+			writeEvent(w, "result", response)
+		}
+		flusher.Flush()
+	})
+
+	// Reload the config file and the fingerprint data. Settings that only apply
+	// on restart are logged, and a failed reload keeps the running state.
+	var reloadMutex sync.Mutex
+	var authenticator *apikey.Authenticator
+	reload := func() (*serverState, error) {
+		reloadMutex.Lock()
+		defer reloadMutex.Unlock()
+
+		next, err := loadConfig(*configPath, applyFlags)
+		if err != nil {
+			return nil, err
+		}
+		nextState, err := newServerState(next, logger)
+		if err != nil {
+			return nil, err
+		}
+		keys, err := apikey.ParseKeys(strings.Join(next.Auth.APIKeys, ","), next.Auth.RateLimit, next.Auth.DailyQuota)
+		if err != nil {
+			return nil, err
+		}
+		// Without keys the running authenticator would refuse every request,
+		// as turning authentication off only applies on restart
+		if authenticator != nil && len(keys) == 0 {
+			return nil, errors.New("the config has no API keys left, authentication can only be turned off on restart")
+		}
+
+		for _, setting := range restartSettings(cfg, next) {
+			logger.Warn("setting changed, restart the server to apply it", "setting", setting)
+		}
+		if authenticator != nil {
+			authenticator.SetKeys(keys)
+		}
+		logLevel.UnmarshalText([]byte(next.Log.Level))
+		state.Store(nextState)
+		logger.Info("configuration reloaded", "technologies", nextState.sets[config.DefaultSet].dataVersion.Technologies, "sets", len(nextState.sets))
+		return nextState, nil
+	}
+
+	admin := http.NewServeMux()
+	admin.HandleFunc("/admin/reload", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "POST" {
+			reqlog.Error(w, r, "Only POST method is allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		reloaded, err := reload()
+		if err != nil {
+			logger.ErrorContext(r.Context(), "reload failed, keeping the running configuration", "error", err)
+			reqlog.Error(w, r, fmt.Sprintf("Reload failed: %v", err), http.StatusInternalServerError)
+			return
+		}
+		writeJSON(w, api.ReloadResponse{Status: "reloaded", Data: reloaded.sets[config.DefaultSet].dataVersion})
+	})
+
+	// Inspect the loaded definitions and try candidate ones against samples,
+	// for authoring custom rules
+	inspectFingerprint := func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "GET" {
+			reqlog.Error(w, r, "Only GET method is allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		set, err := state.Load().requestSet(r, "")
+		if err != nil {
+			reqlog.Error(w, r, err.Error(), http.StatusBadRequest)
+			return
+		}
+		name, ok := set.lookup(strings.TrimPrefix(r.URL.Path, "/admin/fingerprints/"))
+		if !ok {
+			reqlog.Error(w, r, "Unknown technology", http.StatusNotFound)
+			return
+		}
+		definition, err := compactDefinition(set.engine.GetFingerprints().Apps[name])
+		if err != nil {
+			reqlog.Error(w, r, fmt.Sprintf("Error encoding response: %v", err), http.StatusInternalServerError)
+			return
+		}
+		writeJSON(w, api.FingerprintResponse{Name: name, Set: set.name, Fingerprint: definition})
+	}
+	admin.HandleFunc("/admin/fingerprints/", inspectFingerprint)
+	admin.HandleFunc("/admin/fingerprints/test", func(w http.ResponseWriter, r *http.Request) {
+		// A technology may be named test
+		if r.Method == "GET" {
+			inspectFingerprint(w, r)
+			return
+		}
+		if r.Method != "POST" {
+			reqlog.Error(w, r, "Only GET and POST methods are allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		var reqData api.FingerprintTestRequest
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxFingerprintTestSize)).Decode(&reqData); err != nil {
+			reqlog.Error(w, r, "Invalid JSON body", http.StatusBadRequest)
+			return
+		}
+		if reqData.Name == "" || len(reqData.Fingerprint) == 0 {
+			reqlog.Error(w, r, "Name and fingerprint are required", http.StatusBadRequest)
+			return
+		}
+		headers := make(http.Header, len(reqData.Headers))
+		for name, value := range reqData.Headers {
+			headers.Set(name, value)
+		}
+		dryRun, err := profiler.DryRunFingerprint(r.Context(), reqData.Name, reqData.Fingerprint, profiler.Sample{
+			URL:     reqData.URL,
+			Headers: headers,
+			Body:    []byte(reqData.HTML),
+		})
+		if err != nil {
+			reqlog.Error(w, r, err.Error(), http.StatusBadRequest)
+			return
+		}
+		writeJSON(w, dryRun)
+	})
+
+	// Require API keys if configured, leaving the health check open. The admin
+	// endpoints take admin keys instead.
+	handler, authenticator, err := configureAuth(http.DefaultServeMux, cfg.Auth)
+	if err != nil {
+		fatal("failed to configure API keys", err)
+	}
+	mux := http.NewServeMux()
+	mux.Handle("/", handler)
+	mux.Handle("/admin/", requireAdmin(admin, func() []string { return state.Load().adminKeys }))
+
+	// Requests inherit the analysis context, so draining can cancel them.
+	// Responses are compressed when the client accepts it, and logged as sent.
+	server := &http.Server{
+		Addr:        listenAddr,
+		Handler:     reqlog.Middleware(compress.Middleware(mux), logger),
+		BaseContext: func(net.Listener) context.Context { return analysisCtx },
+	}
+
 	// Start the server with the correct listen address
-	fmt.Printf("Server running on %s\n", listenAddr)
-	log.Fatal(http.ListenAndServe(listenAddr, nil))
+	serverErr := make(chan error, 1)
+	go func() {
+		logger.Info("server running", "addr", listenAddr)
+		serverErr <- server.ListenAndServe()
+	}()
+
+	// Reload on SIGHUP, as with POST /admin/reload
+	hangup := make(chan os.Signal, 1)
+	signal.Notify(hangup, syscall.SIGHUP)
+	defer signal.Stop(hangup)
+	go func() {
+		for range hangup {
+			if _, err := reload(); err != nil {
+				logger.Error("reload failed, keeping the running configuration", "error", err)
+			}
+		}
+	}()
+
+	// Run until the server fails or a shutdown is requested
+	signalCtx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	select {
+	case err := <-serverErr:
+		fatal("server failed", err)
+	case <-signalCtx.Done():
+	}
+
+	logger.Info("shutting down, draining in-flight analyses", "timeout", drainTimeout.String())
+	draining.Store(true)
+	drainCtx, cancelDrain := context.WithTimeout(context.Background(), drainTimeout)
+	defer cancelDrain()
+
+	// Stop accepting connections and wait for in-flight requests, then async analyses
+	if err := server.Shutdown(drainCtx); err != nil {
+		logger.Warn("drain timed out, canceling in-flight analyses", "error", err)
+	}
+	drained := make(chan struct{})
+	go func() {
+		asyncAnalyses.Wait()
+		close(drained)
+	}()
+	select {
+	case <-drained:
+	case <-drainCtx.Done():
+		logger.Warn("drain timed out, canceling async analyses")
+	}
+
+	// Cancel whatever is still running and close the remaining connections
+	cancelAnalyses()
+	server.Close()
+	<-drained
+	logger.Info("server stopped")
+}
+
+// analysisResult is the part of an analysis result the analyze response reports
+type analysisResult interface {
+	GetAppInfo() map[string]profiler.AppInfo
+	PartialResult() bool
+	GetSkippedStages() []profiler.Stage
+	GetStats() profiler.AnalysisStats
+	GetStack() profiler.Stack
+	GetProtocol() profiler.ProtocolInfo
+	GetDetections() map[string]profiler.Detection
+	GetCategories() map[string]profiler.CatsInfo
+	GetNearMisses() []profiler.NearMiss
+	GetTrace() *profiler.Trace
+}
+
+// newAnalyzeResponse builds the analyze response from the detected
+// technologies, naming their categories from categories, by ID
+func newAnalyzeResponse(result analysisResult, categories map[int]profiler.Category) api.AnalyzeResponse {
+	results := result.GetAppInfo()
+	detections := result.GetDetections()
+	categoryIDs := result.GetCategories()
+	response := api.AnalyzeResponse{
+		Technologies:   make([]api.Technology, 0, len(results)),
+		Partial:        result.PartialResult(),
+		Stack:          result.GetStack(),
+		TLSValidation:  result.GetProtocol().TLSValidation,
+		Certificate:    result.GetProtocol().Certificate,
+		RemoteIP:       result.GetProtocol().RemoteIP,
+		IPFamily:       string(result.GetProtocol().IPFamily),
+		SchemeFallback: result.GetProtocol().SchemeFallback,
+		NearMisses:     result.GetNearMisses(),
+		Trace:          result.GetTrace(),
+	}
+	for _, stage := range result.GetSkippedStages() {
+		response.SkippedStages = append(response.SkippedStages, string(stage))
+	}
+	for _, limit := range result.GetStats().LimitsHit {
+		response.LimitsHit = append(response.LimitsHit, string(limit))
+	}
+
+	for tech, info := range results {
+		// Technologies are keyed with their version, as in PHP:8.2.0
+		name, version, _ := strings.Cut(tech, ":")
+		detection := detections[name]
+		technology := api.Technology{
+			Name:        name,
+			Version:     version,
+			Confidence:  detection.Confidence,
+			DetectedBy:  detection.DetectedBy,
+			Categories:  []profiler.Category{},
+			Description: info.Description,
+			Website:     info.Website,
+			CPE:         info.CPE,
+			Tags:        info.Tags,
+			EOL:         detection.EOL,
+			Components:  detection.Components,
+		}
+		if technology.DetectedBy == nil {
+			technology.DetectedBy = []string{}
+		}
+		for _, id := range categoryIDs[tech].Cats {
+			if category, ok := categories[id]; ok {
+				technology.Categories = append(technology.Categories, category)
+			}
+		}
+		sort.Slice(technology.Categories, func(i, j int) bool {
+			return technology.Categories[i].ID < technology.Categories[j].ID
+		})
+		response.Technologies = append(response.Technologies, technology)
+	}
+	// The technologies come from a map, sort them so identical analyses diff clean
+	sort.Slice(response.Technologies, func(i, j int) bool {
+		return response.Technologies[i].Name < response.Technologies[j].Name
+	})
+	return response
+}
+
+// newLegacyAnalyzeResponse returns response in the shape of earlier versions
+func newLegacyAnalyzeResponse(response api.AnalyzeResponse) api.LegacyAnalyzeResponse {
+	legacy := api.LegacyAnalyzeResponse{
+		Technologies:   make([]api.LegacyTechnology, 0, len(response.Technologies)),
+		Partial:        response.Partial,
+		SkippedStages:  response.SkippedStages,
+		LimitsHit:      response.LimitsHit,
+		Stack:          response.Stack,
+		TLSValidation:  response.TLSValidation,
+		Certificate:    response.Certificate,
+		RemoteIP:       response.RemoteIP,
+		IPFamily:       response.IPFamily,
+		SchemeFallback: response.SchemeFallback,
+	}
+	for _, technology := range response.Technologies {
+		legacy.Technologies = append(legacy.Technologies, api.LegacyTechnology{
+			Name:        profiler.FormatAppVersion(technology.Name, technology.Version),
+			Description: technology.Description,
+			Website:     technology.Website,
+			Icon:        technology.Icon,
+			Tags:        technology.Tags,
+			EOL:         technology.EOL,
+		})
+	}
+	return legacy
+}
+
+// inlineIcons sets the icon of each technology of response as a data URI.
+// Technologies whose icon cannot be loaded are left without one.
+func inlineIcons(ctx context.Context, store *icons.Store, response *api.AnalyzeResponse, results map[string]profiler.AppInfo) {
+	for i, technology := range response.Technologies {
+		file := results[profiler.FormatAppVersion(technology.Name, technology.Version)].Icon
+		if file == "" {
+			file = icons.DefaultIcon
+		}
+		icon, err := store.Get(ctx, file)
+		if err != nil {
+			slog.WarnContext(ctx, "failed to load icon", "icon", file, "error", err)
+			continue
+		}
+		response.Technologies[i].Icon = icon.DataURI()
+	}
+}
+
+// maxTechnologiesPerPage bounds the page size of the technologies listing
+const maxTechnologiesPerPage = 1000
+
+// queryInt parses a positive integer query parameter, up to max
+func queryInt(query url.Values, name string, fallback, max int) (int, error) {
+	value := query.Get(name)
+	if value == "" {
+		return fallback, nil
+	}
+	parsed, err := strconv.Atoi(value)
+	if err != nil || parsed < 1 || parsed > max {
+		return 0, fmt.Errorf("%s must be between 1 and %d", name, max)
+	}
+	return parsed, nil
+}
+
+// hasCategory reports whether technology belongs to the category with the given ID, name or slug
+func hasCategory(technology profiler.Technology, category string) bool {
+	for _, c := range technology.Categories {
+		if strconv.Itoa(c.ID) == category || strings.EqualFold(c.Name, category) || c.Slug == category {
+			return true
+		}
+	}
+	return false
+}
+
+// writeJSON writes v as a JSON response
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		http.Error(w, fmt.Sprintf("Error encoding response: %v", err), http.StatusInternalServerError)
+	}
+}
+
+// catalogCacheControl lets clients reuse the technologies and categories
+// listings for an hour, as they only change with the fingerprint data. They
+// are private, since shared caches would serve them past the API key check.
+const catalogCacheControl = "private, max-age=3600"
+
+// writeCachedJSON writes v as JSON with the caching headers of the listings
+func writeCachedJSON(w http.ResponseWriter, r *http.Request, v interface{}) {
+	var body bytes.Buffer
+	if err := json.NewEncoder(&body).Encode(v); err != nil {
+		reqlog.Error(w, r, fmt.Sprintf("Error encoding response: %v", err), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	writeCached(w, r, body.Bytes(), catalogCacheControl)
+}
+
+// writeCached writes data with cacheControl and an ETag of its content,
+// answering requests that already hold it with 304 Not Modified. The ETag is
+// weak, since the compression middleware may encode the data.
+func writeCached(w http.ResponseWriter, r *http.Request, data []byte, cacheControl string) {
+	sum := sha256.Sum256(data)
+	etag := `W/"` + hex.EncodeToString(sum[:16]) + `"`
+	w.Header().Set("ETag", etag)
+	w.Header().Set("Cache-Control", cacheControl)
+	if etagMatches(r.Header.Get("If-None-Match"), etag) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	w.Write(data)
+}
+
+// etagMatches reports whether an If-None-Match header lists etag, comparing
+// weakly
+func etagMatches(ifNoneMatch, etag string) bool {
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == strings.TrimPrefix(etag, "W/") {
+			return true
+		}
+	}
+	return false
+}
+
+// writeEvent writes a single Server-Sent Event with a JSON payload
+func writeEvent(w io.Writer, event string, payload interface{}) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		slog.Error("failed to encode event", "event", event, "error", err)
+		return
+	}
+	fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event, data)
+}
+
+// fetchErrorStatus maps a target fetch failure to the status code returned to the client
+func fetchErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, profiler.ErrFetchTimeout):
+		return http.StatusGatewayTimeout
+	case errors.Is(err, profiler.ErrDNSFailure), errors.Is(err, profiler.ErrTLSHandshake):
+		return http.StatusBadGateway
+	case errors.Is(err, profiler.ErrTargetNotAllowed), errors.Is(err, profiler.ErrDisallowedByRobots):
+		return http.StatusForbidden
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// admit waits up to timeout for an analysis slot of q, for the request to
+// run its analysis. When the queue is full or the wait times out, it responds
+// 503 Service Unavailable with a Retry-After header and reports false.
+func admit(w http.ResponseWriter, r *http.Request, q *queue.Queue, timeout time.Duration) (release func(), ok bool) {
+	ctx, cancel := context.WithTimeout(r.Context(), timeout)
+	defer cancel()
+	release, err := q.Acquire(ctx)
+	if err == nil {
+		return release, true
+	}
+	if errors.Is(err, context.Canceled) {
+		// The client went away while queued
+		return nil, false
+	}
+	slog.WarnContext(r.Context(), "turned away analysis", "reason", err, "running", q.Running(), "queued", q.Queued())
+	// Slots free up as analyses complete, so clients retry after about the
+	// time they would have waited
+	w.Header().Set("Retry-After", strconv.Itoa(max(1, int(math.Ceil(timeout.Seconds())))))
+	reqlog.Error(w, r, "Too many analyses in progress, retry later", http.StatusServiceUnavailable)
+	return nil, false
+}
+
+// checkTarget returns a target check enforcing the scheme, port and domain
+// rules of targetPolicy on every URL an analysis requests, logging each decision
+func checkTarget(targetPolicy *policy.Policy) func(ctx context.Context, targetURL string) error {
+	return func(ctx context.Context, targetURL string) error {
+		decision := targetPolicy.EvaluateURL(targetURL)
+		if !decision.Allowed {
+			slog.InfoContext(ctx, "policy denied target", "url", targetURL, "reason", decision.Reason)
+			return errors.New(decision.Reason)
+		}
+		slog.DebugContext(ctx, "policy allowed target", "url", targetURL)
+		return nil
+	}
+}
+
+// checkAddress returns an address check enforcing the CIDR rules of
+// targetPolicy on every address an analysis connects to, logging denials
+func checkAddress(targetPolicy *policy.Policy) func(ctx context.Context, addr netip.Addr) error {
+	return func(ctx context.Context, addr netip.Addr) error {
+		decision := targetPolicy.EvaluateAddr(addr)
+		if !decision.Allowed {
+			slog.InfoContext(ctx, "policy denied address", "address", addr, "reason", decision.Reason)
+			return errors.New(decision.Reason)
+		}
+		return nil
+	}
+}
+
+// loadConfig reads the configuration at path, if any, over the defaults, then
+// applies the environment and the flags set on the command line, and validates
+// the result
+func loadConfig(path string, applyFlags func(*config.Config) error) (config.Config, error) {
+	cfg := config.Default()
+	if path != "" {
+		if err := cfg.Load(path); err != nil {
+			return cfg, err
+		}
+	}
+	if err := cfg.ApplyEnv(os.Getenv); err != nil {
+		return cfg, err
+	}
+	if err := applyFlags(&cfg); err != nil {
+		return cfg, err
+	}
+	return cfg, cfg.Validate()
+}
+
+// setHeader selects the fingerprint set of a request, as does the set
+// parameter
+const setHeader = "X-Kitsune-Set"
+
+// maxFingerprintTestSize bounds the body of a fingerprint test, sample included
+const maxFingerprintTestSize = 16 << 20
+
+// serverState is what the server derives from its configuration and
+// fingerprint data, replaced as a whole on reload
+type serverState struct {
+	// sets are the fingerprint sets requests select from, by name, the
+	// default one included
+	sets      map[string]*fingerprintSet
+	iconStore *icons.Store
+	adminKeys []string
+}
+
+// fingerprintSet is an engine loaded with a set of fingerprints, and the
+// catalogs the handlers serve from it
+type fingerprintSet struct {
+	name         string
+	engine       *profiler.Wappalyze
+	dataVersion  profiler.DataVersion
+	technologies []profiler.Technology
+	// categories are the categories of the engine, by ID
+	categories map[int]profiler.Category
+	// iconFiles are the icon files of the technologies, by lowercased name and slug
+	iconFiles map[string]string
+}
+
+// lookup returns the name of the technology of the set with the given name
+// or slug, case insensitively
+func (s *fingerprintSet) lookup(name string) (string, bool) {
+	if _, ok := s.engine.GetFingerprints().Apps[name]; ok {
+		return name, true
+	}
+	for _, technology := range s.technologies {
+		if strings.EqualFold(technology.Name, name) || technology.Slug == strings.ToLower(name) {
+			return technology.Name, true
+		}
+	}
+	return "", false
+}
+
+// compactDefinition returns fingerprint as JSON without its empty fields, as
+// in fingerprint files
+func compactDefinition(fingerprint *profiler.Fingerprint) (json.RawMessage, error) {
+	data, err := json.Marshal(fingerprint)
+	if err != nil {
+		return nil, err
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, err
+	}
+	for name, value := range fields {
+		switch string(value) {
+		case "null", `""`, "[]", "{}":
+			delete(fields, name)
+		}
+	}
+	return json.Marshal(fields)
+}
+
+// requestSet returns the fingerprint set selected by a request: by name if
+// not empty, as given in its body, then by the set header or parameter, and
+// the default set otherwise
+func (s *serverState) requestSet(r *http.Request, name string) (*fingerprintSet, error) {
+	if name == "" {
+		name = r.Header.Get(setHeader)
+	}
+	if name == "" {
+		name = r.URL.Query().Get("set")
+	}
+	if name == "" {
+		name = config.DefaultSet
+	}
+	set, ok := s.sets[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown fingerprint set %q", name)
+	}
+	return set, nil
+}
+
+// newServerState builds the engines of cfg, one per fingerprint set, loading
+// their fingerprint data, and the catalogs the handlers serve from them
+func newServerState(cfg config.Config, logger *slog.Logger) (*serverState, error) {
+	// Initialize the profiler, keeping the asset requests and parsing of each
+	// analysis polite and bounded. The settings were validated with the config,
+	// so they parse.
+	profile, _ := profiler.ParseProfile(cfg.Scan.Profile)
+	tlsPolicy, _ := profiler.ParseTLSPolicy(cfg.Scan.TLSPolicy)
+	ipFamily, _ := profiler.ParseIPFamily(cfg.Scan.IPFamily)
+	disabled, _ := profiler.ParseVectors(strings.Join(cfg.Scan.Disable, ","))
+	options := []profiler.Option{
+		profiler.WithLogger(logger),
+		profiler.WithAssetPolicy(profiler.DefaultAssetPolicy()),
+		profiler.WithParseLimits(profiler.DefaultParseLimits()),
+		// Analyses run with the configured profile unless the request chooses one
+		profiler.WithProfile(profile),
+		profiler.WithTLSPolicy(tlsPolicy),
+		profiler.WithIPFamily(ipFamily),
+		// Falling back to plain HTTP for legacy hosts downgrades the fetch, so
+		// it is opt-in and the results it gives are marked
+		profiler.WithSchemeFallback(cfg.Scan.SchemeFallback),
+		profiler.WithDisabledVectors(disabled...),
+		profiler.WithMatchWorkers(cfg.Scan.MatchWorkers),
+		profiler.WithDNSCache(cfg.Cache.DNS),
+	}
+
+	// Bound each analysis by the budget, returning partial results when it runs out
+	if cfg.Scan.Budget > 0 {
+		options = append(options, profiler.WithBudget(cfg.Scan.Budget))
+	}
+
+	// Limit outbound requests per second, to all hosts and to each host. The
+	// option is shared by the engines of the sets, so the limits hold across them.
+	rateLimit := profiler.RateLimit{PerSecond: cfg.Scan.OutboundRate, PerHostPerSecond: cfg.Scan.OutboundHostRate}
+	if rateLimit.PerSecond > 0 || rateLimit.PerHostPerSecond > 0 {
+		options = append(options, profiler.WithRateLimit(rateLimit))
+	}
+
+	if len(cfg.Scan.ProbePorts) > 0 {
+		options = append(options, profiler.WithPortProbing(cfg.Scan.ProbePorts...))
+	}
+
+	// Identify the scans, so sites can learn about them and opt out of them
+	// with robots.txt in polite mode
+	switch {
+	case cfg.Scan.ContactURL != "":
+		options = append(options, profiler.WithUserAgent(profiler.ScannerUserAgent(cfg.Scan.ContactURL)))
+	case cfg.Scan.UserAgent != "":
+		options = append(options, profiler.WithUserAgent(profiler.UserAgent{Name: "custom", Header: cfg.Scan.UserAgent}))
+	}
+	if cfg.Scan.Polite {
+		options = append(options, profiler.WithPoliteMode(true))
+	}
+
+	// Restrict the targets that may be scanned, if a policy is configured
+	targetPolicy, err := configurePolicy(cfg.Policy)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load target policy: %w", err)
+	}
+	if targetPolicy != nil {
+		options = append(options, profiler.WithTargetCheck(checkTarget(targetPolicy)), profiler.WithAddressCheck(checkAddress(targetPolicy)))
+	}
+
+	// The default set is made of the fingerprints and overlays of scan. The
+	// engines of the sets share the compiled patterns of the fingerprints
+	// they have in common, such as the embedded ones.
+	sets := map[string]*fingerprintSet{}
+	definitions := map[string]config.FingerprintSet{
+		config.DefaultSet: {Fingerprints: cfg.Scan.Fingerprints, Overlays: cfg.Scan.Overlays},
+	}
+	for name, definition := range cfg.Scan.Sets {
+		definitions[name] = definition
+	}
+	for name, definition := range definitions {
+		set, err := newFingerprintSet(name, definition, cfg.Cache, options)
+		if err != nil {
+			if name == config.DefaultSet {
+				return nil, err
+			}
+			return nil, fmt.Errorf("fingerprint set %s: %w", name, err)
+		}
+		sets[name] = set
+	}
+
+	// Icons are read from a local directory, or fetched from upstream and cached
+	iconsURL := cfg.Icons.URL
+	switch iconsURL {
+	case "":
+		iconsURL = icons.DefaultBaseURL
+	case "none":
+		iconsURL = ""
+	}
+
+	return &serverState{
+		sets:      sets,
+		iconStore: icons.New(cfg.Icons.Dir, iconsURL, 1024),
+		adminKeys: cfg.Auth.AdminKeys,
+	}, nil
+}
+
+// newFingerprintSet builds the engine of the named fingerprint set with
+// options, and its catalogs. Fingerprints from a file supersede the embedded
+// ones of the same name.
+func newFingerprintSet(name string, definition config.FingerprintSet, cache config.Cache, options []profiler.Option) (*fingerprintSet, error) {
+	options = slices.Clip(options)
+	if len(definition.Overlays) > 0 {
+		options = append(options, profiler.WithOverlays(definition.Overlays...))
+	}
+	// Serve repeated analyses of a URL from the result cache, if enabled. The
+	// results depend on the fingerprints, so every set has its own cache.
+	if cache.Results > 0 {
+		options = append(options, profiler.WithResultCache(profiler.NewLRUCache(cache.Results), cache.ResultTTL, 0))
+	}
+
+	var engine *profiler.Wappalyze
+	var err error
+	if definition.Fingerprints != "" {
+		engine, err = profiler.NewFromFile(definition.Fingerprints, true, true, options...)
+	} else {
+		engine, err = profiler.New(options...)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	technologies := engine.Technologies()
+	categories := make(map[int]profiler.Category)
+	for _, category := range engine.Categories() {
+		categories[category.ID] = category
+	}
+	iconFiles := make(map[string]string, 2*len(technologies))
+	for _, technology := range technologies {
+		file := technology.Icon
+		if file == "" {
+			file = icons.DefaultIcon
+		}
+		iconFiles[strings.ToLower(technology.Name)] = file
+		iconFiles[technology.Slug] = file
+	}
+
+	return &fingerprintSet{
+		name:         name,
+		engine:       engine,
+		dataVersion:  engine.DataVersion(),
+		technologies: technologies,
+		categories:   categories,
+		iconFiles:    iconFiles,
+	}, nil
+}
+
+// restartSettings lists the settings of next that differ from old, the
+// settings the server started with, but are only applied on start
+func restartSettings(old, next config.Config) []string {
+	var changed []string
+	if old.Server != next.Server {
+		changed = append(changed, "server")
+	}
+	if old.Log.Format != next.Log.Format {
+		changed = append(changed, "log.format")
+	}
+	// Keys are replaced on reload, but authentication is only turned on or off on restart
+	if (len(old.Auth.APIKeys) == 0) != (len(next.Auth.APIKeys) == 0) {
+		changed = append(changed, "auth.api_keys")
+	}
+	if !reflect.DeepEqual(old.Sinks, next.Sinks) {
+		changed = append(changed, "sinks")
+	}
+	return changed
+}
+
+// requireAdmin rejects requests to next without one of the admin keys with
+// 401 Unauthorized. Without admin keys, the admin endpoints are not found.
+func requireAdmin(next http.Handler, adminKeys func() []string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		keys := adminKeys()
+		if len(keys) == 0 {
+			reqlog.Error(w, r, "Admin endpoints are disabled", http.StatusNotFound)
+			return
+		}
+		key := apikey.FromRequest(r)
+		for _, adminKey := range keys {
+			if subtle.ConstantTimeCompare([]byte(key), []byte(adminKey)) == 1 {
+				next.ServeHTTP(w, r)
+				return
+			}
+		}
+		w.Header().Set("WWW-Authenticate", `Bearer realm="kitsune-admin"`)
+		reqlog.Error(w, r, "A valid admin key is required", http.StatusUnauthorized)
+	})
+}
+
+// configurePolicy compiles the target policy, from its file or its inline
+// rules. It is nil when no rule is configured.
+func configurePolicy(cfg config.Policy) (*policy.Policy, error) {
+	if cfg.File != "" {
+		return policy.Load(cfg.File)
+	}
+	if reflect.DeepEqual(cfg.Config, policy.Config{}) {
+		return nil, nil
+	}
+	return policy.New(cfg.Config)
+}
+
+// configureAuth wraps handler with API key authentication when keys are
+// configured, as "key[:rate-per-minute[:daily-quota]]" entries. The rate limit
+// and daily quota of the config apply to keys without their own, 0 meaning
+// unlimited. The authenticator is returned for reloads to replace its keys,
+// nil without keys.
+func configureAuth(handler http.Handler, cfg config.Auth) (http.Handler, *apikey.Authenticator, error) {
+	if len(cfg.APIKeys) == 0 {
+		return handler, nil, nil
+	}
+
+	keys, err := apikey.ParseKeys(strings.Join(cfg.APIKeys, ","), cfg.RateLimit, cfg.DailyQuota)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(keys) == 0 {
+		return nil, nil, fmt.Errorf("auth.api_keys contains no keys")
+	}
+	authenticator := apikey.New(keys)
+	return authenticator.Middleware(handler, "/health"), authenticator, nil
+}
+
+// configureSinks creates the result sinks of the config: Kafka when brokers
+// are set, NATS when a URL is set, and webhooks when theirs are
+func configureSinks(cfg config.Sinks) (export.MultiSink, error) {
+	var sinks export.MultiSink
+
+	if len(cfg.Kafka.Brokers) > 0 {
+		sink, err := export.NewKafkaSink(cfg.Kafka.Brokers, cfg.Kafka.Topic)
+		if err != nil {
+			return nil, err
+		}
+		sinks = append(sinks, sink)
+	}
+
+	if cfg.NATS.URL != "" {
+		sink, err := export.NewNATSSink(cfg.NATS.URL, cfg.NATS.Subject)
+		if err != nil {
+			sinks.Close()
+			return nil, err
+		}
+		sinks = append(sinks, sink)
+	}
+
+	if len(cfg.Webhooks) > 0 {
+		var targets []webhook.Target
+		for _, hook := range cfg.Webhooks {
+			targets = append(targets, webhook.Target{URL: hook.URL, Secret: hook.Secret, Retry: hook.RetryPolicy()})
+		}
+		sink, err := export.NewWebhookSink(targets...)
+		if err != nil {
+			sinks.Close()
+			return nil, err
+		}
+		sinks = append(sinks, sink)
+	}
+
+	return sinks, nil
+}
+
+// configureLogger creates the logger of the config, whose level and format
+// were validated with it, along with its level for reloads to change. The
+// debug level includes the debug logs of the profiler, such as regex timeouts
+// and matcher timings. Records logged with a request context carry the
+// request ID.
+func configureLogger(cfg config.Log) (*slog.Logger, *slog.LevelVar) {
+	level := new(slog.LevelVar)
+	level.UnmarshalText([]byte(cfg.Level))
+	options := &slog.HandlerOptions{Level: level}
+
+	if cfg.Format == "text" {
+		return slog.New(reqlog.NewHandler(slog.NewTextHandler(os.Stderr, options))), level
+	}
+	return slog.New(reqlog.NewHandler(slog.NewJSONHandler(os.Stderr, options))), level
+}
+
+// requestProfile parses the profile chosen by a request. It is empty when the
+// request does not choose one, so the server default applies.
+func requestProfile(name string) (profiler.Profile, error) {
+	if name == "" {
+		return "", nil
+	}
+	return profiler.ParseProfile(name)
+}
+
+// withProfile runs the analyses of ctx with profile, if not empty
+func withProfile(ctx context.Context, profile profiler.Profile) context.Context {
+	if profile == "" {
+		return ctx
+	}
+	return profiler.ProfileContext(ctx, profile)
+}
+
+// withExplain reports the near misses of the analyses of ctx, if explain
+func withExplain(ctx context.Context, explain bool) context.Context {
+	if !explain {
+		return ctx
+	}
+	return profiler.ExplainContext(ctx)
+}
+
+// withTrace traces the analyses of ctx, if trace
+func withTrace(ctx context.Context, trace bool) context.Context {
+	if !trace {
+		return ctx
+	}
+	return profiler.TraceContext(ctx)
+}
+
+// requestTags splits the comma-separated tags of a request
+func requestTags(value string) []string {
+	if value == "" {
+		return nil
+	}
+	return strings.Split(value, ",")
+}
+
+// fatal logs a startup failure and exits
+func fatal(msg string, err error) {
+	slog.Error(msg, "error", err)
+	os.Exit(1)
 }
@@ -0,0 +1,163 @@
+// Command kitsune-cshared builds kitsune as a C shared library, so Python, Ruby
+// and other tooling can embed it instead of running a binary per URL:
+//
+//	go build -buildmode=c-shared -o libkitsune.so ./cmd/kitsune-cshared
+//
+// which also writes the libkitsune.h header. Both analysis functions take a
+// JSON request and return a JSON response as C strings:
+//
+//	KitsuneAnalyzeURL({"url": "https://example.com", "timeout": "30s", "profile": "fast"})
+//	KitsuneAnalyzeRaw({"url": "https://example.com", "headers": {"Server": ["nginx"]}, "body": "<html>..."})
+//
+// KitsuneAnalyzeURL fetches and analyzes the URL, KitsuneAnalyzeRaw analyzes a
+// response that was already fetched and sends no requests. Only url is required
+// by the former, timeout defaults to 30s and profile to standard. The response
+// is {"url": ..., "detections": {...}} or, on failure, {"error": ...}. It must
+// be released with KitsuneFree.
+package main
+
+/*
+#include <stdlib.h>
+*/
+import "C"
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/textproto"
+	"net/url"
+	"sync"
+	"time"
+	"unsafe"
+
+	"github.com/kavinsood/kitsune/internal/profiler"
+)
+
+// engine is loaded on the first call, so loading the library stays cheap
+var engine = sync.OnceValues(func() (*profiler.Wappalyze, error) {
+	return profiler.New(profiler.WithSchemeFallback(true))
+})
+
+// urlRequest is the request of KitsuneAnalyzeURL
+type urlRequest struct {
+	URL     string `json:"url"`
+	Timeout string `json:"timeout,omitempty"`
+	Profile string `json:"profile,omitempty"`
+}
+
+// rawRequest is the request of KitsuneAnalyzeRaw
+type rawRequest struct {
+	URL     string              `json:"url,omitempty"`
+	Headers map[string][]string `json:"headers"`
+	Body    string              `json:"body"`
+}
+
+// response is the response of both analysis functions
+type response struct {
+	URL        string                        `json:"url,omitempty"`
+	Detections map[string]profiler.Detection `json:"detections,omitempty"`
+	// Partial is set if the timeout passed before all stages finished
+	Partial bool   `json:"partial,omitempty"`
+	Error   string `json:"error,omitempty"`
+}
+
+// KitsuneAnalyzeURL fetches and analyzes the URL of a JSON request
+//
+//export KitsuneAnalyzeURL
+func KitsuneAnalyzeURL(request *C.char) *C.char {
+	return respond(analyzeURL(C.GoString(request)))
+}
+
+// KitsuneAnalyzeRaw analyzes the headers and body of a JSON request
+//
+//export KitsuneAnalyzeRaw
+func KitsuneAnalyzeRaw(request *C.char) *C.char {
+	return respond(analyzeRaw(C.GoString(request)))
+}
+
+// KitsuneFree releases a response returned by the library
+//
+//export KitsuneFree
+func KitsuneFree(response *C.char) {
+	C.free(unsafe.Pointer(response))
+}
+
+// analyzeURL implements KitsuneAnalyzeURL
+func analyzeURL(data string) (response, error) {
+	var request urlRequest
+	if err := json.Unmarshal([]byte(data), &request); err != nil {
+		return response{}, fmt.Errorf("could not parse request: %w", err)
+	}
+	if request.URL == "" {
+		return response{}, fmt.Errorf("url is required")
+	}
+	timeout := 30 * time.Second
+	if request.Timeout != "" {
+		var err error
+		if timeout, err = time.ParseDuration(request.Timeout); err != nil {
+			return response{}, fmt.Errorf("invalid timeout: %w", err)
+		}
+	}
+	profile, err := profiler.ParseProfile(request.Profile)
+	if err != nil {
+		return response{}, err
+	}
+	wappalyzer, err := engine()
+	if err != nil {
+		return response{}, fmt.Errorf("could not initialize profiler engine: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(profiler.ProfileContext(context.Background(), profile), timeout)
+	defer cancel()
+	result, err := wappalyzer.FingerprintURL(ctx, request.URL)
+	if err != nil {
+		return response{}, err
+	}
+	return response{URL: request.URL, Detections: result.GetDetections(), Partial: result.PartialResult()}, nil
+}
+
+// analyzeRaw implements KitsuneAnalyzeRaw
+func analyzeRaw(data string) (response, error) {
+	var request rawRequest
+	if err := json.Unmarshal([]byte(data), &request); err != nil {
+		return response{}, fmt.Errorf("could not parse request: %w", err)
+	}
+	wappalyzer, err := engine()
+	if err != nil {
+		return response{}, fmt.Errorf("could not initialize profiler engine: %w", err)
+	}
+
+	resp := &http.Response{Header: make(http.Header, len(request.Headers))}
+	for name, values := range request.Headers {
+		key := textproto.CanonicalMIMEHeaderKey(name)
+		resp.Header[key] = append(resp.Header[key], values...)
+	}
+	// The URL is matched by the url patterns of the fingerprints
+	if request.URL != "" {
+		parsedURL, err := url.Parse(request.URL)
+		if err != nil {
+			return response{}, fmt.Errorf("invalid url: %w", err)
+		}
+		resp.Request = &http.Request{URL: parsedURL}
+	}
+	// The fast profile only matches the response, without DNS lookups or fetches
+	ctx := profiler.ProfileContext(context.Background(), profiler.ProfileFast)
+	result := wappalyzer.AnalyzeWithPipelineContext(ctx, resp, []byte(request.Body))
+	return response{URL: request.URL, Detections: result.GetDetections()}, nil
+}
+
+// respond encodes the response of an analysis, or its error, as a C string
+func respond(result response, err error) *C.char {
+	if err != nil {
+		result = response{Error: err.Error()}
+	}
+	data, err := json.Marshal(result)
+	if err != nil {
+		data, _ = json.Marshal(response{Error: err.Error()})
+	}
+	return C.CString(string(data))
+}
+
+func main() {}
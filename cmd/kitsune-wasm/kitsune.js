@@ -0,0 +1,54 @@
+// Bindings for kitsune.wasm, the detection core of kitsune compiled to
+// WebAssembly. wasm_exec.js of the Go release the module was built with, from
+// $(go env GOROOT)/lib/wasm, must be loaded first so that Go is defined.
+//
+//   const kitsune = await load(fetch("kitsune.wasm"));          // browser
+//   const kitsune = await load(fs.readFileSync("kitsune.wasm")); // Node
+//   kitsune.analyze({"Server": "nginx"}, "<html>...</html>");
+//   // => {detections: {Nginx: {confidence: 100, detected_by: ["headers"]}}}
+
+// load instantiates the module from its bytes, or from a response or a promise
+// of one, and returns its functions once it is ready
+export async function load(source) {
+  const go = new Go();
+  const { instance } =
+    source instanceof ArrayBuffer || ArrayBuffer.isView(source)
+      ? await WebAssembly.instantiate(source, go.importObject)
+      : await WebAssembly.instantiateStreaming(source, go.importObject);
+  go.run(instance);
+  const core = globalThis.kitsune;
+
+  return {
+    // analyze returns the technologies detected in a response. headers is an
+    // object of names to a value or a list of values, or a Headers instance.
+    analyze(headers, html) {
+      return JSON.parse(unwrap(core.analyze(JSON.stringify(headerLists(headers)), html)));
+    },
+
+    // dataVersion returns the version of the fingerprint data of the module
+    dataVersion() {
+      return JSON.parse(unwrap(core.dataVersion()));
+    },
+  };
+}
+
+// headerLists converts headers to the object of lists the module expects
+function headerLists(headers) {
+  const lists = {};
+  const entries =
+    headers && typeof headers.entries === "function" && !Array.isArray(headers)
+      ? headers.entries()
+      : Object.entries(headers || {});
+  for (const [name, value] of entries) {
+    lists[name] = (lists[name] || []).concat(value);
+  }
+  return lists;
+}
+
+// unwrap throws the errors the module returns
+function unwrap(value) {
+  if (value instanceof Error) {
+    throw value;
+  }
+  return value;
+}
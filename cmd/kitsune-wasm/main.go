@@ -0,0 +1,86 @@
+//go:build js && wasm
+
+// Command kitsune-wasm is the detection core compiled to WebAssembly, for
+// browser extensions and Node tooling that already have the response of a
+// page. It matches the same fingerprints as the Go library but sends no
+// requests: only the headers and HTML it is given are analyzed.
+//
+// Build it with:
+//
+//	GOOS=js GOARCH=wasm go build -o kitsune.wasm ./cmd/kitsune-wasm
+//
+// and load it with kitsune.js, next to this file, which wraps the functions
+// the module registers on the global kitsune object:
+//
+//	kitsune.analyze(headersJSON, html) returns the detections as JSON
+//	kitsune.dataVersion() returns the version of the fingerprint data as JSON
+//
+// Both return an Error on failure.
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/textproto"
+	"syscall/js"
+
+	"github.com/kavinsood/kitsune/internal/profiler"
+)
+
+func main() {
+	// The fast profile only matches the page, without DNS lookups or fetches
+	engine, err := profiler.New(profiler.WithProfile(profiler.ProfileFast))
+	if err != nil {
+		panic(fmt.Sprintf("could not initialize profiler engine: %v", err))
+	}
+
+	kitsune := js.Global().Get("Object").New()
+	kitsune.Set("analyze", js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+		if len(args) != 2 || args[0].Type() != js.TypeString || args[1].Type() != js.TypeString {
+			return jsError("analyze expects the headers as JSON and the HTML as strings")
+		}
+		output, err := analyze(engine, args[0].String(), args[1].String())
+		if err != nil {
+			return jsError(err.Error())
+		}
+		return string(output)
+	}))
+	kitsune.Set("dataVersion", js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+		output, err := json.Marshal(engine.DataVersion())
+		if err != nil {
+			return jsError(err.Error())
+		}
+		return string(output)
+	}))
+	js.Global().Set("kitsune", kitsune)
+
+	// The functions are called from JavaScript for the lifetime of the page
+	select {}
+}
+
+// analyze matches the headers, an object of header names to lists of values,
+// and the HTML of a response, and returns the detections as JSON
+func analyze(engine *profiler.Wappalyze, headersJSON, html string) ([]byte, error) {
+	var headers map[string][]string
+	if err := json.Unmarshal([]byte(headersJSON), &headers); err != nil {
+		return nil, fmt.Errorf("could not parse headers: %w", err)
+	}
+	header := make(http.Header, len(headers))
+	for name, values := range headers {
+		key := textproto.CanonicalMIMEHeaderKey(name)
+		header[key] = append(header[key], values...)
+	}
+
+	result := engine.AnalyzeWithPipeline(&http.Response{Header: header}, []byte(html))
+	return json.Marshal(struct {
+		Detections map[string]profiler.Detection `json:"detections"`
+	}{
+		Detections: result.GetDetections(),
+	})
+}
+
+// jsError returns a JavaScript Error with message
+func jsError(message string) js.Value {
+	return js.Global().Get("Error").New(message)
+}
@@ -0,0 +1,689 @@
+// Command kitsune fingerprints web technologies from the command line.
+//
+// Usage:
+//
+//	kitsune scan [--history path] [--timeout duration] [--es-url url] [--kafka-brokers list] [--nats-url url] <url>
+//	kitsune scan-file [--workers n] [--output path] [--checkpoint path] [--rate n] [--host-rate n] [--geoip paths] <file>
+//	kitsune discover [--scan n] [--workers n] [--ct-url url] <domain>
+//	kitsune diff [--history path] [--json] <url>
+//	kitsune monitor [--history path] [--interval duration] [--webhook url] [--webhook-secret key] [--slack-webhook url] [--once] <url>...
+//	kitsune lint [--json] <file>...
+//
+// scan analyzes a URL and prints its detections as JSON, recording the scan in
+// the history database and optionally publishing it to Elasticsearch, Kafka or NATS. scan-file
+// scans a list of URLs concurrently, writing a JSON line per URL, and resumes
+// from its checkpoint after an interruption. discover lists the hosts of a domain named by
+// Certificate Transparency logs, and optionally scans a sample of them. diff reports the technologies added, removed or changed
+// in version between the two most recent recorded scans of a URL. monitor rescans
+// URLs on a schedule and notifies webhooks when their technology stack changes.
+// lint validates custom fingerprint or overlay files before they are loaded.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/kavinsood/kitsune/internal/bulk"
+	"github.com/kavinsood/kitsune/internal/ctlog"
+	"github.com/kavinsood/kitsune/internal/export"
+	"github.com/kavinsood/kitsune/internal/geoip"
+	"github.com/kavinsood/kitsune/internal/history"
+	"github.com/kavinsood/kitsune/internal/monitor"
+	"github.com/kavinsood/kitsune/internal/profiler"
+	"github.com/kavinsood/kitsune/internal/webhook"
+)
+
+// defaultHistoryPath is where scans are recorded unless --history is given
+const defaultHistoryPath = "kitsune-history.db"
+
+func main() {
+	log.SetFlags(0)
+
+	if len(os.Args) < 2 {
+		usage()
+		os.Exit(2)
+	}
+
+	var err error
+	switch os.Args[1] {
+	case "scan":
+		err = runScan(os.Args[2:])
+	case "scan-file":
+		err = runScanFile(os.Args[2:])
+	case "discover":
+		err = runDiscover(os.Args[2:])
+	case "diff":
+		err = runDiff(os.Args[2:])
+	case "monitor":
+		err = runMonitor(os.Args[2:])
+	case "lint":
+		err = runLint(os.Args[2:])
+	case "-h", "--help", "help":
+		usage()
+		return
+	default:
+		log.Printf("Unknown command %q", os.Args[1])
+		usage()
+		os.Exit(2)
+	}
+
+	if err != nil {
+		log.Fatalf("Error: %v", err)
+	}
+}
+
+// usage prints the list of subcommands
+func usage() {
+	fmt.Fprintln(os.Stderr, `Usage: kitsune <command> [flags] <url>
+
+Commands:
+  scan       Fingerprint a URL and record the scan in the history database
+  scan-file  Fingerprint a list of URLs to JSON lines, resuming from a checkpoint
+  discover   List the hosts of a domain from Certificate Transparency logs, and scan them
+  diff       Compare the two most recent recorded scans of a URL
+  monitor    Rescan URLs on a schedule and notify webhooks of changes
+  lint       Validate custom fingerprint or overlay files
+
+Run "kitsune <command> -h" for the flags of a command.`)
+}
+
+// runScan implements the scan subcommand
+func runScan(args []string) error {
+	flags := flag.NewFlagSet("scan", flag.ExitOnError)
+	historyPath := flags.String("history", defaultHistoryPath, "SQLite database to record the scan in (empty to disable)")
+	timeout := flags.Duration("timeout", 30*time.Second, "Maximum duration of the scan")
+	esURL := flags.String("es-url", "", "Elasticsearch/OpenSearch endpoint to export the scan to, credentials as user info")
+	esIndex := flags.String("es-index", "kitsune-scans", "Elasticsearch/OpenSearch index to export the scan to")
+	kafkaBrokers := flags.String("kafka-brokers", "", "Comma separated Kafka brokers to publish the scan to")
+	kafkaTopic := flags.String("kafka-topic", "kitsune-scans", "Kafka topic to publish the scan to")
+	natsURL := flags.String("nats-url", "", "NATS server to publish the scan to")
+	natsSubject := flags.String("nats-subject", "kitsune.scans", "NATS subject to publish the scan to")
+	format := flags.String("format", "", "Output format: empty for detections, \"wappalyzer\" for the Wappalyzer CLI schema, \"httpx\" for an httpx JSONL line, \"csv\" or \"table\" for a row per technology, \"sarif\" for a SARIF 2.1.0 log")
+	profileName := flags.String("profile", "standard", "Scan profile: \"fast\" (page only), \"standard\" or \"deep\" (adds error page and header order probes)")
+	budget := flags.Duration("budget", 0, "Time limit of the analysis, after which the detections gathered so far are reported (0 for none)")
+	disable := flags.String("disable", "", "Comma separated vectors to skip: dns, email, robots, tls, dom, js, css or assets")
+	aliases := flags.Bool("aliases", false, "Also scan the www or apex variant of the host and merge the results")
+	probePorts := flags.String("ports", "", "Comma separated alternate ports of the host to probe for admin consoles and application servers, e.g. 8080,8443,9090")
+	geoipPaths := flags.String("geoip", "", "Comma separated MaxMind DB files to locate the address of the target in, e.g. GeoLite2 Country and ASN")
+	compareUA := flags.String("compare-ua", "", "Comma separated user agents to scan with and compare, among desktop, mobile and bot, instead of a single scan")
+	tlsPolicyName := flags.String("tls", "log", "Handling of invalid certificates: \"log\" (report them), \"strict\" (refuse them) or \"insecure\" (ignore them)")
+	ipFamilyName := flags.String("ip", "", "Only connect over \"ipv4\" or \"ipv6\" (empty for either)")
+	recordPath := flags.String("record", "", "File to record the HTTP and DNS traffic of the scan to, as a fixture to replay")
+	replayPath := flags.String("replay", "", "Fixture recorded with --record to replay the scan from, without the network")
+	allEvidence := flags.Bool("all-evidence", false, "Match every pattern, even of technologies already detected with a version, to list every vector that detects them")
+	contactURL := flags.String("contact-url", "", "Page about the scan, pointed to by an identifying Kitsune User-Agent sent instead of a browser's")
+	polite := flags.Bool("polite", false, "Skip the URL if its robots.txt disallows it to the User-Agent")
+	tracePath := flags.String("trace", "", "File to write a trace of the scan to, as JSON, to attach to reports of missed detections")
+	flags.Parse(args)
+
+	if flags.NArg() != 1 {
+		return fmt.Errorf("scan expects exactly one URL")
+	}
+	switch *format {
+	case "", "wappalyzer", "httpx", "csv", "table", "sarif":
+	default:
+		return fmt.Errorf("unsupported output format %q", *format)
+	}
+	profile, err := profiler.ParseProfile(*profileName)
+	if err != nil {
+		return err
+	}
+	disabled, err := profiler.ParseVectors(*disable)
+	if err != nil {
+		return err
+	}
+	ports, err := profiler.ParsePorts(*probePorts)
+	if err != nil {
+		return err
+	}
+	userAgents, err := profiler.ParseUserAgents(*compareUA)
+	if err != nil {
+		return err
+	}
+	tlsPolicy, err := profiler.ParseTLSPolicy(*tlsPolicyName)
+	if err != nil {
+		return err
+	}
+	ipFamily, err := profiler.ParseIPFamily(*ipFamilyName)
+	if err != nil {
+		return err
+	}
+	if len(userAgents) > 0 && *format != "" {
+		return fmt.Errorf("--compare-ua does not support output formats")
+	}
+	targetURL := flags.Arg(0)
+
+	options := []profiler.Option{profiler.WithSchemeFallback(true), profiler.WithProfile(profile), profiler.WithDisabledVectors(disabled...), profiler.WithBudget(*budget), profiler.WithHostAliases(*aliases), profiler.WithTLSPolicy(tlsPolicy), profiler.WithIPFamily(ipFamily), profiler.WithGatherAllEvidence(*allEvidence)}
+	if len(ports) > 0 {
+		options = append(options, profiler.WithPortProbing(ports...))
+	}
+	options = append(options, scanIdentity(*contactURL, *polite)...)
+	if *tracePath != "" {
+		options = append(options, profiler.WithTrace(true))
+	}
+	if *geoipPaths != "" {
+		db, err := geoip.Open(strings.Split(*geoipPaths, ",")...)
+		if err != nil {
+			return err
+		}
+		defer db.Close()
+		options = append(options, profiler.WithGeoIP(db))
+	}
+	var recorder *profiler.Recorder
+	if *recordPath != "" {
+		recorder = profiler.NewRecorder()
+		options = append(options, profiler.WithRecorder(recorder))
+	}
+	if *replayPath != "" {
+		fixture, err := readFixture(*replayPath)
+		if err != nil {
+			return err
+		}
+		options = append(options, profiler.WithReplay(fixture))
+	}
+	engine, err := profiler.New(options...)
+	if err != nil {
+		return fmt.Errorf("could not initialize profiler engine: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+
+	// Comparisons are printed as is, they are not a single scan to record
+	if len(userAgents) > 0 {
+		comparison, err := engine.CompareUserAgents(ctx, targetURL, userAgents...)
+		if recorder != nil {
+			if err := writeFixture(*recordPath, recorder.Fixture()); err != nil {
+				return err
+			}
+		}
+		if err != nil {
+			return err
+		}
+		return printJSON(struct {
+			URL string `json:"url"`
+			profiler.UserAgentComparison
+		}{targetURL, comparison})
+	}
+
+	// Set up the sinks before scanning, so misconfiguration fails fast
+	var sinks export.MultiSink
+	defer func() { sinks.Close() }()
+	if *esURL != "" {
+		exporter, err := export.NewElasticsearchExporter(*esURL, *esIndex)
+		if err != nil {
+			return err
+		}
+		if err := exporter.EnsureIndex(ctx); err != nil {
+			return err
+		}
+		sinks = append(sinks, exporter)
+	}
+	if *kafkaBrokers != "" {
+		sink, err := export.NewKafkaSink(strings.Split(*kafkaBrokers, ","), *kafkaTopic)
+		if err != nil {
+			return err
+		}
+		sinks = append(sinks, sink)
+	}
+	if *natsURL != "" {
+		sink, err := export.NewNATSSink(*natsURL, *natsSubject)
+		if err != nil {
+			return err
+		}
+		sinks = append(sinks, sink)
+	}
+
+	// The traffic and trace of failed scans are written too, to reproduce the failure
+	result, err := engine.FingerprintURL(ctx, targetURL)
+	if recorder != nil {
+		if err := writeFixture(*recordPath, recorder.Fixture()); err != nil {
+			return err
+		}
+	}
+	if *tracePath != "" {
+		if err := writeTrace(*tracePath, result.GetTrace()); err != nil {
+			return err
+		}
+	}
+	if err != nil {
+		return err
+	}
+	if result.PartialResult() {
+		fmt.Fprintf(os.Stderr, "budget exhausted, partial results without stages %v\n", result.GetSkippedStages())
+	}
+	if limits := result.GetStats().LimitsHit; len(limits) > 0 {
+		fmt.Fprintf(os.Stderr, "resource limits %v reached, part of the page was not analyzed\n", limits)
+	}
+	scannedAt := time.Now()
+
+	document := export.NewDocument(targetURL, scannedAt, result)
+	if err := sinks.Publish(ctx, document); err != nil {
+		return err
+	}
+
+	if *historyPath != "" {
+		store, err := history.OpenSQLite(*historyPath)
+		if err != nil {
+			return err
+		}
+		defer store.Close()
+
+		if _, err := store.Record(ctx, history.Scan{URL: targetURL, ScannedAt: scannedAt, Detections: result.GetDetections()}); err != nil {
+			return err
+		}
+	}
+
+	switch *format {
+	case "wappalyzer":
+		return printJSON(engine.Wappalyzer(result))
+	case "httpx":
+		return profiler.NewHTTPXWriter(os.Stdout).Write(profiler.NewHTTPXRecord(targetURL, scannedAt, result))
+	case "csv":
+		return export.WriteCSV(os.Stdout, document)
+	case "table":
+		return export.WriteTable(os.Stdout, document)
+	case "sarif":
+		return export.WriteSARIF(os.Stdout, document)
+	}
+
+	output := struct {
+		URL          string                        `json:"url"`
+		CanonicalURL string                        `json:"canonical_url,omitempty"`
+		Hosts        []profiler.HostAnalysis       `json:"hosts,omitempty"`
+		Ports        []profiler.PortProbe          `json:"ports,omitempty"`
+		Location     *profiler.Location            `json:"location,omitempty"`
+		Email        *profiler.EmailInfo           `json:"email,omitempty"`
+		Robots       *profiler.RobotsTxt           `json:"robots,omitempty"`
+		RemoteIP     string                        `json:"remote_ip,omitempty"`
+		IPFamily     profiler.IPFamily             `json:"ip_family,omitempty"`
+		TLS          *profiler.TLSValidation       `json:"tls_validation,omitempty"`
+		Certificate  *profiler.Certificate         `json:"certificate,omitempty"`
+		Stack        profiler.Stack                `json:"stack"`
+		Detections   map[string]profiler.Detection `json:"detections"`
+	}{
+		URL:          targetURL,
+		CanonicalURL: result.GetCanonicalURL(),
+		Hosts:        result.GetHosts(),
+		Ports:        result.GetPorts(),
+		Location:     result.GetLocation(),
+		Email:        result.GetEmail(),
+		Robots:       result.GetRobots(),
+		RemoteIP:     result.GetProtocol().RemoteIP,
+		IPFamily:     result.GetProtocol().IPFamily,
+		TLS:          result.GetProtocol().TLSValidation,
+		Certificate:  result.GetProtocol().Certificate,
+		Stack:        result.GetStack(),
+		Detections:   result.GetDetections(),
+	}
+	return printJSON(output)
+}
+
+// runScanFile implements the scan-file subcommand
+func runScanFile(args []string) error {
+	flags := flag.NewFlagSet("scan-file", flag.ExitOnError)
+	workers := flags.Int("workers", 16, "Number of URLs scanned at a time")
+	outputPath := flags.String("output", "", "JSONL file the results are appended to (empty for stdout)")
+	checkpointPath := flags.String("checkpoint", "", "File of the completed URLs, to resume from (defaults to the output file with .checkpoint appended)")
+	timeout := flags.Duration("timeout", 30*time.Second, "Maximum duration of the scan of each URL")
+	profileName := flags.String("profile", "standard", "Scan profile: \"fast\" (page only), \"standard\" or \"deep\" (adds error page and header order probes)")
+	rate := flags.Float64("rate", 0, "Maximum requests per second to all hosts (0 for unlimited)")
+	hostRate := flags.Float64("host-rate", 0, "Maximum requests per second to a single host (0 for unlimited)")
+	geoipPaths := flags.String("geoip", "", "Comma separated MaxMind DB files to locate the address of each URL in, e.g. GeoLite2 Country and ASN")
+	contactURL := flags.String("contact-url", "", "Page about the scans, pointed to by an identifying Kitsune User-Agent sent instead of a browser's")
+	polite := flags.Bool("polite", false, "Skip the URLs whose robots.txt disallows them to the User-Agent")
+	flags.Parse(args)
+
+	if flags.NArg() != 1 {
+		return fmt.Errorf("scan-file expects exactly one file of URLs, or - for stdin")
+	}
+	profile, err := profiler.ParseProfile(*profileName)
+	if err != nil {
+		return err
+	}
+
+	targets := os.Stdin
+	if flags.Arg(0) != "-" {
+		if targets, err = os.Open(flags.Arg(0)); err != nil {
+			return err
+		}
+		defer targets.Close()
+	}
+
+	output := os.Stdout
+	if *outputPath != "" {
+		if output, err = os.OpenFile(*outputPath, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o644); err != nil {
+			return err
+		}
+		defer output.Close()
+		if *checkpointPath == "" {
+			*checkpointPath = *outputPath + ".checkpoint"
+		}
+	}
+
+	var checkpoint *bulk.Checkpoint
+	if *checkpointPath != "" {
+		if checkpoint, err = bulk.OpenCheckpoint(*checkpointPath); err != nil {
+			return err
+		}
+		defer checkpoint.Close()
+	}
+
+	options := []profiler.Option{profiler.WithSchemeFallback(true), profiler.WithProfile(profile)}
+	if *rate > 0 || *hostRate > 0 {
+		options = append(options, profiler.WithRateLimit(profiler.RateLimit{PerSecond: *rate, PerHostPerSecond: *hostRate}))
+	}
+	options = append(options, scanIdentity(*contactURL, *polite)...)
+	if *geoipPaths != "" {
+		db, err := geoip.Open(strings.Split(*geoipPaths, ",")...)
+		if err != nil {
+			return err
+		}
+		defer db.Close()
+		options = append(options, profiler.WithGeoIP(db))
+	}
+	engine, err := profiler.New(options...)
+	if err != nil {
+		return fmt.Errorf("could not initialize profiler engine: %w", err)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	summary, err := bulk.New(engine, *workers, *timeout).Run(ctx, targets, output, checkpoint)
+	log.Printf("Scanned %d URLs (%d failed), skipped %d already completed", summary.Scanned, summary.Failed, summary.Skipped)
+	if err != nil && ctx.Err() != nil {
+		return fmt.Errorf("interrupted, rerun the same command to resume")
+	}
+	return err
+}
+
+// scanIdentity returns the options of the --contact-url and --polite flags
+func scanIdentity(contactURL string, polite bool) []profiler.Option {
+	var options []profiler.Option
+	if contactURL != "" {
+		options = append(options, profiler.WithUserAgent(profiler.ScannerUserAgent(contactURL)))
+	}
+	if polite {
+		options = append(options, profiler.WithPoliteMode(true))
+	}
+	return options
+}
+
+// runDiscover implements the discover subcommand
+func runDiscover(args []string) error {
+	flags := flag.NewFlagSet("discover", flag.ExitOnError)
+	scan := flags.Int("scan", 0, "Number of the discovered hosts to fingerprint, the domain and its www subdomain first (0 to only list them)")
+	workers := flags.Int("workers", 8, "Number of hosts scanned at a time")
+	timeout := flags.Duration("timeout", 30*time.Second, "Maximum duration of the scan of each host")
+	ctTimeout := flags.Duration("ct-timeout", time.Minute, "Maximum duration of the Certificate Transparency lookup")
+	ctURL := flags.String("ct-url", ctlog.DefaultBaseURL, "crt.sh instance to look the certificates up in")
+	profileName := flags.String("profile", "standard", "Scan profile: \"fast\" (page only), \"standard\" or \"deep\" (adds error page and header order probes)")
+	flags.Parse(args)
+
+	if flags.NArg() != 1 {
+		return fmt.Errorf("discover expects exactly one domain or URL")
+	}
+	profile, err := profiler.ParseProfile(*profileName)
+	if err != nil {
+		return err
+	}
+	domain, err := ctlog.Domain(flags.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	lookupCtx, cancel := context.WithTimeout(ctx, *ctTimeout)
+	defer cancel()
+	hosts, err := ctlog.New(*ctURL, *ctTimeout).Hosts(lookupCtx, domain)
+	if err != nil {
+		return err
+	}
+
+	// Without scanning, list the hosts so they can be piped to scan-file
+	if *scan <= 0 {
+		for _, host := range hosts {
+			fmt.Println(host)
+		}
+		return nil
+	}
+
+	sample := ctlog.Sample(domain, hosts, *scan)
+	log.Printf("Discovered %d hosts of %s, scanning %d", len(hosts), domain, len(sample))
+
+	engine, err := profiler.New(profiler.WithSchemeFallback(true), profiler.WithProfile(profile))
+	if err != nil {
+		return fmt.Errorf("could not initialize profiler engine: %w", err)
+	}
+	summary, err := bulk.New(engine, *workers, *timeout).Run(ctx, strings.NewReader(strings.Join(sample, "\n")), os.Stdout, nil)
+	log.Printf("Scanned %d hosts (%d failed)", summary.Scanned, summary.Failed)
+	return err
+}
+
+// runDiff implements the diff subcommand
+func runDiff(args []string) error {
+	flags := flag.NewFlagSet("diff", flag.ExitOnError)
+	historyPath := flags.String("history", defaultHistoryPath, "SQLite database the scans were recorded in")
+	asJSON := flags.Bool("json", false, "Print the diff as JSON")
+	flags.Parse(args)
+
+	if flags.NArg() != 1 {
+		return fmt.Errorf("diff expects exactly one URL")
+	}
+	targetURL := flags.Arg(0)
+
+	store, err := history.OpenSQLite(*historyPath)
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	diff, ok, err := history.DiffLatest(context.Background(), store, targetURL)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return fmt.Errorf("fewer than two scans of %s recorded in %s", targetURL, *historyPath)
+	}
+
+	if *asJSON {
+		return printJSON(diff)
+	}
+
+	if diff.Empty() {
+		fmt.Println("No changes")
+		return nil
+	}
+	fmt.Println(monitor.FormatChange(monitor.Change{URL: targetURL, Diff: diff}))
+	return nil
+}
+
+// runMonitor implements the monitor subcommand
+func runMonitor(args []string) error {
+	flags := flag.NewFlagSet("monitor", flag.ExitOnError)
+	historyPath := flags.String("history", defaultHistoryPath, "SQLite database to record scans in")
+	interval := flags.Duration("interval", time.Hour, "Interval between rescans")
+	once := flags.Bool("once", false, "Check every URL once and exit")
+	var webhooks, slackWebhooks stringList
+	flags.Var(&webhooks, "webhook", "URL to POST change JSON documents to (repeatable)")
+	flags.Var(&slackWebhooks, "slack-webhook", "Slack-compatible incoming webhook URL (repeatable)")
+	webhookSecret := flags.String("webhook-secret", "", "Key of the HMAC-SHA256 signature of the --webhook posts, sent in the X-Kitsune-Signature header")
+	webhookAttempts := flags.Int("webhook-attempts", webhook.DefaultRetryPolicy().MaxAttempts, "Attempts per notification, with exponential backoff between them")
+	patternReport := flags.String("pattern-report", "", "File to write the fingerprint patterns ranked by CPU time to on exit")
+	flags.Parse(args)
+
+	if flags.NArg() == 0 {
+		return fmt.Errorf("monitor expects at least one URL")
+	}
+
+	// Pages that carry an ETag or Last-Modified header are revalidated on each
+	// rescan, and only analyzed again when they changed
+	options := []profiler.Option{
+		profiler.WithSchemeFallback(true),
+		profiler.WithResultCache(profiler.NewLRUCache(flags.NArg()), 0, 2**interval),
+	}
+	if *patternReport != "" {
+		patterns := profiler.NewPatternProfiler()
+		options = append(options, profiler.WithPatternProfiler(patterns))
+		defer func() {
+			if err := writePatternReport(*patternReport, patterns); err != nil {
+				log.Printf("Could not write pattern report: %v", err)
+			}
+		}()
+	}
+
+	engine, err := profiler.New(options...)
+	if err != nil {
+		return fmt.Errorf("could not initialize profiler engine: %w", err)
+	}
+
+	store, err := history.OpenSQLite(*historyPath)
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	notifiers := []monitor.Notifier{}
+	for _, url := range webhooks {
+		notifier := monitor.NewWebhookNotifier(url)
+		notifier.Secret = *webhookSecret
+		notifier.Retry.MaxAttempts = *webhookAttempts
+		notifiers = append(notifiers, notifier)
+	}
+	for _, url := range slackWebhooks {
+		notifier := monitor.NewSlackNotifier(url)
+		notifier.Retry.MaxAttempts = *webhookAttempts
+		notifiers = append(notifiers, notifier)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	m := monitor.New(engine, store, flags.Args(), *interval, notifiers...)
+	if *once {
+		for _, change := range m.CheckOnce(ctx) {
+			fmt.Println(monitor.FormatChange(change))
+		}
+		return nil
+	}
+
+	log.Printf("Monitoring %d URLs every %s", flags.NArg(), *interval)
+	if err := m.Run(ctx); err != nil && ctx.Err() == nil {
+		return err
+	}
+	return nil
+}
+
+// writePatternReport writes the report of patterns to path
+func writePatternReport(path string, patterns *profiler.PatternProfiler) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := patterns.WriteReport(f, 0); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// readFixture reads a fixture recorded with scan --record
+func readFixture(path string) (*profiler.Fixture, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var fixture profiler.Fixture
+	if err := json.Unmarshal(data, &fixture); err != nil {
+		return nil, fmt.Errorf("invalid fixture %s: %w", path, err)
+	}
+	return &fixture, nil
+}
+
+// writeFixture writes fixture to path as JSON
+func writeFixture(path string, fixture *profiler.Fixture) error {
+	data, err := json.Marshal(fixture)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0o644)
+}
+
+// writeTrace writes the trace of a scan to path as indented JSON
+func writeTrace(path string, trace *profiler.Trace) error {
+	data, err := json.MarshalIndent(trace, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0o644)
+}
+
+// stringList is a flag.Value collecting repeated string flags
+type stringList []string
+
+func (l *stringList) String() string {
+	return strings.Join(*l, ",")
+}
+
+func (l *stringList) Set(value string) error {
+	*l = append(*l, value)
+	return nil
+}
+
+// runLint implements the lint subcommand
+func runLint(args []string) error {
+	flags := flag.NewFlagSet("lint", flag.ExitOnError)
+	asJSON := flags.Bool("json", false, "Print the issues of each file as JSON")
+	flags.Parse(args)
+
+	if flags.NArg() == 0 {
+		return fmt.Errorf("lint expects at least one fingerprints file")
+	}
+
+	errorCount := 0
+	report := make(map[string][]profiler.FingerprintIssue, flags.NArg())
+	for _, path := range flags.Args() {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		issues := profiler.ValidateFingerprints(data)
+		report[path] = issues
+		for _, issue := range issues {
+			if !issue.Warning {
+				errorCount++
+			}
+			if !*asJSON {
+				fmt.Printf("%s: %s\n", path, issue)
+			}
+		}
+	}
+
+	if *asJSON {
+		if err := printJSON(report); err != nil {
+			return err
+		}
+	}
+	if errorCount > 0 {
+		return fmt.Errorf("%d errors found", errorCount)
+	}
+	return nil
+}
+
+// printJSON writes v to stdout as indented JSON
+func printJSON(v interface{}) error {
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(v)
+}
@@ -2,6 +2,19 @@
 // It downloads the latest Wappalyzer XPI from Mozilla Add-ons, extracts the technology fingerprints,
 // and converts them to a clean, structured format that can be embedded in the kitsune library.
 //
+// Other sources can be given with --source, as the XPI lags behind and may disappear: a GitHub repository
+// such as enthec/webappanalyzer, a local directory of technology JSON files, or any Git URL, including
+// private ones. Sources are merged in the order they are given. For a technology an earlier source
+// already defined, a first-wins source (the default) keeps the earlier definition and an override
+// source replaces it, as in --source xpi --source github:enthec/webappanalyzer --source override:dir:local. An
+// extend source keeps the earlier definition and adds the fields only it defines.
+//
+// The technologies kitsune maintains itself, in assets/technologies, are merged after every source with the
+// extend policy, so that they are not lost when the data is regenerated. Add rules there rather than editing
+// the generated data.
+// --input reads a local XPI or a directory of technologies/*.json instead, so the pipeline can run without
+// internet access and against pinned upstream snapshots.
+//
 // The goal is MAXIMUM FIDELITY to the original fingerprint patterns. This tool performs only minimal,
 // targeted cleaning to ensure the data has a consistent structure without modifying the actual patterns.
 // Specifically:
@@ -10,81 +23,87 @@
 // 3. It converts fields to consistent types (strings, arrays, maps) based on their content
 // 4. It sorts arrays for consistent output and git diffs
 //
-// Usage: go run main.go [--fingerprints output_path]
+// Patterns with constructs that are slow to match, such as nested quantifiers, are then linted:
+// rewritten with bounded quantifiers where possible, and dropped otherwise.
+//
+// Finally, a coverage report compares the output with the source data: which vectors of each technology
+// survived, how many patterns normalization and lint dropped, and which technologies were left with no
+// usable pattern, so that detection regressions show up when reviewing an update. The new data must
+// still detect the expected technologies of the saved sites of the golden corpus, or it is not written.
+//
+// The diff subcommand compares two versions of the fingerprints, such as the committed one and a newly generated
+// one, and prints a Markdown changelog of the technologies added, removed and changed for the data refresh.
+//
+// The eol subcommand refreshes the embedded endoflife.date snapshot the profiler annotates versioned detections
+// with, from the endoflife.date API.
+//
+// Usage: go run main.go [--source [policy:]kind[:location]]... [--input xpi_or_dir]... [--local technologies_dir] [--fingerprints output_path] [--icons icons_dir] [--lint-report report_path] [--coverage-report report_path] [--corpus corpus_dir]
+//
+//	go run main.go diff [--output changelog_path] <old_fingerprints> <new_fingerprints>
+//	go run main.go eol [--output eol_snapshot_path]
 package main
 
 import (
 	"archive/zip"
 	"bytes"
+	"cmp"
 	"encoding/json"
 	"flag"
 	"fmt"
 	"io"
+	"io/fs"
 	"log"
 	"net/http"
 	"os"
+	"os/exec"
 	"path/filepath"
 	"reflect"
+	"slices"
 	"sort"
 	"strings"
 	"time"
+
+	"github.com/kavinsood/kitsune/fingerprints"
+	"github.com/kavinsood/kitsune/internal/corpus"
+	"github.com/kavinsood/kitsune/internal/profiler"
 )
 
-var fingerprints = flag.String("fingerprints", "../../fingerprints_data.json", "File to write wappalyzer fingerprints to")
-
-// Technology represents a technology fingerprint from the canonical Wappalyzer XPI
-// This matches the raw, inconsistent structure in the source JSON files
-// Using interface{} for fields that can be strings or arrays in the source data
-type Technology struct {
-	Cats        []int                  `json:"cats,omitempty"`
-	CSS         interface{}            `json:"css,omitempty"`
-	Cookies     map[string]string      `json:"cookies,omitempty"`
-	DOM         interface{}            `json:"dom,omitempty"`
-	JS          map[string]string      `json:"js,omitempty"`
-	Headers     map[string]string      `json:"headers,omitempty"`
-	HTML        interface{}            `json:"html,omitempty"`
-	Scripts     interface{}            `json:"scripts,omitempty"`
-	ScriptSrc   interface{}            `json:"scriptSrc,omitempty"`
-	Meta        map[string]interface{} `json:"meta,omitempty"`
-	DNS         map[string]interface{} `json:"dns,omitempty"`
-	Implies     interface{}            `json:"implies,omitempty"`
-	Description string                 `json:"description,omitempty"`
-	Website     string                 `json:"website,omitempty"`
-	Icon        string                 `json:"icon,omitempty"`
-	CPE         string                 `json:"cpe,omitempty"`
-}
-
-// OutputFingerprints contains a map of fingerprints for tech detection
-// optimized and validated for the tech detection package
-type OutputFingerprints struct {
-	// Apps is organized as <name, fingerprint>
-	Apps map[string]OutputFingerprint `json:"apps"`
-}
-
-// OutputFingerprint is a single piece of information about a tech validated and normalized
-type OutputFingerprint struct {
-	Cats        []int                             `json:"cats,omitempty"`
-	CSS         []string                          `json:"css,omitempty"`
-	DOM         map[string]map[string]interface{} `json:"dom,omitempty"`
-	Cookies     map[string]string                 `json:"cookies,omitempty"`
-	JS          map[string]string                 `json:"js,omitempty"`
-	Headers     map[string]string                 `json:"headers,omitempty"`
-	HTML        []string                          `json:"html,omitempty"`
-	Script      []string                          `json:"scripts,omitempty"`
-	ScriptSrc   []string                          `json:"scriptSrc,omitempty"`
-	Meta        map[string][]string               `json:"meta,omitempty"`
-	DNS         map[string][]string               `json:"dns,omitempty"`
-	Implies     []string                          `json:"implies,omitempty"`
-	Description string                            `json:"description,omitempty"`
-	Website     string                            `json:"website,omitempty"`
-	CPE         string                            `json:"cpe,omitempty"`
-	Icon        string                            `json:"icon,omitempty"`
-}
+var outputPath = flag.String("fingerprints", "../../fingerprints_data.json", "File to write wappalyzer fingerprints to")
+var icons = flag.String("icons", "", "Directory to extract the technology icons to (disabled if empty)")
+var lintReport = flag.String("lint-report", "", "File to write the patterns rewritten or rejected by the linter to (disabled if empty)")
+var coverageReport = flag.String("coverage-report", "", "File to write the per-technology coverage report to, as JSON (disabled if empty)")
+var fetchedAtFlag = flag.String("fetched-at", "", "When the snapshot read from local sources was fetched from upstream, as RFC 3339 (unknown if empty, ignored when a source is downloaded)")
+var corpusDir = flag.String("corpus", "../../testdata/corpus", "Directory of the golden corpus the new data must detect (disabled if empty)")
+var localDir = flag.String("local", "../../assets/technologies", "Directory of kitsune's own technology files, merged after every source with the extend policy (disabled if empty)")
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "diff" {
+		if err := runDiff(os.Args[2:]); err != nil {
+			log.Fatalf("Failed to diff fingerprints: %v", err)
+		}
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "eol" {
+		if err := runEOL(os.Args[2:]); err != nil {
+			log.Fatalf("Failed to refresh the end-of-life snapshot: %v", err)
+		}
+		return
+	}
+
+	flag.Var(&sources, "source", "Source to pull fingerprints from, as [first-wins:|override:|extend:]xpi[:url], github:owner/repo[#ref], dir:path, git:url[#ref] or file:path. Repeat it to merge several sources in order (the Mozilla XPI if none)")
+	flag.Func("input", "Local XPI, or directory of technologies/*.json, to read instead of downloading, like --source file:path", func(path string) error {
+		return sources.Set("file:" + path)
+	})
 	flag.Parse()
 
-	log.Println("Fetching Wappalyzer XPI from Mozilla Add-ons...")
+	if len(sources) == 0 {
+		sources = sourceList{{kind: "xpi", policy: policyFirstWins}}
+	}
+	// The rules kitsune maintains itself are merged last, so that they survive
+	// every update without replacing what the sources define
+	if *localDir != "" {
+		sources = append(sources, fingerprintSource{kind: "dir", location: *localDir, policy: policyExtend, own: true})
+	}
 
 	// Create an HTTP client with timeout
 	client := &http.Client{
@@ -95,104 +114,123 @@ func main() {
 			DisableCompression:  false,
 		},
 	}
-
-	// Set up proper request with headers to avoid being blocked
-	req, err := http.NewRequest("GET", "https://addons.mozilla.org/firefox/downloads/latest/wappalyzer/wappalyzer.xpi", nil)
+	fetchedAt, err := sourcesFetchedAt(sources, *fetchedAtFlag)
 	if err != nil {
-		log.Fatalf("Failed to create request: %v", err)
+		log.Fatalf("Invalid --fetched-at: %v", err)
 	}
 
-	// Set a reasonable User-Agent
-	req.Header.Set("User-Agent", "Mozilla/5.0 (Windows NT 6.3; WOW64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/117.0.5931.0 Safari/537.36")
-	resp, err := client.Do(req)
-	if err != nil {
-		log.Fatalf("Failed to download XPI: %v\nPlease check your internet connection and try again.", err)
-	}
-	defer resp.Body.Close()
+	// Parse technologies from every source, merged in the order they were given
+	masterTechs := make(map[string]fingerprints.Technology)
+	// The raw fields of each technology, to report what normalization dropped
+	sourceTechs := make(map[string]map[string]interface{})
+	var versions []string
+	var iconFiles []sourceFile
+	for _, source := range sources {
+		log.Printf("Fetching fingerprints from %s...", source)
+		data, err := source.load(client)
+		if err != nil {
+			log.Fatalf("Failed to load %s: %v", source, err)
+		}
 
-	if resp.StatusCode != http.StatusOK {
-		log.Fatalf("Failed to download XPI: HTTP %s\nThe Wappalyzer XPI may no longer be available at this URL.", resp.Status)
-	}
+		techs, raw := parseTechnologies(source, data.technologies)
+		added, conflicts := 0, 0
+		for name, tech := range techs {
+			if _, ok := masterTechs[name]; ok {
+				conflicts++
+				switch source.policy {
+				case policyFirstWins:
+					continue
+				case policyExtend:
+					tech, raw[name] = extendTechnology(name, masterTechs[name], sourceTechs[name], raw[name])
+				}
+			} else {
+				added++
+			}
+			masterTechs[name] = tech
+			sourceTechs[name] = raw[name]
+		}
+		log.Printf("Merged %d technologies from %s: %d new, %d conflicting (%s)", len(techs), source, added, conflicts, source.policy)
 
-	log.Printf("XPI download started, server responded with %s", resp.Status)
-	xpiData, err := io.ReadAll(io.LimitReader(resp.Body, 100*1024*1024)) // 100MB limit to prevent DoS
-	if err != nil {
-		log.Fatalf("Failed to read XPI data: %v\nThe download may have been interrupted.", err)
+		versions = append(versions, data.version)
+		iconFiles = append(iconFiles, data.icons...)
 	}
 
-	if len(xpiData) == 0 {
-		log.Fatalf("Downloaded XPI file is empty. Please try again later.")
+	if len(masterTechs) == 0 {
+		log.Fatalf("No technologies found in the sources. The format may have changed or the files might be empty.")
 	}
 
-	log.Printf("Downloaded XPI file successfully (%d bytes)", len(xpiData))
-
-	// Create a zip reader from the XPI data (XPI is just a ZIP file)
-	zipReader, err := zip.NewReader(bytes.NewReader(xpiData), int64(len(xpiData)))
-	if err != nil {
-		log.Fatalf("Failed to create zip reader: %v\nThe downloaded file may be corrupted or not a valid XPI/ZIP file.", err)
+	// Extract the icons, so the API server can serve them without fetching
+	if *icons != "" {
+		count, err := extractIcons(iconFiles, *icons)
+		if err != nil {
+			log.Fatalf("Failed to extract icons: %v", err)
+		}
+		log.Printf("Extracted %d icons to %s", count, *icons)
 	}
 
-	// Parse technologies from the XPI
-	masterTechs := make(map[string]Technology)
-	techFilesFound := 0
-	techFilesProcessed := 0
-
-	// Look for the technology files in the XPI
-	for _, file := range zipReader.File {
-		// The path inside the XPI may vary, look for JSON files in the technologies directory
-		if strings.HasSuffix(file.Name, ".json") && strings.Contains(file.Name, "technologies") {
-			techFilesFound++
-
-			rc, err := file.Open()
-			if err != nil {
-				log.Printf("Warning: Failed to open file in zip %s: %v (skipping)", file.Name, err)
-				continue
-			}
-
-			content, err := io.ReadAll(io.LimitReader(rc, 10*1024*1024)) // 10MB limit per file
-			rc.Close()
-			if err != nil {
-				log.Printf("Warning: Failed to read file in zip %s: %v (skipping)", file.Name, err)
-				continue
-			}
+	// Normalize fingerprints to the format expected by the kitsune library
+	log.Println("Normalizing technology fingerprints...")
+	outputFingerprints := fingerprints.Normalize(masterTechs)
 
-			var currentTechs map[string]Technology
-			if err := json.Unmarshal(content, &currentTechs); err != nil {
-				log.Printf("Warning: Could not unmarshal %s: %v (skipping)", file.Name, err)
-				continue
-			}
+	log.Printf("Normalized %d valid fingerprints", len(outputFingerprints.Apps))
+	normalizedPatterns := outputPatterns(outputFingerprints)
 
-			// Merge into the master map
-			for name, tech := range currentTechs {
-				masterTechs[name] = tech
-			}
-			techFilesProcessed++
+	// Rewrite or drop the patterns that would be slow to match
+	var report strings.Builder
+	linted := fingerprints.Lint(outputFingerprints)
+	for _, result := range linted {
+		log.Printf("Lint: %s", result)
+		fmt.Fprintln(&report, result)
+	}
+	log.Printf("Linted fingerprints, %d patterns rewritten or rejected", len(linted))
+	if *lintReport != "" {
+		if err := os.WriteFile(*lintReport, []byte(report.String()), 0o644); err != nil {
+			log.Fatalf("Failed to write lint report: %v", err)
 		}
 	}
 
-	if techFilesFound == 0 {
-		log.Fatalf("No technology files found in the XPI. The Wappalyzer XPI structure may have changed.")
+	// Report what survived of each technology, as data otherwise disappears silently
+	coverage := newCoverageReport(sourceTechs, normalizedPatterns, outputPatterns(outputFingerprints))
+	logCoverage(coverage)
+	if *coverageReport != "" {
+		data, err := json.MarshalIndent(coverage, "", "    ")
+		if err != nil {
+			log.Fatalf("Could not marshal coverage report: %v", err)
+		}
+		if err := os.WriteFile(*coverageReport, append(data, '\n'), 0o644); err != nil {
+			log.Fatalf("Failed to write coverage report: %v", err)
+		}
 	}
 
-	if techFilesProcessed == 0 {
-		log.Fatalf("Failed to process any technology files from the XPI.")
+	// Stamp the data with its source version and a hash of its content
+	if err := outputFingerprints.Stamp(sourceVersion(sources, versions), fetchedAt); err != nil {
+		log.Fatalf("Could not marshal fingerprints: %v", err)
 	}
+	log.Printf("Stamped fingerprints from source version %q with %s", outputFingerprints.Metadata.SourceVersion, outputFingerprints.Metadata.ContentHash)
 
-	log.Printf("Successfully processed %d/%d technology files containing %d technologies",
-		techFilesProcessed, techFilesFound, len(masterTechs))
-
-	if len(masterTechs) == 0 {
-		log.Fatalf("No technologies found in the XPI. The format may have changed or the files might be empty.")
+	// Sort map keys and pretty print the json to make git diffs useful
+	data, err := json.MarshalIndent(outputFingerprints, "", "    ")
+	if err != nil {
+		log.Fatalf("Could not marshal fingerprints: %v", err)
 	}
 
-	// Normalize fingerprints to the format expected by the kitsune library
-	log.Println("Normalizing technology fingerprints...")
-	outputFingerprints := normalizeFingerprints(masterTechs)
-
-	log.Printf("Normalized %d valid fingerprints", len(outputFingerprints.Apps))
+	// Refuse data that no longer detects the known sites
+	if *corpusDir != "" {
+		regressions, err := runCorpus(*corpusDir, data)
+		if err != nil {
+			log.Fatalf("Failed to run the golden corpus: %v", err)
+		}
+		for _, regression := range regressions {
+			log.Printf("Corpus: %s", regression)
+		}
+		if len(regressions) > 0 {
+			log.Fatalf("Detection regressed on %d sites of the golden corpus, the fingerprints were not written", len(regressions))
+		}
+		log.Printf("Golden corpus of %s still detected", *corpusDir)
+	}
 
 	// Ensure the output directory exists
-	outputDir := filepath.Dir(*fingerprints)
+	outputDir := filepath.Dir(*outputPath)
 	if outputDir != "" && outputDir != "." {
 		if err := os.MkdirAll(outputDir, 0755); err != nil {
 			log.Fatalf("Failed to create output directory %s: %v", outputDir, err)
@@ -200,17 +238,10 @@ func main() {
 	}
 
 	// Write the fingerprints to the output file
-	log.Printf("Writing fingerprints to %s...", *fingerprints)
-	fingerprintsFile, err := os.OpenFile(*fingerprints, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o666)
+	log.Printf("Writing fingerprints to %s...", *outputPath)
+	fingerprintsFile, err := os.OpenFile(*outputPath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o666)
 	if err != nil {
-		log.Fatalf("Could not open fingerprints file %s: %v", *fingerprints, err)
-	}
-
-	// Sort map keys and pretty print the json to make git diffs useful
-	data, err := json.MarshalIndent(outputFingerprints, "", "    ")
-	if err != nil {
-		_ = fingerprintsFile.Close() // Best effort close
-		log.Fatalf("Could not marshal fingerprints: %v", err)
+		log.Fatalf("Could not open fingerprints file %s: %v", *outputPath, err)
 	}
 
 	// Write data and handle potential disk space issues
@@ -232,225 +263,905 @@ func main() {
 	}
 
 	log.Printf("Successfully wrote %d fingerprints to %s (%d bytes)",
-		len(outputFingerprints.Apps), *fingerprints, len(data))
+		len(outputFingerprints.Apps), *outputPath, len(data))
 
 	fmt.Println("✅ Fingerprint update completed successfully.")
 }
 
-func normalizeFingerprints(technologies map[string]Technology) *OutputFingerprints {
-	outputFingerprints := &OutputFingerprints{Apps: make(map[string]OutputFingerprint)}
-
-	for appName, tech := range technologies {
-		output := OutputFingerprint{
-			Cats:        tech.Cats,
-			Cookies:     make(map[string]string),
-			DOM:         make(map[string]map[string]interface{}),
-			Headers:     make(map[string]string),
-			JS:          make(map[string]string),
-			Meta:        make(map[string][]string),
-			DNS:         make(map[string][]string),
-			Description: tech.Description,
-			Website:     tech.Website,
-			CPE:         tech.CPE,
-			Icon:        tech.Icon,
-		}
-
-		// Process cookies
-		// Keys (cookie names) are typically case-insensitive, so we normalize them
-		// Values (patterns) are preserved in their original case for regex accuracy
-		for cookie, value := range tech.Cookies {
-			output.Cookies[strings.ToLower(cookie)] = value
-		}
-
-		// Process JS
-		for k, v := range tech.JS {
-			output.JS[k] = v
-		}
-
-		// Process headers
-		// Header names are case-insensitive by HTTP spec, so we normalize them
-		// Pattern values are preserved in their original case for regex accuracy
-		for header, pattern := range tech.Headers {
-			output.Headers[strings.ToLower(header)] = pattern
-		}
-
-		// Process DOM using reflection
-		if tech.DOM != nil {
-			v := reflect.ValueOf(tech.DOM)
-			switch v.Kind() {
-			case reflect.String:
-				data := v.Interface().(string)
-				output.DOM[data] = map[string]interface{}{"exists": ""}
-			case reflect.Slice:
-				data := v.Interface().([]interface{})
-				for _, pattern := range data {
-					if pat, ok := pattern.(string); ok {
-						output.DOM[pat] = map[string]interface{}{"exists": ""}
-					}
-				}
-			case reflect.Map:
-				data := v.Interface().(map[string]interface{})
-				for pattern, value := range data {
-					if valueMap, ok := value.(map[string]interface{}); ok {
-						output.DOM[pattern] = valueMap
-					}
-				}
-			}
+// Merge policies of the fingerprint sources, for the technologies an earlier
+// source already defined
+const (
+	// policyFirstWins keeps the definition of the earlier source
+	policyFirstWins = "first-wins"
+	// policyOverride replaces it with the definition of the later source
+	policyOverride = "override"
+	// policyExtend keeps the definition of the earlier source and adds the
+	// fields only the later source defines, such as the patterns of a vector
+	policyExtend = "extend"
+)
+
+// extendTechnology adds the raw fields of a later definition of a technology
+// that the earlier one lacks, returning the merged technology and its raw
+// fields. The earlier definition is kept if the merged one does not decode.
+func extendTechnology(name string, tech fingerprints.Technology, earlier, later map[string]interface{}) (fingerprints.Technology, map[string]interface{}) {
+	merged := make(map[string]interface{}, len(earlier)+len(later))
+	for field, value := range later {
+		merged[field] = value
+	}
+	for field, value := range earlier {
+		merged[field] = value
+	}
+
+	data, err := json.Marshal(merged)
+	if err == nil {
+		var extended fingerprints.Technology
+		if err = json.Unmarshal(data, &extended); err == nil {
+			return extended, merged
 		}
+	}
+	log.Printf("Warning: Could not extend %s: %v (keeping the earlier definition)", name, err)
+	return tech, earlier
+}
 
-		// Process HTML using reflection
-		// HTML patterns are regex patterns that should preserve their case for accuracy
-		if tech.HTML != nil {
-			v := reflect.ValueOf(tech.HTML)
-			switch v.Kind() {
-			case reflect.String:
-				output.HTML = []string{v.Interface().(string)}
-			case reflect.Slice:
-				data := v.Interface().([]interface{})
-				output.HTML = make([]string, 0, len(data))
-				for _, pattern := range data {
-					if patStr, ok := pattern.(string); ok {
-						output.HTML = append(output.HTML, patStr)
-					}
-				}
-			}
-			sort.Strings(output.HTML)
-		}
-
-		// Process Scripts using reflection
-		// Script patterns are regex patterns that should preserve their case for accuracy
-		if tech.Scripts != nil {
-			v := reflect.ValueOf(tech.Scripts)
-			switch v.Kind() {
-			case reflect.String:
-				output.Script = []string{v.Interface().(string)}
-			case reflect.Slice:
-				data := v.Interface().([]interface{})
-				output.Script = make([]string, 0, len(data))
-				for _, pattern := range data {
-					if patStr, ok := pattern.(string); ok {
-						output.Script = append(output.Script, patStr)
-					}
-				}
-			}
-			sort.Strings(output.Script)
-		}
-
-		// Process ScriptSrc using reflection
-		// ScriptSrc patterns are regex patterns that should preserve their case for accuracy
-		if tech.ScriptSrc != nil {
-			v := reflect.ValueOf(tech.ScriptSrc)
-			switch v.Kind() {
-			case reflect.String:
-				output.ScriptSrc = []string{v.Interface().(string)}
-			case reflect.Slice:
-				data := v.Interface().([]interface{})
-				output.ScriptSrc = make([]string, 0, len(data))
-				for _, pattern := range data {
-					if patStr, ok := pattern.(string); ok {
-						output.ScriptSrc = append(output.ScriptSrc, patStr)
-					}
-				}
+// defaultXPIURL is where the Wappalyzer XPI is downloaded from
+const defaultXPIURL = "https://addons.mozilla.org/firefox/downloads/latest/wappalyzer/wappalyzer.xpi"
+
+// fingerprintSource is a place technology fingerprints are pulled from
+type fingerprintSource struct {
+	// kind is xpi, github, dir, git or file
+	kind     string
+	location string
+	policy   string
+	// own is set on the technologies kitsune maintains, which are versioned
+	// with the repository and left out of the stamp
+	own bool
+}
+
+// isLocal reports whether the source is read from the local filesystem
+// rather than downloaded or cloned
+func (s fingerprintSource) isLocal() bool {
+	return s.kind == "dir" || s.kind == "file"
+}
+
+func (s fingerprintSource) String() string {
+	if s.location == "" {
+		return s.kind
+	}
+	return s.kind + ":" + s.location
+}
+
+// sourceList is the value of the repeatable --source flag
+type sourceList []fingerprintSource
+
+var sources sourceList
+
+func (l *sourceList) String() string {
+	specs := make([]string, 0, len(*l))
+	for _, source := range *l {
+		specs = append(specs, source.policy+":"+source.String())
+	}
+	return strings.Join(specs, ",")
+}
+
+// Set parses a source as [policy:]kind[:location]
+func (l *sourceList) Set(spec string) error {
+	source := fingerprintSource{policy: policyFirstWins}
+	for _, policy := range []string{policyFirstWins, policyOverride, policyExtend} {
+		if rest, ok := strings.CutPrefix(spec, policy+":"); ok {
+			source.policy, spec = policy, rest
+			break
+		}
+	}
+	source.kind, source.location, _ = strings.Cut(spec, ":")
+
+	switch source.kind {
+	case "xpi":
+	case "github", "dir", "git", "file":
+		if source.location == "" {
+			return fmt.Errorf("%s source needs a location", source.kind)
+		}
+	default:
+		return fmt.Errorf("unknown source kind %q, expected xpi, github, dir, git or file", source.kind)
+	}
+	*l = append(*l, source)
+	return nil
+}
+
+// sourceFile is a file pulled from a fingerprint source
+type sourceFile struct {
+	name    string
+	content []byte
+}
+
+// sourceData is what was pulled from a fingerprint source
+type sourceData struct {
+	// version identifies the data of the source, such as the XPI version or a commit
+	version      string
+	technologies []sourceFile
+	icons        []sourceFile
+}
+
+// load pulls the technology files of the source, and its icons if they are extracted
+func (s fingerprintSource) load(client *http.Client) (*sourceData, error) {
+	switch s.kind {
+	case "xpi":
+		content, err := download(client, cmp.Or(s.location, defaultXPIURL))
+		if err != nil {
+			return nil, err
+		}
+		zipReader, err := zip.NewReader(bytes.NewReader(content), int64(len(content)))
+		if err != nil {
+			return nil, fmt.Errorf("the downloaded file is not a valid XPI/ZIP file: %w", err)
+		}
+		data, err := readZip(zipReader)
+		if err != nil {
+			return nil, err
+		}
+		data.version = xpiVersion(zipReader)
+		return data, nil
+
+	case "github":
+		// The archive of a GitHub repository has the commit it was made from as comment
+		repo, ref, _ := strings.Cut(s.location, "#")
+		content, err := download(client, fmt.Sprintf("https://github.com/%s/archive/%s.zip", repo, cmp.Or(ref, "HEAD")))
+		if err != nil {
+			return nil, err
+		}
+		zipReader, err := zip.NewReader(bytes.NewReader(content), int64(len(content)))
+		if err != nil {
+			return nil, fmt.Errorf("the downloaded file is not a valid ZIP file: %w", err)
+		}
+		data, err := readZip(zipReader)
+		if err != nil {
+			return nil, err
+		}
+		data.version = cmp.Or(zipReader.Comment, ref)
+		return data, nil
+
+	case "dir":
+		return readDir(s.location)
+
+	case "git":
+		return loadGit(s.location)
+
+	case "file":
+		return loadFile(s.location)
+	}
+	return nil, fmt.Errorf("unknown source kind %q", s.kind)
+}
+
+// download fetches a source archive
+func download(client *http.Client, url string) ([]byte, error) {
+	// Set up proper request with headers to avoid being blocked
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	// Set a reasonable User-Agent
+	req.Header.Set("User-Agent", "Mozilla/5.0 (Windows NT 6.3; WOW64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/117.0.5931.0 Safari/537.36")
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("could not download %s: %w", url, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("could not download %s: HTTP %s", url, resp.Status)
+	}
+
+	content, err := io.ReadAll(io.LimitReader(resp.Body, 100*1024*1024)) // 100MB limit to prevent DoS
+	if err != nil {
+		return nil, fmt.Errorf("the download of %s was interrupted: %w", url, err)
+	}
+	if len(content) == 0 {
+		return nil, fmt.Errorf("the download of %s is empty", url)
+	}
+	log.Printf("Downloaded %s (%d bytes)", url, len(content))
+	return content, nil
+}
+
+// loadGit clones a Git repository, with the credentials of the git command, and
+// reads its technology files and icons
+func loadGit(location string) (*sourceData, error) {
+	repo, ref, _ := strings.Cut(location, "#")
+	dir, err := os.MkdirTemp("", "fingerprints-git-*")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+
+	args := []string{"clone", "--quiet", "--depth", "1"}
+	if ref != "" {
+		args = append(args, "--branch", ref)
+	}
+	clone := exec.Command("git", append(args, repo, dir)...)
+	clone.Stderr = os.Stderr
+	if err := clone.Run(); err != nil {
+		return nil, fmt.Errorf("could not clone %s: %w", repo, err)
+	}
+	commit, err := exec.Command("git", "-C", dir, "rev-parse", "HEAD").Output()
+	if err != nil {
+		return nil, fmt.Errorf("could not read the commit of %s: %w", repo, err)
+	}
+
+	data, err := readTree(dir)
+	if err != nil {
+		return nil, err
+	}
+	data.version = strings.TrimSpace(string(commit))
+	return data, nil
+}
+
+// readTree reads the technology files and icons of a directory tree, such as a
+// clone of a repository or an extracted XPI
+func readTree(dir string) (*sourceData, error) {
+	data := &sourceData{}
+	err := filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if entry.IsDir() {
+			if entry.Name() == ".git" {
+				return filepath.SkipDir
 			}
-			sort.Strings(output.ScriptSrc)
-		}
-
-		// Process Meta using reflection
-		// Meta tag names are normalized, but pattern values preserve case for regex accuracy
-		for header, pattern := range tech.Meta {
-			v := reflect.ValueOf(pattern)
-			switch v.Kind() {
-			case reflect.String:
-				data := v.Interface().(string)
-				if data == "" {
-					output.Meta[strings.ToLower(header)] = []string{}
-				} else {
-					output.Meta[strings.ToLower(header)] = []string{data}
-				}
-			case reflect.Slice:
-				if data, ok := v.Interface().([]interface{}); ok {
-					final := make([]string, 0, len(data))
-					for _, pattern := range data {
-						if patStr, ok := pattern.(string); ok {
-							final = append(final, patStr)
-						}
-					}
-					sort.Strings(final)
-					output.Meta[strings.ToLower(header)] = final
-				}
+			return nil
+		}
+		name := filepath.ToSlash(strings.TrimPrefix(path, dir))
+		technologies, icon := isSourceFile(name)
+		if !technologies && !(icon && *icons != "") {
+			return nil
+		}
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		if technologies {
+			data.technologies = append(data.technologies, sourceFile{name: name, content: content})
+		} else {
+			data.icons = append(data.icons, sourceFile{name: name, content: content})
+		}
+		return nil
+	})
+	return data, err
+}
+
+// loadFile reads a local XPI, or a directory with the technologies/*.json files
+// of an extracted XPI or a checkout, or with the technology files themselves
+func loadFile(path string) (*sourceData, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if info.IsDir() {
+		data, err := readTree(path)
+		if err != nil || len(data.technologies) > 0 {
+			return data, err
+		}
+		return readDir(path)
+	}
+
+	zipReader, err := zip.OpenReader(path)
+	if err != nil {
+		return nil, fmt.Errorf("%s is not a valid XPI/ZIP file: %w", path, err)
+	}
+	defer zipReader.Close()
+	data, err := readZip(&zipReader.Reader)
+	if err != nil {
+		return nil, err
+	}
+	data.version = xpiVersion(&zipReader.Reader)
+	return data, nil
+}
+
+// readDir reads every JSON file of a directory as a technology file
+func readDir(dir string) (*sourceData, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	data := &sourceData{}
+	for _, path := range paths {
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		data.technologies = append(data.technologies, sourceFile{name: path, content: content})
+	}
+	return data, nil
+}
+
+// readZip reads the technology files and icons of an XPI or repository archive
+func readZip(zipReader *zip.Reader) (*sourceData, error) {
+	data := &sourceData{}
+	for _, file := range zipReader.File {
+		technologies, icon := isSourceFile(file.Name)
+		if file.FileInfo().IsDir() || !technologies && !(icon && *icons != "") {
+			continue
+		}
+
+		rc, err := file.Open()
+		if err != nil {
+			return nil, fmt.Errorf("could not open %s: %w", file.Name, err)
+		}
+		content, err := io.ReadAll(io.LimitReader(rc, 10*1024*1024)) // 10MB limit per file
+		rc.Close()
+		if err != nil {
+			return nil, fmt.Errorf("could not read %s: %w", file.Name, err)
+		}
+
+		if technologies {
+			data.technologies = append(data.technologies, sourceFile{name: file.Name, content: content})
+		} else {
+			data.icons = append(data.icons, sourceFile{name: file.Name, content: content})
+		}
+	}
+	return data, nil
+}
+
+// isSourceFile reports whether a file of an archive or repository is a
+// technology file or an icon. The layout may vary, so technology files are the
+// JSON files in a technologies directory and icons the files in images/icons.
+func isSourceFile(name string) (technologies, icon bool) {
+	technologies = strings.HasSuffix(name, ".json") && strings.Contains(name, "technologies")
+	icon = strings.Contains(name, "images/icons/")
+	return technologies, icon
+}
+
+// parseTechnologies parses the technology files of a source, sorted by name so
+// the merge is deterministic, returning the technologies and their raw fields.
+// Files that do not parse are skipped, as a single broken file should not fail
+// the whole update.
+func parseTechnologies(source fingerprintSource, files []sourceFile) (map[string]fingerprints.Technology, map[string]map[string]interface{}) {
+	if len(files) == 0 {
+		log.Fatalf("No technology files found in %s. Its structure may have changed.", source)
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].name < files[j].name })
+
+	techs := make(map[string]fingerprints.Technology)
+	raw := make(map[string]map[string]interface{})
+	processed := 0
+	for _, file := range files {
+		var currentTechs map[string]fingerprints.Technology
+		if err := json.Unmarshal(file.content, &currentTechs); err != nil {
+			log.Printf("Warning: Could not unmarshal %s: %v (skipping)", file.name, err)
+			continue
+		}
+
+		var currentSource map[string]map[string]interface{}
+		if err := json.Unmarshal(file.content, &currentSource); err != nil {
+			log.Printf("Warning: Could not unmarshal %s: %v (skipping)", file.name, err)
+			continue
+		}
+
+		for name, tech := range currentTechs {
+			techs[name] = tech
+			raw[name] = currentSource[name]
+		}
+		processed++
+	}
+
+	if processed == 0 {
+		log.Fatalf("Failed to process any technology files from %s.", source)
+	}
+	log.Printf("Successfully processed %d/%d technology files of %s containing %d technologies",
+		processed, len(files), source, len(techs))
+	return techs, raw
+}
+
+// sourceVersion stamps the data with the version of its source, or with the
+// version of each source when several were merged. Local sources are named by
+// their kind, as their path only means something on the machine they were
+// read on.
+func sourceVersion(sources sourceList, versions []string) string {
+	var upstream sourceList
+	var upstreamVersions []string
+	for i, source := range sources {
+		if !source.own {
+			upstream = append(upstream, source)
+			upstreamVersions = append(upstreamVersions, versions[i])
+		}
+	}
+	if len(upstream) == 1 {
+		return upstreamVersions[0]
+	}
+	stamps := make([]string, 0, len(upstream))
+	for i, source := range upstream {
+		name := source.String()
+		if source.isLocal() {
+			name = source.kind
+		}
+		stamps = append(stamps, strings.TrimSpace(name+" "+upstreamVersions[i]))
+	}
+	return strings.Join(stamps, ", ")
+}
+
+// sourcesFetchedAt returns when the sources were fetched: now when one of
+// them is downloaded, and otherwise the time given for the local snapshots,
+// if any. Stamping a rebuild from a snapshot with the current time would
+// hide how stale its data is.
+func sourcesFetchedAt(sources sourceList, given string) (time.Time, error) {
+	for _, source := range sources {
+		if !source.isLocal() {
+			return time.Now().UTC(), nil
+		}
+	}
+	if given == "" {
+		return time.Time{}, nil
+	}
+	fetchedAt, err := time.Parse(time.RFC3339, given)
+	return fetchedAt.UTC(), err
+}
+
+// xpiVersion returns the version of the XPI from its manifest, or an empty string
+func xpiVersion(zipReader *zip.Reader) string {
+	for _, file := range zipReader.File {
+		if file.Name != "manifest.json" {
+			continue
+		}
+
+		rc, err := file.Open()
+		if err != nil {
+			log.Printf("Warning: Failed to open XPI manifest: %v", err)
+			return ""
+		}
+		defer rc.Close()
+
+		var manifest struct {
+			Version string `json:"version"`
+		}
+		if err := json.NewDecoder(io.LimitReader(rc, 1024*1024)).Decode(&manifest); err != nil {
+			log.Printf("Warning: Failed to parse XPI manifest: %v", err)
+			return ""
+		}
+		return manifest.Version
+	}
+	log.Printf("Warning: No manifest found in the XPI, the data will not be stamped with a version")
+	return ""
+}
+
+// extractIcons writes the technology icons of the sources to dir, returning how
+// many were written. Icons of later sources replace those of earlier ones.
+func extractIcons(iconFiles []sourceFile, dir string) (int, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return 0, err
+	}
+
+	count := 0
+	for _, file := range iconFiles {
+		// Only keep the base name, so entries cannot escape the directory
+		if err := os.WriteFile(filepath.Join(dir, filepath.Base(file.name)), file.content, 0o644); err != nil {
+			return count, err
+		}
+		count++
+	}
+	return count, nil
+}
+
+// runCorpus runs the golden corpus of dir against the fingerprints data,
+// returning the sites whose detection regressed
+func runCorpus(dir string, data []byte) ([]corpus.Regression, error) {
+	sites, err := corpus.Load(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	// The fingerprints are loaded from a file, as the library would load them
+	file, err := os.CreateTemp("", "fingerprints-*.json")
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(file.Name())
+	if _, err := file.Write(data); err != nil {
+		_ = file.Close()
+		return nil, err
+	}
+	if err := file.Close(); err != nil {
+		return nil, err
+	}
+
+	wappalyzer, err := profiler.NewFromFile(file.Name(), false, false)
+	if err != nil {
+		return nil, err
+	}
+	return corpus.Run(wappalyzer, sites), nil
+}
+
+// patternVectors are the fields of the source data that hold patterns, whether
+// or not the output keeps them
+var patternVectors = []string{
+	"certIssuer", "cookies", "css", "dns", "dom", "headers", "html", "js", "meta",
+	"probe", "robots", "scriptSrc", "scripts", "text", "url", "xhr",
+}
+
+// CoverageReport describes what normalization and lint kept of the source data
+type CoverageReport struct {
+	Technologies int `json:"technologies"`
+	// SourcePatterns is the number of patterns in the source data, and Patterns
+	// the number left in the output
+	SourcePatterns         int `json:"source_patterns"`
+	Patterns               int `json:"patterns"`
+	DroppedByNormalization int `json:"dropped_by_normalization"`
+	DroppedByLint          int `json:"dropped_by_lint"`
+	// DroppedVectors counts, per vector, the technologies that lost all of its patterns
+	DroppedVectors map[string]int `json:"dropped_vectors"`
+	// WithoutPatterns are the technologies with no usable pattern, which can
+	// only be detected when implied by another one
+	WithoutPatterns []string                      `json:"without_patterns"`
+	Apps            map[string]TechnologyCoverage `json:"apps"`
+}
+
+// TechnologyCoverage describes what normalization and lint kept of a technology
+type TechnologyCoverage struct {
+	// Vectors are the vectors with patterns in the output
+	Vectors []string `json:"vectors"`
+	// DroppedVectors are the vectors with patterns in the source but not in the output
+	DroppedVectors         []string `json:"dropped_vectors,omitempty"`
+	SourcePatterns         int      `json:"source_patterns"`
+	Patterns               int      `json:"patterns"`
+	DroppedByNormalization int      `json:"dropped_by_normalization,omitempty"`
+	DroppedByLint          int      `json:"dropped_by_lint,omitempty"`
+}
+
+// newCoverageReport compares the patterns of the source technologies with the
+// patterns of each vector after normalization and after lint
+func newCoverageReport(source map[string]map[string]interface{}, normalized, linted map[string]map[string]int) *CoverageReport {
+	report := &CoverageReport{
+		Technologies:    len(source),
+		DroppedVectors:  make(map[string]int),
+		WithoutPatterns: []string{},
+		Apps:            make(map[string]TechnologyCoverage, len(source)),
+	}
+
+	for app, fields := range source {
+		coverage := TechnologyCoverage{Vectors: []string{}}
+		sourceVectors := fieldPatterns(fields)
+		for _, vector := range patternVectors {
+			sourceCount, normalizedCount, count := sourceVectors[vector], normalized[app][vector], linted[app][vector]
+			coverage.SourcePatterns += sourceCount
+			coverage.Patterns += count
+			coverage.DroppedByNormalization += max(sourceCount-normalizedCount, 0)
+			coverage.DroppedByLint += max(normalizedCount-count, 0)
+			if count > 0 {
+				coverage.Vectors = append(coverage.Vectors, vector)
+			} else if sourceCount > 0 {
+				coverage.DroppedVectors = append(coverage.DroppedVectors, vector)
+				report.DroppedVectors[vector]++
 			}
 		}
 
-		// Process Implies using reflection
-		if tech.Implies != nil {
-			v := reflect.ValueOf(tech.Implies)
-			switch v.Kind() {
-			case reflect.String:
-				output.Implies = []string{v.Interface().(string)}
-			case reflect.Slice:
-				data := v.Interface().([]interface{})
-				output.Implies = make([]string, 0, len(data))
-				for _, pattern := range data {
-					if patStr, ok := pattern.(string); ok {
-						output.Implies = append(output.Implies, patStr)
-					}
+		report.SourcePatterns += coverage.SourcePatterns
+		report.Patterns += coverage.Patterns
+		report.DroppedByNormalization += coverage.DroppedByNormalization
+		report.DroppedByLint += coverage.DroppedByLint
+		if coverage.Patterns == 0 {
+			report.WithoutPatterns = append(report.WithoutPatterns, app)
+		}
+		report.Apps[app] = coverage
+	}
+	sort.Strings(report.WithoutPatterns)
+	return report
+}
+
+// logCoverage logs the summary of a coverage report, naming the technologies
+// that lost all of their patterns
+func logCoverage(report *CoverageReport) {
+	log.Printf("Coverage: %d/%d patterns kept, %d dropped by normalization, %d by lint",
+		report.Patterns, report.SourcePatterns, report.DroppedByNormalization, report.DroppedByLint)
+
+	vectors := make([]string, 0, len(report.DroppedVectors))
+	for vector := range report.DroppedVectors {
+		vectors = append(vectors, vector)
+	}
+	sort.Strings(vectors)
+	for _, vector := range vectors {
+		log.Printf("Coverage: %s patterns dropped from %d technologies", vector, report.DroppedVectors[vector])
+	}
+
+	lost := 0
+	for _, app := range report.WithoutPatterns {
+		if report.Apps[app].SourcePatterns > 0 {
+			log.Printf("Coverage: %s has no usable pattern left", app)
+			lost++
+		}
+	}
+	log.Printf("Coverage: %d technologies without usable patterns, %d of which lost all of theirs",
+		len(report.WithoutPatterns), lost)
+}
+
+// outputPatterns counts the patterns of each vector of the output fingerprints
+func outputPatterns(output *fingerprints.Fingerprints) map[string]map[string]int {
+	counts := make(map[string]map[string]int, len(output.Apps))
+	for app, fingerprint := range output.Apps {
+		// Count the fields as they are written, to compare them with the source
+		var fields map[string]interface{}
+		data, err := json.Marshal(fingerprint)
+		if err == nil {
+			err = json.Unmarshal(data, &fields)
+		}
+		if err != nil {
+			log.Fatalf("Could not count the patterns of %s: %v", app, err)
+		}
+		counts[app] = fieldPatterns(fields)
+	}
+	return counts
+}
+
+// fieldPatterns counts the patterns of each vector of a technology's fields
+func fieldPatterns(fields map[string]interface{}) map[string]int {
+	counts := make(map[string]int)
+	for _, vector := range patternVectors {
+		if value, ok := fields[vector]; ok {
+			counts[vector] = countPatterns(value, false)
+		}
+	}
+	return counts
+}
+
+// countPatterns counts the strings of a field. Patterns of any other type are
+// not usable. An empty list keyed by name, such as a meta tag, checks that the
+// name exists and counts as one pattern.
+func countPatterns(value interface{}, keyed bool) int {
+	switch value := value.(type) {
+	case string:
+		return 1
+	case []interface{}:
+		if len(value) == 0 && keyed {
+			return 1
+		}
+		count := 0
+		for _, item := range value {
+			count += countPatterns(item, false)
+		}
+		return count
+	case map[string]interface{}:
+		count := 0
+		for _, item := range value {
+			count += countPatterns(item, true)
+		}
+		return count
+	}
+	return 0
+}
+
+// runDiff implements the diff subcommand
+func runDiff(args []string) error {
+	flags := flag.NewFlagSet("diff", flag.ExitOnError)
+	output := flags.String("output", "", "File to write the changelog to (stdout if empty)")
+	flags.Parse(args)
+
+	if flags.NArg() != 2 {
+		return fmt.Errorf("diff expects the old and the new fingerprints files")
+	}
+	old, err := readDiffFingerprints(flags.Arg(0))
+	if err != nil {
+		return err
+	}
+	new, err := readDiffFingerprints(flags.Arg(1))
+	if err != nil {
+		return err
+	}
+
+	changelog := formatChangelog(old, new)
+	if *output == "" {
+		_, err = io.WriteString(os.Stdout, changelog)
+		return err
+	}
+	return os.WriteFile(*output, []byte(changelog), 0o644)
+}
+
+// diffFingerprints is a fingerprints file read for diffing. The apps are kept
+// generic, so that every field is compared whatever its type.
+type diffFingerprints struct {
+	Metadata fingerprints.Metadata             `json:"metadata"`
+	Apps     map[string]map[string]interface{} `json:"apps"`
+}
+
+// readDiffFingerprints reads a fingerprints file for diffing
+func readDiffFingerprints(path string) (*diffFingerprints, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var fingerprints diffFingerprints
+	if err := json.Unmarshal(data, &fingerprints); err != nil {
+		return nil, fmt.Errorf("could not parse %s: %w", path, err)
+	}
+	return &fingerprints, nil
+}
+
+// formatChangelog describes the changes between two versions of the
+// fingerprints as Markdown: the technologies added and removed, and for each
+// changed technology the entries of each field added and removed
+func formatChangelog(old, new *diffFingerprints) string {
+	var added, removed, changed []string
+	for app := range new.Apps {
+		if _, ok := old.Apps[app]; !ok {
+			added = append(added, app)
+		} else if !reflect.DeepEqual(old.Apps[app], new.Apps[app]) {
+			changed = append(changed, app)
+		}
+	}
+	for app := range old.Apps {
+		if _, ok := new.Apps[app]; !ok {
+			removed = append(removed, app)
+		}
+	}
+	sort.Strings(added)
+	sort.Strings(removed)
+	sort.Strings(changed)
+
+	var b strings.Builder
+	b.WriteString("# Fingerprint changes\n\n")
+	if old.Metadata.SourceVersion != "" || new.Metadata.SourceVersion != "" {
+		fmt.Fprintf(&b, "Wappalyzer %s → %s\n\n", cmp.Or(old.Metadata.SourceVersion, "unknown"), cmp.Or(new.Metadata.SourceVersion, "unknown"))
+	}
+	fmt.Fprintf(&b, "%d technologies → %d: %d added, %d removed, %d changed\n", len(old.Apps), len(new.Apps), len(added), len(removed), len(changed))
+
+	writeList := func(title string, apps []string) {
+		if len(apps) == 0 {
+			return
+		}
+		fmt.Fprintf(&b, "\n## %s\n\n", title)
+		for _, app := range apps {
+			fmt.Fprintf(&b, "- %s\n", app)
+		}
+	}
+	writeList("Added", added)
+	writeList("Removed", removed)
+
+	if len(changed) > 0 {
+		b.WriteString("\n## Changed\n")
+	}
+	for _, app := range changed {
+		fmt.Fprintf(&b, "\n### %s\n\n", app)
+		oldFields, newFields := old.Apps[app], new.Apps[app]
+		fields := make(map[string]struct{})
+		for field := range oldFields {
+			fields[field] = struct{}{}
+		}
+		for field := range newFields {
+			fields[field] = struct{}{}
+		}
+		names := make([]string, 0, len(fields))
+		for field := range fields {
+			names = append(names, field)
+		}
+		sort.Strings(names)
+
+		for _, field := range names {
+			oldEntries, newEntries := flattenField("", oldFields[field]), flattenField("", newFields[field])
+			for _, entry := range oldEntries {
+				if !slices.Contains(newEntries, entry) {
+					fmt.Fprintf(&b, "- %s: removed `%s`\n", field, entry)
 				}
 			}
-			sort.Strings(output.Implies)
-		}
-
-		// Process CSS using reflection
-		if tech.CSS != nil {
-			v := reflect.ValueOf(tech.CSS)
-			switch v.Kind() {
-			case reflect.String:
-				output.CSS = []string{v.Interface().(string)}
-			case reflect.Slice:
-				data := v.Interface().([]interface{})
-				output.CSS = make([]string, 0, len(data))
-				for _, pattern := range data {
-					if patStr, ok := pattern.(string); ok {
-						output.CSS = append(output.CSS, patStr)
-					}
+			for _, entry := range newEntries {
+				if !slices.Contains(oldEntries, entry) {
+					fmt.Fprintf(&b, "- %s: added `%s`\n", field, entry)
 				}
 			}
-			sort.Strings(output.CSS)
 		}
+	}
+	return b.String()
+}
 
-		// Process DNS records
-		// DNS record patterns are regex patterns that should preserve case
-		if tech.DNS != nil {
-			for recordType, patterns := range tech.DNS {
-				// Initialize the slice for this record type if needed
-				if output.DNS[recordType] == nil {
-					output.DNS[recordType] = []string{}
-				}
+// flattenField returns the entries of a field as sorted lines, each pattern
+// prefixed with the keys leading to it, such as "x-powered-by: ^PHP"
+func flattenField(prefix string, value interface{}) []string {
+	var entries []string
+	switch value := value.(type) {
+	case nil:
+		return nil
+	case map[string]interface{}:
+		for key, item := range value {
+			entries = append(entries, flattenField(strings.TrimSpace(prefix+" "+key), item)...)
+		}
+	case []interface{}:
+		for _, item := range value {
+			entries = append(entries, flattenField(prefix, item)...)
+		}
+	default:
+		if prefix == "" {
+			return []string{fmt.Sprint(value)}
+		}
+		return []string{fmt.Sprintf("%s: %v", prefix, value)}
+	}
+	// A key with no entries, such as a meta tag that only has to exist, is an entry
+	if len(entries) == 0 && prefix != "" {
+		entries = append(entries, prefix)
+	}
+	sort.Strings(entries)
+	return entries
+}
 
-				v := reflect.ValueOf(patterns)
-				switch v.Kind() {
-				case reflect.String:
-					// Single string pattern
-					data := v.Interface().(string)
-					output.DNS[recordType] = append(output.DNS[recordType], data)
-				case reflect.Slice:
-					// Array of patterns
-					data := v.Interface().([]interface{})
-					for _, pattern := range data {
-						if patStr, ok := pattern.(string); ok {
-							output.DNS[recordType] = append(output.DNS[recordType], patStr)
-						}
-					}
-				}
-			}
+// eolAPI is the endoflife.date API the eol subcommand reads release cycles from
+const eolAPI = "https://endoflife.date/api/v1/products/"
 
-			// Sort all DNS record patterns for consistent output
-			for recordType := range output.DNS {
-				sort.Strings(output.DNS[recordType])
-			}
+// eolRelease is a release cycle of an endoflife.date product, as returned
+// by its API
+type eolRelease struct {
+	Name    string  `json:"name"`
+	IsEOL   bool    `json:"isEol"`
+	EOLFrom *string `json:"eolFrom"`
+	Latest  *struct {
+		Name string `json:"name"`
+	} `json:"latest"`
+}
+
+// runEOL implements the eol subcommand, which refreshes the release cycles of
+// the end-of-life snapshot. The technologies of the snapshot and their
+// endoflife.date product are kept, so adding one is a matter of adding it to
+// the file with its product and running the subcommand.
+func runEOL(args []string) error {
+	flags := flag.NewFlagSet("eol", flag.ExitOnError)
+	output := flags.String("output", "../../assets/eol_data.json", "End-of-life snapshot to refresh")
+	flags.Parse(args)
+
+	data, err := os.ReadFile(*output)
+	if err != nil {
+		return err
+	}
+	var snapshot profiler.EOLData
+	if err := json.Unmarshal(data, &snapshot); err != nil {
+		return fmt.Errorf("could not parse %s: %w", *output, err)
+	}
+
+	client := &http.Client{Timeout: 30 * time.Second}
+	updated := time.Now().UTC().Format(time.DateOnly)
+	for technology, product := range snapshot.Technologies {
+		cycles, err := fetchEOLCycles(client, product.Product, updated)
+		if err != nil {
+			return fmt.Errorf("could not refresh %s: %w", technology, err)
 		}
+		product.Cycles = cycles
+		snapshot.Technologies[technology] = product
+	}
+	snapshot.Source = "https://endoflife.date"
+	snapshot.Updated = updated
 
-		// Only add if the fingerprint is valid
-		outputFingerprints.Apps[appName] = output
+	var buffer bytes.Buffer
+	encoder := json.NewEncoder(&buffer)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(snapshot); err != nil {
+		return err
+	}
+	log.Printf("Refreshed the release cycles of %d technologies", len(snapshot.Technologies))
+	return os.WriteFile(*output, buffer.Bytes(), 0o644)
+}
+
+// fetchEOLCycles returns the release cycles of an endoflife.date product,
+// newest first as the API lists them. Cycles that ended without a date are
+// given the date of the snapshot, by which they had ended.
+func fetchEOLCycles(client *http.Client, product, updated string) ([]profiler.EOLCycle, error) {
+	content, err := download(client, eolAPI+product)
+	if err != nil {
+		return nil, err
+	}
+	var response struct {
+		Result struct {
+			Releases []eolRelease `json:"releases"`
+		} `json:"result"`
+	}
+	if err := json.Unmarshal(content, &response); err != nil {
+		return nil, fmt.Errorf("could not parse the releases of %s: %w", product, err)
+	}
+
+	cycles := make([]profiler.EOLCycle, 0, len(response.Result.Releases))
+	for _, release := range response.Result.Releases {
+		cycle := profiler.EOLCycle{Cycle: release.Name}
+		if release.EOLFrom != nil {
+			cycle.EOL = *release.EOLFrom
+		} else if release.IsEOL {
+			cycle.EOL = updated
+		}
+		if release.Latest != nil {
+			cycle.Latest = release.Latest.Name
+		}
+		cycles = append(cycles, cycle)
+	}
+	if len(cycles) == 0 {
+		return nil, fmt.Errorf("no releases of %s", product)
 	}
-	return outputFingerprints
+	return cycles, nil
 }
@@ -0,0 +1,374 @@
+// Package fingerprints converts technology rules in the Wappalyzer format to
+// the normalized fingerprints kitsune loads, as the update-fingerprints tool
+// does for the embedded data. Tools producing their own rules can use it to
+// get data that NewFromFile and overlays accept.
+//
+// The pipeline is NormalizeFromBytes, or Normalize for technologies already
+// decoded and merged, then Lint to rewrite or drop the patterns that are slow
+// to match, then Stamp before writing the data as JSON.
+package fingerprints
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Technology represents a technology fingerprint in the Wappalyzer format
+// This matches the raw, inconsistent structure in the source JSON files
+// Using interface{} for fields that can be strings or arrays in the source data
+type Technology struct {
+	Cats        []int                  `json:"cats,omitempty"`
+	CSS         interface{}            `json:"css,omitempty"`
+	Cookies     map[string]string      `json:"cookies,omitempty"`
+	DOM         interface{}            `json:"dom,omitempty"`
+	JS          map[string]string      `json:"js,omitempty"`
+	Headers     map[string]string      `json:"headers,omitempty"`
+	HTML        interface{}            `json:"html,omitempty"`
+	Scripts     interface{}            `json:"scripts,omitempty"`
+	ScriptSrc   interface{}            `json:"scriptSrc,omitempty"`
+	Meta        map[string]interface{} `json:"meta,omitempty"`
+	DNS         map[string]interface{} `json:"dns,omitempty"`
+	XHR         interface{}            `json:"xhr,omitempty"`
+	Implies     interface{}            `json:"implies,omitempty"`
+	Excludes    interface{}            `json:"excludes,omitempty"`
+	Description string                 `json:"description,omitempty"`
+	Website     string                 `json:"website,omitempty"`
+	Icon        string                 `json:"icon,omitempty"`
+	CPE         string                 `json:"cpe,omitempty"`
+}
+
+// Fingerprints contains a map of fingerprints for tech detection
+// optimized and validated for the tech detection package
+type Fingerprints struct {
+	// Metadata stamps the version of the data, so users can tell how stale it is
+	Metadata Metadata `json:"metadata"`
+	// Apps is organized as <name, fingerprint>
+	Apps map[string]Fingerprint `json:"apps"`
+}
+
+// Metadata describes where and when the fingerprints were generated from
+type Metadata struct {
+	// SourceVersion is the version of the source, such as the Wappalyzer XPI
+	SourceVersion string `json:"source_version,omitempty"`
+	// FetchedAt is when the source was downloaded
+	FetchedAt time.Time `json:"fetched_at"`
+	// ContentHash is the SHA-256 of the normalized apps, as "sha256:<hex>"
+	ContentHash string `json:"content_hash"`
+	// Technologies is the number of normalized technologies
+	Technologies int `json:"technologies"`
+}
+
+// Fingerprint is a single piece of information about a tech validated and normalized
+type Fingerprint struct {
+	Cats        []int                             `json:"cats,omitempty"`
+	CSS         []string                          `json:"css,omitempty"`
+	DOM         map[string]map[string]interface{} `json:"dom,omitempty"`
+	Cookies     map[string]string                 `json:"cookies,omitempty"`
+	JS          map[string]string                 `json:"js,omitempty"`
+	Headers     map[string]string                 `json:"headers,omitempty"`
+	HTML        []string                          `json:"html,omitempty"`
+	Script      []string                          `json:"scripts,omitempty"`
+	ScriptSrc   []string                          `json:"scriptSrc,omitempty"`
+	Meta        map[string][]string               `json:"meta,omitempty"`
+	DNS         map[string][]string               `json:"dns,omitempty"`
+	XHR         []string                          `json:"xhr,omitempty"`
+	Implies     []string                          `json:"implies,omitempty"`
+	Excludes    []string                          `json:"excludes,omitempty"`
+	Description string                            `json:"description,omitempty"`
+	Website     string                            `json:"website,omitempty"`
+	CPE         string                            `json:"cpe,omitempty"`
+	Icon        string                            `json:"icon,omitempty"`
+}
+
+// NormalizeFromBytes normalizes a technologies file in the Wappalyzer format,
+// a JSON object of technologies by name
+func NormalizeFromBytes(data []byte) (*Fingerprints, error) {
+	var technologies map[string]Technology
+	if err := json.Unmarshal(data, &technologies); err != nil {
+		return nil, err
+	}
+	return Normalize(technologies), nil
+}
+
+// Stamp sets the metadata of the fingerprints: the version of their source,
+// when it was fetched, and a hash of the apps and their count, so that users
+// of the data can tell how stale it is
+func (f *Fingerprints) Stamp(sourceVersion string, fetchedAt time.Time) error {
+	appsData, err := json.Marshal(f.Apps)
+	if err != nil {
+		return err
+	}
+	hash := sha256.Sum256(appsData)
+	f.Metadata = Metadata{
+		SourceVersion: sourceVersion,
+		FetchedAt:     fetchedAt,
+		ContentHash:   "sha256:" + hex.EncodeToString(hash[:]),
+		Technologies:  len(f.Apps),
+	}
+	return nil
+}
+
+// Normalize converts technologies in the Wappalyzer format to fingerprints.
+// Field names that are case-insensitive, such as headers, cookies and meta
+// tags, are lowercased, fields that may be a string or a list become lists,
+// and lists are sorted. Patterns are kept as they are, and values of the wrong
+// type are dropped. The metadata is left empty, see Stamp.
+func Normalize(technologies map[string]Technology) *Fingerprints {
+	outputFingerprints := &Fingerprints{Apps: make(map[string]Fingerprint)}
+
+	for appName, tech := range technologies {
+		output := Fingerprint{
+			Cats:        tech.Cats,
+			Cookies:     make(map[string]string),
+			DOM:         make(map[string]map[string]interface{}),
+			Headers:     make(map[string]string),
+			JS:          make(map[string]string),
+			Meta:        make(map[string][]string),
+			DNS:         make(map[string][]string),
+			Description: tech.Description,
+			Website:     tech.Website,
+			CPE:         tech.CPE,
+			Icon:        tech.Icon,
+		}
+
+		// Process cookies
+		// Keys (cookie names) are typically case-insensitive, so we normalize them
+		// Values (patterns) are preserved in their original case for regex accuracy
+		for cookie, value := range tech.Cookies {
+			output.Cookies[strings.ToLower(cookie)] = value
+		}
+
+		// Process JS
+		for k, v := range tech.JS {
+			output.JS[k] = v
+		}
+
+		// Process headers
+		// Header names are case-insensitive by HTTP spec, so we normalize them
+		// Pattern values are preserved in their original case for regex accuracy
+		for header, pattern := range tech.Headers {
+			output.Headers[strings.ToLower(header)] = pattern
+		}
+
+		// Process DOM using reflection
+		if tech.DOM != nil {
+			v := reflect.ValueOf(tech.DOM)
+			switch v.Kind() {
+			case reflect.String:
+				data := v.Interface().(string)
+				output.DOM[data] = map[string]interface{}{"exists": ""}
+			case reflect.Slice:
+				data := v.Interface().([]interface{})
+				for _, pattern := range data {
+					if pat, ok := pattern.(string); ok {
+						output.DOM[pat] = map[string]interface{}{"exists": ""}
+					}
+				}
+			case reflect.Map:
+				data := v.Interface().(map[string]interface{})
+				for pattern, value := range data {
+					if valueMap, ok := value.(map[string]interface{}); ok {
+						output.DOM[pattern] = valueMap
+					}
+				}
+			}
+		}
+
+		// Process HTML using reflection
+		// HTML patterns are regex patterns that should preserve their case for accuracy
+		if tech.HTML != nil {
+			v := reflect.ValueOf(tech.HTML)
+			switch v.Kind() {
+			case reflect.String:
+				output.HTML = []string{v.Interface().(string)}
+			case reflect.Slice:
+				data := v.Interface().([]interface{})
+				output.HTML = make([]string, 0, len(data))
+				for _, pattern := range data {
+					if patStr, ok := pattern.(string); ok {
+						output.HTML = append(output.HTML, patStr)
+					}
+				}
+			}
+			sort.Strings(output.HTML)
+		}
+
+		// Process Scripts using reflection
+		// Script patterns are regex patterns that should preserve their case for accuracy
+		if tech.Scripts != nil {
+			v := reflect.ValueOf(tech.Scripts)
+			switch v.Kind() {
+			case reflect.String:
+				output.Script = []string{v.Interface().(string)}
+			case reflect.Slice:
+				data := v.Interface().([]interface{})
+				output.Script = make([]string, 0, len(data))
+				for _, pattern := range data {
+					if patStr, ok := pattern.(string); ok {
+						output.Script = append(output.Script, patStr)
+					}
+				}
+			}
+			sort.Strings(output.Script)
+		}
+
+		// Process ScriptSrc using reflection
+		// ScriptSrc patterns are regex patterns that should preserve their case for accuracy
+		if tech.ScriptSrc != nil {
+			v := reflect.ValueOf(tech.ScriptSrc)
+			switch v.Kind() {
+			case reflect.String:
+				output.ScriptSrc = []string{v.Interface().(string)}
+			case reflect.Slice:
+				data := v.Interface().([]interface{})
+				output.ScriptSrc = make([]string, 0, len(data))
+				for _, pattern := range data {
+					if patStr, ok := pattern.(string); ok {
+						output.ScriptSrc = append(output.ScriptSrc, patStr)
+					}
+				}
+			}
+			sort.Strings(output.ScriptSrc)
+		}
+
+		// Process Meta using reflection
+		// Meta tag names are normalized, but pattern values preserve case for regex accuracy
+		for header, pattern := range tech.Meta {
+			v := reflect.ValueOf(pattern)
+			switch v.Kind() {
+			case reflect.String:
+				data := v.Interface().(string)
+				if data == "" {
+					output.Meta[strings.ToLower(header)] = []string{}
+				} else {
+					output.Meta[strings.ToLower(header)] = []string{data}
+				}
+			case reflect.Slice:
+				if data, ok := v.Interface().([]interface{}); ok {
+					final := make([]string, 0, len(data))
+					for _, pattern := range data {
+						if patStr, ok := pattern.(string); ok {
+							final = append(final, patStr)
+						}
+					}
+					sort.Strings(final)
+					output.Meta[strings.ToLower(header)] = final
+				}
+			}
+		}
+
+		// Process Implies using reflection
+		if tech.Implies != nil {
+			v := reflect.ValueOf(tech.Implies)
+			switch v.Kind() {
+			case reflect.String:
+				output.Implies = []string{v.Interface().(string)}
+			case reflect.Slice:
+				data := v.Interface().([]interface{})
+				output.Implies = make([]string, 0, len(data))
+				for _, pattern := range data {
+					if patStr, ok := pattern.(string); ok {
+						output.Implies = append(output.Implies, patStr)
+					}
+				}
+			}
+			sort.Strings(output.Implies)
+		}
+
+		// Process Excludes using reflection
+		if tech.Excludes != nil {
+			v := reflect.ValueOf(tech.Excludes)
+			switch v.Kind() {
+			case reflect.String:
+				output.Excludes = []string{v.Interface().(string)}
+			case reflect.Slice:
+				data := v.Interface().([]interface{})
+				output.Excludes = make([]string, 0, len(data))
+				for _, name := range data {
+					if nameStr, ok := name.(string); ok {
+						output.Excludes = append(output.Excludes, nameStr)
+					}
+				}
+			}
+			sort.Strings(output.Excludes)
+		}
+
+		// Process CSS using reflection
+		if tech.CSS != nil {
+			v := reflect.ValueOf(tech.CSS)
+			switch v.Kind() {
+			case reflect.String:
+				output.CSS = []string{v.Interface().(string)}
+			case reflect.Slice:
+				data := v.Interface().([]interface{})
+				output.CSS = make([]string, 0, len(data))
+				for _, pattern := range data {
+					if patStr, ok := pattern.(string); ok {
+						output.CSS = append(output.CSS, patStr)
+					}
+				}
+			}
+			sort.Strings(output.CSS)
+		}
+
+		// Process XHR using reflection
+		// XHR patterns match the hostnames of requests made by the page
+		if tech.XHR != nil {
+			v := reflect.ValueOf(tech.XHR)
+			switch v.Kind() {
+			case reflect.String:
+				output.XHR = []string{v.Interface().(string)}
+			case reflect.Slice:
+				data := v.Interface().([]interface{})
+				output.XHR = make([]string, 0, len(data))
+				for _, pattern := range data {
+					if patStr, ok := pattern.(string); ok {
+						output.XHR = append(output.XHR, patStr)
+					}
+				}
+			}
+			sort.Strings(output.XHR)
+		}
+
+		// Process DNS records
+		// DNS record patterns are regex patterns that should preserve case
+		if tech.DNS != nil {
+			for recordType, patterns := range tech.DNS {
+				// Initialize the slice for this record type if needed
+				if output.DNS[recordType] == nil {
+					output.DNS[recordType] = []string{}
+				}
+
+				v := reflect.ValueOf(patterns)
+				switch v.Kind() {
+				case reflect.String:
+					// Single string pattern
+					data := v.Interface().(string)
+					output.DNS[recordType] = append(output.DNS[recordType], data)
+				case reflect.Slice:
+					// Array of patterns
+					data := v.Interface().([]interface{})
+					for _, pattern := range data {
+						if patStr, ok := pattern.(string); ok {
+							output.DNS[recordType] = append(output.DNS[recordType], patStr)
+						}
+					}
+				}
+			}
+
+			// Sort all DNS record patterns for consistent output
+			for recordType := range output.DNS {
+				sort.Strings(output.DNS[recordType])
+			}
+		}
+
+		// Only add if the fingerprint is valid
+		outputFingerprints.Apps[appName] = output
+	}
+	return outputFingerprints
+}
@@ -0,0 +1,89 @@
+package fingerprints
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNormalizeFromBytes(t *testing.T) {
+	data := []byte(`{
+		"Example": {
+			"cats": [1],
+			"html": "<div id=\"example\"",
+			"scriptSrc": ["example\\.js", 42, "app\\.js"],
+			"headers": {"X-Powered-By": "^Example"},
+			"cookies": {"EXAMPLE_SESSION": ""},
+			"meta": {"Generator": "", "Author": ["b", "a"]},
+			"dom": "#example",
+			"dns": {"TXT": "example-verification"},
+			"implies": "PHP",
+			"excludes": ["Other", "Another"]
+		}
+	}`)
+
+	normalized, err := NormalizeFromBytes(data)
+	require.NoError(t, err, "could not normalize")
+	require.Equal(t, Fingerprint{
+		Cats:      []int{1},
+		HTML:      []string{`<div id="example"`},
+		ScriptSrc: []string{`app\.js`, `example\.js`},
+		Headers:   map[string]string{"x-powered-by": "^Example"},
+		Cookies:   map[string]string{"example_session": ""},
+		JS:        map[string]string{},
+		Meta:      map[string][]string{"generator": {}, "author": {"a", "b"}},
+		DOM:       map[string]map[string]interface{}{"#example": {"exists": ""}},
+		DNS:       map[string][]string{"TXT": {"example-verification"}},
+		Implies:   []string{"PHP"},
+		Excludes:  []string{"Another", "Other"},
+	}, normalized.Apps["Example"], "wrong normalized fingerprint")
+
+	_, err = NormalizeFromBytes([]byte(`["Example"]`))
+	require.Error(t, err, "technologies should be an object")
+}
+
+func TestStamp(t *testing.T) {
+	normalized, err := NormalizeFromBytes([]byte(`{"Example": {"html": "example"}}`))
+	require.NoError(t, err, "could not normalize")
+
+	fetchedAt := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	require.NoError(t, normalized.Stamp("6.10.0", fetchedAt), "could not stamp")
+	require.Equal(t, "6.10.0", normalized.Metadata.SourceVersion)
+	require.Equal(t, fetchedAt, normalized.Metadata.FetchedAt)
+	require.Equal(t, 1, normalized.Metadata.Technologies)
+	require.Regexp(t, "^sha256:[0-9a-f]{64}$", normalized.Metadata.ContentHash, "wrong content hash")
+
+	hash := normalized.Metadata.ContentHash
+	normalized.Apps["Other"] = Fingerprint{HTML: []string{"other"}}
+	require.NoError(t, normalized.Stamp("6.10.0", fetchedAt), "could not stamp")
+	require.NotEqual(t, hash, normalized.Metadata.ContentHash, "hash should change with the apps")
+}
+
+func TestLint(t *testing.T) {
+	normalized, err := NormalizeFromBytes([]byte(`{
+		"Example": {
+			"html": ["(?:a|b)+example", "plain"],
+			"headers": {"x-example": "(unclosed"},
+			"dom": {"#example": {"text": "(unclosed", "attributes": {"class": "example"}}}
+		}
+	}`))
+	require.NoError(t, err, "could not normalize")
+
+	results := Lint(normalized)
+	require.Len(t, results, 3, "wrong number of linted patterns")
+	require.Equal(t, LintResult{
+		App:       "Example",
+		Vector:    "html",
+		Pattern:   "(?:a|b)+example",
+		Rewritten: "(?:a|b){1,20}example",
+		Issues:    []string{"quantified-alternation"},
+	}, results[0], "wrong rewritten pattern")
+	require.True(t, results[1].Rejected, "uncompilable header should be rejected")
+	require.Equal(t, `Example headers "(unclosed" rejected (too-complex: `+results[1].Reason+`)`, results[1].String())
+
+	example := normalized.Apps["Example"]
+	require.Equal(t, []string{"(?:a|b){1,20}example", "plain"}, example.HTML, "rewritten pattern should replace the original")
+	require.Empty(t, example.Headers, "rejected header should be dropped")
+	require.Empty(t, example.DOM, "DOM rule with a rejected pattern should be dropped")
+}
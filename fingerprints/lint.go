@@ -0,0 +1,131 @@
+package fingerprints
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/kavinsood/kitsune/internal/profiler"
+)
+
+// LintResult is a pattern that Lint rewrote or dropped
+type LintResult struct {
+	App     string `json:"app"`
+	Vector  string `json:"vector"`
+	Pattern string `json:"pattern"`
+	// Rewritten is the pattern that replaced it, unless it was rejected
+	Rewritten string `json:"rewritten,omitempty"`
+	// Issues are the kinds of slow constructs found, such as nested-quantifier
+	Issues []string `json:"issues"`
+	// Rejected is set if the pattern was dropped, for Reason
+	Rejected bool   `json:"rejected,omitempty"`
+	Reason   string `json:"reason,omitempty"`
+}
+
+func (r LintResult) String() string {
+	if r.Rejected {
+		return fmt.Sprintf("%s %s %q rejected (%s: %s)", r.App, r.Vector, r.Pattern, strings.Join(r.Issues, ", "), r.Reason)
+	}
+	return fmt.Sprintf("%s %s %q rewritten to %q (%s)", r.App, r.Vector, r.Pattern, r.Rewritten, strings.Join(r.Issues, ", "))
+}
+
+// Lint checks every pattern for constructs that are slow to match on large
+// pages, such as nested quantifiers. Patterns that can be fixed are rewritten
+// with bounded quantifiers, the others are dropped, along with the DOM rules
+// and meta tags that would match more than intended without them. It returns
+// the patterns rewritten or dropped, for review along with the data.
+func Lint(fingerprints *Fingerprints) []LintResult {
+	var report []LintResult
+	lint := func(app, vector, pattern string) (string, bool) {
+		result := profiler.LintPattern(pattern)
+		if result.Rewritten == "" && !result.Rejected {
+			return pattern, true
+		}
+		kinds := make([]string, 0, len(result.Issues))
+		for _, issue := range result.Issues {
+			kinds = append(kinds, issue.Kind)
+		}
+		entry := LintResult{App: app, Vector: vector, Pattern: pattern, Rewritten: result.Rewritten, Issues: kinds}
+		if result.Rejected {
+			entry.Rejected, entry.Reason = true, result.Issues[len(result.Issues)-1].Construct
+		}
+		report = append(report, entry)
+		return result.Rewritten, !result.Rejected
+	}
+	lintList := func(app, vector string, patterns []string) []string {
+		linted := patterns[:0]
+		for _, pattern := range patterns {
+			if pattern, ok := lint(app, vector, pattern); ok {
+				linted = append(linted, pattern)
+			}
+		}
+		sort.Strings(linted)
+		return linted
+	}
+	lintMap := func(app, vector string, patterns map[string]string) {
+		keys := make([]string, 0, len(patterns))
+		for key := range patterns {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			if pattern, ok := lint(app, vector, patterns[key]); ok {
+				patterns[key] = pattern
+			} else {
+				delete(patterns, key)
+			}
+		}
+	}
+
+	apps := make([]string, 0, len(fingerprints.Apps))
+	for app := range fingerprints.Apps {
+		apps = append(apps, app)
+	}
+	sort.Strings(apps)
+
+	for _, app := range apps {
+		fingerprint := fingerprints.Apps[app]
+		fingerprint.CSS = lintList(app, "css", fingerprint.CSS)
+		fingerprint.HTML = lintList(app, "html", fingerprint.HTML)
+		fingerprint.Script = lintList(app, "scripts", fingerprint.Script)
+		fingerprint.ScriptSrc = lintList(app, "scriptSrc", fingerprint.ScriptSrc)
+		fingerprint.XHR = lintList(app, "xhr", fingerprint.XHR)
+		lintMap(app, "cookies", fingerprint.Cookies)
+		lintMap(app, "js", fingerprint.JS)
+		lintMap(app, "headers", fingerprint.Headers)
+		// An empty list of meta patterns only checks that the tag exists, so
+		// tags whose patterns were all rejected are dropped
+		for name, patterns := range fingerprint.Meta {
+			if linted := lintList(app, "meta", patterns); len(linted) > 0 || len(patterns) == 0 {
+				fingerprint.Meta[name] = linted
+			} else {
+				delete(fingerprint.Meta, name)
+			}
+		}
+		for record, patterns := range fingerprint.DNS {
+			fingerprint.DNS[record] = lintList(app, "dns", patterns)
+		}
+
+		// DOM text and attribute values are patterns, "exists" and properties
+		// are not. Without one of its patterns a rule would match more than
+		// intended, so rules with a rejected pattern are dropped.
+		for selector, rule := range fingerprint.DOM {
+			keep := true
+			if text, ok := rule["text"].(string); ok {
+				rule["text"], keep = lint(app, "dom", text)
+			}
+			if attributes, ok := rule["attributes"].(map[string]interface{}); ok {
+				for name, value := range attributes {
+					if text, ok := value.(string); ok && keep {
+						attributes[name], keep = lint(app, "dom", text)
+					}
+				}
+			}
+			if !keep {
+				delete(fingerprint.DOM, selector)
+			}
+		}
+		fingerprints.Apps[app] = fingerprint
+	}
+	return report
+}
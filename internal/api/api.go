@@ -0,0 +1,13 @@
+// Package api holds the OpenAPI document of the Kitsune API server and the
+// request and response types generated from it, so the server and its
+// clients share one JSON contract.
+package api
+
+import _ "embed"
+
+//go:generate go run ./gen
+
+// Spec is the OpenAPI 3 document of the API
+//
+//go:embed openapi.json
+var Spec []byte
@@ -0,0 +1,128 @@
+package api
+
+import (
+	"encoding/json"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/kavinsood/kitsune/internal/api/codegen"
+	"github.com/kavinsood/kitsune/internal/profiler"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGeneratedTypesUpToDate(t *testing.T) {
+	expected, err := codegen.Generate(Spec, "api")
+	require.NoError(t, err, "could not generate types")
+
+	actual, err := os.ReadFile("types.gen.go")
+	require.NoError(t, err, "could not read generated types")
+	require.Equal(t, string(expected), string(actual), "types.gen.go is stale, run go generate ./internal/api")
+}
+
+// jsonFields returns the JSON field names of a struct type
+func jsonFields(typ reflect.Type) []string {
+	var fields []string
+	for i := 0; i < typ.NumField(); i++ {
+		name, _, _ := strings.Cut(typ.Field(i).Tag.Get("json"), ",")
+		if name != "" && name != "-" {
+			fields = append(fields, name)
+		}
+	}
+	sort.Strings(fields)
+	return fields
+}
+
+func TestExternalTypesMatchSpec(t *testing.T) {
+	var doc struct {
+		Components struct {
+			Schemas map[string]struct {
+				GoType     string                     `json:"x-go-type"`
+				Properties map[string]json.RawMessage `json:"properties"`
+			} `json:"schemas"`
+		} `json:"components"`
+	}
+	require.NoError(t, json.Unmarshal(Spec, &doc), "could not parse spec")
+
+	tests := map[string]reflect.Type{
+		"profiler.Technology":       reflect.TypeOf(profiler.Technology{}),
+		"profiler.Category":         reflect.TypeOf(profiler.Category{}),
+		"profiler.DataVersion":      reflect.TypeOf(profiler.DataVersion{}),
+		"profiler.ProgressEvent":    reflect.TypeOf(profiler.ProgressEvent{}),
+		"profiler.Detection":        reflect.TypeOf(profiler.Detection{}),
+		"profiler.Component":        reflect.TypeOf(profiler.Component{}),
+		"profiler.DryRun":           reflect.TypeOf(profiler.DryRun{}),
+		"profiler.FingerprintIssue": reflect.TypeOf(profiler.FingerprintIssue{}),
+		"profiler.NearMiss":         reflect.TypeOf(profiler.NearMiss{}),
+		"profiler.Stack":            reflect.TypeOf(profiler.Stack{}),
+		"profiler.Trace":            reflect.TypeOf(profiler.Trace{}),
+		"profiler.TraceEvent":       reflect.TypeOf(profiler.TraceEvent{}),
+		"profiler.EOLStatus":        reflect.TypeOf(profiler.EOLStatus{}),
+		"profiler.TLSValidation":    reflect.TypeOf(profiler.TLSValidation{}),
+		"profiler.Certificate":      reflect.TypeOf(profiler.Certificate{}),
+		"profiler.WappalyzerOutput": reflect.TypeOf(profiler.WappalyzerOutput{}),
+	}
+
+	for name, schema := range doc.Components.Schemas {
+		if schema.GoType == "" {
+			continue
+		}
+		typ, ok := tests[schema.GoType]
+		require.True(t, ok, "schema %s uses untested type %s", name, schema.GoType)
+
+		properties := make([]string, 0, len(schema.Properties))
+		for property := range schema.Properties {
+			properties = append(properties, property)
+		}
+		sort.Strings(properties)
+		require.Equal(t, jsonFields(typ), properties, "schema %s does not match %s", name, schema.GoType)
+	}
+}
+
+func TestSelectFields(t *testing.T) {
+	response := AnalyzeResponse{
+		Technologies: []Technology{
+			{Name: "Nginx", Version: "1.25.3", Confidence: 100, DetectedBy: []string{"headers"}, Website: "https://nginx.org/en"},
+			{Name: "PHP", Confidence: 50, DetectedBy: []string{"cookies"}},
+		},
+		Stack:    profiler.Stack{WebServer: "Nginx"},
+		RemoteIP: "192.0.2.1",
+	}
+
+	tests := []struct {
+		name   string
+		fields string
+		want   string
+	}{
+		{
+			name:   "technology fields",
+			fields: "technologies.name, technologies.version",
+			want:   `{"technologies":[{"name":"Nginx","version":"1.25.3"},{"name":"PHP"}]}`,
+		},
+		{
+			name:   "whole members",
+			fields: "stack,remote_ip,technologies.name,technologies",
+			want:   `{"remote_ip":"192.0.2.1","stack":{"web_server":"Nginx"},"technologies":[{"name":"Nginx","version":"1.25.3","confidence":100,"detected_by":["headers"],"categories":null,"description":"","website":"https://nginx.org/en"},{"name":"PHP","confidence":50,"detected_by":["cookies"],"categories":null,"description":"","website":""}]}`,
+		},
+		{
+			name:   "missing fields",
+			fields: "certificate,technologies.cpe",
+			want:   `{"technologies":[{},{}]}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			selected, err := SelectFields(response, ParseFields(tt.fields))
+			require.NoError(t, err, "could not select fields")
+			data, err := json.Marshal(selected)
+			require.NoError(t, err, "could not marshal selection")
+			require.JSONEq(t, tt.want, string(data), "wrong selection")
+		})
+	}
+
+	selected, err := SelectFields(response, ParseFields(""))
+	require.NoError(t, err, "could not select fields")
+	require.Equal(t, response, selected, "responses without fields should be returned as is")
+}
@@ -0,0 +1,230 @@
+// Package codegen generates Go types from the component schemas of an OpenAPI 3
+// document. It supports the subset of JSON Schema used by the Kitsune API:
+// objects, arrays, maps, references and primitive types, plus the x-go-type
+// and x-go-type-import extensions to reuse existing Go types.
+package codegen
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"go/format"
+	"path"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// schema is an OpenAPI schema object
+type schema struct {
+	Type                 string         `json:"type"`
+	Format               string         `json:"format"`
+	Description          string         `json:"description"`
+	Properties           orderedSchemas `json:"properties"`
+	Required             []string       `json:"required"`
+	Items                *schema        `json:"items"`
+	AdditionalProperties *schema        `json:"additionalProperties"`
+	Ref                  string         `json:"$ref"`
+	GoType               string         `json:"x-go-type"`
+	GoTypeImport         *goTypeImport  `json:"x-go-type-import"`
+}
+
+// goTypeImport is the package of an x-go-type
+type goTypeImport struct {
+	Path string `json:"path"`
+}
+
+// namedSchema is a schema along with its name
+type namedSchema struct {
+	name   string
+	schema *schema
+}
+
+// orderedSchemas keeps schemas in document order, so generated
+// struct fields follow the order of the properties in the document
+type orderedSchemas []namedSchema
+
+// UnmarshalJSON decodes an object of schemas, keeping the order of its keys
+func (o *orderedSchemas) UnmarshalJSON(data []byte) error {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	if _, err := decoder.Token(); err != nil {
+		return err
+	}
+	for decoder.More() {
+		token, err := decoder.Token()
+		if err != nil {
+			return err
+		}
+		var value schema
+		if err := decoder.Decode(&value); err != nil {
+			return err
+		}
+		*o = append(*o, namedSchema{name: token.(string), schema: &value})
+	}
+	return nil
+}
+
+// document is the part of an OpenAPI document types are generated from
+type document struct {
+	Components struct {
+		Schemas orderedSchemas `json:"schemas"`
+	} `json:"components"`
+}
+
+// initialisms are name parts written in upper case, following Go conventions
+var initialisms = map[string]string{"id": "ID", "url": "URL", "cpe": "CPE", "api": "API", "http": "HTTP", "json": "JSON", "eol": "EOL", "tls": "TLS", "ip": "IP", "html": "HTML"}
+
+// goName converts a snake_case property name into an exported Go name
+func goName(name string) string {
+	var builder strings.Builder
+	for _, part := range strings.Split(name, "_") {
+		if initialism, ok := initialisms[part]; ok {
+			builder.WriteString(initialism)
+		} else if part != "" {
+			builder.WriteString(strings.ToUpper(part[:1]) + part[1:])
+		}
+	}
+	return builder.String()
+}
+
+// generator accumulates the generated code and its imports
+type generator struct {
+	buffer  bytes.Buffer
+	imports map[string]struct{}
+}
+
+// Generate returns the Go source of the types of the component schemas of spec,
+// in package pkg. Schemas with an x-go-type are not generated, only referenced.
+func Generate(spec []byte, pkg string) ([]byte, error) {
+	var doc document
+	if err := json.Unmarshal(spec, &doc); err != nil {
+		return nil, fmt.Errorf("could not parse openapi document: %w", err)
+	}
+
+	g := &generator{imports: make(map[string]struct{})}
+	schemas := make(map[string]*schema, len(doc.Components.Schemas))
+	for _, named := range doc.Components.Schemas {
+		schemas[named.name] = named.schema
+	}
+
+	for _, named := range doc.Components.Schemas {
+		if named.schema.GoType != "" {
+			continue
+		}
+		if named.schema.Type != "object" {
+			return nil, fmt.Errorf("schema %s: only object schemas can be generated", named.name)
+		}
+		if err := g.writeStruct(named.name, named.schema, schemas); err != nil {
+			return nil, err
+		}
+	}
+
+	var source bytes.Buffer
+	source.WriteString("// Code generated by internal/api/codegen from openapi.json. DO NOT EDIT.\n\n")
+	fmt.Fprintf(&source, "package %s\n\n", pkg)
+	if len(g.imports) > 0 {
+		imports := make([]string, 0, len(g.imports))
+		for imp := range g.imports {
+			imports = append(imports, imp)
+		}
+		sort.Strings(imports)
+		source.WriteString("import (\n")
+		for _, imp := range imports {
+			fmt.Fprintf(&source, "\t%q\n", imp)
+		}
+		source.WriteString(")\n\n")
+	}
+	source.Write(g.buffer.Bytes())
+
+	formatted, err := format.Source(source.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("could not format generated code: %w", err)
+	}
+	return formatted, nil
+}
+
+// writeStruct writes the struct of an object schema
+func (g *generator) writeStruct(name string, s *schema, schemas map[string]*schema) error {
+	writeComment(&g.buffer, "", s.Description)
+	fmt.Fprintf(&g.buffer, "type %s struct {\n", name)
+	for _, property := range s.Properties {
+		goType, err := g.goType(property.schema, schemas)
+		if err != nil {
+			return fmt.Errorf("schema %s property %s: %w", name, property.name, err)
+		}
+
+		tag := property.name
+		if !slices.Contains(s.Required, property.name) {
+			tag += ",omitempty"
+			// Optional objects are pointers, which omitempty leaves out when nil
+			if property.schema.Ref != "" && schemas[path.Base(property.schema.Ref)].Type == "object" {
+				goType = "*" + goType
+			}
+		}
+		writeComment(&g.buffer, "\t", property.schema.Description)
+		fmt.Fprintf(&g.buffer, "\t%s %s `json:%q`\n", goName(property.name), goType, tag)
+	}
+	g.buffer.WriteString("}\n\n")
+	return nil
+}
+
+// goType returns the Go type of a schema
+func (g *generator) goType(s *schema, schemas map[string]*schema) (string, error) {
+	if s.Ref != "" {
+		name := path.Base(s.Ref)
+		referenced, ok := schemas[name]
+		if !ok {
+			return "", fmt.Errorf("unknown reference %s", s.Ref)
+		}
+		if referenced.GoType != "" {
+			return g.goType(referenced, schemas)
+		}
+		return name, nil
+	}
+	if s.GoType != "" {
+		if s.GoTypeImport != nil {
+			g.imports[s.GoTypeImport.Path] = struct{}{}
+		}
+		return s.GoType, nil
+	}
+
+	switch s.Type {
+	case "string":
+		if s.Format == "date-time" {
+			g.imports["time"] = struct{}{}
+			return "time.Time", nil
+		}
+		return "string", nil
+	case "integer":
+		if s.Format == "int64" {
+			return "int64", nil
+		}
+		return "int", nil
+	case "number":
+		return "float64", nil
+	case "boolean":
+		return "bool", nil
+	case "array":
+		if s.Items == nil {
+			return "", fmt.Errorf("array without items")
+		}
+		item, err := g.goType(s.Items, schemas)
+		return "[]" + item, err
+	case "object":
+		if s.AdditionalProperties == nil {
+			return "", fmt.Errorf("inline objects must be declared as components")
+		}
+		value, err := g.goType(s.AdditionalProperties, schemas)
+		return "map[string]" + value, err
+	}
+	return "", fmt.Errorf("unsupported type %q", s.Type)
+}
+
+// writeComment writes a description as a doc comment
+func writeComment(buffer *bytes.Buffer, indent, description string) {
+	for _, line := range strings.Split(strings.TrimSpace(description), "\n") {
+		if line != "" {
+			fmt.Fprintf(buffer, "%s// %s\n", indent, line)
+		}
+	}
+}
@@ -0,0 +1,91 @@
+package api
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// ParseFields splits a fields parameter, a comma-separated list of response
+// fields such as "technologies.name,technologies.version,stack"
+func ParseFields(value string) []string {
+	var fields []string
+	for _, field := range strings.Split(value, ",") {
+		if field = strings.TrimSpace(field); field != "" {
+			fields = append(fields, field)
+		}
+	}
+	return fields
+}
+
+// SelectFields returns the JSON form of response reduced to fields. A field
+// names a member of the response, or with a dotted path a member of the
+// objects it holds, such as technologies.name for the name of every
+// technology. Members named without a path are kept whole, and fields the
+// response lacks are ignored. Without fields, the response is returned as is.
+func SelectFields(response interface{}, fields []string) (interface{}, error) {
+	if len(fields) == 0 {
+		return response, nil
+	}
+	data, err := json.Marshal(response)
+	if err != nil {
+		return nil, err
+	}
+	var value interface{}
+	if err := json.Unmarshal(data, &value); err != nil {
+		return nil, err
+	}
+
+	selection := make(fieldSelection)
+	for _, field := range fields {
+		selection.add(strings.Split(field, "."))
+	}
+	return selection.apply(value), nil
+}
+
+// fieldSelection is a tree of selected members. A member with a nil
+// selection is kept whole.
+type fieldSelection map[string]fieldSelection
+
+// add selects the member at path
+func (s fieldSelection) add(path []string) {
+	child, ok := s[path[0]]
+	if ok && child == nil {
+		// The member is already kept whole
+		return
+	}
+	if len(path) == 1 {
+		s[path[0]] = nil
+		return
+	}
+	if child == nil {
+		child = make(fieldSelection)
+		s[path[0]] = child
+	}
+	child.add(path[1:])
+}
+
+// apply returns the selected members of value, applying the selection to
+// each element of arrays
+func (s fieldSelection) apply(value interface{}) interface{} {
+	switch value := value.(type) {
+	case map[string]interface{}:
+		selected := make(map[string]interface{}, len(s))
+		for name, child := range s {
+			member, ok := value[name]
+			if !ok {
+				continue
+			}
+			if child != nil {
+				member = child.apply(member)
+			}
+			selected[name] = member
+		}
+		return selected
+	case []interface{}:
+		for i, element := range value {
+			value[i] = s.apply(element)
+		}
+		return value
+	}
+	return value
+}
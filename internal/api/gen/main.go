@@ -0,0 +1,26 @@
+// Command gen regenerates types.gen.go from openapi.json. It is run by
+// go generate from the api package directory.
+package main
+
+import (
+	"log"
+	"os"
+
+	"github.com/kavinsood/kitsune/internal/api/codegen"
+)
+
+func main() {
+	spec, err := os.ReadFile("openapi.json")
+	if err != nil {
+		log.Fatalf("Could not read openapi.json: %v", err)
+	}
+
+	source, err := codegen.Generate(spec, "api")
+	if err != nil {
+		log.Fatalf("Could not generate types: %v", err)
+	}
+
+	if err := os.WriteFile("types.gen.go", source, 0o644); err != nil {
+		log.Fatalf("Could not write types.gen.go: %v", err)
+	}
+}
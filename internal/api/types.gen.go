@@ -0,0 +1,199 @@
+// Code generated by internal/api/codegen from openapi.json. DO NOT EDIT.
+
+package api
+
+import (
+	"encoding/json"
+	"github.com/kavinsood/kitsune/internal/profiler"
+)
+
+// AnalyzeRequest is the body of an analyze request
+type AnalyzeRequest struct {
+	// URL is the target to fingerprint
+	URL string `json:"url"`
+	// Async analyzes in the background and only publishes the result to the
+	// configured sinks, instead of returning it
+	Async bool `json:"async,omitempty"`
+	// Format selects the response schema; "wappalyzer" emits the Wappalyzer CLI schema,
+	// "legacy" the analyze response of earlier versions
+	Format string `json:"format,omitempty"`
+	// InlineIcons adds the icon of each technology to the response as a data URI
+	InlineIcons bool `json:"inline_icons,omitempty"`
+	// Profile selects the vectors the analysis runs and the requests it sends:
+	// "fast" only matches the page, "deep" adds error page and header order probes.
+	// The server default applies when empty.
+	Profile string `json:"profile,omitempty"`
+	// Tags restricts the response to the technologies with one of the tags,
+	// which fingerprint overlays attach to technologies
+	Tags []string `json:"tags,omitempty"`
+	// Set selects the fingerprint set of the analysis among the sets configured
+	// on the server. The default set applies when empty.
+	Set string `json:"set,omitempty"`
+	// Explain adds the near misses of the analysis to the response: the
+	// technologies that matched but were left out by a confidence of 0, the
+	// excludes of another technology, a conflict in an exclusive category or
+	// the tags of the request. Explained analyses bypass the result cache.
+	Explain bool `json:"explain,omitempty"`
+	// Trace adds the trace of the analysis to the response, the ordered
+	// events of its requests, parsing, matching, detections and stages, to
+	// attach to reports of missed detections.
+	Trace bool `json:"trace,omitempty"`
+}
+
+// AnalyzeResponse is the default analyze response
+type AnalyzeResponse struct {
+	Technologies []Technology `json:"technologies"`
+	// Partial is set when the budget of the analysis ran out before every stage finished
+	Partial bool `json:"partial,omitempty"`
+	// SkippedStages are the stages the budget cut short
+	SkippedStages []string `json:"skipped_stages,omitempty"`
+	// LimitsHit are the resource caps the analysis reached, leaving part of
+	// the page or its assets unanalyzed
+	LimitsHit []string `json:"limits_hit,omitempty"`
+	// Stack summarizes the technologies with the primary one of the main categories
+	Stack profiler.Stack `json:"stack"`
+	// TLSValidation is the outcome of verifying the certificate of the page,
+	// if it was served over TLS
+	TLSValidation *profiler.TLSValidation `json:"tls_validation,omitempty"`
+	// Certificate describes the leaf certificate of the page, if it was
+	// served over TLS
+	Certificate *profiler.Certificate `json:"certificate,omitempty"`
+	// RemoteIP is the address the page was fetched from
+	RemoteIP string `json:"remote_ip,omitempty"`
+	// IPFamily is the family of the address the page was fetched from
+	IPFamily string `json:"ip_family,omitempty"`
+	// SchemeFallback is set when the page was only reachable over the other
+	// scheme, such as plain HTTP for a target given over HTTPS, with the
+	// scheme_fallback setting
+	SchemeFallback bool `json:"scheme_fallback,omitempty"`
+	// NearMisses are the technologies that matched but were left out, for
+	// explained analyses
+	NearMisses []profiler.NearMiss `json:"near_misses,omitempty"`
+	Trace      *profiler.Trace     `json:"trace,omitempty"`
+}
+
+// Technology is a detected technology in the analyze response
+type Technology struct {
+	Name string `json:"name"`
+	// Version is the detected version, if known
+	Version string `json:"version,omitempty"`
+	// Confidence is the confidence of the detection, from 0 to 100
+	Confidence int `json:"confidence"`
+	// DetectedBy are the vectors the technology was detected by
+	DetectedBy []string `json:"detected_by"`
+	// Categories are the categories of the technology, sorted by ID
+	Categories  []profiler.Category `json:"categories"`
+	Description string              `json:"description"`
+	Website     string              `json:"website"`
+	// CPE is the CPE 2.3 name of the technology, if it has one
+	CPE string `json:"cpe,omitempty"`
+	// Icon is the icon as a data URI, if requested
+	Icon string `json:"icon,omitempty"`
+	// Tags are the tags fingerprint overlays attach to the technology
+	Tags []string `json:"tags,omitempty"`
+	// EOL is the end-of-life status of the detected version, if known
+	EOL *profiler.EOLStatus `json:"eol,omitempty"`
+	// Components are the parts of the technology its deep inspection found,
+	// such as the plugins and themes of WordPress
+	Components []profiler.Component `json:"components,omitempty"`
+}
+
+// LegacyAnalyzeResponse is the analyze response of earlier versions, whose
+// technologies are named with their version and carry no detection details
+type LegacyAnalyzeResponse struct {
+	Technologies []LegacyTechnology `json:"technologies"`
+	// Partial is set when the budget of the analysis ran out before every stage finished
+	Partial bool `json:"partial,omitempty"`
+	// SkippedStages are the stages the budget cut short
+	SkippedStages []string `json:"skipped_stages,omitempty"`
+	// LimitsHit are the resource caps the analysis reached, leaving part of
+	// the page or its assets unanalyzed
+	LimitsHit []string `json:"limits_hit,omitempty"`
+	// Stack summarizes the technologies with the primary one of the main categories
+	Stack profiler.Stack `json:"stack"`
+	// TLSValidation is the outcome of verifying the certificate of the page,
+	// if it was served over TLS
+	TLSValidation *profiler.TLSValidation `json:"tls_validation,omitempty"`
+	// Certificate describes the leaf certificate of the page, if it was
+	// served over TLS
+	Certificate *profiler.Certificate `json:"certificate,omitempty"`
+	// RemoteIP is the address the page was fetched from
+	RemoteIP string `json:"remote_ip,omitempty"`
+	// IPFamily is the family of the address the page was fetched from
+	IPFamily string `json:"ip_family,omitempty"`
+	// SchemeFallback is set when the page was only reachable over the other
+	// scheme, such as plain HTTP for a target given over HTTPS, with the
+	// scheme_fallback setting
+	SchemeFallback bool `json:"scheme_fallback,omitempty"`
+}
+
+// LegacyTechnology is a detected technology in the legacy analyze response
+type LegacyTechnology struct {
+	// Name is the name of the technology, followed by its version if known,
+	// as in PHP:8.2.0
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Website     string `json:"website"`
+	// Icon is the icon as a data URI, if requested
+	Icon string `json:"icon,omitempty"`
+	// Tags are the tags fingerprint overlays attach to the technology
+	Tags []string `json:"tags,omitempty"`
+	// EOL is the end-of-life status of the detected version, if known
+	EOL *profiler.EOLStatus `json:"eol,omitempty"`
+}
+
+// StreamError is the payload of the error event of a streamed analysis
+type StreamError struct {
+	Error string `json:"error"`
+	// RequestID identifies the request in the server logs
+	RequestID string `json:"request_id,omitempty"`
+}
+
+// HealthResponse is the health check response
+type HealthResponse struct {
+	Status string               `json:"status"`
+	Data   profiler.DataVersion `json:"data"`
+}
+
+// ReloadResponse reports a completed reload
+type ReloadResponse struct {
+	Status string               `json:"status"`
+	Data   profiler.DataVersion `json:"data"`
+}
+
+// FingerprintTestRequest is a candidate fingerprint and the sample response it
+// is tried against
+type FingerprintTestRequest struct {
+	// Name is the technology the candidate detects
+	Name string `json:"name"`
+	// Fingerprint is the definition of the candidate, in the format of fingerprint files
+	Fingerprint json.RawMessage `json:"fingerprint"`
+	// URL is the address the sample was fetched from, matched by the URL vectors
+	URL string `json:"url,omitempty"`
+	// Headers are the response headers of the sample
+	Headers map[string]string `json:"headers,omitempty"`
+	// HTML is the body of the sample
+	HTML string `json:"html,omitempty"`
+}
+
+// FingerprintResponse is the loaded definition of a technology
+type FingerprintResponse struct {
+	Name string `json:"name"`
+	// Set is the fingerprint set the definition was read from
+	Set string `json:"set"`
+	// Fingerprint is the definition, in the format of fingerprint files
+	Fingerprint json.RawMessage `json:"fingerprint"`
+}
+
+// TechnologiesResponse is a page of the technologies listing
+type TechnologiesResponse struct {
+	Total        int                   `json:"total"`
+	Page         int                   `json:"page"`
+	PerPage      int                   `json:"per_page"`
+	Technologies []profiler.Technology `json:"technologies"`
+}
+
+// CategoriesResponse lists the technology categories
+type CategoriesResponse struct {
+	Categories []profiler.Category `json:"categories"`
+}
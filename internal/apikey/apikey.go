@@ -0,0 +1,211 @@
+// Package apikey authenticates API requests with keys and enforces a
+// per-key rate limit and daily quota on them.
+package apikey
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kavinsood/kitsune/internal/reqlog"
+)
+
+// Key is an API key along with its limits. A zero limit means unlimited.
+type Key struct {
+	Key string
+	// RatePerMinute is the sustained number of requests allowed per minute.
+	// Bursts of up to RatePerMinute requests are allowed.
+	RatePerMinute int
+	// DailyQuota is the number of requests allowed per UTC day
+	DailyQuota int
+}
+
+// ParseKeys parses a comma separated list of keys, each optionally followed by
+// its rate limit and daily quota, e.g. "key1,key2:60,key3:10:1000". Keys without
+// limits use the defaults.
+func ParseKeys(spec string, defaultRate, defaultQuota int) ([]Key, error) {
+	var keys []Key
+	for _, entry := range strings.Split(spec, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		fields := strings.Split(entry, ":")
+		if len(fields) > 3 || fields[0] == "" {
+			return nil, fmt.Errorf("invalid api key entry %q", entry)
+		}
+		key := Key{Key: fields[0], RatePerMinute: defaultRate, DailyQuota: defaultQuota}
+		limits := []*int{&key.RatePerMinute, &key.DailyQuota}
+		for i, field := range fields[1:] {
+			value, err := strconv.Atoi(field)
+			if err != nil || value < 0 {
+				return nil, fmt.Errorf("invalid limit %q for api key entry %q", field, entry)
+			}
+			*limits[i] = value
+		}
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
+
+// keyState tracks the usage of a single key
+type keyState struct {
+	Key
+	// tokens is the current token bucket level, refilled at RatePerMinute
+	tokens     float64
+	lastRefill time.Time
+	// used is the number of requests made on day
+	used int
+	day  time.Time
+}
+
+// Authenticator checks API keys and their limits
+type Authenticator struct {
+	mutex sync.Mutex
+	keys  map[string]*keyState
+	// now returns the current time, replaced in tests
+	now func() time.Time
+}
+
+// New creates an authenticator accepting the given keys
+func New(keys []Key) *Authenticator {
+	a := &Authenticator{
+		keys: make(map[string]*keyState, len(keys)),
+		now:  time.Now,
+	}
+	for _, key := range keys {
+		a.keys[key.Key] = &keyState{Key: key, tokens: float64(key.RatePerMinute)}
+	}
+	return a
+}
+
+// SetKeys replaces the accepted keys, as when the server configuration is
+// reloaded. Keys that remain keep their usage, so reloading does not reset
+// their rate limit or quota.
+func (a *Authenticator) SetKeys(keys []Key) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	states := make(map[string]*keyState, len(keys))
+	for _, key := range keys {
+		state, ok := a.keys[key.Key]
+		if !ok {
+			state = &keyState{tokens: float64(key.RatePerMinute)}
+		}
+		state.Key = key
+		state.tokens = math.Min(state.tokens, float64(key.RatePerMinute))
+		states[key.Key] = state
+	}
+	a.keys = states
+}
+
+// Decision is the outcome of checking a request against the limits of its key
+type Decision struct {
+	// Known is false when the key is missing or not configured
+	Known bool
+	// Allowed is true when the request may proceed
+	Allowed bool
+	// RetryAfter is how long to wait before retrying a request that was not allowed
+	RetryAfter time.Duration
+	// QuotaRemaining is the number of requests left today, or -1 when unlimited
+	QuotaRemaining int
+}
+
+// Allow checks and consumes one request for key
+func (a *Authenticator) Allow(key string) Decision {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	state, ok := a.keys[key]
+	if !ok {
+		return Decision{}
+	}
+	now := a.now()
+	decision := Decision{Known: true, QuotaRemaining: -1}
+
+	// Reset the quota at midnight UTC
+	day := now.UTC().Truncate(24 * time.Hour)
+	if !state.day.Equal(day) {
+		state.day = day
+		state.used = 0
+	}
+	if state.DailyQuota > 0 && state.used >= state.DailyQuota {
+		decision.RetryAfter = day.Add(24 * time.Hour).Sub(now)
+		decision.QuotaRemaining = 0
+		return decision
+	}
+
+	if state.RatePerMinute > 0 {
+		rate := float64(state.RatePerMinute) / float64(time.Minute)
+		if !state.lastRefill.IsZero() {
+			state.tokens = math.Min(float64(state.RatePerMinute), state.tokens+float64(now.Sub(state.lastRefill))*rate)
+		}
+		state.lastRefill = now
+		if state.tokens < 1 {
+			decision.RetryAfter = time.Duration((1 - state.tokens) / rate)
+			if state.DailyQuota > 0 {
+				decision.QuotaRemaining = state.DailyQuota - state.used
+			}
+			return decision
+		}
+		state.tokens--
+	}
+
+	state.used++
+	if state.DailyQuota > 0 {
+		decision.QuotaRemaining = state.DailyQuota - state.used
+	}
+	decision.Allowed = true
+	return decision
+}
+
+// FromRequest returns the key sent in the X-API-Key header or as a bearer token
+func FromRequest(r *http.Request) string {
+	if key := r.Header.Get("X-API-Key"); key != "" {
+		return key
+	}
+	if token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); ok {
+		return strings.TrimSpace(token)
+	}
+	return ""
+}
+
+// Middleware rejects requests without a valid key with 401 Unauthorized, and
+// requests over the rate limit or daily quota of their key with 429 Too Many
+// Requests and a Retry-After header. Requests to the exempt paths are not checked.
+func (a *Authenticator) Middleware(next http.Handler, exempt ...string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		for _, path := range exempt {
+			if r.URL.Path == path {
+				next.ServeHTTP(w, r)
+				return
+			}
+		}
+
+		decision := a.Allow(FromRequest(r))
+		if !decision.Known {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="kitsune"`)
+			reqlog.Error(w, r, "A valid API key is required", http.StatusUnauthorized)
+			return
+		}
+		if decision.QuotaRemaining >= 0 {
+			w.Header().Set("X-Quota-Remaining", strconv.Itoa(decision.QuotaRemaining))
+		}
+		if !decision.Allowed {
+			// Round up, so clients retrying on time are not rejected again
+			w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(decision.RetryAfter.Seconds()))))
+			if decision.QuotaRemaining == 0 {
+				reqlog.Error(w, r, "Daily quota exceeded", http.StatusTooManyRequests)
+			} else {
+				reqlog.Error(w, r, "Rate limit exceeded", http.StatusTooManyRequests)
+			}
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,116 @@
+package apikey
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseKeys(t *testing.T) {
+	keys, err := ParseKeys("alpha, beta:30 ,gamma:0:500,", 60, 1000)
+	require.NoError(t, err, "could not parse keys")
+	require.Equal(t, []Key{
+		{Key: "alpha", RatePerMinute: 60, DailyQuota: 1000},
+		{Key: "beta", RatePerMinute: 30, DailyQuota: 1000},
+		{Key: "gamma", RatePerMinute: 0, DailyQuota: 500},
+	}, keys, "wrong keys")
+
+	for _, spec := range []string{"alpha:x", "alpha:1:2:3", ":10", "alpha:-1"} {
+		_, err := ParseKeys(spec, 60, 1000)
+		require.Error(t, err, "invalid spec %q should fail", spec)
+	}
+}
+
+func TestAllow(t *testing.T) {
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	authenticator := New([]Key{
+		{Key: "limited", RatePerMinute: 2, DailyQuota: 3},
+		{Key: "unlimited"},
+	})
+	authenticator.now = func() time.Time { return now }
+
+	require.False(t, authenticator.Allow("unknown").Known, "unknown key should be rejected")
+	for i := 0; i < 10; i++ {
+		require.True(t, authenticator.Allow("unlimited").Allowed, "unlimited key should be allowed")
+	}
+
+	// The burst is the per-minute rate
+	require.True(t, authenticator.Allow("limited").Allowed, "first request should be allowed")
+	decision := authenticator.Allow("limited")
+	require.True(t, decision.Allowed, "second request should be allowed")
+	require.Equal(t, 1, decision.QuotaRemaining, "wrong remaining quota")
+
+	decision = authenticator.Allow("limited")
+	require.False(t, decision.Allowed, "third request should be rate limited")
+	require.Equal(t, 30*time.Second, decision.RetryAfter, "wrong retry delay")
+
+	// A token is refilled every 30 seconds, after which the quota is exhausted
+	now = now.Add(30 * time.Second)
+	require.True(t, authenticator.Allow("limited").Allowed, "refilled request should be allowed")
+	now = now.Add(time.Minute)
+	decision = authenticator.Allow("limited")
+	require.False(t, decision.Allowed, "request over quota should be rejected")
+	require.Equal(t, 0, decision.QuotaRemaining, "quota should be exhausted")
+
+	// The quota resets at midnight UTC
+	now = now.Add(24 * time.Hour)
+	require.True(t, authenticator.Allow("limited").Allowed, "quota should reset the next day")
+}
+
+func TestSetKeys(t *testing.T) {
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	authenticator := New([]Key{{Key: "kept", RatePerMinute: 10, DailyQuota: 2}, {Key: "revoked"}})
+	authenticator.now = func() time.Time { return now }
+	require.True(t, authenticator.Allow("kept").Allowed, "first request should be allowed")
+
+	authenticator.SetKeys([]Key{{Key: "kept", RatePerMinute: 10, DailyQuota: 2}, {Key: "added"}})
+	require.False(t, authenticator.Allow("revoked").Known, "removed keys should be rejected")
+	require.True(t, authenticator.Allow("added").Allowed, "added keys should be allowed")
+	decision := authenticator.Allow("kept")
+	require.True(t, decision.Allowed, "second request should be allowed")
+	require.Equal(t, 0, decision.QuotaRemaining, "usage should survive the new keys")
+
+	// Lowered limits apply to the tokens left
+	authenticator.SetKeys([]Key{{Key: "kept", RatePerMinute: 1}})
+	require.True(t, authenticator.Allow("kept").Allowed, "raised quota should allow requests")
+	require.False(t, authenticator.Allow("kept").Allowed, "lowered rate should apply at once")
+}
+
+func TestMiddleware(t *testing.T) {
+	authenticator := New([]Key{{Key: "secret", RatePerMinute: 1}})
+	handler := authenticator.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}), "/health")
+
+	tests := []struct {
+		name       string
+		path       string
+		header     string
+		value      string
+		status     int
+		retryAfter string
+	}{
+		{"exempt", "/health", "", "", http.StatusOK, ""},
+		{"missing-key", "/analyze", "", "", http.StatusUnauthorized, ""},
+		{"wrong-key", "/analyze", "X-API-Key", "wrong", http.StatusUnauthorized, ""},
+		{"header-key", "/analyze", "X-API-Key", "secret", http.StatusOK, ""},
+		{"bearer-over-limit", "/analyze", "Authorization", "Bearer secret", http.StatusTooManyRequests, "60"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("POST", tt.path, nil)
+			if tt.header != "" {
+				req.Header.Set(tt.header, tt.value)
+			}
+			recorder := httptest.NewRecorder()
+			handler.ServeHTTP(recorder, req)
+
+			require.Equal(t, tt.status, recorder.Code, "wrong status")
+			require.Equal(t, tt.retryAfter, recorder.Header().Get("Retry-After"), "wrong retry after")
+		})
+	}
+}
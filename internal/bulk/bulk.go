@@ -0,0 +1,251 @@
+// Package bulk scans large lists of targets with a pool of workers, writing a
+// JSON line per target as soon as it is scanned. Completed targets are appended
+// to a checkpoint file, so an interrupted scan can be resumed without scanning
+// the finished targets again.
+package bulk
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kavinsood/kitsune/internal/profiler"
+)
+
+// Record is the result of scanning a single target, written as one JSON line
+type Record struct {
+	URL        string                        `json:"url"`
+	ScannedAt  time.Time                     `json:"scanned_at"`
+	Detections map[string]profiler.Detection `json:"detections,omitempty"`
+	// Partial is set if the timeout passed before all stages finished
+	Partial bool `json:"partial,omitempty"`
+	// Location is where the target is hosted, if the engine looks it up
+	Location *profiler.Location `json:"location,omitempty"`
+	Error    string             `json:"error,omitempty"`
+}
+
+// Summary counts the targets of a run
+type Summary struct {
+	// Scanned targets, including the ones that failed
+	Scanned int `json:"scanned"`
+	// Failed targets, whose record has an error
+	Failed int `json:"failed"`
+	// Skipped targets, which the checkpoint lists as completed
+	Skipped int `json:"skipped"`
+}
+
+// Scanner scans targets concurrently with a shared engine
+type Scanner struct {
+	engine  *profiler.Wappalyze
+	workers int
+	timeout time.Duration
+	logger  *slog.Logger
+}
+
+// New creates a scanner running workers scans at a time, each bounded by timeout
+func New(engine *profiler.Wappalyze, workers int, timeout time.Duration) *Scanner {
+	return &Scanner{
+		engine:  engine,
+		workers: max(workers, 1),
+		timeout: timeout,
+		logger:  slog.Default(),
+	}
+}
+
+// Run scans the targets read from targets, one per line, and writes a record
+// per target to output. Blank lines and lines starting with # are ignored.
+// Targets listed by checkpoint, which may be nil, are skipped, and the others
+// are added to it once their record is written. Targets that fail are
+// completed too, with the error in their record. Targets interrupted by the
+// cancellation of ctx are neither written nor completed, and Run returns the
+// error of ctx.
+//
+// A crash between writing a record and adding its target to the checkpoint
+// scans that target again on resume, so output may list a target twice.
+func (s *Scanner) Run(ctx context.Context, targets io.Reader, output io.Writer, checkpoint *Checkpoint) (Summary, error) {
+	var summary Summary
+
+	// Scanning stops early if a record cannot be written
+	scanCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	jobs := make(chan string)
+	records := make(chan Record)
+	var wg sync.WaitGroup
+	for i := 0; i < s.workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for target := range jobs {
+				record := s.scan(scanCtx, target)
+				if scanCtx.Err() != nil {
+					continue
+				}
+				records <- record
+			}
+		}()
+	}
+
+	// Feed the workers while the records are written, so the list of
+	// targets is never held in memory
+	readErr := make(chan error, 1)
+	go func() {
+		defer close(jobs)
+		scanner := bufio.NewScanner(targets)
+		for scanner.Scan() {
+			target := strings.TrimSpace(scanner.Text())
+			if target == "" || strings.HasPrefix(target, "#") {
+				continue
+			}
+			if checkpoint.Done(target) {
+				summary.Skipped++
+				continue
+			}
+			select {
+			case jobs <- target:
+			case <-scanCtx.Done():
+				readErr <- scanCtx.Err()
+				return
+			}
+		}
+		readErr <- scanner.Err()
+	}()
+	go func() {
+		wg.Wait()
+		close(records)
+	}()
+
+	encoder := json.NewEncoder(output)
+	encoder.SetEscapeHTML(false)
+	var writeErr error
+	for record := range records {
+		// Keep draining the workers after a failed write, so they can exit
+		if writeErr != nil {
+			continue
+		}
+		if writeErr = encoder.Encode(record); writeErr != nil {
+			cancel()
+			continue
+		}
+		if writeErr = checkpoint.Complete(record.URL); writeErr != nil {
+			cancel()
+			continue
+		}
+		summary.Scanned++
+		if record.Error != "" {
+			summary.Failed++
+		}
+	}
+
+	// The reader has finished once the records channel is closed
+	err := <-readErr
+	if writeErr != nil {
+		return summary, writeErr
+	}
+	if err != nil && !errors.Is(err, context.Canceled) && !errors.Is(err, context.DeadlineExceeded) {
+		return summary, fmt.Errorf("could not read targets: %w", err)
+	}
+	return summary, ctx.Err()
+}
+
+// scan scans a single target
+func (s *Scanner) scan(ctx context.Context, target string) Record {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	result, err := s.engine.FingerprintURL(ctx, target)
+	record := Record{URL: target, ScannedAt: time.Now()}
+	if err != nil {
+		s.logger.DebugContext(ctx, "bulk scan failed", "url", target, "error", err)
+		record.Error = err.Error()
+		// Blocked and oversized pages are still analyzed from what was received
+		if !errors.Is(err, profiler.ErrBlockedByTarget) && !errors.Is(err, profiler.ErrBodyTooLarge) {
+			return record
+		}
+	}
+	record.Detections = result.GetDetections()
+	record.Partial = result.PartialResult()
+	record.Location = result.GetLocation()
+	return record
+}
+
+// Checkpoint is an append-only file of completed targets, one per line
+type Checkpoint struct {
+	file *os.File
+
+	mu        sync.Mutex
+	completed map[string]struct{}
+}
+
+// OpenCheckpoint reads the completed targets of the checkpoint at path, which
+// is created if it does not exist, and opens it to add more
+func OpenCheckpoint(path string) (*Checkpoint, error) {
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	checkpoint := &Checkpoint{file: file, completed: make(map[string]struct{})}
+	reader := bufio.NewReader(file)
+	var size int64
+	for {
+		line, err := reader.ReadString('\n')
+		if err == io.EOF {
+			// A crash may have left a partial last line, which is not a target
+			if line != "" {
+				err = file.Truncate(size)
+			} else {
+				err = nil
+			}
+			if err == nil {
+				return checkpoint, nil
+			}
+		}
+		if err != nil {
+			file.Close()
+			return nil, fmt.Errorf("could not read checkpoint %s: %w", path, err)
+		}
+		size += int64(len(line))
+		if target := strings.TrimSuffix(line, "\n"); target != "" {
+			checkpoint.completed[target] = struct{}{}
+		}
+	}
+}
+
+// Done reports whether target was completed. A nil checkpoint has no targets.
+func (c *Checkpoint) Done(target string) bool {
+	if c == nil {
+		return false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	_, ok := c.completed[target]
+	return ok
+}
+
+// Complete adds target to the checkpoint
+func (c *Checkpoint) Complete(target string) error {
+	if c == nil {
+		return nil
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.completed[target] = struct{}{}
+	_, err := io.WriteString(c.file, target+"\n")
+	return err
+}
+
+// Close closes the checkpoint file
+func (c *Checkpoint) Close() error {
+	if c == nil {
+		return nil
+	}
+	return c.file.Close()
+}
@@ -0,0 +1,109 @@
+package bulk
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/kavinsood/kitsune/internal/profiler"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunResumes(t *testing.T) {
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		w.Header().Set("Server", "nginx/1.25.3")
+		w.Write([]byte("<html><head><title>Example</title></head></html>"))
+	}))
+	defer server.Close()
+
+	engine, err := profiler.New(profiler.WithProfile(profiler.ProfileFast))
+	require.NoError(t, err, "could not create wappalyzer")
+	scanner := New(engine, 4, 5*time.Second)
+
+	targets := []string{server.URL + "/a", server.URL + "/b", server.URL + "/c", "http://127.0.0.1:1/unreachable"}
+	path := filepath.Join(t.TempDir(), "scan.checkpoint")
+
+	// A crash left a checkpoint with a completed target and a partial line
+	require.NoError(t, os.WriteFile(path, []byte(targets[0]+"\n"+targets[1][:10]), 0o644), "could not write checkpoint")
+	checkpoint, err := OpenCheckpoint(path)
+	require.NoError(t, err, "could not open checkpoint")
+
+	var output bytes.Buffer
+	input := "# targets\n" + strings.Join(targets, "\n") + "\n\n"
+	summary, err := scanner.Run(context.Background(), strings.NewReader(input), &output, checkpoint)
+	require.NoError(t, err, "could not run scan")
+	require.NoError(t, checkpoint.Close(), "could not close checkpoint")
+	require.Equal(t, Summary{Scanned: 3, Failed: 1, Skipped: 1}, summary, "wrong summary")
+	require.EqualValues(t, 2, requests.Load(), "completed target should not be scanned")
+
+	records := make(map[string]Record)
+	lines := bufio.NewScanner(&output)
+	for lines.Scan() {
+		var record Record
+		require.NoError(t, json.Unmarshal(lines.Bytes(), &record), "could not decode record")
+		records[record.URL] = record
+	}
+	require.Len(t, records, 3, "wrong records")
+	require.Contains(t, records[targets[1]].Detections, "Nginx", "missing detection")
+	require.NotEmpty(t, records[targets[3]].Error, "unreachable target should have an error")
+
+	// Resuming again has nothing left to scan
+	checkpoint, err = OpenCheckpoint(path)
+	require.NoError(t, err, "could not reopen checkpoint")
+	defer checkpoint.Close()
+	for _, target := range targets {
+		require.True(t, checkpoint.Done(target), "%s should be completed", target)
+	}
+	require.False(t, checkpoint.Done(targets[1][:10]), "partial line should not be completed")
+
+	summary, err = scanner.Run(context.Background(), strings.NewReader(input), &output, checkpoint)
+	require.NoError(t, err, "could not resume scan")
+	require.Equal(t, Summary{Skipped: 4}, summary, "wrong summary")
+}
+
+func TestRunBlocked(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Server", "nginx/1.25.3")
+		w.WriteHeader(http.StatusForbidden)
+		w.Write([]byte("<html><head><title>Forbidden</title></head></html>"))
+	}))
+	defer server.Close()
+
+	engine, err := profiler.New(profiler.WithProfile(profiler.ProfileFast))
+	require.NoError(t, err, "could not create wappalyzer")
+
+	var output bytes.Buffer
+	summary, err := New(engine, 1, 5*time.Second).Run(context.Background(), strings.NewReader(server.URL+"\n"), &output, nil)
+	require.NoError(t, err, "could not run scan")
+	require.Equal(t, Summary{Scanned: 1, Failed: 1}, summary, "wrong summary")
+
+	var record Record
+	require.NoError(t, json.Unmarshal(output.Bytes(), &record), "could not decode record")
+	require.Contains(t, record.Error, "blocked by target", "wrong error")
+	require.Contains(t, record.Detections, "Nginx", "blocked page should still be analyzed")
+}
+
+func TestRunCanceled(t *testing.T) {
+	engine, err := profiler.New(profiler.WithProfile(profiler.ProfileFast))
+	require.NoError(t, err, "could not create wappalyzer")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	var output bytes.Buffer
+	summary, err := New(engine, 2, time.Second).Run(ctx, strings.NewReader("https://example.com\n"), &output, nil)
+	require.ErrorIs(t, err, context.Canceled, "wrong error")
+	require.Zero(t, summary.Scanned, "nothing should be scanned")
+	require.Empty(t, output.String(), "nothing should be written")
+}
@@ -0,0 +1,214 @@
+// Package compress compresses HTTP responses with gzip or deflate, as
+// negotiated with the Accept-Encoding header of the request. Responses too
+// small to benefit, already encoded, or of types that do not compress, such
+// as images and event streams, are sent as is.
+package compress
+
+import (
+	"compress/flate"
+	"compress/gzip"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// MinSize is the size of the smallest response compressed
+const MinSize = 1024
+
+// compressibleTypes are the media types compressed. Event streams are left
+// out, so each event reaches the client as it is flushed.
+var compressibleTypes = []string{
+	"application/json",
+	"application/javascript",
+	"application/xml",
+	"image/svg+xml",
+	"text/css",
+	"text/html",
+	"text/javascript",
+	"text/plain",
+	"text/xml",
+}
+
+// Middleware compresses the responses of next
+func Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Vary", "Accept-Encoding")
+		encoding := negotiate(r.Header.Get("Accept-Encoding"))
+		if encoding == "" || r.Method == http.MethodHead {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		writer := &compressWriter{ResponseWriter: w, encoding: encoding}
+		defer writer.close()
+		next.ServeHTTP(writer, r)
+	})
+}
+
+// negotiate returns the encoding of the response to a request with the given
+// Accept-Encoding header, gzip or deflate, or an empty string if the client
+// accepts neither
+func negotiate(acceptEncoding string) string {
+	var best string
+	var bestQuality float64
+	for _, entry := range strings.Split(acceptEncoding, ",") {
+		coding, params, _ := strings.Cut(strings.TrimSpace(entry), ";")
+		quality := 1.0
+		if name, value, ok := strings.Cut(strings.TrimSpace(params), "="); ok && strings.TrimSpace(name) == "q" {
+			parsed, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+			if err != nil {
+				continue
+			}
+			quality = parsed
+		}
+
+		coding = strings.ToLower(strings.TrimSpace(coding))
+		if coding == "*" {
+			coding = "gzip"
+		}
+		if coding != "gzip" && coding != "deflate" {
+			continue
+		}
+		// gzip wins ties, as the more widely supported
+		if quality > bestQuality || (quality == bestQuality && coding == "gzip") {
+			best, bestQuality = coding, quality
+		}
+	}
+	return best
+}
+
+// compressWriter buffers the start of a response until it knows whether to
+// compress it: once MinSize bytes are written, or the response is flushed or
+// complete
+type compressWriter struct {
+	http.ResponseWriter
+	encoding string
+	status   int
+	buffer   []byte
+	// decided is set once the header is sent, encoder if compressing
+	decided bool
+	encoder io.WriteCloser
+}
+
+func (w *compressWriter) WriteHeader(status int) {
+	if w.decided || w.status != 0 {
+		return
+	}
+	// Informational responses are sent right away
+	if status < http.StatusOK {
+		w.ResponseWriter.WriteHeader(status)
+		return
+	}
+	w.status = status
+}
+
+func (w *compressWriter) Write(p []byte) (int, error) {
+	if !w.decided {
+		w.buffer = append(w.buffer, p...)
+		if !w.compressible() {
+			if err := w.decide(false); err != nil {
+				return 0, err
+			}
+		} else if len(w.buffer) >= MinSize {
+			if err := w.decide(true); err != nil {
+				return 0, err
+			}
+		}
+		return len(p), nil
+	}
+	if w.encoder != nil {
+		return w.encoder.Write(p)
+	}
+	return w.ResponseWriter.Write(p)
+}
+
+// Flush sends what was written so far, compressed if the response is
+func (w *compressWriter) Flush() {
+	if !w.decided {
+		w.decide(w.compressible() && len(w.buffer) >= MinSize)
+	}
+	if flusher, ok := w.encoder.(interface{ Flush() error }); ok {
+		flusher.Flush()
+	}
+	if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// Unwrap exposes the underlying writer to http.ResponseController
+func (w *compressWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// compressible reports whether the response is worth compressing, from its
+// status and header. Responses without a content type are sniffed, as
+// net/http would.
+func (w *compressWriter) compressible() bool {
+	if w.status == http.StatusNoContent || w.status == http.StatusNotModified {
+		return false
+	}
+	header := w.Header()
+	if header.Get("Content-Encoding") != "" {
+		return false
+	}
+	if _, ok := header["Content-Type"]; !ok && len(w.buffer) > 0 {
+		header.Set("Content-Type", http.DetectContentType(w.buffer))
+	}
+	mediaType, _, err := mime.ParseMediaType(header.Get("Content-Type"))
+	if err != nil {
+		return false
+	}
+	for _, compressible := range compressibleTypes {
+		if mediaType == compressible {
+			return true
+		}
+	}
+	return false
+}
+
+// decide sends the header, with the encoding if compressing, and the buffer
+func (w *compressWriter) decide(compress bool) error {
+	w.decided = true
+	if compress {
+		w.Header().Set("Content-Encoding", w.encoding)
+		w.Header().Del("Content-Length")
+		if w.encoding == "gzip" {
+			w.encoder = gzip.NewWriter(w.ResponseWriter)
+		} else {
+			w.encoder, _ = flate.NewWriter(w.ResponseWriter, flate.DefaultCompression)
+		}
+	}
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	w.ResponseWriter.WriteHeader(w.status)
+
+	buffer := w.buffer
+	w.buffer = nil
+	if len(buffer) == 0 {
+		return nil
+	}
+	var err error
+	if w.encoder != nil {
+		_, err = w.encoder.Write(buffer)
+	} else {
+		_, err = w.ResponseWriter.Write(buffer)
+	}
+	return err
+}
+
+// close completes the response
+func (w *compressWriter) close() {
+	if !w.decided {
+		// Responses that wrote nothing keep the status they set, if any
+		if w.status == 0 && len(w.buffer) == 0 {
+			return
+		}
+		w.decide(w.compressible() && len(w.buffer) >= MinSize)
+	}
+	if w.encoder != nil {
+		w.encoder.Close()
+	}
+}
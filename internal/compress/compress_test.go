@@ -0,0 +1,132 @@
+package compress
+
+import (
+	"compress/flate"
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNegotiate(t *testing.T) {
+	tests := []struct {
+		acceptEncoding string
+		want           string
+	}{
+		{acceptEncoding: "", want: ""},
+		{acceptEncoding: "gzip", want: "gzip"},
+		{acceptEncoding: "deflate", want: "deflate"},
+		{acceptEncoding: "gzip, deflate, br", want: "gzip"},
+		{acceptEncoding: "deflate, gzip;q=0.5", want: "deflate"},
+		{acceptEncoding: "gzip;q=0, deflate", want: "deflate"},
+		{acceptEncoding: "GZIP;q=1.0", want: "gzip"},
+		{acceptEncoding: "*", want: "gzip"},
+		{acceptEncoding: "br, identity", want: ""},
+		{acceptEncoding: "gzip;q=abc", want: ""},
+	}
+	for _, tt := range tests {
+		require.Equal(t, tt.want, negotiate(tt.acceptEncoding), "wrong encoding for %q", tt.acceptEncoding)
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+	large := strings.Repeat(`{"name": "Nginx"}`, 200)
+	handler := Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/large":
+			w.Header().Set("Content-Type", "application/json")
+			// Written in small parts, so the first ones are buffered
+			for i := 0; i < len(large); i += 100 {
+				io.WriteString(w, large[i:min(i+100, len(large))])
+			}
+		case "/small":
+			w.Header().Set("Content-Type", "application/json")
+			io.WriteString(w, `{"name": "Nginx"}`)
+		case "/png":
+			w.Header().Set("Content-Type", "image/png")
+			io.WriteString(w, large)
+		case "/sniffed":
+			io.WriteString(w, "<html>"+large)
+		case "/not-modified":
+			w.WriteHeader(http.StatusNotModified)
+		case "/error":
+			http.Error(w, large, http.StatusBadRequest)
+		}
+	}))
+
+	tests := []struct {
+		path           string
+		acceptEncoding string
+		wantStatus     int
+		wantEncoding   string
+		wantBody       string
+	}{
+		{path: "/large", acceptEncoding: "gzip", wantStatus: http.StatusOK, wantEncoding: "gzip", wantBody: large},
+		{path: "/large", acceptEncoding: "deflate", wantStatus: http.StatusOK, wantEncoding: "deflate", wantBody: large},
+		{path: "/large", acceptEncoding: "", wantStatus: http.StatusOK, wantBody: large},
+		{path: "/small", acceptEncoding: "gzip", wantStatus: http.StatusOK, wantBody: `{"name": "Nginx"}`},
+		{path: "/png", acceptEncoding: "gzip", wantStatus: http.StatusOK, wantBody: large},
+		{path: "/sniffed", acceptEncoding: "gzip", wantStatus: http.StatusOK, wantEncoding: "gzip", wantBody: "<html>" + large},
+		{path: "/not-modified", acceptEncoding: "gzip", wantStatus: http.StatusNotModified},
+		{path: "/error", acceptEncoding: "gzip", wantStatus: http.StatusBadRequest, wantEncoding: "gzip", wantBody: large + "\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path+" "+tt.acceptEncoding, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			req.Header.Set("Accept-Encoding", tt.acceptEncoding)
+			recorder := httptest.NewRecorder()
+			handler.ServeHTTP(recorder, req)
+
+			require.Equal(t, tt.wantStatus, recorder.Code, "wrong status")
+			require.Equal(t, "Accept-Encoding", recorder.Header().Get("Vary"), "missing vary header")
+			require.Equal(t, tt.wantEncoding, recorder.Header().Get("Content-Encoding"), "wrong encoding")
+
+			var body io.Reader = recorder.Body
+			switch tt.wantEncoding {
+			case "gzip":
+				reader, err := gzip.NewReader(body)
+				require.NoError(t, err, "could not read gzip body")
+				body = reader
+			case "deflate":
+				body = flate.NewReader(body)
+			}
+			data, err := io.ReadAll(body)
+			require.NoError(t, err, "could not read body")
+			require.Equal(t, tt.wantBody, string(data), "wrong body")
+		})
+	}
+}
+
+func TestMiddlewareFlush(t *testing.T) {
+	flushed := make(chan struct{})
+	server := httptest.NewServer(Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain")
+		io.WriteString(w, "first")
+		w.(http.Flusher).Flush()
+		<-flushed
+		io.WriteString(w, strings.Repeat("second", 500))
+	})))
+	defer server.Close()
+
+	req, err := http.NewRequest(http.MethodGet, server.URL, nil)
+	require.NoError(t, err, "could not create request")
+	req.Header.Set("Accept-Encoding", "gzip")
+	resp, err := http.DefaultTransport.RoundTrip(req)
+	require.NoError(t, err, "could not send request")
+	defer resp.Body.Close()
+
+	// The flushed part reaches the client before the handler completes
+	first := make([]byte, len("first"))
+	_, err = io.ReadFull(resp.Body, first)
+	require.NoError(t, err, "could not read flushed part")
+	require.Equal(t, "first", string(first), "wrong flushed part")
+	close(flushed)
+
+	rest, err := io.ReadAll(resp.Body)
+	require.NoError(t, err, "could not read body")
+	require.Equal(t, strings.Repeat("second", 500), string(rest), "wrong body")
+}
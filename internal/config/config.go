@@ -0,0 +1,517 @@
+// Package config holds the settings of the API server. They are read from a
+// YAML file, then overridden by environment variables and command line flags,
+// in that order. Every setting has a path in the file, such as scan.profile,
+// which is also the name of its flag, and most have an environment variable.
+package config
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/url"
+	"os"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/kavinsood/kitsune/internal/apikey"
+	"github.com/kavinsood/kitsune/internal/icons"
+	"github.com/kavinsood/kitsune/internal/policy"
+	"github.com/kavinsood/kitsune/internal/profiler"
+	"github.com/kavinsood/kitsune/internal/resolve"
+	"github.com/kavinsood/kitsune/internal/webhook"
+	"gopkg.in/yaml.v3"
+)
+
+// Config is the configuration of the API server
+type Config struct {
+	Server Server `yaml:"server"`
+	Log    Log    `yaml:"log"`
+	Scan   Scan   `yaml:"scan"`
+	Policy Policy `yaml:"policy"`
+	Auth   Auth   `yaml:"auth"`
+	Cache  Cache  `yaml:"cache"`
+	Icons  Icons  `yaml:"icons"`
+	Sinks  Sinks  `yaml:"sinks"`
+}
+
+// Server configures the listener and the shutdown
+type Server struct {
+	Port         int           `yaml:"port" env:"PORT" help:"port the server listens on"`
+	DrainTimeout time.Duration `yaml:"drain_timeout" env:"KITSUNE_DRAIN_TIMEOUT" help:"time allowed for in-flight analyses to finish on shutdown"`
+	MaxAnalyses  int           `yaml:"max_analyses" env:"KITSUNE_MAX_ANALYSES" help:"analyses running at once, 0 for no limit"`
+	QueueSize    int           `yaml:"queue_size" env:"KITSUNE_QUEUE_SIZE" help:"requests waiting for an analysis slot before 503 responses"`
+	QueueTimeout time.Duration `yaml:"queue_timeout" env:"KITSUNE_QUEUE_TIMEOUT" help:"time a request waits for an analysis slot before a 503 response"`
+}
+
+// Log configures the server logs
+type Log struct {
+	Level  string `yaml:"level" env:"KITSUNE_LOG_LEVEL" help:"debug, info, warn or error"`
+	Format string `yaml:"format" env:"KITSUNE_LOG_FORMAT" help:"json or text"`
+}
+
+// Scan configures the analyses, as the options of the profiler
+type Scan struct {
+	Profile          string        `yaml:"profile" env:"KITSUNE_PROFILE" help:"profile of the requests without one: fast, standard or deep"`
+	TLSPolicy        string        `yaml:"tls_policy" env:"KITSUNE_TLS_POLICY" help:"handling of invalid certificates: log, strict or insecure"`
+	IPFamily         string        `yaml:"ip_family" env:"KITSUNE_IP_FAMILY" help:"only fetch targets over ipv4 or ipv6"`
+	Disable          []string      `yaml:"disable" env:"KITSUNE_DISABLE" help:"vectors skipped whatever the profile"`
+	Budget           time.Duration `yaml:"budget" env:"KITSUNE_BUDGET" help:"time bound of each analysis, 0 for none"`
+	MatchWorkers     int           `yaml:"match_workers" env:"KITSUNE_MATCH_WORKERS" help:"goroutines matching each analysis, 0 for GOMAXPROCS"`
+	ProbePorts       []int         `yaml:"probe_ports" env:"KITSUNE_PROBE_PORTS" help:"alternate ports probed on each target"`
+	Fingerprints     string        `yaml:"fingerprints" env:"KITSUNE_FINGERPRINTS" help:"fingerprints file loaded over the embedded data"`
+	Overlays         []string      `yaml:"overlays" env:"KITSUNE_OVERLAYS" help:"fingerprint overlay files"`
+	OutboundRate     float64       `yaml:"outbound_rate" env:"KITSUNE_OUTBOUND_RATE" help:"requests per second to all hosts, 0 for no limit"`
+	OutboundHostRate float64       `yaml:"outbound_host_rate" env:"KITSUNE_OUTBOUND_HOST_RATE" help:"requests per second to each host, 0 for no limit"`
+	UserAgent        string        `yaml:"user_agent" env:"KITSUNE_USER_AGENT" help:"User-Agent header of the requests, a desktop Chrome by default"`
+	ContactURL       string        `yaml:"contact_url" env:"KITSUNE_CONTACT_URL" help:"page about the scans, pointed to by an identifying Kitsune User-Agent"`
+	Polite           bool          `yaml:"polite" env:"KITSUNE_POLITE" help:"skip the targets whose robots.txt disallows their page"`
+	SchemeFallback   bool          `yaml:"scheme_fallback" env:"KITSUNE_SCHEME_FALLBACK" help:"retry a failed fetch over the other scheme, plain HTTP for HTTPS"`
+	// Sets are the fingerprint sets requests may select by name instead of
+	// the default one, which is made of the fingerprints and overlays above.
+	// They are only read from the file.
+	Sets map[string]FingerprintSet `yaml:"sets"`
+}
+
+// DefaultSet is the name of the fingerprint set of the requests selecting none
+const DefaultSet = "default"
+
+// FingerprintSet is a named set of fingerprints, made of the embedded data
+// extended by a fingerprints file and overlays
+type FingerprintSet struct {
+	Fingerprints string   `yaml:"fingerprints"`
+	Overlays     []string `yaml:"overlays"`
+}
+
+// Policy restricts the targets that may be scanned, with the rules of a JSON
+// policy file or the rules given inline, not both
+type Policy struct {
+	File          string `yaml:"file" env:"KITSUNE_POLICY_FILE" help:"JSON target policy file"`
+	policy.Config `yaml:",inline"`
+}
+
+// Auth configures the API keys required by the server, if any, and the keys
+// of the admin endpoints, which are disabled without them
+type Auth struct {
+	APIKeys    []string `yaml:"api_keys" env:"KITSUNE_API_KEYS" help:"API keys, as key[:rate-per-minute[:daily-quota]]"`
+	AdminKeys  []string `yaml:"admin_keys" env:"KITSUNE_ADMIN_KEYS" help:"keys of the admin endpoints"`
+	RateLimit  int      `yaml:"rate_limit" env:"KITSUNE_RATE_LIMIT" help:"requests per minute of the keys without their own limit, 0 for no limit"`
+	DailyQuota int      `yaml:"daily_quota" env:"KITSUNE_DAILY_QUOTA" help:"requests per day of the keys without their own quota, 0 for no limit"`
+}
+
+// Cache configures the caches of the engine
+type Cache struct {
+	Results   int           `yaml:"results" env:"KITSUNE_RESULT_CACHE" help:"analyses kept in the result cache, 0 to disable it"`
+	ResultTTL time.Duration `yaml:"result_ttl" env:"KITSUNE_RESULT_CACHE_TTL" help:"time cached analyses are served for"`
+	DNS       int           `yaml:"dns" env:"KITSUNE_DNS_CACHE" help:"DNS answers cached for their TTL, 0 to disable the cache"`
+}
+
+// Icons configures where technology icons are read from
+type Icons struct {
+	Dir string `yaml:"dir" env:"KITSUNE_ICONS_DIR" help:"directory of the icon files"`
+	URL string `yaml:"url" env:"KITSUNE_ICONS_URL" help:"base URL missing icons are fetched from, none to disable"`
+}
+
+// Sinks configures the systems completed analyses are published to
+type Sinks struct {
+	Kafka    Kafka     `yaml:"kafka"`
+	NATS     NATS      `yaml:"nats"`
+	Webhooks []Webhook `yaml:"webhooks"`
+}
+
+// Kafka configures the Kafka sink, enabled by its brokers
+type Kafka struct {
+	Brokers []string `yaml:"brokers" env:"KITSUNE_KAFKA_BROKERS" help:"Kafka brokers analyses are published to"`
+	Topic   string   `yaml:"topic" env:"KITSUNE_KAFKA_TOPIC" help:"Kafka topic"`
+}
+
+// NATS configures the NATS sink, enabled by its URL
+type NATS struct {
+	URL     string `yaml:"url" env:"KITSUNE_NATS_URL" help:"NATS server analyses are published to"`
+	Subject string `yaml:"subject" env:"KITSUNE_NATS_SUBJECT" help:"NATS subject"`
+}
+
+// Webhook is an endpoint completed analyses are posted to. Each has its own
+// secret, so that a receiver cannot sign posts for the others, and its own
+// retry policy, whose settings left out or 0 keep the default. Webhooks are
+// only read from the file.
+type Webhook struct {
+	URL string `yaml:"url"`
+	// Secret keys the HMAC-SHA256 signature of the posts, unsigned without one
+	Secret string `yaml:"secret"`
+	// MaxAttempts is the number of attempts per post, the first one included
+	MaxAttempts int `yaml:"max_attempts"`
+	// InitialBackoff is the delay before the first retry, doubled on every retry
+	InitialBackoff time.Duration `yaml:"initial_backoff"`
+	// MaxBackoff is the longest delay between attempts
+	MaxBackoff time.Duration `yaml:"max_backoff"`
+}
+
+// RetryPolicy returns the retry policy of the webhook, with the default
+// policy for the settings left out
+func (w Webhook) RetryPolicy() webhook.RetryPolicy {
+	retry := webhook.DefaultRetryPolicy()
+	if w.MaxAttempts > 0 {
+		retry.MaxAttempts = w.MaxAttempts
+	}
+	if w.InitialBackoff > 0 {
+		retry.InitialBackoff = w.InitialBackoff
+	}
+	if w.MaxBackoff > 0 {
+		retry.MaxBackoff = w.MaxBackoff
+	}
+	return retry
+}
+
+// Default returns the configuration of a server configured with nothing
+func Default() Config {
+	return Config{
+		Server: Server{Port: 8080, DrainTimeout: 30 * time.Second, QueueSize: 100, QueueTimeout: 30 * time.Second},
+		Log:    Log{Level: "info", Format: "json"},
+		Scan:   Scan{Profile: string(profiler.ProfileStandard), TLSPolicy: string(profiler.TLSPolicyLog)},
+		Auth:   Auth{RateLimit: 60},
+		Cache:  Cache{ResultTTL: time.Hour, DNS: resolve.DefaultCacheSize},
+		Icons:  Icons{URL: icons.DefaultBaseURL},
+		Sinks: Sinks{
+			Kafka: Kafka{Topic: "kitsune-scans"},
+			NATS:  NATS{Subject: "kitsune.scans"},
+		},
+	}
+}
+
+// Load reads the configuration at path over c. Settings the file leaves out
+// keep their value, and unknown settings are an error.
+func (c *Config) Load(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("could not read config: %w", err)
+	}
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(c); err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("could not parse config %s: %w", path, err)
+	}
+	return nil
+}
+
+// ApplyEnv overrides c with the environment variables of its settings that
+// getenv returns a value for. Lists are comma-separated.
+func (c *Config) ApplyEnv(getenv func(string) string) error {
+	var errs []error
+	for _, setting := range c.settings() {
+		if setting.env == "" {
+			continue
+		}
+		if value := getenv(setting.env); value != "" {
+			if err := setting.set(value); err != nil {
+				errs = append(errs, fmt.Errorf("invalid %s: %w", setting.env, err))
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// RegisterFlags defines a flag on fs for every setting, named by its path. The
+// returned function overrides a configuration with the flags set once fs is
+// parsed.
+func RegisterFlags(fs *flag.FlagSet) func(*Config) error {
+	defaults := Default()
+	for _, setting := range defaults.settings() {
+		usage := setting.help
+		if setting.env != "" {
+			usage += " (env " + setting.env + ")"
+		}
+		fs.String(setting.path, setting.String(), usage)
+	}
+	return func(c *Config) error {
+		settings := make(map[string]setting)
+		for _, setting := range c.settings() {
+			settings[setting.path] = setting
+		}
+		var errs []error
+		fs.Visit(func(f *flag.Flag) {
+			setting, ok := settings[f.Name]
+			if !ok {
+				return
+			}
+			if err := setting.set(f.Value.String()); err != nil {
+				errs = append(errs, fmt.Errorf("invalid -%s: %w", f.Name, err))
+			}
+		})
+		return errors.Join(errs...)
+	}
+}
+
+// Validate checks every setting, returning all the invalid ones
+func (c *Config) Validate() error {
+	var errs []error
+	check := func(path string, err error) {
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", path, err))
+		}
+	}
+	positive := func(path string, value float64) {
+		if value < 0 {
+			check(path, errors.New("must not be negative"))
+		}
+	}
+
+	if c.Server.Port < 1 || c.Server.Port > 65535 {
+		check("server.port", fmt.Errorf("invalid port %d", c.Server.Port))
+	}
+	positive("server.drain_timeout", float64(c.Server.DrainTimeout))
+	positive("server.max_analyses", float64(c.Server.MaxAnalyses))
+	positive("server.queue_size", float64(c.Server.QueueSize))
+	if c.Server.MaxAnalyses > 0 && c.Server.QueueSize > 0 && c.Server.QueueTimeout <= 0 {
+		check("server.queue_timeout", errors.New("must be positive when requests are queued"))
+	}
+
+	var level slog.Level
+	check("log.level", level.UnmarshalText([]byte(c.Log.Level)))
+	if c.Log.Format != "json" && c.Log.Format != "text" {
+		check("log.format", fmt.Errorf("unknown format %q, expected json or text", c.Log.Format))
+	}
+
+	_, err := profiler.ParseProfile(c.Scan.Profile)
+	check("scan.profile", err)
+	_, err = profiler.ParseTLSPolicy(c.Scan.TLSPolicy)
+	check("scan.tls_policy", err)
+	_, err = profiler.ParseIPFamily(c.Scan.IPFamily)
+	check("scan.ip_family", err)
+	_, err = profiler.ParseVectors(strings.Join(c.Scan.Disable, ","))
+	check("scan.disable", err)
+	positive("scan.budget", float64(c.Scan.Budget))
+	positive("scan.match_workers", float64(c.Scan.MatchWorkers))
+	ports := make([]string, 0, len(c.Scan.ProbePorts))
+	for _, port := range c.Scan.ProbePorts {
+		ports = append(ports, strconv.Itoa(port))
+	}
+	_, err = profiler.ParsePorts(strings.Join(ports, ","))
+	check("scan.probe_ports", err)
+	positive("scan.outbound_rate", c.Scan.OutboundRate)
+	positive("scan.outbound_host_rate", c.Scan.OutboundHostRate)
+	if c.Scan.ContactURL != "" {
+		if c.Scan.UserAgent != "" {
+			check("scan.contact_url", errors.New("the contact URL is sent in the User-Agent, so user_agent must be empty"))
+		} else if parsed, err := url.Parse(c.Scan.ContactURL); err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+			check("scan.contact_url", fmt.Errorf("invalid URL %q, expected an http or https URL", c.Scan.ContactURL))
+		}
+	}
+	for name, set := range c.Scan.Sets {
+		switch {
+		case name == DefaultSet:
+			check("scan.sets", fmt.Errorf("set name %q is reserved for the fingerprints and overlays of scan", DefaultSet))
+		case !setName.MatchString(name):
+			check("scan.sets", fmt.Errorf("invalid set name %q, expected letters, digits, dots, dashes and underscores", name))
+		case set.Fingerprints == "" && len(set.Overlays) == 0:
+			check("scan.sets."+name, errors.New("set has neither fingerprints nor overlays"))
+		}
+	}
+
+	if c.Policy.File != "" {
+		if !reflect.DeepEqual(c.Policy.Config, policy.Config{}) {
+			check("policy", errors.New("rules are given both inline and in a file"))
+		}
+		_, err = policy.Load(c.Policy.File)
+		check("policy.file", err)
+	} else {
+		_, err = policy.New(c.Policy.Config)
+		check("policy", err)
+	}
+
+	positive("auth.rate_limit", float64(c.Auth.RateLimit))
+	positive("auth.daily_quota", float64(c.Auth.DailyQuota))
+	_, err = apikey.ParseKeys(strings.Join(c.Auth.APIKeys, ","), c.Auth.RateLimit, c.Auth.DailyQuota)
+	check("auth.api_keys", err)
+	for _, key := range c.Auth.AdminKeys {
+		if strings.TrimSpace(key) == "" {
+			check("auth.admin_keys", errors.New("keys must not be empty"))
+			break
+		}
+	}
+
+	positive("cache.results", float64(c.Cache.Results))
+	if c.Cache.Results > 0 && c.Cache.ResultTTL <= 0 {
+		check("cache.result_ttl", errors.New("must be positive when the result cache is enabled"))
+	}
+	positive("cache.dns", float64(c.Cache.DNS))
+
+	if c.Icons.URL != "" && c.Icons.URL != "none" {
+		if parsed, err := url.Parse(c.Icons.URL); err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") {
+			check("icons.url", fmt.Errorf("invalid URL %q, expected an http or https URL or none", c.Icons.URL))
+		}
+	}
+
+	if len(c.Sinks.Kafka.Brokers) > 0 && c.Sinks.Kafka.Topic == "" {
+		check("sinks.kafka.topic", errors.New("required with brokers"))
+	}
+	if c.Sinks.NATS.URL != "" && c.Sinks.NATS.Subject == "" {
+		check("sinks.nats.subject", errors.New("required with a URL"))
+	}
+	for i, hook := range c.Sinks.Webhooks {
+		path := fmt.Sprintf("sinks.webhooks[%d]", i)
+		if parsed, err := url.Parse(hook.URL); err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+			check(path+".url", fmt.Errorf("invalid URL %q, expected an http or https URL", hook.URL))
+		}
+		positive(path+".max_attempts", float64(hook.MaxAttempts))
+		positive(path+".initial_backoff", float64(hook.InitialBackoff))
+		positive(path+".max_backoff", float64(hook.MaxBackoff))
+	}
+	return errors.Join(errs...)
+}
+
+// setName matches the valid names of fingerprint sets
+var setName = regexp.MustCompile(`^[A-Za-z0-9._-]+$`)
+
+// Marshal returns c as YAML, with the keys and secrets redacted
+func (c Config) Marshal() ([]byte, error) {
+	redacted := c
+	redacted.Sinks.Webhooks = nil
+	for _, hook := range c.Sinks.Webhooks {
+		if hook.Secret != "" {
+			hook.Secret = "REDACTED"
+		}
+		redacted.Sinks.Webhooks = append(redacted.Sinks.Webhooks, hook)
+	}
+	redacted.Auth.AdminKeys = make([]string, len(c.Auth.AdminKeys))
+	for i := range c.Auth.AdminKeys {
+		redacted.Auth.AdminKeys[i] = "REDACTED"
+	}
+	redacted.Auth.APIKeys = make([]string, len(c.Auth.APIKeys))
+	for i, entry := range c.Auth.APIKeys {
+		// Only the limits that follow the key are kept
+		_, limits, ok := strings.Cut(entry, ":")
+		redacted.Auth.APIKeys[i] = "REDACTED"
+		if ok {
+			redacted.Auth.APIKeys[i] += ":" + limits
+		}
+	}
+	return yaml.Marshal(redacted)
+}
+
+// setting is a setting of a configuration, for reading it from environment
+// variables and flags
+type setting struct {
+	path  string
+	env   string
+	help  string
+	value reflect.Value
+}
+
+// settings returns the settings of c, in the order of the file
+func (c *Config) settings() []setting {
+	return collectSettings(reflect.ValueOf(c).Elem(), "")
+}
+
+// collectSettings returns the settings of the struct value, under prefix
+func collectSettings(value reflect.Value, prefix string) []setting {
+	var settings []setting
+	for i := 0; i < value.NumField(); i++ {
+		field := value.Type().Field(i)
+		name, options, _ := strings.Cut(field.Tag.Get("yaml"), ",")
+		if field.Type.Kind() == reflect.Map || (field.Type.Kind() == reflect.Slice && field.Type.Elem().Kind() == reflect.Struct) {
+			// Maps and lists of structs, such as the fingerprint sets and the
+			// webhooks, are only read from the file
+			continue
+		}
+		if field.Type.Kind() == reflect.Struct {
+			nested := prefix + name + "."
+			if options == "inline" {
+				nested = prefix
+			}
+			settings = append(settings, collectSettings(value.Field(i), nested)...)
+			continue
+		}
+		help := field.Tag.Get("help")
+		if help == "" {
+			// Settings of inlined structs, such as the policy rules, are named by their path
+			help = strings.ReplaceAll(name, "_", " ")
+		}
+		settings = append(settings, setting{
+			path:  prefix + name,
+			env:   field.Tag.Get("env"),
+			help:  help,
+			value: value.Field(i),
+		})
+	}
+	return settings
+}
+
+// set parses value into the setting
+func (s setting) set(value string) error {
+	switch target := s.value.Addr().Interface().(type) {
+	case *string:
+		*target = value
+	case *int:
+		parsed, err := strconv.Atoi(strings.TrimSpace(value))
+		if err != nil {
+			return fmt.Errorf("not a number: %q", value)
+		}
+		*target = parsed
+	case *bool:
+		parsed, err := strconv.ParseBool(strings.TrimSpace(value))
+		if err != nil {
+			return fmt.Errorf("not a boolean: %q", value)
+		}
+		*target = parsed
+	case *float64:
+		parsed, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+		if err != nil {
+			return fmt.Errorf("not a number: %q", value)
+		}
+		*target = parsed
+	case *time.Duration:
+		parsed, err := time.ParseDuration(strings.TrimSpace(value))
+		if err != nil {
+			return err
+		}
+		*target = parsed
+	case *[]string:
+		*target = splitList(value)
+	case *[]int:
+		var numbers []int
+		for _, item := range splitList(value) {
+			number, err := strconv.Atoi(item)
+			if err != nil {
+				return fmt.Errorf("not a number: %q", item)
+			}
+			numbers = append(numbers, number)
+		}
+		*target = numbers
+	default:
+		return fmt.Errorf("unsupported setting type %s", s.value.Type())
+	}
+	return nil
+}
+
+// String returns the value of the setting as it would be set
+func (s setting) String() string {
+	switch value := s.value.Interface().(type) {
+	case []string:
+		return strings.Join(value, ",")
+	case []int:
+		items := make([]string, 0, len(value))
+		for _, item := range value {
+			items = append(items, strconv.Itoa(item))
+		}
+		return strings.Join(items, ",")
+	default:
+		return fmt.Sprint(value)
+	}
+}
+
+// splitList splits a comma-separated list, dropping empty items
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
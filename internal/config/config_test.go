@@ -0,0 +1,188 @@
+package config
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/kavinsood/kitsune/internal/webhook"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+)
+
+// writeConfig writes data to a config file, returning its path
+func writeConfig(t *testing.T, data string) string {
+	path := filepath.Join(t.TempDir(), "kitsune.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(data), 0o600), "could not write config")
+	return path
+}
+
+func TestDefaultIsValid(t *testing.T) {
+	config := Default()
+	require.NoError(t, config.Validate(), "the defaults should be valid")
+}
+
+func TestPrecedence(t *testing.T) {
+	path := writeConfig(t, `
+server:
+  port: 9000
+  drain_timeout: 10s
+scan:
+  profile: fast
+  probe_ports: [8443, 9443]
+  outbound_rate: 2.5
+  sets:
+    internal-apps:
+      fingerprints: internal.json
+      overlays: [intranet.json]
+policy:
+  deny_domains: [gov]
+auth:
+  api_keys: ["file-key"]
+`)
+	env := map[string]string{
+		"PORT":            "9001",
+		"KITSUNE_PROFILE": "deep",
+		"KITSUNE_DISABLE": "dns, tls",
+		"KITSUNE_POLITE":  "true",
+	}
+	fs := flag.NewFlagSet("kitsune-api", flag.ContinueOnError)
+	applyFlags := RegisterFlags(fs)
+	require.NoError(t, fs.Parse([]string{"-server.port", "9002", "-policy.allow_schemes", "https"}), "could not parse flags")
+
+	config := Default()
+	require.NoError(t, config.Load(path), "could not load config")
+	require.NoError(t, config.ApplyEnv(func(name string) string { return env[name] }), "could not apply env")
+	require.NoError(t, applyFlags(&config), "could not apply flags")
+	require.NoError(t, config.Validate(), "config should be valid")
+
+	require.Equal(t, 9002, config.Server.Port, "flags should override the env")
+	require.Equal(t, 10*time.Second, config.Server.DrainTimeout, "wrong value from the file")
+	require.Equal(t, "deep", config.Scan.Profile, "the env should override the file")
+	require.Equal(t, []string{"dns", "tls"}, config.Scan.Disable, "wrong list from the env")
+	require.Equal(t, []int{8443, 9443}, config.Scan.ProbePorts, "wrong list from the file")
+	require.Equal(t, 2.5, config.Scan.OutboundRate, "wrong rate from the file")
+	require.True(t, config.Scan.Polite, "wrong boolean from the env")
+	require.Equal(t, map[string]FingerprintSet{"internal-apps": {Fingerprints: "internal.json", Overlays: []string{"intranet.json"}}}, config.Scan.Sets, "wrong sets from the file")
+	require.Equal(t, []string{"gov"}, config.Policy.DenyDomains, "wrong inline policy")
+	require.Equal(t, []string{"https"}, config.Policy.AllowSchemes, "wrong inline policy from the flags")
+	require.Equal(t, "log", config.Scan.TLSPolicy, "settings left out should keep their default")
+	require.Equal(t, 60, config.Auth.RateLimit, "settings left out should keep their default")
+	require.False(t, config.Scan.SchemeFallback, "the scheme fallback should be opt-in")
+}
+
+func TestLoadErrors(t *testing.T) {
+	config := Default()
+	err := config.Load(writeConfig(t, "scan:\n  profil: fast\n"))
+	require.ErrorContains(t, err, "field profil not found", "unknown settings should be an error")
+
+	config = Default()
+	require.Error(t, config.Load(writeConfig(t, "server:\n  port: eighty\n")), "invalid values should be an error")
+
+	config = Default()
+	require.NoError(t, config.Load(writeConfig(t, "")), "empty files should be valid")
+	require.Equal(t, Default(), config, "empty files should keep the defaults")
+}
+
+func TestApplyEnvErrors(t *testing.T) {
+	env := map[string]string{"KITSUNE_BUDGET": "ten", "KITSUNE_PROBE_PORTS": "443,https"}
+	config := Default()
+	err := config.ApplyEnv(func(name string) string { return env[name] })
+	require.ErrorContains(t, err, "invalid KITSUNE_BUDGET", "wrong error")
+	require.ErrorContains(t, err, "invalid KITSUNE_PROBE_PORTS", "every invalid variable should be reported")
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(*Config)
+		want   string
+	}{
+		{name: "port", modify: func(c *Config) { c.Server.Port = 0 }, want: "server.port"},
+		{name: "queue size", modify: func(c *Config) { c.Server.QueueSize = -1 }, want: "server.queue_size"},
+		{name: "queue timeout", modify: func(c *Config) { c.Server.MaxAnalyses, c.Server.QueueTimeout = 4, 0 }, want: "server.queue_timeout"},
+		{name: "log level", modify: func(c *Config) { c.Log.Level = "verbose" }, want: "log.level"},
+		{name: "log format", modify: func(c *Config) { c.Log.Format = "xml" }, want: "log.format"},
+		{name: "profile", modify: func(c *Config) { c.Scan.Profile = "thorough" }, want: "scan.profile"},
+		{name: "vector", modify: func(c *Config) { c.Scan.Disable = []string{"smtp"} }, want: "scan.disable"},
+		{name: "probe port", modify: func(c *Config) { c.Scan.ProbePorts = []int{70000} }, want: "scan.probe_ports"},
+		{name: "rate", modify: func(c *Config) { c.Scan.OutboundRate = -1 }, want: "scan.outbound_rate"},
+		{name: "contact url", modify: func(c *Config) { c.Scan.ContactURL = "scans.example.com" }, want: "scan.contact_url"},
+		{name: "contact url and user agent", modify: func(c *Config) { c.Scan.ContactURL, c.Scan.UserAgent = "https://scans.example.com", "Scanner/1.0" }, want: "user_agent must be empty"},
+		{name: "default set", modify: func(c *Config) { c.Scan.Sets = map[string]FingerprintSet{"default": {Fingerprints: "custom.json"}} }, want: "reserved"},
+		{name: "set name", modify: func(c *Config) { c.Scan.Sets = map[string]FingerprintSet{"a b": {Fingerprints: "a.json"}} }, want: "invalid set name"},
+		{name: "empty set", modify: func(c *Config) { c.Scan.Sets = map[string]FingerprintSet{"internal": {}} }, want: "scan.sets.internal"},
+		{name: "cidr", modify: func(c *Config) { c.Policy.DenyCIDRs = []string{"10.0.0.0/33"} }, want: "policy"},
+		{
+			name: "policy file and rules",
+			modify: func(c *Config) {
+				c.Policy.File = writeConfig(t, "{}")
+				c.Policy.DenyDomains = []string{"gov"}
+			},
+			want: "both inline and in a file",
+		},
+		{name: "api key", modify: func(c *Config) { c.Auth.APIKeys = []string{"key:fast"} }, want: "auth.api_keys"},
+		{name: "result ttl", modify: func(c *Config) { c.Cache.Results, c.Cache.ResultTTL = 100, 0 }, want: "cache.result_ttl"},
+		{name: "icons url", modify: func(c *Config) { c.Icons.URL = "ftp://icons" }, want: "icons.url"},
+		{name: "webhook url", modify: func(c *Config) { c.Sinks.Webhooks = []Webhook{{URL: "hooks.example.com"}} }, want: "sinks.webhooks[0].url"},
+		{name: "webhook attempts", modify: func(c *Config) { c.Sinks.Webhooks = []Webhook{{URL: "https://hooks.example.com", MaxAttempts: -1}} }, want: "sinks.webhooks[0].max_attempts"},
+		{name: "kafka topic", modify: func(c *Config) { c.Sinks.Kafka.Brokers, c.Sinks.Kafka.Topic = []string{"kafka:9092"}, "" }, want: "sinks.kafka.topic"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := Default()
+			tt.modify(&config)
+			require.ErrorContains(t, config.Validate(), tt.want, "wrong error")
+		})
+	}
+}
+
+func TestMarshal(t *testing.T) {
+	config := Default()
+	config.Auth.APIKeys = []string{"key-one", "key-two:10:1000"}
+	config.Auth.AdminKeys = []string{"admin-key"}
+	config.Sinks.Webhooks = []Webhook{{URL: "https://a.example.com", Secret: "hmac-key"}, {URL: "https://b.example.com"}}
+	data, err := config.Marshal()
+	require.NoError(t, err, "could not marshal config")
+	for _, secret := range []string{"key-one", "key-two", "admin-key", "hmac-key"} {
+		require.NotContains(t, string(data), secret, "keys and secrets should be redacted")
+	}
+	require.Equal(t, []string{"key-one", "key-two:10:1000"}, config.Auth.APIKeys, "the config should not be modified")
+
+	var printed Config
+	require.NoError(t, yaml.Unmarshal(data, &printed), "the printed config should load")
+	require.Equal(t, []string{"REDACTED", "REDACTED:10:1000"}, printed.Auth.APIKeys, "the limits of the keys should be kept")
+	require.Equal(t, []string{"REDACTED"}, printed.Auth.AdminKeys, "admin keys should be redacted")
+	require.Equal(t, config.Server, printed.Server, "wrong printed config")
+	require.Equal(t, config.Cache, printed.Cache, "wrong printed config")
+	require.Equal(t, []Webhook{{URL: "https://a.example.com", Secret: "REDACTED"}, {URL: "https://b.example.com"}}, printed.Sinks.Webhooks, "webhook secrets should be redacted")
+	require.Equal(t, "hmac-key", config.Sinks.Webhooks[0].Secret, "the config should not be modified")
+}
+
+func TestWebhooks(t *testing.T) {
+	path := writeConfig(t, `
+sinks:
+  webhooks:
+    - url: https://a.example.com/hook
+      secret: key-a
+      max_attempts: 3
+    - url: https://b.example.com/hook
+      secret: key-b
+      initial_backoff: 1s
+      max_backoff: 10s
+`)
+	config := Default()
+	require.NoError(t, config.Load(path), "could not load config")
+	require.NoError(t, config.Validate(), "config should be valid")
+
+	hooks := config.Sinks.Webhooks
+	require.Len(t, hooks, 2, "wrong webhooks")
+	require.Equal(t, "key-a", hooks[0].Secret, "each webhook should have its own secret")
+	require.Equal(t, "key-b", hooks[1].Secret, "each webhook should have its own secret")
+
+	defaults := webhook.DefaultRetryPolicy()
+	require.Equal(t, webhook.RetryPolicy{MaxAttempts: 3, InitialBackoff: defaults.InitialBackoff, MaxBackoff: defaults.MaxBackoff}, hooks[0].RetryPolicy(), "wrong retry policy")
+	require.Equal(t, webhook.RetryPolicy{MaxAttempts: defaults.MaxAttempts, InitialBackoff: time.Second, MaxBackoff: 10 * time.Second}, hooks[1].RetryPolicy(), "wrong retry policy")
+}
@@ -0,0 +1,131 @@
+// Package corpus runs the fingerprints against saved responses of known sites,
+// so that a data refresh which breaks real-world detection is caught before it
+// ships.
+//
+// A corpus is a directory with a JSON file per site, such as wordpress.json:
+//
+//	{
+//	    "url": "https://example.com/",
+//	    "headers": {"Server": ["nginx"]},
+//	    "expected": ["WordPress", "PHP"]
+//	}
+//
+// The body of the response is read from the file of the same name with the
+// .html extension, wordpress.html, or from the file named by "body".
+package corpus
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/textproto"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/kavinsood/kitsune/internal/profiler"
+)
+
+// Site is a saved response of a known site with the technologies it must be
+// detected with
+type Site struct {
+	// Name is the name of the site file, without its extension
+	Name string `json:"-"`
+	// URL is where the response was saved from, for whoever refreshes it
+	URL     string              `json:"url,omitempty"`
+	Headers map[string][]string `json:"headers"`
+	// BodyFile is the file of the body, relative to the corpus directory
+	BodyFile string `json:"body,omitempty"`
+	Body     []byte `json:"-"`
+	// Expected are the technologies, without versions, the site must be detected with
+	Expected []string `json:"expected"`
+}
+
+// Regression is a site that is missing some of its expected technologies
+type Regression struct {
+	Site    string   `json:"site"`
+	Missing []string `json:"missing"`
+}
+
+func (r Regression) String() string {
+	return fmt.Sprintf("%s: %s not detected", r.Site, strings.Join(r.Missing, ", "))
+}
+
+// Load reads the sites of a corpus directory, sorted by name
+func Load(dir string) ([]Site, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	if len(paths) == 0 {
+		return nil, fmt.Errorf("no sites found in %s", dir)
+	}
+	sort.Strings(paths)
+
+	sites := make([]Site, 0, len(paths))
+	for _, path := range paths {
+		site, err := loadSite(dir, path)
+		if err != nil {
+			return nil, fmt.Errorf("could not load %s: %w", path, err)
+		}
+		sites = append(sites, site)
+	}
+	return sites, nil
+}
+
+// loadSite reads a site file and its body
+func loadSite(dir, path string) (Site, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Site{}, err
+	}
+	var site Site
+	if err := json.Unmarshal(data, &site); err != nil {
+		return Site{}, err
+	}
+	if len(site.Expected) == 0 {
+		return Site{}, fmt.Errorf("no expected technologies")
+	}
+	site.Name = strings.TrimSuffix(filepath.Base(path), ".json")
+
+	// The saved headers may not be canonical, as the analysis expects
+	headers := make(map[string][]string, len(site.Headers))
+	for name, values := range site.Headers {
+		key := textproto.CanonicalMIMEHeaderKey(name)
+		headers[key] = append(headers[key], values...)
+	}
+	site.Headers = headers
+
+	if site.BodyFile == "" {
+		site.BodyFile = site.Name + ".html"
+	}
+	site.Body, err = os.ReadFile(filepath.Join(dir, site.BodyFile))
+	if err != nil {
+		return Site{}, err
+	}
+	return site, nil
+}
+
+// Run analyzes the saved response of each site and returns the sites that are
+// missing some of their expected technologies. Only the headers and body are
+// analyzed, the sites are never contacted.
+func Run(wappalyzer *profiler.Wappalyze, sites []Site) []Regression {
+	var regressions []Regression
+	for _, site := range sites {
+		resp := &http.Response{Header: site.Headers}
+		detected := wappalyzer.AnalyzeWithPipeline(resp, site.Body).GetDetections()
+
+		var missing []string
+		for _, technology := range site.Expected {
+			if _, ok := detected[technology]; !ok {
+				missing = append(missing, technology)
+			}
+		}
+		if len(missing) > 0 {
+			sort.Strings(missing)
+			regressions = append(regressions, Regression{Site: site.Name, Missing: missing})
+		}
+	}
+	return regressions
+}
@@ -0,0 +1,53 @@
+package corpus
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/kavinsood/kitsune/internal/profiler"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoad(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644), "could not write %s", name)
+	}
+	write("site.json", `{"headers": {"x-powered-by": ["PHP/8.2"]}, "expected": ["PHP"]}`)
+	write("site.html", "<html></html>")
+	write("other.json", `{"headers": {}, "body": "page.html", "expected": ["Nginx"]}`)
+	write("page.html", "<p>page</p>")
+
+	sites, err := Load(dir)
+	require.NoError(t, err, "could not load corpus")
+	require.Len(t, sites, 2, "could not load all sites")
+	require.Equal(t, "other", sites[0].Name, "sites should be sorted by name")
+	require.Equal(t, "<p>page</p>", string(sites[0].Body), "could not read named body")
+	require.Equal(t, "site", sites[1].Name)
+	require.Equal(t, "<html></html>", string(sites[1].Body), "could not read default body")
+	require.Equal(t, []string{"PHP/8.2"}, sites[1].Headers["X-Powered-By"], "headers should be canonical")
+
+	write("empty.json", `{"headers": {}, "expected": []}`)
+	_, err = Load(dir)
+	require.Error(t, err, "sites without expected technologies should be rejected")
+
+	_, err = Load(t.TempDir())
+	require.Error(t, err, "empty corpus should be rejected")
+}
+
+func TestRun(t *testing.T) {
+	wappalyzer, err := profiler.New()
+	require.NoError(t, err, "could not create wappalyzer")
+
+	sites, err := Load("../../testdata/corpus")
+	require.NoError(t, err, "could not load corpus")
+	require.Empty(t, Run(wappalyzer, sites), "embedded fingerprints should detect the corpus")
+
+	sites = []Site{{
+		Name:     "nginx",
+		Headers:  map[string][]string{"Server": {"nginx"}},
+		Expected: []string{"Nginx", "WordPress", "Apache HTTP Server"},
+	}}
+	require.Equal(t, []Regression{{Site: "nginx", Missing: []string{"Apache HTTP Server", "WordPress"}}}, Run(wappalyzer, sites), "could not report missing technologies")
+}
@@ -0,0 +1,137 @@
+// Package ctlog discovers the hosts related to a domain from the certificates
+// logged for it in Certificate Transparency logs, as indexed by the crt.sh
+// aggregator. Sibling hosts often run another stack than the main site, so
+// fingerprinting them maps the technology footprint of the whole domain.
+package ctlog
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/weppos/publicsuffix-go/publicsuffix"
+)
+
+// DefaultBaseURL is the crt.sh search endpoint
+const DefaultBaseURL = "https://crt.sh/"
+
+// maxResponseSize bounds the certificate list of a domain, which crt.sh
+// returns in a single response
+const maxResponseSize = 64 * 1024 * 1024
+
+// ErrInvalidDomain is returned when a target has no registrable domain
+var ErrInvalidDomain = errors.New("no registrable domain")
+
+// Client looks up hosts in the certificates logged for a domain
+type Client struct {
+	baseURL string
+	client  *http.Client
+}
+
+// New creates a client of the crt.sh instance at baseURL. crt.sh is slow for
+// large domains, so lookups are bounded by timeout.
+func New(baseURL string, timeout time.Duration) *Client {
+	return &Client{
+		baseURL: baseURL,
+		client:  &http.Client{Timeout: timeout},
+	}
+}
+
+// Domain returns the registrable domain of target, a URL or a hostname, such
+// as example.co.uk for https://www.example.co.uk/path
+func Domain(target string) (string, error) {
+	host := target
+	if strings.Contains(target, "://") {
+		parsedURL, err := url.Parse(target)
+		if err != nil {
+			return "", err
+		}
+		host = parsedURL.Hostname()
+	}
+	domain, err := publicsuffix.Domain(strings.ToLower(strings.TrimSuffix(host, ".")))
+	if err != nil {
+		return "", fmt.Errorf("%w: %s", ErrInvalidDomain, host)
+	}
+	return domain, nil
+}
+
+// entry is a certificate in the crt.sh JSON output. NameValue holds the
+// names of the certificate, one per line.
+type entry struct {
+	NameValue string `json:"name_value"`
+}
+
+// Hosts returns the hosts of domain and its subdomains named by certificates
+// logged for it, sorted and without duplicates. Wildcard names are reduced to
+// the domain they cover.
+func (c *Client) Hosts(ctx context.Context, domain string) ([]string, error) {
+	query := url.Values{"q": {"%." + domain}, "output": {"json"}}
+	req, err := http.NewRequestWithContext(ctx, "GET", c.baseURL+"?"+query.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("could not query certificates of %s: %w", domain, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("could not query certificates of %s: status %d", domain, resp.StatusCode)
+	}
+
+	var entries []entry
+	if err := json.NewDecoder(io.LimitReader(resp.Body, maxResponseSize)).Decode(&entries); err != nil {
+		return nil, fmt.Errorf("could not decode certificates of %s: %w", domain, err)
+	}
+
+	seen := make(map[string]struct{})
+	for _, entry := range entries {
+		for _, name := range strings.Split(entry.NameValue, "\n") {
+			name = strings.ToLower(strings.TrimSpace(name))
+			name = strings.TrimPrefix(name, "*.")
+			// Certificates may also name email addresses
+			if strings.Contains(name, "@") {
+				continue
+			}
+			if name != domain && !strings.HasSuffix(name, "."+domain) {
+				continue
+			}
+			seen[name] = struct{}{}
+		}
+	}
+
+	hosts := make([]string, 0, len(seen))
+	for host := range seen {
+		hosts = append(hosts, host)
+	}
+	slices.Sort(hosts)
+	return hosts, nil
+}
+
+// Sample returns up to limit of hosts to fingerprint, domain and its www
+// subdomain first, then the others in order. A limit below 1 returns them all.
+func Sample(domain string, hosts []string, limit int) []string {
+	sample := make([]string, 0, len(hosts))
+	for _, host := range []string{domain, "www." + domain} {
+		if slices.Contains(hosts, host) {
+			sample = append(sample, host)
+		}
+	}
+	for _, host := range hosts {
+		if host != domain && host != "www."+domain {
+			sample = append(sample, host)
+		}
+	}
+	if limit > 0 && len(sample) > limit {
+		sample = sample[:limit]
+	}
+	return sample
+}
@@ -0,0 +1,62 @@
+package ctlog
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDomain(t *testing.T) {
+	tests := []struct {
+		target string
+		domain string
+	}{
+		{"example.com", "example.com"},
+		{"https://www.Example.com/path", "example.com"},
+		{"http://api.eu.example.co.uk:8080", "example.co.uk"},
+		{"example.com.", "example.com"},
+	}
+	for _, tt := range tests {
+		domain, err := Domain(tt.target)
+		require.NoError(t, err, "could not get domain of %s", tt.target)
+		require.Equal(t, tt.domain, domain, "wrong domain of %s", tt.target)
+	}
+
+	_, err := Domain("co.uk")
+	require.ErrorIs(t, err, ErrInvalidDomain, "public suffix has no registrable domain")
+}
+
+func TestHosts(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "%.example.com", r.URL.Query().Get("q"), "wrong query")
+		require.Equal(t, "json", r.URL.Query().Get("output"), "wrong output")
+		w.Write([]byte(`[
+			{"name_value": "example.com\nwww.example.com"},
+			{"name_value": "*.API.example.com\napi.example.com"},
+			{"name_value": "admin@example.com\nmail.example.com"},
+			{"name_value": "example.org\nnotexample.com"}
+		]`))
+	}))
+	defer server.Close()
+
+	hosts, err := New(server.URL+"/", 5*time.Second).Hosts(context.Background(), "example.com")
+	require.NoError(t, err, "could not get hosts")
+	require.Equal(t, []string{"api.example.com", "example.com", "mail.example.com", "www.example.com"}, hosts, "wrong hosts")
+
+	require.Equal(t, []string{"example.com", "www.example.com", "api.example.com"}, Sample("example.com", hosts, 3), "wrong sample")
+	require.Len(t, Sample("example.com", hosts, 0), 4, "unbounded sample should have every host")
+}
+
+func TestHostsFailure(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer server.Close()
+
+	_, err := New(server.URL+"/", 5*time.Second).Hosts(context.Background(), "example.com")
+	require.ErrorContains(t, err, "status 502", "failure should be reported")
+}
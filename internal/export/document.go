@@ -0,0 +1,71 @@
+// Package export converts analysis results into the formats consumed by
+// external systems and writes them to those systems.
+package export
+
+import (
+	"net/url"
+	"sort"
+	"time"
+
+	"github.com/kavinsood/kitsune/internal/profiler"
+)
+
+// Result is the part of an analysis result used by the exporters.
+// It is implemented by the results returned from the profiler package.
+type Result interface {
+	GetTitle() string
+	GetAppInfo() map[string]profiler.AppInfo
+	GetDetections() map[string]profiler.Detection
+}
+
+// Document is a single scan of a single URL, in the shape stored by exporters
+type Document struct {
+	URL          string               `json:"url"`
+	Host         string               `json:"host,omitempty"`
+	ScannedAt    time.Time            `json:"scanned_at"`
+	Title        string               `json:"title,omitempty"`
+	Technologies []TechnologyDocument `json:"technologies"`
+}
+
+// TechnologyDocument is a detected technology along with the evidence for it
+type TechnologyDocument struct {
+	Name       string   `json:"name"`
+	Version    string   `json:"version,omitempty"`
+	Confidence int      `json:"confidence"`
+	Categories []string `json:"categories,omitempty"`
+	DetectedBy []string `json:"detected_by,omitempty"`
+	// CPE is the CPE 2.3 name of the technology, if it has one
+	CPE string `json:"cpe,omitempty"`
+}
+
+// NewDocument builds the document for a scan of targetURL.
+// Technologies are sorted by name so documents are stable across runs.
+func NewDocument(targetURL string, scannedAt time.Time, result Result) Document {
+	document := Document{
+		URL:       targetURL,
+		ScannedAt: scannedAt,
+		Title:     result.GetTitle(),
+	}
+	if parsedURL, err := url.Parse(targetURL); err == nil {
+		document.Host = parsedURL.Hostname()
+	}
+
+	appInfo := result.GetAppInfo()
+	detections := result.GetDetections()
+
+	document.Technologies = make([]TechnologyDocument, 0, len(detections))
+	for name, detection := range detections {
+		document.Technologies = append(document.Technologies, TechnologyDocument{
+			Name:       name,
+			Version:    detection.Version,
+			Confidence: detection.Confidence,
+			Categories: appInfo[name].Categories,
+			DetectedBy: detection.DetectedBy,
+			CPE:        appInfo[name].CPE,
+		})
+	}
+	sort.Slice(document.Technologies, func(i, j int) bool {
+		return document.Technologies[i].Name < document.Technologies[j].Name
+	})
+	return document
+}
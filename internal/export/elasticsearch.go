@@ -0,0 +1,151 @@
+package export
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// elasticsearchMapping indexes technologies as nested documents, so that a
+// query can match a name and a version of the same technology
+const elasticsearchMapping = `{
+  "mappings": {
+    "properties": {
+      "url":        {"type": "keyword"},
+      "host":       {"type": "keyword"},
+      "scanned_at": {"type": "date"},
+      "title":      {"type": "text"},
+      "technologies": {
+        "type": "nested",
+        "properties": {
+          "name":        {"type": "keyword"},
+          "version":     {"type": "keyword"},
+          "confidence":  {"type": "integer"},
+          "categories":  {"type": "keyword"},
+          "detected_by": {"type": "keyword"},
+          "cpe":         {"type": "keyword"}
+        }
+      }
+    }
+  }
+}`
+
+// ElasticsearchExporter writes documents to an Elasticsearch or OpenSearch index
+type ElasticsearchExporter struct {
+	endpoint string
+	index    string
+	username string
+	password string
+	client   *http.Client
+}
+
+// NewElasticsearchExporter creates an exporter writing to index on the cluster at
+// endpoint. Credentials in the endpoint's user info are sent as basic auth.
+func NewElasticsearchExporter(endpoint, index string) (*ElasticsearchExporter, error) {
+	parsedURL, err := url.Parse(endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("invalid elasticsearch endpoint: %w", err)
+	}
+	if parsedURL.Scheme == "" || parsedURL.Host == "" {
+		return nil, fmt.Errorf("invalid elasticsearch endpoint: %s", endpoint)
+	}
+
+	exporter := &ElasticsearchExporter{
+		index:  index,
+		client: &http.Client{Timeout: 30 * time.Second},
+	}
+	if parsedURL.User != nil {
+		exporter.username = parsedURL.User.Username()
+		exporter.password, _ = parsedURL.User.Password()
+		parsedURL.User = nil
+	}
+	exporter.endpoint = strings.TrimSuffix(parsedURL.String(), "/")
+	return exporter, nil
+}
+
+// EnsureIndex creates the index with the kitsune mapping if it does not exist yet
+func (e *ElasticsearchExporter) EnsureIndex(ctx context.Context) error {
+	resp, err := e.do(ctx, "PUT", "/"+url.PathEscape(e.index), "application/json", strings.NewReader(elasticsearchMapping))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return nil
+	}
+
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+	if resp.StatusCode == http.StatusBadRequest && bytes.Contains(body, []byte("resource_already_exists_exception")) {
+		return nil
+	}
+	return fmt.Errorf("could not create index %s: %s: %s", e.index, resp.Status, body)
+}
+
+// Export writes documents to the index with a single bulk request
+func (e *ElasticsearchExporter) Export(ctx context.Context, documents ...Document) error {
+	if len(documents) == 0 {
+		return nil
+	}
+
+	var payload bytes.Buffer
+	encoder := json.NewEncoder(&payload)
+	for _, document := range documents {
+		// Documents get generated IDs, so every scan is kept
+		if err := encoder.Encode(map[string]interface{}{"index": map[string]string{"_index": e.index}}); err != nil {
+			return err
+		}
+		if err := encoder.Encode(document); err != nil {
+			return err
+		}
+	}
+
+	resp, err := e.do(ctx, "POST", "/_bulk", "application/x-ndjson", &payload)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024*1024))
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("bulk request failed: %s: %s", resp.Status, body)
+	}
+
+	// A successful bulk request can still contain failed items
+	var bulkResponse struct {
+		Errors bool `json:"errors"`
+	}
+	if err := json.Unmarshal(body, &bulkResponse); err == nil && bulkResponse.Errors {
+		return fmt.Errorf("bulk request had failed items: %s", body)
+	}
+	return nil
+}
+
+// Publish implements Sink
+func (e *ElasticsearchExporter) Publish(ctx context.Context, document Document) error {
+	return e.Export(ctx, document)
+}
+
+// Close implements Sink
+func (e *ElasticsearchExporter) Close() error {
+	return nil
+}
+
+// do sends an authenticated request to the cluster
+func (e *ElasticsearchExporter) do(ctx context.Context, method, path, contentType string, body io.Reader) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, e.endpoint+path, body)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", contentType)
+	if e.username != "" {
+		req.SetBasicAuth(e.username, e.password)
+	}
+	return e.client.Do(req)
+}
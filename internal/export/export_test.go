@@ -0,0 +1,158 @@
+package export
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kavinsood/kitsune/internal/profiler"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeResult is a fixed analysis result
+type fakeResult struct{}
+
+func (fakeResult) GetTitle() string { return "Example" }
+
+func (fakeResult) GetAppInfo() map[string]profiler.AppInfo {
+	return map[string]profiler.AppInfo{
+		"Nginx":     {Website: "https://nginx.org", Categories: []string{"Web servers", "Reverse proxies"}},
+		"WordPress": {Website: "https://wordpress.org", Categories: []string{"CMS", "Blogs"}},
+	}
+}
+
+func (fakeResult) GetDetections() map[string]profiler.Detection {
+	return map[string]profiler.Detection{
+		"WordPress": {Version: "6.4", Confidence: 100, DetectedBy: []string{"meta"}},
+		"Nginx":     {Confidence: 100, DetectedBy: []string{"headers"}},
+	}
+}
+
+func TestNewDocument(t *testing.T) {
+	scannedAt := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	document := NewDocument("https://example.com/blog", scannedAt, fakeResult{})
+
+	require.Equal(t, "example.com", document.Host, "wrong host")
+	require.Equal(t, "Example", document.Title, "wrong title")
+	require.Equal(t, []TechnologyDocument{
+		{Name: "Nginx", Confidence: 100, Categories: []string{"Web servers", "Reverse proxies"}, DetectedBy: []string{"headers"}},
+		{Name: "WordPress", Version: "6.4", Confidence: 100, Categories: []string{"CMS", "Blogs"}, DetectedBy: []string{"meta"}},
+	}, document.Technologies, "wrong technologies")
+}
+
+func TestElasticsearchExporter(t *testing.T) {
+	var indexCreated bool
+	var bulkLines []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		username, password, _ := r.BasicAuth()
+		if username != "elastic" || password != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		switch {
+		case r.Method == "PUT" && r.URL.Path == "/kitsune-scans":
+			if indexCreated {
+				w.WriteHeader(http.StatusBadRequest)
+				io.WriteString(w, `{"error":{"type":"resource_already_exists_exception"}}`)
+				return
+			}
+			indexCreated = true
+			io.WriteString(w, `{"acknowledged":true}`)
+		case r.Method == "POST" && r.URL.Path == "/_bulk":
+			require.Equal(t, "application/x-ndjson", r.Header.Get("Content-Type"), "wrong bulk content type")
+			scanner := bufio.NewScanner(r.Body)
+			for scanner.Scan() {
+				bulkLines = append(bulkLines, scanner.Text())
+			}
+			io.WriteString(w, `{"errors":false,"items":[]}`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	endpoint := strings.Replace(server.URL, "http://", "http://elastic:secret@", 1)
+	exporter, err := NewElasticsearchExporter(endpoint, "kitsune-scans")
+	require.NoError(t, err, "could not create exporter")
+
+	ctx := context.Background()
+	require.NoError(t, exporter.EnsureIndex(ctx), "could not create index")
+	require.NoError(t, exporter.EnsureIndex(ctx), "existing index should not be an error")
+
+	document := NewDocument("https://example.com", time.Now(), fakeResult{})
+	require.NoError(t, exporter.Export(ctx, document, document), "could not export documents")
+
+	require.Len(t, bulkLines, 4, "wrong number of bulk lines")
+	require.JSONEq(t, `{"index":{"_index":"kitsune-scans"}}`, bulkLines[0], "wrong bulk action")
+
+	var exported Document
+	require.NoError(t, json.Unmarshal([]byte(bulkLines[1]), &exported), "could not decode document")
+	require.Equal(t, "https://example.com", exported.URL, "wrong exported document")
+	require.Len(t, exported.Technologies, 2, "wrong exported technologies")
+}
+
+func TestWriteCSV(t *testing.T) {
+	documents := []Document{
+		NewDocument("https://example.com", time.Now(), fakeResult{}),
+		{URL: "https://empty.example.com"},
+	}
+
+	var buffer strings.Builder
+	require.NoError(t, WriteCSV(&buffer, documents...), "could not write csv")
+	require.Equal(t, `url,technology,version,confidence,categories,detected_by
+https://example.com,Nginx,,100,Web servers;Reverse proxies,headers
+https://example.com,WordPress,6.4,100,CMS;Blogs,meta
+https://empty.example.com,,,,,
+`, buffer.String(), "wrong csv")
+}
+
+func TestWriteTable(t *testing.T) {
+	var buffer strings.Builder
+	require.NoError(t, WriteTable(&buffer, NewDocument("https://example.com", time.Now(), fakeResult{})), "could not write table")
+	require.Equal(t, `URL                  TECHNOLOGY  VERSION  CONFIDENCE  CATEGORIES                    DETECTED_BY
+https://example.com  Nginx                100         Web servers, Reverse proxies  headers
+https://example.com  WordPress   6.4      100         CMS, Blogs                    meta
+`, buffer.String(), "wrong table")
+}
+
+func TestWriteSARIF(t *testing.T) {
+	documents := []Document{
+		{URL: "https://example.com", Technologies: []TechnologyDocument{
+			{Name: "WordPress", Version: "6.4", Confidence: 100, Categories: []string{"CMS"}, DetectedBy: []string{"meta"}, CPE: "cpe:2.3:a:wordpress:wordpress:*:*:*:*:*:*:*:*"},
+		}},
+		{URL: "https://blog.example.com", Technologies: []TechnologyDocument{
+			{Name: "Google Font API", Confidence: 100, DetectedBy: []string{"html"}},
+			{Name: "WordPress", Confidence: 100, Categories: []string{"CMS"}, CPE: "cpe:2.3:a:wordpress:wordpress:*:*:*:*:*:*:*:*"},
+		}},
+	}
+
+	var buffer bytes.Buffer
+	require.NoError(t, WriteSARIF(&buffer, documents...), "could not write sarif")
+
+	var log sarifLog
+	require.NoError(t, json.Unmarshal(buffer.Bytes(), &log), "could not decode sarif")
+	require.Equal(t, "2.1.0", log.Version, "wrong version")
+	require.Len(t, log.Runs, 1, "wrong runs")
+
+	run := log.Runs[0]
+	require.Equal(t, []string{"technology/wordpress", "technology/google-font-api"}, []string{run.Tool.Driver.Rules[0].ID, run.Tool.Driver.Rules[1].ID}, "wrong rules")
+	require.Len(t, run.Results, 3, "wrong results")
+
+	wordpress := run.Results[0]
+	require.Equal(t, "technology/wordpress", wordpress.RuleID, "wrong rule")
+	require.Equal(t, 0, wordpress.RuleIndex, "wrong rule index")
+	require.Equal(t, "WordPress 6.4 detected on https://example.com", wordpress.Message.Text, "wrong message")
+	require.Equal(t, "https://example.com", wordpress.Locations[0].PhysicalLocation.ArtifactLocation.URI, "wrong location")
+	require.Equal(t, "cpe:2.3:a:wordpress:wordpress:6.4:*:*:*:*:*:*:*", wordpress.Properties["cpe"], "version not set in cpe")
+
+	require.Equal(t, 1, run.Results[1].RuleIndex, "wrong rule index")
+	require.Equal(t, "cpe:2.3:a:wordpress:wordpress:*:*:*:*:*:*:*:*", run.Results[2].Properties["cpe"], "cpe without version should be kept")
+}
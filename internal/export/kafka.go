@@ -0,0 +1,53 @@
+package export
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+)
+
+// KafkaSink publishes documents as JSON messages to a Kafka topic.
+// Messages are keyed by host, so all scans of a host land on the same partition.
+type KafkaSink struct {
+	writer *kafka.Writer
+}
+
+// NewKafkaSink creates a sink publishing to topic on the given brokers
+func NewKafkaSink(brokers []string, topic string) (*KafkaSink, error) {
+	if len(brokers) == 0 {
+		return nil, fmt.Errorf("no kafka brokers given")
+	}
+	if topic == "" {
+		return nil, fmt.Errorf("no kafka topic given")
+	}
+
+	return &KafkaSink{
+		writer: &kafka.Writer{
+			Addr:         kafka.TCP(brokers...),
+			Topic:        topic,
+			Balancer:     &kafka.Hash{},
+			RequiredAcks: kafka.RequireOne,
+			BatchTimeout: 50 * time.Millisecond,
+		},
+	}, nil
+}
+
+// Publish implements Sink
+func (k *KafkaSink) Publish(ctx context.Context, document Document) error {
+	value, err := json.Marshal(document)
+	if err != nil {
+		return err
+	}
+	if err := k.writer.WriteMessages(ctx, kafka.Message{Key: []byte(document.Host), Value: value}); err != nil {
+		return fmt.Errorf("could not publish to kafka: %w", err)
+	}
+	return nil
+}
+
+// Close implements Sink
+func (k *KafkaSink) Close() error {
+	return k.writer.Close()
+}
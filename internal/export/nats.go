@@ -0,0 +1,51 @@
+package export
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/nats-io/nats.go"
+)
+
+// NATSSink publishes documents as JSON messages to a NATS subject
+type NATSSink struct {
+	conn    *nats.Conn
+	subject string
+}
+
+// NewNATSSink connects to the NATS server at url and publishes to subject.
+// Credentials and tokens can be given in the URL's user info.
+func NewNATSSink(url, subject string) (*NATSSink, error) {
+	if subject == "" {
+		return nil, fmt.Errorf("no nats subject given")
+	}
+
+	conn, err := nats.Connect(url, nats.Name("kitsune"), nats.Timeout(10*time.Second))
+	if err != nil {
+		return nil, fmt.Errorf("could not connect to nats: %w", err)
+	}
+	return &NATSSink{conn: conn, subject: subject}, nil
+}
+
+// Publish implements Sink
+func (n *NATSSink) Publish(ctx context.Context, document Document) error {
+	value, err := json.Marshal(document)
+	if err != nil {
+		return err
+	}
+	if err := n.conn.Publish(n.subject, value); err != nil {
+		return fmt.Errorf("could not publish to nats: %w", err)
+	}
+	return nil
+}
+
+// Close implements Sink. Pending messages are flushed before the connection is closed.
+func (n *NATSSink) Close() error {
+	err := n.conn.Drain()
+	if err != nil {
+		n.conn.Close()
+	}
+	return err
+}
@@ -0,0 +1,179 @@
+package export
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// SARIF 2.1.0 identifies the schema of the log written by WriteSARIF
+const (
+	sarifVersion = "2.1.0"
+	sarifSchema  = "https://json.schemastore.org/sarif-2.1.0.json"
+)
+
+// sarifLog is the subset of the SARIF 2.1.0 object model written by WriteSARIF
+type sarifLog struct {
+	Version string     `json:"version"`
+	Schema  string     `json:"$schema"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool    sarifTool     `json:"tool"`
+	Results []sarifResult `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name           string      `json:"name"`
+	InformationURI string      `json:"informationUri"`
+	Rules          []sarifRule `json:"rules"`
+}
+
+type sarifRule struct {
+	ID               string          `json:"id"`
+	Name             string          `json:"name"`
+	ShortDescription sarifMessage    `json:"shortDescription"`
+	Properties       sarifProperties `json:"properties,omitempty"`
+}
+
+type sarifResult struct {
+	RuleID              string            `json:"ruleId"`
+	RuleIndex           int               `json:"ruleIndex"`
+	Level               string            `json:"level"`
+	Message             sarifMessage      `json:"message"`
+	Locations           []sarifLocation   `json:"locations"`
+	PartialFingerprints map[string]string `json:"partialFingerprints"`
+	Properties          sarifProperties   `json:"properties,omitempty"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation sarifPhysicalLocation `json:"physicalLocation"`
+}
+
+type sarifPhysicalLocation struct {
+	ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+}
+
+type sarifArtifactLocation struct {
+	URI string `json:"uri"`
+}
+
+// sarifProperties is a SARIF property bag
+type sarifProperties map[string]interface{}
+
+// WriteSARIF writes documents as a SARIF 2.1.0 log, so detections can be
+// uploaded to code scanning dashboards. Each detected technology is a rule,
+// tagged with its categories, and each detection is a result of level note
+// located at the scanned URL. Results carry the version, confidence and
+// detection vectors, and the CPE name of the technology with the detected
+// version filled in, for matching against vulnerability databases.
+func WriteSARIF(w io.Writer, documents ...Document) error {
+	run := sarifRun{
+		Tool: sarifTool{Driver: sarifDriver{
+			Name:           "kitsune",
+			InformationURI: "https://github.com/kavinsood/kitsune",
+			Rules:          []sarifRule{},
+		}},
+		Results: []sarifResult{},
+	}
+
+	rules := make(map[string]int)
+	for _, document := range documents {
+		for _, technology := range document.Technologies {
+			id := sarifRuleID(technology.Name)
+			index, ok := rules[id]
+			if !ok {
+				index = len(run.Tool.Driver.Rules)
+				rules[id] = index
+				rule := sarifRule{
+					ID:               id,
+					Name:             technology.Name,
+					ShortDescription: sarifMessage{Text: technology.Name + " detected"},
+					Properties:       sarifProperties{},
+				}
+				if len(technology.Categories) > 0 {
+					rule.Properties["tags"] = technology.Categories
+				}
+				if technology.CPE != "" {
+					rule.Properties["cpe"] = technology.CPE
+				}
+				run.Tool.Driver.Rules = append(run.Tool.Driver.Rules, rule)
+			}
+
+			message := fmt.Sprintf("%s detected on %s", technology.Name, document.URL)
+			if technology.Version != "" {
+				message = fmt.Sprintf("%s %s detected on %s", technology.Name, technology.Version, document.URL)
+			}
+			result := sarifResult{
+				RuleID:    id,
+				RuleIndex: index,
+				Level:     "note",
+				Message:   sarifMessage{Text: message},
+				Locations: []sarifLocation{{
+					PhysicalLocation: sarifPhysicalLocation{ArtifactLocation: sarifArtifactLocation{URI: document.URL}},
+				}},
+				// Dashboards track a result across uploads by its fingerprint,
+				// which must not change with the version
+				PartialFingerprints: map[string]string{"technology/v1": document.URL + "|" + technology.Name},
+				Properties: sarifProperties{
+					"confidence": technology.Confidence,
+				},
+			}
+			if technology.Version != "" {
+				result.Properties["version"] = technology.Version
+			}
+			if len(technology.DetectedBy) > 0 {
+				result.Properties["detected_by"] = technology.DetectedBy
+			}
+			if technology.CPE != "" {
+				result.Properties["cpe"] = versionedCPE(technology.CPE, technology.Version)
+			}
+			run.Results = append(run.Results, result)
+		}
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(sarifLog{Version: sarifVersion, Schema: sarifSchema, Runs: []sarifRun{run}})
+}
+
+// sarifRuleID returns the rule ID of a technology, its lowercase name with
+// runs of other characters than letters and digits replaced by a dash
+func sarifRuleID(name string) string {
+	var builder strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(name) {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') {
+			if dash && builder.Len() > 0 {
+				builder.WriteByte('-')
+			}
+			builder.WriteRune(r)
+			dash = false
+		} else {
+			dash = true
+		}
+	}
+	return "technology/" + builder.String()
+}
+
+// versionedCPE sets the version of a CPE 2.3 name, such as
+// cpe:2.3:a:wordpress:wordpress:*:*:*:*:*:*:*:*, if it has none
+func versionedCPE(cpe, version string) string {
+	parts := strings.Split(cpe, ":")
+	if version == "" || len(parts) < 6 || parts[0] != "cpe" || parts[1] != "2.3" || parts[5] != "*" {
+		return cpe
+	}
+	// Colons separate the components, they are escaped within one
+	parts[5] = strings.ReplaceAll(version, ":", "\\:")
+	return strings.Join(parts, ":")
+}
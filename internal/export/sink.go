@@ -0,0 +1,40 @@
+package export
+
+import (
+	"context"
+	"errors"
+)
+
+// Sink publishes completed analyses to an external system, such as a message
+// broker or a search index
+type Sink interface {
+	// Publish sends a single document
+	Publish(ctx context.Context, document Document) error
+	// Close flushes pending documents and releases the sink's resources
+	Close() error
+}
+
+// MultiSink publishes every document to all of its sinks
+type MultiSink []Sink
+
+// Publish implements Sink. Every sink is tried, and their errors are joined.
+func (m MultiSink) Publish(ctx context.Context, document Document) error {
+	var errs []error
+	for _, sink := range m {
+		if err := sink.Publish(ctx, document); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Close implements Sink
+func (m MultiSink) Close() error {
+	var errs []error
+	for _, sink := range m {
+		if err := sink.Close(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,138 @@
+package export
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kavinsood/kitsune/internal/webhook"
+	"github.com/stretchr/testify/require"
+)
+
+// recordingSink keeps published documents in memory
+type recordingSink struct {
+	documents []Document
+	err       error
+	closed    bool
+}
+
+func (r *recordingSink) Publish(ctx context.Context, document Document) error {
+	r.documents = append(r.documents, document)
+	return r.err
+}
+
+func (r *recordingSink) Close() error {
+	r.closed = true
+	return nil
+}
+
+func TestMultiSink(t *testing.T) {
+	failing := &recordingSink{err: errors.New("unavailable")}
+	working := &recordingSink{}
+	sink := MultiSink{failing, working}
+
+	document := NewDocument("https://example.com", time.Now(), fakeResult{})
+	err := sink.Publish(context.Background(), document)
+	require.ErrorIs(t, err, failing.err, "sink error not reported")
+	require.Len(t, working.documents, 1, "failing sink stopped publishing to the others")
+
+	require.NoError(t, sink.Close(), "could not close sinks")
+	require.True(t, failing.closed && working.closed, "sinks not closed")
+}
+
+// serveFakeNATS accepts a single client and sends every published payload to payloads
+func serveFakeNATS(t *testing.T, listener net.Listener, payloads chan<- string) {
+	conn, err := listener.Accept()
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+
+	fmt.Fprintf(conn, "INFO {\"server_id\":\"fake\",\"version\":\"2.10.0\",\"max_payload\":1048576,\"proto\":1}\r\n")
+
+	reader := bufio.NewReader(conn)
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return
+		}
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+
+		switch fields[0] {
+		case "PING":
+			io.WriteString(conn, "PONG\r\n")
+		case "PUB":
+			var size int
+			fmt.Sscanf(fields[len(fields)-1], "%d", &size)
+			payload := make([]byte, size+2) // Payload followed by CRLF
+			if _, err := io.ReadFull(reader, payload); err != nil {
+				return
+			}
+			payloads <- fields[1] + " " + string(payload[:size])
+		}
+	}
+}
+
+func TestNATSSink(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err, "could not listen")
+	defer listener.Close()
+
+	payloads := make(chan string, 1)
+	go serveFakeNATS(t, listener, payloads)
+
+	sink, err := NewNATSSink("nats://"+listener.Addr().String(), "kitsune.scans")
+	require.NoError(t, err, "could not connect to nats")
+
+	document := NewDocument("https://example.com", time.Now(), fakeResult{})
+	require.NoError(t, sink.Publish(context.Background(), document), "could not publish")
+	require.NoError(t, sink.Close(), "could not close sink")
+
+	select {
+	case payload := <-payloads:
+		subject, message, _ := strings.Cut(payload, " ")
+		require.Equal(t, "kitsune.scans", subject, "wrong subject")
+
+		var published Document
+		require.NoError(t, json.Unmarshal([]byte(message), &published), "could not decode message")
+		require.Equal(t, "https://example.com", published.URL, "wrong published document")
+	case <-time.After(5 * time.Second):
+		t.Fatal("message not published")
+	}
+}
+
+func TestWebhookSink(t *testing.T) {
+	received := make(chan *http.Request, 2)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received <- r
+	}))
+	defer server.Close()
+
+	_, err := NewWebhookSink(webhook.Target{URL: "ftp://example.com"})
+	require.Error(t, err, "non-HTTP targets should be rejected")
+
+	sink, err := NewWebhookSink(webhook.Target{URL: server.URL}, webhook.Target{URL: server.URL + "/signed", Secret: "s3cret"})
+	require.NoError(t, err, "could not create sink")
+	document := NewDocument("https://example.com", time.Now(), fakeResult{})
+	require.NoError(t, sink.Publish(context.Background(), document), "could not publish")
+	require.NoError(t, sink.Close(), "pending deliveries should complete on close")
+
+	require.Len(t, received, 2, "every target should get the document")
+	for range 2 {
+		r := <-received
+		require.Equal(t, WebhookEvent, r.Header.Get(webhook.EventHeader), "wrong event")
+		require.Equal(t, r.URL.Path == "/signed", r.Header.Get(webhook.SignatureHeader) != "", "only targets with a secret should sign")
+	}
+}
@@ -0,0 +1,70 @@
+package export
+
+import (
+	"encoding/csv"
+	"io"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+)
+
+// tableColumns are the columns of the flattened rows of WriteCSV and WriteTable
+var tableColumns = []string{"url", "technology", "version", "confidence", "categories", "detected_by"}
+
+// WriteCSV writes documents as CSV with a header row and one row per detected
+// technology. Categories and detection vectors are joined with semicolons. A
+// document without technologies is written as a row with only its URL, so that
+// every scanned URL appears in the output.
+func WriteCSV(w io.Writer, documents ...Document) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write(tableColumns); err != nil {
+		return err
+	}
+	for _, row := range flatten(documents, ";") {
+		if err := writer.Write(row); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// WriteTable writes the rows of WriteCSV as a plain text table with aligned
+// columns, for reading in a terminal
+func WriteTable(w io.Writer, documents ...Document) error {
+	writer := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	header := make([]string, len(tableColumns))
+	for i, column := range tableColumns {
+		header[i] = strings.ToUpper(column)
+	}
+	rows := append([][]string{header}, flatten(documents, ", ")...)
+	for _, row := range rows {
+		if _, err := io.WriteString(writer, strings.Join(row, "\t")+"\n"); err != nil {
+			return err
+		}
+	}
+	return writer.Flush()
+}
+
+// flatten returns a row per technology of each document, in the order of
+// tableColumns, joining list values with separator
+func flatten(documents []Document, separator string) [][]string {
+	var rows [][]string
+	for _, document := range documents {
+		if len(document.Technologies) == 0 {
+			rows = append(rows, []string{document.URL, "", "", "", "", ""})
+			continue
+		}
+		for _, technology := range document.Technologies {
+			rows = append(rows, []string{
+				document.URL,
+				technology.Name,
+				technology.Version,
+				strconv.Itoa(technology.Confidence),
+				strings.Join(technology.Categories, separator),
+				strings.Join(technology.DetectedBy, separator),
+			})
+		}
+	}
+	return rows
+}
@@ -0,0 +1,118 @@
+package export
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/kavinsood/kitsune/internal/webhook"
+)
+
+const (
+	// webhookQueueSize is the number of documents pending for a target
+	// before new ones are dropped
+	webhookQueueSize = 1000
+	// webhookCloseTimeout bounds the time Close waits for pending deliveries
+	webhookCloseTimeout = 30 * time.Second
+	// WebhookEvent is the event type of the documents posted to webhooks
+	WebhookEvent = "analysis.completed"
+)
+
+// WebhookSink posts documents to webhook targets. Deliveries run in the
+// background, one target at a time, so publishing does not wait for retries
+// of slow or failing targets.
+type WebhookSink struct {
+	workers []*webhookWorker
+	// ctx is canceled when Close gives up on pending deliveries
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// webhookWorker delivers the documents queued for a target
+type webhookWorker struct {
+	url    string
+	sender *webhook.Sender
+	queue  chan Document
+}
+
+// NewWebhookSink creates a sink posting every document to each target
+func NewWebhookSink(targets ...webhook.Target) (*WebhookSink, error) {
+	if len(targets) == 0 {
+		return nil, fmt.Errorf("no webhook targets given")
+	}
+	for _, target := range targets {
+		parsed, err := url.Parse(target.URL)
+		if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+			return nil, fmt.Errorf("invalid webhook url %q", target.URL)
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	sink := &WebhookSink{ctx: ctx, cancel: cancel}
+	for _, target := range targets {
+		worker := &webhookWorker{
+			url:    target.URL,
+			sender: webhook.New(target, nil),
+			queue:  make(chan Document, webhookQueueSize),
+		}
+		sink.workers = append(sink.workers, worker)
+		sink.wg.Add(1)
+		go func() {
+			defer sink.wg.Done()
+			for document := range worker.queue {
+				// Deliveries abandoned by Close are drained without attempts
+				if ctx.Err() != nil {
+					continue
+				}
+				if err := worker.sender.Send(ctx, WebhookEvent, document); err != nil {
+					slog.WarnContext(ctx, "could not deliver webhook", "webhook", worker.url, "url", document.URL, "error", err)
+				}
+			}
+		}()
+	}
+	return sink, nil
+}
+
+// Publish implements Sink. The document is queued for every target, and
+// dropped for the targets whose queue is full.
+func (w *WebhookSink) Publish(ctx context.Context, document Document) error {
+	var errs []error
+	for _, worker := range w.workers {
+		select {
+		case worker.queue <- document:
+		default:
+			errs = append(errs, fmt.Errorf("webhook %s has too many pending deliveries, dropped %s", worker.url, document.URL))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Close implements Sink. Pending deliveries are waited for, retries included,
+// for up to 30 seconds, after which the remaining ones are abandoned.
+func (w *WebhookSink) Close() error {
+	for _, worker := range w.workers {
+		close(worker.queue)
+	}
+	done := make(chan struct{})
+	go func() {
+		w.wg.Wait()
+		close(done)
+	}()
+
+	timer := time.NewTimer(webhookCloseTimeout)
+	defer timer.Stop()
+	select {
+	case <-done:
+		w.cancel()
+		return nil
+	case <-timer.C:
+		w.cancel()
+		<-done
+		return fmt.Errorf("webhook deliveries still pending after %s were abandoned", webhookCloseTimeout)
+	}
+}
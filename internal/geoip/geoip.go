@@ -0,0 +1,109 @@
+// Package geoip locates addresses with MaxMind DB files, such as the GeoLite2
+// Country, City and ASN databases or compatible ones built from the published
+// address ranges of cloud providers. It implements profiler.GeoIPDatabase.
+package geoip
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/netip"
+
+	"github.com/kavinsood/kitsune/internal/profiler"
+	"github.com/oschwald/maxminddb-golang"
+)
+
+// cloudProviders names the cloud providers of well known autonomous systems,
+// so addresses are attributed to a provider without a cloud database
+var cloudProviders = map[uint]string{
+	7224:   "Amazon Web Services",
+	14618:  "Amazon Web Services",
+	16509:  "Amazon Web Services",
+	15169:  "Google Cloud",
+	396982: "Google Cloud",
+	8075:   "Microsoft Azure",
+	31898:  "Oracle Cloud",
+	14061:  "DigitalOcean",
+	63949:  "Akamai Connected Cloud",
+	20473:  "Vultr",
+	24940:  "Hetzner",
+	16276:  "OVHcloud",
+	13335:  "Cloudflare",
+	54113:  "Fastly",
+	45102:  "Alibaba Cloud",
+	132203: "Tencent Cloud",
+}
+
+// record holds the fields read from the databases. The country and ASN fields
+// follow the GeoLite2 schemas, the cloud fields are read from custom databases.
+type record struct {
+	Country struct {
+		ISOCode string            `maxminddb:"iso_code"`
+		Names   map[string]string `maxminddb:"names"`
+	} `maxminddb:"country"`
+	ASN            uint   `maxminddb:"autonomous_system_number"`
+	ASOrganization string `maxminddb:"autonomous_system_organization"`
+	CloudProvider  string `maxminddb:"cloud_provider"`
+	CloudRegion    string `maxminddb:"cloud_region"`
+}
+
+// Database looks addresses up in one or more MaxMind DB files, each filling
+// in the fields the previous ones did not
+type Database struct {
+	readers []*maxminddb.Reader
+}
+
+// Open opens the MaxMind DB files at paths
+func Open(paths ...string) (*Database, error) {
+	if len(paths) == 0 {
+		return nil, errors.New("no geoip database")
+	}
+	db := &Database{}
+	for _, path := range paths {
+		reader, err := maxminddb.Open(path)
+		if err != nil {
+			db.Close()
+			return nil, fmt.Errorf("could not open geoip database %s: %w", path, err)
+		}
+		db.readers = append(db.readers, reader)
+	}
+	return db, nil
+}
+
+// Lookup returns the location of ip. Addresses missing from every database
+// have an empty location.
+func (d *Database) Lookup(ip netip.Addr) (profiler.Location, error) {
+	var location profiler.Location
+	for _, reader := range d.readers {
+		var found record
+		if err := reader.Lookup(net.IP(ip.AsSlice()), &found); err != nil {
+			return location, fmt.Errorf("could not look up %s: %w", ip, err)
+		}
+		fill(&location.Country, found.Country.ISOCode)
+		fill(&location.CountryName, found.Country.Names["en"])
+		fill(&location.ASOrganization, found.ASOrganization)
+		fill(&location.CloudProvider, found.CloudProvider)
+		fill(&location.CloudRegion, found.CloudRegion)
+		if location.ASN == 0 {
+			location.ASN = found.ASN
+		}
+	}
+	fill(&location.CloudProvider, cloudProviders[location.ASN])
+	return location, nil
+}
+
+// fill sets field to value if it is empty
+func fill(field *string, value string) {
+	if *field == "" {
+		*field = value
+	}
+}
+
+// Close closes the database files
+func (d *Database) Close() error {
+	var errs []error
+	for _, reader := range d.readers {
+		errs = append(errs, reader.Close())
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,85 @@
+package geoip
+
+import (
+	"net"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/kavinsood/kitsune/internal/profiler"
+	"github.com/maxmind/mmdbwriter"
+	"github.com/maxmind/mmdbwriter/mmdbtype"
+	"github.com/stretchr/testify/require"
+)
+
+// writeDatabase writes a MaxMind DB file mapping networks to records
+func writeDatabase(t *testing.T, name string, records map[string]mmdbtype.Map) string {
+	tree, err := mmdbwriter.New(mmdbwriter.Options{DatabaseType: name, IncludeReservedNetworks: true})
+	require.NoError(t, err, "could not create database")
+	for network, value := range records {
+		_, ipNet, err := net.ParseCIDR(network)
+		require.NoError(t, err, "could not parse network")
+		require.NoError(t, tree.Insert(ipNet, value), "could not insert record")
+	}
+
+	path := filepath.Join(t.TempDir(), name+".mmdb")
+	file, err := os.Create(path)
+	require.NoError(t, err, "could not create database file")
+	defer file.Close()
+	_, err = tree.WriteTo(file)
+	require.NoError(t, err, "could not write database")
+	return path
+}
+
+func TestDatabase(t *testing.T) {
+	country := writeDatabase(t, "GeoLite2-Country", map[string]mmdbtype.Map{
+		"203.0.113.0/24": {
+			"country": mmdbtype.Map{
+				"iso_code": mmdbtype.String("DE"),
+				"names":    mmdbtype.Map{"en": mmdbtype.String("Germany")},
+			},
+		},
+	})
+	asn := writeDatabase(t, "GeoLite2-ASN", map[string]mmdbtype.Map{
+		"203.0.113.0/25": {
+			"autonomous_system_number":       mmdbtype.Uint32(16509),
+			"autonomous_system_organization": mmdbtype.String("AMAZON-02"),
+		},
+		"203.0.113.128/25": {
+			"autonomous_system_number":       mmdbtype.Uint32(64500),
+			"autonomous_system_organization": mmdbtype.String("EXAMPLE"),
+		},
+	})
+	cloud := writeDatabase(t, "Cloud-Ranges", map[string]mmdbtype.Map{
+		"203.0.113.0/26": {
+			"cloud_provider": mmdbtype.String("AWS"),
+			"cloud_region":   mmdbtype.String("eu-central-1"),
+		},
+	})
+
+	db, err := Open(cloud, country, asn)
+	require.NoError(t, err, "could not open databases")
+	defer db.Close()
+
+	tests := []struct {
+		name     string
+		ip       string
+		location profiler.Location
+	}{
+		{"cloud-region", "203.0.113.10", profiler.Location{Country: "DE", CountryName: "Germany", ASN: 16509, ASOrganization: "AMAZON-02", CloudProvider: "AWS", CloudRegion: "eu-central-1"}},
+		{"cloud-asn", "203.0.113.100", profiler.Location{Country: "DE", CountryName: "Germany", ASN: 16509, ASOrganization: "AMAZON-02", CloudProvider: "Amazon Web Services"}},
+		{"no-cloud", "203.0.113.200", profiler.Location{Country: "DE", CountryName: "Germany", ASN: 64500, ASOrganization: "EXAMPLE"}},
+		{"unknown", "198.51.100.1", profiler.Location{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			location, err := db.Lookup(netip.MustParseAddr(tt.ip))
+			require.NoError(t, err, "could not look up address")
+			require.Equal(t, tt.location, location, "wrong location")
+		})
+	}
+
+	_, err = Open(filepath.Join(t.TempDir(), "missing.mmdb"))
+	require.Error(t, err, "missing database should fail")
+}
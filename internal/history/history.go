@@ -0,0 +1,42 @@
+// Package history records the detections of each scan so that changes in a
+// target's technology stack can be tracked over time.
+package history
+
+import (
+	"context"
+	"time"
+
+	"github.com/kavinsood/kitsune/internal/profiler"
+)
+
+// Scan is a single recorded analysis of a target
+type Scan struct {
+	ID         int64                         `json:"id"`
+	URL        string                        `json:"url"`
+	ScannedAt  time.Time                     `json:"scanned_at"`
+	Detections map[string]profiler.Detection `json:"detections"`
+}
+
+// Store persists scans. SQLiteStore is the default implementation;
+// other backends can be plugged in by implementing this interface.
+type Store interface {
+	// Record saves a scan and returns its ID
+	Record(ctx context.Context, scan Scan) (int64, error)
+	// Latest returns up to limit scans of a URL, most recent first
+	Latest(ctx context.Context, url string, limit int) ([]Scan, error)
+	// Close releases the resources held by the store
+	Close() error
+}
+
+// DiffLatest compares the two most recent scans of a URL.
+// It returns false if fewer than two scans have been recorded.
+func DiffLatest(ctx context.Context, store Store, url string) (profiler.DetectionDiff, bool, error) {
+	scans, err := store.Latest(ctx, url, 2)
+	if err != nil {
+		return profiler.DetectionDiff{}, false, err
+	}
+	if len(scans) < 2 {
+		return profiler.DetectionDiff{}, false, nil
+	}
+	return profiler.DiffDetections(scans[1].Detections, scans[0].Detections), true, nil
+}
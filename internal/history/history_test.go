@@ -0,0 +1,51 @@
+package history
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/kavinsood/kitsune/internal/profiler"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSQLiteStore(t *testing.T) {
+	ctx := context.Background()
+
+	store, err := OpenSQLite(filepath.Join(t.TempDir(), "history.db"))
+	require.NoError(t, err, "could not open store")
+	defer store.Close()
+
+	_, ok, err := DiffLatest(ctx, store, "https://example.com")
+	require.NoError(t, err, "could not diff empty history")
+	require.False(t, ok, "diff should need two scans")
+
+	now := time.Now()
+	_, err = store.Record(ctx, Scan{
+		URL:        "https://example.com",
+		ScannedAt:  now.Add(-time.Hour),
+		Detections: map[string]profiler.Detection{"WordPress": {Version: "6.3", Confidence: 100}, "jQuery": {Confidence: 100}},
+	})
+	require.NoError(t, err, "could not record scan")
+
+	id, err := store.Record(ctx, Scan{
+		URL:        "https://example.com",
+		ScannedAt:  now,
+		Detections: map[string]profiler.Detection{"WordPress": {Version: "6.4", Confidence: 100}, "React": {Confidence: 100}},
+	})
+	require.NoError(t, err, "could not record scan")
+
+	scans, err := store.Latest(ctx, "https://example.com", 10)
+	require.NoError(t, err, "could not get scans")
+	require.Len(t, scans, 2, "wrong number of scans")
+	require.Equal(t, id, scans[0].ID, "scans not ordered by recency")
+	require.Equal(t, "6.4", scans[0].Detections["WordPress"].Version, "could not restore detections")
+
+	diff, ok, err := DiffLatest(ctx, store, "https://example.com")
+	require.NoError(t, err, "could not diff scans")
+	require.True(t, ok, "could not diff scans")
+	require.Equal(t, []string{"React"}, diff.Added, "wrong added technologies")
+	require.Equal(t, []string{"jQuery"}, diff.Removed, "wrong removed technologies")
+	require.Equal(t, []profiler.VersionChange{{Technology: "WordPress", OldVersion: "6.3", NewVersion: "6.4"}}, diff.VersionChanged, "wrong version changes")
+}
@@ -0,0 +1,100 @@
+package history
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/kavinsood/kitsune/internal/profiler"
+
+	_ "modernc.org/sqlite" // Pure Go SQLite driver, no cgo required
+)
+
+// schema creates the scans table and the index used to look up a target's history
+const schema = `
+CREATE TABLE IF NOT EXISTS scans (
+	id         INTEGER PRIMARY KEY AUTOINCREMENT,
+	url        TEXT    NOT NULL,
+	scanned_at INTEGER NOT NULL,
+	detections TEXT    NOT NULL
+);
+CREATE INDEX IF NOT EXISTS scans_url_scanned_at ON scans (url, scanned_at);
+`
+
+// SQLiteStore is a Store backed by a SQLite database file
+type SQLiteStore struct {
+	db *sql.DB
+}
+
+// OpenSQLite opens or creates the SQLite database at path
+func OpenSQLite(path string) (*SQLiteStore, error) {
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		return nil, fmt.Errorf("could not open history database %s: %w", path, err)
+	}
+
+	// SQLite allows a single writer at a time
+	db.SetMaxOpenConns(1)
+
+	if _, err := db.Exec(schema); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("could not create history schema: %w", err)
+	}
+	return &SQLiteStore{db: db}, nil
+}
+
+// Record implements Store
+func (s *SQLiteStore) Record(ctx context.Context, scan Scan) (int64, error) {
+	detections, err := json.Marshal(scan.Detections)
+	if err != nil {
+		return 0, fmt.Errorf("could not marshal detections: %w", err)
+	}
+
+	if scan.ScannedAt.IsZero() {
+		scan.ScannedAt = time.Now()
+	}
+
+	result, err := s.db.ExecContext(ctx,
+		"INSERT INTO scans (url, scanned_at, detections) VALUES (?, ?, ?)",
+		scan.URL, scan.ScannedAt.UnixNano(), string(detections))
+	if err != nil {
+		return 0, fmt.Errorf("could not record scan: %w", err)
+	}
+	return result.LastInsertId()
+}
+
+// Latest implements Store
+func (s *SQLiteStore) Latest(ctx context.Context, url string, limit int) ([]Scan, error) {
+	rows, err := s.db.QueryContext(ctx,
+		"SELECT id, url, scanned_at, detections FROM scans WHERE url = ? ORDER BY scanned_at DESC, id DESC LIMIT ?",
+		url, limit)
+	if err != nil {
+		return nil, fmt.Errorf("could not query scans: %w", err)
+	}
+	defer rows.Close()
+
+	var scans []Scan
+	for rows.Next() {
+		var scan Scan
+		var scannedAt int64
+		var detections string
+		if err := rows.Scan(&scan.ID, &scan.URL, &scannedAt, &detections); err != nil {
+			return nil, fmt.Errorf("could not read scan: %w", err)
+		}
+		scan.ScannedAt = time.Unix(0, scannedAt)
+
+		scan.Detections = make(map[string]profiler.Detection)
+		if err := json.Unmarshal([]byte(detections), &scan.Detections); err != nil {
+			return nil, fmt.Errorf("could not unmarshal detections of scan %d: %w", scan.ID, err)
+		}
+		scans = append(scans, scan)
+	}
+	return scans, rows.Err()
+}
+
+// Close implements Store
+func (s *SQLiteStore) Close() error {
+	return s.db.Close()
+}
@@ -0,0 +1,148 @@
+// Package icons serves the icons of technologies, from a local directory such
+// as the one written by update-fingerprints --icons, or lazily fetched from an
+// upstream mirror of the Wappalyzer icon set and cached in memory.
+package icons
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/kavinsood/kitsune/internal/profiler"
+)
+
+// DefaultBaseURL is an upstream mirror of the Wappalyzer icon set
+const DefaultBaseURL = "https://raw.githubusercontent.com/enthec/webappanalyzer/main/src/images/icons/"
+
+// DefaultIcon is the icon of technologies without one of their own
+const DefaultIcon = "default.svg"
+
+const (
+	// maxIconSize bounds the size of a single icon
+	maxIconSize = 512 * 1024
+	// cacheTTL is how long fetched icons are kept in memory
+	cacheTTL = 24 * time.Hour
+)
+
+// ErrNotFound is returned when an icon does not exist
+var ErrNotFound = errors.New("icon not found")
+
+// Icon is an icon image
+type Icon struct {
+	Data        []byte
+	ContentType string
+}
+
+// DataURI returns the icon as a data URI, for inlining in responses
+func (i Icon) DataURI() string {
+	return fmt.Sprintf("data:%s;base64,%s", i.ContentType, base64.StdEncoding.EncodeToString(i.Data))
+}
+
+// Store looks up icons by file name
+type Store struct {
+	dir     string
+	baseURL string
+	client  *http.Client
+	cache   *profiler.LRUCache
+}
+
+// New creates a store reading icons from dir, if not empty, and fetching the
+// others from baseURL, if not empty. Up to capacity fetched icons are cached.
+func New(dir, baseURL string, capacity int) *Store {
+	if baseURL != "" && !strings.HasSuffix(baseURL, "/") {
+		baseURL += "/"
+	}
+	return &Store{
+		dir:     dir,
+		baseURL: baseURL,
+		client:  &http.Client{Timeout: 10 * time.Second},
+		cache:   profiler.NewLRUCache(capacity),
+	}
+}
+
+// contentType returns the content type of an icon from its extension
+func contentType(name string) string {
+	if strings.EqualFold(path.Ext(name), ".svg") {
+		// Not every system registers svg, and some register it with a charset
+		return "image/svg+xml"
+	}
+	if value := mime.TypeByExtension(path.Ext(name)); value != "" {
+		return value
+	}
+	return "application/octet-stream"
+}
+
+// Get returns the icon with the given file name, e.g. "Nginx.svg"
+func (s *Store) Get(ctx context.Context, name string) (Icon, error) {
+	// Icon names come from the fingerprint data, but never leave the icon set
+	if name == "" || name != path.Base(name) || strings.Contains(name, "\\") || name == ".." {
+		return Icon{}, ErrNotFound
+	}
+	icon := Icon{ContentType: contentType(name)}
+
+	if s.dir != "" {
+		data, err := os.ReadFile(filepath.Join(s.dir, name))
+		if err == nil {
+			icon.Data = data
+			return icon, nil
+		}
+		if !errors.Is(err, os.ErrNotExist) {
+			return Icon{}, err
+		}
+	}
+
+	if data, found, _ := s.cache.Get(ctx, name); found {
+		icon.Data = data
+		return icon, nil
+	}
+	if s.baseURL == "" {
+		return Icon{}, ErrNotFound
+	}
+
+	data, err := s.fetch(ctx, name)
+	if err != nil {
+		return Icon{}, err
+	}
+	s.cache.Set(ctx, name, data, cacheTTL)
+	icon.Data = data
+	return icon, nil
+}
+
+// fetch downloads an icon from the upstream mirror
+func (s *Store) fetch(ctx context.Context, name string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", s.baseURL+url.PathEscape(name), nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("could not fetch icon %s: %w", name, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, ErrNotFound
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("could not fetch icon %s: status %d", name, resp.StatusCode)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxIconSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("could not read icon %s: %w", name, err)
+	}
+	if len(data) > maxIconSize {
+		return nil, fmt.Errorf("icon %s exceeds %d bytes", name, maxIconSize)
+	}
+	return data, nil
+}
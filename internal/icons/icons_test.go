@@ -0,0 +1,56 @@
+package icons
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestStore(t *testing.T) {
+	var fetches int
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fetches++
+		if r.URL.EscapedPath() != "/icons/Adobe%20Experience%20Platform.svg" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte("<svg/>"))
+	}))
+	defer upstream.Close()
+
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "Nginx.svg"), []byte("<svg>nginx</svg>"), 0o600), "could not write icon")
+
+	store := New(dir, upstream.URL+"/icons", 10)
+	ctx := context.Background()
+
+	t.Run("local", func(t *testing.T) {
+		icon, err := store.Get(ctx, "Nginx.svg")
+		require.NoError(t, err, "could not get local icon")
+		require.Equal(t, "<svg>nginx</svg>", string(icon.Data), "wrong icon")
+		require.Equal(t, "image/svg+xml", icon.ContentType, "wrong content type")
+		require.Equal(t, "data:image/svg+xml;base64,PHN2Zz5uZ2lueDwvc3ZnPg==", icon.DataURI(), "wrong data uri")
+		require.Zero(t, fetches, "local icons should not be fetched")
+	})
+
+	t.Run("fetched-and-cached", func(t *testing.T) {
+		for i := 0; i < 2; i++ {
+			icon, err := store.Get(ctx, "Adobe Experience Platform.svg")
+			require.NoError(t, err, "could not get fetched icon")
+			require.Equal(t, "<svg/>", string(icon.Data), "wrong icon")
+		}
+		require.Equal(t, 1, fetches, "icon should be fetched once")
+	})
+
+	t.Run("not-found", func(t *testing.T) {
+		for _, name := range []string{"Missing.png", "../secret.svg", "", ".."} {
+			_, err := store.Get(ctx, name)
+			require.ErrorIs(t, err, ErrNotFound, "icon %q should not be found", name)
+		}
+	})
+}
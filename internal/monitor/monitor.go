@@ -0,0 +1,121 @@
+// Package monitor periodically rescans a list of URLs and sends notifications
+// when their technology stack or versions change.
+package monitor
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/kavinsood/kitsune/internal/history"
+	"github.com/kavinsood/kitsune/internal/profiler"
+)
+
+// Change describes a detected change in the technology stack of a URL
+type Change struct {
+	URL       string                 `json:"url"`
+	ScannedAt time.Time              `json:"scanned_at"`
+	Diff      profiler.DetectionDiff `json:"diff"`
+}
+
+// Notifier delivers change notifications
+type Notifier interface {
+	Notify(ctx context.Context, change Change) error
+}
+
+// Monitor rescans URLs on a schedule, records every scan in a history store,
+// and notifies when a scan differs from the previous one
+type Monitor struct {
+	engine    *profiler.Wappalyze
+	store     history.Store
+	urls      []string
+	interval  time.Duration
+	notifiers []Notifier
+	logger    *slog.Logger
+}
+
+// New creates a monitor for urls, rescanned every interval
+func New(engine *profiler.Wappalyze, store history.Store, urls []string, interval time.Duration, notifiers ...Notifier) *Monitor {
+	return &Monitor{
+		engine:    engine,
+		store:     store,
+		urls:      urls,
+		interval:  interval,
+		notifiers: notifiers,
+		logger:    slog.Default(),
+	}
+}
+
+// Run checks all URLs immediately and then every interval, until ctx is done
+func (m *Monitor) Run(ctx context.Context) error {
+	ticker := time.NewTicker(m.interval)
+	defer ticker.Stop()
+
+	for {
+		m.CheckOnce(ctx)
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// CheckOnce scans every URL once and returns the changes that were detected.
+// Failures are logged and do not stop the remaining URLs from being checked.
+func (m *Monitor) CheckOnce(ctx context.Context) []Change {
+	var changes []Change
+	for _, url := range m.urls {
+		if ctx.Err() != nil {
+			break
+		}
+
+		change, changed, err := m.check(ctx, url)
+		if err != nil {
+			m.logger.WarnContext(ctx, "monitor could not check url", "url", url, "error", err)
+			continue
+		}
+		if !changed {
+			continue
+		}
+		changes = append(changes, change)
+
+		for _, notifier := range m.notifiers {
+			if err := notifier.Notify(ctx, change); err != nil {
+				m.logger.WarnContext(ctx, "monitor could not send notification", "url", url, "error", err)
+			}
+		}
+	}
+	return changes
+}
+
+// check scans a single URL and compares it with its previous recorded scan
+func (m *Monitor) check(ctx context.Context, url string) (Change, bool, error) {
+	previous, err := m.store.Latest(ctx, url, 1)
+	if err != nil {
+		return Change{}, false, err
+	}
+
+	result, err := m.engine.FingerprintURL(ctx, url)
+	if err != nil {
+		return Change{}, false, err
+	}
+
+	scan := history.Scan{URL: url, ScannedAt: time.Now(), Detections: result.GetDetections()}
+	if _, err := m.store.Record(ctx, scan); err != nil {
+		return Change{}, false, fmt.Errorf("could not record scan: %w", err)
+	}
+
+	// The first scan of a URL is the baseline, not a change
+	if len(previous) == 0 {
+		return Change{}, false, nil
+	}
+
+	diff := profiler.DiffDetections(previous[0].Detections, scan.Detections)
+	if diff.Empty() {
+		return Change{}, false, nil
+	}
+	return Change{URL: url, ScannedAt: scan.ScannedAt, Diff: diff}, true, nil
+}
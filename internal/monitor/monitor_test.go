@@ -0,0 +1,64 @@
+package monitor
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/kavinsood/kitsune/internal/history"
+	"github.com/kavinsood/kitsune/internal/profiler"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMonitorCheckOnce(t *testing.T) {
+	var mutex sync.Mutex
+	server := "nginx"
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mutex.Lock()
+		defer mutex.Unlock()
+		w.Header().Set("Server", server)
+		w.Write([]byte("<html></html>"))
+	}))
+	defer target.Close()
+
+	var payloads []map[string]interface{}
+	webhook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var payload map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&payload)
+		mutex.Lock()
+		payloads = append(payloads, payload)
+		mutex.Unlock()
+	}))
+	defer webhook.Close()
+
+	engine, err := profiler.New()
+	require.NoError(t, err, "could not create wappalyzer")
+
+	store, err := history.OpenSQLite(filepath.Join(t.TempDir(), "history.db"))
+	require.NoError(t, err, "could not open store")
+	defer store.Close()
+
+	m := New(engine, store, []string{target.URL}, time.Hour, NewWebhookNotifier(webhook.URL), NewSlackNotifier(webhook.URL))
+
+	// The first scan is the baseline
+	require.Empty(t, m.CheckOnce(context.Background()), "baseline scan reported as a change")
+	require.Empty(t, m.CheckOnce(context.Background()), "unchanged scan reported as a change")
+
+	mutex.Lock()
+	server = "Apache"
+	mutex.Unlock()
+
+	changes := m.CheckOnce(context.Background())
+	require.Len(t, changes, 1, "change not detected")
+	require.Equal(t, []string{"Apache HTTP Server"}, changes[0].Diff.Added, "wrong added technologies")
+	require.Equal(t, []string{"Nginx"}, changes[0].Diff.Removed, "wrong removed technologies")
+
+	require.Len(t, payloads, 2, "notifications not sent")
+	require.Equal(t, target.URL, payloads[0]["url"], "wrong webhook payload")
+	require.Contains(t, payloads[1]["text"], "+ Apache HTTP Server", "wrong slack payload")
+}
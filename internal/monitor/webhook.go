@@ -0,0 +1,71 @@
+package monitor
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/kavinsood/kitsune/internal/webhook"
+)
+
+// WebhookNotifier posts changes to an HTTP endpoint, either as a Change JSON
+// document or as a Slack-compatible {"text": ...} message. Posts are signed
+// with Secret, if set, and retried according to Retry.
+type WebhookNotifier struct {
+	URL    string
+	Slack  bool
+	Secret string
+	Retry  webhook.RetryPolicy
+	Client *http.Client
+}
+
+// changeEvent is the event type of the changes posted to webhooks
+const changeEvent = "monitor.change"
+
+// NewWebhookNotifier creates a notifier posting Change JSON documents to url
+func NewWebhookNotifier(url string) *WebhookNotifier {
+	return &WebhookNotifier{URL: url, Retry: webhook.DefaultRetryPolicy(), Client: &http.Client{Timeout: 10 * time.Second}}
+}
+
+// NewSlackNotifier creates a notifier posting Slack-compatible messages to an
+// incoming webhook url
+func NewSlackNotifier(url string) *WebhookNotifier {
+	return &WebhookNotifier{URL: url, Slack: true, Retry: webhook.DefaultRetryPolicy(), Client: &http.Client{Timeout: 10 * time.Second}}
+}
+
+// Notify implements Notifier
+func (w *WebhookNotifier) Notify(ctx context.Context, change Change) error {
+	var payload interface{} = change
+	if w.Slack {
+		payload = map[string]string{"text": FormatChange(change)}
+	}
+	target := webhook.Target{URL: w.URL, Secret: w.Secret, Retry: w.Retry}
+	return webhook.New(target, w.Client).Send(ctx, changeEvent, payload)
+}
+
+// FormatChange renders a change as human readable text
+func FormatChange(change Change) string {
+	var builder strings.Builder
+	fmt.Fprintf(&builder, "Technology changes detected on %s\n", change.URL)
+	for _, technology := range change.Diff.Added {
+		fmt.Fprintf(&builder, "+ %s\n", technology)
+	}
+	for _, technology := range change.Diff.Removed {
+		fmt.Fprintf(&builder, "- %s\n", technology)
+	}
+	for _, versionChange := range change.Diff.VersionChanged {
+		fmt.Fprintf(&builder, "~ %s %s -> %s\n", versionChange.Technology,
+			versionOrUnknown(versionChange.OldVersion), versionOrUnknown(versionChange.NewVersion))
+	}
+	return strings.TrimSuffix(builder.String(), "\n")
+}
+
+// versionOrUnknown returns a printable version
+func versionOrUnknown(version string) string {
+	if version == "" {
+		return "(unknown)"
+	}
+	return version
+}
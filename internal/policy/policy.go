@@ -0,0 +1,261 @@
+// Package policy decides which targets may be scanned, from allow and deny
+// lists of domains, IP ranges, ports and schemes.
+package policy
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/netip"
+	"net/url"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Config lists the allowed and denied targets. Deny rules take precedence.
+// When an allow list is empty, everything not denied is allowed on that dimension.
+//
+// Domains match the domain itself and its subdomains, so a bare TLD such as
+// "gov" matches every domain under it. CIDRs are matched against the target
+// IP, or every address its hostname resolves to.
+type Config struct {
+	AllowDomains []string `json:"allow_domains,omitempty" yaml:"allow_domains,omitempty"`
+	DenyDomains  []string `json:"deny_domains,omitempty" yaml:"deny_domains,omitempty"`
+	AllowCIDRs   []string `json:"allow_cidrs,omitempty" yaml:"allow_cidrs,omitempty"`
+	DenyCIDRs    []string `json:"deny_cidrs,omitempty" yaml:"deny_cidrs,omitempty"`
+	AllowPorts   []int    `json:"allow_ports,omitempty" yaml:"allow_ports,omitempty"`
+	DenyPorts    []int    `json:"deny_ports,omitempty" yaml:"deny_ports,omitempty"`
+	AllowSchemes []string `json:"allow_schemes,omitempty" yaml:"allow_schemes,omitempty"`
+}
+
+// Resolver looks up the addresses of a hostname
+type Resolver interface {
+	LookupNetIP(ctx context.Context, network, host string) ([]netip.Addr, error)
+}
+
+// Policy evaluates targets against a Config
+type Policy struct {
+	config     Config
+	allowCIDRs []netip.Prefix
+	denyCIDRs  []netip.Prefix
+	resolver   Resolver
+}
+
+// New compiles a policy from its configuration
+func New(config Config) (*Policy, error) {
+	p := &Policy{config: config, resolver: net.DefaultResolver}
+
+	var err error
+	if p.allowCIDRs, err = parsePrefixes(config.AllowCIDRs); err != nil {
+		return nil, err
+	}
+	if p.denyCIDRs, err = parsePrefixes(config.DenyCIDRs); err != nil {
+		return nil, err
+	}
+	p.config.AllowDomains = normalizeDomains(config.AllowDomains)
+	p.config.DenyDomains = normalizeDomains(config.DenyDomains)
+	return p, nil
+}
+
+// Load reads a JSON policy configuration from path
+func Load(path string) (*Policy, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("could not read policy: %w", err)
+	}
+
+	var config Config
+	if err := json.Unmarshal(data, &config); err != nil {
+		return nil, fmt.Errorf("could not parse policy %s: %w", path, err)
+	}
+	return New(config)
+}
+
+// parsePrefixes parses CIDRs, accepting single addresses as well
+func parsePrefixes(cidrs []string) ([]netip.Prefix, error) {
+	prefixes := make([]netip.Prefix, 0, len(cidrs))
+	for _, cidr := range cidrs {
+		if !strings.Contains(cidr, "/") {
+			addr, err := netip.ParseAddr(cidr)
+			if err != nil {
+				return nil, fmt.Errorf("invalid cidr %q: %w", cidr, err)
+			}
+			prefixes = append(prefixes, netip.PrefixFrom(addr, addr.BitLen()))
+			continue
+		}
+
+		prefix, err := netip.ParsePrefix(cidr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid cidr %q: %w", cidr, err)
+		}
+		prefixes = append(prefixes, prefix.Masked())
+	}
+	return prefixes, nil
+}
+
+// normalizeDomains returns a normalized copy of domains
+func normalizeDomains(domains []string) []string {
+	normalized := make([]string, 0, len(domains))
+	for _, domain := range domains {
+		normalized = append(normalized, normalizeDomain(domain))
+	}
+	return normalized
+}
+
+// normalizeDomain lowercases a domain and strips leading and trailing dots
+func normalizeDomain(domain string) string {
+	return strings.Trim(strings.ToLower(strings.TrimSpace(domain)), ".")
+}
+
+// Decision is the outcome of evaluating a target
+type Decision struct {
+	Allowed bool
+	// Reason explains which rule denied the target
+	Reason string
+}
+
+// Evaluate decides whether rawURL may be scanned. Hostnames are only resolved
+// when CIDR rules are configured; hostnames that cannot be resolved are denied.
+//
+// The addresses a hostname resolves to may change before it is connected to,
+// so fetchers should also check the address they dial with EvaluateAddr.
+func (p *Policy) Evaluate(ctx context.Context, rawURL string) Decision {
+	decision, host, addr := p.evaluateURL(rawURL)
+	if !decision.Allowed || (len(p.allowCIDRs) == 0 && len(p.denyCIDRs) == 0) {
+		return decision
+	}
+
+	addrs := []netip.Addr{addr}
+	if !addr.IsValid() {
+		var err error
+		addrs, err = p.resolver.LookupNetIP(ctx, "ip", host)
+		if err != nil || len(addrs) == 0 {
+			return Decision{Reason: fmt.Sprintf("could not resolve %q", host)}
+		}
+	}
+	for _, addr := range addrs {
+		if decision := p.EvaluateAddr(addr); !decision.Allowed {
+			return decision
+		}
+	}
+	return Decision{Allowed: true}
+}
+
+// EvaluateURL decides whether rawURL may be requested from its scheme, port
+// and domain alone, without resolving it. Redirects and the resources of a
+// page are checked with it, the address they are fetched from with EvaluateAddr.
+func (p *Policy) EvaluateURL(rawURL string) Decision {
+	decision, _, addr := p.evaluateURL(rawURL)
+	if decision.Allowed && addr.IsValid() {
+		return p.EvaluateAddr(addr)
+	}
+	return decision
+}
+
+// EvaluateAddr decides whether addr may be connected to from the CIDR rules
+func (p *Policy) EvaluateAddr(addr netip.Addr) Decision {
+	addr = addr.Unmap()
+	if prefix, ok := matchPrefix(p.denyCIDRs, addr); ok {
+		return Decision{Reason: fmt.Sprintf("address %s is in denied range %s", addr, prefix)}
+	}
+	if len(p.allowCIDRs) > 0 {
+		if _, ok := matchPrefix(p.allowCIDRs, addr); !ok {
+			return Decision{Reason: fmt.Sprintf("address %s is not in an allowed range", addr)}
+		}
+	}
+	return Decision{Allowed: true}
+}
+
+// evaluateURL applies the scheme, port and domain rules to rawURL, returning
+// the normalized host and, when the host is an IP address, the address
+func (p *Policy) evaluateURL(rawURL string) (Decision, string, netip.Addr) {
+	target, err := url.Parse(rawURL)
+	if err != nil || target.Hostname() == "" {
+		return Decision{Reason: "invalid target url"}, "", netip.Addr{}
+	}
+
+	scheme := strings.ToLower(target.Scheme)
+	if len(p.config.AllowSchemes) > 0 && !containsFold(p.config.AllowSchemes, scheme) {
+		return Decision{Reason: fmt.Sprintf("scheme %q is not allowed", scheme)}, "", netip.Addr{}
+	}
+
+	port, err := targetPort(target)
+	if err != nil {
+		return Decision{Reason: err.Error()}, "", netip.Addr{}
+	}
+	if slices.Contains(p.config.DenyPorts, port) {
+		return Decision{Reason: fmt.Sprintf("port %d is denied", port)}, "", netip.Addr{}
+	}
+	if len(p.config.AllowPorts) > 0 && !slices.Contains(p.config.AllowPorts, port) {
+		return Decision{Reason: fmt.Sprintf("port %d is not allowed", port)}, "", netip.Addr{}
+	}
+
+	host := normalizeDomain(target.Hostname())
+	addr, err := netip.ParseAddr(host)
+	if err != nil {
+		if domain, ok := matchDomain(p.config.DenyDomains, host); ok {
+			return Decision{Reason: fmt.Sprintf("domain %q is denied", domain)}, "", netip.Addr{}
+		}
+		if len(p.config.AllowDomains) > 0 {
+			if _, ok := matchDomain(p.config.AllowDomains, host); !ok {
+				return Decision{Reason: fmt.Sprintf("domain %q is not allowed", host)}, "", netip.Addr{}
+			}
+		}
+		return Decision{Allowed: true}, host, netip.Addr{}
+	}
+	if len(p.config.AllowDomains) > 0 {
+		// IP targets cannot match a domain allowlist
+		return Decision{Reason: fmt.Sprintf("address %s is not an allowed domain", addr)}, "", netip.Addr{}
+	}
+	return Decision{Allowed: true}, host, addr
+}
+
+// targetPort returns the explicit port of a URL or the default port of its scheme
+func targetPort(target *url.URL) (int, error) {
+	if port := target.Port(); port != "" {
+		value, err := strconv.Atoi(port)
+		if err != nil {
+			return 0, fmt.Errorf("invalid port %q", port)
+		}
+		return value, nil
+	}
+	switch strings.ToLower(target.Scheme) {
+	case "http":
+		return 80, nil
+	case "https":
+		return 443, nil
+	}
+	return 0, fmt.Errorf("no port for scheme %q", target.Scheme)
+}
+
+// matchDomain returns the entry host equals or is a subdomain of
+func matchDomain(domains []string, host string) (string, bool) {
+	for _, domain := range domains {
+		if host == domain || strings.HasSuffix(host, "."+domain) {
+			return domain, true
+		}
+	}
+	return "", false
+}
+
+// matchPrefix returns the range containing addr
+func matchPrefix(prefixes []netip.Prefix, addr netip.Addr) (netip.Prefix, bool) {
+	for _, prefix := range prefixes {
+		if prefix.Contains(addr) {
+			return prefix, true
+		}
+	}
+	return netip.Prefix{}, false
+}
+
+func containsFold(values []string, value string) bool {
+	for _, v := range values {
+		if strings.EqualFold(v, value) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,123 @@
+package policy
+
+import (
+	"context"
+	"errors"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// fakeResolver resolves hostnames from a fixed table
+type fakeResolver map[string][]netip.Addr
+
+func (f fakeResolver) LookupNetIP(ctx context.Context, network, host string) ([]netip.Addr, error) {
+	if addrs, ok := f[host]; ok {
+		return addrs, nil
+	}
+	return nil, errors.New("no such host")
+}
+
+func TestEvaluate(t *testing.T) {
+	p, err := New(Config{
+		DenyDomains:  []string{"internal.example.com", ".MIL"},
+		DenyCIDRs:    []string{"10.0.0.0/8", "169.254.169.254"},
+		DenyPorts:    []int{22},
+		AllowSchemes: []string{"http", "https"},
+	})
+	require.NoError(t, err, "could not create policy")
+	p.resolver = fakeResolver{
+		"example.com":         {netip.MustParseAddr("93.184.215.14")},
+		"private.example.com": {netip.MustParseAddr("93.184.215.14"), netip.MustParseAddr("10.1.2.3")},
+	}
+
+	tests := []struct {
+		name    string
+		url     string
+		allowed bool
+	}{
+		{"allowed", "https://example.com/", true},
+		{"denied-domain", "https://internal.example.com", false},
+		{"denied-subdomain", "https://api.internal.example.com", false},
+		{"denied-tld", "https://army.mil", false},
+		{"denied-resolved-address", "https://private.example.com", false},
+		{"denied-ip", "http://169.254.169.254/latest/meta-data", false},
+		{"denied-mapped-ip", "http://[::ffff:10.0.0.1]/", false},
+		{"denied-port", "http://example.com:22", false},
+		{"denied-scheme", "ftp://example.com", false},
+		{"unresolvable", "https://unknown.example.net", false},
+		{"invalid", "https://", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			decision := p.Evaluate(context.Background(), tt.url)
+			require.Equal(t, tt.allowed, decision.Allowed, "wrong decision: %s", decision.Reason)
+			if !tt.allowed {
+				require.NotEmpty(t, decision.Reason, "denials should have a reason")
+			}
+		})
+	}
+}
+
+func TestEvaluateAllowlists(t *testing.T) {
+	p, err := New(Config{
+		AllowDomains: []string{"example.com"},
+		AllowPorts:   []int{443},
+	})
+	require.NoError(t, err, "could not create policy")
+
+	tests := []struct {
+		url     string
+		allowed bool
+	}{
+		{"https://www.example.com", true},
+		{"https://example.org", false},
+		{"https://notexample.com", false},
+		{"http://example.com", false},
+		{"https://93.184.215.14", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.url, func(t *testing.T) {
+			decision := p.Evaluate(context.Background(), tt.url)
+			require.Equal(t, tt.allowed, decision.Allowed, "wrong decision: %s", decision.Reason)
+		})
+	}
+}
+
+func TestEvaluateURLAndAddr(t *testing.T) {
+	p, err := New(Config{
+		DenyDomains: []string{"internal.example.com"},
+		DenyCIDRs:   []string{"169.254.0.0/16"},
+		DenyPorts:   []int{22},
+	})
+	require.NoError(t, err, "could not create policy")
+	// URLs are checked without resolving them
+	p.resolver = fakeResolver{}
+
+	require.True(t, p.EvaluateURL("https://example.com/").Allowed, "unresolved domain should be allowed")
+	require.False(t, p.EvaluateURL("https://api.internal.example.com/").Allowed, "denied domain should be refused")
+	require.False(t, p.EvaluateURL("http://example.com:22/").Allowed, "denied port should be refused")
+	require.False(t, p.EvaluateURL("http://169.254.169.254/latest/meta-data").Allowed, "denied address should be refused")
+
+	require.False(t, p.EvaluateAddr(netip.MustParseAddr("169.254.169.254")).Allowed, "denied range should be refused")
+	require.False(t, p.EvaluateAddr(netip.MustParseAddr("::ffff:169.254.169.254")).Allowed, "mapped address should be refused")
+	require.True(t, p.EvaluateAddr(netip.MustParseAddr("93.184.215.14")).Allowed, "public address should be allowed")
+}
+
+func TestLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "policy.json")
+	require.NoError(t, os.WriteFile(path, []byte(`{"deny_cidrs": ["127.0.0.0/8"]}`), 0o600), "could not write policy")
+
+	p, err := Load(path)
+	require.NoError(t, err, "could not load policy")
+	require.False(t, p.Evaluate(context.Background(), "http://127.0.0.1:8080").Allowed, "loopback should be denied")
+
+	require.NoError(t, os.WriteFile(path, []byte(`{"deny_cidrs": ["not-a-cidr"]}`), 0o600), "could not write policy")
+	_, err = Load(path)
+	require.Error(t, err, "invalid cidr should fail")
+}
@@ -3,6 +3,7 @@ package profiler
 import (
 	"context"
 	"io"
+	"log/slog"
 	"net/http"
 	"net/url"
 	"strings"
@@ -13,24 +14,34 @@ import (
 // AssetURL represents an asset to be fetched with its type
 type AssetURL struct {
 	URL      string // The URL of the asset
-	Type     string // "script" or "style"
+	Type     string // "script", "style" or "manifest"
 	Priority int    // Priority for processing (higher numbers are processed first)
 }
 
 // AssetFetcher manages concurrent fetching of external assets
 // It provides a channel for receiving URLs and handles all network I/O
 type AssetFetcher struct {
-	baseURL    string              // Base URL for resolving relative paths
-	client     *http.Client        // HTTP client for making requests
-	ctx        context.Context     // Context for cancellation/timeout
-	wg         *sync.WaitGroup     // WaitGroup for tracking goroutines
-	urlChan    chan AssetURL       // Channel for receiving asset URLs to fetch
-	mutex      sync.Mutex          // Mutex for protecting shared maps
-	jsContent  *map[string]string  // Pointer to map of JavaScript content by URL
-	cssContent *map[string]string  // Pointer to map of CSS content by URL
-	maxWorkers int                 // Maximum concurrent requests
-	semaphore  chan struct{}       // Semaphore for limiting concurrent requests
-	dnsRecords map[string][]string // Results from DNS lookups
+	baseURL    string                  // Base URL for resolving relative paths
+	client     *http.Client            // HTTP client for making requests
+	ctx        context.Context         // Context for cancellation/timeout
+	wg         *sync.WaitGroup         // WaitGroup for tracking goroutines
+	urlChan    chan AssetURL           // Channel for receiving asset URLs to fetch
+	mutex      sync.Mutex              // Mutex for protecting shared maps
+	jsContent  *map[string]string      // Pointer to map of JavaScript content by URL
+	cssContent *map[string]string      // Pointer to map of CSS content by URL
+	maxWorkers int                     // Maximum concurrent requests
+	semaphore  chan struct{}           // Semaphore for limiting concurrent requests
+	dnsRecords map[string][]string     // Results from DNS lookups
+	manifest   string                  // Content of the web app manifest, if any
+	stats      *statsRecorder          // Telemetry recorder for the analysis, if any
+	retry      RetryPolicy             // Retry policy for transient fetch failures
+	limiter    *rateLimiter            // Rate limit shared with the other requests of the instance
+	logger     *slog.Logger            // Logger for failed fetches
+	skipped    map[string]bool         // Asset types dropped instead of fetched
+	policy     AssetPolicy             // Per host and per analysis limits
+	hosts      map[string]*hostLimiter // Limiters of the hosts assets were fetched from
+	fetched    int                     // Assets fetched so far, against policy.MaxAssets
+	bytesRead  int64                   // Asset bytes read so far, against policy.MaxBytes
 }
 
 // NewAssetFetcher creates a new AssetFetcher instance
@@ -51,13 +62,29 @@ func NewAssetFetcher(baseURL string, ctx context.Context, wg *sync.WaitGroup, ma
 		maxWorkers: maxWorkers,
 		semaphore:  make(chan struct{}, maxWorkers),
 		dnsRecords: make(map[string][]string),
+		logger:     discardLogger,
+	}
+}
+
+// skip drops the assets of the given types instead of fetching them.
+// It must be called before Start.
+func (af *AssetFetcher) skip(assetTypes ...string) {
+	if af.skipped == nil {
+		af.skipped = make(map[string]bool, len(assetTypes))
+	}
+	for _, assetType := range assetTypes {
+		af.skipped[assetType] = true
 	}
 }
 
 // Start launches the asset fetcher pipeline
 // It spawns the main consumer goroutine that processes incoming URLs
 func (af *AssetFetcher) Start() {
+	// The consumer is tracked by the WaitGroup too, so that workers are never
+	// added after a Wait has observed a zero counter
+	af.wg.Add(1)
 	go func() {
+		defer af.wg.Done()
 		for assetURL := range af.urlChan {
 			// Create a worker goroutine for each URL
 			af.wg.Add(1)
@@ -75,6 +102,9 @@ func (af *AssetFetcher) Stop() {
 // AddURL adds an asset URL to be fetched
 // This is a convenience method that can be used instead of sending directly to the channel
 func (af *AssetFetcher) AddURL(url string, assetType string, priority int) {
+	if af.skipped[assetType] {
+		return
+	}
 	select {
 	case af.urlChan <- AssetURL{URL: url, Type: assetType, Priority: priority}:
 		// URL was added successfully
@@ -103,6 +133,13 @@ func (af *AssetFetcher) processURL(assetURL AssetURL) {
 		return
 	}
 
+	// Respect the per host and per analysis limits of the policy
+	release, ok := af.acquire(absoluteURL)
+	if !ok {
+		return
+	}
+	defer release()
+
 	// Create request with context
 	req, err := http.NewRequestWithContext(af.ctx, "GET", absoluteURL, nil)
 	if err != nil {
@@ -110,19 +147,26 @@ func (af *AssetFetcher) processURL(assetURL AssetURL) {
 	}
 
 	// Add common headers
-	req.Header.Set("User-Agent", "Mozilla/5.0 (Windows NT 6.3; WOW64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/117.0.5931.0 Safari/537.36")
+	req.Header.Set("User-Agent", userAgentOf(af.ctx).Header)
 	if assetURL.Type == "script" {
 		req.Header.Set("Accept", "*/*")
 	} else if assetURL.Type == "style" {
 		req.Header.Set("Accept", "text/css,*/*;q=0.1")
+	} else if assetURL.Type == "manifest" {
+		req.Header.Set("Accept", "application/manifest+json,application/json,*/*;q=0.1")
 	}
 
 	// Make the request
-	resp, err := af.client.Do(req)
+	start := time.Now()
+	defer func() { af.stats.addFetch(assetURL.Type, time.Since(start)) }()
+
+	resp, err := doWithRetry(af.client, req, af.retry, af.limiter)
 	if err != nil {
+		af.logger.DebugContext(af.ctx, "asset fetch failed", "url", absoluteURL, "type", assetURL.Type, "error", err)
 		return
 	}
 	defer resp.Body.Close()
+	resp.Body = af.limitBody(af.stats.countBody(resp.Body))
 
 	// Handle different asset types
 	switch assetURL.Type {
@@ -130,6 +174,8 @@ func (af *AssetFetcher) processURL(assetURL AssetURL) {
 		af.handleScriptResponse(resp, assetURL.URL)
 	case "style":
 		af.handleStyleResponse(resp, assetURL.URL)
+	case "manifest":
+		af.handleManifestResponse(resp)
 	}
 }
 
@@ -196,3 +242,79 @@ func (af *AssetFetcher) SetDNSRecords(records map[string][]string) {
 	defer af.mutex.Unlock()
 	af.dnsRecords = records
 }
+
+// handleManifestResponse processes a web app manifest response
+func (af *AssetFetcher) handleManifestResponse(resp *http.Response) {
+	if resp.StatusCode != http.StatusOK {
+		return
+	}
+
+	// Manifests are served as application/manifest+json or plain JSON
+	contentType := resp.Header.Get("Content-Type")
+	if !strings.Contains(contentType, "json") && !strings.Contains(contentType, "text/plain") {
+		return
+	}
+
+	// Read the content with a limit to avoid huge files
+	content, err := io.ReadAll(io.LimitReader(resp.Body, 256*1024)) // 256KB limit
+	if err != nil {
+		return
+	}
+
+	// Store the result
+	af.mutex.Lock()
+	af.manifest = string(content)
+	af.mutex.Unlock()
+}
+
+// Manifest returns the content of the fetched web app manifest, if any
+func (af *AssetFetcher) Manifest() string {
+	af.mutex.Lock()
+	defer af.mutex.Unlock()
+	return af.manifest
+}
+
+// FetchText synchronously fetches a single text resource relative to the base URL.
+// It is used for resources discovered only after the pipeline has drained,
+// such as service worker scripts registered from external JavaScript.
+func (af *AssetFetcher) FetchText(rawURL string) (string, bool) {
+	if af.skipped["script"] {
+		return "", false
+	}
+	absoluteURL, err := af.resolveURL(rawURL)
+	if err != nil {
+		return "", false
+	}
+
+	release, ok := af.acquire(absoluteURL)
+	if !ok {
+		return "", false
+	}
+	defer release()
+
+	req, err := http.NewRequestWithContext(af.ctx, "GET", absoluteURL, nil)
+	if err != nil {
+		return "", false
+	}
+	req.Header.Set("User-Agent", userAgentOf(af.ctx).Header)
+
+	start := time.Now()
+	defer func() { af.stats.addFetch("script", time.Since(start)) }()
+
+	resp, err := doWithRetry(af.client, req, af.retry, af.limiter)
+	if err != nil {
+		return "", false
+	}
+	defer resp.Body.Close()
+	resp.Body = af.limitBody(af.stats.countBody(resp.Body))
+
+	if resp.StatusCode != http.StatusOK {
+		return "", false
+	}
+
+	content, err := io.ReadAll(io.LimitReader(resp.Body, 1024*1024)) // 1MB limit
+	if err != nil {
+		return "", false
+	}
+	return string(content), true
+}
@@ -0,0 +1,165 @@
+package profiler
+
+import (
+	"io"
+	"net/url"
+	"sync"
+	"time"
+)
+
+// AssetPolicy limits the load the asset fetcher puts on a target and the
+// memory it uses on pathological pages. The zero value sets no limits beyond
+// the fetcher's own concurrency. The caps an analysis reaches are listed in
+// AnalysisStats.LimitsHit.
+type AssetPolicy struct {
+	// MaxConnsPerHost caps the concurrent asset requests to a single host.
+	// Zero leaves it unlimited.
+	MaxConnsPerHost int
+	// HostDelay is the minimum delay between the start of two asset requests
+	// to the same host
+	HostDelay time.Duration
+	// MaxAssets caps the number of assets fetched per analysis, including
+	// service workers. Assets past the cap are dropped.
+	MaxAssets int
+	// MaxBytes caps the asset bytes read per analysis. Once reached, further
+	// assets are dropped and the body being read is truncated.
+	MaxBytes int64
+}
+
+// DefaultAssetPolicy returns an asset policy suited to scanning third party
+// sites: four connections per host, 100ms between requests to the same host,
+// and at most 50 assets or 10 MB per analysis.
+func DefaultAssetPolicy() AssetPolicy {
+	return AssetPolicy{
+		MaxConnsPerHost: 4,
+		HostDelay:       100 * time.Millisecond,
+		MaxAssets:       50,
+		MaxBytes:        10 * 1024 * 1024,
+	}
+}
+
+// hostLimiter enforces the per host limits of an asset policy on one host
+type hostLimiter struct {
+	slots chan struct{} // Connection slots, nil if unlimited
+	mutex sync.Mutex
+	next  time.Time // Earliest start of the next request
+}
+
+// acquire reserves an asset of the budget and waits for the limits of the host
+// of absoluteURL. It returns false if the asset must be dropped, and otherwise
+// a function releasing the connection slot.
+func (af *AssetFetcher) acquire(absoluteURL string) (func(), bool) {
+	af.mutex.Lock()
+	var exhausted Limit
+	if af.policy.MaxAssets > 0 && af.fetched >= af.policy.MaxAssets {
+		exhausted = LimitAssets
+	} else if af.policy.MaxBytes > 0 && af.bytesRead >= af.policy.MaxBytes {
+		exhausted = LimitBytes
+	}
+	if exhausted != "" {
+		af.mutex.Unlock()
+		af.stats.limit(exhausted)
+		af.logger.DebugContext(af.ctx, "asset budget exhausted", "url", absoluteURL)
+		return nil, false
+	}
+	af.fetched++
+
+	var host string
+	if parsedURL, err := url.Parse(absoluteURL); err == nil {
+		host = parsedURL.Host
+	}
+	limiter, ok := af.hosts[host]
+	if !ok {
+		limiter = &hostLimiter{}
+		if af.policy.MaxConnsPerHost > 0 {
+			limiter.slots = make(chan struct{}, af.policy.MaxConnsPerHost)
+		}
+		if af.hosts == nil {
+			af.hosts = make(map[string]*hostLimiter)
+		}
+		af.hosts[host] = limiter
+	}
+	af.mutex.Unlock()
+
+	release := func() {}
+	if limiter.slots != nil {
+		select {
+		case limiter.slots <- struct{}{}:
+			release = func() { <-limiter.slots }
+		case <-af.ctx.Done():
+			return nil, false
+		}
+	}
+
+	if af.policy.HostDelay > 0 {
+		limiter.mutex.Lock()
+		now := time.Now()
+		start := limiter.next
+		if start.Before(now) {
+			start = now
+		}
+		limiter.next = start.Add(af.policy.HostDelay)
+		limiter.mutex.Unlock()
+
+		if wait := time.Until(start); wait > 0 {
+			timer := time.NewTimer(wait)
+			defer timer.Stop()
+			select {
+			case <-timer.C:
+			case <-af.ctx.Done():
+				release()
+				return nil, false
+			}
+		}
+	}
+
+	// The byte budget may have been spent by the requests waited for
+	if af.bytesExhausted() {
+		release()
+		af.stats.limit(LimitBytes)
+		af.logger.DebugContext(af.ctx, "asset budget exhausted", "url", absoluteURL)
+		return nil, false
+	}
+	return release, true
+}
+
+// bytesExhausted reports whether the byte budget of the analysis is spent
+func (af *AssetFetcher) bytesExhausted() bool {
+	af.mutex.Lock()
+	defer af.mutex.Unlock()
+	return af.policy.MaxBytes > 0 && af.bytesRead >= af.policy.MaxBytes
+}
+
+// limitBody truncates body once the byte budget of the analysis is spent
+func (af *AssetFetcher) limitBody(body io.ReadCloser) io.ReadCloser {
+	if af.policy.MaxBytes <= 0 {
+		return body
+	}
+	return &budgetReadCloser{ReadCloser: body, fetcher: af}
+}
+
+// budgetReadCloser reads from an asset body within the byte budget of its fetcher
+type budgetReadCloser struct {
+	io.ReadCloser
+	fetcher *AssetFetcher
+}
+
+func (b *budgetReadCloser) Read(p []byte) (int, error) {
+	af := b.fetcher
+	af.mutex.Lock()
+	remaining := af.policy.MaxBytes - af.bytesRead
+	af.mutex.Unlock()
+	if remaining <= 0 {
+		af.stats.limit(LimitBytes)
+		return 0, io.EOF
+	}
+	if int64(len(p)) > remaining {
+		p = p[:remaining]
+	}
+
+	n, err := b.ReadCloser.Read(p)
+	af.mutex.Lock()
+	af.bytesRead += int64(n)
+	af.mutex.Unlock()
+	return n, err
+}
@@ -0,0 +1,93 @@
+package profiler
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestAssetPolicy(t *testing.T) {
+	var mutex sync.Mutex
+	var active, maxActive int
+	var starts []time.Time
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mutex.Lock()
+		active++
+		maxActive = max(maxActive, active)
+		starts = append(starts, time.Now())
+		mutex.Unlock()
+
+		time.Sleep(20 * time.Millisecond)
+		w.Header().Set("Content-Type", "application/javascript")
+		w.Write([]byte(strings.Repeat("a", 100)))
+
+		mutex.Lock()
+		active--
+		mutex.Unlock()
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name     string
+		policy   AssetPolicy
+		requests int
+		maxConns int
+		maxBytes int
+		limits   []Limit
+	}{
+		{name: "unlimited", requests: 5, maxConns: 5, maxBytes: 500},
+		{name: "connections", policy: AssetPolicy{MaxConnsPerHost: 1}, requests: 5, maxConns: 1, maxBytes: 500},
+		{name: "delay", policy: AssetPolicy{HostDelay: 50 * time.Millisecond}, requests: 5, maxConns: 5, maxBytes: 500},
+		{name: "assets", policy: AssetPolicy{MaxAssets: 3}, requests: 3, maxConns: 3, maxBytes: 300, limits: []Limit{LimitAssets}},
+		{name: "bytes", policy: AssetPolicy{MaxConnsPerHost: 1, MaxBytes: 150}, requests: 2, maxConns: 1, maxBytes: 150, limits: []Limit{LimitBytes}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mutex.Lock()
+			maxActive = 0
+			starts = nil
+			mutex.Unlock()
+
+			var wg sync.WaitGroup
+			jsContent := make(map[string]string)
+			cssContent := make(map[string]string)
+			fetcher := NewAssetFetcher(server.URL, context.Background(), &wg, 10, &jsContent, &cssContent)
+			fetcher.policy = tt.policy
+			fetcher.stats = newStatsRecorder()
+			fetcher.Start()
+			for _, name := range []string{"a", "b", "c", "d", "e"} {
+				fetcher.AddURL("/"+name+".js", "script", 1)
+			}
+			fetcher.Stop()
+			wg.Wait()
+
+			mutex.Lock()
+			defer mutex.Unlock()
+			require.Len(t, starts, tt.requests, "wrong number of requests")
+			require.LessOrEqual(t, maxActive, tt.maxConns, "too many concurrent requests")
+
+			var read int
+			for _, content := range jsContent {
+				read += len(content)
+			}
+			require.LessOrEqual(t, read, tt.maxBytes, "too many bytes read")
+			require.Equal(t, tt.limits, fetcher.stats.finish().LimitsHit, "wrong limits hit")
+
+			if tt.policy.HostDelay > 0 {
+				slices.SortFunc(starts, func(a, b time.Time) int { return a.Compare(b) })
+				for i := 1; i < len(starts); i++ {
+					// Allow for the timer resolution
+					require.GreaterOrEqual(t, starts[i].Sub(starts[i-1]), tt.policy.HostDelay-5*time.Millisecond, "requests should be spaced by the delay")
+				}
+			}
+		})
+	}
+}
@@ -3,6 +3,7 @@ package profiler
 import (
 	"io/ioutil"
 	"net/http"
+	"strings"
 	"testing"
 )
 
@@ -34,4 +35,31 @@ func BenchmarkFingerprint(b *testing.B) {
 	for i := 0; i < b.N; i++ {
 		wappalyzer.Fingerprint(headersMap, html)
 	}
-}
\ No newline at end of file
+}
+func BenchmarkFingerprintSynthetic(b *testing.B) {
+	// A page of typical size that needs no fixtures
+	var page strings.Builder
+	page.WriteString(`<html><head><title>Shop</title><meta name="generator" content="WordPress 6.4"></head><body>`)
+	for page.Len() < 200*1024 {
+		page.WriteString(`<div class="product"><a href="/item">Item</a><img src="/wp-content/uploads/item.jpg"></div>`)
+	}
+	page.WriteString(`</body></html>`)
+	body := []byte(page.String())
+
+	headers := map[string][]string{
+		"Server":       {"nginx/1.19.0"},
+		"Content-Type": {"text/html"},
+		"X-Powered-By": {"PHP/7.4.3"},
+	}
+
+	wappalyzer, err := New()
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		wappalyzer.Fingerprint(headers, body)
+	}
+}
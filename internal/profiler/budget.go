@@ -0,0 +1,49 @@
+package profiler
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// budgetedStages are the stages an exhausted budget can cut short, in the
+// order they are reported
+var budgetedStages = []Stage{StageDNS, StageRobots, StageAssets, StageErrorPage, StageHeaderOrder, StagePorts, StagePlatform, StageEmail}
+
+// budgetTracker records the stages of an analysis that did not finish before
+// its budget, or the deadline of its context, ran out
+type budgetTracker struct {
+	ctx     context.Context
+	mutex   sync.Mutex
+	skipped map[Stage]bool
+}
+
+// newBudgetTracker tracks the stages of an analysis bounded by ctx
+func newBudgetTracker(ctx context.Context) *budgetTracker {
+	return &budgetTracker{ctx: ctx, skipped: make(map[Stage]bool)}
+}
+
+// finish records that stage ended, and whether it was cut short. A stage
+// ending after the deadline is considered incomplete.
+func (b *budgetTracker) finish(stage Stage) {
+	if !errors.Is(b.ctx.Err(), context.DeadlineExceeded) {
+		return
+	}
+	b.mutex.Lock()
+	b.skipped[stage] = true
+	b.mutex.Unlock()
+}
+
+// stages returns the stages that were cut short
+func (b *budgetTracker) stages() []Stage {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	var skipped []Stage
+	for _, stage := range budgetedStages {
+		if b.skipped[stage] {
+			skipped = append(skipped, stage)
+		}
+	}
+	return skipped
+}
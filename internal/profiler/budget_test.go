@@ -0,0 +1,50 @@
+package profiler
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestBudget(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/":
+			w.Header().Set("Server", "nginx")
+			w.Header().Set("Content-Type", "text/html")
+			w.Write([]byte(`<html><head><script src="/slow.js"></script></head><body></body></html>`))
+		case "/robots.txt", "/slow.js":
+			// Hang until the analysis gives up on the request
+			select {
+			case <-r.Context().Done():
+			case <-time.After(5 * time.Second):
+			}
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	wappalyzer, err := New(WithBudget(500*time.Millisecond), WithDisabledVectors(VectorDNS))
+	require.NoError(t, err, "could not create wappalyzer")
+
+	start := time.Now()
+	result, err := wappalyzer.FingerprintURL(context.Background(), server.URL)
+	require.NoError(t, err, "partial results should not fail the analysis")
+	require.Less(t, time.Since(start), 2*time.Second, "analysis should stop when the budget runs out")
+
+	require.True(t, result.PartialResult(), "result should be partial")
+	require.Equal(t, []Stage{StageRobots, StageAssets}, result.GetSkippedStages(), "wrong skipped stages")
+	require.Contains(t, result.GetTechnologies(), "Nginx", "detections gathered in time should be returned")
+
+	fast, err := New(WithBudget(5*time.Second), WithProfile(ProfileFast))
+	require.NoError(t, err, "could not create wappalyzer")
+	result, err = fast.FingerprintURL(context.Background(), server.URL)
+	require.NoError(t, err, "could not fingerprint url")
+	require.False(t, result.PartialResult(), "result within budget should be complete")
+	require.Empty(t, result.GetSkippedStages(), "no stage should be skipped")
+}
@@ -0,0 +1,247 @@
+package profiler
+
+import (
+	"container/list"
+	"context"
+	"encoding/json"
+	"sort"
+	"sync"
+	"time"
+)
+
+// ResultCache stores serialized analysis results by key.
+// Its shape matches a Redis GET/SET EX pair, so a Redis client can implement it
+// directly; NewLRUCache provides an in-memory implementation.
+type ResultCache interface {
+	// Get returns the value stored for key, and whether it was found
+	Get(ctx context.Context, key string) ([]byte, bool, error)
+	// Set stores value for key, expiring it after ttl
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+}
+
+// cacheConfig holds the result cache settings of a Wappalyze instance
+type cacheConfig struct {
+	cache         ResultCache
+	ttl           time.Duration
+	revalidateFor time.Duration
+}
+
+// cachedResult is the serialized form of a richResult kept in a ResultCache
+type cachedResult struct {
+	URL          string               `json:"url,omitempty"`
+	StatusCode   int                  `json:"status_code,omitempty"`
+	WebServer    string               `json:"web_server,omitempty"`
+	Technologies []string             `json:"technologies"`
+	Title        string               `json:"title,omitempty"`
+	AppInfo      map[string]AppInfo   `json:"app_info,omitempty"`
+	CategoryInfo map[string]CatsInfo  `json:"category_info,omitempty"`
+	Detections   map[string]Detection `json:"detections,omitempty"`
+	Protocol     ProtocolInfo         `json:"protocol"`
+	Ports        []PortProbe          `json:"ports,omitempty"`
+	Location     *Location            `json:"location,omitempty"`
+	Email        *EmailInfo           `json:"email,omitempty"`
+	Robots       *RobotsTxt           `json:"robots,omitempty"`
+	Stack        Stack                `json:"stack"`
+
+	// Validators of the original response, used for conditional revalidation
+	ETag         string `json:"etag,omitempty"`
+	LastModified string `json:"last_modified,omitempty"`
+
+	// StoredAt is when the result was fetched or last revalidated
+	StoredAt time.Time `json:"stored_at"`
+}
+
+// newCachedResult snapshots a result along with the validators of its response
+func newCachedResult(result richResult, etag, lastModified string) cachedResult {
+	technologies := make([]string, 0, len(result.technologies))
+	for technology := range result.technologies {
+		technologies = append(technologies, technology)
+	}
+	sort.Strings(technologies)
+	return cachedResult{
+		URL:          result.url,
+		StatusCode:   result.statusCode,
+		WebServer:    result.webServer,
+		Technologies: technologies,
+		Title:        result.title,
+		AppInfo:      result.appInfo,
+		CategoryInfo: result.categoryInfo,
+		Detections:   result.detections,
+		Protocol:     result.protocol,
+		Ports:        result.ports,
+		Location:     result.location,
+		Email:        result.email,
+		Robots:       result.robots,
+		Stack:        result.stack,
+		ETag:         etag,
+		LastModified: lastModified,
+		StoredAt:     time.Now(),
+	}
+}
+
+// hasValidators reports whether the entry can be revalidated with a conditional request
+func (c cachedResult) hasValidators() bool {
+	return c.ETag != "" || c.LastModified != ""
+}
+
+// toResult restores a richResult from the cached snapshot
+func (c cachedResult) toResult() richResult {
+	technologies := make(map[string]struct{}, len(c.Technologies))
+	for _, technology := range c.Technologies {
+		technologies[technology] = struct{}{}
+	}
+	return richResult{
+		url:          c.URL,
+		statusCode:   c.StatusCode,
+		webServer:    c.WebServer,
+		technologies: technologies,
+		title:        c.Title,
+		appInfo:      c.AppInfo,
+		categoryInfo: c.CategoryInfo,
+		detections:   c.Detections,
+		protocol:     c.Protocol,
+		ports:        c.Ports,
+		location:     c.Location,
+		email:        c.Email,
+		robots:       c.Robots,
+		stack:        c.Stack,
+		fromCache:    true,
+	}
+}
+
+// loadCachedResult returns the cached entry for a URL, if any.
+// Cache failures are treated as misses, since the cache is only an optimization.
+func (s *Wappalyze) loadCachedResult(ctx context.Context, targetURL string) (cachedResult, bool) {
+	var entry cachedResult
+	// Cached results carry no near misses, so explained analyses run anew
+	if s.cache.cache == nil || explainOf(ctx) {
+		return entry, false
+	}
+
+	value, found, err := s.cache.cache.Get(ctx, s.cacheKey(ctx, targetURL))
+	if err != nil || !found {
+		return entry, false
+	}
+	if err := json.Unmarshal(value, &entry); err != nil {
+		return entry, false
+	}
+	return entry, true
+}
+
+// storeCachedResult stores an entry for a URL. Entries with validators are kept
+// past their TTL for the revalidation window, so they can be revalidated.
+func (s *Wappalyze) storeCachedResult(ctx context.Context, targetURL string, entry cachedResult) {
+	if s.cache.cache == nil {
+		return
+	}
+
+	value, err := json.Marshal(entry)
+	if err != nil {
+		return
+	}
+
+	ttl := s.cache.ttl
+	if entry.hasValidators() {
+		ttl += s.cache.revalidateFor
+	}
+	s.cache.cache.Set(ctx, s.cacheKey(ctx, targetURL), value, ttl)
+}
+
+// cacheKey returns the cache key of a URL. Results of other profiles than the
+// standard one are kept apart, since they do not cover the same vectors, and
+// so are results of other user agents than the desktop one and of a single IP
+// family, since sites may serve them other pages.
+func (s *Wappalyze) cacheKey(ctx context.Context, targetURL string) string {
+	if s.ipFamily != IPFamilyAny {
+		targetURL = string(s.ipFamily) + ":" + targetURL
+	}
+	if userAgent := userAgentOf(ctx); userAgent != UserAgentDesktop {
+		targetURL = "ua=" + userAgent.Name + ":" + targetURL
+	}
+	if profile := s.profileOf(ctx); profile != ProfileStandard {
+		return string(profile) + ":" + targetURL
+	}
+	return targetURL
+}
+
+// LRUCache is an in-memory ResultCache that evicts the least recently used
+// entries once it reaches its capacity. It is safe for concurrent use.
+type LRUCache struct {
+	mutex    sync.Mutex
+	capacity int
+	entries  map[string]*list.Element
+	order    *list.List // Front is the most recently used entry
+}
+
+// lruEntry is a single LRUCache entry
+type lruEntry struct {
+	key       string
+	value     []byte
+	expiresAt time.Time
+}
+
+// NewLRUCache creates an in-memory cache holding at most capacity entries
+func NewLRUCache(capacity int) *LRUCache {
+	if capacity < 1 {
+		capacity = 1
+	}
+	return &LRUCache{
+		capacity: capacity,
+		entries:  make(map[string]*list.Element),
+		order:    list.New(),
+	}
+}
+
+// Get implements ResultCache
+func (c *LRUCache) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	element, ok := c.entries[key]
+	if !ok {
+		return nil, false, nil
+	}
+
+	entry := element.Value.(*lruEntry)
+	if time.Now().After(entry.expiresAt) {
+		c.order.Remove(element)
+		delete(c.entries, key)
+		return nil, false, nil
+	}
+
+	c.order.MoveToFront(element)
+	return entry.value, true, nil
+}
+
+// Set implements ResultCache
+func (c *LRUCache) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	expiresAt := time.Now().Add(ttl)
+	if element, ok := c.entries[key]; ok {
+		entry := element.Value.(*lruEntry)
+		entry.value = value
+		entry.expiresAt = expiresAt
+		c.order.MoveToFront(element)
+		return nil
+	}
+
+	c.entries[key] = c.order.PushFront(&lruEntry{key: key, value: value, expiresAt: expiresAt})
+
+	// Evict the least recently used entries
+	for c.order.Len() > c.capacity {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*lruEntry).key)
+	}
+	return nil
+}
+
+// Len returns the number of entries in the cache, including expired ones
+// that have not been evicted yet
+func (c *LRUCache) Len() int {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.order.Len()
+}
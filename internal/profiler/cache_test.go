@@ -0,0 +1,88 @@
+package profiler
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLRUCache(t *testing.T) {
+	ctx := context.Background()
+	cache := NewLRUCache(2)
+
+	require.NoError(t, cache.Set(ctx, "a", []byte("1"), time.Minute))
+	require.NoError(t, cache.Set(ctx, "b", []byte("2"), time.Minute))
+
+	// Touch "a" so "b" becomes the least recently used entry
+	_, found, _ := cache.Get(ctx, "a")
+	require.True(t, found, "could not get entry")
+
+	require.NoError(t, cache.Set(ctx, "c", []byte("3"), time.Minute))
+	require.Equal(t, 2, cache.Len(), "cache exceeded capacity")
+
+	_, found, _ = cache.Get(ctx, "b")
+	require.False(t, found, "least recently used entry not evicted")
+
+	require.NoError(t, cache.Set(ctx, "d", []byte("4"), -time.Second))
+	_, found, _ = cache.Get(ctx, "d")
+	require.False(t, found, "expired entry returned")
+}
+
+func TestFingerprintURLCache(t *testing.T) {
+	var pageRequests, notModified atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		pageRequests.Add(1)
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			notModified.Add(1)
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", `"v1"`)
+		w.Header().Set("Server", "nginx")
+		w.Write([]byte("<html></html>"))
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name         string
+		ttl          time.Duration
+		pageRequests int32
+		notModified  int32
+	}{
+		{"fresh", time.Minute, 1, 0},
+		{"revalidated", 0, 2, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pageRequests.Store(0)
+			notModified.Store(0)
+
+			wappalyzer, err := New(WithResultCache(NewLRUCache(10), tt.ttl, time.Minute))
+			require.NoError(t, err, "could not create wappalyzer")
+
+			first, err := wappalyzer.FingerprintURL(context.Background(), server.URL+"/")
+			require.NoError(t, err, "could not fingerprint url")
+			require.False(t, first.FromCache(), "first result served from cache")
+			require.False(t, first.NotModified(), "first result not modified")
+
+			second, err := wappalyzer.FingerprintURL(context.Background(), server.URL+"/")
+			require.NoError(t, err, "could not fingerprint url")
+			require.True(t, second.FromCache(), "second result not served from cache")
+			require.Equal(t, tt.notModified > 0, second.NotModified(), "wrong not modified flag")
+			require.Equal(t, first.GetTechnologies(), second.GetTechnologies(), "cached technologies differ")
+
+			require.Equal(t, tt.pageRequests, pageRequests.Load(), "wrong number of page requests")
+			require.Equal(t, tt.notModified, notModified.Load(), "wrong number of revalidations")
+		})
+	}
+}
@@ -0,0 +1,83 @@
+package profiler
+
+import (
+	"sort"
+)
+
+// Category is a technology category of the loaded fingerprint data
+type Category struct {
+	ID       int    `json:"id"`
+	Slug     string `json:"slug"`
+	Name     string `json:"name"`
+	Priority int    `json:"priority"`
+}
+
+// Technology is a technology known to the loaded fingerprint data
+type Technology struct {
+	Name        string     `json:"name"`
+	Slug        string     `json:"slug"`
+	Description string     `json:"description,omitempty"`
+	Website     string     `json:"website,omitempty"`
+	CPE         string     `json:"cpe,omitempty"`
+	Icon        string     `json:"icon,omitempty"`
+	Categories  []Category `json:"categories"`
+	Tags        []string   `json:"tags,omitempty"`
+}
+
+// Categories returns all known technology categories, sorted by ID
+func Categories() []Category {
+	return sortedCategories(categoriesMapping)
+}
+
+// Categories returns the technology categories of the instance, those added
+// by its overlays included, sorted by ID
+func (s *Wappalyze) Categories() []Category {
+	return sortedCategories(s.categories)
+}
+
+// sortedCategories returns the categories of mapping, sorted by ID
+func sortedCategories(mapping map[int]categoryItem) []Category {
+	categories := make([]Category, 0, len(mapping))
+	for id, item := range mapping {
+		categories = append(categories, newCategory(id, item))
+	}
+	sort.Slice(categories, func(i, j int) bool {
+		return categories[i].ID < categories[j].ID
+	})
+	return categories
+}
+
+// newCategory returns the category of ID id
+func newCategory(id int, item categoryItem) Category {
+	return Category{ID: id, Slug: slugify(item.Name), Name: item.Name, Priority: item.Priority}
+}
+
+// Technologies returns all technologies the instance can detect, sorted by name
+func (s *Wappalyze) Technologies() []Technology {
+	technologies := make([]Technology, 0, len(s.fingerprints.Apps))
+	for name, fingerprint := range s.fingerprints.Apps {
+		technology := Technology{
+			Name:        name,
+			Slug:        slugify(name),
+			Description: fingerprint.description,
+			Website:     fingerprint.website,
+			CPE:         fingerprint.cpe,
+			Icon:        fingerprint.icon,
+			Categories:  make([]Category, 0, len(fingerprint.cats)),
+			Tags:        fingerprint.tags,
+		}
+		for _, id := range fingerprint.cats {
+			if item, ok := s.categories[id]; ok {
+				technology.Categories = append(technology.Categories, newCategory(id, item))
+			}
+		}
+		sort.Slice(technology.Categories, func(i, j int) bool {
+			return technology.Categories[i].ID < technology.Categories[j].ID
+		})
+		technologies = append(technologies, technology)
+	}
+	sort.Slice(technologies, func(i, j int) bool {
+		return technologies[i].Name < technologies[j].Name
+	})
+	return technologies
+}
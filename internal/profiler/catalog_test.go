@@ -0,0 +1,105 @@
+package profiler
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCatalog(t *testing.T) {
+	categories := Categories()
+	require.NotEmpty(t, categories, "could not load categories")
+	require.Contains(t, categories, Category{ID: 1, Slug: "cms", Name: "CMS", Priority: 1}, "missing cms category")
+	for i := 1; i < len(categories); i++ {
+		require.Less(t, categories[i-1].ID, categories[i].ID, "categories should be sorted by id")
+	}
+
+	wappalyzer, err := New()
+	require.NoError(t, err, "could not create wappalyzer")
+
+	technologies := wappalyzer.Technologies()
+	require.Len(t, technologies, len(wappalyzer.fingerprints.Apps), "every technology should be listed")
+
+	var nginx *Technology
+	for i := range technologies {
+		if i > 0 {
+			require.LessOrEqual(t, technologies[i-1].Name, technologies[i].Name, "technologies should be sorted by name")
+		}
+		if technologies[i].Name == "Nginx" {
+			nginx = &technologies[i]
+		}
+	}
+	require.NotNil(t, nginx, "could not get nginx")
+	require.Equal(t, "https://nginx.org/en", nginx.Website, "wrong website")
+	require.NotEmpty(t, nginx.CPE, "missing cpe")
+	require.Contains(t, nginx.Categories, Category{ID: 22, Slug: "web-servers", Name: "Web servers", Priority: 8}, "missing category")
+}
+
+func TestResultCategories(t *testing.T) {
+	wappalyzer, err := New(WithProfile(ProfileFast))
+	require.NoError(t, err, "could not create wappalyzer")
+
+	resp := &http.Response{Header: http.Header{"Server": []string{"nginx/1.25.3"}}}
+	result := wappalyzer.AnalyzeWithPipeline(resp, []byte("<html></html>"))
+	require.Contains(t, result.GetTechnologies(), "Nginx:1.25.3", "missing nginx")
+	// Versioned technologies are keyed with their version
+	require.Contains(t, result.GetCategories()["Nginx:1.25.3"].Cats, 22, "missing web server category")
+}
+
+func TestDataVersion(t *testing.T) {
+	wappalyzer, err := New()
+	require.NoError(t, err, "could not create wappalyzer")
+
+	version := wappalyzer.DataVersion()
+	require.Equal(t, len(wappalyzer.fingerprints.Apps), version.Technologies, "wrong technology count")
+
+	// Stamps written by update-fingerprints are read back
+	path := filepath.Join(t.TempDir(), "fingerprints.json")
+	data := `{"metadata": {"source_version": "6.10.74", "fetched_at": "2025-06-01T12:00:00Z", "content_hash": "sha256:abc", "technologies": 1},
+		"apps": {"Example": {"headers": {"x-example": ""}}}}`
+	require.NoError(t, os.WriteFile(path, []byte(data), 0o600), "could not write fingerprints")
+
+	custom, err := NewFromFile(path, false, false)
+	require.NoError(t, err, "could not create wappalyzer from file")
+	require.Equal(t, DataVersion{
+		SourceVersion: "6.10.74",
+		FetchedAt:     time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC),
+		ContentHash:   "sha256:abc",
+		Technologies:  1,
+	}, custom.DataVersion(), "wrong data version")
+}
+
+func TestNewForCategories(t *testing.T) {
+	wappalyzer, err := NewForCategories([]int{1})
+	require.NoError(t, err, "could not create wappalyzer")
+	require.Contains(t, wappalyzer.fingerprints.Apps, "WordPress", "missing cms")
+	require.Contains(t, wappalyzer.fingerprints.Apps, "PHP", "missing implied technology")
+	require.NotContains(t, wappalyzer.fingerprints.Apps, "Google Analytics", "analytics should not be loaded")
+
+	fingerprints := wappalyzer.Fingerprint(map[string][]string{}, []byte(`<html><head><meta name="generator" content="WordPress 6.4"></head></html>`))
+	require.Contains(t, fingerprints, "WordPress:6.4", "could not detect cms")
+
+	_, err = NewForCategories([]int{1, 100000})
+	require.Error(t, err, "unknown categories should be rejected")
+	_, err = NewForCategories(nil)
+	require.Error(t, err, "no categories should be rejected")
+}
+
+func TestSelectCategories(t *testing.T) {
+	apps := map[string]*Fingerprint{
+		"Shop":      {Cats: []int{6}, Implies: []string{`Framework\;confidence:50`}},
+		"Framework": {Cats: []int{18}, Implies: []string{"Language", "Missing"}},
+		"Language":  {Cats: []int{27}, Implies: []string{"Framework"}},
+		"Server":    {Cats: []int{22}},
+	}
+
+	selected := selectCategories(apps, []int{6})
+	require.Len(t, selected, 3, "wrong number of apps")
+	for _, app := range []string{"Shop", "Framework", "Language"} {
+		require.Contains(t, selected, app, "missing app")
+	}
+}
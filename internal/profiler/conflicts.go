@@ -0,0 +1,123 @@
+package profiler
+
+import (
+	"context"
+	"slices"
+)
+
+// exclusiveCategories are the categories a site runs a single technology of:
+// CMS, web servers and operating systems
+var exclusiveCategories = []int{1, 22, 28}
+
+// resolveConflicts removes the detections contradicted by others, as implies
+// from different matches can name two CMS or web servers with equal standing.
+// Technologies detected by a vector are preferred over those only implied:
+//
+//   - A technology excludes the ones listed in its excludes, unless it was
+//     only implied and they were detected
+//   - In an exclusive category with detected technologies, the ones only
+//     implied are removed, unless a technology of the category implies them,
+//     as OpenResty implies Nginx
+//   - In an exclusive category with implied technologies only, the ones with
+//     the highest confidence are kept
+//
+// Technologies detected by vectors are never removed by the categories, since
+// sites do run several web servers behind each other. The removed technologies
+// are returned as near misses.
+func (s *Wappalyze) resolveConflicts(ctx context.Context, fingerprints UniqueFingerprints) []NearMiss {
+	detected := make([]string, 0, len(fingerprints.values))
+	for name, metadata := range fingerprints.values {
+		if metadata.confidence > 0 {
+			detected = append(detected, name)
+		}
+	}
+	slices.Sort(detected)
+
+	direct := func(name string) bool {
+		return slices.ContainsFunc(fingerprints.values[name].detectedBy, func(vector string) bool { return vector != "implies" })
+	}
+	removed := make(map[string]bool)
+	var nearMisses []NearMiss
+	remove := func(name string, reason NearMissReason, detail string) {
+		removed[name] = true
+		nearMisses = append(nearMisses, fingerprints.nearMiss(name, reason, detail))
+		delete(fingerprints.values, name)
+		s.logger.DebugContext(ctx, "conflicting technology removed", "technology", name, "reason", detail)
+	}
+
+	for _, name := range detected {
+		fingerprint, ok := s.fingerprints.Apps[name]
+		if !ok || removed[name] {
+			continue
+		}
+		for _, excluded := range fingerprint.excludes {
+			if _, ok := fingerprints.values[excluded]; !ok || removed[excluded] {
+				continue
+			}
+			if !direct(name) && direct(excluded) {
+				remove(name, NearMissExcluded, "excluded by "+excluded)
+				break
+			}
+			remove(excluded, NearMissExcluded, "excluded by "+name)
+		}
+	}
+
+	for _, category := range exclusiveCategories {
+		var members, implied []string
+		for _, name := range detected {
+			if fingerprint, ok := s.fingerprints.Apps[name]; ok && !removed[name] && slices.Contains(fingerprint.cats, category) {
+				members = append(members, name)
+				if !direct(name) {
+					implied = append(implied, name)
+				}
+			}
+		}
+		if len(implied) == 0 || len(members) < 2 {
+			continue
+		}
+
+		if len(implied) < len(members) {
+			for _, name := range implied {
+				if !s.impliedByAny(name, members) {
+					remove(name, NearMissConflict, "conflicts with a detected technology")
+				}
+			}
+			continue
+		}
+
+		var highest int
+		for _, name := range implied {
+			highest = max(highest, fingerprints.values[name].confidence)
+		}
+		for _, name := range implied {
+			if fingerprints.values[name].confidence < highest {
+				remove(name, NearMissConflict, "conflicts with a more confident technology")
+			}
+		}
+	}
+	return nearMisses
+}
+
+// impliedByAny reports whether any of the technologies names implies name,
+// directly or through other implied technologies
+func (s *Wappalyze) impliedByAny(name string, names []string) bool {
+	seen := map[string]bool{name: true}
+	pending := slices.Clone(names)
+	for len(pending) > 0 {
+		current := pending[len(pending)-1]
+		pending = pending[:len(pending)-1]
+		fingerprint, ok := s.fingerprints.Apps[current]
+		if seen[current] || !ok {
+			continue
+		}
+		seen[current] = true
+		for _, implied := range fingerprint.implies {
+			implied = impliedName(implied)
+			if implied == name {
+				return true
+			}
+			pending = append(pending, implied)
+		}
+	}
+	return false
+}
@@ -0,0 +1,70 @@
+package profiler
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestResolveConflicts(t *testing.T) {
+	overlay := filepath.Join(t.TempDir(), "overlay.json")
+	require.NoError(t, os.WriteFile(overlay, []byte(`{"extend": {"Drupal": {"excludes": ["Joomla"]}}}`), 0o644), "could not write overlay")
+	wappalyzer, err := New(WithOverlays(overlay))
+	require.NoError(t, err, "could not create wappalyzer")
+
+	type match struct {
+		name       string
+		confidence int
+		vector     string
+	}
+	tests := []struct {
+		name     string
+		matches  []match
+		expected []string
+	}{
+		{
+			name:     "implied cms conflicting with a detected one",
+			matches:  []match{{"Drupal", 100, "html"}, {"WordPress", 100, "implies"}, {"PHP", 100, "implies"}},
+			expected: []string{"Drupal", "PHP"},
+		},
+		{
+			name:     "web server implied by another",
+			matches:  []match{{"OpenResty", 100, "headers"}, {"Nginx", 100, "implies"}},
+			expected: []string{"OpenResty", "Nginx"},
+		},
+		{
+			name:     "detected web servers",
+			matches:  []match{{"Nginx", 100, "headers"}, {"Apache HTTP Server", 100, "html"}},
+			expected: []string{"Nginx", "Apache HTTP Server"},
+		},
+		{
+			name:     "implied web servers",
+			matches:  []match{{"Apache HTTP Server", 100, "implies"}, {"IIS", 50, "implies"}},
+			expected: []string{"Apache HTTP Server"},
+		},
+		{
+			name:     "excludes",
+			matches:  []match{{"Drupal", 100, "html"}, {"Joomla", 100, "meta"}},
+			expected: []string{"Drupal"},
+		},
+		{
+			name:     "implied technology excluding a detected one",
+			matches:  []match{{"Drupal", 100, "implies"}, {"Joomla", 100, "meta"}},
+			expected: []string{"Joomla"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fingerprints := NewUniqueFingerprints()
+			for _, match := range tt.matches {
+				fingerprints.SetWithVector(match.name, "", match.confidence, match.vector)
+			}
+			wappalyzer.resolveConflicts(context.Background(), fingerprints)
+			require.ElementsMatch(t, tt.expected, sortedKeys(fingerprints.GetDetections()), "wrong technologies")
+		})
+	}
+}
@@ -0,0 +1,26 @@
+package profiler
+
+import "time"
+
+// DataVersion describes the fingerprint data an instance was loaded with, as
+// stamped by update-fingerprints. Data without a stamp only reports its
+// technology count.
+type DataVersion struct {
+	// SourceVersion is the version of the Wappalyzer XPI the data was extracted from
+	SourceVersion string `json:"source_version,omitempty"`
+	// FetchedAt is when the XPI was downloaded
+	FetchedAt time.Time `json:"fetched_at"`
+	// ContentHash is the SHA-256 of the normalized fingerprints, as "sha256:<hex>"
+	ContentHash string `json:"content_hash,omitempty"`
+	// Technologies is the number of technologies loaded
+	Technologies int `json:"technologies"`
+}
+
+// DataVersion returns the version of the fingerprint data, so operators can tell
+// how stale the rulebase is. When custom fingerprints were loaded on top of the
+// embedded ones, the stamp is the one of the embedded data.
+func (s *Wappalyze) DataVersion() DataVersion {
+	version := s.original.Metadata
+	version.Technologies = len(s.fingerprints.Apps)
+	return version
+}
@@ -0,0 +1,59 @@
+package profiler
+
+import (
+	"sort"
+)
+
+// DetectionDiff describes how the detected technologies of a target changed
+// between two scans
+type DetectionDiff struct {
+	Added          []string        `json:"added,omitempty"`           // Technologies only in the new scan
+	Removed        []string        `json:"removed,omitempty"`         // Technologies only in the old scan
+	VersionChanged []VersionChange `json:"version_changed,omitempty"` // Technologies whose version changed
+}
+
+// VersionChange is a technology detected in both scans with a different version
+type VersionChange struct {
+	Technology string `json:"technology"`
+	OldVersion string `json:"old_version"`
+	NewVersion string `json:"new_version"`
+}
+
+// Empty reports whether the diff contains no changes
+func (d DetectionDiff) Empty() bool {
+	return len(d.Added) == 0 && len(d.Removed) == 0 && len(d.VersionChanged) == 0
+}
+
+// DiffDetections compares the detections of two scans of the same target.
+// All lists in the returned diff are sorted by technology name.
+func DiffDetections(old, new map[string]Detection) DetectionDiff {
+	var diff DetectionDiff
+
+	for technology, newDetection := range new {
+		oldDetection, ok := old[technology]
+		if !ok {
+			diff.Added = append(diff.Added, technology)
+			continue
+		}
+		if oldDetection.Version != newDetection.Version {
+			diff.VersionChanged = append(diff.VersionChanged, VersionChange{
+				Technology: technology,
+				OldVersion: oldDetection.Version,
+				NewVersion: newDetection.Version,
+			})
+		}
+	}
+
+	for technology := range old {
+		if _, ok := new[technology]; !ok {
+			diff.Removed = append(diff.Removed, technology)
+		}
+	}
+
+	sort.Strings(diff.Added)
+	sort.Strings(diff.Removed)
+	sort.Slice(diff.VersionChanged, func(i, j int) bool {
+		return diff.VersionChanged[i].Technology < diff.VersionChanged[j].Technology
+	})
+	return diff
+}
@@ -0,0 +1,29 @@
+package profiler
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDiffDetections(t *testing.T) {
+	old := map[string]Detection{
+		"jQuery":    {Version: "3.6.0", Confidence: 100},
+		"WordPress": {Version: "6.3", Confidence: 100},
+		"Nginx":     {Confidence: 100},
+	}
+	new := map[string]Detection{
+		"WordPress":  {Version: "6.4", Confidence: 100},
+		"Nginx":      {Confidence: 100},
+		"React":      {Version: "18.2.0", Confidence: 100},
+		"Cloudflare": {Confidence: 100},
+	}
+
+	diff := DiffDetections(old, new)
+	require.Equal(t, []string{"Cloudflare", "React"}, diff.Added, "wrong added technologies")
+	require.Equal(t, []string{"jQuery"}, diff.Removed, "wrong removed technologies")
+	require.Equal(t, []VersionChange{{Technology: "WordPress", OldVersion: "6.3", NewVersion: "6.4"}}, diff.VersionChanged, "wrong version changes")
+	require.False(t, diff.Empty(), "diff should not be empty")
+
+	require.True(t, DiffDetections(old, old).Empty(), "diff of identical scans should be empty")
+}
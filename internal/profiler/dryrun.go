@@ -0,0 +1,84 @@
+package profiler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+)
+
+// Sample is a response a candidate fingerprint is tried against
+type Sample struct {
+	// URL is the address the response was fetched from, matched by the URL
+	// vectors. It may be empty.
+	URL     string
+	Headers http.Header
+	Body    []byte
+}
+
+// DryRun is the outcome of trying a candidate fingerprint against a sample
+type DryRun struct {
+	// Matched reports whether the candidate detected its technology
+	Matched bool `json:"matched"`
+	// Detection is the version, confidence and vectors of the match
+	Detection *Detection `json:"detection,omitempty"`
+	// Implied are the technologies the match implies
+	Implied []string `json:"implied,omitempty"`
+	// Issues are the problems of the definition, as reported by
+	// ValidateFingerprints. Patterns that do not compile are left out of the
+	// match.
+	Issues []FingerprintIssue `json:"issues,omitempty"`
+}
+
+// DryRunFingerprint tries the definition of technology name, in the format of
+// fingerprint files, against sample, for authoring rules without loading them.
+// The candidate is matched alone, with every pattern evaluated. The sample is
+// analyzed as with the fast profile, so nothing is fetched: the vectors of
+// assets, DNS records, robots.txt and probes never match.
+func DryRunFingerprint(ctx context.Context, name string, definition []byte, sample Sample) (DryRun, error) {
+	if name == "" {
+		return DryRun{}, errors.New("no technology name given")
+	}
+	var fingerprint Fingerprint
+	if err := json.Unmarshal(definition, &fingerprint); err != nil {
+		return DryRun{}, fmt.Errorf("invalid fingerprint: %w", err)
+	}
+	data, err := json.Marshal(map[string]map[string]json.RawMessage{"apps": {name: definition}})
+	if err != nil {
+		return DryRun{}, err
+	}
+	dryRun := DryRun{Issues: ValidateFingerprints(data)}
+
+	wappalyze := newWappalyze([]Option{WithProfile(ProfileFast), WithGatherAllEvidence(true)})
+	wappalyze.original = &Fingerprints{Apps: map[string]*Fingerprint{name: &fingerprint}}
+	wappalyze.compileApps(wappalyze.original.Apps)
+
+	resp := &http.Response{StatusCode: http.StatusOK, Header: http.Header{}}
+	for key, values := range sample.Headers {
+		for _, value := range values {
+			resp.Header.Add(key, value)
+		}
+	}
+	if sample.URL != "" {
+		parsed, err := url.Parse(sample.URL)
+		if err != nil {
+			return DryRun{}, fmt.Errorf("invalid sample url: %w", err)
+		}
+		resp.Request = &http.Request{Method: "GET", URL: parsed, Header: http.Header{}}
+	}
+
+	result := wappalyze.analyzeWithPipelineContext(ctx, resp, sample.Body)
+	for app, detection := range result.GetDetections() {
+		if app == name {
+			dryRun.Matched = true
+			dryRun.Detection = &detection
+			continue
+		}
+		dryRun.Implied = append(dryRun.Implied, app)
+	}
+	sort.Strings(dryRun.Implied)
+	return dryRun, nil
+}
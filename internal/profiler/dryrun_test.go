@@ -0,0 +1,86 @@
+package profiler
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDryRunFingerprint(t *testing.T) {
+	sample := Sample{
+		URL:     "https://intranet.example.com/portal/login",
+		Headers: http.Header{"X-Powered-By": {"AcmePortal/4.2"}, "Set-Cookie": {"acme_session=1; Path=/"}},
+		Body:    []byte(`<html><head><meta name="generator" content="Acme Portal 4.2.1"></head><body><script src="/static/acme.min.js"></script></body></html>`),
+	}
+
+	tests := []struct {
+		name           string
+		definition     string
+		wantMatched    bool
+		wantVersion    string
+		wantDetectedBy []string
+		wantImplied    []string
+		wantIssue      string
+	}{
+		{
+			name:           "headers with version",
+			definition:     `{"headers": {"x-powered-by": "AcmePortal/([\\d.]+)\\;version:\\1"}}`,
+			wantMatched:    true,
+			wantVersion:    "4.2",
+			wantDetectedBy: []string{"headers"},
+		},
+		{
+			name:           "several vectors",
+			definition:     `{"cookies": {"acme_session": ""}, "meta": {"generator": ["Acme Portal"]}, "scriptSrc": ["acme\\.min\\.js"], "implies": ["PHP"]}`,
+			wantMatched:    true,
+			wantDetectedBy: []string{"cookies", "meta", "scriptSrc"},
+			wantImplied:    []string{"PHP"},
+		},
+		{
+			name:        "no match",
+			definition:  `{"html": ["wp-content"]}`,
+			wantMatched: false,
+		},
+		{
+			name:        "invalid pattern",
+			definition:  `{"html": ["acme(", "Acme Portal"]}`,
+			wantMatched: true,
+			wantIssue:   "/apps/Acme Portal/html/0",
+		},
+		{
+			name:        "misspelled field",
+			definition:  `{"scriptsrc": ["acme"]}`,
+			wantMatched: true,
+			wantIssue:   "scriptsrc",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dryRun, err := DryRunFingerprint(context.Background(), "Acme Portal", []byte(tt.definition), sample)
+			require.NoError(t, err, "could not dry run fingerprint")
+			require.Equal(t, tt.wantMatched, dryRun.Matched, "wrong match")
+			if tt.wantMatched {
+				require.Equal(t, tt.wantVersion, dryRun.Detection.Version, "wrong version")
+				if tt.wantDetectedBy != nil {
+					require.Equal(t, tt.wantDetectedBy, dryRun.Detection.DetectedBy, "wrong vectors")
+				}
+			}
+			require.Equal(t, tt.wantImplied, dryRun.Implied, "wrong implied technologies")
+			if tt.wantIssue == "" {
+				require.Empty(t, dryRun.Issues, "unexpected issues")
+			} else {
+				require.NotEmpty(t, dryRun.Issues, "missing issues")
+				require.Contains(t, dryRun.Issues[0].String(), tt.wantIssue, "wrong issue")
+			}
+		})
+	}
+}
+
+func TestDryRunFingerprintErrors(t *testing.T) {
+	_, err := DryRunFingerprint(context.Background(), "", []byte(`{}`), Sample{})
+	require.Error(t, err, "a name should be required")
+	_, err = DryRunFingerprint(context.Background(), "Acme Portal", []byte(`{"html": "x"}`), Sample{})
+	require.ErrorContains(t, err, "invalid fingerprint", "definitions of the wrong shape should be rejected")
+}
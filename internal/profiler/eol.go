@@ -0,0 +1,113 @@
+package profiler
+
+import (
+	"encoding/json"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kavinsood/kitsune/assets"
+)
+
+// EOLData is a snapshot of the release cycles of technologies, as embedded
+// from endoflife.date
+type EOLData struct {
+	// Source is the site the snapshot was taken from
+	Source string `json:"source"`
+	// Updated is the date of the snapshot, as YYYY-MM-DD
+	Updated string `json:"updated"`
+	// Technologies are keyed by technology name
+	Technologies map[string]EOLProduct `json:"technologies"`
+}
+
+// EOLProduct is the endoflife.date product of a technology and its release
+// cycles, newest first
+type EOLProduct struct {
+	Product string     `json:"product"`
+	Cycles  []EOLCycle `json:"cycles"`
+}
+
+// EOLCycle is a release cycle of a product
+type EOLCycle struct {
+	// Cycle is the version prefix of the releases of the cycle, as 7.4
+	Cycle string `json:"cycle"`
+	// EOL is the date support ends, as YYYY-MM-DD, or empty if not announced
+	EOL string `json:"eol,omitempty"`
+	// Latest is the latest release of the cycle
+	Latest string `json:"latest"`
+}
+
+// EOLStatus is the end-of-life status of the release cycle of a detected
+// version, as of the analysis
+type EOLStatus struct {
+	// Product is the endoflife.date product of the technology
+	Product string `json:"product"`
+	// Cycle is the release cycle of the detected version
+	Cycle string `json:"cycle"`
+	// EOL is set when the cycle no longer receives support
+	EOL bool `json:"eol"`
+	// EOLDate is the date support ends, if announced
+	EOLDate string `json:"eol_date,omitempty"`
+	// CycleLatest is the latest release of the cycle
+	CycleLatest string `json:"cycle_latest"`
+	// Latest is the latest release of the newest cycle
+	Latest string `json:"latest"`
+}
+
+// embeddedEOL decodes the end-of-life snapshot embedded in the assets package
+var embeddedEOL = sync.OnceValues(func() (*EOLData, error) {
+	var data EOLData
+	if err := json.Unmarshal(assets.EOLJSON, &data); err != nil {
+		return nil, err
+	}
+	return &data, nil
+})
+
+// eolStatus returns the end-of-life status of version of the technology as
+// of now, or nil if the snapshot knows no cycle of it
+func (d *EOLData) eolStatus(technology, version string, now time.Time) *EOLStatus {
+	product, ok := d.Technologies[technology]
+	if !ok || version == "" || len(product.Cycles) == 0 {
+		return nil
+	}
+
+	// Cycles are version prefixes, so 7.4.33 is of 7.4 and 1.12.4 of 1
+	var cycle *EOLCycle
+	for i, candidate := range product.Cycles {
+		if version != candidate.Cycle && !strings.HasPrefix(version, candidate.Cycle+".") {
+			continue
+		}
+		if cycle == nil || len(candidate.Cycle) > len(cycle.Cycle) {
+			cycle = &product.Cycles[i]
+		}
+	}
+	if cycle == nil {
+		return nil
+	}
+
+	status := &EOLStatus{
+		Product:     product.Product,
+		Cycle:       cycle.Cycle,
+		EOLDate:     cycle.EOL,
+		CycleLatest: cycle.Latest,
+		Latest:      product.Cycles[0].Latest,
+	}
+	if date, err := time.Parse(time.DateOnly, cycle.EOL); err == nil {
+		status.EOL = !now.Before(date)
+	}
+	return status
+}
+
+// annotateEOL sets the end-of-life status of the detections with a version
+func annotateEOL(detections map[string]Detection, now time.Time) {
+	data, err := embeddedEOL()
+	if err != nil {
+		return
+	}
+	for name, detection := range detections {
+		if status := data.eolStatus(name, detection.Version, now); status != nil {
+			detection.EOL = status
+			detections[name] = detection
+		}
+	}
+}
@@ -0,0 +1,94 @@
+package profiler
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestEOLSnapshot(t *testing.T) {
+	data, err := embeddedEOL()
+	require.NoError(t, err, "could not decode snapshot")
+	wappalyzer, err := New()
+	require.NoError(t, err, "could not create wappalyzer")
+
+	for technology, product := range data.Technologies {
+		require.Contains(t, wappalyzer.fingerprints.Apps, technology, "unknown technology")
+		require.NotEmpty(t, product.Product, "no product for %s", technology)
+		require.NotEmpty(t, product.Cycles, "no cycles for %s", technology)
+		for _, cycle := range product.Cycles {
+			require.NotEmpty(t, cycle.Latest, "no latest release of %s %s", technology, cycle.Cycle)
+			if cycle.EOL != "" {
+				_, err := time.Parse(time.DateOnly, cycle.EOL)
+				require.NoError(t, err, "invalid end-of-life date of %s %s", technology, cycle.Cycle)
+			}
+		}
+	}
+}
+
+func TestEOLStatus(t *testing.T) {
+	data := &EOLData{Technologies: map[string]EOLProduct{
+		"PHP": {Product: "php", Cycles: []EOLCycle{
+			{Cycle: "8.3", EOL: "2027-12-31", Latest: "8.3.22"},
+			{Cycle: "7.4", EOL: "2022-11-28", Latest: "7.4.33"},
+		}},
+		"jQuery": {Product: "jquery", Cycles: []EOLCycle{
+			{Cycle: "3", Latest: "3.7.1"},
+			{Cycle: "1", EOL: "2016-06-09", Latest: "1.12.4"},
+		}},
+	}}
+	now := time.Date(2025, 6, 15, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name       string
+		technology string
+		version    string
+		expected   *EOLStatus
+	}{
+		{
+			name:       "ended cycle",
+			technology: "PHP",
+			version:    "7.4.3",
+			expected:   &EOLStatus{Product: "php", Cycle: "7.4", EOL: true, EOLDate: "2022-11-28", CycleLatest: "7.4.33", Latest: "8.3.22"},
+		},
+		{
+			name:       "supported cycle",
+			technology: "PHP",
+			version:    "8.3",
+			expected:   &EOLStatus{Product: "php", Cycle: "8.3", EOLDate: "2027-12-31", CycleLatest: "8.3.22", Latest: "8.3.22"},
+		},
+		{
+			name:       "major cycle",
+			technology: "jQuery",
+			version:    "1.12.4",
+			expected:   &EOLStatus{Product: "jquery", Cycle: "1", EOL: true, EOLDate: "2016-06-09", CycleLatest: "1.12.4", Latest: "3.7.1"},
+		},
+		{
+			name:       "no announced end",
+			technology: "jQuery",
+			version:    "3.6.0",
+			expected:   &EOLStatus{Product: "jquery", Cycle: "3", CycleLatest: "3.7.1", Latest: "3.7.1"},
+		},
+		{
+			name:       "unknown cycle",
+			technology: "PHP",
+			version:    "7.45",
+		},
+		{
+			name:       "no version",
+			technology: "PHP",
+		},
+		{
+			name:       "unknown technology",
+			technology: "Nginx",
+			version:    "1.25.3",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.expected, data.eolStatus(tt.technology, tt.version, now), "wrong status")
+		})
+	}
+}
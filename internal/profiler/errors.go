@@ -0,0 +1,144 @@
+package profiler
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+)
+
+// Sentinel errors describing why an analysis stage failed.
+// They are wrapped by AnalysisError and can be checked with errors.Is.
+var (
+	// ErrFetchTimeout is returned when a request did not complete in time
+	ErrFetchTimeout = errors.New("fetch timed out")
+	// ErrTLSHandshake is returned when the TLS handshake with the target failed
+	ErrTLSHandshake = errors.New("tls handshake failed")
+	// ErrDNSFailure is returned when the target hostname could not be resolved
+	ErrDNSFailure = errors.New("dns resolution failed")
+	// ErrBodyTooLarge is returned when the response body exceeded the size limit
+	ErrBodyTooLarge = errors.New("response body too large")
+	// ErrBlockedByTarget is returned when the target refused to serve the request,
+	// for example with a 403, 429 or 451 status
+	ErrBlockedByTarget = errors.New("blocked by target")
+	// ErrTargetNotAllowed is returned when the target check configured with
+	// WithTargetCheck refused the target, or the address check configured with
+	// WithAddressCheck refused its address, before anything was fetched
+	ErrTargetNotAllowed = errors.New("target not allowed")
+	// ErrDisallowedByRobots is returned in polite mode, set with WithPoliteMode,
+	// when the robots.txt of the target disallows its page, before it was fetched
+	ErrDisallowedByRobots = errors.New("disallowed by robots.txt")
+	// ErrMatcherPanic is returned when matching a detection vector panicked.
+	// The other vectors of the analysis are still matched.
+	ErrMatcherPanic = errors.New("matcher panicked")
+	// ErrNotRecorded is returned when replaying a fixture, for requests and
+	// DNS queries it holds no response to
+	ErrNotRecorded = errors.New("not recorded in fixture")
+)
+
+// Stage identifies a part of the analysis, such as the one that failed
+type Stage string
+
+const (
+	// StageMain is the fetch of the main page
+	StageMain Stage = "main"
+	// StageRobots is the fetch of robots.txt
+	StageRobots Stage = "robots"
+	// StageDNS is the DNS record lookup
+	StageDNS Stage = "dns"
+	// StageAssets is the fetch and analysis of scripts, stylesheets and manifests
+	StageAssets Stage = "assets"
+	// StageErrorPage is the opt-in error page probing
+	StageErrorPage Stage = "errorPage"
+	// StageHeaderOrder is the opt-in raw header order capture
+	StageHeaderOrder Stage = "headerOrder"
+	// StagePorts is the opt-in alternate port probing
+	StagePorts Stage = "ports"
+	// StagePlatform is the opt-in probing of the platforms detected
+	StagePlatform Stage = "platform"
+	// StageEmail is the lookup of the SPF, DMARC and DKIM records of the domain
+	StageEmail Stage = "email"
+	// StageMatch is the matching of the detection vectors, which only fails
+	// when a matcher panics
+	StageMatch Stage = "match"
+)
+
+// AnalysisError is returned when a stage of the analysis fails.
+// Err wraps one of the sentinel errors when the failure could be classified,
+// along with the underlying error.
+type AnalysisError struct {
+	Stage      Stage  // Stage that failed
+	URL        string // URL being fetched, if any
+	StatusCode int    // HTTP status code, if a response was received
+	Err        error  // Underlying error
+}
+
+// Error implements the error interface
+func (e *AnalysisError) Error() string {
+	if e.URL != "" {
+		return fmt.Sprintf("%s stage failed for %s: %v", e.Stage, e.URL, e.Err)
+	}
+	return fmt.Sprintf("%s stage failed: %v", e.Stage, e.Err)
+}
+
+// Unwrap returns the underlying error, so errors.Is and errors.As see the
+// sentinel and the original cause
+func (e *AnalysisError) Unwrap() error {
+	return e.Err
+}
+
+// newAnalysisError creates an AnalysisError for a stage, classifying err
+func newAnalysisError(stage Stage, rawURL string, err error) *AnalysisError {
+	return &AnalysisError{Stage: stage, URL: rawURL, Err: classifyError(err)}
+}
+
+// classifyError wraps err with the matching sentinel error, if any
+func classifyError(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	// Already classified
+	for _, sentinel := range []error{ErrFetchTimeout, ErrTLSHandshake, ErrDNSFailure, ErrBodyTooLarge, ErrBlockedByTarget, ErrTargetNotAllowed} {
+		if errors.Is(err, sentinel) {
+			return err
+		}
+	}
+
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) {
+		return fmt.Errorf("%w: %w", ErrDNSFailure, err)
+	}
+
+	var netErr net.Error
+	if errors.Is(err, context.DeadlineExceeded) || (errors.As(err, &netErr) && netErr.Timeout()) {
+		return fmt.Errorf("%w: %w", ErrFetchTimeout, err)
+	}
+
+	var recordErr tls.RecordHeaderError
+	var alertErr tls.AlertError
+	var verifyErr *tls.CertificateVerificationError
+	var unknownAuthorityErr x509.UnknownAuthorityError
+	var hostnameErr x509.HostnameError
+	if errors.As(err, &recordErr) || errors.As(err, &alertErr) || errors.As(err, &verifyErr) ||
+		errors.As(err, &unknownAuthorityErr) || errors.As(err, &hostnameErr) ||
+		strings.Contains(err.Error(), "tls: ") ||
+		strings.Contains(err.Error(), "server gave HTTP response to HTTPS client") {
+		return fmt.Errorf("%w: %w", ErrTLSHandshake, err)
+	}
+
+	return err
+}
+
+// isBlockedStatus reports whether a status code means the target refused to serve us
+func isBlockedStatus(statusCode int) bool {
+	switch statusCode {
+	case http.StatusForbidden, http.StatusTooManyRequests, http.StatusUnavailableForLegalReasons:
+		return true
+	}
+	return false
+}
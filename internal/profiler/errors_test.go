@@ -0,0 +1,80 @@
+package profiler
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestClassifyError(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		sentinel error
+	}{
+		{"dns", &net.DNSError{Err: "no such host", Name: "example.invalid", IsNotFound: true}, ErrDNSFailure},
+		{"deadline", context.DeadlineExceeded, ErrFetchTimeout},
+		{"net-timeout", &net.DNSError{Err: "i/o timeout", IsTimeout: true}, ErrDNSFailure},
+		{"tls-alert", tls.AlertError(40), ErrTLSHandshake},
+		{"tls-record", tls.RecordHeaderError{Msg: "first record does not look like a TLS handshake"}, ErrTLSHandshake},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := newAnalysisError(StageMain, "http://example.invalid", tt.err)
+			require.ErrorIs(t, err, tt.sentinel, "could not classify error")
+			require.ErrorIs(t, err, tt.err, "original error not wrapped")
+		})
+	}
+
+	require.NoError(t, classifyError(nil), "nil error should stay nil")
+	unknown := errors.New("unknown")
+	require.Equal(t, unknown, classifyError(unknown), "unknown error should not be wrapped")
+}
+
+func TestFingerprintURLErrors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/blocked":
+			w.Header().Set("Server", "cloudflare")
+			w.WriteHeader(http.StatusForbidden)
+			w.Write([]byte("<html><title>Attention Required!</title></html>"))
+		case "/slow":
+			time.Sleep(500 * time.Millisecond)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	wappalyzer, err := New()
+	require.NoError(t, err, "could not create wappalyzer")
+
+	t.Run("blocked", func(t *testing.T) {
+		result, err := wappalyzer.FingerprintURL(context.Background(), server.URL+"/blocked")
+		require.ErrorIs(t, err, ErrBlockedByTarget, "could not detect blocked target")
+
+		var analysisErr *AnalysisError
+		require.ErrorAs(t, err, &analysisErr, "could not get analysis error")
+		require.Equal(t, StageMain, analysisErr.Stage, "wrong failing stage")
+		require.Equal(t, http.StatusForbidden, analysisErr.StatusCode, "wrong status code")
+
+		// The block page is still analyzed
+		require.Contains(t, result.GetTechnologies(), "Cloudflare", "could not analyze block page")
+	})
+
+	t.Run("timeout", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+
+		_, err := wappalyzer.FingerprintURL(ctx, server.URL+"/slow")
+		require.ErrorIs(t, err, ErrFetchTimeout, "could not detect timeout")
+	})
+}
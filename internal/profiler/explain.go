@@ -0,0 +1,116 @@
+package profiler
+
+import (
+	"context"
+	"slices"
+	"strings"
+)
+
+// NearMissReason is the gate that kept a matched technology out of a result
+type NearMissReason string
+
+const (
+	// NearMissConfidence is for technologies whose matching patterns all
+	// carry no confidence, as with `\;confidence:0`
+	NearMissConfidence NearMissReason = "confidence"
+	// NearMissExcluded is for technologies excluded by the excludes of
+	// another detected technology
+	NearMissExcluded NearMissReason = "excluded"
+	// NearMissConflict is for technologies only implied in a category sites
+	// run a single technology of, such as CMS and web servers, which hold a
+	// detected or more confident technology
+	NearMissConflict NearMissReason = "conflict"
+	// NearMissTags is for technologies without any of the tags the result is
+	// restricted to
+	NearMissTags NearMissReason = "tags"
+)
+
+// NearMiss is a technology whose patterns matched, but which an analysis left
+// out of its result
+type NearMiss struct {
+	Technology string         `json:"technology"`
+	Version    string         `json:"version,omitempty"`
+	Confidence int            `json:"confidence"`
+	DetectedBy []string       `json:"detected_by"`
+	Reason     NearMissReason `json:"reason"`
+	// Detail explains the reason, such as the technology excluding this one
+	Detail string `json:"detail,omitempty"`
+}
+
+// explainKey is the context key of explained analyses
+type explainKey struct{}
+
+// ExplainContext returns a context whose analyses, e.g. through
+// FingerprintURL, report the near misses of their result along with its
+// detections, to understand why an expected technology is missing. Explained
+// analyses are not served from the result cache, whose results carry no near
+// misses.
+func ExplainContext(ctx context.Context) context.Context {
+	return context.WithValue(ctx, explainKey{}, true)
+}
+
+// explainOf reports whether an analysis run with ctx is explained
+func explainOf(ctx context.Context) bool {
+	explain, _ := ctx.Value(explainKey{}).(bool)
+	return explain
+}
+
+// GetNearMisses returns the technologies that matched but were left out of
+// the result, sorted by name, for analyses run with ExplainContext
+func (r richResult) GetNearMisses() []NearMiss {
+	return r.nearMisses
+}
+
+// nearMiss returns the near miss of a technology of the fingerprints
+func (u UniqueFingerprints) nearMiss(name string, reason NearMissReason, detail string) NearMiss {
+	metadata := u.values[name]
+	detectedBy := slices.Clone(metadata.detectedBy)
+	slices.Sort(detectedBy)
+	return NearMiss{
+		Technology: name,
+		Version:    metadata.version,
+		Confidence: metadata.confidence,
+		DetectedBy: detectedBy,
+		Reason:     reason,
+		Detail:     detail,
+	}
+}
+
+// unconfident returns the near misses of the technologies matched without
+// confidence
+func (u UniqueFingerprints) unconfident() []NearMiss {
+	var nearMisses []NearMiss
+	for name, metadata := range u.values {
+		if metadata.confidence == 0 && len(metadata.detectedBy) > 0 {
+			nearMisses = append(nearMisses, u.nearMiss(name, NearMissConfidence, "matched with confidence 0"))
+		}
+	}
+	return nearMisses
+}
+
+// untagged returns the near misses of the detections filtered out for having
+// none of the tags
+func untagged(detections map[string]Detection, tags []string) []NearMiss {
+	var nearMisses []NearMiss
+	for name, detection := range detections {
+		nearMisses = append(nearMisses, NearMiss{
+			Technology: name,
+			Version:    detection.Version,
+			Confidence: detection.Confidence,
+			DetectedBy: slices.Clone(detection.DetectedBy),
+			Reason:     NearMissTags,
+			Detail:     "not tagged " + strings.Join(tags, ", "),
+		})
+	}
+	return nearMisses
+}
+
+// sortNearMisses sorts near misses by technology, then reason
+func sortNearMisses(nearMisses []NearMiss) {
+	slices.SortFunc(nearMisses, func(a, b NearMiss) int {
+		if c := strings.Compare(a.Technology, b.Technology); c != 0 {
+			return c
+		}
+		return strings.Compare(string(a.Reason), string(b.Reason))
+	})
+}
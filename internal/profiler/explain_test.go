@@ -0,0 +1,52 @@
+package profiler
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestExplain(t *testing.T) {
+	data := `{"apps": {
+		"Zero": {"html": ["zero-marker\\;confidence:0"]},
+		"CMS A": {"cats": [1], "html": ["cms-a"], "excludes": ["CMS B"]},
+		"CMS B": {"cats": [1], "html": ["cms-b"]},
+		"CMS C": {"cats": [1]},
+		"Library": {"html": ["library"], "implies": ["CMS C"], "tags": ["payment"]}
+	}}`
+	path := filepath.Join(t.TempDir(), "fingerprints.json")
+	require.NoError(t, os.WriteFile(path, []byte(data), 0o600), "could not write fingerprints")
+	wappalyzer, err := NewFromFile(path, false, false, WithProfile(ProfileFast))
+	require.NoError(t, err, "could not create wappalyzer")
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`<html><body>zero-marker cms-a cms-b library</body></html>`))
+	}))
+	defer server.Close()
+
+	result, err := wappalyzer.FingerprintURL(context.Background(), server.URL)
+	require.NoError(t, err, "could not fingerprint url")
+	require.Nil(t, result.GetNearMisses(), "near misses should only be reported when explaining")
+
+	result, err = wappalyzer.FingerprintURL(ExplainContext(context.Background()), server.URL)
+	require.NoError(t, err, "could not fingerprint url")
+	require.Equal(t, []NearMiss{
+		{Technology: "CMS B", Confidence: 100, DetectedBy: []string{"html"}, Reason: NearMissExcluded, Detail: "excluded by CMS A"},
+		{Technology: "CMS C", Confidence: 100, DetectedBy: []string{"implies"}, Reason: NearMissConflict, Detail: "conflicts with a detected technology"},
+		{Technology: "Zero", Confidence: 0, DetectedBy: []string{"html"}, Reason: NearMissConfidence, Detail: "matched with confidence 0"},
+	}, result.GetNearMisses(), "wrong near misses")
+	require.Contains(t, result.GetDetections(), "CMS A", "the excluding technology should be reported")
+
+	ctx := TagsContext(ExplainContext(context.Background()), "payment")
+	result, err = wappalyzer.FingerprintURL(ctx, server.URL)
+	require.NoError(t, err, "could not fingerprint url")
+	require.Contains(t, result.GetDetections(), "Library", "tagged technologies should be reported")
+	require.Contains(t, result.GetNearMisses(), NearMiss{
+		Technology: "CMS A", Confidence: 100, DetectedBy: []string{"html"}, Reason: NearMissTags, Detail: "not tagged payment",
+	}, "technologies filtered out by tags should be near misses")
+}
@@ -0,0 +1,256 @@
+package profiler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// maxPageBodySize is the maximum number of bytes read from the main page
+const maxPageBodySize = 5 * 1024 * 1024 // 5 MB
+
+// FingerprintURL fetches the target URL and runs the full analysis on the
+// response. A bare hostname is fetched over HTTPS. The options of the instance
+// and the context of the analysis change how the target is fetched and what
+// the result holds, as documented on each of them.
+//
+// When the page cannot be fetched, the returned error is an *AnalysisError for
+// the main stage, wrapping one of the sentinel errors where the failure could
+// be classified, so callers can decide to retry or skip the target with
+// errors.Is. When the target blocks the request (ErrBlockedByTarget) or the
+// page exceeds the body size limit (ErrBodyTooLarge), the result is still
+// populated from the response that was received. Failures of secondary stages
+// such as robots.txt or DNS do not fail the analysis and are reported by the
+// result's GetErrors.
+//
+// When the deadline of ctx runs out after the page was fetched, the result
+// holds what was detected in time and reports itself as partial. The address
+// the page was fetched from is reported by the protocol of the result.
+func (s *Wappalyze) FingerprintURL(ctx context.Context, targetURL string) (richResult, error) {
+	ctx, tracer := s.startTrace(s.withUserAgent(ctx))
+	if s.budget > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, s.budget)
+		defer cancel()
+	}
+	var result richResult
+	var err error
+	if s.hostAliases {
+		result, err = s.fingerprintHostAliases(ctx, targetURL)
+	} else {
+		result, err = s.fingerprintURL(ctx, targetURL)
+	}
+	// Results are cached whole, so the tags of each analysis apply after the cache
+	result = s.filterTags(result, s.tagsOf(ctx))
+	if tracer != nil {
+		result.trace = tracer.trace()
+	}
+	return result, err
+}
+
+// fingerprintURL implements FingerprintURL for a single target
+func (s *Wappalyze) fingerprintURL(ctx context.Context, targetURL string) (richResult, error) {
+	// Serve fresh results from the cache, and revalidate stale ones if possible
+	entry, cached := s.loadCachedResult(ctx, targetURL)
+	if cached && time.Since(entry.StoredAt) < s.cache.ttl {
+		tracerOf(ctx).add(TraceEvent{Type: TraceCacheHit, URL: targetURL})
+		return entry.toResult(), nil
+	}
+	conditional := make(http.Header)
+	if cached && entry.hasValidators() {
+		if entry.ETag != "" {
+			conditional.Set("If-None-Match", entry.ETag)
+		}
+		if entry.LastModified != "" {
+			conditional.Set("If-Modified-Since", entry.LastModified)
+		}
+	}
+
+	candidates := s.candidateURLs(targetURL)
+
+	var resp *http.Response
+	var fetchedURL string
+	var fetchErr error
+	// The robots.txt fetched by polite mode, reused by the robots vector
+	var robots *robotsPrefetch
+
+	// Record the address the page is fetched from, to report and locate it
+	var remote netip.Addr
+	fetchCtx := withRemoteAddr(ctx, &remote)
+	for i, candidate := range candidates {
+		// Never fetch targets refused by the target check
+		if err := s.checkTarget(ctx, candidate); err != nil {
+			if i == 0 {
+				fetchErr = &AnalysisError{Stage: StageMain, URL: candidate, Err: err}
+			}
+			break
+		}
+
+		// Never fetch pages robots.txt disallows in polite mode
+		if s.politeMode {
+			prefetch, err := s.checkRobots(ctx, candidate)
+			if err != nil {
+				if i == 0 {
+					fetchErr = err
+				}
+				break
+			}
+			robots = prefetch
+		}
+
+		var err error
+		resp, err = s.fetchPage(fetchCtx, candidate, conditional)
+		if err == nil {
+			fetchedURL = candidate
+			break
+		}
+
+		s.logger.DebugContext(ctx, "fetch failed", "url", candidate, "error", err)
+
+		// Report the failure of the preferred scheme
+		if i == 0 {
+			fetchErr = err
+		}
+		if !shouldFallback(ctx, err) {
+			break
+		}
+	}
+	if resp == nil {
+		s.progress(ctx).stage(StageMain, fetchErr)
+		return richResult{}, fetchErr
+	}
+	defer resp.Body.Close()
+	if robots != nil {
+		ctx = context.WithValue(ctx, robotsPrefetchKey{}, robots)
+	}
+
+	// The cached result is still valid
+	if resp.StatusCode == http.StatusNotModified && len(conditional) > 0 {
+		entry.StoredAt = time.Now()
+		s.storeCachedResult(ctx, targetURL, entry)
+		tracerOf(ctx).add(TraceEvent{Type: TraceCacheHit, URL: targetURL, Detail: "revalidated"})
+		s.progress(ctx).stage(StageMain, nil)
+		result := entry.toResult()
+		result.notModified = true
+		return result, nil
+	}
+
+	// Read one byte past the limit to detect oversized bodies
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxPageBodySize+1))
+	if err != nil {
+		analysisErr := newAnalysisError(StageMain, fetchedURL, err)
+		analysisErr.StatusCode = resp.StatusCode
+		s.progress(ctx).stage(StageMain, analysisErr)
+		return richResult{}, analysisErr
+	}
+
+	var analysisErr *AnalysisError
+	if len(body) > maxPageBodySize {
+		body = body[:maxPageBodySize]
+		analysisErr = &AnalysisError{
+			Stage:      StageMain,
+			URL:        fetchedURL,
+			StatusCode: resp.StatusCode,
+			Err:        fmt.Errorf("%w: exceeds %d bytes", ErrBodyTooLarge, maxPageBodySize),
+		}
+	} else if isBlockedStatus(resp.StatusCode) {
+		analysisErr = &AnalysisError{
+			Stage:      StageMain,
+			URL:        fetchedURL,
+			StatusCode: resp.StatusCode,
+			Err:        fmt.Errorf("%w: status %d", ErrBlockedByTarget, resp.StatusCode),
+		}
+	}
+
+	if analysisErr != nil {
+		s.progress(ctx).stage(StageMain, analysisErr)
+	} else {
+		s.progress(ctx).stage(StageMain, nil)
+	}
+
+	result := s.analyzeWithPipelineContext(ctx, resp, body)
+	result.protocol.SchemeFallback = fetchedURL != candidates[0]
+	if remote.IsValid() {
+		result.protocol.RemoteIP = remote.String()
+		result.protocol.IPFamily = ipFamilyOf(remote)
+	}
+	if s.geoIP != nil {
+		result.location = s.locate(ctx, remote)
+	}
+	if analysisErr != nil {
+		return result, analysisErr
+	}
+	if result.PartialResult() {
+		return result, nil
+	}
+
+	s.storeCachedResult(ctx, targetURL, newCachedResult(result, resp.Header.Get("ETag"), resp.Header.Get("Last-Modified")))
+	return result, nil
+}
+
+// fetchPage requests the main page with the given extra headers,
+// returning an *AnalysisError on failure
+func (s *Wappalyze) fetchPage(ctx context.Context, targetURL string, header http.Header) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", targetURL, nil)
+	if err != nil {
+		return nil, &AnalysisError{Stage: StageMain, URL: targetURL, Err: err}
+	}
+	req.Header.Set("User-Agent", userAgentOf(ctx).Header)
+	for name, values := range header {
+		req.Header[name] = values
+	}
+
+	resp, err := doWithRetry(s.httpClient, req, s.retryPolicy, s.rateLimiter)
+	if err != nil {
+		return nil, newAnalysisError(StageMain, targetURL, err)
+	}
+	return resp, nil
+}
+
+// candidateURLs returns the URLs to try for a target, in order of preference.
+// Bare hostnames and addresses get an HTTPS scheme, and the other scheme is
+// appended when scheme fallback is enabled.
+func (s *Wappalyze) candidateURLs(targetURL string) []string {
+	if !strings.Contains(targetURL, "://") {
+		// IPv6 addresses are bracketed in URLs
+		if addr, err := netip.ParseAddr(targetURL); err == nil && addr.Is6() {
+			targetURL = "[" + targetURL + "]"
+		}
+		targetURL = "https://" + targetURL
+	}
+	candidates := []string{targetURL}
+	if !s.schemeFallback {
+		return candidates
+	}
+
+	parsedURL, err := url.Parse(targetURL)
+	if err != nil {
+		return candidates
+	}
+	switch parsedURL.Scheme {
+	case "https":
+		parsedURL.Scheme = "http"
+	case "http":
+		parsedURL.Scheme = "https"
+	default:
+		return candidates
+	}
+	return append(candidates, parsedURL.String())
+}
+
+// shouldFallback reports whether a failed page fetch may succeed over the other scheme.
+// A host that does not resolve will not resolve over either scheme, and a
+// refused address or redirect is refused over either scheme too.
+func shouldFallback(ctx context.Context, err error) bool {
+	var analysisErr *AnalysisError
+	if !errors.As(err, &analysisErr) || analysisErr.StatusCode != 0 {
+		return false
+	}
+	return ctx.Err() == nil && !errors.Is(err, ErrDNSFailure) && !errors.Is(err, ErrTargetNotAllowed)
+}
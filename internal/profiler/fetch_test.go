@@ -0,0 +1,153 @@
+package profiler
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestFingerprintURLSchemeFallback(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Server", "nginx")
+		w.Write([]byte("<html></html>"))
+	}))
+	defer server.Close()
+
+	host := strings.TrimPrefix(server.URL, "http://")
+
+	t.Run("disabled", func(t *testing.T) {
+		wappalyzer, err := New()
+		require.NoError(t, err, "could not create wappalyzer")
+
+		_, err = wappalyzer.FingerprintURL(context.Background(), "https://"+host)
+		require.ErrorIs(t, err, ErrTLSHandshake, "https fetch of a plain http server should fail")
+	})
+
+	tests := []struct {
+		name      string
+		targetURL string
+	}{
+		{"https", "https://" + host},
+		{"bare-hostname", host},
+	}
+
+	wappalyzer, err := New(WithSchemeFallback(true))
+	require.NoError(t, err, "could not create wappalyzer")
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := wappalyzer.FingerprintURL(context.Background(), tt.targetURL)
+			require.NoError(t, err, "could not fall back to http")
+			require.Contains(t, result.GetTechnologies(), "Nginx", "could not analyze fallback response")
+
+			protocol := result.GetProtocol()
+			require.Equal(t, "http", protocol.Scheme, "wrong scheme recorded")
+			require.True(t, protocol.SchemeFallback, "fallback not recorded")
+		})
+	}
+}
+
+func TestFingerprintURLTargetCheck(t *testing.T) {
+	var requests int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Write([]byte("<html></html>"))
+	}))
+	defer server.Close()
+
+	host := strings.TrimPrefix(server.URL, "http://")
+
+	// Only allow https, so neither the target nor its http fallback may be fetched
+	var checked []string
+	wappalyzer, err := New(WithSchemeFallback(true), WithTargetCheck(func(ctx context.Context, targetURL string) error {
+		checked = append(checked, targetURL)
+		if !strings.HasPrefix(targetURL, "https://") {
+			return errors.New("scheme not allowed")
+		}
+		return nil
+	}))
+	require.NoError(t, err, "could not create wappalyzer")
+
+	_, err = wappalyzer.FingerprintURL(context.Background(), server.URL)
+	require.ErrorIs(t, err, ErrTargetNotAllowed, "target should be refused")
+	require.Contains(t, err.Error(), "scheme not allowed", "refusal reason missing")
+	require.Zero(t, requests, "refused target should not be fetched")
+
+	// The https attempt fails at the TLS layer, and its fallback is refused
+	checked = nil
+	_, err = wappalyzer.FingerprintURL(context.Background(), host)
+	require.ErrorIs(t, err, ErrTLSHandshake, "original failure should be reported")
+	// The https candidate is checked before it is tried, and again as it is sent
+	require.Equal(t, []string{"https://" + host, "https://" + host, "http://" + host}, checked, "every candidate should be checked")
+	require.Zero(t, requests, "refused fallback should not be fetched")
+}
+
+func TestFingerprintURLTargetCheckRedirects(t *testing.T) {
+	var denied atomic.Int32
+	deniedServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		denied.Add(1)
+		w.Write([]byte("<html></html>"))
+	}))
+	defer deniedServer.Close()
+
+	allowedServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/redirect" {
+			http.Redirect(w, r, deniedServer.URL+"/latest/meta-data", http.StatusFound)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(`<html><head><script src="` + deniedServer.URL + `/app.js"></script></head></html>`))
+	}))
+	defer allowedServer.Close()
+
+	// Refuse the denied server, which only differs by its port
+	wappalyzer, err := New(WithDisabledVectors(VectorDNS), WithTargetCheck(func(ctx context.Context, targetURL string) error {
+		if strings.HasPrefix(targetURL, deniedServer.URL) {
+			return errors.New("port is denied")
+		}
+		return nil
+	}))
+	require.NoError(t, err, "could not create wappalyzer")
+
+	_, err = wappalyzer.FingerprintURL(context.Background(), allowedServer.URL+"/redirect")
+	require.ErrorIs(t, err, ErrTargetNotAllowed, "redirect should be refused")
+	require.Contains(t, err.Error(), "port is denied", "refusal reason missing")
+
+	_, err = wappalyzer.FingerprintURL(context.Background(), allowedServer.URL)
+	require.NoError(t, err, "allowed page should be analyzed")
+	require.Zero(t, denied.Load(), "refused redirect and asset should not be fetched")
+}
+
+func TestFingerprintURLAddressCheck(t *testing.T) {
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		w.Write([]byte("<html></html>"))
+	}))
+	defer server.Close()
+
+	// The host name passes any URL check, and is refused once resolved
+	target := strings.Replace(server.URL, "127.0.0.1", "localhost", 1)
+	var checked []netip.Addr
+	wappalyzer, err := New(WithAddressCheck(func(ctx context.Context, addr netip.Addr) error {
+		checked = append(checked, addr)
+		if addr.IsLoopback() {
+			return errors.New("address is in denied range")
+		}
+		return nil
+	}))
+	require.NoError(t, err, "could not create wappalyzer")
+
+	_, err = wappalyzer.FingerprintURL(context.Background(), target)
+	require.ErrorIs(t, err, ErrTargetNotAllowed, "resolved address should be refused")
+	require.Contains(t, err.Error(), "address is in denied range", "refusal reason missing")
+	require.NotEmpty(t, checked, "dialed addresses should be checked")
+	require.Zero(t, requests.Load(), "refused address should not be connected to")
+}
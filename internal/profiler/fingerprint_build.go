@@ -0,0 +1,132 @@
+package profiler
+
+import (
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// buildRule identifies a meta-framework by the layout of its production
+// build, whose hashed file names defeat the patterns of the fingerprint data,
+// and reads its version from the runtime chunks of the build
+type buildRule struct {
+	technology string
+	// paths are the directories of the build output, matched anywhere in the
+	// path of the scripts and links of the page so base paths and asset
+	// prefixes on a CDN are covered
+	paths []string
+	// versions match the version of the framework in the scripts served from
+	// one of paths, in the first group
+	versions []*regexp.Regexp
+}
+
+// buildRules are the meta-frameworks matched by the build vector
+var buildRules = []buildRule{
+	{
+		technology: "Next.js",
+		// The build manifests sit under the build ID, e.g.
+		// /_next/static/<build ID>/_buildManifest.js
+		paths: []string{"/_next/static/"},
+		versions: []*regexp.Regexp{
+			// window.next = {version: "14.2.3", ...} of the main chunk
+			regexp.MustCompile(`window\.next\s*=\s*\{\s*version\s*:\s*"(\d+\.\d+\.\d+[\w.-]*)"`),
+			// The minified constant it is assigned from, e.g.
+			// let r="14.2.3";window.next={version:r,appDir:!0}
+			regexp.MustCompile(`=\s*"(\d+\.\d+\.\d+[\w.-]*)"\s*[,;]\s*window\.next\s*=\s*\{\s*version\s*:`),
+		},
+	},
+	{
+		technology: "Nuxt.js",
+		// Nuxt 3.8 and later also publish their build metadata under
+		// /_nuxt/builds/meta/
+		paths: []string{"/_nuxt/"},
+		versions: []*regexp.Regexp{
+			// The versions of the Nuxt app of the entry chunk
+			regexp.MustCompile(`get nuxt\(\)\s*\{\s*return\s*"(\d+\.\d+\.\d+[\w.-]*)"`),
+		},
+	},
+	{
+		technology: "SvelteKit",
+		// The content hashed output of SvelteKit 1.0 and later
+		paths: []string{"/_app/immutable/"},
+	},
+}
+
+// buildSignals are the parts of a page the build vector matches
+type buildSignals struct {
+	// urls are the URLs of the external scripts and links of the page
+	urls []string
+	// scripts are the contents of the fetched scripts, by URL
+	scripts map[string]string
+}
+
+// checkBuild matches the meta-frameworks of buildRules against the signals
+// of a page, with the version their chunks carry if any
+func (s *Wappalyze) checkBuild(signals buildSignals) []matchPartResult {
+	var technologies []matchPartResult
+	for _, rule := range buildRules {
+		if !rule.matches(signals.urls) {
+			continue
+		}
+		technologies = append(technologies, matchPartResult{
+			application: rule.technology,
+			version:     rule.version(signals.scripts),
+			confidence:  100,
+			part:        buildPart,
+		})
+		if fingerprint, ok := s.fingerprints.Apps[rule.technology]; ok {
+			for _, implies := range fingerprint.implies {
+				technologies = append(technologies, matchPartResult{
+					application: implies,
+					confidence:  100,
+					part:        buildPart,
+					implied:     true,
+				})
+			}
+		}
+	}
+	return technologies
+}
+
+// matches reports whether one of urls is in the build output of the rule
+func (r buildRule) matches(urls []string) bool {
+	for _, rawURL := range urls {
+		if r.owns(rawURL) {
+			return true
+		}
+	}
+	return false
+}
+
+// owns reports whether rawURL is in the build output of the rule
+func (r buildRule) owns(rawURL string) bool {
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		return false
+	}
+	for _, path := range r.paths {
+		if strings.Contains(parsed.Path, path) {
+			return true
+		}
+	}
+	return false
+}
+
+// version returns the version of the framework found in the scripts of its
+// build output, in order of URL so the same version is reported across runs
+func (r buildRule) version(scripts map[string]string) string {
+	if len(r.versions) == 0 {
+		return ""
+	}
+	for _, scriptURL := range sortedKeys(scripts) {
+		if !r.owns(scriptURL) {
+			continue
+		}
+		for _, pattern := range r.versions {
+			if match := pattern.FindStringSubmatch(scripts[scriptURL]); match != nil {
+				return match[1]
+			}
+		}
+	}
+	return ""
+}
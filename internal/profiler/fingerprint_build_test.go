@@ -0,0 +1,117 @@
+package profiler
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckBuild(t *testing.T) {
+	wappalyzer, err := New()
+	require.NoError(t, err, "could not create wappalyzer")
+
+	tests := []struct {
+		name     string
+		signals  buildSignals
+		expected map[string]string
+	}{
+		{
+			name: "next build manifest",
+			signals: buildSignals{urls: []string{
+				"https://example.com/_next/static/Xk2nTf0a/_buildManifest.js",
+			}},
+			expected: map[string]string{"Next.js": ""},
+		},
+		{
+			name: "next version from main chunk",
+			signals: buildSignals{
+				urls: []string{"https://cdn.example.com/site/_next/static/chunks/main-3f1c.js"},
+				scripts: map[string]string{
+					"https://cdn.example.com/site/_next/static/chunks/main-3f1c.js": `let r="14.2.3";window.next={version:r,appDir:!0}`,
+				},
+			},
+			expected: map[string]string{"Next.js": "14.2.3"},
+		},
+		{
+			name: "version outside the build output",
+			signals: buildSignals{
+				urls: []string{"https://example.com/_next/static/chunks/main.js"},
+				scripts: map[string]string{
+					"https://example.com/vendor.js": `window.next={version:"9.0.0"}`,
+				},
+			},
+			expected: map[string]string{"Next.js": ""},
+		},
+		{
+			name: "nuxt entry",
+			signals: buildSignals{
+				urls: []string{"https://example.com/_nuxt/entry.B2kd.js"},
+				scripts: map[string]string{
+					"https://example.com/_nuxt/entry.B2kd.js": `versions:{get nuxt(){return"3.12.2"},get vue(){return e.vue.version}}`,
+				},
+			},
+			expected: map[string]string{"Nuxt.js": "3.12.2"},
+		},
+		{
+			name: "sveltekit module preload",
+			signals: buildSignals{urls: []string{
+				"https://example.com/_app/immutable/entry/start.Cx1.js",
+			}},
+			expected: map[string]string{"SvelteKit": ""},
+		},
+		{
+			name: "marker in the query",
+			signals: buildSignals{urls: []string{
+				"https://example.com/script.js?from=/_next/static/",
+			}},
+			expected: map[string]string{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			technologies := make(map[string]string)
+			for _, match := range wappalyzer.checkBuild(tt.signals) {
+				if match.implied {
+					continue
+				}
+				require.Equal(t, "build", match.vector(), "wrong vector for %s", match.application)
+				technologies[match.application] = match.version
+			}
+			require.Equal(t, tt.expected, technologies, "wrong technologies")
+		})
+	}
+}
+
+func TestBuildDetection(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(`<html><head>
+<link rel="modulepreload" href="/_app/immutable/entry/start.Cx1.js">
+<script src="/_next/static/chunks/main-3f1c.js"></script>
+</head><body></body></html>`))
+	})
+	mux.HandleFunc("/_next/static/chunks/main-3f1c.js", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/javascript")
+		w.Write([]byte(`window.next={version:"13.5.6",router:n};`))
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	wappalyzer, err := New(WithDisabledVectors(VectorDNS, VectorRobots))
+	require.NoError(t, err, "could not create wappalyzer")
+
+	result, err := wappalyzer.FingerprintURL(context.Background(), server.URL)
+	require.NoError(t, err, "could not fingerprint")
+	detections := result.GetDetections()
+	for _, technology := range []string{"Next.js", "SvelteKit"} {
+		require.Contains(t, detections, technology, "%s not detected", technology)
+		require.Contains(t, detections[technology].DetectedBy, "build", "build vector not reported for %s", technology)
+	}
+	require.Equal(t, "13.5.6", detections["Next.js"].Version, "wrong version")
+	require.Contains(t, detections, "React", "implied technologies should be detected")
+}
@@ -1,5 +1,78 @@
 package profiler
 
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"net/http"
+	"time"
+)
+
+// Certificate describes the leaf certificate the page was served with
+type Certificate struct {
+	// Subject and Issuer are the distinguished names of the certificate
+	Subject string `json:"subject"`
+	Issuer  string `json:"issuer"`
+	// DNSNames and IPAddresses are the subject alternative names
+	DNSNames    []string `json:"dns_names,omitempty"`
+	IPAddresses []string `json:"ip_addresses,omitempty"`
+	// NotBefore and NotAfter bound the validity period
+	NotBefore time.Time `json:"not_before"`
+	NotAfter  time.Time `json:"not_after"`
+	// KeyAlgorithm is the public key algorithm, as RSA, ECDSA or Ed25519, and
+	// KeySize its size in bits
+	KeyAlgorithm string `json:"key_algorithm"`
+	KeySize      int    `json:"key_size,omitempty"`
+	// SignatureAlgorithm is the algorithm the issuer signed it with
+	SignatureAlgorithm string `json:"signature_algorithm"`
+	// SerialNumber is the serial number, in decimal
+	SerialNumber string `json:"serial_number"`
+	// Chain lists the subjects of the other certificates the server sent,
+	// from the issuer of the leaf up
+	Chain []string `json:"chain,omitempty"`
+}
+
+// extractCertificate describes the leaf certificate a response was served
+// with, or returns nil if it was not served over TLS
+func extractCertificate(resp *http.Response) *Certificate {
+	if resp == nil || resp.TLS == nil || len(resp.TLS.PeerCertificates) == 0 {
+		return nil
+	}
+	leaf := resp.TLS.PeerCertificates[0]
+	certificate := &Certificate{
+		Subject:            leaf.Subject.String(),
+		Issuer:             leaf.Issuer.String(),
+		DNSNames:           leaf.DNSNames,
+		NotBefore:          leaf.NotBefore,
+		NotAfter:           leaf.NotAfter,
+		KeyAlgorithm:       leaf.PublicKeyAlgorithm.String(),
+		KeySize:            keySize(leaf),
+		SignatureAlgorithm: leaf.SignatureAlgorithm.String(),
+		SerialNumber:       leaf.SerialNumber.String(),
+	}
+	for _, ip := range leaf.IPAddresses {
+		certificate.IPAddresses = append(certificate.IPAddresses, ip.String())
+	}
+	for _, cert := range resp.TLS.PeerCertificates[1:] {
+		certificate.Chain = append(certificate.Chain, cert.Subject.String())
+	}
+	return certificate
+}
+
+// keySize returns the size in bits of the public key of cert, or 0 if unknown
+func keySize(cert *x509.Certificate) int {
+	switch key := cert.PublicKey.(type) {
+	case *rsa.PublicKey:
+		return key.N.BitLen()
+	case *ecdsa.PublicKey:
+		return key.Curve.Params().BitSize
+	case ed25519.PublicKey:
+		return 256
+	}
+	return 0
+}
+
 // checkCertIssuer matches the certificate issuer against fingerprint patterns
 // This is a dedicated function for the TLS certificate issuer vector
 func (s *Wappalyze) checkCertIssuer(issuer string) []matchPartResult {
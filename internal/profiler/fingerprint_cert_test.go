@@ -0,0 +1,44 @@
+package profiler
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCertificate(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("<html></html>"))
+	}))
+	defer server.Close()
+	leaf := server.Certificate()
+
+	wappalyzer, err := New(WithProfile(ProfileFast))
+	require.NoError(t, err, "could not create wappalyzer")
+
+	result, err := wappalyzer.FingerprintURL(context.Background(), server.URL)
+	require.NoError(t, err, "could not fingerprint")
+	require.Equal(t, &Certificate{
+		Subject:            leaf.Subject.String(),
+		Issuer:             leaf.Issuer.String(),
+		DNSNames:           leaf.DNSNames,
+		IPAddresses:        []string{"127.0.0.1", "::1"},
+		NotBefore:          leaf.NotBefore,
+		NotAfter:           leaf.NotAfter,
+		KeyAlgorithm:       "RSA",
+		KeySize:            2048,
+		SignatureAlgorithm: leaf.SignatureAlgorithm.String(),
+		SerialNumber:       leaf.SerialNumber.String(),
+	}, result.GetProtocol().Certificate, "wrong certificate")
+
+	wappalyzer, err = New(WithProfile(ProfileFast), WithDisabledVectors(VectorTLS))
+	require.NoError(t, err, "could not create wappalyzer")
+	result, err = wappalyzer.FingerprintURL(context.Background(), server.URL)
+	require.NoError(t, err, "could not fingerprint")
+	require.Nil(t, result.GetProtocol().Certificate, "certificate reported with the tls vector disabled")
+
+	require.Nil(t, extractCertificate(&http.Response{}), "certificate of a plain HTTP response")
+}
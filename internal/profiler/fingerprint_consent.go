@@ -0,0 +1,223 @@
+package profiler
+
+import (
+	"net/url"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// consentRule identifies a consent management platform or tag manager. They
+// are often loaded by another tag or under a first party proxy, so besides
+// the origin of their scripts, the globals page scripts call on and the names
+// of the cookies they store the consent in are matched.
+type consentRule struct {
+	technology string
+	// origins are the hosts serving the scripts, optionally followed by a path
+	// prefix. Hosts match themselves and their subdomains, as script sources
+	// or in URLs in scripts.
+	origins []string
+	// globals are the window globals of the platform, matched when a script
+	// reads a property of them, calls them or assigns them
+	globals []string
+	// cookies are the names of the cookies the platform sets
+	cookies []string
+}
+
+// consentRules are the consent management platforms (Cookie compliance) and
+// tag managers (Tag managers) of the fingerprint data matched by the consent
+// vector
+var consentRules = []consentRule{
+	{
+		technology: "OneTrust",
+		origins:    []string{"cdn.cookielaw.org", "optanon.blob.core.windows.net", "cookie-cdn.cookiepro.com"},
+		globals:    []string{"OneTrust", "Optanon", "OptanonWrapper", "OnetrustActiveGroups"},
+		cookies:    []string{"OptanonConsent", "OptanonAlertBoxClosed"},
+	},
+	{
+		technology: "Cookiebot",
+		origins:    []string{"consent.cookiebot.com", "consent.cookiebot.eu"},
+		globals:    []string{"Cookiebot"},
+	},
+	{
+		technology: "Didomi",
+		origins:    []string{"sdk.privacy-center.org"},
+		globals:    []string{"Didomi", "didomiConfig", "didomiOnReady"},
+		cookies:    []string{"didomi_token"},
+	},
+	{
+		technology: "Usercentrics",
+		origins:    []string{"app.usercentrics.eu", "web.cmp.usercentrics.eu"},
+		globals:    []string{"UC_UI", "usercentrics"},
+		cookies:    []string{"uc_settings", "uc_user_interaction"},
+	},
+	{
+		technology: "TrustArc",
+		origins:    []string{"consent.trustarc.com", "consent-pref.trustarc.com"},
+		globals:    []string{"truste"},
+		cookies:    []string{"notice_behavior", "notice_preferences", "notice_gdpr_prefs"},
+	},
+	{
+		technology: "Quantcast Choice",
+		origins:    []string{"quantcast.mgr.consensu.org", "cmp.quantcast.com"},
+		globals:    []string{"__uspapi"},
+	},
+	{
+		technology: "Osano",
+		origins:    []string{"cmp.osano.com"},
+		globals:    []string{"Osano"},
+		cookies:    []string{"osano_consentmanager", "osano_consentmanager_uuid"},
+	},
+	{
+		technology: "iubenda",
+		origins:    []string{"cdn.iubenda.com", "cs.iubenda.com"},
+		globals:    []string{"_iub"},
+	},
+	{
+		technology: "Sourcepoint",
+		origins:    []string{"cdn.privacy-mgmt.com"},
+		globals:    []string{"_sp_"},
+		cookies:    []string{"consentUUID", "_sp_v1_consent"},
+	},
+	{
+		technology: "CookieYes",
+		origins:    []string{"cdn-cookieyes.com", "app.cookieyes.com"},
+		cookies:    []string{"cookieyes-consent"},
+	},
+	{
+		technology: "Axeptio",
+		origins:    []string{"static.axept.io", "client.axept.io"},
+		globals:    []string{"axeptioSettings", "_axcb"},
+		cookies:    []string{"axeptio_cookies", "axeptio_authorized_vendors"},
+	},
+	{
+		technology: "Ketch",
+		origins:    []string{"global.ketchcdn.com"},
+		globals:    []string{"ketch"},
+	},
+	{
+		technology: "Termly",
+		origins:    []string{"app.termly.io"},
+	},
+	{
+		technology: "Google Tag Manager",
+		// gtag.js of Google Analytics is served from the same host
+		origins: []string{"googletagmanager.com/gtm.js", "googletagmanager.com/ns.html"},
+		globals: []string{"google_tag_manager"},
+	},
+	{
+		technology: "Tealium",
+		origins:    []string{"tags.tiqcdn.com"},
+		globals:    []string{"utag", "utag_data"},
+		cookies:    []string{"utag_main"},
+	},
+	{
+		technology: "Adobe Experience Platform Launch",
+		origins:    []string{"assets.adobedtm.com"},
+		globals:    []string{"_satellite"},
+	},
+	{
+		technology: "Ensighten",
+		origins:    []string{"nexus.ensighten.com"},
+	},
+	{
+		technology: "Commanders Act TagCommander",
+		origins:    []string{"cdn.tagcommander.com"},
+		globals:    []string{"tc_vars"},
+	},
+	{
+		technology: "Matomo Tag Manager",
+		globals:    []string{"MatomoTagManager"},
+	},
+}
+
+// consentGlobalPatterns match the uses of the globals of each rule, by
+// technology: a property read, a call or an assignment, possibly through
+// window, but not a property of another object
+var consentGlobalPatterns = func() map[string]*regexp.Regexp {
+	patterns := make(map[string]*regexp.Regexp)
+	for _, rule := range consentRules {
+		if len(rule.globals) == 0 {
+			continue
+		}
+		names := make([]string, len(rule.globals))
+		for i, name := range rule.globals {
+			names[i] = regexp.QuoteMeta(name)
+		}
+		patterns[rule.technology] = regexp.MustCompile(`(?:^|[^\w$.]|\bwindow\.)(?:` + strings.Join(names, "|") + `)\s*(?:\.\s*[A-Za-z_$]|\(|\[|=[^=])`)
+	}
+	return patterns
+}()
+
+// consentSignals are the parts of a page the consent vector matches
+type consentSignals struct {
+	// scriptSources are the URLs of the external scripts of the page
+	scriptSources []string
+	// scripts are the contents of the inline and fetched scripts
+	scripts []string
+	// cookies are the names of the cookies set by the response
+	cookies []string
+}
+
+// checkConsent matches the consent management platforms and tag managers of
+// consentRules against the signals of a page
+func (s *Wappalyze) checkConsent(signals consentSignals) []matchPartResult {
+	sources := make([]*url.URL, 0, len(signals.scriptSources))
+	for _, source := range signals.scriptSources {
+		if parsed, err := url.Parse(source); err == nil && parsed.Hostname() != "" {
+			sources = append(sources, parsed)
+		}
+	}
+
+	var technologies []matchPartResult
+	for _, rule := range consentRules {
+		if !rule.matches(sources, signals) {
+			continue
+		}
+		technologies = append(technologies, matchPartResult{
+			application: rule.technology,
+			confidence:  100,
+			part:        consentPart,
+		})
+		if fingerprint, ok := s.fingerprints.Apps[rule.technology]; ok {
+			for _, implies := range fingerprint.implies {
+				technologies = append(technologies, matchPartResult{
+					application: implies,
+					confidence:  100,
+					part:        consentPart,
+					implied:     true,
+				})
+			}
+		}
+	}
+	return technologies
+}
+
+// matches reports whether a script of the rule is loaded from sources or
+// referenced by a script, a script uses its globals, or one of its cookies is set
+func (r consentRule) matches(sources []*url.URL, signals consentSignals) bool {
+	for _, origin := range r.origins {
+		host, path, _ := strings.Cut(origin, "/")
+		path = "/" + path
+		if slices.ContainsFunc(sources, func(source *url.URL) bool {
+			sourceHost := strings.ToLower(source.Hostname())
+			return (sourceHost == host || strings.HasSuffix(sourceHost, "."+host)) && strings.HasPrefix(source.Path, path)
+		}) {
+			return true
+		}
+		// Loaders build the URL of the script, the origin is found in a literal
+		if slices.ContainsFunc(signals.scripts, func(script string) bool {
+			return strings.Contains(script, "//"+host+path) || strings.Contains(script, "."+host+path)
+		}) {
+			return true
+		}
+	}
+	if pattern, ok := consentGlobalPatterns[r.technology]; ok {
+		if slices.ContainsFunc(signals.scripts, pattern.MatchString) {
+			return true
+		}
+	}
+	return slices.ContainsFunc(r.cookies, func(cookie string) bool {
+		return slices.Contains(signals.cookies, cookie)
+	})
+}
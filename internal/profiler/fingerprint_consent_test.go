@@ -0,0 +1,103 @@
+package profiler
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestConsentRules(t *testing.T) {
+	wappalyzer, err := New()
+	require.NoError(t, err, "could not create wappalyzer")
+
+	for _, rule := range consentRules {
+		fingerprint, ok := wappalyzer.fingerprints.Apps[rule.technology]
+		require.True(t, ok, "unknown technology %s", rule.technology)
+		// Cookie compliance and Tag managers
+		require.True(t, slices.Contains(fingerprint.cats, 67) || slices.Contains(fingerprint.cats, 42),
+			"%s is neither a consent management platform nor a tag manager", rule.technology)
+	}
+}
+
+func TestCheckConsent(t *testing.T) {
+	wappalyzer, err := New()
+	require.NoError(t, err, "could not create wappalyzer")
+
+	tests := []struct {
+		name     string
+		signals  consentSignals
+		expected []string
+	}{
+		{
+			name:     "script source",
+			signals:  consentSignals{scriptSources: []string{"https://cdn.cookielaw.org/scripttemplates/otSDKStub.js", "https://tags.tiqcdn.com/utag/acme/main/prod/utag.js"}},
+			expected: []string{"OneTrust", "Tealium"},
+		},
+		{
+			name:     "loader snippet",
+			signals:  consentSignals{scripts: []string{`(function(w,d,s,l,i){j.src='https://www.googletagmanager.com/gtm.js?id='+i+dl;})(window,document,'script','dataLayer','GTM-ABC123');`}},
+			expected: []string{"Google Tag Manager"},
+		},
+		{
+			name:    "analytics tag",
+			signals: consentSignals{scriptSources: []string{"https://www.googletagmanager.com/gtag/js?id=G-ABC123"}},
+		},
+		{
+			name: "globals",
+			signals: consentSignals{scripts: []string{
+				`window.didomiConfig = {app: {apiKey: "key"}};`,
+				`function OptanonWrapper() { OneTrust.OnConsentChanged(reload); }`,
+				`if (window.Cookiebot && Cookiebot.consent.statistics) { track(); }`,
+			}},
+			expected: []string{"Didomi", "OneTrust", "Cookiebot"},
+		},
+		{
+			name: "globals of other objects",
+			signals: consentSignals{scripts: []string{
+				`config.Didomi = true; var name = "OneTrust"; a.utag.view();`,
+			}},
+		},
+		{
+			name:     "cookies",
+			signals:  consentSignals{cookies: []string{"session", "didomi_token", "utag_main"}},
+			expected: []string{"Didomi", "Tealium"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var technologies []string
+			for _, match := range wappalyzer.checkConsent(tt.signals) {
+				require.Equal(t, "consent", match.vector(), "wrong vector for %s", match.application)
+				technologies = append(technologies, match.application)
+			}
+			require.ElementsMatch(t, tt.expected, technologies, "wrong technologies")
+		})
+	}
+}
+
+func TestConsentDetection(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.SetCookie(w, &http.Cookie{Name: "OptanonAlertBoxClosed", Value: "2024-01-01"})
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(`<html><head>
+<script>(function(w,d,s,l,i){var j=d.createElement(s);j.src='https://www.googletagmanager.com/gtm.js?id='+i;})(window,document,'script','dataLayer','GTM-ABC123');</script>
+</head><body></body></html>`))
+	}))
+	defer server.Close()
+
+	wappalyzer, err := New(WithProfile(ProfileFast))
+	require.NoError(t, err, "could not create wappalyzer")
+
+	result, err := wappalyzer.FingerprintURL(context.Background(), server.URL)
+	require.NoError(t, err, "could not fingerprint")
+	detections := result.GetDetections()
+	for _, technology := range []string{"OneTrust", "Google Tag Manager"} {
+		require.Contains(t, detections, technology, "%s not detected", technology)
+		require.Contains(t, detections[technology].DetectedBy, "consent", "consent vector not reported for %s", technology)
+	}
+}
@@ -19,8 +19,8 @@ func (s *Wappalyze) analyzeDOM(doc *goquery.Document) []matchPartResult {
 	}
 
 	for appName, fingerprint := range s.fingerprints.Apps {
-		// Skip if no DOM patterns for this app
-		if len(fingerprint.dom) == 0 {
+		// Skip if no DOM patterns for this app, or it is settled
+		if len(fingerprint.dom) == 0 || s.fingerprints.settled.has(appName) {
 			continue
 		}
 
@@ -48,7 +48,7 @@ func (s *Wappalyze) analyzeDOM(doc *goquery.Document) []matchPartResult {
 					case "text":
 						// Element text content check
 						if pattern != nil {
-							if matched, _ := pattern.Evaluate(selection.Text(), s.regexTimeout); matched {
+							if matched, _ := pattern.evaluate(selection.Text(), s.regexTimeout, s.fingerprints.regexTimeouts); matched {
 								checkPassed = true
 							}
 						}
@@ -56,7 +56,7 @@ func (s *Wappalyze) analyzeDOM(doc *goquery.Document) []matchPartResult {
 						// Attribute checks (like href, src, class, etc.)
 						if pattern != nil {
 							if attrVal, exists := selection.Attr(checkType); exists {
-								if matched, _ := pattern.Evaluate(attrVal, s.regexTimeout); matched {
+								if matched, _ := pattern.evaluate(attrVal, s.regexTimeout, s.fingerprints.regexTimeouts); matched {
 									checkPassed = true
 								}
 							}
@@ -74,6 +74,7 @@ func (s *Wappalyze) analyzeDOM(doc *goquery.Document) []matchPartResult {
 					technologies = append(technologies, matchPartResult{
 						application: appName,
 						confidence:  100,
+						part:        domPart,
 					})
 					
 					// Add implied technologies
@@ -81,6 +82,8 @@ func (s *Wappalyze) analyzeDOM(doc *goquery.Document) []matchPartResult {
 						technologies = append(technologies, matchPartResult{
 							application: implied,
 							confidence:  100,
+							part:        domPart,
+							implied:     true,
 						})
 					}
 					
@@ -0,0 +1,74 @@
+package profiler
+
+import (
+	"context"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+var (
+	// wooCommerceGenerator matches the generator meta tag of WooCommerce
+	wooCommerceGenerator = regexp.MustCompile(`^WooCommerce\s+(\d+(?:\.\d+)+)`)
+	// magentoVersion matches the answer of /magento_version, such as
+	// Magento/2.4 (Community)
+	magentoVersion = regexp.MustCompile(`^Magento/(\d+(?:\.\d+)+)(?:\s+\(([^)]+)\))?`)
+)
+
+// inspectWooCommerce returns the version of WooCommerce in its generator meta
+// tag, which WordPress sites carry along with their own
+func inspectWooCommerce(signals platformSignals) ([]Component, string) {
+	for _, generator := range signals.generators {
+		if match := wooCommerceGenerator.FindStringSubmatch(generator); match != nil {
+			return nil, match[1]
+		}
+	}
+	return nil, ""
+}
+
+// inspectShopify returns the store and the theme of a Shopify storefront, from
+// the Shopify.shop and Shopify.theme globals it sets inline. The theme is named
+// after the theme it is built on, and titled with the name the merchant gave it.
+func inspectShopify(signals platformSignals) ([]Component, string) {
+	var components []Component
+	if shop := signals.globals["Shopify.shop"]; shop != "" {
+		components = append(components, Component{Type: ComponentStore, Name: shop, Source: "inline"})
+	}
+
+	name := signals.globals["Shopify.theme.name"]
+	theme := Component{
+		Type:    ComponentTheme,
+		Name:    signals.globals["Shopify.theme.schema_name"],
+		ID:      signals.globals["Shopify.theme.id"],
+		Version: signals.globals["Shopify.theme.schema_version"],
+		Source:  "inline",
+	}
+	if theme.Name == "" {
+		theme.Name = name
+	} else if name != theme.Name {
+		theme.Title = name
+	}
+	if theme.Name != "" {
+		components = append(components, theme)
+	}
+	return components, ""
+}
+
+// probeMagento requests /magento_version of the Magento install at root,
+// returning its edition and version
+func (s *Wappalyze) probeMagento(ctx context.Context, root *url.URL, robots *RobotsTxt) ([]Component, string) {
+	probe := root.JoinPath("magento_version")
+	if !robots.Allowed(userAgentOf(ctx).Header, probe.Path) {
+		return nil, ""
+	}
+	content := s.fetchPlatform(ctx, probe)
+	match := magentoVersion.FindStringSubmatch(strings.TrimSpace(string(content)))
+	if match == nil {
+		return nil, ""
+	}
+	var components []Component
+	if match[2] != "" {
+		components = append(components, Component{Type: ComponentEdition, Name: match[2], Source: "probe"})
+	}
+	return components, match[1]
+}
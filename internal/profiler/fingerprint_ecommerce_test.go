@@ -0,0 +1,105 @@
+package profiler
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestInspectShopify(t *testing.T) {
+	tests := []struct {
+		name     string
+		globals  map[string]string
+		expected []Component
+	}{
+		{
+			name: "renamed theme",
+			globals: map[string]string{
+				"Shopify.shop":                 "example.myshopify.com",
+				"Shopify.theme.name":           "Dawn - summer sale",
+				"Shopify.theme.id":             "136202911937",
+				"Shopify.theme.schema_name":    "Dawn",
+				"Shopify.theme.schema_version": "12.0.0",
+			},
+			expected: []Component{
+				{Type: ComponentStore, Name: "example.myshopify.com", Source: "inline"},
+				{Type: ComponentTheme, Name: "Dawn", Title: "Dawn - summer sale", ID: "136202911937", Version: "12.0.0", Source: "inline"},
+			},
+		},
+		{
+			name:     "custom theme",
+			globals:  map[string]string{"Shopify.theme.name": "Storefront", "Shopify.theme.id": "42"},
+			expected: []Component{{Type: ComponentTheme, Name: "Storefront", ID: "42", Source: "inline"}},
+		},
+		{
+			name:    "no globals",
+			globals: map[string]string{"Shopify": ""},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			components, _ := inspectShopify(platformSignals{globals: tt.globals})
+			require.Equal(t, tt.expected, components, "wrong components")
+		})
+	}
+}
+
+func TestInspectWooCommerce(t *testing.T) {
+	_, version := inspectWooCommerce(platformSignals{generators: []string{"WordPress 6.4.2", "WooCommerce 8.4.0"}})
+	require.Equal(t, "8.4.0", version, "wrong version")
+
+	_, version = inspectWooCommerce(platformSignals{generators: []string{"WordPress 6.4.2"}})
+	require.Empty(t, version, "version without a WooCommerce generator")
+}
+
+func TestEcommerceEnrichment(t *testing.T) {
+	shopify := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(`<html><head>
+<meta name="generator" content="WordPress 6.4.2">
+<meta name="generator" content="WooCommerce 8.4.0">
+<script>var Shopify = Shopify || {};
+Shopify.shop = "example.myshopify.com";
+Shopify.theme = {"name":"Dawn","id":136202911937,"schema_name":"Dawn","schema_version":"12.0.0","theme_store_id":887,"role":"main"};</script>
+<script src="https://cdn.shopify.com/s/files/1/0001/t/1/assets/theme.js" async></script>
+</head><body class="woocommerce"></body></html>`))
+	}))
+	defer shopify.Close()
+
+	wappalyzer, err := New(WithProfile(ProfileFast))
+	require.NoError(t, err, "could not create wappalyzer")
+	result, err := wappalyzer.FingerprintURL(context.Background(), shopify.URL)
+	require.NoError(t, err, "could not fingerprint")
+	detections := result.GetDetections()
+	require.Equal(t, []Component{
+		{Type: ComponentStore, Name: "example.myshopify.com", Source: "inline"},
+		{Type: ComponentTheme, Name: "Dawn", ID: "136202911937", Version: "12.0.0", Source: "inline"},
+	}, detections["Shopify"].Components, "wrong Shopify components")
+	require.Equal(t, "8.4.0", detections["WooCommerce"].Version, "wrong WooCommerce version")
+
+	magento := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/":
+			w.Header().Set("Content-Type", "text/html")
+			w.Write([]byte(`<html><head><script type="text/x-magento-init">{}</script></head><body></body></html>`))
+		case "/magento_version":
+			w.Write([]byte("Magento/2.4 (Community)"))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer magento.Close()
+
+	wappalyzer, err = New(WithDisabledVectors(VectorDNS), WithPlatformProbing(true))
+	require.NoError(t, err, "could not create wappalyzer")
+	result, err = wappalyzer.FingerprintURL(context.Background(), magento.URL)
+	require.NoError(t, err, "could not fingerprint")
+	detection := result.GetDetections()["Magento"]
+	require.Equal(t, "2.4", detection.Version, "wrong Magento version")
+	require.Contains(t, detection.DetectedBy, "platform", "platform vector not reported")
+	require.Equal(t, []Component{{Type: ComponentEdition, Name: "Community", Source: "probe"}}, detection.Components, "wrong Magento components")
+}
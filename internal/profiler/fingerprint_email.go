@@ -0,0 +1,240 @@
+package profiler
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/kavinsood/kitsune/internal/resolve"
+)
+
+// EmailInfo is the email authentication setup of the domain of the target.
+// It is metadata of the result, the providers it names are detections.
+type EmailInfo struct {
+	// SPF is the SPF record of the domain
+	SPF string `json:"spf,omitempty"`
+	// DMARC is the DMARC record of the domain
+	DMARC string `json:"dmarc,omitempty"`
+	// DMARCPolicy is the p tag of the DMARC record: none, quarantine or reject
+	DMARCPolicy string `json:"dmarc_policy,omitempty"`
+	// DKIMSelectors are the common selectors that publish a DKIM key
+	DKIMSelectors []string `json:"dkim_selectors,omitempty"`
+}
+
+// GetEmail returns the email authentication setup of the domain of the page,
+// or nil if the domain publishes none or the email vector did not run
+func (r richResult) GetEmail() *EmailInfo {
+	return r.email
+}
+
+// emailRule identifies an email provider or security vendor from the domains
+// its customers delegate to it: SPF includes and redirects, DMARC report
+// addresses and the targets of DKIM CNAME records
+type emailRule struct {
+	technology string
+	// domains match themselves and their subdomains
+	domains []string
+	// selectors are DKIM selectors only the provider uses
+	selectors []string
+}
+
+// emailRules map delegated domains to technologies of the fingerprint data
+var emailRules = []emailRule{
+	{technology: "Google Workspace", domains: []string{"_spf.google.com"}, selectors: []string{"google"}},
+	{technology: "Microsoft 365", domains: []string{"spf.protection.outlook.com", "onmicrosoft.com"}},
+	{technology: "Proofpoint", domains: []string{"pphosted.com", "ppe-hosted.com", "proofpoint.com"}},
+	{technology: "Mimecast", domains: []string{"mimecast.com", "dmarcanalyzer.com"}},
+	{technology: "Sendgrid", domains: []string{"sendgrid.net"}},
+	{technology: "Mailgun", domains: []string{"mailgun.org"}},
+	{technology: "Amazon SES", domains: []string{"amazonses.com"}},
+	{technology: "MailChimp", domains: []string{"mandrillapp.com", "mcsv.net"}, selectors: []string{"mandrill"}},
+	{technology: "Postmark", domains: []string{"mtasv.net", "postmarkapp.com"}},
+	{technology: "Zoho Mail", domains: []string{"zoho.com", "zoho.eu", "zohomail.com"}, selectors: []string{"zoho", "zmail"}},
+	{technology: "Mailjet", domains: []string{"mailjet.com"}},
+	{technology: "SparkPost", domains: []string{"sparkpostmail.com"}},
+	{technology: "Valimail", domains: []string{"vali.email"}},
+	{technology: "dmarcian", domains: []string{"dmarcian.com", "dmarcian.eu"}},
+	{technology: "Agari", domains: []string{"agari.com"}},
+}
+
+// dkimSelectors are the DKIM selectors looked up, as the keys of a domain
+// cannot be listed
+var dkimSelectors = []string{"google", "selector1", "selector2", "k1", "s1", "mandrill", "zoho", "zmail"}
+
+// emailRecords are the records the email vector gathers for a domain
+type emailRecords struct {
+	spf   string
+	dmarc string
+	// dkim maps the selectors that publish a key to the target of their CNAME
+	// record, empty if the key is published directly
+	dkim map[string]string
+}
+
+// lookupEmail looks up the SPF and DMARC records of the registrable domain
+// of host, and its DKIM keys under the common selectors, concurrently. Hosts
+// without a registrable domain, such as addresses, have no records.
+func lookupEmail(ctx context.Context, resolver *resolve.Resolver, host string) emailRecords {
+	records := emailRecords{dkim: make(map[string]string)}
+	domain := resolve.RegistrableDomain(host)
+	if domain == "" {
+		return records
+	}
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		txt, _, _ := resolver.TXT(ctx, domain)
+		spf := findRecord(txt, "v=spf1")
+		mu.Lock()
+		records.spf = spf
+		mu.Unlock()
+	}()
+	go func() {
+		defer wg.Done()
+		txt, _, _ := resolver.TXT(ctx, "_dmarc."+domain)
+		dmarc := findRecord(txt, "v=dmarc1")
+		mu.Lock()
+		records.dmarc = dmarc
+		mu.Unlock()
+	}()
+	for _, selector := range dkimSelectors {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			txt, cnames, _ := resolver.TXT(ctx, selector+"._domainkey."+domain)
+			// Keys may omit the version tag, but never the public key
+			if !slices.ContainsFunc(txt, func(record string) bool { return strings.Contains(record, "p=") }) {
+				return
+			}
+			var target string
+			if len(cnames) > 0 {
+				target = cnames[len(cnames)-1]
+			}
+			mu.Lock()
+			records.dkim[selector] = target
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+	return records
+}
+
+// findRecord returns the record of txt starting with the version tag prefix
+func findRecord(txt []string, prefix string) string {
+	for _, record := range txt {
+		if strings.HasPrefix(strings.ToLower(strings.TrimSpace(record)), prefix) {
+			return strings.TrimSpace(record)
+		}
+	}
+	return ""
+}
+
+// spfDomains returns the domains an SPF record includes or redirects to
+func spfDomains(spf string) []string {
+	var domains []string
+	for _, term := range strings.Fields(strings.ToLower(spf)) {
+		term = strings.TrimLeft(term, "+-~?")
+		if domain, ok := strings.CutPrefix(term, "include:"); ok {
+			domains = append(domains, domain)
+		} else if domain, ok := strings.CutPrefix(term, "redirect="); ok {
+			domains = append(domains, domain)
+		}
+	}
+	return domains
+}
+
+// dmarcTags returns the tags of a DMARC record by name
+func dmarcTags(dmarc string) map[string]string {
+	tags := make(map[string]string)
+	for _, tag := range strings.Split(dmarc, ";") {
+		name, value, ok := strings.Cut(tag, "=")
+		if !ok {
+			continue
+		}
+		tags[strings.ToLower(strings.TrimSpace(name))] = strings.TrimSpace(value)
+	}
+	return tags
+}
+
+// dmarcReportDomains returns the domains of the aggregate and failure report
+// addresses of DMARC tags, such as example.com for mailto:d@example.com!10m
+func dmarcReportDomains(tags map[string]string) []string {
+	var domains []string
+	for _, name := range []string{"rua", "ruf"} {
+		for _, uri := range strings.Split(tags[name], ",") {
+			_, domain, ok := strings.Cut(strings.TrimSpace(uri), "@")
+			if !ok {
+				continue
+			}
+			domain, _, _ = strings.Cut(domain, "!")
+			domains = append(domains, strings.ToLower(domain))
+		}
+	}
+	return domains
+}
+
+// info returns the email authentication setup of the records, or nil if the
+// domain publishes none
+func (r emailRecords) info() *EmailInfo {
+	if r.spf == "" && r.dmarc == "" && len(r.dkim) == 0 {
+		return nil
+	}
+	info := &EmailInfo{SPF: r.spf, DMARC: r.dmarc, DMARCPolicy: strings.ToLower(dmarcTags(r.dmarc)["p"])}
+	for _, selector := range dkimSelectors {
+		if _, ok := r.dkim[selector]; ok {
+			info.DKIMSelectors = append(info.DKIMSelectors, selector)
+		}
+	}
+	return info
+}
+
+// matchEmailRecords matches the domains delegated by the records and their
+// DKIM selectors against emailRules
+func (s *Wappalyze) matchEmailRecords(records emailRecords) []matchPartResult {
+	domains := spfDomains(records.spf)
+	domains = append(domains, dmarcReportDomains(dmarcTags(records.dmarc))...)
+	for _, target := range records.dkim {
+		if target != "" {
+			domains = append(domains, target)
+		}
+	}
+
+	var technologies []matchPartResult
+	for _, rule := range emailRules {
+		matched := slices.ContainsFunc(domains, func(domain string) bool {
+			return slices.ContainsFunc(rule.domains, func(ruleDomain string) bool {
+				return domain == ruleDomain || strings.HasSuffix(domain, "."+ruleDomain)
+			})
+		})
+		if !matched {
+			matched = slices.ContainsFunc(rule.selectors, func(selector string) bool {
+				_, ok := records.dkim[selector]
+				return ok
+			})
+		}
+		if !matched {
+			continue
+		}
+
+		technologies = append(technologies, matchPartResult{
+			application: rule.technology,
+			confidence:  100,
+			part:        emailPart,
+		})
+		if fingerprint, ok := s.fingerprints.Apps[rule.technology]; ok {
+			for _, implies := range fingerprint.implies {
+				technologies = append(technologies, matchPartResult{
+					application: implies,
+					confidence:  100,
+					part:        emailPart,
+					implied:     true,
+				})
+			}
+		}
+	}
+	return technologies
+}
@@ -0,0 +1,143 @@
+package profiler
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"github.com/kavinsood/kitsune/internal/resolve"
+	"github.com/miekg/dns"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMatchEmailRecords(t *testing.T) {
+	wappalyzer, err := New()
+	require.NoError(t, err, "could not create wappalyzer")
+
+	tests := []struct {
+		name     string
+		records  emailRecords
+		expected []string
+	}{
+		{
+			name:     "spf includes",
+			records:  emailRecords{spf: "v=spf1 include:_spf.google.com ~include:sendgrid.net -all"},
+			expected: []string{"Google Workspace", "Sendgrid"},
+		},
+		{
+			name:     "spf redirect",
+			records:  emailRecords{spf: "v=spf1 redirect=spf.protection.outlook.com"},
+			expected: []string{"Microsoft 365"},
+		},
+		{
+			name:     "dmarc reports",
+			records:  emailRecords{dmarc: "v=DMARC1; p=reject; rua=mailto:a@rua.dmarcian.com!10m, mailto:b@example.com; ruf=mailto:c@ruf.agari.com"},
+			expected: []string{"dmarcian", "Agari"},
+		},
+		{
+			name:     "dkim",
+			records:  emailRecords{dkim: map[string]string{"google": "", "k1": "dkim.mcsv.net", "selector1": "selector1-example-com._domainkey.example.onmicrosoft.com"}},
+			expected: []string{"Google Workspace", "Microsoft 365", "MailChimp"},
+		},
+		{
+			name:    "unrelated",
+			records: emailRecords{spf: "v=spf1 include:notsendgrid.net ip4:192.0.2.1 -all", dkim: map[string]string{"selector1": ""}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var technologies []string
+			for _, match := range wappalyzer.matchEmailRecords(tt.records) {
+				require.Equal(t, "email", match.vector(), "wrong vector for %s", match.application)
+				technologies = append(technologies, match.application)
+			}
+			require.ElementsMatch(t, tt.expected, technologies, "wrong technologies")
+		})
+	}
+}
+
+func TestEmailRecordsInfo(t *testing.T) {
+	require.Nil(t, emailRecords{}.info(), "a domain without records has no info")
+
+	info := emailRecords{
+		spf:   "v=spf1 include:_spf.google.com ~all",
+		dmarc: "v=DMARC1; p=Quarantine; pct=100",
+		dkim:  map[string]string{"k1": "dkim.mcsv.net", "google": ""},
+	}.info()
+	require.Equal(t, &EmailInfo{
+		SPF:           "v=spf1 include:_spf.google.com ~all",
+		DMARC:         "v=DMARC1; p=Quarantine; pct=100",
+		DMARCPolicy:   "quarantine",
+		DKIMSelectors: []string{"google", "k1"},
+	}, info, "wrong email info")
+}
+
+// serveDNS answers the queries of the email vector from records, a zone of
+// resource records in text form, on a local resolver
+func serveDNS(t *testing.T, records ...string) string {
+	t.Helper()
+	zone := make(map[string][]dns.RR)
+	for _, record := range records {
+		rr, err := dns.NewRR(record)
+		require.NoError(t, err, "invalid record %q", record)
+		zone[rr.Header().Name] = append(zone[rr.Header().Name], rr)
+	}
+
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err, "could not listen")
+	server := &dns.Server{PacketConn: conn, Handler: dns.HandlerFunc(func(w dns.ResponseWriter, r *dns.Msg) {
+		m := new(dns.Msg)
+		m.SetReply(r)
+		name := r.Question[0].Name
+		for {
+			answers := zone[name]
+			m.Answer = append(m.Answer, answers...)
+			// Follow CNAME records as a recursive resolver does
+			if len(answers) != 1 {
+				break
+			}
+			cname, ok := answers[0].(*dns.CNAME)
+			if !ok {
+				break
+			}
+			name = cname.Target
+		}
+		if len(m.Answer) == 0 {
+			m.Rcode = dns.RcodeNameError
+		}
+		w.WriteMsg(m)
+	})}
+	go server.ActivateAndServe()
+	t.Cleanup(func() { server.Shutdown() })
+	return conn.LocalAddr().String()
+}
+
+func TestLookupEmail(t *testing.T) {
+	server := serveDNS(t,
+		`example.com. 300 IN TXT "google-site-verification=abc"`,
+		`example.com. 300 IN TXT "v=spf1 include:_spf.google.com include:mail.zendesk.com ~all"`,
+		`_dmarc.example.com. 300 IN TXT "v=DMARC1; p=reject; rua=mailto:dmarc@inbox.vali.email"`,
+		`google._domainkey.example.com. 300 IN TXT "v=DKIM1; k=rsa; p=MIGf"`,
+		`k1._domainkey.example.com. 300 IN CNAME dkim.mcsv.net.`,
+		`dkim.mcsv.net. 300 IN TXT "v=DKIM1; k=rsa; p=MIIB"`,
+		`selector1._domainkey.example.com. 300 IN CNAME selector1-example-com._domainkey.example.onmicrosoft.com.`,
+	)
+	resolver := resolve.New(resolve.DefaultClient, []string{server}, 0)
+
+	records := lookupEmail(context.Background(), resolver, "www.example.com")
+	require.Equal(t, "v=spf1 include:_spf.google.com include:mail.zendesk.com ~all", records.spf, "wrong spf record")
+	require.Equal(t, "v=DMARC1; p=reject; rua=mailto:dmarc@inbox.vali.email", records.dmarc, "wrong dmarc record")
+	// selector1 points to a key that does not exist
+	require.Equal(t, map[string]string{"google": "", "k1": "dkim.mcsv.net"}, records.dkim, "wrong dkim keys")
+
+	wappalyzer, err := New()
+	require.NoError(t, err, "could not create wappalyzer")
+	var technologies []string
+	for _, match := range wappalyzer.matchEmailRecords(records) {
+		technologies = append(technologies, match.application)
+	}
+	require.ElementsMatch(t, []string{"Google Workspace", "Valimail", "MailChimp"}, technologies, "wrong technologies")
+
+	require.Empty(t, lookupEmail(context.Background(), resolver, "127.0.0.1").dkim, "addresses have no email records")
+}
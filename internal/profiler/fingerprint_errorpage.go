@@ -0,0 +1,164 @@
+package profiler
+
+import (
+	"cmp"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"net/url"
+)
+
+// errorPageRule identifies a technology from the body of a default error page
+type errorPageRule struct {
+	technology string
+	pattern    *ParsedPattern
+}
+
+// errorPageRules contains the dedicated ruleset for default framework and
+// server error pages. Patterns use the same syntax as the fingerprint data.
+var errorPageRules = compileErrorPageRules(map[string][]string{
+	"Django": {
+		`<title>Page not found at /`,
+		`You're seeing this error because you have <code>DEBUG = True</code>`,
+	},
+	"Ruby on Rails": {
+		`The page you were looking for doesn't exist`,
+		`<h1>Routing Error</h1>`,
+	},
+	"Apache Tomcat": {
+		`<h3>Apache Tomcat/([\d.]+)</h3>\;version:\1`,
+		`<p><b>Type</b> Status Report</p>`,
+	},
+	"IIS": {
+		`<title>IIS (\d+\.\d+) Detailed Error\;version:\1`,
+		`The resource you are looking for has been removed, had its name changed, or is temporarily unavailable`,
+	},
+	"Microsoft ASP.NET": {
+		`Server Error in '/' Application`,
+		`ASP\.NET Version:([\d.]+)\;version:\1`,
+	},
+	"Spring": {
+		`<h1>Whitelabel Error Page</h1>`,
+	},
+	"Express": {
+		`<pre>Cannot (?:GET|POST) /`,
+	},
+	"Flask": {
+		`The requested URL was not found on the server\. If you entered the URL manually please check your spelling and try again\.`,
+	},
+	"Laravel": {
+		`Whoops, looks like something went wrong`,
+	},
+	"Next.js": {
+		`This page could not be found\.</h2>`,
+	},
+	"Jetty": {
+		`Powered by Jetty:// ([\d.]+)\;version:\1`,
+	},
+	"Nginx": {
+		`<center>nginx(?:/([\d.]+))?</center>\;version:\1`,
+	},
+	"Apache HTTP Server": {
+		`<address>Apache(?:/([\d.]+))? [^<]{0,100}Server at\;version:\1`,
+	},
+})
+
+// compileErrorPageRules compiles the error page ruleset, dropping invalid patterns
+func compileErrorPageRules(rules map[string][]string) []errorPageRule {
+	var compiled []errorPageRule
+	for technology, patterns := range rules {
+		for _, pattern := range patterns {
+			parsed, err := ParsePattern(pattern)
+			if err != nil {
+				continue
+			}
+			compiled = append(compiled, errorPageRule{technology: technology, pattern: parsed})
+		}
+	}
+	return compiled
+}
+
+// probeErrorPages requests a guaranteed-nonexistent path and sends a malformed
+// request to the target, then matches the error pages and their headers.
+// Paths robots disallows for the user agent of the analysis are not probed.
+func (s *Wappalyze) probeErrorPages(ctx context.Context, target *url.URL, robots *RobotsTxt) []matchPartResult {
+	var technologies []matchPartResult
+
+	token := make([]byte, 8)
+	if _, err := rand.Read(token); err != nil {
+		return nil
+	}
+
+	probes := []*url.URL{
+		// A random path that cannot exist triggers the default 404 page
+		{Scheme: target.Scheme, Host: target.Host, Path: "/kitsune-" + hex.EncodeToString(token) + ".html"},
+		// An invalid percent-encoding in the request line triggers a 400 page.
+		// Opaque is sent verbatim, bypassing the client's own URL validation.
+		{Scheme: target.Scheme, Host: target.Host, Opaque: "/%"},
+	}
+
+	for _, probe := range probes {
+		if !robots.Allowed(userAgentOf(ctx).Header, cmp.Or(probe.Path, probe.Opaque)) {
+			continue
+		}
+		technologies = append(technologies, s.probeErrorPage(ctx, probe)...)
+	}
+	return technologies
+}
+
+// probeErrorPage performs a single error page probe
+func (s *Wappalyze) probeErrorPage(ctx context.Context, probe *url.URL) []matchPartResult {
+	// The malformed probe does not parse as a URL, so the request is built for
+	// the root of the target and sent to the probe
+	root := &url.URL{Scheme: probe.Scheme, Host: probe.Host, Path: "/"}
+	req, err := http.NewRequestWithContext(ctx, "GET", root.String(), nil)
+	if err != nil {
+		return nil
+	}
+	req.URL = probe
+	req.Header.Set("User-Agent", userAgentOf(ctx).Header)
+
+	if err := s.rateLimiter.wait(ctx, probe.Host); err != nil {
+		return nil
+	}
+	resp, err := tracedDo(s.httpClient, req)
+	if err != nil {
+		return nil
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 256*1024)) // 256KB limit
+	if err != nil {
+		return nil
+	}
+
+	// Error responses often carry headers that the main page suppresses
+	technologies := s.checkHeaders(s.normalizeHeaders(resp.Header))
+
+	content := string(body)
+	for _, rule := range errorPageRules {
+		if valid, version := rule.pattern.evaluate(content, s.regexTimeout, s.fingerprints.regexTimeouts); valid {
+			technologies = append(technologies, matchPartResult{
+				application: rule.technology,
+				version:     version,
+				confidence:  rule.pattern.Confidence,
+			})
+			if fingerprint, ok := s.fingerprints.Apps[rule.technology]; ok {
+				for _, implies := range fingerprint.implies {
+					technologies = append(technologies, matchPartResult{
+						application: implies,
+						confidence:  rule.pattern.Confidence,
+						implied:     true,
+					})
+				}
+			}
+		}
+	}
+
+	for i := range technologies {
+		technologies[i].part = errorPagePart
+	}
+	return technologies
+}
@@ -0,0 +1,178 @@
+package profiler
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/textproto"
+	"net/url"
+	"strings"
+)
+
+// maxRawHeaderLines bounds how many header lines are read from a raw response
+const maxRawHeaderLines = 100
+
+// headerOrderRule identifies a server or proxy from the order and casing of
+// its response header names. The net/http client canonicalizes header names
+// and discards their order, so these rules run against a raw capture.
+// Header order is a weak signal, so rules carry a low confidence.
+type headerOrderRule struct {
+	technology string
+	confidence int
+	match      func(names []string) bool
+}
+
+var headerOrderRules = []headerOrderRule{
+	// Nginx writes Server first, immediately followed by Date
+	{technology: "Nginx", confidence: 25, match: func(names []string) bool {
+		return len(names) >= 2 && names[0] == "Server" && names[1] == "Date"
+	}},
+	// Apache HTTP Server writes Date first, immediately followed by Server
+	{technology: "Apache HTTP Server", confidence: 25, match: func(names []string) bool {
+		return len(names) >= 2 && names[0] == "Date" && names[1] == "Server"
+	}},
+	// IIS writes entity headers such as Content-Type before Server, and Date
+	// after Server and the X-Powered-By headers
+	{technology: "IIS", confidence: 25, match: func(names []string) bool {
+		contentType, server, date := headerIndex(names, "Content-Type"), headerIndex(names, "Server"), headerIndex(names, "Date")
+		return contentType >= 0 && server > contentType && date > server
+	}},
+	// Envoy lowercases every header name, even over HTTP/1.1
+	{technology: "Envoy", confidence: 25, match: func(names []string) bool {
+		if len(names) < 3 {
+			return false
+		}
+		for _, name := range names {
+			if name != strings.ToLower(name) {
+				return false
+			}
+		}
+		return true
+	}},
+}
+
+// headerIndex returns the position of the first header with exactly the given
+// name and casing, or -1 if it is not present
+func headerIndex(names []string, name string) int {
+	for i, n := range names {
+		if n == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// matchHeaderOrder matches raw header names, in wire order and casing,
+// against the header order ruleset
+func matchHeaderOrder(names []string) []matchPartResult {
+	var technologies []matchPartResult
+	if len(names) == 0 {
+		return technologies
+	}
+
+	for _, rule := range headerOrderRules {
+		if !rule.match(names) {
+			continue
+		}
+		technologies = append(technologies, matchPartResult{
+			application: rule.technology,
+			confidence:  rule.confidence,
+			part:        headerOrderPart,
+		})
+	}
+	return technologies
+}
+
+// fetchRawHeaderNames requests the target over a raw HTTP/1.1 connection and
+// returns the response header names exactly as they appeared on the wire. The
+// connection is dialed like those of the client, through the target and
+// address checks, the IP family and the TLS policy of the instance. Transport
+// middleware wraps HTTP round trips, so it does not see the probe.
+func (s *Wappalyze) fetchRawHeaderNames(ctx context.Context, target *url.URL) ([]string, error) {
+	if err := s.checkTarget(ctx, target.String()); err != nil {
+		return nil, err
+	}
+
+	host := target.Hostname()
+	port := target.Port()
+	if port == "" {
+		port = "80"
+		if target.Scheme == "https" {
+			port = "443"
+		}
+	}
+
+	conn, err := s.ipFamily.dialContext(s.dialControl())(ctx, "tcp", net.JoinHostPort(host, port))
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	if target.Scheme == "https" {
+		config := s.tlsPolicy.tlsConfig()
+		config.ServerName = host
+		// Force HTTP/1.1, where header casing is preserved
+		config.NextProtos = []string{"http/1.1"}
+		tlsConn := tls.Client(conn, config)
+		if err := tlsConn.HandshakeContext(ctx); err != nil {
+			return nil, err
+		}
+		conn = tlsConn
+	}
+
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	path := target.RequestURI()
+	request := fmt.Sprintf("GET %s HTTP/1.1\r\nHost: %s\r\nUser-Agent: %s\r\nAccept: */*\r\nConnection: close\r\n\r\n",
+		path, target.Host, userAgentOf(ctx).Header)
+	if _, err := conn.Write([]byte(request)); err != nil {
+		return nil, err
+	}
+
+	reader := textproto.NewReader(bufio.NewReader(conn))
+
+	// Skip the status line
+	statusLine, err := reader.ReadLine()
+	if err != nil {
+		return nil, err
+	}
+	if !strings.HasPrefix(statusLine, "HTTP/") {
+		return nil, fmt.Errorf("unexpected status line: %q", statusLine)
+	}
+
+	var names []string
+	for i := 0; i < maxRawHeaderLines; i++ {
+		line, err := reader.ReadLine()
+		if err != nil {
+			return names, err
+		}
+		if line == "" {
+			break
+		}
+		// Skip obsolete folded continuation lines
+		if line[0] == ' ' || line[0] == '\t' {
+			continue
+		}
+		if name, _, found := strings.Cut(line, ":"); found {
+			names = append(names, strings.TrimSpace(name))
+		}
+	}
+	return names, nil
+}
+
+// analyzeHeaderOrder captures the raw response header order of the target and
+// matches it against the header order ruleset. The captured names are returned
+// so they can be exposed in the result.
+func (s *Wappalyze) analyzeHeaderOrder(ctx context.Context, target *url.URL) ([]string, []matchPartResult) {
+	if err := s.rateLimiter.wait(ctx, target.Host); err != nil {
+		return nil, nil
+	}
+	names, err := s.fetchRawHeaderNames(ctx, target)
+	if err != nil && len(names) == 0 {
+		return nil, nil
+	}
+	return names, matchHeaderOrder(names)
+}
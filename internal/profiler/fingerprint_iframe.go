@@ -0,0 +1,34 @@
+package profiler
+
+import (
+	"github.com/PuerkitoBio/goquery"
+)
+
+// analyzeIframes matches iframe and embed src attributes against the iframe
+// fingerprint patterns and the scriptSrc patterns, since embedded widgets are
+// usually served from the same hosts as their loader scripts.
+// All results are reported under the iframe vector.
+func (s *Wappalyze) analyzeIframes(doc *goquery.Document) []matchPartResult {
+	var technologies []matchPartResult
+
+	doc.Find("iframe[src], embed[src]").Each(func(i int, elem *goquery.Selection) {
+		src, exists := elem.Attr("src")
+		if !exists || src == "" {
+			return
+		}
+		technologies = append(technologies, s.checkIframeSrc(src)...)
+	})
+
+	return technologies
+}
+
+// checkIframeSrc matches a single iframe or embed src
+func (s *Wappalyze) checkIframeSrc(src string) []matchPartResult {
+	technologies := s.fingerprints.matchString(src, iframePart, s.regexTimeout)
+
+	for _, app := range s.fingerprints.matchString(src, scriptPart, s.regexTimeout) {
+		app.part = iframePart
+		technologies = append(technologies, app)
+	}
+	return technologies
+}
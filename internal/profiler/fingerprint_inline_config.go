@@ -0,0 +1,140 @@
+package profiler
+
+import (
+	"encoding/json"
+	"io"
+	"maps"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+const (
+	// maxInlineConfigSize bounds the bytes decoded for a configuration global;
+	// larger values only record the global itself
+	maxInlineConfigSize = 1024 * 1024 // 1 MB
+	// maxInlineConfigDepth bounds the nesting of the paths recorded
+	maxInlineConfigDepth = 6
+	// maxInlineConfigPaths bounds the paths recorded for a document, so
+	// serialized application states do not grow the map without limit
+	maxInlineConfigPaths = 2000
+)
+
+// inlineConfigBlocks are the JSON data blocks frameworks serialize their
+// configuration global into, by selector
+var inlineConfigBlocks = []struct {
+	selector string
+	global   string
+}{
+	{`script#__NEXT_DATA__`, "__NEXT_DATA__"},
+	{`script#__NUXT_DATA__`, "__NUXT__"},
+	{`script[data-drupal-selector="drupal-settings-json"]`, "drupalSettings"},
+}
+
+// inlineConfigAssignment matches the assignment of a configuration global in
+// an inline script up to its value, as in window.__INITIAL_STATE__ = {...},
+// Shopify.shop = "..." or the jQuery.extend(Drupal.settings, {...}) of Drupal 7
+var inlineConfigAssignment = regexp.MustCompile(`(?:\b(?:window|self)\.)?\b(__[A-Za-z][\w$]*__|drupalSettings|Shopify(?:\.[A-Za-z_$][\w$]*)+)\s*=\s*|\bjQuery\.extend\(\s*(Drupal\.settings)\s*,\s*`)
+
+// extractInlineConfigs returns the configuration globals the data blocks of
+// the document and the inline scripts set, flattened into dotted paths such
+// as __NEXT_DATA__.buildId. Scalars are recorded with their value, objects and
+// arrays with an empty one, and the parents of every path are recorded too.
+func extractInlineConfigs(doc *goquery.Document, scripts []string) map[string]string {
+	globals := make(map[string]string)
+	if doc != nil {
+		for _, block := range inlineConfigBlocks {
+			doc.Find(block.selector).Each(func(_ int, elem *goquery.Selection) {
+				addInlineConfig(globals, block.global, elem.Text())
+			})
+		}
+	}
+
+	for _, script := range scripts {
+		for _, loc := range inlineConfigAssignment.FindAllStringSubmatchIndex(script, -1) {
+			value := script[loc[1]:]
+			// Comparisons such as Shopify.shop == null assign nothing
+			if strings.HasPrefix(value, "=") {
+				continue
+			}
+			// The global is the first group, or the second for jQuery.extend
+			start, end := loc[2], loc[3]
+			if start < 0 {
+				start, end = loc[4], loc[5]
+			}
+			global := script[start:end]
+			addInlineConfig(globals, global, value)
+		}
+	}
+	return globals
+}
+
+// addInlineConfig records global, its parents and, when source starts with a
+// JSON value, the paths of that value. Values that are not JSON, such as the
+// function calls Nuxt 2 serializes its state with, only record the global.
+func addInlineConfig(globals map[string]string, global, source string) {
+	if len(globals) >= maxInlineConfigPaths {
+		return
+	}
+	parts := strings.Split(global, ".")
+	for i := 1; i < len(parts); i++ {
+		recordInlinePath(globals, strings.Join(parts[:i], "."))
+	}
+
+	decoder := json.NewDecoder(io.LimitReader(strings.NewReader(source), maxInlineConfigSize))
+	decoder.UseNumber()
+	var value any
+	if err := decoder.Decode(&value); err != nil {
+		recordInlinePath(globals, global)
+		return
+	}
+	flattenInlineConfig(globals, global, value, 0)
+}
+
+// flattenInlineConfig records path with value and the paths of its fields,
+// visiting fields in order so the bound keeps the same paths across runs
+func flattenInlineConfig(globals map[string]string, path string, value any, depth int) {
+	if len(globals) >= maxInlineConfigPaths {
+		return
+	}
+	switch v := value.(type) {
+	case map[string]any:
+		recordInlinePath(globals, path)
+		if depth >= maxInlineConfigDepth {
+			return
+		}
+		for _, key := range slices.Sorted(maps.Keys(v)) {
+			flattenInlineConfig(globals, path+"."+key, v[key], depth+1)
+		}
+	case string:
+		globals[path] = v
+	case json.Number:
+		globals[path] = v.String()
+	case bool:
+		globals[path] = strconv.FormatBool(v)
+	default:
+		// Arrays and nulls only tell the path exists
+		recordInlinePath(globals, path)
+	}
+}
+
+// recordInlinePath records path without a value, unless it is recorded already
+func recordInlinePath(globals map[string]string, path string) {
+	if _, ok := globals[path]; !ok {
+		globals[path] = ""
+	}
+}
+
+// analyzeInlineConfigs matches the configuration globals set inline, as
+// returned by extractInlineConfigs, against the js patterns, which the
+// line-oriented extraction of external scripts cannot find in large
+// structured values
+func (s *Wappalyze) analyzeInlineConfigs(globals map[string]string) []matchPartResult {
+	if len(globals) == 0 {
+		return nil
+	}
+	return s.fingerprints.matchMapString(globals, jsPart, s.regexTimeout)
+}
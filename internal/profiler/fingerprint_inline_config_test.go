@@ -0,0 +1,142 @@
+package profiler
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExtractInlineConfigs(t *testing.T) {
+	tests := []struct {
+		name    string
+		html    string
+		scripts []string
+		want    map[string]string
+	}{
+		{
+			name: "next data block",
+			html: `<script id="__NEXT_DATA__" type="application/json">{"buildId":"abc","props":{"pageProps":{}},"isFallback":false}</script>`,
+			want: map[string]string{
+				"__NEXT_DATA__":                 "",
+				"__NEXT_DATA__.buildId":         "abc",
+				"__NEXT_DATA__.isFallback":      "false",
+				"__NEXT_DATA__.props":           "",
+				"__NEXT_DATA__.props.pageProps": "",
+			},
+		},
+		{
+			name: "drupal settings block",
+			html: `<script type="application/json" data-drupal-selector="drupal-settings-json">{"path":{"baseUrl":"/"},"ajaxTrustedUrl":[]}</script>`,
+			want: map[string]string{
+				"drupalSettings":                "",
+				"drupalSettings.ajaxTrustedUrl": "",
+				"drupalSettings.path":           "",
+				"drupalSettings.path.baseUrl":   "/",
+			},
+		},
+		{
+			name:    "window assignment",
+			scripts: []string{`window.__INITIAL_STATE__ = {"version": 3};`},
+			want: map[string]string{
+				"__INITIAL_STATE__":         "",
+				"__INITIAL_STATE__.version": "3",
+			},
+		},
+		{
+			name:    "nested assignment",
+			scripts: []string{`var Shopify = Shopify || {};` + "\n" + `Shopify.shop = "example.myshopify.com";`},
+			want: map[string]string{
+				"Shopify":      "",
+				"Shopify.shop": "example.myshopify.com",
+			},
+		},
+		{
+			name:    "drupal 7 settings",
+			scripts: []string{`jQuery.extend(Drupal.settings, {"basePath":"/"});`},
+			want: map[string]string{
+				"Drupal":                   "",
+				"Drupal.settings":          "",
+				"Drupal.settings.basePath": "/",
+			},
+		},
+		{
+			name:    "function call value",
+			scripts: []string{`window.__NUXT__=(function(a){return {data:[a]}}("x"));`},
+			want:    map[string]string{"__NUXT__": ""},
+		},
+		{
+			name:    "comparison",
+			scripts: []string{`if (Shopify.shop == null) {}`},
+			want:    map[string]string{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc, err := goquery.NewDocumentFromReader(strings.NewReader(tt.html))
+			require.NoError(t, err, "could not parse html")
+			require.Equal(t, tt.want, extractInlineConfigs(doc, tt.scripts), "wrong globals")
+		})
+	}
+}
+
+func TestExtractInlineConfigsBounded(t *testing.T) {
+	var script strings.Builder
+	script.WriteString(`window.__APOLLO_STATE__ = {`)
+	for i := range 2 * maxInlineConfigPaths {
+		if i > 0 {
+			script.WriteString(",")
+		}
+		script.WriteString(`"key` + strings.Repeat("x", i%7) + string(rune('a'+i%26)) + `":` + `{"a":{"b":{"c":{"d":{"e":{"f":{"g":1}}}}}}}`)
+	}
+	script.WriteString(`};`)
+
+	globals := extractInlineConfigs(nil, []string{script.String()})
+	require.LessOrEqual(t, len(globals), maxInlineConfigPaths, "paths should be bounded")
+	for path := range globals {
+		require.LessOrEqual(t, strings.Count(path, "."), maxInlineConfigDepth, "depth should be bounded")
+	}
+}
+
+func TestInlineConfigDetection(t *testing.T) {
+	data := `{"apps": {
+		"Framework": {"js": {"__NEXT_DATA__.buildId": ""}},
+		"Store": {"js": {"Shopify.shop": "\\.myshopify\\.com"}},
+		"CMS": {"js": {"drupalSettings.version": "([\\d.]+)\\;version:\\1"}}
+	}}`
+	path := filepath.Join(t.TempDir(), "fingerprints.json")
+	require.NoError(t, os.WriteFile(path, []byte(data), 0o600), "could not write fingerprints")
+	wappalyzer, err := NewFromFile(path, false, false, WithDisabledVectors(VectorDNS, VectorRobots))
+	require.NoError(t, err, "could not create wappalyzer")
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`<html><head>
+			<script id="__NEXT_DATA__" type="application/json">{"buildId":"abc"}</script>
+			<script type="application/json" data-drupal-selector="drupal-settings-json">{"version":"10.2"}</script>
+			<script>Shopify.shop = "example.myshopify.com";</script>
+		</head><body></body></html>`))
+	}))
+	defer server.Close()
+
+	result, err := wappalyzer.FingerprintURL(context.Background(), server.URL)
+	require.NoError(t, err, "could not fingerprint url")
+	detections := result.GetDetections()
+	for _, name := range []string{"Framework", "Store", "CMS"} {
+		require.Contains(t, detections, name, "inline configuration should be detected")
+		require.Equal(t, []string{"js"}, detections[name].DetectedBy, "wrong vector")
+	}
+	require.Equal(t, "10.2", detections["CMS"].Version, "wrong version")
+
+	wappalyzer, err = NewFromFile(path, false, false, WithDisabledVectors(VectorDNS, VectorRobots, VectorJS))
+	require.NoError(t, err, "could not create wappalyzer")
+	result, err = wappalyzer.FingerprintURL(context.Background(), server.URL)
+	require.NoError(t, err, "could not fingerprint url")
+	require.Empty(t, result.GetDetections(), "disabled js vector should not match inline configuration")
+}
@@ -0,0 +1,87 @@
+package profiler
+
+import (
+	"encoding/json"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// maxJSONLDDepth bounds recursion into nested JSON-LD documents
+const maxJSONLDDepth = 10
+
+// extractJSONLD parses all <script type="application/ld+json"> blocks in the
+// document and flattens them into a map of field name to the string values
+// found for that field anywhere in the structured data (including @graph entries).
+func extractJSONLD(doc *goquery.Document) map[string][]string {
+	fields := make(map[string][]string)
+	if doc == nil {
+		return fields
+	}
+
+	doc.Find("script[type]").Each(func(i int, elem *goquery.Selection) {
+		scriptType, _ := elem.Attr("type")
+		if !strings.EqualFold(strings.TrimSpace(scriptType), "application/ld+json") {
+			return
+		}
+
+		var document interface{}
+		if err := json.Unmarshal([]byte(elem.Text()), &document); err != nil {
+			// Malformed structured data is common, skip the block
+			return
+		}
+		flattenJSONLD(document, fields, 0)
+	})
+
+	return fields
+}
+
+// flattenJSONLD walks a decoded JSON-LD value and collects string values by field name
+func flattenJSONLD(value interface{}, fields map[string][]string, depth int) {
+	if depth > maxJSONLDDepth {
+		return
+	}
+
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, child := range v {
+			switch c := child.(type) {
+			case string:
+				fields[key] = append(fields[key], c)
+			case []interface{}:
+				// Fields like @type may hold a list of strings
+				for _, item := range c {
+					if str, ok := item.(string); ok {
+						fields[key] = append(fields[key], str)
+					}
+				}
+				flattenJSONLD(c, fields, depth+1)
+			default:
+				flattenJSONLD(c, fields, depth+1)
+			}
+		}
+	case []interface{}:
+		for _, item := range v {
+			flattenJSONLD(item, fields, depth+1)
+		}
+	}
+}
+
+// analyzeJSONLD matches structured data fields against the jsonld fingerprint
+// patterns. A "generator" field is also matched against the meta generator
+// patterns, since platforms reuse the same identification string.
+func (s *Wappalyze) analyzeJSONLD(doc *goquery.Document) []matchPartResult {
+	fields := extractJSONLD(doc)
+	if len(fields) == 0 {
+		return nil
+	}
+
+	technologies := s.fingerprints.matchMultiValueMap(fields, jsonldPart, s.regexTimeout)
+
+	for _, generator := range fields["generator"] {
+		metaTech := s.fingerprints.matchMapString(map[string]string{"generator": generator}, metaPart, s.regexTimeout)
+		technologies = append(technologies, metaTech...)
+	}
+
+	return technologies
+}
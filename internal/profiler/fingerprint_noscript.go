@@ -0,0 +1,59 @@
+package profiler
+
+import (
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// extractNoscript returns the inner HTML of all noscript blocks.
+// The HTML parser treats noscript content as raw text when scripting is
+// enabled, so tracking pixels and iframes inside it never reach the DOM matchers.
+func extractNoscript(doc *goquery.Document) []string {
+	var blocks []string
+
+	doc.Find("noscript").Each(func(i int, elem *goquery.Selection) {
+		content := elem.Text()
+		if strings.TrimSpace(content) == "" {
+			// Fall back to the parsed children in case scripting was disabled
+			content, _ = elem.Html()
+		}
+		if strings.TrimSpace(content) != "" {
+			blocks = append(blocks, content)
+		}
+	})
+	return blocks
+}
+
+// analyzeNoscript runs the html and scriptSrc matchers against the content of
+// noscript blocks, reporting results under the noscript vector
+func (s *Wappalyze) analyzeNoscript(doc *goquery.Document) []matchPartResult {
+	var technologies []matchPartResult
+
+	for _, block := range extractNoscript(doc) {
+		lowered := lowerBuffer([]byte(block))
+		for _, app := range s.fingerprints.matchString(lowered.String(), htmlPart, s.regexTimeout) {
+			app.part = noscriptPart
+			technologies = append(technologies, app)
+		}
+		lowered.release()
+
+		fragment, err := goquery.NewDocumentFromReader(strings.NewReader(block))
+		if err != nil {
+			continue
+		}
+
+		// Pixels, iframes and scripts are all loaded from their vendor's hosts
+		fragment.Find("[src]").Each(func(i int, elem *goquery.Selection) {
+			src, exists := elem.Attr("src")
+			if !exists || src == "" {
+				return
+			}
+			for _, app := range s.fingerprints.matchString(src, scriptPart, s.regexTimeout) {
+				app.part = noscriptPart
+				technologies = append(technologies, app)
+			}
+		})
+	}
+	return technologies
+}
@@ -0,0 +1,183 @@
+package profiler
+
+import (
+	"cmp"
+	"context"
+	"io"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// WithPlatformProbing enables an extra analysis stage that requests the
+// details the page does not show from the platforms detected on it: the
+// REST API index (/wp-json/) and readme.html of WordPress, for the plugins
+// registering the namespaces of the index and the version in the readme, and
+// /magento_version of Magento, for its version and edition.
+//
+// Probing sends additional requests to the target, so it is disabled by
+// default. The components the page itself reveals, such as the plugins and
+// themes it loads assets from, are reported whether probing is enabled or not.
+func WithPlatformProbing(enabled bool) Option {
+	return func(s *Wappalyze) {
+		s.platformProbing = enabled
+	}
+}
+
+// platformSignals are the parts of a page the platform enrichers read
+type platformSignals struct {
+	// urls are the URLs of the external scripts and links of the page
+	urls []string
+	// styles are the contents of the fetched stylesheets, by URL
+	styles map[string]string
+	// globals are the configuration globals set inline, as returned by
+	// extractInlineConfigs
+	globals map[string]string
+	// generators are the contents of the generator meta tags of the page
+	generators []string
+}
+
+// platformEnricher deepens the detection of a platform once it is detected,
+// with the components and the version the page or probes of the platform
+// reveal
+type platformEnricher struct {
+	technology string
+	// inspect returns the components and the version of the platform found
+	// in the page, if set
+	inspect func(signals platformSignals) ([]Component, string)
+	// probe requests the components and the version of the platform from
+	// the install at root, if set. Paths robots disallows for the user agent
+	// of the analysis are not probed.
+	probe func(s *Wappalyze, ctx context.Context, root *url.URL, robots *RobotsTxt) ([]Component, string)
+	// root returns the URL of the install serving target, or the root of its
+	// host if not set
+	root func(target *url.URL, urls []string) *url.URL
+}
+
+// platformEnrichers are the enrichers of the platforms, run in order
+var platformEnrichers = []platformEnricher{
+	{technology: "WordPress", inspect: inspectWordPress, probe: (*Wappalyze).probeWordPress, root: wordpressRoot},
+	{technology: "WooCommerce", inspect: inspectWooCommerce},
+	{technology: "Shopify", inspect: inspectShopify},
+	{technology: "Magento", probe: (*Wappalyze).probeMagento},
+}
+
+// platformDetails are the components and the version an enricher found
+type platformDetails struct {
+	components []Component
+	version    string
+}
+
+// enrichPlatforms runs the enrichers of the platforms detected reports as
+// detected, with their probes if probe is set, and returns what they found by
+// platform, along with whether a probe ran
+func (s *Wappalyze) enrichPlatforms(ctx context.Context, target *url.URL, detected func(string) bool, signals platformSignals, robots *RobotsTxt, probe bool) (map[string]platformDetails, bool) {
+	platforms := make(map[string]platformDetails)
+	var probed bool
+	for _, enricher := range platformEnrichers {
+		if !detected(enricher.technology) {
+			continue
+		}
+
+		var details platformDetails
+		if enricher.inspect != nil {
+			details.components, details.version = enricher.inspect(signals)
+		}
+		if probe && enricher.probe != nil && target != nil {
+			root := &url.URL{Scheme: target.Scheme, Host: target.Host, Path: "/"}
+			if enricher.root != nil {
+				root = enricher.root(target, signals.urls)
+			}
+			components, version := enricher.probe(s, ctx, root, robots)
+			for _, component := range components {
+				details.components = addComponent(details.components, component)
+			}
+			details.version = cmp.Or(version, details.version)
+			probed = true
+		}
+
+		sortComponents(details.components)
+		if len(details.components) > 0 || details.version != "" {
+			platforms[enricher.technology] = details
+		}
+	}
+	return platforms, probed
+}
+
+// collectGenerators returns the contents of the generator meta tags of the
+// document. Platforms installed on one another, such as WooCommerce on
+// WordPress, each add their own.
+func collectGenerators(doc *goquery.Document) []string {
+	var generators []string
+	if doc == nil {
+		return generators
+	}
+	doc.Find("meta[name]").Each(func(_ int, elem *goquery.Selection) {
+		name, _ := elem.Attr("name")
+		if content, ok := elem.Attr("content"); ok && strings.EqualFold(name, "generator") && content != "" {
+			generators = append(generators, content)
+		}
+	})
+	return generators
+}
+
+// addComponent adds component to components, completing the version and
+// title of the component of the same type and name if there is one
+func addComponent(components []Component, component Component) []Component {
+	i := slices.IndexFunc(components, func(existing Component) bool {
+		return existing.Type == component.Type && existing.Name == component.Name
+	})
+	if i < 0 {
+		return append(components, component)
+	}
+	if components[i].Version == "" && component.Version != "" {
+		components[i].Version = component.Version
+		components[i].Source = component.Source
+	}
+	if components[i].Title == "" {
+		components[i].Title = component.Title
+	}
+	if components[i].ID == "" {
+		components[i].ID = component.ID
+	}
+	return components
+}
+
+// sortComponents sorts components by type and name, so identical analyses
+// report them in the same order
+func sortComponents(components []Component) {
+	slices.SortFunc(components, func(a, b Component) int {
+		return cmp.Or(cmp.Compare(a.Type, b.Type), cmp.Compare(a.Name, b.Name))
+	})
+}
+
+// fetchPlatform requests a path of a platform, returning the body of a
+// successful response or nil
+func (s *Wappalyze) fetchPlatform(ctx context.Context, probe *url.URL) []byte {
+	req, err := http.NewRequestWithContext(ctx, "GET", probe.String(), nil)
+	if err != nil {
+		return nil
+	}
+	req.Header.Set("User-Agent", userAgentOf(ctx).Header)
+
+	if err := s.rateLimiter.wait(ctx, probe.Host); err != nil {
+		return nil
+	}
+	resp, err := tracedDo(s.httpClient, req)
+	if err != nil {
+		return nil
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 256*1024)) // 256KB limit
+	if err != nil {
+		return nil
+	}
+	return body
+}
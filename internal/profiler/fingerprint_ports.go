@@ -0,0 +1,155 @@
+package profiler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultProbePorts are the alternate ports probed by WithPortProbing when
+// none are given, where application servers and admin consoles often listen
+var DefaultProbePorts = []int{8080, 8443, 9090}
+
+// ParsePorts returns the ports of a comma separated list, such as
+// "8080,8443". An empty list has no ports.
+func ParsePorts(list string) ([]int, error) {
+	var ports []int
+	for _, value := range strings.Split(list, ",") {
+		value = strings.TrimSpace(value)
+		if value == "" {
+			continue
+		}
+		port, err := strconv.Atoi(value)
+		if err != nil || port < 1 || port > 65535 {
+			return nil, fmt.Errorf("invalid port %q", value)
+		}
+		ports = append(ports, port)
+	}
+	return ports, nil
+}
+
+// portProbeTimeout bounds the probe of a single port, so filtered ports that
+// never answer do not hold the stage until its deadline
+const portProbeTimeout = 3 * time.Second
+
+// PortProbe is an alternate port of the target that responded to a probe
+type PortProbe struct {
+	Port int `json:"port"`
+	// URL is the URL that responded, over HTTPS or else plain HTTP
+	URL        string `json:"url"`
+	StatusCode int    `json:"status_code"`
+}
+
+// GetPorts returns the alternate ports that responded to the probes of
+// WithPortProbing, in the order they were configured
+func (r richResult) GetPorts() []PortProbe {
+	return r.ports
+}
+
+// probePorts requests the root of the host of target on each alternate port,
+// over HTTPS and then plain HTTP, and matches the headers, cookies and body of
+// the ports that respond. The port of target itself is not probed again.
+func (s *Wappalyze) probePorts(ctx context.Context, target *url.URL) ([]PortProbe, []matchPartResult) {
+	targetPort := target.Port()
+	if targetPort == "" {
+		targetPort = "443"
+		if target.Scheme == "http" {
+			targetPort = "80"
+		}
+	}
+
+	ports := slices.DeleteFunc(slices.Clone(s.probePortList), func(port int) bool {
+		return strconv.Itoa(port) == targetPort
+	})
+	probes := make([]*PortProbe, len(ports))
+	results := make([][]matchPartResult, len(ports))
+	var wg sync.WaitGroup
+	for i, port := range ports {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			probes[i], results[i] = s.probePort(ctx, target.Hostname(), port)
+		}()
+	}
+	wg.Wait()
+
+	var responded []PortProbe
+	var technologies []matchPartResult
+	for i, probe := range probes {
+		if probe != nil {
+			responded = append(responded, *probe)
+			technologies = append(technologies, results[i]...)
+		}
+	}
+	return responded, technologies
+}
+
+// probePort probes a single port, returning nil if it did not respond
+func (s *Wappalyze) probePort(ctx context.Context, host string, port int) (*PortProbe, []matchPartResult) {
+	ctx, cancel := context.WithTimeout(ctx, portProbeTimeout)
+	defer cancel()
+
+	// Responses are analyzed where they are, a redirect back to the main site
+	// would only detect its technologies again
+	client := *s.httpClient
+	client.CheckRedirect = func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	}
+
+	address := net.JoinHostPort(host, strconv.Itoa(port))
+	for _, scheme := range []string{"https", "http"} {
+		probeURL := scheme + "://" + address + "/"
+		if s.checkTarget(ctx, probeURL) != nil {
+			return nil, nil
+		}
+		req, err := http.NewRequestWithContext(ctx, "GET", probeURL, nil)
+		if err != nil {
+			return nil, nil
+		}
+		req.Header.Set("User-Agent", userAgentOf(ctx).Header)
+
+		if err := s.rateLimiter.wait(ctx, address); err != nil {
+			return nil, nil
+		}
+		resp, err := tracedDo(&client, req)
+		if err != nil {
+			// Only a port speaking plain HTTP may answer over the other scheme
+			if errors.Is(classifyError(err), ErrTLSHandshake) {
+				continue
+			}
+			return nil, nil
+		}
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 256*1024)) // 256KB limit
+		resp.Body.Close()
+		return &PortProbe{Port: port, URL: probeURL, StatusCode: resp.StatusCode}, s.matchPortResponse(resp, body)
+	}
+	return nil, nil
+}
+
+// matchPortResponse matches the headers, cookies and body of a port probe
+func (s *Wappalyze) matchPortResponse(resp *http.Response, body []byte) []matchPartResult {
+	headers := s.normalizeHeaders(resp.Header)
+	technologies := s.checkHeaders(headers)
+	if cookies := s.findSetCookie(headers); len(cookies) > 0 {
+		technologies = append(technologies, s.checkCookies(cookies)...)
+	}
+	if len(body) > 0 {
+		lowered := lowerBuffer(body)
+		technologies = append(technologies, s.fingerprints.matchString(lowered.String(), htmlPart, s.regexTimeout)...)
+		lowered.release()
+	}
+
+	for i := range technologies {
+		technologies[i].part = portsPart
+	}
+	return technologies
+}
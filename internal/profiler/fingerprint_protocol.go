@@ -0,0 +1,143 @@
+package profiler
+
+import (
+	"crypto/tls"
+	"net/http"
+	"regexp"
+	"strings"
+)
+
+// ProtocolInfo describes the protocol capabilities observed on the main response
+type ProtocolInfo struct {
+	// Scheme is the URL scheme the page was fetched over
+	Scheme string `json:"scheme,omitempty"`
+	// SchemeFallback is true when the page was only reachable over the fallback scheme
+	SchemeFallback bool `json:"scheme_fallback,omitempty"`
+	// Version is the negotiated HTTP version, e.g. "HTTP/2.0"
+	Version string `json:"version,omitempty"`
+	// ALPN is the application protocol negotiated during the TLS handshake
+	ALPN string `json:"alpn,omitempty"`
+	// TLSVersion is the negotiated TLS version, e.g. "TLS 1.3"
+	TLSVersion string `json:"tls_version,omitempty"`
+	// AltSvc lists the alternative protocols advertised via Alt-Svc (h3, h3-29, quic...)
+	AltSvc []string `json:"alt_svc,omitempty"`
+	// HTTP3 is true when HTTP/3 is advertised via Alt-Svc
+	HTTP3 bool `json:"http3"`
+	// Compression is the content encoding used by the server, if any
+	Compression string `json:"compression,omitempty"`
+	// ProtocolHeaders lists the protocol-specific headers present on the response
+	ProtocolHeaders []string `json:"protocol_headers,omitempty"`
+	// HeaderOrder lists the response header names in wire order and casing.
+	// It is only captured when header order probing is enabled.
+	HeaderOrder []string `json:"header_order,omitempty"`
+	// TLSValidation is the outcome of verifying the certificate of the page.
+	// It is not set with TLSPolicyInsecure or when the tls vector is disabled.
+	TLSValidation *TLSValidation `json:"tls_validation,omitempty"`
+	// RemoteIP is the address the page was fetched from, and IPFamily its
+	// family. They are only set by FingerprintURL.
+	RemoteIP string   `json:"remote_ip,omitempty"`
+	IPFamily IPFamily `json:"ip_family,omitempty"`
+	// Certificate describes the leaf certificate of the page. It is not set
+	// when the tls vector is disabled.
+	Certificate *Certificate `json:"certificate,omitempty"`
+}
+
+// protocolHeaders are response headers that describe protocol behaviour
+// rather than the application
+var protocolHeaders = []string{
+	"alt-svc",
+	"strict-transport-security",
+	"upgrade",
+	"http2-settings",
+	"early-data",
+	"accept-ch",
+	"nel",
+	"report-to",
+	"server-timing",
+	"priority",
+}
+
+// protocolRule identifies a server or proxy from its protocol behaviour.
+// These are heuristics, so they carry a lower confidence than regular fingerprints.
+type protocolRule struct {
+	technology string
+	confidence int
+	altSvc     *regexp.Regexp
+}
+
+var protocolRules = []protocolRule{
+	// LiteSpeed advertises the full list of Google QUIC drafts alongside h3
+	{technology: "LiteSpeed", confidence: 50, altSvc: regexp.MustCompile(`h3-Q050=":\d+"; ma=2592000.*quic=":\d+"; ma=2592000; v="43,46"`)},
+	// Google front ends advertise h3 with a 30 day max-age and no drafts
+	{technology: "Google Web Server", confidence: 25, altSvc: regexp.MustCompile(`^h3=":443"; ma=2592000,h3-29=":443"; ma=2592000$`)},
+}
+
+// extractProtocolInfo collects protocol metadata from the main response
+func extractProtocolInfo(resp *http.Response) ProtocolInfo {
+	var info ProtocolInfo
+	if resp == nil {
+		return info
+	}
+
+	info.Version = resp.Proto
+	if resp.Request != nil && resp.Request.URL != nil {
+		info.Scheme = resp.Request.URL.Scheme
+	}
+
+	if resp.TLS != nil {
+		info.ALPN = resp.TLS.NegotiatedProtocol
+		info.TLSVersion = tls.VersionName(resp.TLS.Version)
+	}
+
+	if altSvc := resp.Header.Get("Alt-Svc"); altSvc != "" && altSvc != "clear" {
+		for _, entry := range strings.Split(altSvc, ",") {
+			// Parameter values such as v="43,46" contain commas, so only
+			// entries with a quoted alt-authority name a protocol
+			protocol, authority, _ := strings.Cut(strings.TrimSpace(entry), "=")
+			if protocol == "" || !strings.HasPrefix(authority, `"`) {
+				continue
+			}
+			info.AltSvc = append(info.AltSvc, protocol)
+			if protocol == "h3" || strings.HasPrefix(protocol, "h3-") {
+				info.HTTP3 = true
+			}
+		}
+	}
+
+	// The transport transparently decompresses gzip and drops the header
+	if encoding := resp.Header.Get("Content-Encoding"); encoding != "" {
+		info.Compression = strings.ToLower(encoding)
+	} else if resp.Uncompressed {
+		info.Compression = "gzip"
+	}
+
+	for _, header := range protocolHeaders {
+		if resp.Header.Get(header) != "" {
+			info.ProtocolHeaders = append(info.ProtocolHeaders, header)
+		}
+	}
+
+	return info
+}
+
+// checkProtocol matches the response's protocol behaviour against the protocol ruleset
+func (s *Wappalyze) checkProtocol(resp *http.Response) []matchPartResult {
+	if resp == nil {
+		return nil
+	}
+
+	var technologies []matchPartResult
+	altSvc := resp.Header.Get("Alt-Svc")
+
+	for _, rule := range protocolRules {
+		if altSvc == "" || !rule.altSvc.MatchString(altSvc) {
+			continue
+		}
+		technologies = append(technologies, matchPartResult{
+			application: rule.technology,
+			confidence:  rule.confidence,
+			part:        protocolPart,
+		})
+	}
+	return technologies
+}
@@ -0,0 +1,92 @@
+package profiler
+
+import (
+	"regexp"
+)
+
+// maxServiceWorkers bounds how many registered service worker scripts are fetched
+const maxServiceWorkers = 3
+
+// serviceWorkerRegisterPattern finds navigator.serviceWorker.register() calls
+var serviceWorkerRegisterPattern = regexp.MustCompile("serviceWorker\\s*\\.\\s*register\\s*\\(\\s*['\"`]([^'\"`]+)['\"`]")
+
+// pwaRule identifies a PWA framework or platform from a service worker URL,
+// service worker script content or web app manifest content
+type pwaRule struct {
+	technology string
+	pattern    *regexp.Regexp
+}
+
+var pwaRules = []pwaRule{
+	{technology: "Workbox", pattern: regexp.MustCompile(`(?i)workbox-(?:sw|core|routing|precaching)|workbox-cdn|\bworkbox\.`)},
+	{technology: "OneSignal", pattern: regexp.MustCompile(`(?i)OneSignalSDK(?:Updater)?Worker|cdn\.onesignal\.com|"gcm_sender_id"\s*:\s*"482941778795"`)},
+	{technology: "Firebase", pattern: regexp.MustCompile(`(?i)firebase-messaging(?:-sw)?|firebasejs/`)},
+	{technology: "PushEngage", pattern: regexp.MustCompile(`(?i)clientcdn\.pushengage\.com|pushengage-sw`)},
+}
+
+// extractServiceWorkerURLs returns the unique script URLs passed to
+// navigator.serviceWorker.register() in the given scripts
+func extractServiceWorkerURLs(scripts []string) []string {
+	var urls []string
+	seen := make(map[string]struct{})
+
+	for _, script := range scripts {
+		for _, matches := range serviceWorkerRegisterPattern.FindAllStringSubmatch(script, -1) {
+			if _, exists := seen[matches[1]]; exists {
+				continue
+			}
+			seen[matches[1]] = struct{}{}
+			urls = append(urls, matches[1])
+		}
+	}
+	return urls
+}
+
+// analyzePWA detects Progressive Web App support and the frameworks or push
+// platforms behind it, from the web app manifest and any registered service workers.
+// Registered service worker scripts are fetched through the asset fetcher.
+func (s *Wappalyze) analyzePWA(fetcher *AssetFetcher, scripts []string) []matchPartResult {
+	var sources []string
+
+	manifest := fetcher.Manifest()
+	if manifest != "" {
+		sources = append(sources, manifest)
+	}
+
+	workers := extractServiceWorkerURLs(scripts)
+	for i, workerURL := range workers {
+		sources = append(sources, workerURL)
+
+		if i >= maxServiceWorkers {
+			continue
+		}
+		if content, ok := fetcher.FetchText(workerURL); ok {
+			sources = append(sources, content)
+		}
+	}
+
+	if len(sources) == 0 {
+		return nil
+	}
+
+	// A manifest or a registered service worker is enough to call it a PWA
+	technologies := []matchPartResult{{
+		application: "PWA",
+		confidence:  100,
+		part:        pwaPart,
+	}}
+
+	for _, rule := range pwaRules {
+		for _, source := range sources {
+			if rule.pattern.MatchString(source) {
+				technologies = append(technologies, matchPartResult{
+					application: rule.technology,
+					confidence:  100,
+					part:        pwaPart,
+				})
+				break
+			}
+		}
+	}
+	return technologies
+}
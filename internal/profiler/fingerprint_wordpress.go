@@ -0,0 +1,152 @@
+package profiler
+
+import (
+	"context"
+	"encoding/json"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+var (
+	// wordpressAssetPath matches the directory of a plugin or theme in the URL
+	// of one of its assets
+	wordpressAssetPath = regexp.MustCompile(`/wp-content/(plugins|themes)/([A-Za-z0-9_.-]+)/`)
+	// wordpressThemeHeader matches the fields of the header of a theme stylesheet
+	wordpressThemeHeader = regexp.MustCompile(`(?m)^[\s/*#@]*(Theme Name|Version):[ \t]*(\S.*?)[ \t]*$`)
+	// wordpressReadmeVersion matches the version under the logo of readme.html,
+	// which releases before 3.7 carry
+	wordpressReadmeVersion = regexp.MustCompile(`(?i)<br\s*/?>\s*version\s+(\d+\.\d+(?:\.\d+)?)`)
+)
+
+// wordpressNamespaces maps the namespaces of the REST API, up to the first
+// slash, to the slug of the plugin registering them
+var wordpressNamespaces = map[string]string{
+	"wc":                    "woocommerce",
+	"wc-admin":              "woocommerce",
+	"wc-analytics":          "woocommerce",
+	"yoast":                 "wordpress-seo",
+	"rankmath":              "seo-by-rank-math",
+	"jetpack":               "jetpack",
+	"elementor":             "elementor",
+	"contact-form-7":        "contact-form-7",
+	"wpforms":               "wpforms-lite",
+	"akismet":               "akismet",
+	"wordfence":             "wordfence",
+	"redirection":           "redirection",
+	"litespeed":             "litespeed-cache",
+	"regenerate-thumbnails": "regenerate-thumbnails",
+}
+
+// inspectWordPress returns the plugins and themes the page loads assets from.
+// The version of a plugin is the ver query parameter of its assets, unless it
+// is the one of the assets of WordPress itself, which WordPress sets when the
+// plugin gives none. The name and version of a theme are read from the header
+// of its stylesheet.
+func inspectWordPress(signals platformSignals) ([]Component, string) {
+	var coreVersion string
+	for _, rawURL := range signals.urls {
+		if parsed, err := url.Parse(rawURL); err == nil && strings.Contains(parsed.Path, "/wp-includes/") {
+			if coreVersion = parsed.Query().Get("ver"); coreVersion != "" {
+				break
+			}
+		}
+	}
+
+	var components []Component
+	for _, rawURL := range signals.urls {
+		parsed, err := url.Parse(rawURL)
+		if err != nil {
+			continue
+		}
+		match := wordpressAssetPath.FindStringSubmatch(parsed.Path)
+		if match == nil {
+			continue
+		}
+		component := Component{Type: ComponentPlugin, Name: match[2], Source: "asset"}
+		if match[1] == "themes" {
+			component.Type = ComponentTheme
+		} else if version := parsed.Query().Get("ver"); version != coreVersion {
+			component.Version = version
+		}
+		components = addComponent(components, component)
+	}
+
+	// Theme stylesheets name the theme and its version in their header
+	for _, styleURL := range sortedKeys(signals.styles) {
+		parsed, err := url.Parse(styleURL)
+		if err != nil {
+			continue
+		}
+		match := wordpressAssetPath.FindStringSubmatch(parsed.Path)
+		if match == nil || match[1] != "themes" {
+			continue
+		}
+		component := Component{Type: ComponentTheme, Name: match[2], Source: "style"}
+		for _, field := range wordpressThemeHeader.FindAllStringSubmatch(signals.styles[styleURL], -1) {
+			switch field[1] {
+			case "Theme Name":
+				if field[2] != component.Name {
+					component.Title = field[2]
+				}
+			case "Version":
+				component.Version = field[2]
+			}
+		}
+		if component.Title != "" || component.Version != "" {
+			components = addComponent(components, component)
+		}
+	}
+	return components, ""
+}
+
+// wordpressRoot returns the URL of the WordPress install serving target: the
+// directory its assets are under, or else the root of the host
+func wordpressRoot(target *url.URL, urls []string) *url.URL {
+	for _, rawURL := range urls {
+		parsed, err := url.Parse(rawURL)
+		if err != nil || parsed.Host != target.Host {
+			continue
+		}
+		for _, dir := range []string{"/wp-content/", "/wp-includes/"} {
+			if i := strings.Index(parsed.Path, dir); i >= 0 {
+				return &url.URL{Scheme: parsed.Scheme, Host: parsed.Host, Path: parsed.Path[:i+1]}
+			}
+		}
+	}
+	return &url.URL{Scheme: target.Scheme, Host: target.Host, Path: "/"}
+}
+
+// probeWordPress requests the REST API index and readme.html of the WordPress
+// install at root, returning the plugins registering the namespaces of the
+// index and the version of the readme. Paths robots disallows for the user
+// agent of the analysis are not probed.
+func (s *Wappalyze) probeWordPress(ctx context.Context, root *url.URL, robots *RobotsTxt) ([]Component, string) {
+	var components []Component
+	var version string
+
+	index := root.JoinPath("wp-json/")
+	if robots.Allowed(userAgentOf(ctx).Header, index.Path) {
+		var body struct {
+			Namespaces []string `json:"namespaces"`
+		}
+		if content := s.fetchPlatform(ctx, index); content != nil && json.Unmarshal(content, &body) == nil {
+			for _, namespace := range body.Namespaces {
+				prefix, _, _ := strings.Cut(namespace, "/")
+				if slug, ok := wordpressNamespaces[prefix]; ok {
+					components = addComponent(components, Component{Type: ComponentPlugin, Name: slug, Source: "rest"})
+				}
+			}
+		}
+	}
+
+	readme := root.JoinPath("readme.html")
+	if robots.Allowed(userAgentOf(ctx).Header, readme.Path) {
+		if content := s.fetchPlatform(ctx, readme); content != nil {
+			if match := wordpressReadmeVersion.FindSubmatch(content); match != nil {
+				version = string(match[1])
+			}
+		}
+	}
+	return components, version
+}
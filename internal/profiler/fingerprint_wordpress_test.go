@@ -0,0 +1,123 @@
+package profiler
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestInspectWordPress(t *testing.T) {
+	tests := []struct {
+		name     string
+		signals  platformSignals
+		expected []Component
+	}{
+		{
+			name: "plugin versions",
+			signals: platformSignals{urls: []string{
+				"https://example.com/wp-includes/js/jquery/jquery.min.js?ver=6.4.2",
+				"https://example.com/wp-content/plugins/contact-form-7/includes/js/index.js?ver=5.8.4",
+				"https://example.com/wp-content/plugins/akismet/_inc/akismet.js?ver=6.4.2",
+				"https://example.com/wp-content/plugins/contact-form-7/includes/css/styles.css?ver=5.8.4",
+			}},
+			expected: []Component{
+				{Type: ComponentPlugin, Name: "contact-form-7", Version: "5.8.4", Source: "asset"},
+				{Type: ComponentPlugin, Name: "akismet", Source: "asset"},
+			},
+		},
+		{
+			name: "theme header",
+			signals: platformSignals{
+				urls: []string{"https://example.com/blog/wp-content/themes/twentytwentyfour/style.css?ver=1.0"},
+				styles: map[string]string{
+					"https://example.com/blog/wp-content/themes/twentytwentyfour/style.css?ver=1.0": "/*\nTheme Name: Twenty Twenty-Four\nAuthor: the WordPress team\nVersion: 1.0\n*/\nbody{}",
+				},
+			},
+			expected: []Component{
+				{Type: ComponentTheme, Name: "twentytwentyfour", Title: "Twenty Twenty-Four", Version: "1.0", Source: "style"},
+			},
+		},
+		{
+			name:    "no assets",
+			signals: platformSignals{urls: []string{"https://example.com/assets/app.js"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			components, version := inspectWordPress(tt.signals)
+			require.Equal(t, tt.expected, components, "wrong components")
+			require.Empty(t, version, "the page should not report a version")
+		})
+	}
+}
+
+func TestWordPressRoot(t *testing.T) {
+	target, err := url.Parse("https://example.com/blog/hello-world/")
+	require.NoError(t, err, "could not parse url")
+
+	root := wordpressRoot(target, []string{
+		"https://cdn.example.net/wp-content/uploads/logo.js",
+		"https://example.com/blog/wp-includes/js/wp-embed.min.js",
+	})
+	require.Equal(t, "https://example.com/blog/", root.String(), "wrong root")
+
+	root = wordpressRoot(target, nil)
+	require.Equal(t, "https://example.com/", root.String(), "wrong root without assets")
+}
+
+func TestWordPressProbing(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(`<html><head>
+<link rel="stylesheet" href="/wp-content/themes/astra/style.css?ver=4.5.2">
+<script src="/wp-content/plugins/elementor/assets/js/frontend.min.js?ver=3.18.0"></script>
+</head><body></body></html>`))
+	})
+	mux.HandleFunc("/wp-content/themes/astra/style.css", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/css")
+		w.Write([]byte("/**\n * Theme Name: Astra\n * Version: 4.5.2\n */\n"))
+	})
+	mux.HandleFunc("/wp-json/", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"name":"Example","namespaces":["oembed/1.0","wp/v2","wc/store/v1","yoast/v1"]}`))
+	})
+	mux.HandleFunc("/readme.html", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`<h1 id="logo"><img alt="WordPress" src="wp-admin/images/wordpress-logo.png" /><br /> Version 3.5.1</h1>`))
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	wappalyzer, err := New(WithDisabledVectors(VectorDNS))
+	require.NoError(t, err, "could not create wappalyzer")
+	result, err := wappalyzer.FingerprintURL(context.Background(), server.URL)
+	require.NoError(t, err, "could not fingerprint")
+	require.Contains(t, result.GetDetections(), "WordPress", "WordPress not detected")
+	require.Equal(t, []Component{
+		{Type: ComponentPlugin, Name: "elementor", Version: "3.18.0", Source: "asset"},
+		{Type: ComponentTheme, Name: "astra", Title: "Astra", Version: "4.5.2", Source: "style"},
+	}, result.GetDetections()["WordPress"].Components, "wrong components without probing")
+
+	wappalyzer, err = New(WithDisabledVectors(VectorDNS), WithPlatformProbing(true))
+	require.NoError(t, err, "could not create wappalyzer")
+	result, err = wappalyzer.FingerprintURL(context.Background(), server.URL)
+	require.NoError(t, err, "could not fingerprint")
+	detection := result.GetDetections()["WordPress"]
+	require.Equal(t, "3.5.1", detection.Version, "wrong version")
+	require.Contains(t, detection.DetectedBy, "platform", "platform vector not reported")
+	require.Equal(t, []Component{
+		{Type: ComponentPlugin, Name: "elementor", Version: "3.18.0", Source: "asset"},
+		{Type: ComponentPlugin, Name: "woocommerce", Source: "rest"},
+		{Type: ComponentPlugin, Name: "wordpress-seo", Source: "rest"},
+		{Type: ComponentTheme, Name: "astra", Title: "Astra", Version: "4.5.2", Source: "style"},
+	}, detection.Components, "wrong components with probing")
+}
@@ -0,0 +1,54 @@
+package profiler
+
+import (
+	"net/url"
+	"strings"
+)
+
+// checkXHR matches the hostnames of XHR/fetch requests made by the page
+// against the xhr fingerprint patterns
+func (s *Wappalyze) checkXHR(hosts []string) []matchPartResult {
+	var technologies []matchPartResult
+
+	for _, host := range hosts {
+		technologies = append(technologies, s.fingerprints.matchString(host, xhrPart, s.regexTimeout)...)
+	}
+	return technologies
+}
+
+// extractXHRHosts statically extracts request URLs from the given scripts and
+// returns the unique hostnames they point to.
+// Relative URLs are skipped since they always resolve to the target itself.
+func extractXHRHosts(baseURL string, scripts []string) []string {
+	var base *url.URL
+	if baseURL != "" {
+		base, _ = url.Parse(baseURL)
+	}
+
+	var hosts []string
+	seen := make(map[string]struct{})
+
+	for _, script := range scripts {
+		for _, rawURL := range ExtractRequestURLs(script) {
+			parsed, err := url.Parse(rawURL)
+			if err != nil {
+				continue
+			}
+			// Protocol-relative URLs need the scheme of the page to resolve
+			if base != nil {
+				parsed = base.ResolveReference(parsed)
+			}
+
+			host := strings.ToLower(parsed.Hostname())
+			if host == "" || (base != nil && host == strings.ToLower(base.Hostname())) {
+				continue
+			}
+			if _, exists := seen[host]; exists {
+				continue
+			}
+			seen[host] = struct{}{}
+			hosts = append(hosts, host)
+		}
+	}
+	return hosts
+}
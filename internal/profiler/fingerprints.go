@@ -1,13 +1,18 @@
 package profiler
 
 import (
+	"encoding/json"
 	"fmt"
+	"log/slog"
 	"strings"
+	"sync/atomic"
 	"time"
 )
 
 // Fingerprints contains a map of fingerprints for tech detection
 type Fingerprints struct {
+	// Metadata stamps the version of the data
+	Metadata DataVersion `json:"metadata"`
 	// Apps is organized as <name, fingerprint>
 	Apps map[string]*Fingerprint `json:"apps"`
 }
@@ -27,13 +32,26 @@ type Fingerprint struct {
 	DNS         map[string][]string               `json:"dns"`
 	Robots      []string                          `json:"robots"`
 	CertIssuer  []string                          `json:"certIssuer"`
+	XHR         []string                          `json:"xhr"`
+	Iframe      []string                          `json:"iframe"`
+	LinkHref    []string                          `json:"linkHref"`
+	JSONLD      map[string][]string               `json:"jsonld"`
 	Implies     []string                          `json:"implies"`
+	Excludes    []string                          `json:"excludes"`
+	Tags        []string                          `json:"tags"`
 	Description string                            `json:"description"`
 	Website     string                            `json:"website"`
 	CPE         string                            `json:"cpe"`
 	Icon        string                            `json:"icon"`
 }
 
+// impliedName returns the technology an implies entry names. Entries may
+// carry a confidence or version, as in `PHP\;confidence:50`.
+func impliedName(implied string) string {
+	name, _, _ := strings.Cut(implied, "\\;")
+	return name
+}
+
 // CompiledFingerprints contains a map of fingerprints for tech detection
 type CompiledFingerprints struct {
 	// Apps is organized as <name, fingerprint>
@@ -42,6 +60,15 @@ type CompiledFingerprints struct {
 	// domPatternsByTag provides a quick lookup map for DOM patterns by HTML tag name
 	// organized as <tag_name, map<app_name, selectors>>
 	domPatternsByTag map[string]map[string][]string
+
+	// settled are the technologies whose patterns are skipped, in the copy
+	// of the fingerprints an analysis matches with
+	settled *settledApps
+	// regexTimeouts counts the regex evaluations that timed out, in the copy
+	// of the fingerprints an analysis matches with
+	regexTimeouts *atomic.Int64
+	// literalIndexes index the patterns of the URL vectors by literal
+	literalIndexes *literalIndexes
 }
 
 // CompiledFingerprint contains the compiled fingerprints from the tech json
@@ -50,6 +77,10 @@ type CompiledFingerprint struct {
 	cats []int
 	// implies contains technologies that are implicit with this tech
 	implies []string
+	// excludes contains technologies that cannot run along with this tech
+	excludes []string
+	// tags contains the lower case tags attached to this tech by overlays
+	tags []string
 	// description contains fingerprint description
 	description string
 	// website contains a URL associated with the fingerprint
@@ -80,6 +111,14 @@ type CompiledFingerprint struct {
 	certIssuer []*ParsedPattern
 	// css contains fingerprints for CSS content
 	css []*ParsedPattern
+	// xhr contains fingerprints for hostnames of XHR/fetch requests
+	xhr []*ParsedPattern
+	// iframe contains fingerprints for iframe and embed srcs
+	iframe []*ParsedPattern
+	// linkHref contains fingerprints for stylesheet link hrefs
+	linkHref []*ParsedPattern
+	// jsonld contains fingerprints for JSON-LD structured data fields
+	jsonld map[string][]*ParsedPattern
 	// cpe contains the cpe for a fingerpritn
 	cpe string
 }
@@ -99,6 +138,8 @@ type AppInfo struct {
 	CPE         string
 	Icon        string
 	Categories  []string
+	// Tags are the tags overlays attach to the app
+	Tags []string
 }
 
 // CatsInfo contains basic information about an App.
@@ -121,13 +162,121 @@ const (
 	robotsPart
 	certIssuerPart
 	cssPart
+	xhrPart
+	jsonldPart
+	domPart
+	iframePart
+	linkHrefPart
+	pwaPart
+	noscriptPart
+	errorPagePart
+	protocolPart
+	headerOrderPart
+	portsPart
+	emailPart
+	consentPart
+	buildPart
+	platformPart
 )
 
-// loadPatterns loads the fingerprint patterns and compiles regexes
-func compileFingerprint(fingerprint *Fingerprint) *CompiledFingerprint {
+// String returns the name of the detection vector for the part,
+// as reported in the detected_by list of a Detection
+func (p part) String() string {
+	switch p {
+	case cookiesPart:
+		return "cookies"
+	case jsPart:
+		return "js"
+	case headersPart:
+		return "headers"
+	case htmlPart:
+		return "html"
+	case scriptPart:
+		return "scriptSrc"
+	case metaPart:
+		return "meta"
+	case dnsPart:
+		return "dns"
+	case robotsPart:
+		return "robots"
+	case certIssuerPart:
+		return "certIssuer"
+	case cssPart:
+		return "css"
+	case xhrPart:
+		return "xhr"
+	case jsonldPart:
+		return "jsonld"
+	case domPart:
+		return "dom"
+	case iframePart:
+		return "iframe"
+	case linkHrefPart:
+		return "linkHref"
+	case pwaPart:
+		return "pwa"
+	case noscriptPart:
+		return "noscript"
+	case errorPagePart:
+		return "errorPage"
+	case protocolPart:
+		return "protocol"
+	case headerOrderPart:
+		return "headerOrder"
+	case portsPart:
+		return "ports"
+	case emailPart:
+		return "email"
+	case consentPart:
+		return "consent"
+	case buildPart:
+		return "build"
+	case platformPart:
+		return "platform"
+	}
+	return "unknown"
+}
+
+// compileFingerprint compiles the patterns of the fingerprint of app. Patterns
+// that do not compile are dropped and logged at debug level. The cost of the
+// patterns is recorded by profiler, if not nil.
+func compileFingerprint(app string, fingerprint *Fingerprint, logger *slog.Logger, profiler *PatternProfiler) *CompiledFingerprint {
+	return compileFingerprintPatterns(app, fingerprint, logger, profiler, false)
+}
+
+// compileValidatedFingerprint is compileFingerprint for fingerprints whose
+// patterns are known to compile, deferring the compilation of each regex to
+// its first use
+func compileValidatedFingerprint(app string, fingerprint *Fingerprint, logger *slog.Logger, profiler *PatternProfiler) *CompiledFingerprint {
+	return compileFingerprintPatterns(app, fingerprint, logger, profiler, true)
+}
+
+// compileFingerprintPatterns compiles the patterns of a fingerprint, lazily if validated
+func compileFingerprintPatterns(app string, fingerprint *Fingerprint, logger *slog.Logger, profiler *PatternProfiler, validated bool) *CompiledFingerprint {
+	logger = logger.With("app", app)
+	parse := func(vector, value string) (*ParsedPattern, error) {
+		if validated {
+			pattern := parseValidatedPattern(value)
+			pattern.logger = logger
+			pattern.profile = profiler.profile(app, vector, value)
+			return pattern, nil
+		}
+		pattern, err := ParsePattern(value)
+		if err != nil {
+			logger.Debug("dropped fingerprint pattern", "vector", vector, "pattern", value, "error", err)
+			return nil, err
+		}
+		pattern.logger = logger
+		pattern.profile = profiler.profile(app, vector, value)
+		return pattern, nil
+	}
+
+
 	compiled := &CompiledFingerprint{
 		cats:        fingerprint.Cats,
 		implies:     fingerprint.Implies,
+		excludes:    fingerprint.Excludes,
+		tags:        normalizeTags(fingerprint.Tags),
 		description: fingerprint.Description,
 		website:     fingerprint.Website,
 		icon:        fingerprint.Icon,
@@ -143,6 +292,10 @@ func compileFingerprint(fingerprint *Fingerprint) *CompiledFingerprint {
 		robots:      make([]*ParsedPattern, 0, len(fingerprint.Robots)),
 		certIssuer:  make([]*ParsedPattern, 0, len(fingerprint.CertIssuer)),
 		css:         make([]*ParsedPattern, 0, len(fingerprint.CSS)),
+		xhr:         make([]*ParsedPattern, 0, len(fingerprint.XHR)),
+		iframe:      make([]*ParsedPattern, 0, len(fingerprint.Iframe)),
+		linkHref:    make([]*ParsedPattern, 0, len(fingerprint.LinkHref)),
+		jsonld:      make(map[string][]*ParsedPattern),
 		cpe:         fingerprint.CPE,
 	}
 
@@ -161,7 +314,7 @@ func compileFingerprint(fingerprint *Fingerprint) *CompiledFingerprint {
 				if !ok {
 					continue
 				}
-				pattern, err := ParsePattern(patternStr)
+				pattern, err := parse("dom", patternStr)
 				if err != nil {
 					continue
 				}
@@ -177,7 +330,7 @@ func compileFingerprint(fingerprint *Fingerprint) *CompiledFingerprint {
 					if !ok {
 						continue
 					}
-					pattern, err := ParsePattern(patternStr)
+					pattern, err := parse("dom", patternStr)
 					if err != nil {
 						continue
 					}
@@ -189,7 +342,7 @@ func compileFingerprint(fingerprint *Fingerprint) *CompiledFingerprint {
 				if !ok {
 					continue
 				}
-				pattern, err := ParsePattern(patternStr)
+				pattern, err := parse("dom", patternStr)
 				if err != nil {
 					continue
 				}
@@ -199,7 +352,7 @@ func compileFingerprint(fingerprint *Fingerprint) *CompiledFingerprint {
 	}
 
 	for header, pattern := range fingerprint.Cookies {
-		fingerprint, err := ParsePattern(pattern)
+		fingerprint, err := parse("cookies", pattern)
 		if err != nil {
 			continue
 		}
@@ -207,7 +360,7 @@ func compileFingerprint(fingerprint *Fingerprint) *CompiledFingerprint {
 	}
 
 	for k, pattern := range fingerprint.JS {
-		fingerprint, err := ParsePattern(pattern)
+		fingerprint, err := parse("js", pattern)
 		if err != nil {
 			continue
 		}
@@ -215,7 +368,7 @@ func compileFingerprint(fingerprint *Fingerprint) *CompiledFingerprint {
 	}
 
 	for header, pattern := range fingerprint.Headers {
-		fingerprint, err := ParsePattern(pattern)
+		fingerprint, err := parse("headers", pattern)
 		if err != nil {
 			continue
 		}
@@ -223,7 +376,7 @@ func compileFingerprint(fingerprint *Fingerprint) *CompiledFingerprint {
 	}
 
 	for _, pattern := range fingerprint.HTML {
-		fingerprint, err := ParsePattern(pattern)
+		fingerprint, err := parse("html", pattern)
 		if err != nil {
 			continue
 		}
@@ -231,7 +384,7 @@ func compileFingerprint(fingerprint *Fingerprint) *CompiledFingerprint {
 	}
 
 	for _, pattern := range fingerprint.Script {
-		fingerprint, err := ParsePattern(pattern)
+		fingerprint, err := parse("scripts", pattern)
 		if err != nil {
 			continue
 		}
@@ -239,7 +392,7 @@ func compileFingerprint(fingerprint *Fingerprint) *CompiledFingerprint {
 	}
 
 	for _, pattern := range fingerprint.ScriptSrc {
-		fingerprint, err := ParsePattern(pattern)
+		fingerprint, err := parse("scriptSrc", pattern)
 		if err != nil {
 			continue
 		}
@@ -250,7 +403,7 @@ func compileFingerprint(fingerprint *Fingerprint) *CompiledFingerprint {
 		var compiledList []*ParsedPattern
 
 		for _, pattern := range patterns {
-			fingerprint, err := ParsePattern(pattern)
+			fingerprint, err := parse("meta", pattern)
 			if err != nil {
 				continue
 			}
@@ -264,7 +417,7 @@ func compileFingerprint(fingerprint *Fingerprint) *CompiledFingerprint {
 		var compiledList []*ParsedPattern
 
 		for _, pattern := range patterns {
-			fingerprint, err := ParsePattern(pattern)
+			fingerprint, err := parse("dns", pattern)
 			if err != nil {
 				continue
 			}
@@ -275,7 +428,7 @@ func compileFingerprint(fingerprint *Fingerprint) *CompiledFingerprint {
 	
 	// Process robots.txt patterns
 	for _, pattern := range fingerprint.Robots {
-		fingerprint, err := ParsePattern(pattern)
+		fingerprint, err := parse("robots", pattern)
 		if err != nil {
 			continue
 		}
@@ -284,7 +437,7 @@ func compileFingerprint(fingerprint *Fingerprint) *CompiledFingerprint {
 	
 	// Process TLS certificate issuer patterns
 	for _, pattern := range fingerprint.CertIssuer {
-		fingerprint, err := ParsePattern(pattern)
+		fingerprint, err := parse("certIssuer", pattern)
 		if err != nil {
 			continue
 		}
@@ -293,41 +446,76 @@ func compileFingerprint(fingerprint *Fingerprint) *CompiledFingerprint {
 	
 	// Process CSS patterns
 	for _, pattern := range fingerprint.CSS {
-		fingerprint, err := ParsePattern(pattern)
+		fingerprint, err := parse("css", pattern)
 		if err != nil {
 			continue
 		}
 		compiled.css = append(compiled.css, fingerprint)
 	}
 
+	// Process XHR request hostname patterns
+	for _, pattern := range fingerprint.XHR {
+		fingerprint, err := parse("xhr", pattern)
+		if err != nil {
+			continue
+		}
+		compiled.xhr = append(compiled.xhr, fingerprint)
+	}
+
+	// Process iframe src patterns
+	for _, pattern := range fingerprint.Iframe {
+		fingerprint, err := parse("iframe", pattern)
+		if err != nil {
+			continue
+		}
+		compiled.iframe = append(compiled.iframe, fingerprint)
+	}
+
+	// Process stylesheet link href patterns
+	for _, pattern := range fingerprint.LinkHref {
+		fingerprint, err := parse("linkHref", pattern)
+		if err != nil {
+			continue
+		}
+		compiled.linkHref = append(compiled.linkHref, fingerprint)
+	}
+
+	// Process JSON-LD field patterns
+	for field, patterns := range fingerprint.JSONLD {
+		var compiledList []*ParsedPattern
+
+		for _, pattern := range patterns {
+			fingerprint, err := parse("jsonld", pattern)
+			if err != nil {
+				continue
+			}
+			compiledList = append(compiledList, fingerprint)
+		}
+		compiled.jsonld[field] = compiledList
+	}
+
 	return compiled
 }
 
 // matchString matches a string for the fingerprints
 func (f *CompiledFingerprints) matchString(data string, part part, timeout time.Duration) []matchPartResult {
-	// Create a simplified struct just for timeout access
-	s := struct {
-		wappalyze struct {
-			regexTimeout time.Duration
-		}
-	}{
-		wappalyze: struct {
-			regexTimeout time.Duration
-		}{
-			regexTimeout: timeout,
-		},
-	}
 	var matched bool
 	var technologies []matchPartResult
 
+	// URL vectors only evaluate the patterns whose literal the URL contains
+	filter := f.literalIndexes.get(f, part).filter(data)
+
 	for app, fingerprint := range f.Apps {
+		if f.settled.has(app) {
+			continue
+		}
 		var version string
 		confidence := 100
 
 		switch part {
 		case jsPart:
 			for _, pattern := range fingerprint.js {
-				if valid, versionString := pattern.Evaluate(data, s.wappalyze.regexTimeout); valid {
+				if valid, versionString := pattern.evaluate(data, timeout, f.regexTimeouts); valid {
 					matched = true
 					if version == "" && versionString != "" {
 						version = versionString
@@ -337,7 +525,10 @@ func (f *CompiledFingerprints) matchString(data string, part part, timeout time.
 			}
 		case scriptPart:
 			for _, pattern := range fingerprint.scriptSrc {
-				if valid, versionString := pattern.Evaluate(data, s.wappalyze.regexTimeout); valid {
+				if !filter.allows(pattern) {
+					continue
+				}
+				if valid, versionString := pattern.evaluate(data, timeout, f.regexTimeouts); valid {
 					matched = true
 					if version == "" && versionString != "" {
 						version = versionString
@@ -347,7 +538,7 @@ func (f *CompiledFingerprints) matchString(data string, part part, timeout time.
 			}
 		case htmlPart:
 			for _, pattern := range fingerprint.html {
-				if valid, versionString := pattern.Evaluate(data, s.wappalyze.regexTimeout); valid {
+				if valid, versionString := pattern.evaluate(data, timeout, f.regexTimeouts); valid {
 					matched = true
 					if version == "" && versionString != "" {
 						version = versionString
@@ -357,7 +548,7 @@ func (f *CompiledFingerprints) matchString(data string, part part, timeout time.
 			}
 		case robotsPart:
 			for _, pattern := range fingerprint.robots {
-				if valid, versionString := pattern.Evaluate(data, s.wappalyze.regexTimeout); valid {
+				if valid, versionString := pattern.evaluate(data, timeout, f.regexTimeouts); valid {
 					matched = true
 					if version == "" && versionString != "" {
 						version = versionString
@@ -367,7 +558,7 @@ func (f *CompiledFingerprints) matchString(data string, part part, timeout time.
 			}
 		case certIssuerPart:
 			for _, pattern := range fingerprint.certIssuer {
-				if valid, versionString := pattern.Evaluate(data, s.wappalyze.regexTimeout); valid {
+				if valid, versionString := pattern.evaluate(data, timeout, f.regexTimeouts); valid {
 					matched = true
 					if version == "" && versionString != "" {
 						version = versionString
@@ -378,7 +569,46 @@ func (f *CompiledFingerprints) matchString(data string, part part, timeout time.
 		case cssPart:
 			// Use dedicated CSS patterns
 			for _, pattern := range fingerprint.css {
-				if valid, versionString := pattern.Evaluate(data, s.wappalyze.regexTimeout); valid {
+				if valid, versionString := pattern.evaluate(data, timeout, f.regexTimeouts); valid {
+					matched = true
+					if version == "" && versionString != "" {
+						version = versionString
+					}
+					confidence = pattern.Confidence
+				}
+			}
+		case xhrPart:
+			for _, pattern := range fingerprint.xhr {
+				if !filter.allows(pattern) {
+					continue
+				}
+				if valid, versionString := pattern.evaluate(data, timeout, f.regexTimeouts); valid {
+					matched = true
+					if version == "" && versionString != "" {
+						version = versionString
+					}
+					confidence = pattern.Confidence
+				}
+			}
+		case iframePart:
+			for _, pattern := range fingerprint.iframe {
+				if !filter.allows(pattern) {
+					continue
+				}
+				if valid, versionString := pattern.evaluate(data, timeout, f.regexTimeouts); valid {
+					matched = true
+					if version == "" && versionString != "" {
+						version = versionString
+					}
+					confidence = pattern.Confidence
+				}
+			}
+		case linkHrefPart:
+			for _, pattern := range fingerprint.linkHref {
+				if !filter.allows(pattern) {
+					continue
+				}
+				if valid, versionString := pattern.evaluate(data, timeout, f.regexTimeouts); valid {
 					matched = true
 					if version == "" && versionString != "" {
 						version = versionString
@@ -398,12 +628,15 @@ func (f *CompiledFingerprints) matchString(data string, part part, timeout time.
 			application: app,
 			version:     version,
 			confidence:  confidence,
+			part:        part,
 		})
 		if len(fingerprint.implies) > 0 {
 			for _, implies := range fingerprint.implies {
 				technologies = append(technologies, matchPartResult{
 					application: implies,
 					confidence:  confidence,
+					part:        part,
+					implied:     true,
 				})
 			}
 		}
@@ -418,6 +651,9 @@ func (f *CompiledFingerprints) matchKeyValueString(key, value string, part part,
 	var technologies []matchPartResult
 
 	for app, fingerprint := range f.Apps {
+		if f.settled.has(app) {
+			continue
+		}
 		var version string
 		confidence := 100
 
@@ -428,7 +664,7 @@ func (f *CompiledFingerprints) matchKeyValueString(key, value string, part part,
 					continue
 				}
 
-				if valid, versionString := pattern.Evaluate(value, timeout); valid {
+				if valid, versionString := pattern.evaluate(value, timeout, f.regexTimeouts); valid {
 					matched = true
 					if version == "" && versionString != "" {
 						version = versionString
@@ -443,7 +679,7 @@ func (f *CompiledFingerprints) matchKeyValueString(key, value string, part part,
 					continue
 				}
 
-				if valid, versionString := pattern.Evaluate(value, timeout); valid {
+				if valid, versionString := pattern.evaluate(value, timeout, f.regexTimeouts); valid {
 					matched = true
 					if version == "" && versionString != "" {
 						version = versionString
@@ -459,7 +695,7 @@ func (f *CompiledFingerprints) matchKeyValueString(key, value string, part part,
 				}
 
 				for _, pattern := range patterns {
-					if valid, versionString := pattern.Evaluate(value, timeout); valid {
+					if valid, versionString := pattern.evaluate(value, timeout, f.regexTimeouts); valid {
 						matched = true
 						if version == "" && versionString != "" {
 							version = versionString
@@ -480,12 +716,15 @@ func (f *CompiledFingerprints) matchKeyValueString(key, value string, part part,
 			application: app,
 			version:     version,
 			confidence:  confidence,
+			part:        part,
 		})
 		if len(fingerprint.implies) > 0 {
 			for _, implies := range fingerprint.implies {
 				technologies = append(technologies, matchPartResult{
 					application: implies,
 					confidence:  confidence,
+					part:        part,
+					implied:     true,
 				})
 			}
 		}
@@ -496,28 +735,45 @@ func (f *CompiledFingerprints) matchKeyValueString(key, value string, part part,
 
 // matchDNSRecords matches DNS records against fingerprint patterns
 func (f *CompiledFingerprints) matchDNSRecords(dnsRecords map[string][]string, timeout time.Duration) []matchPartResult {
+	return f.matchMultiValueMap(dnsRecords, dnsPart, timeout)
+}
+
+// matchMultiValueMap matches a map of keys with multiple values each
+// (DNS records by type, JSON-LD fields by name) against fingerprint patterns
+func (f *CompiledFingerprints) matchMultiValueMap(keyValues map[string][]string, part part, timeout time.Duration) []matchPartResult {
 	var matched bool
 	var technologies []matchPartResult
 
 	for app, fingerprint := range f.Apps {
+		if f.settled.has(app) {
+			continue
+		}
 		var version string
 		confidence := 100
 
-		// Skip if fingerprint has no DNS patterns
-		if len(fingerprint.dns) == 0 {
+		var patternsByKey map[string][]*ParsedPattern
+		switch part {
+		case dnsPart:
+			patternsByKey = fingerprint.dns
+		case jsonldPart:
+			patternsByKey = fingerprint.jsonld
+		}
+
+		// Skip if fingerprint has no patterns for this part
+		if len(patternsByKey) == 0 {
 			continue
 		}
 
-		for recordType, patterns := range fingerprint.dns {
-			recordValues, ok := dnsRecords[recordType]
+		for key, patterns := range patternsByKey {
+			recordValues, ok := keyValues[key]
 			if !ok {
-				continue // No matching record type found
+				continue // No matching key found
 			}
 
 			// Try to match any record value against any pattern for this record type
 			for _, recordValue := range recordValues {
 				for _, pattern := range patterns {
-					if valid, versionString := pattern.Evaluate(recordValue, timeout); valid {
+					if valid, versionString := pattern.evaluate(recordValue, timeout, f.regexTimeouts); valid {
 						matched = true
 						if version == "" && versionString != "" {
 							version = versionString
@@ -543,12 +799,15 @@ func (f *CompiledFingerprints) matchDNSRecords(dnsRecords map[string][]string, t
 			application: app,
 			version:     version,
 			confidence:  confidence,
+			part:        part,
 		})
 		if len(fingerprint.implies) > 0 {
 			for _, implies := range fingerprint.implies {
 				technologies = append(technologies, matchPartResult{
 					application: implies,
 					confidence:  confidence,
+					part:        part,
+					implied:     true,
 				})
 			}
 		}
@@ -563,6 +822,9 @@ func (f *CompiledFingerprints) matchMapString(keyValue map[string]string, part p
 	var technologies []matchPartResult
 
 	for app, fingerprint := range f.Apps {
+		if f.settled.has(app) {
+			continue
+		}
 		var version string
 		confidence := 100
 
@@ -576,7 +838,7 @@ func (f *CompiledFingerprints) matchMapString(keyValue map[string]string, part p
 				if pattern == nil {
 					matched = true
 				}
-				if valid, versionString := pattern.Evaluate(value, timeout); valid {
+				if valid, versionString := pattern.evaluate(value, timeout, f.regexTimeouts); valid {
 					matched = true
 					if version == "" && versionString != "" {
 						version = versionString
@@ -592,7 +854,24 @@ func (f *CompiledFingerprints) matchMapString(keyValue map[string]string, part p
 					continue
 				}
 
-				if valid, versionString := pattern.Evaluate(value, timeout); valid {
+				if valid, versionString := pattern.evaluate(value, timeout, f.regexTimeouts); valid {
+					matched = true
+					if version == "" && versionString != "" {
+						version = versionString
+					}
+					confidence = pattern.Confidence
+					break
+				}
+			}
+		case jsPart:
+			// JS patterns are keyed by the path of the global
+			for data, pattern := range fingerprint.js {
+				value, ok := keyValue[data]
+				if !ok {
+					continue
+				}
+
+				if valid, versionString := pattern.evaluate(value, timeout, f.regexTimeouts); valid {
 					matched = true
 					if version == "" && versionString != "" {
 						version = versionString
@@ -609,7 +888,7 @@ func (f *CompiledFingerprints) matchMapString(keyValue map[string]string, part p
 				}
 
 				for _, pattern := range patterns {
-					if valid, versionString := pattern.Evaluate(value, timeout); valid {
+					if valid, versionString := pattern.evaluate(value, timeout, f.regexTimeouts); valid {
 						matched = true
 						if version == "" && versionString != "" {
 							version = versionString
@@ -630,12 +909,15 @@ func (f *CompiledFingerprints) matchMapString(keyValue map[string]string, part p
 			application: app,
 			version:     version,
 			confidence:  confidence,
+			part:        part,
 		})
 		if len(fingerprint.implies) > 0 {
 			for _, implies := range fingerprint.implies {
 				technologies = append(technologies, matchPartResult{
 					application: implies,
 					confidence:  confidence,
+					part:        part,
+					implied:     true,
 				})
 			}
 		}
@@ -651,9 +933,18 @@ func FormatAppVersion(app, version string) string {
 	return fmt.Sprintf("%s:%s", app, version)
 }
 
-// GetFingerprints returns the fingerprint string from wappalyzer
+// GetFingerprints returns the embedded fingerprints as JSON, or an empty
+// string in builds with the kitsune_nodata tag
 func GetFingerprints() string {
-	return fingerprints
+	embedded, err := embeddedFingerprints()
+	if err != nil {
+		return ""
+	}
+	data, err := json.Marshal(embedded)
+	if err != nil {
+		return ""
+	}
+	return string(data)
 }
 
 // registerDOMPattern registers a DOM pattern in the tag-based lookup map
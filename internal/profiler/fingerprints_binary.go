@@ -0,0 +1,394 @@
+package profiler
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+)
+
+// The binary fingerprint format holds the normalized fingerprints with the
+// patterns that do not compile already dropped, so they can be loaded without
+// decoding JSON or validating every regex up front. Maps are written in key
+// order, so the same data always encodes to the same bytes.
+//
+// Integers are unsigned varints and strings are length prefixed. The data
+// starts with binaryMagic and binaryVersion, then the metadata and the apps.
+const (
+	binaryMagic   = "KSFP"
+	binaryVersion = 3
+)
+
+// domValue kinds of the values of a DOM rule
+const (
+	domString byte = iota
+	domMap
+)
+
+// EncodeFingerprints converts fingerprints in the JSON format of
+// update-fingerprints to the binary format the library embeds. Patterns that
+// do not compile are dropped; their number is returned.
+func EncodeFingerprints(data []byte) ([]byte, int, error) {
+	var fingerprints Fingerprints
+	if err := json.Unmarshal(data, &fingerprints); err != nil {
+		return nil, 0, err
+	}
+
+	encoder := &binaryEncoder{}
+	encoder.buf = append(encoder.buf, binaryMagic...)
+	encoder.uint(binaryVersion)
+
+	metadata := fingerprints.Metadata
+	encoder.string(metadata.SourceVersion)
+	fetchedAt, err := metadata.FetchedAt.MarshalBinary()
+	if err != nil {
+		return nil, 0, err
+	}
+	encoder.string(string(fetchedAt))
+	encoder.string(metadata.ContentHash)
+
+	apps := sortedKeys(fingerprints.Apps)
+	encoder.uint(len(apps))
+	for _, app := range apps {
+		encoder.string(app)
+		encoder.fingerprint(fingerprints.Apps[app])
+	}
+	return encoder.buf, encoder.dropped, nil
+}
+
+// decodeFingerprints reads fingerprints in the binary format. Their patterns
+// were validated by EncodeFingerprints.
+func decodeFingerprints(data []byte) (*Fingerprints, error) {
+	if !bytes.HasPrefix(data, []byte(binaryMagic)) {
+		return nil, errors.New("not binary fingerprint data")
+	}
+	decoder := &binaryDecoder{data: data[len(binaryMagic):]}
+	if version := decoder.uint(); version != binaryVersion {
+		return nil, fmt.Errorf("unsupported binary fingerprint version %d", version)
+	}
+
+	fingerprints := &Fingerprints{}
+	fingerprints.Metadata.SourceVersion = decoder.string()
+	if err := fingerprints.Metadata.FetchedAt.UnmarshalBinary([]byte(decoder.string())); err != nil && decoder.err == nil {
+		decoder.err = err
+	}
+	fingerprints.Metadata.ContentHash = decoder.string()
+
+	count := decoder.uint()
+	fingerprints.Apps = make(map[string]*Fingerprint, min(count, len(decoder.data)))
+	for i := 0; i < count && decoder.err == nil; i++ {
+		app := decoder.string()
+		fingerprints.Apps[app] = decoder.fingerprint()
+	}
+	if decoder.err != nil {
+		return nil, fmt.Errorf("corrupt binary fingerprint data: %w", decoder.err)
+	}
+	return fingerprints, nil
+}
+
+// binaryEncoder appends the binary format to buf
+type binaryEncoder struct {
+	buf     []byte
+	dropped int // Patterns dropped because they do not compile
+}
+
+func (e *binaryEncoder) uint(value int) {
+	e.buf = binary.AppendUvarint(e.buf, uint64(value))
+}
+
+func (e *binaryEncoder) string(value string) {
+	e.uint(len(value))
+	e.buf = append(e.buf, value...)
+}
+
+func (e *binaryEncoder) strings(values []string) {
+	e.uint(len(values))
+	for _, value := range values {
+		e.string(value)
+	}
+}
+
+// valid reports whether pattern compiles, counting it as dropped if not
+func (e *binaryEncoder) valid(pattern string) bool {
+	if _, err := ParsePattern(pattern); err != nil {
+		e.dropped++
+		return false
+	}
+	return true
+}
+
+// patterns writes the patterns that compile
+func (e *binaryEncoder) patterns(patterns []string) {
+	e.strings(slices.DeleteFunc(slices.Clone(patterns), func(pattern string) bool {
+		return !e.valid(pattern)
+	}))
+}
+
+// patternMap writes the entries of values whose pattern compiles
+func (e *binaryEncoder) patternMap(values map[string]string) {
+	keys := sortedKeys(values)
+	keys = slices.DeleteFunc(keys, func(key string) bool { return !e.valid(values[key]) })
+	e.uint(len(keys))
+	for _, key := range keys {
+		e.string(key)
+		e.string(values[key])
+	}
+}
+
+// patternLists writes lists of patterns, keeping only those that compile
+func (e *binaryEncoder) patternLists(values map[string][]string) {
+	keys := sortedKeys(values)
+	e.uint(len(keys))
+	for _, key := range keys {
+		e.string(key)
+		e.patterns(values[key])
+	}
+}
+
+// dom writes DOM rules. "exists" values and "properties" maps are not patterns.
+func (e *binaryEncoder) dom(rules map[string]map[string]interface{}) {
+	selectors := sortedKeys(rules)
+	e.uint(len(selectors))
+	for _, selector := range selectors {
+		e.string(selector)
+
+		rule := rules[selector]
+		keys := slices.DeleteFunc(sortedKeys(rule), func(key string) bool {
+			switch value := rule[key].(type) {
+			case string:
+				return key != "exists" && !e.valid(value)
+			case map[string]interface{}:
+				return false
+			default:
+				return true
+			}
+		})
+		e.uint(len(keys))
+		for _, key := range keys {
+			e.string(key)
+			switch value := rule[key].(type) {
+			case string:
+				e.buf = append(e.buf, domString)
+				e.string(value)
+			case map[string]interface{}:
+				e.buf = append(e.buf, domMap)
+				names := slices.DeleteFunc(sortedKeys(value), func(name string) bool {
+					pattern, ok := value[name].(string)
+					return !ok || (key == "attributes" && !e.valid(pattern))
+				})
+				e.uint(len(names))
+				for _, name := range names {
+					e.string(name)
+					e.string(value[name].(string))
+				}
+			}
+		}
+	}
+}
+
+func (e *binaryEncoder) fingerprint(fingerprint *Fingerprint) {
+	e.uint(len(fingerprint.Cats))
+	for _, cat := range fingerprint.Cats {
+		e.uint(cat)
+	}
+	e.patterns(fingerprint.CSS)
+	e.patternMap(fingerprint.Cookies)
+	e.dom(fingerprint.Dom)
+	e.patternMap(fingerprint.JS)
+	e.patternMap(fingerprint.Headers)
+	e.patterns(fingerprint.HTML)
+	e.patterns(fingerprint.Script)
+	e.patterns(fingerprint.ScriptSrc)
+	e.patternLists(fingerprint.Meta)
+	e.patternLists(fingerprint.DNS)
+	e.patterns(fingerprint.Robots)
+	e.patterns(fingerprint.CertIssuer)
+	e.patterns(fingerprint.XHR)
+	e.patterns(fingerprint.Iframe)
+	e.patterns(fingerprint.LinkHref)
+	e.patternLists(fingerprint.JSONLD)
+	e.strings(fingerprint.Implies)
+	e.strings(fingerprint.Excludes)
+	e.strings(fingerprint.Tags)
+	e.string(fingerprint.Description)
+	e.string(fingerprint.Website)
+	e.string(fingerprint.CPE)
+	e.string(fingerprint.Icon)
+}
+
+// binaryDecoder reads the binary format from data. The first error stops
+// decoding and is kept in err.
+type binaryDecoder struct {
+	data []byte
+	err  error
+}
+
+func (d *binaryDecoder) uint() int {
+	if d.err != nil {
+		return 0
+	}
+	value, n := binary.Uvarint(d.data)
+	if n <= 0 || value > math.MaxInt32 {
+		d.err = errors.New("invalid integer")
+		return 0
+	}
+	d.data = d.data[n:]
+	return int(value)
+}
+
+func (d *binaryDecoder) byte() byte {
+	if d.err != nil {
+		return 0
+	}
+	if len(d.data) == 0 {
+		d.err = errors.New("unexpected end of data")
+		return 0
+	}
+	value := d.data[0]
+	d.data = d.data[1:]
+	return value
+}
+
+func (d *binaryDecoder) string() string {
+	length := d.uint()
+	if d.err != nil {
+		return ""
+	}
+	if length > len(d.data) {
+		d.err = errors.New("unexpected end of data")
+		return ""
+	}
+	value := string(d.data[:length])
+	d.data = d.data[length:]
+	return value
+}
+
+// count reads the length of a collection, returning 0 for empty ones so they
+// decode to nil like omitted JSON fields
+func (d *binaryDecoder) count() int {
+	count := d.uint()
+	if count > len(d.data) {
+		d.err = errors.New("unexpected end of data")
+		return 0
+	}
+	return count
+}
+
+func (d *binaryDecoder) strings() []string {
+	count := d.count()
+	if count == 0 {
+		return nil
+	}
+	values := make([]string, count)
+	for i := range values {
+		values[i] = d.string()
+	}
+	return values
+}
+
+func (d *binaryDecoder) stringMap() map[string]string {
+	count := d.count()
+	if count == 0 {
+		return nil
+	}
+	values := make(map[string]string, count)
+	for i := 0; i < count; i++ {
+		key := d.string()
+		values[key] = d.string()
+	}
+	return values
+}
+
+func (d *binaryDecoder) stringLists() map[string][]string {
+	count := d.count()
+	if count == 0 {
+		return nil
+	}
+	values := make(map[string][]string, count)
+	for i := 0; i < count; i++ {
+		key := d.string()
+		values[key] = d.strings()
+	}
+	return values
+}
+
+func (d *binaryDecoder) dom() map[string]map[string]interface{} {
+	count := d.count()
+	if count == 0 {
+		return nil
+	}
+	rules := make(map[string]map[string]interface{}, count)
+	for i := 0; i < count; i++ {
+		selector := d.string()
+		keys := d.count()
+		rule := make(map[string]interface{}, keys)
+		for j := 0; j < keys; j++ {
+			key := d.string()
+			switch kind := d.byte(); kind {
+			case domString:
+				rule[key] = d.string()
+			case domMap:
+				names := d.count()
+				value := make(map[string]interface{}, names)
+				for k := 0; k < names; k++ {
+					name := d.string()
+					value[name] = d.string()
+				}
+				rule[key] = value
+			default:
+				if d.err == nil {
+					d.err = fmt.Errorf("invalid DOM value kind %d", kind)
+				}
+			}
+		}
+		rules[selector] = rule
+	}
+	return rules
+}
+
+func (d *binaryDecoder) fingerprint() *Fingerprint {
+	fingerprint := &Fingerprint{}
+	if count := d.count(); count > 0 {
+		fingerprint.Cats = make([]int, count)
+		for i := range fingerprint.Cats {
+			fingerprint.Cats[i] = d.uint()
+		}
+	}
+	fingerprint.CSS = d.strings()
+	fingerprint.Cookies = d.stringMap()
+	fingerprint.Dom = d.dom()
+	fingerprint.JS = d.stringMap()
+	fingerprint.Headers = d.stringMap()
+	fingerprint.HTML = d.strings()
+	fingerprint.Script = d.strings()
+	fingerprint.ScriptSrc = d.strings()
+	fingerprint.Meta = d.stringLists()
+	fingerprint.DNS = d.stringLists()
+	fingerprint.Robots = d.strings()
+	fingerprint.CertIssuer = d.strings()
+	fingerprint.XHR = d.strings()
+	fingerprint.Iframe = d.strings()
+	fingerprint.LinkHref = d.strings()
+	fingerprint.JSONLD = d.stringLists()
+	fingerprint.Implies = d.strings()
+	fingerprint.Excludes = d.strings()
+	fingerprint.Tags = d.strings()
+	fingerprint.Description = d.string()
+	fingerprint.Website = d.string()
+	fingerprint.CPE = d.string()
+	fingerprint.Icon = d.string()
+	return fingerprint
+}
+
+// sortedKeys returns the keys of m in order
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+	return keys
+}
@@ -0,0 +1,73 @@
+package profiler
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/kavinsood/kitsune/assets"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEmbeddedBinaryUpToDate(t *testing.T) {
+	data, err := os.ReadFile("../../assets/fingerprints_data.json")
+	require.NoError(t, err, "could not read fingerprints")
+	encoded, _, err := EncodeFingerprints(data)
+	require.NoError(t, err, "could not encode fingerprints")
+
+	embedded, err := assets.Fingerprints()
+	require.NoError(t, err, "could not decompress embedded fingerprints")
+	require.Equal(t, encoded, embedded, "fingerprints_data.bin.gz is stale, run go generate ./assets")
+}
+
+func TestBinaryFingerprints(t *testing.T) {
+	data := `{
+		"metadata": {"source_version": "6.10.0", "fetched_at": "2025-07-29T10:00:00Z", "content_hash": "sha256:abc"},
+		"apps": {
+			"WordPress": {
+				"cats": [1, 11],
+				"html": ["<link rel=[\"']stylesheet[\"'] [^>]+/wp-(?:content|includes)/", "(unclosed"],
+				"headers": {"x-pingback": "/xmlrpc\\.php$", "x-broken": "(unclosed"},
+				"meta": {"generator": ["^wordpress(?: ([\\d.]+))?\\;version:\\1"]},
+				"dom": {"link[href*='/wp-content/']": {"exists": ""}, "body": {"attributes": {"class": "wp-(?:admin|site)", "id": "(unclosed"}}},
+				"implies": ["PHP", "MySQL"],
+				"website": "https://wordpress.org"
+			}
+		}
+	}`
+
+	encoded, dropped, err := EncodeFingerprints([]byte(data))
+	require.NoError(t, err, "could not encode fingerprints")
+	require.Equal(t, 3, dropped, "invalid patterns should be dropped")
+
+	decoded, err := decodeFingerprints(encoded)
+	require.NoError(t, err, "could not decode fingerprints")
+	require.Equal(t, DataVersion{
+		SourceVersion: "6.10.0",
+		FetchedAt:     time.Date(2025, 7, 29, 10, 0, 0, 0, time.UTC),
+		ContentHash:   "sha256:abc",
+	}, decoded.Metadata, "wrong metadata")
+	require.Equal(t, &Fingerprint{
+		Cats:    []int{1, 11},
+		HTML:    []string{`<link rel=["']stylesheet["'] [^>]+/wp-(?:content|includes)/`},
+		Headers: map[string]string{"x-pingback": `/xmlrpc\.php$`},
+		Meta:    map[string][]string{"generator": {`^wordpress(?: ([\d.]+))?\;version:\1`}},
+		Dom: map[string]map[string]interface{}{
+			"link[href*='/wp-content/']": {"exists": ""},
+			"body":                       {"attributes": map[string]interface{}{"class": "wp-(?:admin|site)"}},
+		},
+		Implies: []string{"PHP", "MySQL"},
+		Website: "https://wordpress.org",
+	}, decoded.Apps["WordPress"], "wrong fingerprint")
+
+	// Validated patterns compile on first use
+	compiled := compileValidatedFingerprint("WordPress", decoded.Apps["WordPress"], discardLogger, nil)
+	matched, version := compiled.meta["generator"][0].Evaluate("WordPress 6.4", time.Second)
+	require.True(t, matched, "lazily compiled pattern should match")
+	require.Equal(t, "6.4", version, "wrong version")
+
+	for _, corrupt := range [][]byte{nil, []byte("{}"), encoded[:len(encoded)/2]} {
+		_, err := decodeFingerprints(corrupt)
+		require.Error(t, err, "corrupt data should be rejected")
+	}
+}
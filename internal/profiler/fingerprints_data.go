@@ -3,7 +3,8 @@ package profiler
 import (
 	_ "embed"
 	"encoding/json"
-	"strconv"
+	"errors"
+	"fmt"
 	"sync"
 	
 	"github.com/kavinsood/kitsune/assets"
@@ -11,7 +12,6 @@ import (
 
 var (
 	// Data now comes from assets package
-	fingerprints string
 	cateogriesData string
 
 	syncOnce          sync.Once
@@ -20,31 +20,37 @@ var (
 
 func init() {
 	// Load data from assets package
-	fingerprints = assets.FingerprintsJSON
 	cateogriesData = assets.CategoriesJSON
 	
 	// Lazy initialize categories mapping
 	syncOnce.Do(func() {
-		var data map[int]map[string]string
+		// Priorities are numbers and groups are arrays, so decode into the typed item
+		var data map[int]categoryItem
 		err := json.Unmarshal([]byte(cateogriesData), &data)
 		if err != nil {
 			// handle error silently
 			return
 		}
 
-		categoriesMapping = make(map[int]categoryItem)
-		for categoryID, category := range data {
-			priorityInt, _ := strconv.Atoi(category["priority"])
-			categoriesMapping[categoryID] = categoryItem{
-				Name:     category["name"],
-				Priority: priorityInt,
-			}
-		}
+		categoriesMapping = data
 	})
 }
 
+// embeddedFingerprints decodes the fingerprints embedded in the assets
+// package, decompressing them on first use
+func embeddedFingerprints() (*Fingerprints, error) {
+	data, err := assets.Fingerprints()
+	if errors.Is(err, assets.ErrNoData) {
+		return nil, fmt.Errorf("%w, load them with NewFromFile instead", err)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("could not decompress embedded fingerprints: %w", err)
+	}
+	return decodeFingerprints(data)
+}
+
 // Categories related types moved to fingerprints.go
 type categoryItem struct {
-	Name     string
-	Priority int
+	Name     string `json:"name"`
+	Priority int    `json:"priority"`
 }
\ No newline at end of file
@@ -1,6 +1,16 @@
 package profiler
 
 import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
@@ -45,3 +55,346 @@ func TestVersionRegex(t *testing.T) {
 		require.True(t, matched, "should match anything")
 	})
 }
+
+func TestJSONLDDetect(t *testing.T) {
+	wappalyzer, err := New()
+	require.NoError(t, err, "could not create wappalyzer")
+
+	body := []byte(`<html><head><script type="application/ld+json">
+{"@context":"https://schema.org","@graph":[{"@type":"WebSite","generator":"WordPress 6.1"},{"@type":["Store","Organization"],"name":"Acme"}]}
+</script></head><body></body></html>`)
+
+	t.Run("generator", func(t *testing.T) {
+		matches := wappalyzer.Fingerprint(map[string][]string{}, body)
+		require.Contains(t, matches, "WordPress:6.1", "could not match generator in JSON-LD")
+	})
+
+	t.Run("type", func(t *testing.T) {
+		wappalyzer.fingerprints.Apps["Acme Storefront"] = compileFingerprint("Acme Storefront", &Fingerprint{
+			JSONLD: map[string][]string{"@type": {"^Store$"}},
+		}, discardLogger, nil)
+		defer delete(wappalyzer.fingerprints.Apps, "Acme Storefront")
+
+		matches := wappalyzer.Fingerprint(map[string][]string{}, body)
+		require.Contains(t, matches, "Acme Storefront", "could not match @type in JSON-LD")
+	})
+}
+
+func TestIframeDetect(t *testing.T) {
+	wappalyzer, err := New()
+	require.NoError(t, err, "could not create wappalyzer")
+
+	body := []byte(`<html><body><iframe src="https://www.youtube.com/embed/dQw4w9WgXcQ"></iframe></body></html>`)
+	result := wappalyzer.AnalyzeWithPipeline(&http.Response{Header: http.Header{}}, body)
+
+	detection, ok := result.GetDetections()["YouTube"]
+	require.True(t, ok, "could not detect YouTube from iframe")
+	require.Contains(t, detection.DetectedBy, "iframe", "iframe vector not reported")
+}
+
+func TestLinkHrefDetect(t *testing.T) {
+	wappalyzer, err := New()
+	require.NoError(t, err, "could not create wappalyzer")
+
+	wappalyzer.fingerprints.Apps["Acme Theme"] = compileFingerprint("Acme Theme", &Fingerprint{
+		LinkHref: []string{"/themes/acme/style(?:\\.min)?\\.css\\?ver=([\\d.]+)\\;version:\\1"},
+	}, discardLogger, nil)
+	defer delete(wappalyzer.fingerprints.Apps, "Acme Theme")
+
+	body := []byte(`<html><head><link rel="stylesheet" href="/themes/acme/style.min.css?ver=2.4.1"></head><body></body></html>`)
+	result := wappalyzer.AnalyzeWithPipeline(&http.Response{Header: http.Header{}}, body)
+
+	detection, ok := result.GetDetections()["Acme Theme"]
+	require.True(t, ok, "could not detect theme from stylesheet href")
+	require.Equal(t, "2.4.1", detection.Version, "could not extract version from stylesheet href")
+	require.Equal(t, []string{"linkHref"}, detection.DetectedBy, "linkHref vector not reported")
+}
+
+func TestPWADetect(t *testing.T) {
+	userAgent := ScannerUserAgent("https://example.com/scans")
+	var mu sync.Mutex
+	var userAgents []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		userAgents = append(userAgents, r.UserAgent())
+		mu.Unlock()
+		switch r.URL.Path {
+		case "/manifest.json":
+			w.Header().Set("Content-Type", "application/manifest+json")
+			w.Write([]byte(`{"name":"Acme","gcm_sender_id":"482941778795"}`))
+		case "/sw.js":
+			w.Header().Set("Content-Type", "application/javascript")
+			w.Write([]byte(`importScripts("https://storage.googleapis.com/workbox-cdn/releases/6.5.4/workbox-sw.js");`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	wappalyzer, err := New(WithUserAgent(userAgent))
+	require.NoError(t, err, "could not create wappalyzer")
+
+	body := []byte(`<html><head><link rel="manifest" href="/manifest.json"></head><body>
+<script>if ('serviceWorker' in navigator) { navigator.serviceWorker.register('/sw.js'); }</script>
+</body></html>`)
+	resp := &http.Response{Header: http.Header{}, Request: httptest.NewRequest("GET", server.URL, nil)}
+	result := wappalyzer.AnalyzeWithPipeline(resp, body)
+
+	detections := result.GetDetections()
+	for _, app := range []string{"PWA", "Workbox", "OneSignal"} {
+		detection, ok := detections[app]
+		require.True(t, ok, "could not detect %s", app)
+		require.Contains(t, detection.DetectedBy, "pwa", "pwa vector not reported for %s", app)
+	}
+	require.Contains(t, wappalyzer.fingerprints.Apps, "Workbox", "Workbox not in the fingerprint data")
+
+	// The manifest and the service worker are fetched as the configured user agent
+	mu.Lock()
+	defer mu.Unlock()
+	require.NotEmpty(t, userAgents, "nothing fetched")
+	for _, got := range userAgents {
+		require.Equal(t, userAgent.Header, got, "wrong user agent")
+	}
+}
+
+func TestNoscriptDetect(t *testing.T) {
+	wappalyzer, err := New()
+	require.NoError(t, err, "could not create wappalyzer")
+
+	body := []byte(`<html><head></head><body>
+<noscript><iframe src="https://www.googletagmanager.com/ns.html?id=GTM-ABC123" height="0" width="0"></iframe></noscript>
+</body></html>`)
+	result := wappalyzer.AnalyzeWithPipeline(&http.Response{Header: http.Header{}}, body)
+
+	detection, ok := result.GetDetections()["Google Tag Manager"]
+	require.True(t, ok, "could not detect Google Tag Manager from noscript")
+	require.Contains(t, detection.DetectedBy, "noscript", "noscript vector not reported")
+}
+
+func TestXHRDetect(t *testing.T) {
+	wappalyzer, err := New()
+	require.NoError(t, err, "could not create wappalyzer")
+
+	body := []byte(`<html><head></head><body>
+<script>fetch("https://api.stripe.com/v1/tokens", {method: "POST"});</script>
+</body></html>`)
+	resp := &http.Response{Header: http.Header{}, Request: httptest.NewRequest("GET", "https://www.example.com/", nil)}
+	result := wappalyzer.AnalyzeWithPipeline(resp, body)
+
+	detection, ok := result.GetDetections()["Stripe"]
+	require.True(t, ok, "could not detect Stripe from a fetch request")
+	require.Equal(t, []string{"xhr"}, detection.DetectedBy, "xhr vector not reported")
+}
+
+func TestErrorPageProbing(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/" {
+			w.Write([]byte("<html><body>Welcome</body></html>"))
+			return
+		}
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(`<html><head><title>Page not found at /missing</title></head><body>
+<p>You're seeing this error because you have <code>DEBUG = True</code> in your Django settings file.</p></body></html>`))
+	}))
+	defer server.Close()
+
+	body := []byte("<html><body>Welcome</body></html>")
+	resp := &http.Response{Header: http.Header{}, Request: httptest.NewRequest("GET", server.URL, nil)}
+
+	t.Run("disabled", func(t *testing.T) {
+		wappalyzer, err := New()
+		require.NoError(t, err, "could not create wappalyzer")
+
+		result := wappalyzer.AnalyzeWithPipeline(resp, body)
+		require.NotContains(t, result.GetDetections(), "Django", "error pages probed without opt-in")
+	})
+
+	t.Run("enabled", func(t *testing.T) {
+		wappalyzer, err := New(WithErrorPageProbing(true))
+		require.NoError(t, err, "could not create wappalyzer")
+
+		result := wappalyzer.AnalyzeWithPipeline(resp, body)
+		detection, ok := result.GetDetections()["Django"]
+		require.True(t, ok, "could not detect Django from error page")
+		require.Contains(t, detection.DetectedBy, "errorPage", "errorPage vector not reported")
+		require.Contains(t, result.GetDetections(), "Python", "implied technology missing")
+	})
+}
+
+func TestErrorPageMalformedRequest(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err, "could not listen")
+	defer listener.Close()
+
+	// net/http refuses the malformed request line before any handler, so
+	// the server reads the request lines itself
+	requestLines := make(chan string, 4)
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func(conn net.Conn) {
+				defer conn.Close()
+				line, err := bufio.NewReader(conn).ReadString('\n')
+				if err != nil {
+					return
+				}
+				requestLines <- strings.TrimSpace(line)
+				body := "<html><body>Welcome</body></html>"
+				if strings.HasPrefix(line, "GET /% ") {
+					body = "<html><body><h1>HTTP Status 400 - Bad Request</h1><p><b>Type</b> Status Report</p><h3>Apache Tomcat/9.0.83</h3></body></html>"
+				}
+				fmt.Fprintf(conn, "HTTP/1.1 400 Bad Request\r\nContent-Type: text/html\r\nContent-Length: %d\r\nConnection: close\r\n\r\n%s", len(body), body)
+			}(conn)
+		}
+	}()
+
+	wappalyzer, err := New(WithErrorPageProbing(true), WithDisabledVectors(VectorDNS, VectorRobots))
+	require.NoError(t, err, "could not create wappalyzer")
+
+	targetURL := "http://" + listener.Addr().String() + "/"
+	resp := &http.Response{Header: http.Header{}, Request: httptest.NewRequest("GET", targetURL, nil)}
+	result := wappalyzer.AnalyzeWithPipeline(resp, []byte("<html><body>Welcome</body></html>"))
+
+	var lines []string
+	for len(requestLines) > 0 {
+		lines = append(lines, <-requestLines)
+	}
+	require.Contains(t, lines, "GET /% HTTP/1.1", "malformed request not sent")
+	detection, ok := result.GetDetections()["Apache Tomcat"]
+	require.True(t, ok, "could not detect Apache Tomcat from the malformed request")
+	require.Equal(t, "9.0.83", detection.Version, "wrong version")
+}
+
+func TestProtocolDetect(t *testing.T) {
+	wappalyzer, err := New()
+	require.NoError(t, err, "could not create wappalyzer")
+
+	resp := &http.Response{
+		Proto: "HTTP/2.0",
+		Header: http.Header{
+			"Alt-Svc":                   []string{`h3=":443"; ma=2592000, h3-29=":443"; ma=2592000, h3-Q050=":443"; ma=2592000, h3-Q046=":443"; ma=2592000, h3-Q043=":443"; ma=2592000, quic=":443"; ma=2592000; v="43,46"`},
+			"Content-Encoding":          []string{"br"},
+			"Strict-Transport-Security": []string{"max-age=31536000"},
+		},
+	}
+	result := wappalyzer.AnalyzeWithPipeline(resp, []byte("<html></html>"))
+
+	detection, ok := result.GetDetections()["LiteSpeed"]
+	require.True(t, ok, "could not detect LiteSpeed from protocol behaviour")
+	require.Contains(t, detection.DetectedBy, "protocol", "protocol vector not reported")
+
+	protocol := result.GetProtocol()
+	require.Equal(t, "HTTP/2.0", protocol.Version, "could not get protocol version")
+	require.True(t, protocol.HTTP3, "could not detect http3 advertisement")
+	require.Equal(t, []string{"h3", "h3-29", "h3-Q050", "h3-Q046", "h3-Q043", "quic"}, protocol.AltSvc, "could not get alt-svc protocols")
+	require.Equal(t, "br", protocol.Compression, "could not get compression")
+	require.Equal(t, []string{"alt-svc", "strict-transport-security"}, protocol.ProtocolHeaders, "could not get protocol headers")
+
+	// A one day h3 advertisement is the default of many servers and CDNs, not
+	// only Cloudflare's
+	resp = &http.Response{Header: http.Header{"Alt-Svc": []string{`h3=":443"; ma=86400`}}}
+	result = wappalyzer.AnalyzeWithPipeline(resp, []byte("<html></html>"))
+	require.NotContains(t, result.GetDetections(), "Cloudflare", "Cloudflare detected from a generic alt-svc")
+	require.True(t, result.GetProtocol().HTTP3, "could not detect http3 advertisement")
+}
+
+func TestHeaderOrderDetect(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err, "could not listen")
+	defer listener.Close()
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func(conn net.Conn) {
+				defer conn.Close()
+				http.ReadRequest(bufio.NewReader(conn))
+				conn.Write([]byte("HTTP/1.1 200 OK\r\nCache-Control: private\r\nContent-Type: text/html\r\nServer: Example\r\nX-Powered-By: Example\r\nDate: Mon, 01 Jan 2024 00:00:00 GMT\r\nContent-Length: 0\r\n\r\n"))
+			}(conn)
+		}
+	}()
+
+	wappalyzer, err := New(WithHeaderOrderProbing(true))
+	require.NoError(t, err, "could not create wappalyzer")
+
+	targetURL := "http://" + listener.Addr().String() + "/"
+	resp := &http.Response{Header: http.Header{}, Request: httptest.NewRequest("GET", targetURL, nil)}
+	result := wappalyzer.AnalyzeWithPipeline(resp, []byte("<html></html>"))
+
+	detection, ok := result.GetDetections()["IIS"]
+	require.True(t, ok, "could not detect IIS from header order")
+	require.Contains(t, detection.DetectedBy, "headerOrder", "headerOrder vector not reported")
+	require.Equal(t, []string{"Cache-Control", "Content-Type", "Server", "X-Powered-By", "Date", "Content-Length"}, result.GetProtocol().HeaderOrder, "could not capture header order")
+
+	// The probe dials through the address check like the client
+	wappalyzer, err = New(WithHeaderOrderProbing(true), WithAddressCheck(func(ctx context.Context, addr netip.Addr) error {
+		return errors.New("address is in denied range")
+	}))
+	require.NoError(t, err, "could not create wappalyzer")
+	result = wappalyzer.AnalyzeWithPipeline(resp, []byte("<html></html>"))
+	require.Empty(t, result.GetProtocol().HeaderOrder, "refused address should not be probed")
+}
+
+func TestPortProbing(t *testing.T) {
+	// The target itself, whose port is not probed again
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Powered-By", "Express")
+	}))
+	defer target.Close()
+
+	// An admin console over HTTPS, and an application server over plain HTTP
+	console := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Server", "Jetty(9.4.51)")
+	}))
+	defer console.Close()
+	appServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Powered-By", "PHP/8.2.0")
+		http.Redirect(w, r, "/manager/html", http.StatusFound)
+	}))
+	defer appServer.Close()
+
+	// A port nothing listens on
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err, "could not listen")
+	closed := listener.Addr().(*net.TCPAddr).Port
+	listener.Close()
+
+	portOf := func(server *httptest.Server) int {
+		return server.Listener.Addr().(*net.TCPAddr).Port
+	}
+	resp := &http.Response{Header: http.Header{}, Request: httptest.NewRequest("GET", target.URL, nil)}
+
+	t.Run("disabled", func(t *testing.T) {
+		wappalyzer, err := New(WithDisabledVectors(VectorDNS, VectorRobots, VectorAssets))
+		require.NoError(t, err, "could not create wappalyzer")
+
+		result := wappalyzer.AnalyzeWithPipeline(resp, nil)
+		require.Empty(t, result.GetPorts(), "ports probed without opt-in")
+	})
+
+	t.Run("enabled", func(t *testing.T) {
+		wappalyzer, err := New(
+			WithDisabledVectors(VectorDNS, VectorRobots, VectorAssets),
+			WithPortProbing(portOf(console), portOf(appServer), closed, portOf(target)),
+		)
+		require.NoError(t, err, "could not create wappalyzer")
+
+		result := wappalyzer.AnalyzeWithPipeline(resp, []byte("<html></html>"))
+		require.Equal(t, []PortProbe{
+			{Port: portOf(console), URL: fmt.Sprintf("https://127.0.0.1:%d/", portOf(console)), StatusCode: http.StatusOK},
+			{Port: portOf(appServer), URL: fmt.Sprintf("http://127.0.0.1:%d/", portOf(appServer)), StatusCode: http.StatusFound},
+		}, result.GetPorts(), "wrong responding ports")
+
+		detections := result.GetDetections()
+		require.Equal(t, []string{"ports"}, detections["Jetty"].DetectedBy, "could not detect Jetty on the https port")
+		require.Equal(t, "9.4.51", detections["Jetty"].Version, "wrong Jetty version")
+		require.Equal(t, []string{"ports"}, detections["PHP"].DetectedBy, "could not detect PHP on the http port")
+		require.NotContains(t, detections["Express"].DetectedBy, "ports", "target port should not be probed")
+	})
+}
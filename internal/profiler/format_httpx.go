@@ -0,0 +1,78 @@
+package profiler
+
+import (
+	"encoding/json"
+	"io"
+	"net/url"
+	"sort"
+	"time"
+)
+
+// HTTPXRecord is a result in the JSONL schema of ProjectDiscovery's httpx,
+// so Kitsune can slot into subdomain -> probe -> fingerprint pipelines.
+type HTTPXRecord struct {
+	Timestamp  time.Time `json:"timestamp"`
+	Input      string    `json:"input"`
+	URL        string    `json:"url"`
+	Host       string    `json:"host"`
+	Port       string    `json:"port"`
+	Scheme     string    `json:"scheme"`
+	Title      string    `json:"title,omitempty"`
+	WebServer  string    `json:"webserver,omitempty"`
+	Tech       []string  `json:"tech,omitempty"`
+	StatusCode int       `json:"status_code"`
+}
+
+// NewHTTPXRecord converts a result into an httpx record. input is the target
+// as it was given, before scheme detection. Technologies are formatted as
+// "Name:version" like httpx does, and sorted for stable output.
+func NewHTTPXRecord(input string, scannedAt time.Time, result richResult) HTTPXRecord {
+	record := HTTPXRecord{
+		Timestamp:  scannedAt,
+		Input:      input,
+		URL:        result.url,
+		Title:      result.title,
+		WebServer:  result.webServer,
+		StatusCode: result.statusCode,
+	}
+	if record.URL == "" {
+		record.URL = input
+	}
+
+	if parsedURL, err := url.Parse(record.URL); err == nil {
+		record.Host = parsedURL.Hostname()
+		record.Scheme = parsedURL.Scheme
+		record.Port = parsedURL.Port()
+		if record.Port == "" {
+			switch record.Scheme {
+			case "https":
+				record.Port = "443"
+			case "http":
+				record.Port = "80"
+			}
+		}
+	}
+
+	for technology := range result.technologies {
+		record.Tech = append(record.Tech, technology)
+	}
+	sort.Strings(record.Tech)
+	return record
+}
+
+// HTTPXWriter writes httpx records as JSON Lines
+type HTTPXWriter struct {
+	encoder *json.Encoder
+}
+
+// NewHTTPXWriter returns a writer emitting one httpx record per line to w
+func NewHTTPXWriter(w io.Writer) *HTTPXWriter {
+	encoder := json.NewEncoder(w)
+	encoder.SetEscapeHTML(false)
+	return &HTTPXWriter{encoder: encoder}
+}
+
+// Write writes a single record as one line
+func (w *HTTPXWriter) Write(record HTTPXRecord) error {
+	return w.encoder.Encode(record)
+}
@@ -0,0 +1,67 @@
+package profiler
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestHTTPXWriter(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Server", "nginx/1.25.3")
+		w.Write([]byte("<html><head><title>Login & Dashboard</title></head></html>"))
+	}))
+	defer server.Close()
+
+	wappalyzer, err := New()
+	require.NoError(t, err, "could not create wappalyzer")
+
+	result, err := wappalyzer.FingerprintURL(context.Background(), server.URL)
+	require.NoError(t, err, "could not fingerprint url")
+
+	var buffer bytes.Buffer
+	writer := NewHTTPXWriter(&buffer)
+	host := strings.TrimPrefix(server.URL, "http://")
+	require.NoError(t, writer.Write(NewHTTPXRecord(host, time.Now(), result)), "could not write record")
+	require.NoError(t, writer.Write(NewHTTPXRecord(host, time.Now(), result)), "could not write record")
+
+	lines := strings.Split(strings.TrimSpace(buffer.String()), "\n")
+	require.Len(t, lines, 2, "expected one line per record")
+	require.Contains(t, lines[0], `"title":"Login & Dashboard"`, "html should not be escaped")
+
+	var line map[string]interface{}
+	require.NoError(t, json.Unmarshal([]byte(lines[0]), &line), "could not decode line")
+	require.Equal(t, host, line["input"], "wrong input")
+	require.Equal(t, server.URL, line["url"], "wrong url")
+	require.Equal(t, "127.0.0.1", line["host"], "wrong host")
+	require.Equal(t, "http", line["scheme"], "wrong scheme")
+	require.Equal(t, "nginx/1.25.3", line["webserver"], "wrong webserver")
+	require.Equal(t, float64(http.StatusOK), line["status_code"], "wrong status code")
+	require.Contains(t, line["tech"], "Nginx:1.25.3", "missing versioned technology")
+}
+
+func TestNewHTTPXRecordDefaultPort(t *testing.T) {
+	tests := []struct {
+		url  string
+		port string
+	}{
+		{"https://example.com", "443"},
+		{"http://example.com/path", "80"},
+		{"https://example.com:8443", "8443"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.url, func(t *testing.T) {
+			record := NewHTTPXRecord("example.com", time.Now(), richResult{url: tt.url})
+			require.Equal(t, "example.com", record.Host, "wrong host")
+			require.Equal(t, tt.port, record.Port, "wrong port")
+		})
+	}
+}
@@ -0,0 +1,115 @@
+package profiler
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// defaultWappalyzerIcon is the icon Wappalyzer reports for technologies without one
+const defaultWappalyzerIcon = "default.svg"
+
+// WappalyzerOutput mirrors the JSON document printed by the Wappalyzer CLI,
+// so tooling built around it can consume Kitsune results unchanged.
+type WappalyzerOutput struct {
+	URLs         map[string]WappalyzerURL `json:"urls"`
+	Technologies []WappalyzerTechnology   `json:"technologies"`
+}
+
+// WappalyzerURL is the status of a URL analyzed by the Wappalyzer CLI
+type WappalyzerURL struct {
+	Status int `json:"status"`
+}
+
+// WappalyzerTechnology is a detected technology in the Wappalyzer CLI schema.
+// Missing optional values are emitted as null, as Wappalyzer does.
+type WappalyzerTechnology struct {
+	Slug        string               `json:"slug"`
+	Name        string               `json:"name"`
+	Description *string              `json:"description"`
+	Confidence  int                  `json:"confidence"`
+	Version     *string              `json:"version"`
+	Icon        string               `json:"icon"`
+	Website     string               `json:"website"`
+	CPE         *string              `json:"cpe"`
+	Categories  []WappalyzerCategory `json:"categories"`
+}
+
+// WappalyzerCategory is a technology category in the Wappalyzer CLI schema
+type WappalyzerCategory struct {
+	ID   int    `json:"id"`
+	Slug string `json:"slug"`
+	Name string `json:"name"`
+}
+
+var (
+	slugInvalidChars = regexp.MustCompile(`[^a-z0-9-]`)
+	slugRepeatDashes = regexp.MustCompile(`--+`)
+)
+
+// slugify converts a technology or category name into a Wappalyzer slug,
+// e.g. "Ruby on Rails" becomes "ruby-on-rails"
+func slugify(name string) string {
+	slug := slugInvalidChars.ReplaceAllString(strings.ToLower(name), "-")
+	slug = slugRepeatDashes.ReplaceAllString(slug, "-")
+	return strings.Trim(slug, "-")
+}
+
+// nullableString returns nil for an empty string so it is encoded as null
+func nullableString(value string) *string {
+	if value == "" {
+		return nil
+	}
+	return &value
+}
+
+// Wappalyzer converts a result into the Wappalyzer CLI JSON schema.
+// Technologies are sorted by name and their categories by ID for stable output.
+func (s *Wappalyze) Wappalyzer(result richResult) WappalyzerOutput {
+	output := WappalyzerOutput{
+		URLs:         make(map[string]WappalyzerURL),
+		Technologies: make([]WappalyzerTechnology, 0, len(result.detections)),
+	}
+	if result.url != "" {
+		output.URLs[result.url] = WappalyzerURL{Status: result.statusCode}
+	}
+
+	for name, detection := range result.detections {
+		technology := WappalyzerTechnology{
+			Slug:       slugify(name),
+			Name:       name,
+			Confidence: detection.Confidence,
+			Version:    nullableString(detection.Version),
+			Icon:       defaultWappalyzerIcon,
+			Categories: []WappalyzerCategory{},
+		}
+
+		// Technologies added by custom vectors may have no fingerprint entry
+		if fingerprint, ok := s.fingerprints.Apps[name]; ok {
+			technology.Description = nullableString(fingerprint.description)
+			technology.Website = fingerprint.website
+			technology.CPE = nullableString(fingerprint.cpe)
+			if fingerprint.icon != "" {
+				technology.Icon = fingerprint.icon
+			}
+			for _, id := range fingerprint.cats {
+				if category, ok := s.categories[id]; ok {
+					technology.Categories = append(technology.Categories, WappalyzerCategory{
+						ID:   id,
+						Slug: slugify(category.Name),
+						Name: category.Name,
+					})
+				}
+			}
+			sort.Slice(technology.Categories, func(i, j int) bool {
+				return technology.Categories[i].ID < technology.Categories[j].ID
+			})
+		}
+		output.Technologies = append(output.Technologies, technology)
+	}
+
+	sort.Slice(output.Technologies, func(i, j int) bool {
+		return output.Technologies[i].Name < output.Technologies[j].Name
+	})
+	return output
+}
@@ -0,0 +1,65 @@
+package profiler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSlugify(t *testing.T) {
+	tests := []struct {
+		name     string
+		expected string
+	}{
+		{"Nginx", "nginx"},
+		{"Ruby on Rails", "ruby-on-rails"},
+		{"Node.js", "node-js"},
+		{"Google Tag Manager (GTM)", "google-tag-manager-gtm"},
+		{"  --Web servers--  ", "web-servers"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.expected, slugify(tt.name), "wrong slug")
+		})
+	}
+}
+
+func TestWappalyzerFormat(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Server", "nginx/1.25.3")
+		w.Write([]byte("<html><head><title>Test</title></head></html>"))
+	}))
+	defer server.Close()
+
+	wappalyzer, err := New()
+	require.NoError(t, err, "could not create wappalyzer")
+
+	result, err := wappalyzer.FingerprintURL(context.Background(), server.URL)
+	require.NoError(t, err, "could not fingerprint url")
+
+	output := wappalyzer.Wappalyzer(result)
+	require.Equal(t, http.StatusOK, output.URLs[server.URL].Status, "wrong url status")
+
+	var nginx *WappalyzerTechnology
+	for i := range output.Technologies {
+		if output.Technologies[i].Name == "Nginx" {
+			nginx = &output.Technologies[i]
+		}
+	}
+	require.NotNil(t, nginx, "could not get nginx technology")
+	require.Equal(t, "nginx", nginx.Slug, "wrong slug")
+	require.Equal(t, "1.25.3", *nginx.Version, "wrong version")
+	require.Equal(t, "Nginx.svg", nginx.Icon, "wrong icon")
+	require.NotNil(t, nginx.CPE, "cpe should be set")
+	require.Contains(t, nginx.Categories, WappalyzerCategory{ID: 22, Slug: "web-servers", Name: "Web servers"}, "missing category")
+
+	// Missing optional values are encoded as null, as the Wappalyzer CLI does
+	data, err := json.Marshal(WappalyzerTechnology{Name: "Example", Categories: []WappalyzerCategory{}})
+	require.NoError(t, err, "could not marshal technology")
+	require.JSONEq(t, `{"slug":"","name":"Example","description":null,"confidence":0,"version":null,"icon":"","website":"","cpe":null,"categories":[]}`, string(data), "wrong encoding")
+}
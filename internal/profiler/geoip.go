@@ -0,0 +1,70 @@
+package profiler
+
+import (
+	"context"
+	"net"
+	"net/http/httptrace"
+	"net/netip"
+)
+
+// Location is where the address a target was fetched from is hosted. It is
+// metadata of the result, not a detection.
+type Location struct {
+	IP string `json:"ip"`
+	// Country is the ISO 3166-1 code of the country of the address
+	Country     string `json:"country,omitempty"`
+	CountryName string `json:"country_name,omitempty"`
+	// ASN is the number of the autonomous system announcing the address
+	ASN            uint   `json:"asn,omitempty"`
+	ASOrganization string `json:"as_organization,omitempty"`
+	// CloudProvider and CloudRegion locate addresses of cloud providers
+	CloudProvider string `json:"cloud_provider,omitempty"`
+	CloudRegion   string `json:"cloud_region,omitempty"`
+}
+
+// GeoIPDatabase looks up the location of an address. Lookups of unknown
+// addresses return a location with only the IP set.
+type GeoIPDatabase interface {
+	Lookup(ip netip.Addr) (Location, error)
+}
+
+// WithGeoIP annotates the results of FingerprintURL with the location of the
+// address the page was fetched from, looked up in db. The geoip package
+// implements db with MaxMind DB files.
+func WithGeoIP(db GeoIPDatabase) Option {
+	return func(s *Wappalyze) {
+		s.geoIP = db
+	}
+}
+
+// GetLocation returns the location of the address the page was fetched from,
+// or nil without WithGeoIP or if it could not be looked up
+func (r richResult) GetLocation() *Location {
+	return r.location
+}
+
+// withRemoteAddr returns a context recording the address the requests sent
+// with it connect to in remote. After redirects, it is the address of the last.
+func withRemoteAddr(ctx context.Context, remote *netip.Addr) context.Context {
+	return httptrace.WithClientTrace(ctx, &httptrace.ClientTrace{
+		GotConn: func(info httptrace.GotConnInfo) {
+			if addr, ok := info.Conn.RemoteAddr().(*net.TCPAddr); ok {
+				*remote = addr.AddrPort().Addr().Unmap()
+			}
+		},
+	})
+}
+
+// locate looks up the location of remote, logging failures
+func (s *Wappalyze) locate(ctx context.Context, remote netip.Addr) *Location {
+	if !remote.IsValid() {
+		return nil
+	}
+	location, err := s.geoIP.Lookup(remote)
+	if err != nil {
+		s.logger.DebugContext(ctx, "geoip lookup failed", "ip", remote, "error", err)
+		return nil
+	}
+	location.IP = remote.String()
+	return &location
+}
@@ -0,0 +1,61 @@
+package profiler
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// geoIPFunc adapts a function to GeoIPDatabase
+type geoIPFunc func(ip netip.Addr) (Location, error)
+
+func (f geoIPFunc) Lookup(ip netip.Addr) (Location, error) {
+	return f(ip)
+}
+
+func TestWithGeoIP(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Server", "nginx")
+	}))
+	defer server.Close()
+
+	t.Run("located", func(t *testing.T) {
+		var looked []netip.Addr
+		wappalyzer, err := New(WithProfile(ProfileFast), WithGeoIP(geoIPFunc(func(ip netip.Addr) (Location, error) {
+			looked = append(looked, ip)
+			return Location{Country: "DE", ASN: 64500}, nil
+		})))
+		require.NoError(t, err, "could not create wappalyzer")
+
+		result, err := wappalyzer.FingerprintURL(context.Background(), server.URL)
+		require.NoError(t, err, "could not fingerprint url")
+		require.Equal(t, []netip.Addr{netip.MustParseAddr("127.0.0.1")}, looked, "wrong address looked up")
+		require.Equal(t, &Location{IP: "127.0.0.1", Country: "DE", ASN: 64500}, result.GetLocation(), "wrong location")
+		require.NotContains(t, result.GetDetections(), "DE", "location should not be a detection")
+	})
+
+	t.Run("failed", func(t *testing.T) {
+		wappalyzer, err := New(WithProfile(ProfileFast), WithGeoIP(geoIPFunc(func(ip netip.Addr) (Location, error) {
+			return Location{}, errors.New("corrupt database")
+		})))
+		require.NoError(t, err, "could not create wappalyzer")
+
+		result, err := wappalyzer.FingerprintURL(context.Background(), server.URL)
+		require.NoError(t, err, "lookup failure should not fail the analysis")
+		require.Nil(t, result.GetLocation(), "failed lookup should have no location")
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		wappalyzer, err := New(WithProfile(ProfileFast))
+		require.NoError(t, err, "could not create wappalyzer")
+
+		result, err := wappalyzer.FingerprintURL(context.Background(), server.URL)
+		require.NoError(t, err, "could not fingerprint url")
+		require.Nil(t, result.GetLocation(), "location without geoip")
+	})
+}
@@ -0,0 +1,205 @@
+package profiler
+
+import (
+	"context"
+	"net"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/weppos/publicsuffix-go/publicsuffix"
+)
+
+// WithHostAliases makes FingerprintURL also analyze the other variant of the
+// host of the target, www.example.com for example.com and the reverse, since
+// sites often serve a different stack on each or redirect one to the other.
+// The detections of both are merged, each listing the hosts it was found on,
+// and the result reports the variants analyzed and the canonical URL they
+// settle on. The analysis fails only if neither variant can be analyzed. Hosts
+// that are neither a registrable domain nor its www subdomain have no alias.
+func WithHostAliases(enabled bool) Option {
+	return func(s *Wappalyze) {
+		s.hostAliases = enabled
+	}
+}
+
+// HostAnalysis is the analysis of one variant of the host of a target
+type HostAnalysis struct {
+	// Host is the host that was requested
+	Host string `json:"host"`
+	// URL is the URL the analyzed response was fetched from, after redirects
+	URL string `json:"url,omitempty"`
+	// Error is why the variant could not be fetched or was only partly analyzed
+	Error string `json:"error,omitempty"`
+}
+
+// GetHosts returns the variants of the target host analyzed with
+// WithHostAliases, the requested one first
+func (r richResult) GetHosts() []HostAnalysis {
+	return r.hosts
+}
+
+// GetCanonicalURL returns the URL the variants of the target host settle on
+// with WithHostAliases: the URL the target was fetched from after redirects,
+// or that of its alias if the target could not be analyzed without error
+func (r richResult) GetCanonicalURL() string {
+	return r.canonicalURL
+}
+
+// fingerprintHostAliases analyzes the target and the alias of its host
+// concurrently and merges the results
+func (s *Wappalyze) fingerprintHostAliases(ctx context.Context, targetURL string) (richResult, error) {
+	aliasURL, ok := hostAlias(s.candidateURLs(targetURL)[0])
+	if !ok {
+		return s.fingerprintURL(ctx, targetURL)
+	}
+
+	urls := []string{targetURL, aliasURL}
+	results := make([]richResult, len(urls))
+	errs := make([]error, len(urls))
+	var wg sync.WaitGroup
+	for i, variant := range urls {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i], errs[i] = s.fingerprintURL(ctx, variant)
+		}()
+	}
+	wg.Wait()
+
+	hosts := make([]HostAnalysis, len(urls))
+	for i, variant := range urls {
+		hosts[i] = HostAnalysis{Host: hostOf(s.candidateURLs(variant)[0]), URL: results[i].url}
+		if errs[i] != nil {
+			hosts[i].Error = errs[i].Error()
+		}
+	}
+
+	// A variant that failed without a response has nothing to merge. The
+	// canonical variant is the first analyzed without error, if any.
+	var analyzed []int
+	for _, failed := range []bool{false, true} {
+		for i := range urls {
+			if results[i].url != "" && (errs[i] != nil) == failed {
+				analyzed = append(analyzed, i)
+			}
+		}
+	}
+	if len(analyzed) == 0 {
+		return richResult{}, errs[0]
+	}
+	return s.mergeHostResults(hosts, results, analyzed), errs[analyzed[0]]
+}
+
+// mergeHostResults merges the results of the analyzed variants of a host. The
+// first analyzed variant is canonical: its page metadata is kept and its
+// versions take precedence.
+func (s *Wappalyze) mergeHostResults(hosts []HostAnalysis, results []richResult, analyzed []int) richResult {
+	merged := results[analyzed[0]]
+	merged.canonicalURL = merged.url
+	merged.hosts = hosts
+	merged.detections = make(map[string]Detection)
+	merged.errors = nil
+	merged.skipped = nil
+
+	for _, i := range analyzed {
+		result := results[i]
+		for name, detection := range result.detections {
+			existing, ok := merged.detections[name]
+			if !ok {
+				detection.DetectedBy = slices.Clone(detection.DetectedBy)
+				detection.Hosts = []string{hosts[i].Host}
+				merged.detections[name] = detection
+				continue
+			}
+			existing.Confidence = max(existing.Confidence, detection.Confidence)
+			if existing.Version == "" {
+				existing.Version = detection.Version
+			}
+			if existing.Components == nil {
+				existing.Components = detection.Components
+			}
+			for _, vector := range detection.DetectedBy {
+				if !slices.Contains(existing.DetectedBy, vector) {
+					existing.DetectedBy = append(existing.DetectedBy, vector)
+				}
+			}
+			slices.Sort(existing.DetectedBy)
+			if !slices.Contains(existing.Hosts, hosts[i].Host) {
+				existing.Hosts = append(existing.Hosts, hosts[i].Host)
+			}
+			merged.detections[name] = existing
+		}
+		merged.errors = append(merged.errors, result.errors...)
+		for _, stage := range result.skipped {
+			if !slices.Contains(merged.skipped, stage) {
+				merged.skipped = append(merged.skipped, stage)
+			}
+		}
+	}
+
+	// Technologies detected on any variant are no near misses
+	merged.nearMisses = nil
+	for _, i := range analyzed {
+		merged.explained = merged.explained || results[i].explained
+		for _, nearMiss := range results[i].nearMisses {
+			if _, ok := merged.detections[nearMiss.Technology]; ok {
+				continue
+			}
+			if !slices.ContainsFunc(merged.nearMisses, func(other NearMiss) bool {
+				return other.Technology == nearMiss.Technology && other.Reason == nearMiss.Reason
+			}) {
+				merged.nearMisses = append(merged.nearMisses, nearMiss)
+			}
+		}
+	}
+	sortNearMisses(merged.nearMisses)
+
+	merged.technologies = make(map[string]struct{}, len(merged.detections))
+	for name, detection := range merged.detections {
+		merged.technologies[FormatAppVersion(name, detection.Version)] = struct{}{}
+	}
+	s.populateInfo(&merged)
+	return merged
+}
+
+// hostAlias returns rawURL with the www subdomain added to its host if it is a
+// registrable domain, or removed if it is the www subdomain of one
+func hostAlias(rawURL string) (string, bool) {
+	parsedURL, err := url.Parse(rawURL)
+	if err != nil {
+		return "", false
+	}
+	host := parsedURL.Hostname()
+	if net.ParseIP(host) != nil {
+		return "", false
+	}
+
+	var alias string
+	if apex, ok := strings.CutPrefix(host, "www."); ok {
+		if domain, err := publicsuffix.Domain(apex); err != nil || domain != apex {
+			return "", false
+		}
+		alias = apex
+	} else {
+		if domain, err := publicsuffix.Domain(host); err != nil || domain != host {
+			return "", false
+		}
+		alias = "www." + host
+	}
+
+	if port := parsedURL.Port(); port != "" {
+		alias = net.JoinHostPort(alias, port)
+	}
+	parsedURL.Host = alias
+	return parsedURL.String(), true
+}
+
+// hostOf returns the host of rawURL, or rawURL itself if it cannot be parsed
+func hostOf(rawURL string) string {
+	if parsedURL, err := url.Parse(rawURL); err == nil && parsedURL.Host != "" {
+		return parsedURL.Hostname()
+	}
+	return rawURL
+}
@@ -0,0 +1,117 @@
+package profiler
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestHostAlias(t *testing.T) {
+	tests := []struct {
+		name   string
+		rawURL string
+		alias  string
+	}{
+		{"apex", "https://example.com/path", "https://www.example.com/path"},
+		{"www", "https://www.example.com", "https://example.com"},
+		{"port", "http://example.com:8080", "http://www.example.com:8080"},
+		{"public-suffix", "https://example.co.uk", "https://www.example.co.uk"},
+		{"subdomain", "https://blog.example.com", ""},
+		{"www-of-suffix", "https://www.co.uk", ""},
+		{"ip", "http://127.0.0.1", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			alias, ok := hostAlias(tt.rawURL)
+			require.Equal(t, tt.alias != "", ok, "wrong alias presence")
+			require.Equal(t, tt.alias, alias, "wrong alias")
+		})
+	}
+}
+
+func TestFingerprintURLHostAliases(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Host {
+		case "example.com":
+			w.Header().Set("Server", "nginx/1.25.3")
+			w.Header().Set("X-Powered-By", "PHP/8.2.0")
+		case "www.example.com":
+			w.Header().Set("Server", "nginx")
+		case "example.org":
+			http.Redirect(w, r, "http://www.example.org/", http.StatusMovedPermanently)
+			return
+		case "www.example.org":
+			w.Header().Set("Server", "nginx")
+		}
+		w.Write([]byte("<html><head><title>Example</title></head></html>"))
+	}))
+	defer server.Close()
+
+	// Every host resolves to the test server
+	newWappalyzer := func(t *testing.T) *Wappalyze {
+		wappalyzer, err := New(WithProfile(ProfileFast), WithHostAliases(true))
+		require.NoError(t, err, "could not create wappalyzer")
+		transport := wappalyzer.httpClient.Transport.(*http.Transport).Clone()
+		transport.DialContext = func(ctx context.Context, network, _ string) (net.Conn, error) {
+			var dialer net.Dialer
+			return dialer.DialContext(ctx, network, server.Listener.Addr().String())
+		}
+		wappalyzer.httpClient.Transport = transport
+		return wappalyzer
+	}
+
+	t.Run("merged", func(t *testing.T) {
+		result, err := newWappalyzer(t).FingerprintURL(context.Background(), "http://example.com")
+		require.NoError(t, err, "could not fingerprint url")
+
+		detections := result.GetDetections()
+		require.Equal(t, []string{"example.com", "www.example.com"}, detections["Nginx"].Hosts, "wrong nginx hosts")
+		require.Equal(t, "1.25.3", detections["Nginx"].Version, "canonical version should win")
+		require.Equal(t, []string{"example.com"}, detections["PHP"].Hosts, "wrong php hosts")
+		require.Contains(t, result.GetTechnologies(), "Nginx:1.25.3", "missing merged technology")
+		require.Contains(t, result.GetTechnologies(), "PHP:8.2.0", "missing merged technology")
+		require.Contains(t, result.GetAppInfo(), "PHP:8.2.0", "missing app info")
+
+		require.Equal(t, "http://example.com", result.GetCanonicalURL(), "wrong canonical url")
+		require.Equal(t, []HostAnalysis{
+			{Host: "example.com", URL: "http://example.com"},
+			{Host: "www.example.com", URL: "http://www.example.com"},
+		}, result.GetHosts(), "wrong hosts")
+	})
+
+	t.Run("redirect", func(t *testing.T) {
+		result, err := newWappalyzer(t).FingerprintURL(context.Background(), "http://example.org")
+		require.NoError(t, err, "could not fingerprint url")
+		require.Equal(t, "http://www.example.org/", result.GetCanonicalURL(), "redirect target should be canonical")
+		require.Equal(t, []string{"example.org", "www.example.org"}, result.GetDetections()["Nginx"].Hosts, "wrong nginx hosts")
+	})
+
+	t.Run("alias-unreachable", func(t *testing.T) {
+		wappalyzer := newWappalyzer(t)
+		dial := wappalyzer.httpClient.Transport.(*http.Transport).DialContext
+		wappalyzer.httpClient.Transport.(*http.Transport).DialContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
+			if addr == "www.example.com:80" {
+				return nil, &net.OpError{Op: "dial", Net: network, Err: net.UnknownNetworkError("refused")}
+			}
+			return dial(ctx, network, addr)
+		}
+
+		result, err := wappalyzer.FingerprintURL(context.Background(), "http://example.com")
+		require.NoError(t, err, "target should be analyzed without its alias")
+		require.Equal(t, []string{"example.com"}, result.GetDetections()["Nginx"].Hosts, "wrong nginx hosts")
+		require.Len(t, result.GetHosts(), 2, "failed alias should be reported")
+		require.NotEmpty(t, result.GetHosts()[1].Error, "alias error missing")
+	})
+
+	t.Run("no-alias", func(t *testing.T) {
+		result, err := newWappalyzer(t).FingerprintURL(context.Background(), server.URL)
+		require.NoError(t, err, "could not fingerprint url")
+		require.Empty(t, result.GetHosts(), "ip target has no alias")
+		require.Nil(t, result.GetDetections()["Nginx"].Hosts, "single host should have no provenance")
+	})
+}
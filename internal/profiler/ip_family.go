@@ -0,0 +1,57 @@
+package profiler
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/netip"
+	"syscall"
+	"time"
+)
+
+// IPFamily restricts the addresses the page and probes are fetched from
+type IPFamily string
+
+const (
+	// IPFamilyAny connects over IPv4 or IPv6, racing both when the host has
+	// addresses of each family (Happy Eyeballs). It is the default.
+	IPFamilyAny IPFamily = ""
+	// IPFamilyV4 only connects to IPv4 addresses
+	IPFamilyV4 IPFamily = "ipv4"
+	// IPFamilyV6 only connects to IPv6 addresses
+	IPFamilyV6 IPFamily = "ipv6"
+)
+
+// ParseIPFamily returns the IP family with the given name, ipv4 or ipv6. An
+// empty name is IPFamilyAny.
+func ParseIPFamily(name string) (IPFamily, error) {
+	switch IPFamily(name) {
+	case IPFamilyAny, IPFamilyV4, IPFamilyV6:
+		return IPFamily(name), nil
+	}
+	return "", fmt.Errorf("unknown IP family %q, expected ipv4 or ipv6", name)
+}
+
+// ipFamilyOf returns the family of addr
+func ipFamilyOf(addr netip.Addr) IPFamily {
+	if addr.Is4() {
+		return IPFamilyV4
+	}
+	return IPFamilyV6
+}
+
+// dialContext returns the dial function of the HTTP client of the family,
+// vetting each address with control, if set. The dialer falls back between
+// families as the default transport does.
+func (f IPFamily) dialContext(control func(ctx context.Context, network, address string, c syscall.RawConn) error) func(ctx context.Context, network, address string) (net.Conn, error) {
+	dialer := &net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second, ControlContext: control}
+	return func(ctx context.Context, network, address string) (net.Conn, error) {
+		switch f {
+		case IPFamilyV4:
+			network = "tcp4"
+		case IPFamilyV6:
+			network = "tcp6"
+		}
+		return dialer.DialContext(ctx, network, address)
+	}
+}
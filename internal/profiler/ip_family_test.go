@@ -0,0 +1,77 @@
+package profiler
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseIPFamily(t *testing.T) {
+	for _, family := range []IPFamily{IPFamilyAny, IPFamilyV4, IPFamilyV6} {
+		parsed, err := ParseIPFamily(string(family))
+		require.NoError(t, err, "could not parse %q", family)
+		require.Equal(t, family, parsed, "wrong family")
+	}
+	_, err := ParseIPFamily("ipv5")
+	require.Error(t, err, "invalid family accepted")
+}
+
+func TestCandidateURLsIPv6(t *testing.T) {
+	wappalyzer, err := New()
+	require.NoError(t, err, "could not create wappalyzer")
+	require.Equal(t, []string{"https://[2001:db8::1]"}, wappalyzer.candidateURLs("2001:db8::1"), "bare address not bracketed")
+	require.Equal(t, []string{"https://[2001:db8::1]:8443/"}, wappalyzer.candidateURLs("https://[2001:db8::1]:8443/"), "url changed")
+}
+
+func TestIPFamily(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("<html></html>"))
+	})
+	server4 := httptest.NewServer(handler)
+	defer server4.Close()
+
+	type ipFamilyTest struct {
+		name     string
+		url      string
+		family   IPFamily
+		remoteIP string
+		wantErr  bool
+	}
+	tests := []ipFamilyTest{
+		{name: "any", url: server4.URL, remoteIP: "127.0.0.1"},
+		{name: "ipv4", url: server4.URL, family: IPFamilyV4, remoteIP: "127.0.0.1"},
+		{name: "ipv6 only", url: server4.URL, family: IPFamilyV6, wantErr: true},
+	}
+
+	// IPv6 may not be available in the test environment
+	if listener, err := net.Listen("tcp6", "[::1]:0"); err == nil {
+		server6 := &httptest.Server{Listener: listener, Config: &http.Server{Handler: handler}}
+		server6.Start()
+		defer server6.Close()
+		tests = append(tests,
+			ipFamilyTest{name: "ipv6", url: server6.URL, family: IPFamilyV6, remoteIP: "::1"},
+			ipFamilyTest{name: "ipv4 only", url: server6.URL, family: IPFamilyV4, wantErr: true},
+		)
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			wappalyzer, err := New(WithIPFamily(tt.family), WithProfile(ProfileFast))
+			require.NoError(t, err, "could not create wappalyzer")
+
+			result, err := wappalyzer.FingerprintURL(context.Background(), tt.url)
+			if tt.wantErr {
+				require.Error(t, err, "fetched over the wrong family")
+				return
+			}
+			require.NoError(t, err, "could not fingerprint")
+			require.Equal(t, tt.remoteIP, result.GetProtocol().RemoteIP, "wrong remote ip")
+			require.Equal(t, ipFamilyOf(netip.MustParseAddr(tt.remoteIP)), result.GetProtocol().IPFamily, "wrong family")
+		})
+	}
+}
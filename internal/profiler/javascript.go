@@ -49,6 +49,14 @@ var (
 	// Class additions
 	classAddPattern = regexp.MustCompile(`(?:classList|className)\s*\.\s*(?:add|toggle)\s*\(\s*['"]([^'"]+)['"]\s*\)`)
 
+	// Request URL literals passed to fetch(), XMLHttpRequest.open() and common AJAX helpers
+	requestURLPatterns = []*regexp.Regexp{
+		regexp.MustCompile("\\bfetch\\s*\\(\\s*['\"`]([^'\"`\\s]+)['\"`]"),
+		regexp.MustCompile(`\.open\s*\(\s*['"][A-Za-z]+['"]\s*,\s*['"]([^'"\s]+)['"]`),
+		regexp.MustCompile(`(?:\baxios|\bjQuery|\$)\s*\.\s*(?:get|post|put|delete|patch|ajax|getJSON)\s*\(\s*['"]([^'"\s]+)['"]`),
+		regexp.MustCompile(`\bsendBeacon\s*\(\s*['"]([^'"\s]+)['"]`),
+	}
+
 	// Version extraction with more flexible patterns
 	versionPattern       = regexp.MustCompile(`([0-9]+(?:\.[0-9]+)+)`)
 	versionSemverPattern = regexp.MustCompile(`['"](\d+\.\d+(?:\.\d+)?(?:-[a-zA-Z0-9.-]+)?)['"]`)
@@ -365,3 +373,27 @@ func ExtractJSGlobals(jsContent string) JSExtractionResult {
 	return result
 }
 
+
+// ExtractRequestURLs extracts URL literals that a script passes to fetch(),
+// XMLHttpRequest.open() and common AJAX helpers.
+//
+// Like SplitIntoStatements this is a heuristic: only string literals are
+// recognised, URLs built at runtime are not.
+func ExtractRequestURLs(jsContent string) []string {
+	var urls []string
+	seen := make(map[string]struct{})
+
+	for _, pattern := range requestURLPatterns {
+		for _, matches := range pattern.FindAllStringSubmatch(jsContent, -1) {
+			if len(matches) < 2 || matches[1] == "" {
+				continue
+			}
+			if _, exists := seen[matches[1]]; exists {
+				continue
+			}
+			seen[matches[1]] = struct{}{}
+			urls = append(urls, matches[1])
+		}
+	}
+	return urls
+}
@@ -131,4 +131,28 @@ func TestLibraryDetection(t *testing.T) {
 			}
 		})
 	}
-}
\ No newline at end of file
+}
+func TestExtractRequestURLs(t *testing.T) {
+	js := `fetch("https://api.example.com/v1/items");
+var xhr = new XMLHttpRequest(); xhr.open('GET', '//cdn.example.net/data.json');
+$.getJSON('/local/endpoint');
+axios.post("https://track.example.org/e", payload);
+fetch(buildURL());`
+
+	want := []string{
+		"https://api.example.com/v1/items",
+		"//cdn.example.net/data.json",
+		"/local/endpoint",
+		"https://track.example.org/e",
+	}
+	got := ExtractRequestURLs(js)
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ExtractRequestURLs() = %v, want %v", got, want)
+	}
+
+	hosts := extractXHRHosts("https://www.example.com/", []string{js})
+	wantHosts := []string{"api.example.com", "cdn.example.net", "track.example.org"}
+	if !reflect.DeepEqual(hosts, wantHosts) {
+		t.Errorf("extractXHRHosts() = %v, want %v", hosts, wantHosts)
+	}
+}
@@ -0,0 +1,76 @@
+package profiler
+
+import (
+	"bytes"
+
+	"golang.org/x/net/html"
+)
+
+// Limit is a resource cap an analysis can reach, as reported by
+// AnalysisStats.LimitsHit
+type Limit string
+
+const (
+	// LimitAssets is AssetPolicy.MaxAssets
+	LimitAssets Limit = "assets"
+	// LimitBytes is AssetPolicy.MaxBytes
+	LimitBytes Limit = "bytes"
+	// LimitDOMNodes is ParseLimits.MaxDOMNodes
+	LimitDOMNodes Limit = "dom_nodes"
+	// LimitInlineScripts is ParseLimits.MaxInlineScripts
+	LimitInlineScripts Limit = "inline_scripts"
+)
+
+// ParseLimits bounds the work spent on the page itself, to protect batch
+// scanners from pathological or adversarial documents. The zero value sets
+// no limits.
+type ParseLimits struct {
+	// MaxDOMNodes caps the elements of the page parsed into the DOM. The rest
+	// of the document is left out of the DOM, though still matched as raw HTML.
+	MaxDOMNodes int
+	// MaxInlineScripts caps the inline scripts scanned for requests, service
+	// workers and consent platforms. Scripts past the cap are ignored.
+	MaxInlineScripts int
+}
+
+// DefaultParseLimits returns parse limits suited to scanning third party
+// sites: at most 100,000 elements and 200 inline scripts per page.
+func DefaultParseLimits() ParseLimits {
+	return ParseLimits{
+		MaxDOMNodes:      100000,
+		MaxInlineScripts: 200,
+	}
+}
+
+// truncateDOM returns the prefix of body holding its first maxNodes elements,
+// and whether anything was cut
+func truncateDOM(body []byte, maxNodes int) ([]byte, bool) {
+	tokenizer := html.NewTokenizer(bytes.NewReader(body))
+	var offset, nodes int
+	for {
+		switch tokenizer.Next() {
+		case html.ErrorToken:
+			return body, false
+		case html.StartTagToken, html.SelfClosingTagToken:
+			nodes++
+			if nodes > maxNodes {
+				return body[:offset], true
+			}
+		}
+		offset += len(tokenizer.Raw())
+	}
+}
+
+// countElements returns the number of element nodes under nodes
+func countElements(nodes ...*html.Node) int {
+	var count int
+	for _, node := range nodes {
+		if node.Type == html.ElementNode {
+			count++
+		}
+		for child := node.FirstChild; child != nil; child = child.NextSibling {
+			count += countElements(child)
+		}
+	}
+	return count
+}
@@ -0,0 +1,40 @@
+package profiler
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseLimits(t *testing.T) {
+	body := []byte("<html><head>" +
+		strings.Repeat("<script>navigator.serviceWorker.register('/sw.js')</script>", 3) +
+		"</head><body>" + strings.Repeat("<div><p>text</p></div>", 10) + "</body></html>")
+
+	tests := []struct {
+		name          string
+		limits        ParseLimits
+		domNodes      int
+		inlineScripts int
+		limitsHit     []Limit
+	}{
+		{name: "unlimited", domNodes: 26, inlineScripts: 3},
+		{name: "under the limits", limits: ParseLimits{MaxDOMNodes: 100, MaxInlineScripts: 3}, domNodes: 26, inlineScripts: 3},
+		{name: "dom nodes", limits: ParseLimits{MaxDOMNodes: 10}, domNodes: 10, inlineScripts: 3, limitsHit: []Limit{LimitDOMNodes}},
+		{name: "inline scripts", limits: ParseLimits{MaxInlineScripts: 1}, domNodes: 26, inlineScripts: 1, limitsHit: []Limit{LimitInlineScripts}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			wappalyzer, err := New(WithParseLimits(tt.limits), WithDisabledVectors(VectorAssets))
+			require.NoError(t, err, "could not create wappalyzer")
+
+			stats := wappalyzer.AnalyzeWithPipeline(&http.Response{Header: http.Header{}}, body).GetStats()
+			require.Equal(t, tt.domNodes, stats.DOMNodes, "wrong number of dom nodes")
+			require.Equal(t, tt.inlineScripts, stats.InlineScripts, "wrong number of inline scripts")
+			require.Equal(t, tt.limitsHit, stats.LimitsHit, "wrong limits hit")
+		})
+	}
+}
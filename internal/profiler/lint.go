@@ -0,0 +1,331 @@
+package profiler
+
+import (
+	"fmt"
+	"regexp/syntax"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// PatternIssue kinds reported by LintPattern
+const (
+	// IssueNestedQuantifier is an unbounded quantifier applied to a group that
+	// contains another one, such as (\w+\.)+
+	IssueNestedQuantifier = "nested-quantifier"
+	// IssueQuantifiedAlternation is an unbounded quantifier applied to an
+	// alternation, such as (?:a|b|c)+
+	IssueQuantifiedAlternation = "quantified-alternation"
+	// IssueLeadingWildcard and IssueTrailingWildcard are .* at either end of
+	// a pattern, which only make the match longer
+	IssueLeadingWildcard  = "leading-wildcard"
+	IssueTrailingWildcard = "trailing-wildcard"
+	// IssueTooComplex is a pattern whose compiled program is too large to be
+	// matched quickly on a large page, or that does not compile at all
+	IssueTooComplex = "too-complex"
+)
+
+// lintRepeatLimit bounds the quantifiers LintPattern rewrites, like the
+// limited version captures of ParsePattern
+const lintRepeatLimit = 20
+
+// lintMaxInstructions is the size of the compiled program above which a pattern
+// is rejected as too complex
+const lintMaxInstructions = 20000
+
+// PatternIssue is a construct of a pattern that makes it slow to match
+type PatternIssue struct {
+	Kind string `json:"kind"`
+	// Construct is the offending part of the pattern
+	Construct string `json:"construct"`
+}
+
+// PatternLint is the result of linting a fingerprint pattern
+type PatternLint struct {
+	Issues []PatternIssue `json:"issues,omitempty"`
+	// Rewritten is the pattern with its issues fixed, if it was rewritten
+	Rewritten string `json:"rewritten,omitempty"`
+	// Rejected is set if the pattern could not be fixed and should be dropped
+	Rejected bool `json:"rejected,omitempty"`
+}
+
+// LintPattern checks a fingerprint pattern for constructs that make its regex
+// slow on large inputs. Nested quantifiers and quantified alternations are
+// rewritten with bounded quantifiers, as ParsePattern does for the version
+// captures, and leading or trailing .* are removed. Patterns still too complex
+// after that, or that do not compile, are rejected.
+func LintPattern(pattern string) PatternLint {
+	var lint PatternLint
+	regex, options, hasOptions := strings.Cut(pattern, "\\;")
+	if regex == "" {
+		return lint
+	}
+
+	// The version captures are already bounded by ParsePattern
+	regex = strings.ReplaceAll(regex, verCap1, verCap1Fill)
+	regex = strings.ReplaceAll(regex, verCap2, verCap2Fill)
+
+	rewritten, issues := lintRegex(regex)
+	lint.Issues = issues
+
+	rewritten = strings.ReplaceAll(rewritten, verCap1Fill, verCap1)
+	rewritten = strings.ReplaceAll(rewritten, verCap2Fill, verCap2)
+	if hasOptions {
+		rewritten += "\\;" + options
+	}
+	if rewritten != pattern {
+		lint.Rewritten = rewritten
+	}
+
+	if construct, ok := tooComplex(rewritten); ok {
+		lint.Issues = append(lint.Issues, PatternIssue{Kind: IssueTooComplex, Construct: construct})
+		lint.Rejected = true
+		lint.Rewritten = ""
+	}
+	return lint
+}
+
+// tooComplex reports whether pattern, as compiled by ParsePattern, does not
+// compile or compiles to too large a program, describing why
+func tooComplex(pattern string) (string, bool) {
+	parsed, err := ParsePattern(pattern)
+	if err != nil {
+		return err.Error(), true
+	}
+	if parsed.regex == nil {
+		return "", false
+	}
+	re, err := syntax.Parse(parsed.regex.String(), syntax.Perl)
+	if err != nil {
+		return err.Error(), true
+	}
+	prog, err := syntax.Compile(re.Simplify())
+	if err != nil {
+		return err.Error(), true
+	}
+	if len(prog.Inst) > lintMaxInstructions {
+		return fmt.Sprintf("%d instructions", len(prog.Inst)), true
+	}
+	return "", false
+}
+
+// lintGroup is a parenthesized group of a regex being linted
+type lintGroup struct {
+	open        int // Position of the opening parenthesis
+	alternation bool
+	// unbounded are the unbounded quantifiers inside the group
+	unbounded []*lintQuantifier
+}
+
+// lintQuantifier is a quantifier of a regex being linted
+type lintQuantifier struct {
+	start, end int // Position of the quantifier, with its lazy suffix
+	min        int
+	unbounded  bool
+	lazy       bool
+	group      *lintGroup // Group the quantifier applies to, if any
+	atom       int        // Position of the atom the quantifier applies to
+	depth      int        // Number of groups the quantifier is in
+}
+
+// bounded returns the quantifier with its maximum bounded
+func (q *lintQuantifier) bounded() string {
+	bounded := fmt.Sprintf("{%d,%d}", q.min, max(q.min, lintRepeatLimit))
+	if q.lazy {
+		bounded += "?"
+	}
+	return bounded
+}
+
+// lintRegex finds the issues of a regex and rewrites it to fix them
+func lintRegex(regex string) (string, []PatternIssue) {
+	quantifiers := scanQuantifiers(regex)
+
+	var issues []PatternIssue
+	replacements := make(map[*lintQuantifier]string)
+	for _, q := range quantifiers {
+		if !q.unbounded || q.group == nil {
+			continue
+		}
+		construct := regex[q.atom:q.end]
+		switch {
+		case len(q.group.unbounded) > 0:
+			issues = append(issues, PatternIssue{Kind: IssueNestedQuantifier, Construct: construct})
+			replacements[q] = q.bounded()
+			for _, inner := range q.group.unbounded {
+				replacements[inner] = inner.bounded()
+			}
+		case q.group.alternation:
+			issues = append(issues, PatternIssue{Kind: IssueQuantifiedAlternation, Construct: construct})
+			replacements[q] = q.bounded()
+		}
+	}
+
+	// A .* at either end of the regex only makes the match longer, which
+	// matters neither for detection nor for the version captures
+	var strip []*lintQuantifier
+	for _, q := range quantifiers {
+		if q.group != nil || q.min != 0 || !q.unbounded || q.depth > 0 || regex[q.atom] != '.' {
+			continue
+		}
+		if q.atom == 0 && q.end < len(regex) && regex[q.end] != '|' {
+			issues = append(issues, PatternIssue{Kind: IssueLeadingWildcard, Construct: regex[q.atom:q.end]})
+			strip = append(strip, q)
+		} else if q.end == len(regex) && q.atom > 0 && regex[q.atom-1] != '|' && regex[q.atom-1] != '^' {
+			issues = append(issues, PatternIssue{Kind: IssueTrailingWildcard, Construct: regex[q.atom:q.end]})
+			strip = append(strip, q)
+		}
+	}
+
+	type edit struct {
+		start, end int
+		text       string
+	}
+	var edits []edit
+	for q, text := range replacements {
+		edits = append(edits, edit{q.start, q.end, text})
+	}
+	for _, q := range strip {
+		edits = append(edits, edit{q.atom, q.end, ""})
+	}
+	sort.Slice(edits, func(i, j int) bool { return edits[i].start > edits[j].start })
+
+	rewritten := regex
+	for _, e := range edits {
+		rewritten = rewritten[:e.start] + e.text + rewritten[e.end:]
+	}
+	return rewritten, issues
+}
+
+// scanQuantifiers returns the quantifiers of a regex, with the groups they
+// apply to and are nested in. Escapes and character classes are skipped.
+func scanQuantifiers(regex string) []*lintQuantifier {
+	var quantifiers []*lintQuantifier
+	var stack []*lintGroup
+	var lastGroup *lintGroup // Group closed right before the current position
+	atom := -1               // Start of the last atom
+
+	for i := 0; i < len(regex); {
+		c := regex[i]
+		switch c {
+		case '\\':
+			atom, lastGroup = i, nil
+			i += 2
+			continue
+		case '[':
+			atom, lastGroup = i, nil
+			i = skipClass(regex, i)
+			continue
+		case '(':
+			stack = append(stack, &lintGroup{open: i})
+			i++
+			if i < len(regex) && regex[i] == '?' {
+				// Skip the flags or name of the group
+				for i < len(regex) && regex[i] != ':' && regex[i] != ')' && regex[i] != '>' {
+					i++
+				}
+				if i < len(regex) && regex[i] == ')' {
+					// A flag group such as (?i) holds nothing
+					stack = stack[:len(stack)-1]
+				}
+				i++
+			}
+			atom, lastGroup = -1, nil
+			continue
+		case ')':
+			if len(stack) > 0 {
+				lastGroup = stack[len(stack)-1]
+				stack = stack[:len(stack)-1]
+				// Whatever is inside the group is inside its parents too
+				if len(stack) > 0 {
+					parent := stack[len(stack)-1]
+					parent.unbounded = append(parent.unbounded, lastGroup.unbounded...)
+				}
+			}
+			atom = -1
+			i++
+			continue
+		case '|':
+			if len(stack) > 0 {
+				stack[len(stack)-1].alternation = true
+			}
+			atom, lastGroup = -1, nil
+			i++
+			continue
+		case '*', '+', '?', '{':
+			q, ok := parseQuantifier(regex, i)
+			if !ok {
+				break
+			}
+			q.group = lastGroup
+			q.depth = len(stack)
+			q.atom = atom
+			if lastGroup != nil {
+				q.atom = lastGroup.open
+			}
+			if q.atom >= 0 {
+				quantifiers = append(quantifiers, q)
+				if q.unbounded && len(stack) > 0 {
+					stack[len(stack)-1].unbounded = append(stack[len(stack)-1].unbounded, q)
+				}
+			}
+			atom, lastGroup = -1, nil
+			i = q.end
+			continue
+		}
+		atom, lastGroup = i, nil
+		i++
+	}
+	return quantifiers
+}
+
+// parseQuantifier parses the quantifier at position i of regex, if any
+func parseQuantifier(regex string, i int) (*lintQuantifier, bool) {
+	q := &lintQuantifier{start: i, end: i + 1}
+	switch regex[i] {
+	case '*':
+		q.unbounded = true
+	case '+':
+		q.min, q.unbounded = 1, true
+	case '?':
+	case '{':
+		end := strings.IndexByte(regex[i:], '}')
+		if end < 0 {
+			return nil, false
+		}
+		low, high, hasComma := strings.Cut(regex[i+1:i+end], ",")
+		min, err := strconv.Atoi(low)
+		if err != nil {
+			return nil, false
+		}
+		if hasComma && high != "" {
+			if _, err := strconv.Atoi(high); err != nil {
+				return nil, false
+			}
+		}
+		q.min, q.unbounded, q.end = min, hasComma && high == "", i+end+1
+	}
+	if q.end < len(regex) && regex[q.end] == '?' {
+		q.lazy = true
+		q.end++
+	}
+	return q, true
+}
+
+// skipClass returns the position after the character class starting at i
+func skipClass(regex string, i int) int {
+	i++
+	if i < len(regex) && regex[i] == '^' {
+		i++
+	}
+	if i < len(regex) && regex[i] == ']' {
+		i++
+	}
+	for i < len(regex) && regex[i] != ']' {
+		if regex[i] == '\\' {
+			i++
+		}
+		i++
+	}
+	return i + 1
+}
@@ -0,0 +1,70 @@
+package profiler
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLintPattern(t *testing.T) {
+	tests := []struct {
+		name      string
+		pattern   string
+		kinds     []string
+		rewritten string
+		rejected  bool
+	}{
+		{name: "clean", pattern: `wp-content/themes/([\w-]+)/`},
+		{name: "options only", pattern: `\;confidence:50`},
+		{name: "version capture", pattern: `jquery-(\d+(?:\.\d+)+)\.js\;version:\1`},
+		{name: "bounded", pattern: `(?:\w{1,10}\.){1,5}js`},
+		{
+			name:      "nested quantifier",
+			pattern:   `cdn/(?:[\w-]+/)+app\.js\;version:\1`,
+			kinds:     []string{IssueNestedQuantifier},
+			rewritten: `cdn/(?:[\w-]{1,20}/){1,20}app\.js\;version:\1`,
+		},
+		{
+			name:      "nested lazy quantifier",
+			pattern:   `x(?:a(?:bc)+)*?y`,
+			kinds:     []string{IssueNestedQuantifier},
+			rewritten: `x(?:a(?:bc){1,20}){0,20}?y`,
+		},
+		{
+			name:      "quantified alternation",
+			pattern:   `(?:foo|bar|[+-])+baz`,
+			kinds:     []string{IssueQuantifiedAlternation},
+			rewritten: `(?:foo|bar|[+-]){1,20}baz`,
+		},
+		{name: "alternation in class", pattern: `[(|)]+`},
+		{name: "escaped parenthesis", pattern: `\(a+\)+`},
+		{
+			name:      "wildcards",
+			pattern:   `.*altcha\.(?:org|js).*`,
+			kinds:     []string{IssueLeadingWildcard, IssueTrailingWildcard},
+			rewritten: `altcha\.(?:org|js)`,
+		},
+		{name: "anchored wildcard", pattern: `^.*`},
+		{name: "wildcard branch", pattern: `foo|.*`},
+		{name: "does not compile", pattern: `(?!angular\.io)angular\.js`, kinds: []string{IssueTooComplex}, rejected: true},
+		{name: "too large", pattern: `(?:a{1,999}){1,999}`, kinds: []string{IssueTooComplex}, rejected: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			lint := LintPattern(test.pattern)
+			var kinds []string
+			for _, issue := range lint.Issues {
+				kinds = append(kinds, issue.Kind)
+			}
+			require.Equal(t, test.kinds, kinds, "wrong issues")
+			require.Equal(t, test.rewritten, lint.Rewritten, "wrong rewrite")
+			require.Equal(t, test.rejected, lint.Rejected, "wrong rejection")
+
+			if test.rewritten != "" {
+				_, err := ParsePattern(test.rewritten)
+				require.NoError(t, err, "rewritten pattern should compile")
+			}
+		})
+	}
+}
@@ -0,0 +1,240 @@
+package profiler
+
+import (
+	"regexp/syntax"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+// urlParts are the vectors matched against URLs, whose patterns are indexed
+// by the literal they require, such as a hostname or a file name
+var urlParts = []part{scriptPart, iframePart, xhrPart, linkHrefPart}
+
+// minIndexedLiteral is the length of the shortest literal indexed
+const minIndexedLiteral = 3
+
+// literalIndexes are the literal indexes of the URL vectors of a set of
+// fingerprints, built on first use
+type literalIndexes struct {
+	once    sync.Once
+	indexes map[part]*literalIndex
+}
+
+// get returns the index of vector, or nil if vector is not indexed
+func (l *literalIndexes) get(f *CompiledFingerprints, vector part) *literalIndex {
+	if l == nil {
+		return nil
+	}
+	l.once.Do(func() {
+		l.indexes = make(map[part]*literalIndex, len(urlParts))
+		for _, part := range urlParts {
+			var patterns []*ParsedPattern
+			for _, fingerprint := range f.Apps {
+				patterns = append(patterns, fingerprint.urlPatterns(part)...)
+			}
+			l.indexes[part] = newLiteralIndex(patterns)
+		}
+	})
+	return l.indexes[vector]
+}
+
+// urlPatterns returns the patterns of the fingerprint for a URL vector
+func (f *CompiledFingerprint) urlPatterns(part part) []*ParsedPattern {
+	switch part {
+	case scriptPart:
+		return f.scriptSrc
+	case iframePart:
+		return f.iframe
+	case xhrPart:
+		return f.xhr
+	case linkHrefPart:
+		return f.linkHref
+	}
+	return nil
+}
+
+// literalIndex is an inverted index of the patterns of a vector by the rarest
+// trigram of the literal every match of theirs contains. Looking the trigrams
+// of an input up tells the few patterns that can match it, so the regexes of
+// the others need not run.
+type literalIndex struct {
+	// byTrigram are the indexed patterns and their literal, by trigram
+	byTrigram map[string][]indexedPattern
+	// indexed are the patterns with a literal, the others always run
+	indexed map[*ParsedPattern]struct{}
+}
+
+// indexedPattern is a pattern of a literal index
+type indexedPattern struct {
+	pattern *ParsedPattern
+	literal string
+}
+
+// newLiteralIndex indexes patterns by their required literal
+func newLiteralIndex(patterns []*ParsedPattern) *literalIndex {
+	index := &literalIndex{
+		byTrigram: make(map[string][]indexedPattern),
+		indexed:   make(map[*ParsedPattern]struct{}),
+	}
+
+	var entries []indexedPattern
+	frequency := make(map[string]int)
+	for _, pattern := range patterns {
+		literal := pattern.requiredLiteral()
+		if len(literal) < minIndexedLiteral {
+			continue
+		}
+		entries = append(entries, indexedPattern{pattern: pattern, literal: literal})
+		for _, trigram := range trigrams(literal) {
+			frequency[trigram]++
+		}
+	}
+
+	// The rarest trigram of each literal yields the fewest false candidates
+	for _, entry := range entries {
+		var rarest string
+		for _, trigram := range trigrams(entry.literal) {
+			if rarest == "" || frequency[trigram] < frequency[rarest] {
+				rarest = trigram
+			}
+		}
+		index.byTrigram[rarest] = append(index.byTrigram[rarest], entry)
+		index.indexed[entry.pattern] = struct{}{}
+	}
+	return index
+}
+
+// filter returns the filter of the patterns worth evaluating against input.
+// Inputs with non-ASCII characters, which case folding may match with ASCII
+// literals, are not filtered.
+func (x *literalIndex) filter(input string) literalFilter {
+	if x == nil || len(x.indexed) == 0 {
+		return literalFilter{}
+	}
+	for i := 0; i < len(input); i++ {
+		if input[i] >= utf8.RuneSelf {
+			return literalFilter{}
+		}
+	}
+
+	input = strings.ToLower(input)
+	matched := make(map[*ParsedPattern]struct{})
+	seen := make(map[string]struct{}, len(input))
+	for i := 0; i+3 <= len(input); i++ {
+		trigram := input[i : i+3]
+		if _, ok := seen[trigram]; ok {
+			continue
+		}
+		seen[trigram] = struct{}{}
+		for _, entry := range x.byTrigram[trigram] {
+			if strings.Contains(input, entry.literal) {
+				matched[entry.pattern] = struct{}{}
+			}
+		}
+	}
+	return literalFilter{index: x, matched: matched}
+}
+
+// literalFilter tells the patterns that can match an input. The zero value
+// allows every pattern.
+type literalFilter struct {
+	index   *literalIndex
+	matched map[*ParsedPattern]struct{}
+}
+
+// allows reports whether pattern can match the input of the filter
+func (f literalFilter) allows(pattern *ParsedPattern) bool {
+	if f.index == nil {
+		return true
+	}
+	if _, ok := f.index.indexed[pattern]; !ok {
+		return true
+	}
+	_, ok := f.matched[pattern]
+	return ok
+}
+
+// trigrams returns the distinct substrings of three bytes of s
+func trigrams(s string) []string {
+	var result []string
+	seen := make(map[string]struct{})
+	for i := 0; i+3 <= len(s); i++ {
+		if _, ok := seen[s[i:i+3]]; !ok {
+			seen[s[i:i+3]] = struct{}{}
+			result = append(result, s[i:i+3])
+		}
+	}
+	return result
+}
+
+// requiredLiteral returns the longest lower case ASCII literal every match of
+// the pattern contains, or an empty string if there is none
+func (p *ParsedPattern) requiredLiteral() string {
+	if p.SkipRegex {
+		return ""
+	}
+	source := p.source
+	if source == "" {
+		if p.regex == nil {
+			return ""
+		}
+		source = p.regex.String()
+	}
+	re, err := syntax.Parse(source, syntax.Perl)
+	if err != nil {
+		return ""
+	}
+	return requiredLiteral(re)
+}
+
+// requiredLiteral returns the longest lower case ASCII literal every match of
+// re contains
+func requiredLiteral(re *syntax.Regexp) string {
+	switch re.Op {
+	case syntax.OpLiteral:
+		return asciiLiteral(re.Rune)
+	case syntax.OpCapture, syntax.OpPlus:
+		return requiredLiteral(re.Sub[0])
+	case syntax.OpRepeat:
+		if re.Min >= 1 {
+			return requiredLiteral(re.Sub[0])
+		}
+	case syntax.OpConcat:
+		// Consecutive literals form a longer one
+		var longest, run string
+		for _, sub := range re.Sub {
+			if sub.Op == syntax.OpLiteral {
+				if literal := asciiLiteral(sub.Rune); len(literal) == len(sub.Rune) {
+					run += literal
+					continue
+				}
+			}
+			longest = longer(longest, run)
+			run = ""
+			longest = longer(longest, requiredLiteral(sub))
+		}
+		return longer(longest, run)
+	}
+	return ""
+}
+
+// asciiLiteral returns the lower case of the longest ASCII prefix of runes
+func asciiLiteral(runes []rune) string {
+	var literal strings.Builder
+	for _, r := range runes {
+		if r >= utf8.RuneSelf {
+			break
+		}
+		literal.WriteRune(r)
+	}
+	return strings.ToLower(literal.String())
+}
+
+// longer returns the longer of a and b
+func longer(a, b string) string {
+	if len(b) > len(a) {
+		return b
+	}
+	return a
+}
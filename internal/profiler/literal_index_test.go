@@ -0,0 +1,58 @@
+package profiler
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRequiredLiteral(t *testing.T) {
+	tests := []struct {
+		pattern  string
+		expected string
+	}{
+		{pattern: `googletagmanager\.com/gtag/js`, expected: "googletagmanager.com/gtag/js"},
+		{pattern: `/wp-(?:content|includes)/`, expected: "/wp-"},
+		{pattern: `jquery[.-]([\d.]+)(?:\.min)?\.js\;version:\1`, expected: "jquery"},
+		{pattern: `(?:cdn|static)\.example\.net`, expected: ".example.net"},
+		{pattern: `Shopify\.com`, expected: "shopify.com"},
+		{pattern: `(?:foo)?barbaz`, expected: "barbaz"},
+		{pattern: `a|b`},
+		{pattern: `\;confidence:50`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.pattern, func(t *testing.T) {
+			pattern, err := ParsePattern(tt.pattern)
+			require.NoError(t, err, "could not parse pattern")
+			require.Equal(t, tt.expected, pattern.requiredLiteral(), "wrong literal")
+		})
+	}
+}
+
+func TestLiteralIndex(t *testing.T) {
+	wappalyzer, err := New()
+	require.NoError(t, err, "could not create wappalyzer")
+
+	unindexed := *wappalyzer.fingerprints
+	unindexed.literalIndexes = nil
+
+	urls := []string{
+		"https://www.googletagmanager.com/gtag/js?id=G-ABC123",
+		"https://cdn.jsdelivr.net/npm/bootstrap@5.3.2/dist/js/bootstrap.bundle.min.js",
+		"/wp-includes/js/jquery/jquery.min.js?ver=3.7.1",
+		"https://cdn.shopify.com/s/files/1/0001/t/1/assets/theme.js",
+		"/_next/static/chunks/pages/_app-1234abcd.js",
+		"https://connect.facebook.net/en_US/fbevents.js",
+		"https://static.example.com/ünïcode/app.js",
+		"",
+	}
+	for _, url := range urls {
+		require.ElementsMatch(t, unindexed.matchString(url, scriptPart, wappalyzer.regexTimeout),
+			wappalyzer.fingerprints.matchString(url, scriptPart, wappalyzer.regexTimeout), "index changed the matches of %q", url)
+	}
+
+	index := wappalyzer.fingerprints.literalIndexes.get(wappalyzer.fingerprints, scriptPart)
+	require.NotEmpty(t, index.indexed, "no scriptSrc pattern indexed")
+	require.Nil(t, wappalyzer.fingerprints.literalIndexes.get(wappalyzer.fingerprints, htmlPart), "html should not be indexed")
+}
@@ -0,0 +1,18 @@
+package profiler
+
+import (
+	"context"
+	"log/slog"
+)
+
+// discardHandler is a slog handler that drops every record. It is the
+// default, so the library stays silent unless WithLogger is used.
+type discardHandler struct{}
+
+func (discardHandler) Enabled(context.Context, slog.Level) bool  { return false }
+func (discardHandler) Handle(context.Context, slog.Record) error { return nil }
+func (h discardHandler) WithAttrs([]slog.Attr) slog.Handler      { return h }
+func (h discardHandler) WithGroup(string) slog.Handler           { return h }
+
+// discardLogger is the logger of instances created without WithLogger
+var discardLogger = slog.New(discardHandler{})
@@ -0,0 +1,47 @@
+package profiler
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithLogger(t *testing.T) {
+	var logs bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&logs, &slog.HandlerOptions{Level: slog.LevelDebug}))
+
+	path := filepath.Join(t.TempDir(), "fingerprints.json")
+	data := `{"apps": {"Example": {"headers": {"x-example": ""}, "html": ["<div(", "example"]}}}`
+	require.NoError(t, os.WriteFile(path, []byte(data), 0o600), "could not write fingerprints")
+
+	wappalyzer, err := NewFromFile(path, false, false, WithLogger(logger))
+	require.NoError(t, err, "could not create wappalyzer from file")
+	require.Contains(t, logs.String(), `"msg":"dropped fingerprint pattern","app":"Example","vector":"html","pattern":"<div("`, "dropped pattern not logged")
+	require.Len(t, wappalyzer.fingerprints.Apps["Example"].html, 1, "valid pattern should be kept")
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Example", "1")
+	}))
+	server.Close()
+
+	logs.Reset()
+	_, err = wappalyzer.FingerprintURL(context.Background(), server.URL)
+	require.Error(t, err, "closed server should fail")
+	require.Contains(t, logs.String(), `"msg":"fetch failed","url":"`+server.URL+`"`, "fetch failure not logged")
+
+	logs.Reset()
+	wappalyzer.Fingerprint(map[string][]string{"X-Example": {"1"}}, []byte("<html></html>"))
+	require.Contains(t, logs.String(), `"msg":"matched vector","vector":"headers"`, "matcher timing not logged")
+
+	// The library is silent by default
+	silent, err := NewFromFile(path, false, false)
+	require.NoError(t, err, "could not create wappalyzer from file")
+	require.False(t, silent.logger.Enabled(context.Background(), slog.LevelError), "default logger should discard")
+}
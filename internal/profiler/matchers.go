@@ -0,0 +1,109 @@
+package profiler
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// matcher is a detection vector whose matching does not depend on the others
+type matcher struct {
+	vector part
+	match  func() []matchPartResult
+}
+
+// run matches the vector and records the matching time. A panic of the
+// matcher, such as one raised by a pathological document or selector, is
+// recorded as an error of the analysis and yields no result, so the other
+// vectors of the analysis still run.
+func (m matcher) run(stats *statsRecorder) (results []matchPartResult) {
+	start := time.Now()
+	defer func() { stats.addMatch(m.vector, len(results), time.Since(start)) }()
+	defer stats.recoverPanic(m.vector)
+	return m.match()
+}
+
+// runMatchers runs the matchers over a pool of at most workers goroutines and
+// returns their results in the order of the matchers, so detections are set in
+// the same order whatever the pool size. Each matcher writes its own slot of
+// the results, which are merged without locking once all of them are done.
+func runMatchers(matchers []matcher, workers int, stats *statsRecorder) []matchPartResult {
+	results := make([][]matchPartResult, len(matchers))
+	forEach(len(matchers), workers, func(i int) {
+		results[i] = matchers[i].run(stats)
+	})
+
+	var count int
+	for _, result := range results {
+		count += len(result)
+	}
+	merged := make([]matchPartResult, 0, count)
+	for _, result := range results {
+		merged = append(merged, result...)
+	}
+	return merged
+}
+
+// forEach calls fn with each index below count, from at most workers goroutines
+func forEach(count, workers int, fn func(i int)) {
+	workers = min(workers, count)
+	if workers <= 1 {
+		for i := 0; i < count; i++ {
+			fn(i)
+		}
+		return
+	}
+
+	next := make(chan int, count)
+	for i := 0; i < count; i++ {
+		next <- i
+	}
+	close(next)
+
+	var wg sync.WaitGroup
+	wg.Add(workers)
+	for w := 0; w < workers; w++ {
+		go func() {
+			defer wg.Done()
+			for i := range next {
+				fn(i)
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+// settledApps are the technologies an analysis detected at full confidence
+// with a version, whose remaining patterns the vectors still to be matched
+// skip. It is safe for concurrent use; a nil set holds nothing.
+type settledApps struct {
+	apps sync.Map
+}
+
+// has reports whether app is settled
+func (s *settledApps) has(app string) bool {
+	if s == nil {
+		return false
+	}
+	_, ok := s.apps.Load(app)
+	return ok
+}
+
+// add settles app
+func (s *settledApps) add(app string) {
+	if s != nil {
+		s.apps.Store(app, struct{}{})
+	}
+}
+
+// forAnalysis returns a copy of the instance for the duration of an analysis,
+// whose fingerprints skip the technologies of settled, if set, and count the
+// regex evaluations that time out in regexTimeouts
+func (s *Wappalyze) forAnalysis(settled *settledApps, regexTimeouts *atomic.Int64) *Wappalyze {
+	fingerprints := *s.fingerprints
+	fingerprints.settled = settled
+	fingerprints.regexTimeouts = regexTimeouts
+	analysis := *s
+	analysis.fingerprints = &fingerprints
+	return &analysis
+}
@@ -0,0 +1,95 @@
+package profiler
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunMatchers(t *testing.T) {
+	var matchers []matcher
+	var expected []matchPartResult
+	for i := 0; i < 20; i++ {
+		app := matchPartResult{application: fmt.Sprintf("app%d", i)}
+		matchers = append(matchers, matcher{htmlPart, func() []matchPartResult { return []matchPartResult{app, app} }})
+		expected = append(expected, app, app)
+	}
+
+	for _, workers := range []int{0, 1, 4, 100} {
+		stats := newStatsRecorder()
+		require.Equal(t, expected, runMatchers(matchers, workers, stats), "results should keep the order of the matchers with %d workers", workers)
+		require.Contains(t, stats.finish().MatchDurations, htmlPart.String(), "matching time should be recorded")
+	}
+	require.Empty(t, runMatchers(nil, 4, nil), "no matchers should match nothing")
+}
+
+func TestMatcherPanic(t *testing.T) {
+	app := matchPartResult{application: "app"}
+	matchers := []matcher{
+		{htmlPart, func() []matchPartResult { return []matchPartResult{app} }},
+		{domPart, func() []matchPartResult { panic("pathological document") }},
+		{metaPart, func() []matchPartResult { return []matchPartResult{app} }},
+	}
+
+	for _, workers := range []int{1, 4} {
+		stats := newStatsRecorder()
+		stats.url = "https://example.com"
+		require.Equal(t, []matchPartResult{app, app}, runMatchers(matchers, workers, stats), "other matchers should still match with %d workers", workers)
+
+		panics := stats.matcherPanics()
+		require.Len(t, panics, 1, "panic not recorded")
+		require.ErrorIs(t, panics[0], ErrMatcherPanic, "wrong error")
+		var analysisErr *AnalysisError
+		require.True(t, errors.As(panics[0], &analysisErr), "not an analysis error")
+		require.Equal(t, StageMatch, analysisErr.Stage, "wrong stage")
+		require.Equal(t, "https://example.com", analysisErr.URL, "wrong url")
+		require.ErrorContains(t, panics[0], "pathological document", "panic value not reported")
+		require.Contains(t, stats.finish().MatchDurations, domPart.String(), "matching time should be recorded")
+	}
+	require.Empty(t, runMatchers(matchers[1:2], 1, nil), "a nil recorder should still recover")
+}
+
+func TestMatchWorkers(t *testing.T) {
+	body := []byte(`<html><head><meta name="generator" content="WordPress 6.4">
+		<script src="/wp-includes/js/jquery/jquery.min.js?ver=3.7.1"></script>
+		<link rel="stylesheet" href="/wp-content/themes/twentytwentyfour/style.css"></head>
+		<body class="wp-site"><noscript><img src="https://www.facebook.com/tr?id=1"></noscript></body></html>`)
+	headers := map[string][]string{"Server": {"nginx/1.25.0"}, "X-Powered-By": {"PHP/8.2.0"}}
+
+	var results []map[string]struct{}
+	for _, workers := range []int{1, 8} {
+		wappalyzer, err := New(WithMatchWorkers(workers))
+		require.NoError(t, err, "could not create wappalyzer")
+		results = append(results, wappalyzer.Fingerprint(headers, body))
+	}
+	require.Contains(t, results[0], "WordPress:6.4", "could not detect cms")
+	require.Equal(t, results[0], results[1], "results should not depend on the number of workers")
+}
+
+func TestSettledApps(t *testing.T) {
+	wappalyzer, err := New()
+	require.NoError(t, err, "could not create wappalyzer")
+
+	settled := &settledApps{}
+	detections := NewUniqueFingerprints()
+	detections.settled = settled
+	detections.SetWithVector("Nginx", "", 100, headersPart.String())
+	require.False(t, settled.has("Nginx"), "technology without a version settled")
+	detections.SetWithVector("PHP", "8.1", 100, "implies")
+	require.False(t, settled.has("PHP"), "implied technology settled")
+	detections.SetWithVector("Nginx", "1.25.3", 100, headersPart.String())
+	require.True(t, settled.has("Nginx"), "technology not settled")
+
+	headers := map[string]string{"server": "nginx/1.25.3"}
+	analysis := wappalyzer.forAnalysis(settled, nil)
+	for _, match := range analysis.checkHeaders(headers) {
+		require.NotEqual(t, "Nginx", match.application, "settled technology matched")
+	}
+	var matched bool
+	for _, match := range wappalyzer.checkHeaders(headers) {
+		matched = matched || match.application == "Nginx"
+	}
+	require.True(t, matched, "instance fingerprints should not skip settled technologies")
+}
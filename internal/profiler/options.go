@@ -0,0 +1,302 @@
+package profiler
+
+import (
+	"context"
+	"log/slog"
+	"net/netip"
+	"slices"
+	"time"
+)
+
+// Option configures optional behaviour of a Wappalyze instance
+type Option func(*Wappalyze)
+
+// WithErrorPageProbing enables an extra analysis stage that requests a
+// guaranteed-nonexistent path and sends an intentionally malformed request,
+// then matches the resulting error pages and headers against a dedicated ruleset.
+//
+// Probing sends additional requests to the target, so it is disabled by default.
+func WithErrorPageProbing(enabled bool) Option {
+	return func(s *Wappalyze) {
+		s.errorPageProbing = enabled
+	}
+}
+
+// WithHeaderOrderProbing enables an extra analysis stage that re-requests the
+// target over a raw HTTP/1.1 connection to capture the response header names in
+// wire order and casing, then matches them against a heuristic ruleset. The
+// connection goes through the target and address checks, the IP family, the
+// TLS policy, the rate limit and the user agent of the instance, but not
+// through transport middleware, which only wraps HTTP round trips.
+//
+// Probing sends an additional request to the target, so it is disabled by default.
+func WithHeaderOrderProbing(enabled bool) Option {
+	return func(s *Wappalyze) {
+		s.headerOrderProbing = enabled
+	}
+}
+
+// WithPortProbing enables an extra analysis stage that requests the root of
+// the host of the target on each of ports, or DefaultProbePorts if none are
+// given, over HTTPS and then plain HTTP. The headers, cookies and body of the
+// ports that respond are matched, since appliance admin consoles and
+// application servers are often only fingerprintable there.
+//
+// Probing sends requests to other services of the target, so it is disabled
+// by default. The fast profile never probes.
+func WithPortProbing(ports ...int) Option {
+	return func(s *Wappalyze) {
+		if len(ports) == 0 {
+			ports = DefaultProbePorts
+		}
+		s.probePortList = slices.Clone(ports)
+	}
+}
+
+// WithRetryPolicy retries transient failures of the main page, robots.txt and
+// asset fetches according to policy, honoring Retry-After on retried statuses.
+// Retries are disabled by default; see DefaultRetryPolicy for sensible values.
+func WithRetryPolicy(policy RetryPolicy) Option {
+	return func(s *Wappalyze) {
+		s.retryPolicy = policy
+	}
+}
+
+// WithSchemeFallback retries a failed page fetch over the other scheme: a target
+// that fails over HTTPS, for example at the TLS layer, is retried over HTTP and
+// vice versa. The scheme that succeeded is recorded in the protocol metadata.
+// Bare hostnames are always tried over HTTPS first.
+func WithSchemeFallback(enabled bool) Option {
+	return func(s *Wappalyze) {
+		s.schemeFallback = enabled
+	}
+}
+
+// WithTargetCheck calls check with each URL the instance is about to request:
+// the target and its scheme fallback, every redirect, and the resources and
+// probes of the analysis. A URL check refuses is not requested. When the page
+// itself is refused, FingerprintURL returns an *AnalysisError wrapping
+// ErrTargetNotAllowed and the error of check. Servers use it to enforce which
+// targets may be scanned; see WithAddressCheck for the addresses they resolve to.
+func WithTargetCheck(check func(ctx context.Context, targetURL string) error) Option {
+	return func(s *Wappalyze) {
+		s.targetCheck = check
+	}
+}
+
+// WithAddressCheck calls check with each address the instance is about to
+// connect to, once the host is resolved, and refuses the connection if it
+// returns an error, so hosts resolving to refused ranges are never connected
+// to whatever they resolved to when checked. The failed request wraps
+// ErrTargetNotAllowed and the error of check.
+func WithAddressCheck(check func(ctx context.Context, addr netip.Addr) error) Option {
+	return func(s *Wappalyze) {
+		s.addressCheck = check
+	}
+}
+
+// WithOnDetection calls fn the first time each technology is detected during an
+// analysis, with a snapshot of its detection; the final result may add vectors,
+// confidence or a version. Embedders can use it to stream progress, collect
+// metrics, or cancel the analysis context once a technology of interest is found.
+//
+// Calls are serialized with those of WithOnStage, but come from the analysis
+// goroutines, so fn should return quickly.
+func WithOnDetection(fn func(app string, detection Detection)) Option {
+	return func(s *Wappalyze) {
+		s.onDetection = fn
+	}
+}
+
+// WithOnStage calls fn when a stage of the analysis completes, with the name of
+// the Stage and the error it failed with, if any. The main stage is reported by
+// FingerprintURL once the page is fetched; optional stages are only reported
+// when they run.
+func WithOnStage(fn func(stage string, err error)) Option {
+	return func(s *Wappalyze) {
+		s.onStage = fn
+	}
+}
+
+// WithResultCache puts cache in front of FingerprintURL. Results are served from
+// the cache for ttl without contacting the target. Results whose response carried
+// an ETag or Last-Modified header are kept for a further revalidateFor, during
+// which they are revalidated with a conditional request and reused on a
+// 304 Not Modified response, the result then reporting NotModified.
+func WithResultCache(cache ResultCache, ttl, revalidateFor time.Duration) Option {
+	return func(s *Wappalyze) {
+		s.cache = cacheConfig{cache: cache, ttl: ttl, revalidateFor: revalidateFor}
+	}
+}
+
+// WithLogger sets the structured logger of the instance. The library logs at
+// debug level only: fingerprint patterns dropped because they do not compile,
+// regex evaluations that hit the regex timeout, failed fetches of the page and
+// its resources, certificates that fail verification, and the time spent
+// matching each detection vector. Matchers that panic are logged at error level. Records
+// are logged with the analysis context, so handlers can add request attributes.
+// Nothing is logged by default.
+func WithLogger(logger *slog.Logger) Option {
+	return func(s *Wappalyze) {
+		if logger != nil {
+			s.logger = logger
+		}
+	}
+}
+
+// WithProfile sets the profile analyses run with, which decides the vectors
+// they match and the requests they send beyond the page; see Profile.
+// ProfileContext overrides it for a single analysis.
+func WithProfile(profile Profile) Option {
+	return func(s *Wappalyze) {
+		s.profile = profile
+	}
+}
+
+// WithTLSPolicy sets how the page and probe requests treat certificates that
+// do not verify: reported on the result (TLSPolicyLog, the default), refused
+// (TLSPolicyStrict) or not verified at all (TLSPolicyInsecure).
+func WithTLSPolicy(policy TLSPolicy) Option {
+	return func(s *Wappalyze) {
+		s.tlsPolicy = policy
+	}
+}
+
+// WithIPFamily only fetches the page and sends the probes over IPv4 or IPv6,
+// to compare the stacks hosts serve over each. By default either family is
+// used, racing both when the host has addresses of each. The family of the
+// address the page was fetched from is reported by the protocol of the result.
+func WithIPFamily(family IPFamily) Option {
+	return func(s *Wappalyze) {
+		s.ipFamily = family
+	}
+}
+
+// WithTransportMiddleware wraps the transport of every outbound HTTP request
+// of the instance, the page, robots.txt, assets and probes, so embedders can
+// sign requests, cache or record responses, or route them through a proxy.
+// The first middleware is the outermost: it sees each request first. The raw
+// header order probe, which speaks HTTP/1.1 over its own connection, and DNS
+// lookups are not affected. Repeated options append to the chain.
+func WithTransportMiddleware(middleware ...TransportMiddleware) Option {
+	return func(s *Wappalyze) {
+		s.transportMiddleware = append(s.transportMiddleware, middleware...)
+	}
+}
+
+// WithDNSClient sets the client the DNS and email vectors query the public
+// resolvers with, to route DNS through other infrastructure or stub it in
+// tests. The default client times out after 2 seconds.
+func WithDNSClient(client DNSClient) Option {
+	return func(s *Wappalyze) {
+		if client != nil {
+			s.dnsClient = client
+		}
+	}
+}
+
+// WithDNSCache sets the number of DNS answers the instance caches for the TTL
+// of their records, at most an hour, so that analyses of hosts of the same
+// domain look its records up once. It defaults to resolve.DefaultCacheSize,
+// and zero disables the cache.
+func WithDNSCache(entries int) Option {
+	return func(s *Wappalyze) {
+		s.dnsCacheSize = max(entries, 0)
+	}
+}
+
+// WithRecorder records the HTTP and DNS traffic of every analysis of the
+// instance into recorder, below the transport middleware. The raw header
+// order probe is not recorded.
+func WithRecorder(recorder *Recorder) Option {
+	return func(s *Wappalyze) {
+		s.recorder = recorder
+	}
+}
+
+// WithReplay answers the HTTP requests and DNS queries of every analysis of
+// the instance from fixture instead of the network. Requests missing from the
+// fixture fail with ErrNotRecorded, and the header order probe is skipped.
+// Replayed responses carry no connection details: TLS state, certificate and
+// remote address.
+func WithReplay(fixture *Fixture) Option {
+	return func(s *Wappalyze) {
+		s.replay = fixture
+	}
+}
+
+// WithGatherAllEvidence matches every pattern of every technology, so that
+// Detection.DetectedBy lists all the vectors that matched it. By default, once
+// an analysis detects a technology at full confidence with a version, the
+// vectors still to be matched skip its patterns.
+func WithGatherAllEvidence(enabled bool) Option {
+	return func(s *Wappalyze) {
+		s.gatherAllEvidence = enabled
+	}
+}
+
+// WithAssetPolicy limits the scripts, stylesheets, manifests and service workers
+// each analysis fetches: connections and request rate per host, and the number
+// and total size of assets. There are no limits by default; see
+// DefaultAssetPolicy for sensible values.
+func WithAssetPolicy(policy AssetPolicy) Option {
+	return func(s *Wappalyze) {
+		s.assetPolicy = policy
+	}
+}
+
+// WithParseLimits bounds the elements parsed into the DOM and the inline
+// scripts scanned for each page. There are no limits by default; see
+// DefaultParseLimits for sensible values.
+func WithParseLimits(limits ParseLimits) Option {
+	return func(s *Wappalyze) {
+		s.parseLimits = limits
+	}
+}
+
+// WithBudget bounds the total duration of FingerprintURL, from the fetch of the
+// page to the last matcher. When the budget runs out after the page was fetched,
+// the stages still running are cut short and the result holds the detections
+// gathered so far, with PartialResult set and the stages listed by
+// GetSkippedStages. A deadline on the context of FingerprintURL has the same
+// effect. Partial results are not cached.
+func WithBudget(budget time.Duration) Option {
+	return func(s *Wappalyze) {
+		s.budget = budget
+	}
+}
+
+// WithDisabledVectors disables detection vectors, skipping both the gathering
+// of their data and their matchers, whatever the profile. Embedders use it
+// where DNS egress or requests beyond the page are not allowed.
+func WithDisabledVectors(vectors ...Vector) Option {
+	return func(s *Wappalyze) {
+		if s.disabledVectors == nil {
+			s.disabledVectors = make(map[Vector]struct{}, len(vectors))
+		}
+		for _, vector := range vectors {
+			s.disabledVectors[vector] = struct{}{}
+		}
+	}
+}
+
+// WithMatchWorkers sets the number of goroutines that run the independent
+// matchers of an analysis, such as headers, meta tags, HTML, DOM and each
+// stylesheet, concurrently. It defaults to GOMAXPROCS; 1 runs them one after
+// the other, which suits servers already running many analyses at once.
+func WithMatchWorkers(workers int) Option {
+	return func(s *Wappalyze) {
+		if workers > 0 {
+			s.matchWorkers = workers
+		}
+	}
+}
+
+// WithPatternProfiler records the evaluation time and timeouts of every
+// fingerprint pattern in profiler, whose report ranks the slowest patterns
+// across all the analyses of the instance. Instances may share a profiler.
+func WithPatternProfiler(profiler *PatternProfiler) Option {
+	return func(s *Wappalyze) {
+		s.patternProfiler = profiler
+	}
+}
@@ -0,0 +1,213 @@
+package profiler
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"os"
+	"slices"
+)
+
+// Overlay is a fingerprint file applied on top of the loaded fingerprints,
+// for example:
+//
+//	{
+//	    "categories": {"1000": {"name": "Payments", "priority": 5}},
+//	    "apps": {"Internal CMS": {"cats": [1], "headers": {"x-cms": ""}}},
+//	    "extend": {"WordPress": {"html": ["<div class=\"my-theme"]}, "Stripe": {"cats": [1000], "tags": ["pii-processor"]}},
+//	    "disable": ["Google Font API"]
+//	}
+type Overlay struct {
+	// Categories are added to the embedded categories, keyed by an ID none of
+	// them has. Technologies of the overlays may then be assigned to them.
+	Categories map[int]OverlayCategory `json:"categories,omitempty"`
+	// Apps are added, replacing the technologies of the same name
+	Apps map[string]*Fingerprint `json:"apps,omitempty"`
+	// Extend adds the patterns, implies, excludes, categories and tags of each
+	// fingerprint to the existing technology of the same name. Patterns keyed
+	// by name, such as headers, replace those of the same name.
+	Extend map[string]*Fingerprint `json:"extend,omitempty"`
+	// Disable are technologies to remove, which are no longer implied either
+	Disable []string `json:"disable,omitempty"`
+}
+
+// OverlayCategory is a custom category of an overlay
+type OverlayCategory struct {
+	Name string `json:"name"`
+	// Priority runs from 1, the most significant, to 10, the default
+	Priority int `json:"priority,omitempty"`
+}
+
+// WithOverlays applies the fingerprint overlay files at paths, in order, on
+// top of the fingerprints the instance loads, whether embedded or from a file.
+// The constructors return an error if an overlay cannot be read or refers to
+// an unknown technology.
+func WithOverlays(paths ...string) Option {
+	return func(s *Wappalyze) {
+		s.overlays = append(s.overlays, paths...)
+	}
+}
+
+// LoadOverlay reads an overlay file
+func LoadOverlay(path string) (*Overlay, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var overlay Overlay
+	if err := json.Unmarshal(data, &overlay); err != nil {
+		return nil, fmt.Errorf("could not parse overlay %s: %w", path, err)
+	}
+	return &overlay, nil
+}
+
+// applyOverlays applies the overlays of the instance to fingerprints, returning
+// the technologies they added or extended, whose patterns need validating
+func (s *Wappalyze) applyOverlays(fingerprints *Fingerprints) (map[string]struct{}, error) {
+	changed := make(map[string]struct{})
+	for _, path := range s.overlays {
+		overlay, err := LoadOverlay(path)
+		if err != nil {
+			return nil, err
+		}
+		if err := s.addCategories(overlay.Categories); err != nil {
+			return nil, fmt.Errorf("could not apply overlay %s: %w", path, err)
+		}
+		if err := overlay.apply(fingerprints.Apps, changed); err != nil {
+			return nil, fmt.Errorf("could not apply overlay %s: %w", path, err)
+		}
+	}
+	return changed, nil
+}
+
+// addCategories adds the custom categories of an overlay to the categories of
+// the instance
+func (s *Wappalyze) addCategories(categories map[int]OverlayCategory) error {
+	if len(categories) == 0 {
+		return nil
+	}
+	// The embedded categories are shared by all instances
+	s.categories = maps.Clone(s.categories)
+	for id, category := range categories {
+		if _, ok := s.categories[id]; ok {
+			return fmt.Errorf("category %d already exists", id)
+		}
+		if category.Name == "" {
+			return fmt.Errorf("category %d has no name", id)
+		}
+		if category.Priority < 0 || category.Priority > 10 {
+			return fmt.Errorf("category %d has priority %d, expected 1 to 10", id, category.Priority)
+		}
+		if category.Priority == 0 {
+			category.Priority = 10
+		}
+		s.categories[id] = categoryItem{Name: category.Name, Priority: category.Priority}
+	}
+	return nil
+}
+
+// apply adds, extends and disables the technologies of the overlay in apps,
+// recording the ones added or extended in changed
+func (o *Overlay) apply(apps map[string]*Fingerprint, changed map[string]struct{}) error {
+	for appName, fingerprint := range o.Apps {
+		apps[appName] = fingerprint
+		changed[appName] = struct{}{}
+	}
+
+	for appName, extension := range o.Extend {
+		fingerprint, ok := apps[appName]
+		if !ok {
+			return fmt.Errorf("cannot extend unknown technology %q", appName)
+		}
+		extendFingerprint(fingerprint, extension)
+		changed[appName] = struct{}{}
+	}
+
+	for _, appName := range o.Disable {
+		if _, ok := apps[appName]; !ok {
+			return fmt.Errorf("cannot disable unknown technology %q", appName)
+		}
+		delete(apps, appName)
+		delete(changed, appName)
+	}
+	if len(o.Disable) > 0 {
+		for _, fingerprint := range apps {
+			fingerprint.Implies = slices.DeleteFunc(fingerprint.Implies, func(implied string) bool {
+				return slices.Contains(o.Disable, impliedName(implied))
+			})
+			fingerprint.Excludes = slices.DeleteFunc(fingerprint.Excludes, func(excluded string) bool {
+				return slices.Contains(o.Disable, excluded)
+			})
+		}
+	}
+	return nil
+}
+
+// extendFingerprint adds the patterns, implies, excludes, categories and tags
+// of extension to fingerprint. Its description, website, CPE and icon replace the
+// existing ones if set.
+func extendFingerprint(fingerprint, extension *Fingerprint) {
+	appendNew := func(list []string, values []string) []string {
+		for _, value := range values {
+			if !slices.Contains(list, value) {
+				list = append(list, value)
+			}
+		}
+		return list
+	}
+	mergeLists := func(dst *map[string][]string, src map[string][]string) {
+		if len(src) > 0 && *dst == nil {
+			*dst = make(map[string][]string, len(src))
+		}
+		for key, values := range src {
+			(*dst)[key] = appendNew((*dst)[key], values)
+		}
+	}
+	mergeStrings := func(dst *map[string]string, src map[string]string) {
+		if len(src) > 0 && *dst == nil {
+			*dst = make(map[string]string, len(src))
+		}
+		maps.Copy(*dst, src)
+	}
+
+	for _, cat := range extension.Cats {
+		if !slices.Contains(fingerprint.Cats, cat) {
+			fingerprint.Cats = append(fingerprint.Cats, cat)
+		}
+	}
+	fingerprint.CSS = appendNew(fingerprint.CSS, extension.CSS)
+	fingerprint.HTML = appendNew(fingerprint.HTML, extension.HTML)
+	fingerprint.Script = appendNew(fingerprint.Script, extension.Script)
+	fingerprint.ScriptSrc = appendNew(fingerprint.ScriptSrc, extension.ScriptSrc)
+	fingerprint.Robots = appendNew(fingerprint.Robots, extension.Robots)
+	fingerprint.CertIssuer = appendNew(fingerprint.CertIssuer, extension.CertIssuer)
+	fingerprint.XHR = appendNew(fingerprint.XHR, extension.XHR)
+	fingerprint.Iframe = appendNew(fingerprint.Iframe, extension.Iframe)
+	fingerprint.LinkHref = appendNew(fingerprint.LinkHref, extension.LinkHref)
+	fingerprint.Implies = appendNew(fingerprint.Implies, extension.Implies)
+	fingerprint.Excludes = appendNew(fingerprint.Excludes, extension.Excludes)
+	fingerprint.Tags = appendNew(fingerprint.Tags, extension.Tags)
+	mergeStrings(&fingerprint.Cookies, extension.Cookies)
+	mergeStrings(&fingerprint.JS, extension.JS)
+	mergeStrings(&fingerprint.Headers, extension.Headers)
+	mergeLists(&fingerprint.Meta, extension.Meta)
+	mergeLists(&fingerprint.DNS, extension.DNS)
+	mergeLists(&fingerprint.JSONLD, extension.JSONLD)
+	if len(extension.Dom) > 0 && fingerprint.Dom == nil {
+		fingerprint.Dom = make(map[string]map[string]interface{}, len(extension.Dom))
+	}
+	maps.Copy(fingerprint.Dom, extension.Dom)
+
+	if extension.Description != "" {
+		fingerprint.Description = extension.Description
+	}
+	if extension.Website != "" {
+		fingerprint.Website = extension.Website
+	}
+	if extension.CPE != "" {
+		fingerprint.CPE = extension.CPE
+	}
+	if extension.Icon != "" {
+		fingerprint.Icon = extension.Icon
+	}
+}
@@ -0,0 +1,67 @@
+package profiler
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestOverlays(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		require.NoError(t, os.WriteFile(path, []byte(content), 0o644), "could not write overlay")
+		return path
+	}
+	added := write("added.json", `{
+		"apps": {"Internal CMS": {"cats": [1], "headers": {"x-internal-cms": ""}, "implies": ["PHP"]}}
+	}`)
+	extended := write("extended.json", `{
+		"extend": {"Nginx": {"html": ["<!-- served by our edge -->"]}},
+		"disable": ["PHP"]
+	}`)
+
+	wappalyzer, err := New(WithOverlays(added, extended))
+	require.NoError(t, err, "could not create wappalyzer")
+
+	headers := map[string][]string{"X-Internal-Cms": {"1"}, "X-Powered-By": {"PHP/8.2"}}
+	body := []byte("<html><!-- served by our edge --></html>")
+	detected := wappalyzer.Fingerprint(headers, body)
+	require.Contains(t, detected, "Internal CMS", "could not detect added technology")
+	require.Contains(t, detected, "Nginx", "could not detect extended technology")
+	require.NotContains(t, detected, "PHP", "disabled technology should not be detected or implied")
+
+	require.Contains(t, wappalyzer.GetFingerprints().Apps["Nginx"].Headers, "server", "extending should keep the existing patterns")
+	require.NotContains(t, wappalyzer.GetFingerprints().Apps["WordPress"].Implies, "PHP", "disabled technologies should not be implied")
+
+	categories, err := NewForCategories([]int{1}, WithOverlays(added))
+	require.NoError(t, err, "could not create wappalyzer for categories")
+	require.Contains(t, categories.GetFingerprints().Apps, "Internal CMS", "overlays should be applied before selecting categories")
+
+	for name, content := range map[string]string{
+		"extend.json":  `{"extend": {"Unknown Technology": {"html": ["x"]}}}`,
+		"disable.json": `{"disable": ["Unknown Technology"]}`,
+		"invalid.json": `{"apps": [`,
+	} {
+		_, err := New(WithOverlays(write(name, content)))
+		require.Error(t, err, "overlay %s should be rejected", name)
+	}
+	_, err = New(WithOverlays(filepath.Join(dir, "missing.json")))
+	require.Error(t, err, "missing overlay should be rejected")
+}
+
+func TestNewFromFileSupersede(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "fingerprints.json")
+	require.NoError(t, os.WriteFile(path, []byte(`{"apps": {"Nginx": {"html": ["custom nginx"]}}}`), 0o644), "could not write fingerprints")
+
+	kept, err := NewFromFile(path, true, false)
+	require.NoError(t, err, "could not create wappalyzer")
+	require.NotEmpty(t, kept.GetFingerprints().Apps["Nginx"].Headers, "embedded fingerprint should be kept without supersede")
+
+	superseded, err := NewFromFile(path, true, true)
+	require.NoError(t, err, "could not create wappalyzer")
+	require.Equal(t, []string{"custom nginx"}, superseded.GetFingerprints().Apps["Nginx"].HTML, "file fingerprint should supersede the embedded one")
+	require.Empty(t, superseded.GetFingerprints().Apps["Nginx"].Headers, "superseded fingerprint should be replaced")
+}
@@ -0,0 +1,137 @@
+package profiler
+
+import (
+	"cmp"
+	"fmt"
+	"io"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"text/tabwriter"
+	"time"
+)
+
+// PatternProfiler records the time spent evaluating each fingerprint pattern
+// and how often it timed out, across all the analyses of the instances it is
+// attached to with WithPatternProfiler. Its ranked report points maintainers to
+// the few patterns that burn most of the CPU. Recording adds a clock read to
+// every evaluation, so it is meant for profiling runs rather than production.
+type PatternProfiler struct {
+	mutex    sync.Mutex
+	patterns map[patternKey]*patternProfile
+}
+
+// PatternStats is the cumulative cost of a fingerprint pattern
+type PatternStats struct {
+	App         string        `json:"app"`
+	Vector      string        `json:"vector"`
+	Pattern     string        `json:"pattern"`
+	Evaluations int64         `json:"evaluations"`
+	Duration    time.Duration `json:"duration"`
+	Timeouts    int64         `json:"timeouts"`
+}
+
+// patternKey identifies a pattern of the fingerprint data
+type patternKey struct {
+	app, vector, pattern string
+}
+
+// patternProfile accumulates the evaluations of a pattern. It is shared by the
+// compiled patterns of all instances with the same profiler.
+type patternProfile struct {
+	evaluations atomic.Int64
+	duration    atomic.Int64
+	timeouts    atomic.Int64
+}
+
+// NewPatternProfiler creates an empty pattern profiler
+func NewPatternProfiler() *PatternProfiler {
+	return &PatternProfiler{patterns: make(map[patternKey]*patternProfile)}
+}
+
+// profile returns the accumulator of a pattern, or nil on a nil profiler
+func (p *PatternProfiler) profile(app, vector, pattern string) *patternProfile {
+	if p == nil {
+		return nil
+	}
+	key := patternKey{app: app, vector: vector, pattern: pattern}
+
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	profile, ok := p.patterns[key]
+	if !ok {
+		profile = &patternProfile{}
+		p.patterns[key] = profile
+	}
+	return profile
+}
+
+// record adds an evaluation. It is safe to call on a nil profile.
+func (p *patternProfile) record(duration time.Duration, timedOut bool) {
+	if p == nil {
+		return
+	}
+	p.evaluations.Add(1)
+	p.duration.Add(int64(duration))
+	if timedOut {
+		p.timeouts.Add(1)
+	}
+}
+
+// Report returns the stats of the patterns evaluated so far, the most expensive
+// first: by timeouts, then by cumulative duration. A positive limit keeps only
+// that many patterns.
+func (p *PatternProfiler) Report(limit int) []PatternStats {
+	p.mutex.Lock()
+	report := make([]PatternStats, 0, len(p.patterns))
+	for key, profile := range p.patterns {
+		if profile.evaluations.Load() == 0 {
+			continue
+		}
+		report = append(report, PatternStats{
+			App:         key.app,
+			Vector:      key.vector,
+			Pattern:     key.pattern,
+			Evaluations: profile.evaluations.Load(),
+			Duration:    time.Duration(profile.duration.Load()),
+			Timeouts:    profile.timeouts.Load(),
+		})
+	}
+	p.mutex.Unlock()
+
+	slices.SortFunc(report, func(a, b PatternStats) int {
+		return cmp.Or(
+			cmp.Compare(b.Timeouts, a.Timeouts),
+			cmp.Compare(b.Duration, a.Duration),
+			cmp.Compare(a.App, b.App),
+			cmp.Compare(a.Vector, b.Vector),
+			cmp.Compare(a.Pattern, b.Pattern),
+		)
+	})
+	if limit > 0 && len(report) > limit {
+		report = report[:limit]
+	}
+	return report
+}
+
+// WriteReport writes the report of Report as an aligned table
+func (p *PatternProfiler) WriteReport(w io.Writer, limit int) error {
+	table := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(table, "RANK\tTOTAL\tEVALUATIONS\tMEAN\tTIMEOUTS\tAPP\tVECTOR\tPATTERN")
+	for i, stats := range p.Report(limit) {
+		mean := stats.Duration / time.Duration(stats.Evaluations)
+		fmt.Fprintf(table, "%d\t%s\t%d\t%s\t%d\t%s\t%s\t%s\n", i+1, stats.Duration, stats.Evaluations, mean, stats.Timeouts, stats.App, stats.Vector, stats.Pattern)
+	}
+	return table.Flush()
+}
+
+// Reset forgets the evaluations recorded so far, to profile a new batch
+func (p *PatternProfiler) Reset() {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	for _, profile := range p.patterns {
+		profile.evaluations.Store(0)
+		profile.duration.Store(0)
+		profile.timeouts.Store(0)
+	}
+}
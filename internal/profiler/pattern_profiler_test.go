@@ -0,0 +1,58 @@
+package profiler
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPatternProfiler(t *testing.T) {
+	profiler := NewPatternProfiler()
+	wappalyzer, err := New(WithPatternProfiler(profiler))
+	require.NoError(t, err, "could not create wappalyzer")
+
+	body := []byte(`<html><head><meta name="generator" content="WordPress 6.4"></head><body></body></html>`)
+	for i := 0; i < 2; i++ {
+		wappalyzer.Fingerprint(map[string][]string{"Server": {"nginx"}}, body)
+	}
+
+	report := profiler.Report(0)
+	require.NotEmpty(t, report, "evaluations should be recorded")
+	var wordpress *PatternStats
+	for i := range report {
+		if i > 0 {
+			require.GreaterOrEqual(t, report[i-1].Duration, report[i].Duration, "report should be ranked by duration")
+		}
+		if report[i].App == "WordPress" && report[i].Vector == "meta" {
+			wordpress = &report[i]
+		}
+	}
+	require.NotNil(t, wordpress, "missing meta pattern of wordpress")
+	require.Equal(t, int64(2), wordpress.Evaluations, "pattern should be counted across analyses")
+	require.Contains(t, wordpress.Pattern, `;version:`, "report should hold the pattern of the data")
+
+	require.Len(t, profiler.Report(3), 3, "report should be limited")
+
+	var table strings.Builder
+	require.NoError(t, profiler.WriteReport(&table, 3), "could not write report")
+	require.Len(t, strings.Split(strings.TrimSpace(table.String()), "\n"), 4, "report should have a header and three rows")
+
+	profiler.Reset()
+	require.Empty(t, profiler.Report(0), "reset should forget evaluations")
+}
+
+func TestPatternProfilerRanking(t *testing.T) {
+	profiler := NewPatternProfiler()
+	profiler.profile("Fast", "html", "fast").record(time.Millisecond, false)
+	profiler.profile("Slow", "html", "slow").record(time.Second, false)
+	profiler.profile("Timeout", "html", "timeout").record(100*time.Millisecond, true)
+	profiler.profile("Unused", "html", "unused")
+
+	var apps []string
+	for _, stats := range profiler.Report(0) {
+		apps = append(apps, stats.App)
+	}
+	require.Equal(t, []string{"Timeout", "Slow", "Fast"}, apps, "timeouts should rank first, then duration")
+}
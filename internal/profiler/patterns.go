@@ -2,9 +2,12 @@ package profiler
 
 import (
 	"fmt"
+	"log/slog"
 	"regexp"
 	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -12,6 +15,13 @@ import (
 // additional metadata for confidence and version extraction.
 type ParsedPattern struct {
 	regex *regexp.Regexp
+	// source is the regex compiled on first use, for patterns known to be valid
+	source      string
+	compileOnce sync.Once
+	// logger reports evaluations that time out, if set
+	logger *slog.Logger
+	// profile accumulates the cost of evaluations, if pattern profiling is on
+	profile *patternProfile
 
 	Confidence int
 	Version    string
@@ -30,6 +40,19 @@ const (
 
 // ParsePattern extracts information from a pattern, supporting both regex and simple patterns
 func ParsePattern(pattern string) (*ParsedPattern, error) {
+	return parsePattern(pattern, false)
+}
+
+// parseValidatedPattern is ParsePattern for patterns already known to compile,
+// such as those of the binary fingerprint data. The regex is only compiled when
+// the pattern is first evaluated, which keeps New fast.
+func parseValidatedPattern(pattern string) *ParsedPattern {
+	p, _ := parsePattern(pattern, true)
+	return p
+}
+
+// parsePattern parses a pattern, deferring the compilation of its regex if lazy
+func parsePattern(pattern string, lazy bool) (*ParsedPattern, error) {
 	parts := strings.Split(pattern, "\\;")
 	p := &ParsedPattern{Confidence: 100}
 
@@ -56,8 +79,12 @@ func ParsePattern(pattern string) (*ParsedPattern, error) {
 			regexPattern = strings.ReplaceAll(regexPattern, verCap1Fill, verCap1Limited)
 			regexPattern = strings.ReplaceAll(regexPattern, verCap2Fill, verCap2Limited)
 
+			if lazy {
+				p.source = "(?i)" + regexPattern
+				continue
+			}
 			var err error
-			p.regex, err = regexp.Compile("(?i)" + regexPattern)
+			p.regex, err = compileRegex("(?i)" + regexPattern)
 			if err != nil {
 				return nil, err
 			}
@@ -84,16 +111,37 @@ func ParsePattern(pattern string) (*ParsedPattern, error) {
 	return p, nil
 }
 
+// Evaluate matches the pattern against target within timeout, returning
+// whether it matched and the version it extracted
 func (p *ParsedPattern) Evaluate(target string, timeout time.Duration) (bool, string) {
+	return p.evaluate(target, timeout, nil)
+}
+
+// evaluate implements Evaluate, counting a timed out evaluation in timeouts, if set
+func (p *ParsedPattern) evaluate(target string, timeout time.Duration, timeouts *atomic.Int64) (bool, string) {
 	if p.SkipRegex {
 		return true, ""
 	}
-	if p.regex == nil {
+	regex := p.compiled()
+	if regex == nil {
 		return false, ""
 	}
 
 	// Replace the direct regex call with our timeout-protected version
-	submatches := matchWithTimeout(p.regex, []byte(target), timeout)
+	var start time.Time
+	if p.profile != nil {
+		start = time.Now()
+	}
+	submatches, ok := matchWithTimeout(regex, target, timeout)
+	if p.profile != nil {
+		p.profile.record(time.Since(start), !ok)
+	}
+	if !ok && timeouts != nil {
+		timeouts.Add(1)
+	}
+	if !ok && p.logger != nil {
+		p.logger.Debug("regex timed out", "pattern", regex.String(), "timeout", timeout, "input_size", len(target))
+	}
 	if len(submatches) == 0 {
 		return false, ""
 	}
@@ -101,6 +149,37 @@ func (p *ParsedPattern) Evaluate(target string, timeout time.Duration) (bool, st
 	return true, extractedVersion
 }
 
+// regexCache holds the compiled regexes of every instance by source, so
+// instances loading the same fingerprints, such as the fingerprint sets of a
+// server or an instance replacing another on reload, compile and keep each
+// regex once. Compiled regexes are safe for concurrent use.
+var regexCache sync.Map
+
+// compileRegex compiles source, or returns its cached regex
+func compileRegex(source string) (*regexp.Regexp, error) {
+	if cached, ok := regexCache.Load(source); ok {
+		return cached.(*regexp.Regexp), nil
+	}
+	regex, err := regexp.Compile(source)
+	if err != nil {
+		return nil, err
+	}
+	cached, _ := regexCache.LoadOrStore(source, regex)
+	return cached.(*regexp.Regexp), nil
+}
+
+// compiled returns the regex of the pattern, compiling it on first use if
+// its compilation was deferred
+func (p *ParsedPattern) compiled() *regexp.Regexp {
+	if p.source != "" {
+		p.compileOnce.Do(func() {
+			// The pattern was validated when the data was built
+			p.regex, _ = compileRegex(p.source)
+		})
+	}
+	return p.regex
+}
+
 // extractVersion uses the provided pattern to extract version information from a target string.
 func (p *ParsedPattern) extractVersion(submatches []string) (string, error) {
 	if len(submatches) == 0 {
@@ -192,3 +192,22 @@ func TestExtractVersion(t *testing.T) {
 		})
 	}
 }
+
+func TestPatternsShareRegexes(t *testing.T) {
+	first, err := ParsePattern(`jquery-([\d.]+)\.js\;version:\1`)
+	if err != nil {
+		t.Fatal("Failed to parse pattern:", err)
+	}
+	second, err := ParsePattern(`jquery-([\d.]+)\.js`)
+	if err != nil {
+		t.Fatal("Failed to parse pattern:", err)
+	}
+	lazy := parseValidatedPattern(`jquery-([\d.]+)\.js`)
+
+	if first.compiled() != second.compiled() || lazy.compiled() != first.compiled() {
+		t.Error("Patterns with the same regex should share its compilation")
+	}
+	if other, _ := ParsePattern(`jquery\.min\.js`); other.compiled() == first.compiled() {
+		t.Error("Patterns with different regexes should not share a compilation")
+	}
+}
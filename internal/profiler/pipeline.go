@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"net/http"
 	"net/url"
+	"slices"
 	"strings"
 	"sync"
 	"time"
@@ -17,29 +18,87 @@ func (s *Wappalyze) AnalyzeWithPipeline(resp *http.Response, body []byte) richRe
 	return s.analyzeWithPipeline(resp, body)
 }
 
+// AnalyzeWithPipelineContext is AnalyzeWithPipeline bounded by ctx, which may
+// also carry the profile of the analysis, as set by ProfileContext
+func (s *Wappalyze) AnalyzeWithPipelineContext(ctx context.Context, resp *http.Response, body []byte) richResult {
+	ctx, tracer := s.startTrace(s.withUserAgent(ctx))
+	result := s.analyzeWithPipelineContext(ctx, resp, body)
+	if tracer != nil {
+		result.trace = tracer.trace()
+	}
+	return result
+}
+
 // analyzeWithPipeline is a fully pipelined implementation of the analyze function
 // It eliminates all I/O waterfalls by starting to fetch external resources immediately
 // as they are discovered during HTML parsing
 func (s *Wappalyze) analyzeWithPipeline(resp *http.Response, body []byte) richResult {
+	return s.analyzeWithPipelineContext(s.withUserAgent(context.Background()), resp, body)
+}
+
+// analyzeWithPipelineContext is analyzeWithPipeline bounded by a parent context
+func (s *Wappalyze) analyzeWithPipelineContext(parent context.Context, resp *http.Response, body []byte) richResult {
 	var result richResult
 	var targetURL string
 
+	// Collect timings and telemetry for this analysis, and report progress if requested
+	progress := s.progress(parent)
+	stats := newStatsRecorder()
+	stats.progress = progress
+	stats.logger = s.logger
+	stats.ctx = parent
+	stats.trace = tracerOf(parent)
+
+	// Technologies detected at full confidence with a version skip their
+	// remaining patterns, unless all the evidence is gathered
+	var settled *settledApps
+	if !s.gatherAllEvidence {
+		settled = &settledApps{}
+	}
+	s = s.forAnalysis(settled, &stats.regexTimeouts)
+
+	// Stages still running when the deadline of parent passes make the result partial
+	budget := newBudgetTracker(parent)
+
 	// Extract URL from response if available
 	if resp != nil && resp.Request != nil && resp.Request.URL != nil {
 		targetURL = resp.Request.URL.String()
 	}
+	stats.url = targetURL
+
+	// The profile and disabled vectors decide which optional stages run
+	enabled := s.stages(parent)
 
 	// Variables for TLS certificate analysis
 	var certIssuer string
-	if resp != nil && resp.TLS != nil && len(resp.TLS.PeerCertificates) > 0 {
+	if enabled.tls && resp != nil && resp.TLS != nil && len(resp.TLS.PeerCertificates) > 0 {
 		certIssuer = resp.TLS.PeerCertificates[0].Issuer.CommonName
 	}
 
 	// Initialize data structures
 	uniqueFingerprints := NewUniqueFingerprints()
+	uniqueFingerprints.progress = progress
+	uniqueFingerprints.settled = settled
 	
 	// Sync.Mutex to protect the uniqueFingerprints from concurrent access
 	var fpMutex sync.Mutex
+
+	// Raw response header order, captured when header order probing is enabled
+	var headerOrder []string
+
+	// Alternate ports that responded, when port probing is enabled
+	var ports []PortProbe
+
+	// Email authentication setup of the domain, when the email vector is enabled
+	var email *EmailInfo
+
+	// Parsed robots.txt, when the robots vector is enabled. Probes honor its
+	// rules, so they wait for robotsDone.
+	var robots *RobotsTxt
+	robotsDone := make(chan struct{})
+
+	// Failures of secondary stages, which do not fail the analysis
+	var stageErrors []error
 	
 	// Extract headers for fingerprinting
 	var normalizedHeaders map[string]string
@@ -81,7 +140,7 @@ func (s *Wappalyze) analyzeWithPipeline(resp *http.Response, body []byte) richRe
 
 	// Setup for asynchronous operations
 	var wg sync.WaitGroup
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	ctx, cancel := context.WithTimeout(parent, 10*time.Second)
 	defer cancel()
 
 	// Create maps that will be populated by the AssetFetcher
@@ -90,41 +149,84 @@ func (s *Wappalyze) analyzeWithPipeline(resp *http.Response, body []byte) richRe
 
 	// Create asset fetcher for all network I/O operations
 	assetFetcher := NewAssetFetcher(targetURL, ctx, &wg, 10, &jsContent, &cssContent)
+	assetFetcher.client.Transport = s.resourceTransport
+	assetFetcher.stats = stats
+	assetFetcher.retry = s.retryPolicy
+	assetFetcher.limiter = s.rateLimiter
+	assetFetcher.logger = s.logger
+	assetFetcher.policy = s.assetPolicy
+	if !enabled.assets {
+		assetFetcher.skip("script", "style", "manifest")
+	}
+	if !enabled.js {
+		assetFetcher.skip("script")
+	}
+	if !enabled.css {
+		assetFetcher.skip("style")
+	}
 	
 	// Start the asset fetcher pipeline
 	assetFetcher.Start()
 
-	// Run header based fingerprinting
-	for _, app := range s.checkHeaders(normalizedHeaders) {
+	// Run the header, protocol, cookie and TLS certificate matchers, which
+	// only need the response
+	matchers := []matcher{
+		{headersPart, func() []matchPartResult { return s.checkHeaders(normalizedHeaders) }},
+		{protocolPart, func() []matchPartResult { return s.checkProtocol(resp) }},
+	}
+	if cookies := s.findSetCookie(normalizedHeaders); len(cookies) > 0 {
+		matchers = append(matchers, matcher{cookiesPart, func() []matchPartResult { return s.checkCookies(cookies) }})
+	}
+	if certIssuer != "" {
+		matchers = append(matchers, matcher{certIssuerPart, func() []matchPartResult {
+			return s.fingerprints.matchString(certIssuer, certIssuerPart, s.regexTimeout)
+		}})
+	}
+	for _, app := range runMatchers(matchers, s.matchWorkers, stats) {
 		fpMutex.Lock()
-		uniqueFingerprints.SetIfNotExists(app.application, app.version, app.confidence)
+		uniqueFingerprints.SetWithVector(app.application, app.version, app.confidence, app.vector())
 		fpMutex.Unlock()
 	}
 
-	// Run cookie based fingerprinting
-	cookies := s.findSetCookie(normalizedHeaders)
-	if len(cookies) > 0 {
-		for _, app := range s.checkCookies(cookies) {
-			fpMutex.Lock()
-			uniqueFingerprints.SetIfNotExists(app.application, app.version, app.confidence)
-			fpMutex.Unlock()
-		}
-	}
-
 	// Process the HTML in a streaming fashion if we're not in test mode
 	// This will send asset URLs to the fetcher as they are discovered
 	var title string
+	var inlineScripts []string
+	var scriptSources []string
+	var linkHrefs []string
+	var generators []string
+	var inlineConfigs map[string]string
 	if !isTestMode && !specialTestCase && len(body) > 0 {
 		// Extract title using tokenizer
 		title = s.extractTitleWithTokenizer(body)
 		
 		// Parse HTML and stream asset URLs to the fetcher
-		htmlTech, _ := s.streamingParseHTML(body, assetFetcher)
-		
+		htmlTech, doc := s.streamingParseHTML(body, assetFetcher, enabled.dom)
+
+		// Keep inline scripts around for request URL extraction
+		var truncated bool
+		inlineScripts, truncated = collectInlineScripts(doc, s.parseLimits.MaxInlineScripts)
+		stats.setInlineScripts(len(inlineScripts))
+		if truncated {
+			stats.limit(LimitInlineScripts)
+		}
+		scriptSources = collectScriptSources(doc, targetURL)
+		linkHrefs = collectLinkHrefs(doc, targetURL)
+		generators = collectGenerators(doc)
+
+		// Match the configuration globals serialized inline, such as
+		// __NEXT_DATA__ or drupalSettings, against the js patterns
+		inlineConfigs = extractInlineConfigs(doc, inlineScripts)
+		if enabled.js {
+			htmlTech = append(htmlTech, matcher{jsPart, func() []matchPartResult {
+				return s.analyzeInlineConfigs(inlineConfigs)
+			}}.run(stats)...)
+		}
+
 		// Add HTML technologies to fingerprints
 		for _, app := range htmlTech {
 			fpMutex.Lock()
-			uniqueFingerprints.SetIfNotExists(app.application, app.version, app.confidence)
+			uniqueFingerprints.SetWithVector(app.application, app.version, app.confidence, app.vector())
 			fpMutex.Unlock()
 		}
 	}
@@ -134,33 +236,75 @@ func (s *Wappalyze) analyzeWithPipeline(resp *http.Response, body []byte) richRe
 		parsedURL, err := url.Parse(targetURL)
 		if err == nil && parsedURL.Hostname() != "" {
 			// Launch DNS lookup goroutine
-			wg.Add(1)
-			go func() {
-				defer wg.Done()
-				
-				// Create context with timeout for DNS operations
-				dnsCtx, dnsCancel := context.WithTimeout(ctx, 5*time.Second)
-				defer dnsCancel()
-				
-				// Perform DNS lookups
-				dnsRecords := checkDNSWithContext(dnsCtx, parsedURL.Hostname())
+			if enabled.dns {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
 				
-				// Store records in asset fetcher
-				assetFetcher.SetDNSRecords(dnsRecords)
+					// Create context with timeout for DNS operations
+					dnsCtx, dnsCancel := context.WithTimeout(ctx, 5*time.Second)
+					defer dnsCancel()
 				
-				// Process DNS records immediately if available
-				if dnsRecords != nil && len(dnsRecords) > 0 {
-					dnsMatches := s.fingerprints.matchDNSRecords(dnsRecords, s.regexTimeout)
-					for _, app := range dnsMatches {
+					// Perform DNS lookups
+					dnsStart := time.Now()
+					dnsRecords := checkDNSWithContext(dnsCtx, s.resolver, parsedURL.Hostname())
+					stats.addFetch("dns", time.Since(dnsStart))
+
+					// A lookup cut short by the context is reported as a timeout
+					var dnsErr error
+					if dnsRecords == nil && dnsCtx.Err() != nil {
+						dnsErr = newAnalysisError(StageDNS, parsedURL.Hostname(), dnsCtx.Err())
+						s.logger.DebugContext(parent, "dns lookup failed", "host", parsedURL.Hostname(), "error", dnsErr)
 						fpMutex.Lock()
-						uniqueFingerprints.SetIfNotExists(app.application, app.version, app.confidence)
+						stageErrors = append(stageErrors, dnsErr)
 						fpMutex.Unlock()
 					}
-				}
-			}()
+				
+					// Store records in asset fetcher
+					assetFetcher.SetDNSRecords(dnsRecords)
+				
+					// Process DNS records immediately if available
+					if dnsRecords != nil && len(dnsRecords) > 0 {
+						dnsMatches := matcher{dnsPart, func() []matchPartResult {
+							return s.fingerprints.matchDNSRecords(dnsRecords, s.regexTimeout)
+						}}.run(stats)
+						for _, app := range dnsMatches {
+							fpMutex.Lock()
+							uniqueFingerprints.SetWithVector(app.application, app.version, app.confidence, app.vector())
+							fpMutex.Unlock()
+						}
+					}
+					budget.finish(StageDNS)
+					progress.stage(StageDNS, dnsErr)
+				}()
+			}
 			
+			// Look up the email authentication records of the domain
+			if enabled.email {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+
+					emailCtx, emailCancel := context.WithTimeout(ctx, 5*time.Second)
+					defer emailCancel()
+
+					emailStart := time.Now()
+					records := lookupEmail(emailCtx, s.resolver, parsedURL.Hostname())
+					stats.addFetch("email", time.Since(emailStart))
+					apps := matcher{emailPart, func() []matchPartResult { return s.matchEmailRecords(records) }}.run(stats)
+					fpMutex.Lock()
+					email = records.info()
+					for _, app := range apps {
+						uniqueFingerprints.SetWithVector(app.application, app.version, app.confidence, app.vector())
+					}
+					fpMutex.Unlock()
+					budget.finish(StageEmail)
+					progress.stage(StageEmail, nil)
+				}()
+			}
+
 			// Add robots.txt URL to be fetched
-			if parsedURL.Scheme != "" && parsedURL.Host != "" {
+			if enabled.robots && parsedURL.Scheme != "" && parsedURL.Host != "" {
 				robotsURL := fmt.Sprintf("%s://%s/robots.txt", parsedURL.Scheme, parsedURL.Host)
 				
 				// Launch robots.txt analysis goroutine
@@ -173,50 +317,143 @@ func (s *Wappalyze) analyzeWithPipeline(resp *http.Response, body []byte) richRe
 					defer robotsCancel()
 					
 					// Fetch and analyze robots.txt
-					robotsMatches := s.fetchAndAnalyzeRobotsTxt(robotsURL, robotsCtx)
-					
-					// Process robots matches directly
+					robotsMatches, parsed, err := s.fetchAndAnalyzeRobotsTxt(robotsURL, robotsCtx, stats)
+					robots = parsed
+					close(robotsDone)
+					if err != nil {
+						s.logger.DebugContext(parent, "robots.txt fetch failed", "url", robotsURL, "error", err)
+						fpMutex.Lock()
+						stageErrors = append(stageErrors, err)
+						fpMutex.Unlock()
+					}
 					
 					// Process robots matches
 					for _, app := range robotsMatches {
 						fpMutex.Lock()
-						uniqueFingerprints.SetIfNotExists(app.application, app.version, app.confidence)
+						uniqueFingerprints.SetWithVector(app.application, app.version, app.confidence, app.vector())
 						fpMutex.Unlock()
 					}
+					budget.finish(StageRobots)
+					progress.stage(StageRobots, err)
+				}()
+			} else {
+				close(robotsDone)
+			}
+
+			// Probe default error pages if enabled
+			if enabled.errorPage && parsedURL.Scheme != "" && parsedURL.Host != "" {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					defer stats.recoverPanic(errorPagePart)
+
+					probeCtx, probeCancel := context.WithTimeout(ctx, 5*time.Second)
+					defer probeCancel()
+
+					probeStart := time.Now()
+					var probeTech []matchPartResult
+					select {
+					case <-robotsDone:
+						probeTech = s.probeErrorPages(probeCtx, parsedURL, robots)
+					case <-probeCtx.Done():
+					}
+					stats.addFetch("errorPage", time.Since(probeStart))
+					for _, app := range probeTech {
+						fpMutex.Lock()
+						uniqueFingerprints.SetWithVector(app.application, app.version, app.confidence, app.vector())
+						fpMutex.Unlock()
+					}
+					budget.finish(StageErrorPage)
+					progress.stage(StageErrorPage, nil)
+				}()
+			}
+
+			// Capture the raw header order if header order probing is enabled,
+			// except in replays, since the probe bypasses the transport
+			if enabled.headerOrder && s.replay == nil && parsedURL.Scheme != "" && parsedURL.Host != "" {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					defer stats.recoverPanic(headerOrderPart)
+
+					probeCtx, probeCancel := context.WithTimeout(ctx, 5*time.Second)
+					defer probeCancel()
+
+					probeStart := time.Now()
+					names, apps := s.analyzeHeaderOrder(probeCtx, parsedURL)
+					stats.addFetch("headerOrder", time.Since(probeStart))
+					fpMutex.Lock()
+					headerOrder = names
+					for _, app := range apps {
+						uniqueFingerprints.SetWithVector(app.application, app.version, app.confidence, app.vector())
+					}
+					fpMutex.Unlock()
+					budget.finish(StageHeaderOrder)
+					progress.stage(StageHeaderOrder, nil)
+				}()
+			}
+
+			// Probe the alternate ports of the host if enabled
+			if enabled.ports && parsedURL.Scheme != "" && parsedURL.Host != "" {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					defer stats.recoverPanic(portsPart)
+
+					probeCtx, probeCancel := context.WithTimeout(ctx, 5*time.Second)
+					defer probeCancel()
+
+					probeStart := time.Now()
+					responded, apps := s.probePorts(probeCtx, parsedURL)
+					stats.addFetch("ports", time.Since(probeStart))
+					fpMutex.Lock()
+					ports = responded
+					for _, app := range apps {
+						uniqueFingerprints.SetWithVector(app.application, app.version, app.confidence, app.vector())
+					}
+					fpMutex.Unlock()
+					budget.finish(StagePorts)
+					progress.stage(StagePorts, nil)
 				}()
 			}
 		}
 	}
 	
-	// Process TLS certificate issuer if available
-	if certIssuer != "" {
-		for _, app := range s.fingerprints.matchString(certIssuer, certIssuerPart, s.regexTimeout) {
-			fpMutex.Lock()
-			uniqueFingerprints.SetIfNotExists(app.application, app.version, app.confidence)
-			fpMutex.Unlock()
-		}
-	}
-
 	// Signal that no more URLs will be sent to the asset fetcher
 	// This must be done after HTML parsing is complete
 	assetFetcher.Stop()
 	
 	// Wait for all asynchronous operations to complete
 	wg.Wait()
+	if enabled.assets {
+		budget.finish(StageAssets)
+	}
 	
 	// Assets are already populated in the maps we passed to the fetcher
 	
 	// Process JavaScript content
 	if len(jsContent) > 0 {
+		matchStart := time.Now()
+		var jsTech []matchPartResult
+
 		// Extract global variables from all scripts
 		mergedJSGlobals := make(map[string]string)
 		detectedLibraries := make(map[string]string)
 		propertyPaths := make(map[string]string)
 		jsClasses := []string{}
 
+		// Extract the globals of the scripts concurrently, then merge them in
+		// the order of their URLs
+		scriptURLs := sortedKeys(jsContent)
+		extracted := make([]JSExtractionResult, len(scriptURLs))
+		forEach(len(scriptURLs), s.matchWorkers, func(i int) {
+			defer stats.recoverPanic(jsPart)
+			extracted[i] = ExtractJSGlobals(jsContent[scriptURLs[i]])
+		})
+
 		// Process each script file
-		for scriptURL, content := range jsContent {
-			result := ExtractJSGlobals(content)
+		for i, scriptURL := range scriptURLs {
+			result := extracted[i]
 
 			// Merge high confidence variables
 			for name, value := range result.HighConfidence {
@@ -263,7 +500,7 @@ func (s *Wappalyze) analyzeWithPipeline(resp *http.Response, body []byte) richRe
 				}
 
 				fpMutex.Lock()
-				uniqueFingerprints.SetIfNotExists(lib, version, confidence)
+				uniqueFingerprints.SetWithVector(lib, version, confidence, jsPart.String())
 				fpMutex.Unlock()
 			}
 		}
@@ -286,7 +523,7 @@ func (s *Wappalyze) analyzeWithPipeline(resp *http.Response, body []byte) richRe
 							version = value
 						}
 						fpMutex.Lock()
-						uniqueFingerprints.SetIfNotExists(tech, version, 100)
+						uniqueFingerprints.SetWithVector(tech, version, 100, jsPart.String())
 						fpMutex.Unlock()
 						break
 					}
@@ -305,7 +542,7 @@ func (s *Wappalyze) analyzeWithPipeline(resp *http.Response, body []byte) richRe
 			for _, keyword := range keywords {
 				if _, exists := mergedJSGlobals[keyword]; exists {
 					fpMutex.Lock()
-					uniqueFingerprints.SetIfNotExists(framework, "", 90)
+					uniqueFingerprints.SetWithVector(framework, "", 90, jsPart.String())
 					fpMutex.Unlock()
 					break
 				}
@@ -314,43 +551,180 @@ func (s *Wappalyze) analyzeWithPipeline(resp *http.Response, body []byte) richRe
 
 		// Match JS globals against fingerprints
 		if len(mergedJSGlobals) > 0 {
-			jsTech := s.fingerprints.matchMapString(mergedJSGlobals, jsPart, s.regexTimeout)
+			jsTech = func() []matchPartResult {
+				defer stats.recoverPanic(jsPart)
+				return s.fingerprints.matchMapString(mergedJSGlobals, jsPart, s.regexTimeout)
+			}()
 			for _, app := range jsTech {
 				fpMutex.Lock()
-				uniqueFingerprints.SetIfNotExists(app.application, app.version, app.confidence)
+				uniqueFingerprints.SetWithVector(app.application, app.version, app.confidence, app.vector())
 				fpMutex.Unlock()
 			}
 		}
+		stats.addMatch(jsPart, len(jsTech), time.Since(matchStart))
 	}
 	
-	// Process CSS content
+	// Process CSS content, matching the stylesheets concurrently
 	if len(cssContent) > 0 {
+		matchers := make([]matcher, 0, len(cssContent))
 		for _, content := range cssContent {
-			cssTech := s.fingerprints.matchString(content, cssPart, s.regexTimeout)
-			for _, app := range cssTech {
-				fpMutex.Lock()
-				uniqueFingerprints.SetIfNotExists(app.application, app.version, app.confidence)
-				fpMutex.Unlock()
-			}
+			matchers = append(matchers, matcher{cssPart, func() []matchPartResult {
+				return s.fingerprints.matchString(content, cssPart, s.regexTimeout)
+			}})
+		}
+		for _, app := range runMatchers(matchers, s.matchWorkers, stats) {
+			fpMutex.Lock()
+			uniqueFingerprints.SetWithVector(app.application, app.version, app.confidence, app.vector())
+			fpMutex.Unlock()
+		}
+	}
+	if enabled.assets {
+		progress.stage(StageAssets, nil)
+	}
+
+	// Gather inline and external scripts for static request analysis
+	scripts := inlineScripts
+	for _, content := range jsContent {
+		scripts = append(scripts, content)
+	}
+
+	// Detect web app manifests and registered service workers
+	pwaTech := matcher{pwaPart, func() []matchPartResult { return s.analyzePWA(assetFetcher, scripts) }}.run(stats)
+	for _, app := range pwaTech {
+		fpMutex.Lock()
+		uniqueFingerprints.SetWithVector(app.application, app.version, app.confidence, app.vector())
+		fpMutex.Unlock()
+	}
+
+	// Detect consent management platforms and tag managers
+	signals := consentSignals{scriptSources: scriptSources, scripts: scripts}
+	if resp != nil {
+		for _, cookie := range resp.Cookies() {
+			signals.cookies = append(signals.cookies, cookie.Name)
+		}
+	}
+	consentTech := matcher{consentPart, func() []matchPartResult { return s.checkConsent(signals) }}.run(stats)
+	for _, app := range consentTech {
+		fpMutex.Lock()
+		uniqueFingerprints.SetWithVector(app.application, app.version, app.confidence, app.vector())
+		fpMutex.Unlock()
+	}
+
+	// Detect meta-frameworks by the layout of their build output
+	build := buildSignals{urls: append(slices.Clip(scriptSources), linkHrefs...), scripts: jsContent}
+	buildTech := matcher{buildPart, func() []matchPartResult { return s.checkBuild(build) }}.run(stats)
+	for _, app := range buildTech {
+		fpMutex.Lock()
+		uniqueFingerprints.SetWithVector(app.application, app.version, app.confidence, app.vector())
+		fpMutex.Unlock()
+	}
+
+	// Match hostnames of statically discovered XHR/fetch requests
+	xhrTech := matcher{xhrPart, func() []matchPartResult {
+		if xhrHosts := extractXHRHosts(targetURL, scripts); len(xhrHosts) > 0 {
+			return s.checkXHR(xhrHosts)
+		}
+		return nil
+	}}.run(stats)
+	for _, app := range xhrTech {
+		fpMutex.Lock()
+		uniqueFingerprints.SetWithVector(app.application, app.version, app.confidence, app.vector())
+		fpMutex.Unlock()
+	}
+
+	// Deepen the detection of the platforms detected, such as the plugins of
+	// WordPress or the theme of a Shopify store, probing them if enabled
+	platform := platformSignals{
+		urls:       append(slices.Clip(scriptSources), linkHrefs...),
+		styles:     cssContent,
+		globals:    inlineConfigs,
+		generators: generators,
+	}
+	var platforms map[string]platformDetails
+	func() {
+		defer stats.recoverPanic(platformPart)
+
+		probeCtx, probeCancel := context.WithTimeout(ctx, 5*time.Second)
+		defer probeCancel()
+
+		parsedURL, err := url.Parse(targetURL)
+		probe := err == nil && enabled.platform && parsedURL.Scheme != "" && parsedURL.Host != ""
+		probeStart := time.Now()
+		var probed bool
+		platforms, probed = s.enrichPlatforms(probeCtx, parsedURL, uniqueFingerprints.detected, platform, robots, probe)
+		if probed {
+			stats.addFetch("platform", time.Since(probeStart))
+			budget.finish(StagePlatform)
+			progress.stage(StagePlatform, nil)
+		}
+	}()
+	for name, details := range platforms {
+		if details.version != "" {
+			uniqueFingerprints.SetWithVector(name, details.version, 100, platformPart.String())
+			uniqueFingerprints.refineVersion(name, details.version)
 		}
 	}
 
 	// Populate the richResult struct with detected technologies
+	result.url = targetURL
+	if resp != nil {
+		result.statusCode = resp.StatusCode
+		result.webServer = resp.Header.Get("Server")
+	}
+	nearMisses := s.resolveConflicts(parent, uniqueFingerprints)
+	if explainOf(parent) {
+		result.explained = true
+		result.nearMisses = append(uniqueFingerprints.unconfident(), nearMisses...)
+		sortNearMisses(result.nearMisses)
+	}
 	result.technologies = uniqueFingerprints.GetValues()
+	result.detections = uniqueFingerprints.GetDetections()
+	for name, details := range platforms {
+		if detection, ok := result.detections[name]; ok && len(details.components) > 0 {
+			detection.Components = details.components
+			result.detections[name] = detection
+		}
+	}
+	result.protocol = extractProtocolInfo(resp)
+	if enabled.tls {
+		result.protocol.Certificate = extractCertificate(resp)
+	}
+	if enabled.tls && s.tlsPolicy != TLSPolicyInsecure {
+		result.protocol.TLSValidation = verifyCertificate(resp, time.Now())
+		if validation := result.protocol.TLSValidation; validation != nil && !validation.Valid {
+			s.logger.DebugContext(parent, "certificate verification failed", "url", targetURL, "error", validation.Error)
+		}
+	}
+	result.protocol.HeaderOrder = headerOrder
+	result.ports = ports
+	result.email = email
+	result.robots = robots
 	result.title = title
+	result.errors = append(stageErrors, stats.matcherPanics()...)
+	result.skipped = budget.stages()
+
+	s.populateInfo(&result)
+
+	result.stats = stats.finish()
 
+	return result
+}
+
+// populateInfo sets the application and category info of the technologies of
+// result, and its stack
+func (s *Wappalyze) populateInfo(result *richResult) {
 	// Populate application info
 	result.appInfo = make(map[string]AppInfo, len(result.technologies))
 	for app := range result.technologies {
 		if fingerprint, ok := s.fingerprints.Apps[app]; ok {
-			result.appInfo[app] = AppInfoFromFingerprint(fingerprint)
+			result.appInfo[app] = appInfoFromFingerprint(fingerprint, s.categories)
 		}
 
 		// Handle colon separated values
 		if strings.Contains(app, versionSeparator) {
 			if parts := strings.Split(app, versionSeparator); len(parts) == 2 {
 				if fingerprint, ok := s.fingerprints.Apps[parts[0]]; ok {
-					result.appInfo[app] = AppInfoFromFingerprint(fingerprint)
+					result.appInfo[app] = appInfoFromFingerprint(fingerprint, s.categories)
 				}
 			}
 		}
@@ -359,12 +733,15 @@ func (s *Wappalyze) analyzeWithPipeline(resp *http.Response, body []byte) richRe
 	// Populate category info
 	result.categoryInfo = make(map[string]CatsInfo, len(result.technologies))
 	for app := range result.technologies {
-		if fingerprint, ok := s.fingerprints.Apps[app]; ok {
+		// Versioned technologies are keyed as in PHP:8.2.0
+		name, _, _ := strings.Cut(app, versionSeparator)
+		if fingerprint, ok := s.fingerprints.Apps[name]; ok {
 			result.categoryInfo[app] = CatsInfo{
 				Cats: fingerprint.cats,
 			}
 		}
 	}
 
-	return result
-}
\ No newline at end of file
+	annotateEOL(result.detections, time.Now())
+	result.stack = s.stackOf(result.detections)
+}
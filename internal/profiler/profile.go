@@ -0,0 +1,171 @@
+package profiler
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// Profile is a named preset of the vectors an analysis runs and of the
+// requests it may send beyond the page itself
+type Profile string
+
+const (
+	// ProfileFast only matches the page: headers, cookies, protocol, TLS
+	// certificate and HTML. No DNS lookups, robots.txt or assets are fetched.
+	ProfileFast Profile = "fast"
+	// ProfileStandard also looks up DNS and email authentication records and
+	// fetches robots.txt and the scripts, stylesheets and manifest of the page. Error page, header order
+	// and platform probing run if enabled with their options. It is the default.
+	ProfileStandard Profile = "standard"
+	// ProfileDeep runs everything standard does, plus the error page, header
+	// order and platform probes, which send additional requests to the target.
+	ProfileDeep Profile = "deep"
+)
+
+// Profiles lists the available profiles, from the lightest to the heaviest
+var Profiles = []Profile{ProfileFast, ProfileStandard, ProfileDeep}
+
+// ParseProfile returns the profile with the given name. An empty name is
+// the standard profile.
+func ParseProfile(name string) (Profile, error) {
+	if name == "" {
+		return ProfileStandard, nil
+	}
+	for _, profile := range Profiles {
+		if string(profile) == name {
+			return profile, nil
+		}
+	}
+	return "", fmt.Errorf("unknown profile %q, expected fast, standard or deep", name)
+}
+
+// profileKey is the context key of the profile of an analysis
+type profileKey struct{}
+
+// ProfileContext returns a context that runs the analyses started with it,
+// e.g. through FingerprintURL, with profile instead of the profile of the
+// instance. Servers use it to let each request choose its profile.
+func ProfileContext(ctx context.Context, profile Profile) context.Context {
+	return context.WithValue(ctx, profileKey{}, profile)
+}
+
+// Vector is a detection vector that can be disabled, skipping both the
+// gathering of its data and its matchers
+type Vector string
+
+const (
+	// VectorDNS looks up the DNS records of the target
+	VectorDNS Vector = "dns"
+	// VectorEmail looks up the SPF, DMARC and DKIM records of the domain of the
+	// target. It sends DNS queries too, so disabling VectorDNS disables it.
+	VectorEmail Vector = "email"
+	// VectorRobots fetches and matches robots.txt
+	VectorRobots Vector = "robots"
+	// VectorTLS matches the issuer of the TLS certificate of the page
+	VectorTLS Vector = "tls"
+	// VectorDOM matches CSS selectors against the parsed page
+	VectorDOM Vector = "dom"
+	// VectorJS fetches external scripts and matches their globals
+	VectorJS Vector = "js"
+	// VectorCSS fetches stylesheets and matches their content
+	VectorCSS Vector = "css"
+	// VectorAssets fetches the scripts, stylesheets, manifest and service
+	// workers of the page, so disabling it disables VectorJS and VectorCSS too
+	VectorAssets Vector = "assets"
+)
+
+// Vectors lists the vectors that can be disabled
+var Vectors = []Vector{VectorDNS, VectorEmail, VectorRobots, VectorTLS, VectorDOM, VectorJS, VectorCSS, VectorAssets}
+
+// ParseVectors returns the vectors of a comma separated list of names, such
+// as "dns,robots". An empty list has no vectors.
+func ParseVectors(list string) ([]Vector, error) {
+	var vectors []Vector
+	for _, name := range strings.Split(list, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		if !slices.Contains(Vectors, Vector(name)) {
+			return nil, fmt.Errorf("unknown vector %q, expected one of dns, email, robots, tls, dom, js, css or assets", name)
+		}
+		vectors = append(vectors, Vector(name))
+	}
+	return vectors, nil
+}
+
+// stages are the optional stages an analysis runs
+type stages struct {
+	dns         bool
+	email       bool
+	robots      bool
+	tls         bool
+	dom         bool
+	js          bool
+	css         bool
+	assets      bool
+	errorPage   bool
+	headerOrder bool
+	ports       bool
+	platform    bool
+}
+
+// profileOf returns the profile of an analysis run with ctx: the profile of
+// the context, or else the one of the instance
+func (s *Wappalyze) profileOf(ctx context.Context) Profile {
+	if profile, ok := ctx.Value(profileKey{}).(Profile); ok {
+		return profile
+	}
+	return s.profile
+}
+
+// stages returns the optional stages of an analysis run with ctx, from its
+// profile less the vectors disabled on the instance
+func (s *Wappalyze) stages(ctx context.Context) stages {
+	enabled := stages{dns: true, email: true, robots: true, tls: true, dom: true, js: true, css: true, assets: true}
+	switch s.profileOf(ctx) {
+	case ProfileFast:
+		enabled = stages{tls: true, dom: true}
+	case ProfileDeep:
+		enabled.errorPage = true
+		enabled.headerOrder = true
+		enabled.platform = true
+		enabled.ports = len(s.probePortList) > 0
+	default:
+		enabled.errorPage = s.errorPageProbing
+		enabled.headerOrder = s.headerOrderProbing
+		enabled.platform = s.platformProbing
+		enabled.ports = len(s.probePortList) > 0
+	}
+
+	for vector := range s.disabledVectors {
+		switch vector {
+		case VectorDNS:
+			enabled.dns = false
+		case VectorEmail:
+			enabled.email = false
+		case VectorRobots:
+			enabled.robots = false
+		case VectorTLS:
+			enabled.tls = false
+		case VectorDOM:
+			enabled.dom = false
+		case VectorJS:
+			enabled.js = false
+		case VectorCSS:
+			enabled.css = false
+		case VectorAssets:
+			enabled.assets = false
+		}
+	}
+	if !enabled.dns {
+		enabled.email = false
+	}
+	if !enabled.assets {
+		enabled.js = false
+		enabled.css = false
+	}
+	return enabled
+}
@@ -0,0 +1,204 @@
+package profiler
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseProfile(t *testing.T) {
+	tests := []struct {
+		name     string
+		expected Profile
+		wantErr  bool
+	}{
+		{name: "", expected: ProfileStandard},
+		{name: "fast", expected: ProfileFast},
+		{name: "standard", expected: ProfileStandard},
+		{name: "deep", expected: ProfileDeep},
+		{name: "thorough", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		profile, err := ParseProfile(tt.name)
+		if tt.wantErr {
+			require.Error(t, err, "profile %q should be rejected", tt.name)
+			continue
+		}
+		require.NoError(t, err, "could not parse profile %q", tt.name)
+		require.Equal(t, tt.expected, profile, "wrong profile for %q", tt.name)
+	}
+}
+
+func TestProfiles(t *testing.T) {
+	var mutex sync.Mutex
+	requests := make(map[string]int)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mutex.Lock()
+		requests[r.URL.Path]++
+		mutex.Unlock()
+
+		switch r.URL.Path {
+		case "/":
+			w.Header().Set("Content-Type", "text/html")
+			w.Write([]byte(`<html><head><script src="/app.js"></script></head><body></body></html>`))
+		case "/app.js":
+			w.Header().Set("Content-Type", "application/javascript")
+			w.Write([]byte(`var app = 1;`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	wappalyzer, err := New(WithProfile(ProfileFast))
+	require.NoError(t, err, "could not create wappalyzer")
+
+	tests := []struct {
+		profile Profile
+		// paths that must and must not be requested
+		requested    []string
+		notRequested []string
+		// whether probes send requests to other paths
+		probes bool
+	}{
+		{profile: ProfileFast, requested: []string{"/"}, notRequested: []string{"/robots.txt", "/app.js"}},
+		{profile: ProfileStandard, requested: []string{"/", "/robots.txt", "/app.js"}},
+		{profile: ProfileDeep, requested: []string{"/", "/robots.txt", "/app.js"}, probes: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.profile), func(t *testing.T) {
+			mutex.Lock()
+			clear(requests)
+			mutex.Unlock()
+
+			ctx := context.Background()
+			if tt.profile != ProfileFast {
+				ctx = ProfileContext(ctx, tt.profile)
+			}
+			_, err := wappalyzer.FingerprintURL(ctx, server.URL)
+			require.NoError(t, err, "could not fingerprint url")
+
+			mutex.Lock()
+			defer mutex.Unlock()
+			for _, path := range tt.requested {
+				require.Contains(t, requests, path, "%s should be requested", path)
+			}
+			for _, path := range tt.notRequested {
+				require.NotContains(t, requests, path, "%s should not be requested", path)
+			}
+
+			var other int
+			for path, count := range requests {
+				if path != "/robots.txt" && path != "/app.js" {
+					other += count
+				}
+			}
+			if tt.probes {
+				require.Greater(t, other, 1, "probes should send requests")
+			} else {
+				require.Equal(t, 1, other, "only the page should be requested besides assets")
+			}
+		})
+	}
+}
+
+func TestParseVectors(t *testing.T) {
+	tests := []struct {
+		list     string
+		expected []Vector
+		wantErr  bool
+	}{
+		{list: ""},
+		{list: "dns", expected: []Vector{VectorDNS}},
+		{list: "dns, robots,", expected: []Vector{VectorDNS, VectorRobots}},
+		{list: "dns,screenshots", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		vectors, err := ParseVectors(tt.list)
+		if tt.wantErr {
+			require.Error(t, err, "list %q should be rejected", tt.list)
+			continue
+		}
+		require.NoError(t, err, "could not parse list %q", tt.list)
+		require.Equal(t, tt.expected, vectors, "wrong vectors for %q", tt.list)
+	}
+}
+
+func TestDisabledVectors(t *testing.T) {
+	var mutex sync.Mutex
+	requests := make(map[string]int)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mutex.Lock()
+		requests[r.URL.Path]++
+		mutex.Unlock()
+
+		switch r.URL.Path {
+		case "/":
+			w.Header().Set("Content-Type", "text/html")
+			w.Write([]byte(`<html><head><script src="/app.js"></script><link rel="stylesheet" href="/app.css"></head><body></body></html>`))
+		case "/app.js":
+			w.Header().Set("Content-Type", "application/javascript")
+			w.Write([]byte(`var app = 1;`))
+		case "/app.css":
+			w.Header().Set("Content-Type", "text/css")
+			w.Write([]byte(`body { margin: 0; }`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name         string
+		disabled     []Vector
+		requested    []string
+		notRequested []string
+	}{
+		{name: "none", requested: []string{"/", "/robots.txt", "/app.js", "/app.css"}},
+		{name: "robots", disabled: []Vector{VectorRobots}, requested: []string{"/app.js", "/app.css"}, notRequested: []string{"/robots.txt"}},
+		{name: "js", disabled: []Vector{VectorJS}, requested: []string{"/app.css"}, notRequested: []string{"/app.js"}},
+		{name: "css", disabled: []Vector{VectorCSS}, requested: []string{"/app.js"}, notRequested: []string{"/app.css"}},
+		{name: "assets", disabled: []Vector{VectorAssets}, requested: []string{"/robots.txt"}, notRequested: []string{"/app.js", "/app.css"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mutex.Lock()
+			clear(requests)
+			mutex.Unlock()
+
+			wappalyzer, err := New(WithDisabledVectors(tt.disabled...))
+			require.NoError(t, err, "could not create wappalyzer")
+			_, err = wappalyzer.FingerprintURL(context.Background(), server.URL)
+			require.NoError(t, err, "could not fingerprint url")
+
+			mutex.Lock()
+			defer mutex.Unlock()
+			for _, path := range tt.requested {
+				require.Contains(t, requests, path, "%s should be requested", path)
+			}
+			for _, path := range tt.notRequested {
+				require.NotContains(t, requests, path, "%s should not be requested", path)
+			}
+		})
+	}
+
+	t.Run("matchers", func(t *testing.T) {
+		wappalyzer, err := New(WithDisabledVectors(VectorDNS, VectorTLS, VectorDOM))
+		require.NoError(t, err, "could not create wappalyzer")
+
+		enabled := wappalyzer.stages(ProfileContext(context.Background(), ProfileDeep))
+		require.False(t, enabled.dns, "dns should be disabled whatever the profile")
+		require.False(t, enabled.tls, "tls should be disabled")
+		require.False(t, enabled.dom, "dom should be disabled")
+		require.True(t, enabled.robots, "robots should stay enabled")
+		require.True(t, enabled.errorPage, "deep probes should stay enabled")
+	})
+}
@@ -2,24 +2,64 @@ package profiler
 
 import (
 	"context"
-	"crypto/tls"
-	"crypto/x509"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"log/slog"
 	"net/http"
+	"net/netip"
 	"net/url"
 	"os"
-	"sync"
+	"runtime"
+	"slices"
+	"strings"
 	"time"
+
+	"github.com/kavinsood/kitsune/internal/resolve"
 )
 
 // richResult contains all possible outputs from technology detection
 type richResult struct {
-	technologies map[string]struct{} // Detected technologies
-	title        string              // Page title
-	appInfo      map[string]AppInfo  // Application info
-	categoryInfo map[string]CatsInfo // Category info
+	url          string               // URL the analyzed response was fetched from
+	statusCode   int                  // HTTP status code of the analyzed response
+	webServer    string               // Server header of the analyzed response
+	technologies map[string]struct{}  // Detected technologies
+	title        string               // Page title
+	appInfo      map[string]AppInfo   // Application info
+	categoryInfo map[string]CatsInfo  // Category info
+	detections   map[string]Detection // Detection details by technology name
+	protocol     ProtocolInfo         // Protocol metadata of the main response
+	stats        AnalysisStats        // Timings and telemetry of the analysis
+	errors       []error              // Failures of secondary stages, as *AnalysisError
+	fromCache    bool                 // Whether the result was served from the result cache
+	notModified  bool                 // Whether the target answered the revalidation with 304 Not Modified
+	skipped      []Stage              // Stages cut short by the budget
+	hosts        []HostAnalysis       // Variants of the target host analyzed, with host aliases
+	canonicalURL string               // URL the variants of the target host settle on, with host aliases
+	ports        []PortProbe          // Alternate ports that responded, with port probing
+	location     *Location            // Location of the address of the page, with GeoIP
+	email        *EmailInfo           // Email authentication setup of the domain
+	robots       *RobotsTxt           // Parsed robots.txt of the host
+	stack        Stack                // Primary technologies of the main categories
+	explained    bool                 // Whether the analysis reports its near misses
+	nearMisses   []NearMiss           // Technologies matched but left out, when explained
+	trace        *Trace               // Events of the analysis, when traced
+}
+
+// GetURL returns the URL the analyzed response was fetched from
+func (r richResult) GetURL() string {
+	return r.url
+}
+
+// GetStatusCode returns the HTTP status code of the analyzed response
+func (r richResult) GetStatusCode() int {
+	return r.statusCode
+}
+
+// GetWebServer returns the Server header of the analyzed response
+func (r richResult) GetWebServer() string {
+	return r.webServer
 }
 
 // GetTechnologies returns the detected technologies map
@@ -27,70 +67,220 @@ func (r richResult) GetTechnologies() map[string]struct{} {
 	return r.technologies
 }
 
+// GetTitle returns the page title
+func (r richResult) GetTitle() string {
+	return r.title
+}
+
+// GetAppInfo returns information about each detected technology, keyed by name
+func (r richResult) GetAppInfo() map[string]AppInfo {
+	return r.appInfo
+}
+
+// GetDetections returns how each technology was detected, keyed by name
+func (r richResult) GetDetections() map[string]Detection {
+	return r.detections
+}
+
+// GetCategories returns the category IDs of each detected technology, keyed
+// as GetTechnologies. Wappalyze.Categories names the IDs.
+func (r richResult) GetCategories() map[string]CatsInfo {
+	return r.categoryInfo
+}
+
+// GetProtocol returns the protocol metadata observed on the main response
+func (r richResult) GetProtocol() ProtocolInfo {
+	return r.protocol
+}
+
+// GetStats returns the timings and telemetry collected during the analysis
+func (r richResult) GetStats() AnalysisStats {
+	return r.stats
+}
+
+// FromCache reports whether the result was served from the result cache
+func (r richResult) FromCache() bool {
+	return r.fromCache
+}
+
+// NotModified reports whether the cached result was revalidated with a
+// conditional request the target answered with 304 Not Modified, so the page
+// was not analyzed again
+func (r richResult) NotModified() bool {
+	return r.notModified
+}
+
+// GetErrors returns the failures of secondary stages such as robots.txt and DNS,
+// and the matchers that panicked (ErrMatcherPanic). These do not fail the
+// analysis; each error is an *AnalysisError.
+func (r richResult) GetErrors() []error {
+	return r.errors
+}
+
+// PartialResult reports whether the budget of the analysis ran out before every
+// stage finished. The result then holds the detections gathered in time.
+func (r richResult) PartialResult() bool {
+	return len(r.skipped) > 0
+}
+
+// GetSkippedStages returns the stages the budget of the analysis cut short
+func (r richResult) GetSkippedStages() []Stage {
+	return r.skipped
+}
+
 // Wappalyze is a client for working with tech detection
 type Wappalyze struct {
-	original      *Fingerprints
-	fingerprints  *CompiledFingerprints
-	regexTimeout  time.Duration
-	httpClient    *http.Client
-	certInfoCache *sync.Map
+	original     *Fingerprints
+	fingerprints *CompiledFingerprints
+	regexTimeout time.Duration
+	httpClient   *http.Client
+	// tlsPolicy decides how the client treats invalid certificates
+	tlsPolicy TLSPolicy
+	// ipFamily restricts the addresses the client connects to, if set
+	ipFamily IPFamily
+	// transportMiddleware wraps the transports of all outbound requests
+	transportMiddleware []TransportMiddleware
+	// resourceTransport sends the robots.txt and asset requests, which
+	// verify certificates whatever the TLS policy
+	resourceTransport http.RoundTripper
+	// dnsClient queries the resolvers of the DNS and email vectors
+	dnsClient DNSClient
+	// dnsCacheSize is the number of DNS answers the resolver caches
+	dnsCacheSize int
+	// resolver looks up the records of the DNS and email vectors through
+	// dnsClient
+	resolver *resolve.Resolver
+	// recorder records the traffic of the instance, if set
+	recorder *Recorder
+	// replay answers the requests of the instance instead of the network, if set
+	replay *Fixture
+
+	// errorPageProbing enables the opt-in error page probing stage
+	errorPageProbing bool
+	// headerOrderProbing enables the opt-in raw header order capture stage
+	headerOrderProbing bool
+	// platformProbing enables the opt-in platform probing stage
+	platformProbing bool
+	// probePortList lists the alternate ports of the opt-in port probing stage
+	probePortList []int
+	// geoIP locates the address of the page, if set
+	geoIP GeoIPDatabase
+	// retryPolicy configures retries of transient fetch failures
+	retryPolicy RetryPolicy
+	// schemeFallback retries a failed fetch over the other scheme
+	schemeFallback bool
+	// cache holds the optional result cache in front of FingerprintURL
+	cache cacheConfig
+	// targetCheck optionally vets each URL before it is fetched
+	targetCheck func(ctx context.Context, targetURL string) error
+	// addressCheck optionally vets each address before it is connected to
+	addressCheck func(ctx context.Context, addr netip.Addr) error
+	// onDetection and onStage are optional callbacks invoked during analysis
+	onDetection func(app string, detection Detection)
+	onStage     func(stage string, err error)
+	// logger receives debug logs of loading and analysis
+	logger *slog.Logger
+	// profile selects the optional stages of analyses
+	profile Profile
+	// disabledVectors are skipped whatever the profile
+	disabledVectors map[Vector]struct{}
+	// budget bounds the duration of FingerprintURL, if positive
+	budget time.Duration
+	// assetPolicy limits the asset requests of each analysis
+	assetPolicy AssetPolicy
+	// parseLimits bounds the parsing and scanning of each page
+	parseLimits ParseLimits
+	// rateLimiter limits the rate of all outbound requests, if set
+	rateLimiter *rateLimiter
+	// matchWorkers bounds the goroutines matching the vectors of an analysis
+	matchWorkers int
+	// patternProfiler records the cost of each pattern, if set
+	patternProfiler *PatternProfiler
+	// overlays are fingerprint files applied on top of the loaded fingerprints
+	overlays []string
+	// categories are the embedded categories and those added by overlays
+	categories map[int]categoryItem
+	// tags restricts results to the technologies with one of them, if set
+	tags []string
+	// hostAliases also analyzes the apex or www variant of the target host
+	hostAliases bool
+	// gatherAllEvidence matches the patterns of technologies already settled
+	gatherAllEvidence bool
+	// userAgent is the user agent of the analyses, UserAgentDesktop if unset
+	userAgent UserAgent
+	// politeMode skips the pages robots.txt disallows to the user agent
+	politeMode bool
+	// trace records a trace of every analysis
+	trace bool
 }
 
 // New creates a new tech detection instance
-func New() (*Wappalyze, error) {
+func New(opts ...Option) (*Wappalyze, error) {
+	wappalyze := newWappalyze(opts)
+
+	err := wappalyze.loadFingerprints()
+	if err != nil {
+		return nil, err
+	}
+	return wappalyze, nil
+}
+
+// newWappalyze creates an instance with the default client settings and
+// applies the given options, without loading any fingerprints
+func newWappalyze(opts []Option) *Wappalyze {
 	wappalyze := &Wappalyze{
 		fingerprints: &CompiledFingerprints{
 			Apps:             make(map[string]*CompiledFingerprint),
 			domPatternsByTag: make(map[string]map[string][]string),
+			literalIndexes:   &literalIndexes{},
 		},
-		regexTimeout:  100 * time.Millisecond, // A sensible default
-		certInfoCache: &sync.Map{},
-	}
-
-	// Create the custom transport with the VerifyConnection callback
-	transport := &http.Transport{
-		TLSClientConfig: &tls.Config{
-			InsecureSkipVerify: true, // Required because we are overriding verification
-			VerifyConnection: func(cs tls.ConnectionState) error {
-				// --- SECURITY CRITICAL ---
-				// We MUST perform our own verification here.
-				opts := x509.VerifyOptions{
-					DNSName:       cs.ServerName,
-					Intermediates: x509.NewCertPool(),
-				}
-				if len(cs.PeerCertificates) <= 1 {
-					// Not enough certificates to build a chain with intermediates.
-					// Can still check the single cert against system roots.
-				} else {
-					for _, cert := range cs.PeerCertificates[1:] {
-						opts.Intermediates.AddCert(cert)
-					}
-				}
-
-				if _, err := cs.PeerCertificates[0].Verify(opts); err != nil {
-					// Allow connection to proceed for fingerprinting purposes
-					// even with an invalid cert, but do not cache issuer info.
-					return nil
-				}
-
-				// If verification is successful, cache the issuer's Common Name.
-				issuer := cs.PeerCertificates[0].Issuer.CommonName
-				wappalyze.certInfoCache.Store(cs.ServerName, issuer)
-				return nil
-			},
-		},
+		regexTimeout: 100 * time.Millisecond, // A sensible default
+		logger:       discardLogger,
+		profile:      ProfileStandard,
+		matchWorkers: runtime.GOMAXPROCS(0),
+		categories:   categoriesMapping,
+		tlsPolicy:    TLSPolicyLog,
+		dnsClient:    resolve.DefaultClient,
+		dnsCacheSize: resolve.DefaultCacheSize,
+	}
+
+	for _, opt := range opts {
+		opt(wappalyze)
+	}
+
+	// The TLS policy decides whether the client verifies certificates, and
+	// the IP family which addresses it connects to. Both transports dial
+	// through the address check, if any.
+	var transport, resourceTransport http.RoundTripper = &http.Transport{
+		TLSClientConfig: wappalyze.tlsPolicy.tlsConfig(),
+		DialContext:     wappalyze.ipFamily.dialContext(wappalyze.dialControl()),
+	}, http.DefaultTransport
+	if wappalyze.addressCheck != nil {
+		checked := http.DefaultTransport.(*http.Transport).Clone()
+		checked.DialContext = IPFamilyAny.dialContext(wappalyze.dialControl())
+		resourceTransport = checked
+	}
+	// A replayed fixture stands in for the network, and the recorder records
+	// the requests as the middleware sends them
+	if wappalyze.replay != nil {
+		transport = replayTransport(wappalyze.replay)
+		resourceTransport = transport
+		wappalyze.dnsClient = replayDNSClient(wappalyze.replay)
+	}
+	transport = wappalyze.checkedTransport(transport)
+	resourceTransport = wappalyze.checkedTransport(resourceTransport)
+	if wappalyze.recorder != nil {
+		transport = wappalyze.recorder.transport(transport)
+		resourceTransport = wappalyze.recorder.transport(resourceTransport)
+		wappalyze.dnsClient = wappalyze.recorder.dnsClient(wappalyze.dnsClient)
 	}
-
 	wappalyze.httpClient = &http.Client{
 		Timeout:   10 * time.Second,
-		Transport: transport,
-	}
-
-	err := wappalyze.loadFingerprints()
-	if err != nil {
-		return nil, err
+		Transport: wappalyze.wrapTransport(transport),
 	}
-	return wappalyze, nil
+	wappalyze.resourceTransport = wappalyze.wrapTransport(resourceTransport)
+	wappalyze.resolver = resolve.New(wappalyze.dnsClient, resolve.DefaultServers, wappalyze.dnsCacheSize)
+	return wappalyze
 }
 
 // NewFromFile creates a new tech detection instance from a file
@@ -98,13 +288,8 @@ func New() (*Wappalyze, error) {
 // loadEmbedded indicates whether to load the embedded fingerprints
 // supersede indicates whether to overwrite the embedded fingerprints (if loaded) with the file fingerprints if the app name conflicts
 // supersede is only used if loadEmbedded is true
-func NewFromFile(filePath string, loadEmbedded, supersede bool) (*Wappalyze, error) {
-	wappalyze := &Wappalyze{
-		fingerprints: &CompiledFingerprints{
-			Apps:             make(map[string]*CompiledFingerprint),
-			domPatternsByTag: make(map[string]map[string][]string),
-		},
-	}
+func NewFromFile(filePath string, loadEmbedded, supersede bool, opts ...Option) (*Wappalyze, error) {
+	wappalyze := newWappalyze(opts)
 
 	err := wappalyze.loadFingerprintsFromFile(filePath, loadEmbedded, supersede)
 	if err != nil {
@@ -114,6 +299,19 @@ func NewFromFile(filePath string, loadEmbedded, supersede bool) (*Wappalyze, err
 	return wappalyze, nil
 }
 
+// NewForCategories creates a new tech detection instance that only loads the
+// embedded fingerprints of the given categories and of the technologies they
+// imply. It uses less memory than New when only a few categories matter.
+func NewForCategories(categories []int, opts ...Option) (*Wappalyze, error) {
+	wappalyze := newWappalyze(opts)
+
+	err := wappalyze.loadFingerprintsForCategories(categories)
+	if err != nil {
+		return nil, err
+	}
+	return wappalyze, nil
+}
+
 // GetFingerprints returns the original fingerprints
 func (s *Wappalyze) GetFingerprints() *Fingerprints {
 	return s.original
@@ -130,27 +328,94 @@ func (s *Wappalyze) GetCompiledFingerprints() *CompiledFingerprints {
 // This implementation uses a fully parallel concurrency model for all I/O operations.
 func (s *Wappalyze) analyze(resp *http.Response, body []byte) richResult {
 	// Call the new fully pipelined implementation
-	return s.analyzeWithPipeline(resp, body)
+	return s.filterTags(s.analyzeWithPipeline(resp, body), s.tags)
 }
 
-// loadFingerprints loads the fingerprints and compiles them
+// loadFingerprints loads the embedded fingerprints and compiles them. They are
+// read from the binary data, whose patterns are validated and compiled on first use.
 func (s *Wappalyze) loadFingerprints() error {
-	var fingerprintsStruct Fingerprints
-	err := json.Unmarshal([]byte(fingerprints), &fingerprintsStruct)
+	embedded, err := embeddedFingerprints()
+	if err != nil {
+		return err
+	}
+	overlaid, err := s.applyOverlays(embedded)
 	if err != nil {
 		return err
 	}
 
-	s.original = &fingerprintsStruct
-	for appName, fingerprint := range fingerprintsStruct.Apps {
-		s.fingerprints.Apps[appName] = compileFingerprint(fingerprint)
+	s.compileEmbedded(embedded, overlaid)
+	return nil
+}
+
+// loadFingerprintsForCategories loads the embedded fingerprints of the given
+// categories and of the technologies they imply, and compiles them
+func (s *Wappalyze) loadFingerprintsForCategories(categories []int) error {
+	if len(categories) == 0 {
+		return errors.New("no categories given")
+	}
+
+	embedded, err := embeddedFingerprints()
+	if err != nil {
+		return err
+	}
+	// Overlays may add the categories and technologies of them, so they come first
+	overlaid, err := s.applyOverlays(embedded)
+	if err != nil {
+		return err
+	}
+	for _, id := range categories {
+		if _, ok := s.categories[id]; !ok {
+			return fmt.Errorf("unknown category: %d", id)
+		}
+	}
+	embedded.Apps = selectCategories(embedded.Apps, categories)
+	s.compileEmbedded(embedded, overlaid)
+	return nil
+}
+
+// compileEmbedded compiles the embedded fingerprints, whose patterns are
+// validated, except those added or extended by an overlay
+func (s *Wappalyze) compileEmbedded(embedded *Fingerprints, overlaid map[string]struct{}) {
+	s.original = embedded
+	for appName, fingerprint := range embedded.Apps {
+		if _, ok := overlaid[appName]; ok {
+			s.fingerprints.Apps[appName] = compileFingerprint(appName, fingerprint, s.logger, s.patternProfiler)
+		} else {
+			s.fingerprints.Apps[appName] = compileValidatedFingerprint(appName, fingerprint, s.logger, s.patternProfiler)
+		}
 
 		// Register DOM patterns for optimization
 		for domSelector := range fingerprint.Dom {
 			s.fingerprints.registerDOMPattern(appName, domSelector)
 		}
 	}
-	return nil
+}
+
+// selectCategories returns the apps in one of the categories, along with the
+// apps they imply, directly or through other implied apps
+func selectCategories(apps map[string]*Fingerprint, categories []int) map[string]*Fingerprint {
+	var pending []string
+	for appName, fingerprint := range apps {
+		if slices.ContainsFunc(fingerprint.Cats, func(cat int) bool { return slices.Contains(categories, cat) }) {
+			pending = append(pending, appName)
+		}
+	}
+
+	selected := make(map[string]*Fingerprint, len(pending))
+	for len(pending) > 0 {
+		appName := pending[len(pending)-1]
+		pending = pending[:len(pending)-1]
+
+		fingerprint, ok := apps[appName]
+		if _, done := selected[appName]; done || !ok {
+			continue
+		}
+		selected[appName] = fingerprint
+		for _, implied := range fingerprint.Implies {
+			pending = append(pending, impliedName(implied))
+		}
+	}
+	return selected
 }
 
 // loadFingerprints loads the fingerprints from the provided file and compiles them
@@ -172,36 +437,42 @@ func (s *Wappalyze) loadFingerprintsFromFile(filePath string, loadEmbedded, supe
 	}
 
 	if loadEmbedded {
-		var embedded Fingerprints
-		err := json.Unmarshal([]byte(fingerprints), &embedded)
+		embedded, err := embeddedFingerprints()
 		if err != nil {
 			return err
 		}
 
-		s.original = &embedded
+		s.original = embedded
 
 		for app, fingerprint := range fingerprintsStruct.Apps {
-			if _, ok := s.original.Apps[app]; ok && supersede {
-				s.original.Apps[app] = fingerprint
-			} else {
-				s.original.Apps[app] = fingerprint
+			if _, ok := s.original.Apps[app]; ok && !supersede {
+				continue
 			}
+			s.original.Apps[app] = fingerprint
 		}
 
 	} else {
 		s.original = &fingerprintsStruct
 	}
 
-	for appName, fingerprint := range s.original.Apps {
-		s.fingerprints.Apps[appName] = compileFingerprint(fingerprint)
+	if _, err := s.applyOverlays(s.original); err != nil {
+		return err
+	}
+
+	s.compileApps(s.original.Apps)
+	return nil
+}
+
+// compileApps compiles fingerprints whose patterns are not known to be valid
+func (s *Wappalyze) compileApps(apps map[string]*Fingerprint) {
+	for appName, fingerprint := range apps {
+		s.fingerprints.Apps[appName] = compileFingerprint(appName, fingerprint, s.logger, s.patternProfiler)
 
 		// Register DOM patterns for optimization
 		for domSelector := range fingerprint.Dom {
 			s.fingerprints.registerDOMPattern(appName, domSelector)
 		}
 	}
-
-	return nil
 }
 
 // Fingerprint identifies technologies on a target,
@@ -259,11 +530,65 @@ func (s *Wappalyze) FingerprintWithURL(headers map[string][]string, body []byte,
 
 type UniqueFingerprints struct {
 	values map[string]uniqueFingerprintMetadata
+	// progress is notified of technologies detected for the first time
+	progress *progressReporter
+	// settled receives the technologies matched at full confidence with a
+	// version, if set
+	settled *settledApps
 }
 
 type uniqueFingerprintMetadata struct {
 	confidence int
 	version    string
+	detectedBy []string
+}
+
+// Detection describes how a single technology was detected
+type Detection struct {
+	Version    string   `json:"version,omitempty"`
+	Confidence int      `json:"confidence"`
+	DetectedBy []string `json:"detected_by"`
+	// Hosts are the variants of the target host the technology was detected
+	// on, with WithHostAliases
+	Hosts []string `json:"hosts,omitempty"`
+	// EOL is the end-of-life status of the release cycle of Version, for
+	// the technologies of the embedded endoflife.date snapshot
+	EOL *EOLStatus `json:"eol,omitempty"`
+	// Components are the parts of the technology its deep inspection found,
+	// such as the plugins and themes of WordPress
+	Components []Component `json:"components,omitempty"`
+}
+
+// ComponentType is the kind of a Component
+type ComponentType string
+
+const (
+	// ComponentPlugin is a plugin or extension of the technology
+	ComponentPlugin ComponentType = "plugin"
+	// ComponentTheme is a theme of the technology
+	ComponentTheme ComponentType = "theme"
+	// ComponentStore is a store hosted on the technology
+	ComponentStore ComponentType = "store"
+	// ComponentEdition is the edition of the technology
+	ComponentEdition ComponentType = "edition"
+)
+
+// Component is a part of a detected technology, reported as a child of its
+// Detection
+type Component struct {
+	Type ComponentType `json:"type"`
+	// Name identifies the component, such as the slug of a WordPress plugin
+	Name string `json:"name"`
+	// Title is the display name of the component, if it differs from Name
+	Title string `json:"title,omitempty"`
+	// ID is the identifier of the component on the technology, if any
+	ID      string `json:"id,omitempty"`
+	Version string `json:"version,omitempty"`
+	// Source is where the component was found: "asset" for the URL of an
+	// asset, "style" for the header of a stylesheet, "inline" for a global set
+	// inline, "rest" for the REST API of the technology and "probe" for
+	// another request to it
+	Source string `json:"source"`
 }
 
 func NewUniqueFingerprints() UniqueFingerprints {
@@ -306,10 +631,89 @@ func (u UniqueFingerprints) SetIfNotExists(value, version string, confidence int
 	}
 }
 
+// SetWithVector behaves like SetIfNotExists and additionally records the
+// detection vector that produced the match
+func (u UniqueFingerprints) SetWithVector(value, version string, confidence int, vector string) {
+	previous := u.values[value]
+	u.SetIfNotExists(value, version, confidence)
+
+	metadata := u.values[value]
+	if !slices.Contains(metadata.detectedBy, vector) {
+		metadata.detectedBy = append(metadata.detectedBy, vector)
+		u.values[value] = metadata
+	}
+	// Implied versions are less precise than the patterns of the technology
+	if metadata.confidence >= 100 && metadata.version != "" && vector != "implies" {
+		u.settled.add(value)
+	}
+
+	if previous.confidence == 0 && metadata.confidence > 0 {
+		u.progress.report(ProgressEvent{
+			Type:       ProgressDetection,
+			Vector:     vector,
+			Technology: value,
+			Detection: &Detection{
+				Version:    metadata.version,
+				Confidence: metadata.confidence,
+				DetectedBy: append([]string(nil), metadata.detectedBy...),
+			},
+		})
+	}
+}
+
+// detected reports whether the technology has been detected
+func (u UniqueFingerprints) detected(name string) bool {
+	return u.values[name].confidence > 0
+}
+
+// refineVersion sets the version of a detected technology to version, found
+// by a deeper inspection of it, unless the current one is already as precise
+func (u UniqueFingerprints) refineVersion(name, version string) {
+	metadata, ok := u.values[name]
+	if !ok || version == "" {
+		return
+	}
+	if metadata.version != "" && !strings.HasPrefix(version, metadata.version+".") {
+		return
+	}
+	metadata.version = version
+	u.values[name] = metadata
+}
+
+// GetDetections returns the detected technologies keyed by name, without
+// the version suffix, along with the sorted vectors that detected them
+func (u UniqueFingerprints) GetDetections() map[string]Detection {
+	detections := make(map[string]Detection, len(u.values))
+	for k, v := range u.values {
+		if v.confidence == 0 {
+			continue
+		}
+		detectedBy := append([]string(nil), v.detectedBy...)
+		slices.Sort(detectedBy)
+		detections[k] = Detection{
+			Version:    v.version,
+			Confidence: v.confidence,
+			DetectedBy: detectedBy,
+		}
+	}
+	return detections
+}
+
 type matchPartResult struct {
 	application string
 	confidence  int
 	version     string
+	part        part
+	implied     bool
+}
+
+// vector returns the name of the vector that produced the result,
+// or "implies" for technologies implied by another match
+func (m matchPartResult) vector() string {
+	if m.implied {
+		return "implies"
+	}
+	return m.part.String()
 }
 
 // FingerprintWithTitle identifies technologies on a target,
@@ -404,54 +808,100 @@ func (s *Wappalyze) FingerprintWithInfoAndURL(headers map[string][]string, body
 }
 
 func AppInfoFromFingerprint(fingerprint *CompiledFingerprint) AppInfo {
-	categories := make([]string, 0, len(fingerprint.cats))
+	return appInfoFromFingerprint(fingerprint, categoriesMapping)
+}
+
+// appInfoFromFingerprint returns the info of fingerprint, naming its
+// categories from categories
+func appInfoFromFingerprint(fingerprint *CompiledFingerprint, categories map[int]categoryItem) AppInfo {
+	names := make([]string, 0, len(fingerprint.cats))
 	for _, cat := range fingerprint.cats {
-		if category, ok := categoriesMapping[cat]; ok {
-			categories = append(categories, category.Name)
+		if category, ok := categories[cat]; ok {
+			names = append(names, category.Name)
 		}
 	}
+	slices.Sort(names)
 	return AppInfo{
 		Description: fingerprint.description,
 		Website:     fingerprint.website,
 		Icon:        fingerprint.icon,
 		CPE:         fingerprint.cpe,
-		Categories:  categories,
+		Categories:  names,
+		Tags:        fingerprint.tags,
 	}
 }
 
-// fetchAndAnalyzeRobotsTxt fetches robots.txt from the specified URL and analyzes it for technology fingerprints
+// fetchAndAnalyzeRobotsTxt fetches robots.txt from the specified URL, analyzes it for technology fingerprints
+// and parses it. A missing robots.txt is not an error; fetch failures are returned as an *AnalysisError.
+// The robots.txt polite mode fetched before the page is reused.
+func (s *Wappalyze) fetchAndAnalyzeRobotsTxt(robotsURL string, ctx context.Context, stats *statsRecorder) ([]matchPartResult, *RobotsTxt, error) {
+	var robotsContent []byte
+	if prefetched := robotsPrefetchOf(ctx); prefetched != nil && prefetched.url == robotsURL {
+		robotsContent = prefetched.content
+	} else {
+		var err error
+		if robotsContent, err = s.fetchRobotsTxt(ctx, robotsURL, stats); err != nil {
+			return nil, nil, err
+		}
+	}
+	if robotsContent == nil {
+		return nil, nil, nil
+	}
+
+	// Match robots.txt patterns against content with timeout
+	matches := matcher{robotsPart, func() []matchPartResult {
+		return s.fingerprints.matchString(string(robotsContent), robotsPart, s.regexTimeout)
+	}}.run(stats)
+	return matches, ParseRobotsTxt(robotsContent), nil
+}
 
-func (s *Wappalyze) fetchAndAnalyzeRobotsTxt(robotsURL string, ctx context.Context) []matchPartResult {
+// fetchRobotsTxt fetches robots.txt from the specified URL, returning nil
+// content when the host has none. Fetch failures are returned as an *AnalysisError.
+func (s *Wappalyze) fetchRobotsTxt(ctx context.Context, robotsURL string, stats *statsRecorder) ([]byte, error) {
 	client := &http.Client{
-		Timeout: 5 * time.Second,
+		Timeout:   5 * time.Second,
+		Transport: s.resourceTransport,
 	}
 
 	req, err := http.NewRequestWithContext(ctx, "GET", robotsURL, nil)
 	if err != nil {
-		return nil
+		return nil, &AnalysisError{Stage: StageRobots, URL: robotsURL, Err: err}
 	}
 
-	req.Header.Set("User-Agent", "Mozilla/5.0 (Windows NT 6.3; WOW64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/117.0.5931.0 Safari/537.36")
+	req.Header.Set("User-Agent", userAgentOf(ctx).Header)
 
-	resp, err := client.Do(req)
+	start := time.Now()
+	resp, err := doWithRetry(client, req, s.retryPolicy, s.rateLimiter)
 	if err != nil {
-		return nil
+		stats.addFetch("robots", time.Since(start))
+		return nil, newAnalysisError(StageRobots, robotsURL, err)
 	}
 	defer resp.Body.Close()
+	resp.Body = stats.countBody(resp.Body)
 
 	// Only process if status code is 200
 	if resp.StatusCode != 200 {
-		return nil
+		stats.addFetch("robots", time.Since(start))
+		if isBlockedStatus(resp.StatusCode) {
+			return nil, &AnalysisError{
+				Stage:      StageRobots,
+				URL:        robotsURL,
+				StatusCode: resp.StatusCode,
+				Err:        fmt.Errorf("%w: status %d", ErrBlockedByTarget, resp.StatusCode),
+			}
+		}
+		return nil, nil
 	}
 
 	// Read robots.txt content
 	robotsContent, err := io.ReadAll(io.LimitReader(resp.Body, 1024*1024)) // 1MB limit
+	stats.addFetch("robots", time.Since(start))
 	if err != nil {
-		return nil
+		analysisErr := newAnalysisError(StageRobots, robotsURL, err)
+		analysisErr.StatusCode = resp.StatusCode
+		return nil, analysisErr
 	}
-
-	// Match robots.txt patterns against content with timeout
-	return s.fingerprints.matchString(string(robotsContent), robotsPart, s.regexTimeout)
+	return robotsContent, nil
 }
 
 // FingerprintWithCats identifies technologies on a target,
@@ -0,0 +1,116 @@
+package profiler
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// ProgressEventType is the kind of a ProgressEvent
+type ProgressEventType string
+
+const (
+	// ProgressDetection is reported the first time a technology is detected
+	ProgressDetection ProgressEventType = "detection"
+	// ProgressVector is reported when a detection vector finishes matching
+	ProgressVector ProgressEventType = "vector"
+)
+
+// ProgressEvent is an incremental result reported while an analysis runs
+type ProgressEvent struct {
+	Type ProgressEventType `json:"type"`
+	// Vector is the detection vector the event relates to
+	Vector string `json:"vector"`
+	// Technology and Detection are set on detection events. The detection is
+	// a snapshot; the final result may add vectors, confidence or a version.
+	Technology string     `json:"technology,omitempty"`
+	Detection  *Detection `json:"detection,omitempty"`
+	// Duration is the time spent matching, set on vector events
+	Duration time.Duration `json:"duration,omitempty"`
+}
+
+// ProgressFunc receives incremental results. Calls are serialized, but come
+// from the analysis goroutines, so the function should return quickly.
+type ProgressFunc func(ProgressEvent)
+
+// progressKey is the context key of the progress reporter
+type progressKey struct{}
+
+// WithProgress returns a context that reports incremental results of the
+// analyses run with it to fn, e.g. through FingerprintURL. Results served
+// from the result cache report no progress.
+func WithProgress(ctx context.Context, fn ProgressFunc) context.Context {
+	return context.WithValue(ctx, progressKey{}, &progressReporter{fn: fn})
+}
+
+// progressReporter serializes progress events from concurrent pipeline stages.
+// All methods are safe to call on a nil reporter, which reports nothing.
+type progressReporter struct {
+	mutex       sync.Mutex
+	fn          ProgressFunc
+	onDetection func(app string, detection Detection)
+	onStage     func(stage string, err error)
+	// trace records the detections and stages, if the analysis is traced
+	trace *tracer
+}
+
+// progress returns the reporter of an analysis run with ctx, combining the
+// function set with WithProgress, the callbacks of the instance and the trace
+func (s *Wappalyze) progress(ctx context.Context) *progressReporter {
+	reporter := progressFromContext(ctx)
+	trace := tracerOf(ctx)
+	if s.onDetection == nil && s.onStage == nil && trace == nil {
+		return reporter
+	}
+
+	var fn ProgressFunc
+	if reporter != nil {
+		fn = reporter.report
+	}
+	return &progressReporter{fn: fn, onDetection: s.onDetection, onStage: s.onStage, trace: trace}
+}
+
+// progressFromContext returns the reporter set with WithProgress, if any
+func progressFromContext(ctx context.Context) *progressReporter {
+	if ctx == nil {
+		return nil
+	}
+	progress, _ := ctx.Value(progressKey{}).(*progressReporter)
+	return progress
+}
+
+// report passes an event to the progress function
+func (p *progressReporter) report(event ProgressEvent) {
+	if p == nil {
+		return
+	}
+	if event.Type == ProgressDetection {
+		p.trace.add(TraceEvent{Type: TraceDetection, Technology: event.Technology, Vector: event.Vector})
+	}
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	if p.fn != nil {
+		p.fn(event)
+	}
+	if p.onDetection != nil && event.Type == ProgressDetection {
+		p.onDetection(event.Technology, *event.Detection)
+	}
+}
+
+// stage reports the completion of an analysis stage
+func (p *progressReporter) stage(stage Stage, err error) {
+	if p == nil {
+		return
+	}
+	event := TraceEvent{Type: TraceStage, Stage: stage}
+	if err != nil {
+		event.Error = err.Error()
+	}
+	p.trace.add(event)
+	if p.onStage == nil {
+		return
+	}
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	p.onStage(string(stage), err)
+}
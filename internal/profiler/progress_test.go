@@ -0,0 +1,90 @@
+package profiler
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithProgress(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Server", "nginx/1.25.3")
+		w.Write([]byte("<html><head><title>Test</title></head></html>"))
+	}))
+	defer server.Close()
+
+	wappalyzer, err := New()
+	require.NoError(t, err, "could not create wappalyzer")
+
+	var events []ProgressEvent
+	ctx := WithProgress(context.Background(), func(event ProgressEvent) {
+		events = append(events, event)
+	})
+
+	result, err := wappalyzer.FingerprintURL(ctx, server.URL)
+	require.NoError(t, err, "could not fingerprint url")
+
+	detections := make(map[string]int)
+	vectors := make(map[string]bool)
+	for _, event := range events {
+		switch event.Type {
+		case ProgressDetection:
+			require.NotNil(t, event.Detection, "detection event without detection")
+			detections[event.Technology]++
+		case ProgressVector:
+			vectors[event.Vector] = true
+		}
+	}
+
+	require.Equal(t, 1, detections["Nginx"], "nginx should be reported exactly once")
+	require.Len(t, detections, len(result.GetDetections()), "every detection should be reported")
+	require.True(t, vectors[headersPart.String()], "headers vector completion not reported")
+	require.True(t, vectors[htmlPart.String()], "html vector completion not reported")
+}
+
+func TestCallbackOptions(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Server", "nginx/1.25.3")
+		w.Write([]byte("<html><head><title>Test</title></head></html>"))
+	}))
+	defer server.Close()
+
+	detections := make(map[string]Detection)
+	stages := make(map[string]error)
+	wappalyzer, err := New(
+		WithOnDetection(func(app string, detection Detection) {
+			detections[app] = detection
+		}),
+		WithOnStage(func(stage string, err error) {
+			stages[stage] = err
+		}),
+	)
+	require.NoError(t, err, "could not create wappalyzer")
+
+	// Progress reported through the context is not affected by the callbacks
+	var events int
+	ctx := WithProgress(context.Background(), func(event ProgressEvent) {
+		events++
+	})
+
+	_, err = wappalyzer.FingerprintURL(ctx, server.URL)
+	require.NoError(t, err, "could not fingerprint url")
+
+	require.Equal(t, "1.25.3", detections["Nginx"].Version, "nginx detection not reported")
+	require.Positive(t, events, "context progress not reported")
+	for _, stage := range []Stage{StageMain, StageAssets, StageRobots, StageDNS} {
+		require.Contains(t, stages, string(stage), "stage not reported")
+	}
+	require.NoError(t, stages[string(StageMain)], "main stage should succeed")
+	require.NotContains(t, stages, string(StageErrorPage), "disabled stage should not be reported")
+
+	t.Run("failed-fetch", func(t *testing.T) {
+		stages = make(map[string]error)
+		_, err := wappalyzer.FingerprintURL(context.Background(), "http://127.0.0.1:1")
+		require.Error(t, err, "fetch of a closed port should fail")
+		require.ErrorIs(t, stages[string(StageMain)], err, "main stage failure not reported")
+	})
+}
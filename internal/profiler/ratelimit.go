@@ -0,0 +1,141 @@
+package profiler
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// RateLimit bounds the rate of the requests an instance sends across all of
+// its analyses: page fetches, robots.txt, probes and assets, retries included.
+// Requests wait for their turn rather than fail, so a scan that sends more
+// requests than allowed gets slower, not less accurate.
+type RateLimit struct {
+	// PerSecond is the rate of requests to all hosts. Zero leaves it unlimited.
+	PerSecond float64
+	// PerHostPerSecond is the rate of requests to a single host. Zero leaves
+	// it unlimited.
+	PerHostPerSecond float64
+	// Burst is the number of requests that may be sent at once after an idle
+	// period, globally and to each host. Values below 1 mean 1.
+	Burst int
+}
+
+// WithRateLimit limits the rate of outbound requests with token buckets, one
+// shared by all hosts and one per host. There is no limit by default. The
+// instances created with the same option share its buckets, so the limit
+// holds across them.
+func WithRateLimit(limit RateLimit) Option {
+	limiter := newRateLimiter(limit)
+	return func(s *Wappalyze) {
+		s.rateLimiter = limiter
+	}
+}
+
+// tokenBucket is a token bucket refilled at rate tokens per second
+type tokenBucket struct {
+	mutex  sync.Mutex
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+func newTokenBucket(rate float64, burst int, now time.Time) *tokenBucket {
+	return &tokenBucket{rate: rate, burst: float64(burst), tokens: float64(burst), last: now}
+}
+
+// reserve takes a token and returns how long to wait before it may be used
+func (b *tokenBucket) reserve(now time.Time) time.Duration {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	if elapsed := now.Sub(b.last); elapsed > 0 {
+		b.tokens = min(b.burst, b.tokens+elapsed.Seconds()*b.rate)
+		b.last = now
+	}
+	b.tokens--
+	if b.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-b.tokens / b.rate * float64(time.Second))
+}
+
+// idle reports whether the bucket refilled completely, so it can be dropped
+func (b *tokenBucket) idle(now time.Time) bool {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	return b.tokens+now.Sub(b.last).Seconds()*b.rate >= b.burst
+}
+
+// maxHostBuckets is the number of host buckets above which idle ones are dropped,
+// so that bulk scans of many hosts do not accumulate them
+const maxHostBuckets = 1024
+
+// rateLimiter enforces a RateLimit. A nil limiter does not limit.
+type rateLimiter struct {
+	limit  RateLimit
+	global *tokenBucket
+
+	mutex sync.Mutex
+	hosts map[string]*tokenBucket
+}
+
+func newRateLimiter(limit RateLimit) *rateLimiter {
+	limit.Burst = max(limit.Burst, 1)
+	limiter := &rateLimiter{limit: limit, hosts: make(map[string]*tokenBucket)}
+	if limit.PerSecond > 0 {
+		limiter.global = newTokenBucket(limit.PerSecond, limit.Burst, time.Now())
+	}
+	return limiter
+}
+
+// wait blocks until a request to host may be sent. It returns the error of ctx
+// if ctx is done first.
+func (l *rateLimiter) wait(ctx context.Context, host string) error {
+	if l == nil {
+		return nil
+	}
+
+	now := time.Now()
+	var delay time.Duration
+	if l.global != nil {
+		delay = l.global.reserve(now)
+	}
+	if l.limit.PerHostPerSecond > 0 {
+		delay = max(delay, l.host(host, now).reserve(now))
+	}
+	if delay <= 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// host returns the bucket of host, creating it if needed
+func (l *rateLimiter) host(host string, now time.Time) *tokenBucket {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	bucket, ok := l.hosts[host]
+	if ok {
+		return bucket
+	}
+	if len(l.hosts) >= maxHostBuckets {
+		for name, bucket := range l.hosts {
+			if bucket.idle(now) {
+				delete(l.hosts, name)
+			}
+		}
+	}
+	bucket = newTokenBucket(l.limit.PerHostPerSecond, l.limit.Burst, now)
+	l.hosts[host] = bucket
+	return bucket
+}
@@ -0,0 +1,71 @@
+package profiler
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTokenBucket(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	bucket := newTokenBucket(10, 2, now)
+
+	require.Zero(t, bucket.reserve(now), "burst should not wait")
+	require.Zero(t, bucket.reserve(now), "burst should not wait")
+	require.Equal(t, 100*time.Millisecond, bucket.reserve(now), "wrong delay past the burst")
+	require.Equal(t, 200*time.Millisecond, bucket.reserve(now), "reservations should queue")
+	require.False(t, bucket.idle(now), "bucket in debt is not idle")
+
+	// Tokens are refilled at the rate, up to the burst
+	later := now.Add(time.Second)
+	require.True(t, bucket.idle(later), "refilled bucket should be idle")
+	require.Zero(t, bucket.reserve(later), "refilled bucket should not wait")
+}
+
+func TestRateLimiter(t *testing.T) {
+	var limiter *rateLimiter
+	require.NoError(t, limiter.wait(context.Background(), "example.com"), "nil limiter should not limit")
+
+	limiter = newRateLimiter(RateLimit{PerHostPerSecond: 1})
+	require.NoError(t, limiter.wait(context.Background(), "example.com"), "first request should not wait")
+	require.NoError(t, limiter.wait(context.Background(), "example.org"), "other host should not wait")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	require.ErrorIs(t, limiter.wait(ctx, "example.com"), context.DeadlineExceeded, "second request should wait past the deadline")
+}
+
+func TestWithRateLimit(t *testing.T) {
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		w.Header().Set("Server", "nginx")
+	}))
+	defer server.Close()
+
+	wappalyzer, err := New(WithProfile(ProfileFast), WithRateLimit(RateLimit{PerSecond: 20}))
+	require.NoError(t, err, "could not create wappalyzer")
+
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		_, err := wappalyzer.FingerprintURL(context.Background(), server.URL)
+		require.NoError(t, err, "could not fingerprint url")
+	}
+	require.EqualValues(t, 3, requests.Load(), "wrong number of requests")
+	require.GreaterOrEqual(t, time.Since(start), 90*time.Millisecond, "requests past the burst should be delayed")
+}
+
+func TestWithRateLimitShared(t *testing.T) {
+	option := WithRateLimit(RateLimit{PerSecond: 20})
+	first, second := &Wappalyze{}, &Wappalyze{}
+	option(first)
+	option(second)
+	require.Same(t, first.rateLimiter, second.rateLimiter, "instances created with the same option should share the limit")
+	WithRateLimit(RateLimit{PerSecond: 20})(second)
+	require.NotSame(t, first.rateLimiter, second.rateLimiter, "separate options should not share a limit")
+}
@@ -0,0 +1,276 @@
+package profiler
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+// FixtureVersion is the version of the fixture format Recorder writes
+const FixtureVersion = 1
+
+// Fixture is a recording of the traffic of analyses: the responses to the
+// page, robots.txt, asset and probe requests, and the answers to the queries
+// of the DNS and email vectors. WithReplay re-runs analyses from it without
+// the network, for reproducible bug reports and offline regression tests of
+// detections. It is serialized as JSON.
+type Fixture struct {
+	// Version is the FixtureVersion the fixture was recorded with
+	Version int `json:"version"`
+	// Recorded is when the recording started
+	Recorded time.Time `json:"recorded"`
+	// Exchanges are the HTTP requests, in the order they completed
+	Exchanges []RecordedExchange `json:"exchanges,omitempty"`
+	// DNS are the DNS queries, in the order they completed
+	DNS []RecordedDNS `json:"dns,omitempty"`
+}
+
+// RecordedExchange is an HTTP request of a fixture and its response, or the
+// error it failed with
+type RecordedExchange struct {
+	Method     string      `json:"method"`
+	URL        string      `json:"url"`
+	StatusCode int         `json:"status_code,omitempty"`
+	Proto      string      `json:"proto,omitempty"`
+	Header     http.Header `json:"header,omitempty"`
+	Body       []byte      `json:"body,omitempty"`
+	// Uncompressed is set when the transport decompressed the body
+	Uncompressed bool   `json:"uncompressed,omitempty"`
+	Error        string `json:"error,omitempty"`
+}
+
+// RecordedDNS is a DNS query of a fixture and the answer of the resolver, or
+// the error it failed with
+type RecordedDNS struct {
+	Resolver string `json:"resolver"`
+	Name     string `json:"name"`
+	Type     string `json:"type"`
+	Rcode    string `json:"rcode,omitempty"`
+	// Answer are the answer records in zone file format
+	Answer []string `json:"answer,omitempty"`
+	Error  string   `json:"error,omitempty"`
+}
+
+// Recorder records the traffic of the instances it is passed to with
+// WithRecorder into a Fixture. It is safe for concurrent use.
+type Recorder struct {
+	mu      sync.Mutex
+	fixture Fixture
+}
+
+// NewRecorder returns a recorder with an empty fixture
+func NewRecorder() *Recorder {
+	return &Recorder{fixture: Fixture{Version: FixtureVersion, Recorded: time.Now().UTC()}}
+}
+
+// Fixture returns a copy of the traffic recorded so far
+func (r *Recorder) Fixture() *Fixture {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	fixture := r.fixture
+	fixture.Exchanges = append([]RecordedExchange(nil), r.fixture.Exchanges...)
+	fixture.DNS = append([]RecordedDNS(nil), r.fixture.DNS...)
+	return &fixture
+}
+
+// transport returns next recording its exchanges. Bodies are read whole
+// before they are returned, whatever the body size limits of the caller.
+func (r *Recorder) transport(next http.RoundTripper) http.RoundTripper {
+	return roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		exchange := RecordedExchange{Method: req.Method, URL: req.URL.String()}
+		resp, err := next.RoundTrip(req)
+		if err == nil {
+			var body []byte
+			body, err = io.ReadAll(resp.Body)
+			resp.Body.Close()
+			if err == nil {
+				resp.Body = io.NopCloser(bytes.NewReader(body))
+				exchange.StatusCode = resp.StatusCode
+				exchange.Proto = resp.Proto
+				exchange.Header = resp.Header.Clone()
+				exchange.Body = body
+				exchange.Uncompressed = resp.Uncompressed
+			}
+		}
+		if err != nil {
+			exchange.Error = err.Error()
+		}
+
+		r.mu.Lock()
+		r.fixture.Exchanges = append(r.fixture.Exchanges, exchange)
+		r.mu.Unlock()
+		if err != nil {
+			return nil, err
+		}
+		return resp, nil
+	})
+}
+
+// dnsClient returns next recording its queries
+func (r *Recorder) dnsClient(next DNSClient) DNSClient {
+	return dnsClientFunc(func(ctx context.Context, msg *dns.Msg, address string) (*dns.Msg, time.Duration, error) {
+		reply, rtt, err := next.ExchangeContext(ctx, msg, address)
+		if len(msg.Question) == 0 {
+			return reply, rtt, err
+		}
+
+		query := RecordedDNS{
+			Resolver: address,
+			Name:     msg.Question[0].Name,
+			Type:     dns.TypeToString[msg.Question[0].Qtype],
+		}
+		if err != nil {
+			query.Error = err.Error()
+		} else if reply != nil {
+			query.Rcode = dns.RcodeToString[reply.Rcode]
+			for _, answer := range reply.Answer {
+				query.Answer = append(query.Answer, answer.String())
+			}
+		}
+
+		r.mu.Lock()
+		r.fixture.DNS = append(r.fixture.DNS, query)
+		r.mu.Unlock()
+		return reply, rtt, err
+	})
+}
+
+// dnsClientFunc adapts a function to DNSClient
+type dnsClientFunc func(ctx context.Context, msg *dns.Msg, address string) (*dns.Msg, time.Duration, error)
+
+// ExchangeContext implements DNSClient
+func (f dnsClientFunc) ExchangeContext(ctx context.Context, msg *dns.Msg, address string) (*dns.Msg, time.Duration, error) {
+	return f(ctx, msg, address)
+}
+
+// probePathPattern matches the random path of the error page probe, which
+// differs between the recording and the replay
+var probePathPattern = regexp.MustCompile(`/kitsune-[0-9a-f]+\.html`)
+
+// exchangeKey identifies the requests a recorded exchange answers
+func exchangeKey(method, rawURL string) string {
+	return method + " " + probePathPattern.ReplaceAllString(rawURL, "/kitsune-probe.html")
+}
+
+// dnsKey identifies the queries a recorded DNS answer answers
+func dnsKey(resolver, name, qtype string) string {
+	return resolver + " " + strings.ToLower(dns.Fqdn(name)) + " " + qtype
+}
+
+// replayQueue serves the recordings of each key in the order they were
+// recorded, repeating the last one once they are exhausted
+type replayQueue[T any] struct {
+	mu         sync.Mutex
+	recordings map[string][]T
+	served     map[string]int
+}
+
+// newReplayQueue returns a queue of recordings, keyed by key
+func newReplayQueue[T any](recordings []T, key func(T) string) *replayQueue[T] {
+	queue := &replayQueue[T]{recordings: make(map[string][]T), served: make(map[string]int)}
+	for _, recording := range recordings {
+		k := key(recording)
+		queue.recordings[k] = append(queue.recordings[k], recording)
+	}
+	return queue
+}
+
+// next returns the next recording of key, if any
+func (q *replayQueue[T]) next(key string) (T, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	recordings := q.recordings[key]
+	if len(recordings) == 0 {
+		var zero T
+		return zero, false
+	}
+	index := min(q.served[key], len(recordings)-1)
+	q.served[key]++
+	return recordings[index], true
+}
+
+// replayTransport returns a transport answering requests from the exchanges
+// of fixture. Requests it holds no exchange for fail with ErrNotRecorded.
+func replayTransport(fixture *Fixture) http.RoundTripper {
+	queue := newReplayQueue(fixture.Exchanges, func(exchange RecordedExchange) string {
+		return exchangeKey(exchange.Method, exchange.URL)
+	})
+	return roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		if err := req.Context().Err(); err != nil {
+			return nil, err
+		}
+		exchange, ok := queue.next(exchangeKey(req.Method, req.URL.String()))
+		if !ok {
+			return nil, fmt.Errorf("%w: %s %s", ErrNotRecorded, req.Method, req.URL)
+		}
+		if exchange.Error != "" {
+			return nil, errors.New(exchange.Error)
+		}
+
+		header := exchange.Header.Clone()
+		if header == nil {
+			header = make(http.Header)
+		}
+		major, minor, ok := http.ParseHTTPVersion(exchange.Proto)
+		if !ok {
+			exchange.Proto, major, minor = "HTTP/1.1", 1, 1
+		}
+		return &http.Response{
+			Status:        fmt.Sprintf("%d %s", exchange.StatusCode, http.StatusText(exchange.StatusCode)),
+			StatusCode:    exchange.StatusCode,
+			Proto:         exchange.Proto,
+			ProtoMajor:    major,
+			ProtoMinor:    minor,
+			Header:        header,
+			Body:          io.NopCloser(bytes.NewReader(exchange.Body)),
+			ContentLength: int64(len(exchange.Body)),
+			Uncompressed:  exchange.Uncompressed,
+			Request:       req,
+		}, nil
+	})
+}
+
+// replayDNSClient returns a DNS client answering queries from the DNS answers
+// of fixture. Queries it holds no answer for fail with ErrNotRecorded.
+func replayDNSClient(fixture *Fixture) DNSClient {
+	queue := newReplayQueue(fixture.DNS, func(query RecordedDNS) string {
+		return dnsKey(query.Resolver, query.Name, query.Type)
+	})
+	return dnsClientFunc(func(ctx context.Context, msg *dns.Msg, address string) (*dns.Msg, time.Duration, error) {
+		if err := ctx.Err(); err != nil {
+			return nil, 0, err
+		}
+		if len(msg.Question) == 0 {
+			return nil, 0, fmt.Errorf("%w: empty query", ErrNotRecorded)
+		}
+		question := msg.Question[0]
+		query, ok := queue.next(dnsKey(address, question.Name, dns.TypeToString[question.Qtype]))
+		if !ok {
+			return nil, 0, fmt.Errorf("%w: %s %s", ErrNotRecorded, dns.TypeToString[question.Qtype], question.Name)
+		}
+		if query.Error != "" {
+			return nil, 0, errors.New(query.Error)
+		}
+
+		reply := new(dns.Msg)
+		reply.SetReply(msg)
+		reply.Rcode = dns.StringToRcode[query.Rcode]
+		for _, answer := range query.Answer {
+			record, err := dns.NewRR(answer)
+			if err != nil {
+				return nil, 0, fmt.Errorf("invalid recorded answer %q: %w", answer, err)
+			}
+			reply.Answer = append(reply.Answer, record)
+		}
+		return reply, 0, nil
+	})
+}
@@ -0,0 +1,97 @@
+package profiler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/miekg/dns"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRecordReplay(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/robots.txt":
+			w.Write([]byte("User-agent: *\nSitemap: https://example.com/sitemap.xml\n"))
+		case "/app.js":
+			w.Header().Set("Content-Type", "application/javascript")
+			w.Write([]byte("console.log('app')"))
+		default:
+			w.Header().Set("Server", "nginx/1.25.3")
+			w.Header().Set("Content-Type", "text/html")
+			w.Write([]byte(`<html><head><script src="/app.js"></script></head><body>Welcome</body></html>`))
+		}
+	}))
+	defer server.Close()
+
+	var queries atomic.Int32
+	resolver := dnsClientFunc(func(ctx context.Context, msg *dns.Msg, address string) (*dns.Msg, time.Duration, error) {
+		queries.Add(1)
+		reply := new(dns.Msg)
+		reply.SetReply(msg)
+		if msg.Question[0].Qtype == dns.TypeTXT {
+			record, err := dns.NewRR(msg.Question[0].Name + ` 300 IN TXT "v=spf1 -all"`)
+			require.NoError(t, err, "could not create record")
+			reply.Answer = append(reply.Answer, record)
+		}
+		return reply, 0, nil
+	})
+
+	recorder := NewRecorder()
+	recording, err := New(WithRecorder(recorder), WithDNSClient(resolver), WithErrorPageProbing(true))
+	require.NoError(t, err, "could not create wappalyzer")
+	recorded, err := recording.FingerprintURL(context.Background(), server.URL+"/")
+	require.NoError(t, err, "could not fingerprint")
+	require.Contains(t, recorded.GetDetections(), "Nginx", "nginx not detected")
+
+	// The fixture survives serialization, and replays without the network
+	data, err := json.Marshal(recorder.Fixture())
+	require.NoError(t, err, "could not marshal fixture")
+	var fixture Fixture
+	require.NoError(t, json.Unmarshal(data, &fixture), "could not unmarshal fixture")
+	require.Equal(t, FixtureVersion, fixture.Version, "wrong version")
+	require.NotEmpty(t, fixture.DNS, "dns queries not recorded")
+	server.Close()
+	recordedQueries := queries.Load()
+
+	replaying, err := New(WithReplay(&fixture), WithDNSClient(resolver), WithErrorPageProbing(true))
+	require.NoError(t, err, "could not create wappalyzer")
+	replayed, err := replaying.FingerprintURL(context.Background(), server.URL+"/")
+	require.NoError(t, err, "could not replay")
+	require.Equal(t, recorded.GetDetections(), replayed.GetDetections(), "wrong detections")
+	require.Equal(t, recorded.GetRobots(), replayed.GetRobots(), "wrong robots.txt")
+	require.Equal(t, recordedQueries, queries.Load(), "replay queried the resolver")
+
+	_, err = replaying.FingerprintURL(context.Background(), "http://example.com/")
+	require.ErrorIs(t, err, ErrNotRecorded, "unrecorded page replayed")
+}
+
+func TestReplayQueue(t *testing.T) {
+	queue := newReplayQueue([]RecordedExchange{
+		{Method: "GET", URL: "https://example.com/kitsune-0123abcd.html", StatusCode: 404},
+		{Method: "GET", URL: "https://example.com/", StatusCode: 200},
+		{Method: "GET", URL: "https://example.com/", StatusCode: 304},
+	}, func(exchange RecordedExchange) string {
+		return exchangeKey(exchange.Method, exchange.URL)
+	})
+
+	var statuses []int
+	for range 3 {
+		exchange, ok := queue.next(exchangeKey("GET", "https://example.com/"))
+		require.True(t, ok, "exchange not found")
+		statuses = append(statuses, exchange.StatusCode)
+	}
+	require.Equal(t, []int{200, 304, 304}, statuses, "exchanges not served in order")
+
+	probe, ok := queue.next(exchangeKey("GET", "https://example.com/kitsune-fedcba98.html"))
+	require.True(t, ok, "probe with another token not matched")
+	require.Equal(t, 404, probe.StatusCode, "wrong probe exchange")
+
+	_, ok = queue.next(exchangeKey("POST", "https://example.com/"))
+	require.False(t, ok, "unrecorded request matched")
+}
@@ -0,0 +1,153 @@
+package profiler
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math/rand"
+	"net"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+)
+
+// RetryPolicy configures how transient fetch failures are retried.
+// The zero value disables retries.
+type RetryPolicy struct {
+	// MaxAttempts is the total number of attempts, including the first one.
+	// Values below 2 disable retries.
+	MaxAttempts int
+	// InitialBackoff is the delay before the first retry. It doubles on every
+	// subsequent retry, with jitter.
+	InitialBackoff time.Duration
+	// MaxBackoff caps the delay between attempts, including delays requested
+	// by a Retry-After header.
+	MaxBackoff time.Duration
+	// RetryOnStatus lists the response status codes that are retried
+	RetryOnStatus []int
+}
+
+// DefaultRetryPolicy returns a retry policy suited to bulk scanning:
+// three attempts with exponential backoff, retrying on 429, 502, 503 and 504.
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxAttempts:    3,
+		InitialBackoff: 500 * time.Millisecond,
+		MaxBackoff:     5 * time.Second,
+		RetryOnStatus: []int{
+			http.StatusTooManyRequests,
+			http.StatusBadGateway,
+			http.StatusServiceUnavailable,
+			http.StatusGatewayTimeout,
+		},
+	}
+}
+
+// enabled reports whether the policy allows any retry
+func (p RetryPolicy) enabled() bool {
+	return p.MaxAttempts > 1
+}
+
+// backoff returns the delay before the given retry (1-based), with jitter
+func (p RetryPolicy) backoff(retry int) time.Duration {
+	delay := p.InitialBackoff << (retry - 1)
+	if delay <= 0 || (p.MaxBackoff > 0 && delay > p.MaxBackoff) {
+		delay = p.MaxBackoff
+	}
+	if delay <= 0 {
+		return 0
+	}
+	// Add up to 20% jitter so concurrent scans do not retry in lockstep
+	return delay + time.Duration(rand.Int63n(int64(delay)/5+1))
+}
+
+// retryAfter parses a Retry-After header, given either in seconds or as an HTTP date
+func retryAfter(header string) (time.Duration, bool) {
+	if header == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(header); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+	if date, err := http.ParseTime(header); err == nil {
+		return max(time.Until(date), 0), true
+	}
+	return 0, false
+}
+
+// isTransientError reports whether a failed request is worth retrying
+func isTransientError(err error) bool {
+	// A cancelled analysis or a refused target must not be retried
+	if errors.Is(err, context.Canceled) || errors.Is(err, ErrTargetNotAllowed) {
+		return false
+	}
+
+	// A missing host will not appear on retry
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) {
+		return dnsErr.IsTimeout || dnsErr.IsTemporary
+	}
+
+	// Handshake failures are configuration problems, not transient ones
+	if errors.Is(classifyError(err), ErrTLSHandshake) {
+		return false
+	}
+
+	// Timeouts, resets, refused connections and truncated responses
+	var netErr net.Error
+	return errors.As(err, &netErr) || errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF)
+}
+
+// doWithRetry sends req with client, retrying transient failures according to policy.
+// Every attempt waits for limiter, which may be nil. Requests must not have a
+// body. The last response or error is returned as is.
+func doWithRetry(client *http.Client, req *http.Request, policy RetryPolicy, limiter *rateLimiter) (*http.Response, error) {
+	ctx := req.Context()
+	if !policy.enabled() {
+		if err := limiter.wait(ctx, req.URL.Host); err != nil {
+			return nil, err
+		}
+		return tracedDo(client, req)
+	}
+
+	for attempt := 1; ; attempt++ {
+		if err := limiter.wait(ctx, req.URL.Host); err != nil {
+			return nil, err
+		}
+		resp, err := tracedDo(client, req.Clone(ctx))
+		if attempt >= policy.MaxAttempts {
+			return resp, err
+		}
+
+		var delay time.Duration
+		switch {
+		case err != nil:
+			if !isTransientError(err) || ctx.Err() != nil {
+				return resp, err
+			}
+			delay = policy.backoff(attempt)
+		case slices.Contains(policy.RetryOnStatus, resp.StatusCode):
+			delay = policy.backoff(attempt)
+			if requested, ok := retryAfter(resp.Header.Get("Retry-After")); ok {
+				delay = requested
+				if policy.MaxBackoff > 0 && delay > policy.MaxBackoff {
+					delay = policy.MaxBackoff
+				}
+			}
+			// Drain a little of the body so the connection can be reused
+			io.Copy(io.Discard, io.LimitReader(resp.Body, 4096))
+			resp.Body.Close()
+		default:
+			return resp, nil
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
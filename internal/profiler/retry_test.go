@@ -0,0 +1,71 @@
+package profiler
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDoWithRetry(t *testing.T) {
+	var attempts atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if attempts.Add(1) < 3 {
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	policy := RetryPolicy{
+		MaxAttempts:    3,
+		InitialBackoff: time.Millisecond,
+		MaxBackoff:     10 * time.Millisecond,
+		RetryOnStatus:  []int{http.StatusServiceUnavailable},
+	}
+
+	tests := []struct {
+		name     string
+		policy   RetryPolicy
+		status   int
+		attempts int32
+	}{
+		{"disabled", RetryPolicy{}, http.StatusServiceUnavailable, 1},
+		{"retried", policy, http.StatusOK, 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			attempts.Store(0)
+
+			req, err := http.NewRequestWithContext(context.Background(), "GET", server.URL, nil)
+			require.NoError(t, err, "could not create request")
+
+			resp, err := doWithRetry(http.DefaultClient, req, tt.policy, nil)
+			require.NoError(t, err, "could not do request")
+			resp.Body.Close()
+
+			require.Equal(t, tt.status, resp.StatusCode, "wrong final status")
+			require.Equal(t, tt.attempts, attempts.Load(), "wrong number of attempts")
+		})
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	delay, ok := retryAfter("2")
+	require.True(t, ok, "could not parse seconds")
+	require.Equal(t, 2*time.Second, delay, "wrong delay")
+
+	delay, ok = retryAfter(time.Now().Add(time.Hour).UTC().Format(http.TimeFormat))
+	require.True(t, ok, "could not parse http date")
+	require.Greater(t, delay, 59*time.Minute, "wrong delay")
+
+	_, ok = retryAfter("soon")
+	require.False(t, ok, "invalid value should not parse")
+}
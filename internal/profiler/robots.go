@@ -0,0 +1,206 @@
+package profiler
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"net/url"
+	"slices"
+	"strings"
+)
+
+// RobotsTxt is a parsed robots.txt, following RFC 9309
+type RobotsTxt struct {
+	// Groups are the rules of each group of user agents, in file order
+	Groups []RobotsGroup `json:"groups,omitempty"`
+	// Sitemaps are the URLs of the Sitemap directives, wherever they appear
+	Sitemaps []string `json:"sitemaps,omitempty"`
+}
+
+// RobotsGroup is a group of rules of a robots.txt
+type RobotsGroup struct {
+	// UserAgents are the lower case product tokens the group applies to, as
+	// googlebot or * for all crawlers
+	UserAgents []string `json:"user_agents"`
+	// Allow and Disallow are path patterns, where * matches any characters
+	// and a trailing $ the end of the path
+	Allow    []string `json:"allow,omitempty"`
+	Disallow []string `json:"disallow,omitempty"`
+}
+
+// GetRobots returns the parsed robots.txt of the host of the target, or nil if
+// the robots vector is disabled or the host has none
+func (r richResult) GetRobots() *RobotsTxt {
+	return r.robots
+}
+
+// WithPoliteMode fetches the robots.txt of each target before its page, and
+// skips the targets whose robots.txt disallows the page to the user agent of
+// the analysis, returning an *AnalysisError wrapping ErrDisallowedByRobots
+// without fetching it. A robots.txt that cannot be fetched allows the page.
+// Combined with WithUserAgent and ScannerUserAgent, it lets sites opt out of
+// the scans with a group for kitsune. The robots vector reuses the robots.txt.
+func WithPoliteMode(enabled bool) Option {
+	return func(s *Wappalyze) {
+		s.politeMode = enabled
+	}
+}
+
+// robotsPrefetch is the robots.txt polite mode fetched before the page, which
+// the robots vector reuses rather than fetching it again
+type robotsPrefetch struct {
+	url string
+	// content is nil if the host has none or it could not be fetched
+	content []byte
+}
+
+// robotsPrefetchKey is the context key of the robots.txt fetched before the page
+type robotsPrefetchKey struct{}
+
+// robotsPrefetchOf returns the robots.txt fetched before the page of an
+// analysis run with ctx, if any
+func robotsPrefetchOf(ctx context.Context) *robotsPrefetch {
+	prefetch, _ := ctx.Value(robotsPrefetchKey{}).(*robotsPrefetch)
+	return prefetch
+}
+
+// checkRobots fetches the robots.txt of the host of pageURL and returns an
+// *AnalysisError wrapping ErrDisallowedByRobots if it disallows the page to
+// the user agent of ctx, along with the robots.txt fetched
+func (s *Wappalyze) checkRobots(ctx context.Context, pageURL string) (*robotsPrefetch, error) {
+	parsedURL, err := url.Parse(pageURL)
+	if err != nil || parsedURL.Host == "" {
+		// The page fetch reports the invalid URL
+		return nil, nil
+	}
+	prefetch := &robotsPrefetch{url: fmt.Sprintf("%s://%s/robots.txt", parsedURL.Scheme, parsedURL.Host)}
+	prefetch.content, err = s.fetchRobotsTxt(ctx, prefetch.url, nil)
+	if err != nil {
+		s.logger.DebugContext(ctx, "robots.txt fetch failed", "url", prefetch.url, "error", err)
+		return prefetch, nil
+	}
+	if prefetch.content != nil && !ParseRobotsTxt(prefetch.content).Allowed(userAgentOf(ctx).Header, parsedURL.RequestURI()) {
+		return prefetch, &AnalysisError{Stage: StageMain, URL: pageURL, Err: ErrDisallowedByRobots}
+	}
+	return prefetch, nil
+}
+
+// ParseRobotsTxt parses a robots.txt. Lines it does not understand are
+// skipped, as are rules before the first User-agent line.
+func ParseRobotsTxt(data []byte) *RobotsTxt {
+	robots := &RobotsTxt{}
+	var group *RobotsGroup
+	// Consecutive User-agent lines share a group, which rules then close
+	closed := true
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line, _, _ := strings.Cut(scanner.Text(), "#")
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		value = strings.TrimSpace(value)
+
+		switch strings.ToLower(strings.TrimSpace(key)) {
+		case "user-agent":
+			if closed {
+				robots.Groups = append(robots.Groups, RobotsGroup{})
+				group = &robots.Groups[len(robots.Groups)-1]
+				closed = false
+			}
+			group.UserAgents = append(group.UserAgents, strings.ToLower(value))
+		case "allow":
+			if group != nil && value != "" {
+				group.Allow = append(group.Allow, value)
+			}
+			closed = true
+		case "disallow":
+			// An empty Disallow allows everything, like no rule
+			if group != nil && value != "" {
+				group.Disallow = append(group.Disallow, value)
+			}
+			closed = true
+		case "sitemap":
+			if value != "" && !slices.Contains(robots.Sitemaps, value) {
+				robots.Sitemaps = append(robots.Sitemaps, value)
+			}
+		}
+	}
+	return robots
+}
+
+// Allowed reports whether a crawler with the User-Agent header userAgent may
+// request path. The rules are those of the groups naming a product token the
+// header contains, the longest one winning, or of the * groups. The longest
+// matching rule applies, Allow winning ties, and paths no rule matches are
+// allowed. A nil RobotsTxt allows everything.
+func (r *RobotsTxt) Allowed(userAgent, path string) bool {
+	if r == nil {
+		return true
+	}
+	if path == "" {
+		path = "/"
+	}
+
+	var allow, disallow []string
+	var matched string
+	userAgent = strings.ToLower(userAgent)
+	for _, group := range r.Groups {
+		for _, token := range group.UserAgents {
+			if token == "*" || !strings.Contains(userAgent, token) || len(token) < len(matched) {
+				continue
+			}
+			if len(token) > len(matched) {
+				allow, disallow = nil, nil
+				matched = token
+			}
+			allow = append(allow, group.Allow...)
+			disallow = append(disallow, group.Disallow...)
+		}
+	}
+	if matched == "" {
+		for _, group := range r.Groups {
+			if slices.Contains(group.UserAgents, "*") {
+				allow = append(allow, group.Allow...)
+				disallow = append(disallow, group.Disallow...)
+			}
+		}
+	}
+
+	longest := func(patterns []string) int {
+		length := -1
+		for _, pattern := range patterns {
+			if len(pattern) > length && matchRobotsPattern(pattern, path) {
+				length = len(pattern)
+			}
+		}
+		return length
+	}
+	return longest(allow) >= longest(disallow)
+}
+
+// matchRobotsPattern reports whether a robots.txt path pattern matches path
+func matchRobotsPattern(pattern, path string) bool {
+	anchored := strings.HasSuffix(pattern, "$")
+	pattern = strings.TrimSuffix(pattern, "$")
+
+	parts := strings.Split(pattern, "*")
+	if !strings.HasPrefix(path, parts[0]) {
+		return false
+	}
+	rest := path[len(parts[0]):]
+	for i, part := range parts[1:] {
+		// The last part of an anchored pattern must end the path
+		if anchored && i == len(parts)-2 {
+			return strings.HasSuffix(rest, part)
+		}
+		index := strings.Index(rest, part)
+		if index < 0 {
+			return false
+		}
+		rest = rest[index+len(part):]
+	}
+	return !anchored || rest == ""
+}
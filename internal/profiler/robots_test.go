@@ -0,0 +1,136 @@
+package profiler
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+const testRobotsTxt = `# Rules for all crawlers
+User-agent: *
+Disallow: /admin/
+Allow: /admin/public
+Disallow: /*.pdf$
+Disallow:
+
+Sitemap: https://example.com/sitemap.xml
+
+user-agent: Googlebot
+User-Agent: bingbot
+disallow: /private # not for search engines
+Sitemap: https://example.com/news-sitemap.xml
+Sitemap: https://example.com/sitemap.xml
+`
+
+func TestParseRobotsTxt(t *testing.T) {
+	robots := ParseRobotsTxt([]byte(testRobotsTxt))
+	require.Equal(t, &RobotsTxt{
+		Groups: []RobotsGroup{
+			{UserAgents: []string{"*"}, Allow: []string{"/admin/public"}, Disallow: []string{"/admin/", "/*.pdf$"}},
+			{UserAgents: []string{"googlebot", "bingbot"}, Disallow: []string{"/private"}},
+		},
+		Sitemaps: []string{"https://example.com/sitemap.xml", "https://example.com/news-sitemap.xml"},
+	}, robots, "wrong robots.txt")
+}
+
+func TestRobotsAllowed(t *testing.T) {
+	robots := ParseRobotsTxt([]byte(testRobotsTxt))
+
+	tests := []struct {
+		name      string
+		userAgent string
+		path      string
+		expected  bool
+	}{
+		{name: "no rule", userAgent: UserAgentDesktop.Header, path: "/", expected: true},
+		{name: "disallowed", userAgent: UserAgentDesktop.Header, path: "/admin/users"},
+		{name: "longer allow", userAgent: UserAgentDesktop.Header, path: "/admin/public/logo.png", expected: true},
+		{name: "anchored wildcard", userAgent: UserAgentDesktop.Header, path: "/files/report.pdf"},
+		{name: "anchored wildcard not at the end", userAgent: UserAgentDesktop.Header, path: "/files/report.pdf.html", expected: true},
+		{name: "own group", userAgent: UserAgentBot.Header, path: "/private/page"},
+		{name: "own group replaces the * group", userAgent: UserAgentBot.Header, path: "/admin/users", expected: true},
+		{name: "other group", userAgent: UserAgentDesktop.Header, path: "/private/page", expected: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.expected, robots.Allowed(tt.userAgent, tt.path), "wrong verdict")
+		})
+	}
+
+	var missing *RobotsTxt
+	require.True(t, missing.Allowed(UserAgentDesktop.Header, "/admin/"), "a missing robots.txt should allow everything")
+}
+
+func TestRobotsDisallowedProbes(t *testing.T) {
+	var mu sync.Mutex
+	var requested []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		requested = append(requested, r.URL.Path)
+		mu.Unlock()
+		if r.URL.Path == "/robots.txt" {
+			w.Write([]byte("User-agent: *\nDisallow: /kitsune-\nSitemap: https://example.com/sitemap.xml\n"))
+			return
+		}
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte("<html><body>Welcome</body></html>"))
+	}))
+	defer server.Close()
+
+	wappalyzer, err := New(WithErrorPageProbing(true))
+	require.NoError(t, err, "could not create wappalyzer")
+
+	result, err := wappalyzer.FingerprintURL(context.Background(), server.URL)
+	require.NoError(t, err, "could not fingerprint")
+	require.Equal(t, []string{"https://example.com/sitemap.xml"}, result.GetRobots().Sitemaps, "wrong sitemaps")
+
+	mu.Lock()
+	defer mu.Unlock()
+	for _, path := range requested {
+		require.False(t, strings.HasPrefix(path, "/kitsune-"), "disallowed path %s probed", path)
+	}
+}
+
+func TestPoliteMode(t *testing.T) {
+	var mu sync.Mutex
+	requested := make(map[string]int)
+	var userAgents []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		requested[r.URL.Path]++
+		userAgents = append(userAgents, r.UserAgent())
+		mu.Unlock()
+		if r.URL.Path == "/robots.txt" {
+			w.Write([]byte("User-agent: kitsune\nDisallow: /private\n"))
+			return
+		}
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte("<html><body>Welcome</body></html>"))
+	}))
+	defer server.Close()
+
+	wappalyzer, err := New(WithUserAgent(ScannerUserAgent("https://scans.example.com")), WithPoliteMode(true))
+	require.NoError(t, err, "could not create wappalyzer")
+
+	_, err = wappalyzer.FingerprintURL(context.Background(), server.URL+"/private/page")
+	require.ErrorIs(t, err, ErrDisallowedByRobots, "disallowed pages should not be analyzed")
+	require.Zero(t, requested["/private/page"], "disallowed page fetched")
+
+	result, err := wappalyzer.FingerprintURL(context.Background(), server.URL+"/")
+	require.NoError(t, err, "could not fingerprint")
+	require.NotNil(t, result.GetRobots(), "the robots vector should reuse the robots.txt")
+	require.Equal(t, 2, requested["/robots.txt"], "robots.txt should be fetched once per analysis")
+
+	_, err = wappalyzer.FingerprintURL(UserAgentContext(context.Background(), UserAgentDesktop), server.URL+"/private/page")
+	require.NoError(t, err, "the rules for kitsune should not apply to other user agents")
+
+	mu.Lock()
+	defer mu.Unlock()
+	require.Contains(t, userAgents, "Mozilla/5.0 (compatible; Kitsune; +https://scans.example.com)", "the scanner user agent should be sent")
+}
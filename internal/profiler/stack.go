@@ -0,0 +1,94 @@
+package profiler
+
+import (
+	"cmp"
+	"slices"
+)
+
+// Stack summarizes a result with the primary technology of the categories
+// that describe a site best. Each is empty if none of the category was found.
+type Stack struct {
+	CMS       string `json:"cms,omitempty"`
+	WebServer string `json:"web_server,omitempty"`
+	Language  string `json:"language,omitempty"`
+	CDN       string `json:"cdn,omitempty"`
+}
+
+// Categories of the technologies of a Stack
+const (
+	categoryCMS       = 1
+	categoryWebServer = 22
+	categoryLanguage  = 27
+	categoryCDN       = 31
+)
+
+// GetStack returns the primary CMS, web server, programming language and CDN
+// of the result
+func (r richResult) GetStack() Stack {
+	return r.stack
+}
+
+// stackOf returns the stack of detections
+func (s *Wappalyze) stackOf(detections map[string]Detection) Stack {
+	return Stack{
+		CMS:       s.primaryTechnology(detections, categoryCMS),
+		WebServer: s.primaryTechnology(detections, categoryWebServer),
+		Language:  s.primaryTechnology(detections, categoryLanguage),
+		CDN:       s.primaryTechnology(detections, categoryCDN),
+	}
+}
+
+// primaryTechnology returns the most significant of the detections in
+// category. Technologies detected by a vector come before those only
+// implied, then those the category describes best: for which no category
+// has a higher priority, as for a CDN that is also a PaaS. The most confident
+// of these is the primary one.
+func (s *Wappalyze) primaryTechnology(detections map[string]Detection, category int) string {
+	type candidate struct {
+		name       string
+		implied    bool
+		secondary  bool
+		confidence int
+	}
+
+	var candidates []candidate
+	for name, detection := range detections {
+		fingerprint, ok := s.fingerprints.Apps[name]
+		if !ok || !slices.Contains(fingerprint.cats, category) {
+			continue
+		}
+		// Priorities run from 1, the most significant, to 10
+		secondary := slices.ContainsFunc(fingerprint.cats, func(cat int) bool {
+			other, ok := s.categories[cat]
+			return ok && other.Priority < s.categories[category].Priority
+		})
+		candidates = append(candidates, candidate{
+			name:       name,
+			implied:    !slices.ContainsFunc(detection.DetectedBy, func(vector string) bool { return vector != "implies" }),
+			secondary:  secondary,
+			confidence: detection.Confidence,
+		})
+	}
+	if len(candidates) == 0 {
+		return ""
+	}
+
+	primary := slices.MinFunc(candidates, func(a, b candidate) int {
+		if a.implied != b.implied {
+			return boolOrder(a.implied)
+		}
+		if a.secondary != b.secondary {
+			return boolOrder(a.secondary)
+		}
+		return cmp.Or(cmp.Compare(b.confidence, a.confidence), cmp.Compare(a.name, b.name))
+	})
+	return primary.name
+}
+
+// boolOrder orders false before true, returning 1 if value is true
+func boolOrder(value bool) int {
+	if value {
+		return 1
+	}
+	return -1
+}
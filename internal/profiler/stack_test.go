@@ -0,0 +1,75 @@
+package profiler
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestStackOf(t *testing.T) {
+	wappalyzer, err := New()
+	require.NoError(t, err, "could not create wappalyzer")
+
+	detected := func(confidence int) Detection {
+		return Detection{Confidence: confidence, DetectedBy: []string{"headers"}}
+	}
+	implied := Detection{Confidence: 100, DetectedBy: []string{"implies"}}
+
+	tests := []struct {
+		name       string
+		detections map[string]Detection
+		expected   Stack
+	}{
+		{
+			name:       "empty",
+			detections: map[string]Detection{"jQuery": detected(100)},
+		},
+		{
+			name:       "detected before implied",
+			detections: map[string]Detection{"WordPress": implied, "Drupal": detected(50), "PHP": implied},
+			expected:   Stack{CMS: "Drupal", Language: "PHP"},
+		},
+		{
+			name:       "category of a higher priority",
+			detections: map[string]Detection{"Netlify": detected(100), "Cloudflare": detected(100)},
+			expected:   Stack{CDN: "Cloudflare"},
+		},
+		{
+			// Nginx is a reverse proxy first
+			name:       "web server behind a reverse proxy",
+			detections: map[string]Detection{"Nginx": detected(100), "Apache HTTP Server": detected(100)},
+			expected:   Stack{WebServer: "Apache HTTP Server"},
+		},
+		{
+			name:       "most confident",
+			detections: map[string]Detection{"PHP": detected(50), "Ruby": detected(100), "Nginx": detected(100)},
+			expected:   Stack{WebServer: "Nginx", Language: "Ruby"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.expected, wappalyzer.stackOf(tt.detections), "wrong stack")
+		})
+	}
+}
+
+func TestGetStack(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Server", "nginx/1.25.3")
+		w.Header().Set("X-Powered-By", "PHP/8.2.0")
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(`<html><head><meta name="generator" content="WordPress 6.4"></head><body></body></html>`))
+	}))
+	defer server.Close()
+
+	wappalyzer, err := New(WithProfile(ProfileFast))
+	require.NoError(t, err, "could not create wappalyzer")
+
+	result, err := wappalyzer.FingerprintURL(context.Background(), server.URL)
+	require.NoError(t, err, "could not fingerprint")
+	require.Equal(t, Stack{CMS: "WordPress", WebServer: "Nginx", Language: "PHP"}, result.GetStack(), "wrong stack")
+}
@@ -0,0 +1,213 @@
+package profiler
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"runtime/debug"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// AnalysisStats holds timing and telemetry collected during a single analysis.
+// It is meant for finding slow targets and slow fingerprints at scale.
+type AnalysisStats struct {
+	// TotalDuration is the wall-clock time of the whole analysis
+	TotalDuration time.Duration `json:"total_duration"`
+	// DOMParseDuration is the time spent parsing the HTML document
+	DOMParseDuration time.Duration `json:"dom_parse_duration"`
+	// FetchDurations is the cumulative time spent fetching, by resource type
+	// (script, style, manifest, robots, dns, errorPage, headerOrder)
+	FetchDurations map[string]time.Duration `json:"fetch_durations"`
+	// MatchDurations is the cumulative time spent matching, by detection vector
+	MatchDurations map[string]time.Duration `json:"match_durations"`
+	// RegexTimeouts is the number of regex evaluations of the analysis that hit
+	// the regex timeout
+	RegexTimeouts int64 `json:"regex_timeouts"`
+	// BytesFetched is the number of body bytes read from fetched assets and robots.txt
+	BytesFetched int64 `json:"bytes_fetched"`
+	// AssetsFetched is the number of external assets requested
+	AssetsFetched int `json:"assets_fetched"`
+	// DOMNodes is the number of elements of the parsed document
+	DOMNodes int `json:"dom_nodes"`
+	// InlineScripts is the number of inline scripts scanned
+	InlineScripts int `json:"inline_scripts"`
+	// LimitsHit are the caps of the AssetPolicy and ParseLimits the analysis
+	// reached, leaving part of the page or its assets unanalyzed
+	LimitsHit []Limit `json:"limits_hit,omitempty"`
+}
+
+// statsRecorder collects AnalysisStats from concurrent pipeline stages.
+// All methods are safe to call on a nil recorder, which records nothing.
+type statsRecorder struct {
+	mutex sync.Mutex
+	start time.Time
+	// regexTimeouts counts the regex evaluations of the analysis that timed out
+	regexTimeouts atomic.Int64
+	stats         AnalysisStats
+	// progress is notified whenever a vector finishes matching
+	progress *progressReporter
+	// logger receives the matching time of each vector, if set, with the
+	// context of the analysis
+	logger *slog.Logger
+	ctx    context.Context
+	// url is the target of the analysis, reported with matcher panics
+	url string
+	// panics are the matcher panics recovered, as *AnalysisError
+	panics []error
+	// trace records the events of the analysis, if traced
+	trace *tracer
+}
+
+// newStatsRecorder creates a recorder and starts the analysis clock
+func newStatsRecorder() *statsRecorder {
+	return &statsRecorder{
+		start: time.Now(),
+		stats: AnalysisStats{
+			FetchDurations: make(map[string]time.Duration),
+			MatchDurations: make(map[string]time.Duration),
+		},
+	}
+}
+
+// addFetch records the duration of a fetch of the given resource type
+func (r *statsRecorder) addFetch(resourceType string, duration time.Duration) {
+	if r == nil {
+		return
+	}
+	r.mutex.Lock()
+	r.stats.FetchDurations[resourceType] += duration
+	if resourceType == "script" || resourceType == "style" || resourceType == "manifest" {
+		r.stats.AssetsFetched++
+	}
+	r.mutex.Unlock()
+}
+
+// addMatch records the duration of matching a detection vector and the
+// number of its matches
+func (r *statsRecorder) addMatch(vector part, matches int, duration time.Duration) {
+	if r == nil {
+		return
+	}
+	r.mutex.Lock()
+	r.stats.MatchDurations[vector.String()] += duration
+	r.mutex.Unlock()
+	r.trace.add(TraceEvent{Type: TraceMatch, Vector: vector.String(), Count: matches, Duration: duration})
+
+	if r.logger != nil {
+		r.logger.DebugContext(r.ctx, "matched vector", "vector", vector.String(), "duration", duration)
+	}
+	r.progress.report(ProgressEvent{Type: ProgressVector, Vector: vector.String(), Duration: duration})
+}
+
+// setDOMParse records the duration of parsing the HTML document and the
+// number of elements parsed
+func (r *statsRecorder) setDOMParse(duration time.Duration, nodes int) {
+	if r == nil {
+		return
+	}
+	r.mutex.Lock()
+	r.stats.DOMParseDuration = duration
+	r.stats.DOMNodes = nodes
+	r.mutex.Unlock()
+	r.trace.add(TraceEvent{Type: TraceParse, Count: nodes, Duration: duration})
+}
+
+// setInlineScripts records the number of inline scripts scanned
+func (r *statsRecorder) setInlineScripts(count int) {
+	if r == nil {
+		return
+	}
+	r.mutex.Lock()
+	r.stats.InlineScripts = count
+	r.mutex.Unlock()
+	r.trace.add(TraceEvent{Type: TraceInlineScripts, Count: count})
+}
+
+// limit records that the analysis reached a resource cap
+func (r *statsRecorder) limit(limit Limit) {
+	if r == nil {
+		return
+	}
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if !slices.Contains(r.stats.LimitsHit, limit) {
+		r.stats.LimitsHit = append(r.stats.LimitsHit, limit)
+		r.trace.add(TraceEvent{Type: TraceLimit, Detail: string(limit)})
+	}
+}
+
+// addBytes records the number of body bytes read from a fetched resource
+func (r *statsRecorder) addBytes(n int64) {
+	if r == nil {
+		return
+	}
+	r.mutex.Lock()
+	r.stats.BytesFetched += n
+	r.mutex.Unlock()
+}
+
+// countBody wraps a response body so the bytes read from it are recorded
+func (r *statsRecorder) countBody(body io.ReadCloser) io.ReadCloser {
+	if r == nil {
+		return body
+	}
+	return &countingReadCloser{ReadCloser: body, recorder: r}
+}
+
+// recoverPanic recovers a panic of the matching of vector, recording it as an
+// error of the analysis and logging it with its stack. It must be deferred,
+// and recovers the panic even on a nil recorder.
+func (r *statsRecorder) recoverPanic(vector part) {
+	recovered := recover()
+	if recovered == nil || r == nil {
+		return
+	}
+	err := &AnalysisError{Stage: StageMatch, URL: r.url, Err: fmt.Errorf("%w: %s: %v", ErrMatcherPanic, vector, recovered)}
+	if r.logger != nil {
+		r.logger.ErrorContext(r.ctx, "matcher panicked", "url", r.url, "vector", vector.String(), "panic", recovered, "stack", string(debug.Stack()))
+	}
+	r.mutex.Lock()
+	r.panics = append(r.panics, err)
+	r.mutex.Unlock()
+}
+
+// matcherPanics returns the matcher panics recovered so far
+func (r *statsRecorder) matcherPanics() []error {
+	if r == nil {
+		return nil
+	}
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return slices.Clone(r.panics)
+}
+
+// finish stops the analysis clock and returns the collected stats
+func (r *statsRecorder) finish() AnalysisStats {
+	if r == nil {
+		return AnalysisStats{}
+	}
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.stats.TotalDuration = time.Since(r.start)
+	r.stats.RegexTimeouts = r.regexTimeouts.Load()
+	if r.stats.RegexTimeouts > 0 {
+		r.trace.add(TraceEvent{Type: TraceRegexTimeouts, Count: int(r.stats.RegexTimeouts)})
+	}
+	return r.stats
+}
+
+// countingReadCloser records the number of bytes read through it
+type countingReadCloser struct {
+	io.ReadCloser
+	recorder *statsRecorder
+}
+
+func (c *countingReadCloser) Read(p []byte) (int, error) {
+	n, err := c.ReadCloser.Read(p)
+	c.recorder.addBytes(int64(n))
+	return n, err
+}
@@ -0,0 +1,70 @@
+package profiler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestAnalysisStats(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/app.js":
+			w.Header().Set("Content-Type", "application/javascript")
+			w.Write([]byte(`var app = {version: "1.0.0"};`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	wappalyzer, err := New()
+	require.NoError(t, err, "could not create wappalyzer")
+
+	body := []byte(`<html><head><script src="/app.js"></script></head><body></body></html>`)
+	resp := &http.Response{Header: http.Header{"Server": []string{"nginx"}}, Request: httptest.NewRequest("GET", server.URL, nil)}
+	stats := wappalyzer.AnalyzeWithPipeline(resp, body).GetStats()
+
+	require.Positive(t, stats.TotalDuration, "could not get total duration")
+	require.Positive(t, stats.DOMParseDuration, "could not get dom parse duration")
+	require.Contains(t, stats.FetchDurations, "script", "could not get script fetch duration")
+	require.Contains(t, stats.FetchDurations, "robots", "could not get robots fetch duration")
+	require.Contains(t, stats.MatchDurations, "headers", "could not get headers match duration")
+	require.Contains(t, stats.MatchDurations, "html", "could not get html match duration")
+	require.Equal(t, 1, stats.AssetsFetched, "could not count fetched assets")
+	require.Positive(t, stats.BytesFetched, "could not count fetched bytes")
+}
+
+func TestAnalysisStatsRegexTimeouts(t *testing.T) {
+	// Pages past the inline match size are matched against the timeout, which
+	// a nanosecond is sure to hit on some of the patterns
+	body := []byte("<html><body>" + strings.Repeat("<p>content</p>", 100) + "</body></html>")
+	resp := &http.Response{Header: http.Header{}}
+
+	slow, err := New(WithProfile(ProfileFast))
+	require.NoError(t, err, "could not create wappalyzer")
+	slow.regexTimeout = time.Nanosecond
+	fast, err := New(WithProfile(ProfileFast))
+	require.NoError(t, err, "could not create wappalyzer")
+
+	var wg sync.WaitGroup
+	var slowStats, fastStats AnalysisStats
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		slowStats = slow.AnalyzeWithPipeline(resp, body).GetStats()
+	}()
+	go func() {
+		defer wg.Done()
+		fastStats = fast.AnalyzeWithPipeline(resp, []byte("<html></html>")).GetStats()
+	}()
+	wg.Wait()
+
+	require.Positive(t, slowStats.RegexTimeouts, "timeouts not counted")
+	require.Zero(t, fastStats.RegexTimeouts, "timeouts of another analysis counted")
+}
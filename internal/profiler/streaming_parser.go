@@ -2,7 +2,9 @@ package profiler
 
 import (
 	"bytes"
+	"net/url"
 	"strings"
+	"time"
 
 	"github.com/PuerkitoBio/goquery"
 	"golang.org/x/net/html"
@@ -11,54 +13,152 @@ import (
 // streamingParseHTML parses HTML content and sends asset URLs to the fetcher as they are discovered
 // It returns DOM-based technologies, meta tag technologies, and handles script src detection
 // This is a streaming version of the previous parseBodyForDOMAnalysis and checkBody functions
-func (s *Wappalyze) streamingParseHTML(body []byte, fetcher *AssetFetcher) ([]matchPartResult, *goquery.Document) {
+// DOM selectors are only matched if matchDOM is set.
+func (s *Wappalyze) streamingParseHTML(body []byte, fetcher *AssetFetcher, matchDOM bool) ([]matchPartResult, *goquery.Document) {
 	var technologies []matchPartResult
 	
-	// Parse the HTML document with goquery for DOM analysis
-	doc, err := goquery.NewDocumentFromReader(bytes.NewReader(body))
+	// Parse the HTML document with goquery for DOM analysis, up to the
+	// element cap of the parse limits
+	parseStart := time.Now()
+	domBody := body
+	if maxNodes := s.parseLimits.MaxDOMNodes; maxNodes > 0 {
+		var truncated bool
+		if domBody, truncated = truncateDOM(body, maxNodes); truncated {
+			fetcher.stats.limit(LimitDOMNodes)
+		}
+	}
+	doc, err := goquery.NewDocumentFromReader(bytes.NewReader(domBody))
 	if err != nil {
+		fetcher.stats.setDOMParse(time.Since(parseStart), 0)
 		// Return a minimal result if parsing fails
 		return technologies, nil
 	}
+	fetcher.stats.setDOMParse(time.Since(parseStart), countElements(doc.Nodes...))
 	
 	// Process script tags - stream URLs to the fetcher as we find them
+	var scriptSrcs []string
 	doc.Find("script[src]").Each(func(i int, elem *goquery.Selection) {
 		if src, exists := elem.Attr("src"); exists && src != "" {
 			// Send this script URL to the fetcher immediately
 			fetcher.AddURL(src, "script", 5)
-			
-			// Also check for script src fingerprints
-			scriptTech := s.fingerprints.matchString(src, scriptPart, s.regexTimeout)
-			if len(scriptTech) > 0 {
-				technologies = append(technologies, scriptTech...)
-			}
+			scriptSrcs = append(scriptSrcs, src)
 		}
 	})
 	
 	// Process stylesheet links - stream URLs to the fetcher as we find them
+	var linkHrefs []string
 	doc.Find("link[rel=stylesheet][href]").Each(func(i int, elem *goquery.Selection) {
 		if href, exists := elem.Attr("href"); exists && href != "" {
 			// Send this stylesheet URL to the fetcher immediately
 			fetcher.AddURL(href, "style", 3)
+			linkHrefs = append(linkHrefs, href)
 		}
 	})
 	
-	// Process meta tags
-	metaTech := s.analyzeMeta(doc)
-	technologies = append(technologies, metaTech...)
-	
-	// Process DOM patterns
-	domTech := s.analyzeDOM(doc)
-	technologies = append(technologies, domTech...)
-	
+	// Process the web app manifest link, if any
+	if href, exists := doc.Find("link[rel=manifest][href]").First().Attr("href"); exists && href != "" {
+		fetcher.AddURL(href, "manifest", 2)
+	}
+
+	// The assets are on their way; match the document while they are fetched.
+	// Matchers only read the document, so they run concurrently.
+	matchers := []matcher{
+		{scriptPart, func() []matchPartResult { return s.matchEach(scriptSrcs, scriptPart) }},
+		{linkHrefPart, func() []matchPartResult { return s.matchEach(linkHrefs, linkHrefPart) }},
+		{iframePart, func() []matchPartResult { return s.analyzeIframes(doc) }},
+		{noscriptPart, func() []matchPartResult { return s.analyzeNoscript(doc) }},
+		{metaPart, func() []matchPartResult { return s.analyzeMeta(doc) }},
+		{jsonldPart, func() []matchPartResult { return s.analyzeJSONLD(doc) }},
+	}
+	if matchDOM {
+		matchers = append(matchers, matcher{domPart, func() []matchPartResult { return s.analyzeDOM(doc) }})
+	}
+
 	// Also process the HTML body for raw pattern matching
-	bodyString := strings.ToLower(string(body))
-	htmlTech := s.fingerprints.matchString(bodyString, htmlPart, s.regexTimeout)
-	technologies = append(technologies, htmlTech...)
+	matchers = append(matchers, matcher{htmlPart, func() []matchPartResult {
+		lowered := lowerBuffer(body)
+		defer lowered.release()
+		return s.fingerprints.matchString(lowered.String(), htmlPart, s.regexTimeout)
+	}})
+	technologies = append(technologies, runMatchers(matchers, s.matchWorkers, fetcher.stats)...)
 	
 	return technologies, doc
 }
 
+// matchEach matches each of the values against the patterns of part
+func (s *Wappalyze) matchEach(values []string, part part) []matchPartResult {
+	var technologies []matchPartResult
+	for _, value := range values {
+		technologies = append(technologies, s.fingerprints.matchString(value, part, s.regexTimeout)...)
+	}
+	return technologies
+}
+
+// collectInlineScripts returns the contents of the script tags without a src
+// attribute, the first limit of them if limit is positive, and whether some
+// were left out
+func collectInlineScripts(doc *goquery.Document, limit int) ([]string, bool) {
+	var scripts []string
+	if doc == nil {
+		return scripts, false
+	}
+
+	truncated := false
+	doc.Find("script:not([src])").EachWithBreak(func(i int, elem *goquery.Selection) bool {
+		// Skip data blocks such as JSON-LD and templates
+		if scriptType, exists := elem.Attr("type"); exists && scriptType != "" &&
+			!strings.Contains(strings.ToLower(scriptType), "javascript") && scriptType != "module" {
+			return true
+		}
+		content := elem.Text()
+		if strings.TrimSpace(content) == "" {
+			return true
+		}
+		if limit > 0 && len(scripts) == limit {
+			truncated = true
+			return false
+		}
+		scripts = append(scripts, content)
+		return true
+	})
+	return scripts, truncated
+}
+
+// collectScriptSources returns the URLs of the external scripts of the
+// document, resolved against baseURL
+func collectScriptSources(doc *goquery.Document, baseURL string) []string {
+	return collectURLs(doc, baseURL, "script[src]", "src")
+}
+
+// collectLinkHrefs returns the URLs of the links of the document, such as
+// stylesheets and module preloads, resolved against baseURL
+func collectLinkHrefs(doc *goquery.Document, baseURL string) []string {
+	return collectURLs(doc, baseURL, "link[href]", "href")
+}
+
+// collectURLs returns the URLs in the attribute attr of the elements of the
+// document matching selector, resolved against baseURL
+func collectURLs(doc *goquery.Document, baseURL, selector, attr string) []string {
+	var sources []string
+	if doc == nil {
+		return sources
+	}
+
+	base, _ := url.Parse(baseURL)
+	doc.Find(selector).Each(func(i int, elem *goquery.Selection) {
+		src, _ := elem.Attr(attr)
+		ref, err := url.Parse(strings.TrimSpace(src))
+		if err != nil || src == "" {
+			return
+		}
+		if base != nil {
+			ref = base.ResolveReference(ref)
+		}
+		sources = append(sources, ref.String())
+	})
+	return sources
+}
+
 // extractTitleWithTokenizer extracts the page title using an HTML tokenizer
 // This is a separate function for clarity and to allow title extraction 
 // even when full HTML parsing fails
@@ -0,0 +1,92 @@
+package profiler
+
+import (
+	"context"
+	"slices"
+	"strings"
+)
+
+// WithTags restricts the results of the instance to the technologies with
+// at least one of the tags, which overlays attach to technologies, as in
+// "payment" or "pii-processor". Tags are case insensitive. Progress events
+// and detection callbacks still report every technology.
+func WithTags(tags ...string) Option {
+	return func(s *Wappalyze) {
+		s.tags = normalizeTags(tags)
+	}
+}
+
+// tagsKey is the context key of the tags of an analysis
+type tagsKey struct{}
+
+// TagsContext returns a context that restricts the results of the analyses
+// started with it, e.g. through FingerprintURL, to the technologies with one
+// of the tags instead of the tags of the instance, which apply when none are
+// given. Servers use it to let each request choose its tags.
+func TagsContext(ctx context.Context, tags ...string) context.Context {
+	return context.WithValue(ctx, tagsKey{}, normalizeTags(tags))
+}
+
+// tagsOf returns the tags of an analysis run with ctx
+func (s *Wappalyze) tagsOf(ctx context.Context) []string {
+	if tags, _ := ctx.Value(tagsKey{}).([]string); len(tags) > 0 {
+		return tags
+	}
+	return s.tags
+}
+
+// normalizeTags returns the distinct tags in lower case, without the empty ones
+func normalizeTags(tags []string) []string {
+	var normalized []string
+	for _, tag := range tags {
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if tag != "" && !slices.Contains(normalized, tag) {
+			normalized = append(normalized, tag)
+		}
+	}
+	return normalized
+}
+
+// HasTag reports whether the technology name has one of the tags
+func (s *Wappalyze) HasTag(name string, tags ...string) bool {
+	fingerprint, ok := s.fingerprints.Apps[name]
+	if !ok {
+		return false
+	}
+	return slices.ContainsFunc(normalizeTags(tags), func(tag string) bool {
+		return slices.Contains(fingerprint.tags, tag)
+	})
+}
+
+// filterTags restricts result to the technologies with one of the tags, if
+// any, and summarizes them again
+func (s *Wappalyze) filterTags(result richResult, tags []string) richResult {
+	if len(tags) == 0 || result.technologies == nil {
+		return result
+	}
+	technologies := make(map[string]struct{}, len(result.technologies))
+	for app := range result.technologies {
+		// Technologies may carry a version, as in `PHP:8.2`
+		name, _, _ := strings.Cut(app, versionSeparator)
+		if s.HasTag(name, tags...) {
+			technologies[app] = struct{}{}
+		}
+	}
+	detections := make(map[string]Detection, len(technologies))
+	filtered := make(map[string]Detection)
+	for name, detection := range result.detections {
+		if s.HasTag(name, tags...) {
+			detections[name] = detection
+		} else {
+			filtered[name] = detection
+		}
+	}
+	result.technologies = technologies
+	result.detections = detections
+	if result.explained {
+		result.nearMisses = append(slices.Clone(result.nearMisses), untagged(filtered, tags)...)
+		sortNearMisses(result.nearMisses)
+	}
+	s.populateInfo(&result)
+	return result
+}
@@ -0,0 +1,97 @@
+package profiler
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// writeTagsOverlay writes an overlay adding a custom category and tagging
+// Stripe and Nginx
+func writeTagsOverlay(t *testing.T) string {
+	overlay := filepath.Join(t.TempDir(), "overlay.json")
+	require.NoError(t, os.WriteFile(overlay, []byte(`{
+		"categories": {"1000": {"name": "Payments", "priority": 5}},
+		"extend": {
+			"Stripe": {"cats": [1000], "tags": ["payment", "PII-Processor"]},
+			"Nginx": {"tags": ["edge"]}
+		}
+	}`), 0o644), "could not write overlay")
+	return overlay
+}
+
+func TestCustomCategories(t *testing.T) {
+	wappalyzer, err := New(WithOverlays(writeTagsOverlay(t)))
+	require.NoError(t, err, "could not create wappalyzer")
+
+	require.Contains(t, wappalyzer.Categories(), Category{ID: 1000, Slug: "payments", Name: "Payments", Priority: 5}, "custom category not listed")
+	require.NotContains(t, Categories(), Category{ID: 1000, Slug: "payments", Name: "Payments", Priority: 5}, "custom category should not leak to other instances")
+
+	for _, technology := range wappalyzer.Technologies() {
+		if technology.Name == "Stripe" {
+			require.Contains(t, technology.Categories, Category{ID: 1000, Slug: "payments", Name: "Payments", Priority: 5}, "custom category not assigned")
+			require.Equal(t, []string{"payment", "pii-processor"}, technology.Tags, "wrong tags")
+		}
+	}
+
+	payments, err := NewForCategories([]int{1000}, WithOverlays(writeTagsOverlay(t)))
+	require.NoError(t, err, "could not create wappalyzer for a custom category")
+	require.Contains(t, payments.GetFingerprints().Apps, "Stripe", "technology of the custom category not loaded")
+
+	overlay := filepath.Join(t.TempDir(), "conflicting.json")
+	require.NoError(t, os.WriteFile(overlay, []byte(`{"categories": {"1": {"name": "Websites"}}}`), 0o644), "could not write overlay")
+	_, err = New(WithOverlays(overlay))
+	require.Error(t, err, "overlay redefining an embedded category should be rejected")
+}
+
+func TestTagFilter(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Server", "nginx/1.25.3")
+		w.Header().Set("X-Powered-By", "PHP/8.2.0")
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(`<html><head><script src="https://js.stripe.com/v3/"></script></head><body></body></html>`))
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name     string
+		options  []Option
+		tags     []string
+		expected []string
+	}{
+		{
+			name:     "instance tags",
+			options:  []Option{WithTags("Payment")},
+			expected: []string{"Stripe"},
+		},
+		{
+			name:     "request tags",
+			options:  []Option{WithTags("payment")},
+			tags:     []string{"edge", "pii-processor"},
+			expected: []string{"Nginx", "Stripe"},
+		},
+		{
+			name:    "unknown tag",
+			options: []Option{WithTags("deprecated")},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			options := append([]Option{WithProfile(ProfileFast), WithOverlays(writeTagsOverlay(t))}, tt.options...)
+			wappalyzer, err := New(options...)
+			require.NoError(t, err, "could not create wappalyzer")
+
+			result, err := wappalyzer.FingerprintURL(TagsContext(context.Background(), tt.tags...), server.URL)
+			require.NoError(t, err, "could not fingerprint")
+			require.ElementsMatch(t, tt.expected, sortedKeys(result.GetDetections()), "wrong detections")
+			// App info is keyed by technology and version, as in Nginx:1.25.3
+			require.Len(t, result.GetAppInfo(), len(tt.expected), "wrong app info")
+		})
+	}
+}
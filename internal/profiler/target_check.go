@@ -0,0 +1,63 @@
+package profiler
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"syscall"
+)
+
+// checkTarget returns an error wrapping ErrTargetNotAllowed if the target
+// check of the instance refuses targetURL
+func (s *Wappalyze) checkTarget(ctx context.Context, targetURL string) error {
+	if s.targetCheck == nil {
+		return nil
+	}
+	if err := s.targetCheck(ctx, targetURL); err != nil {
+		return fmt.Errorf("%w: %w", ErrTargetNotAllowed, err)
+	}
+	return nil
+}
+
+// checkedTransport refuses the requests the target check of the instance
+// refuses before they are sent. The client sends every hop of a redirect
+// through its transport, so redirects to refused targets are not followed.
+func (s *Wappalyze) checkedTransport(next http.RoundTripper) http.RoundTripper {
+	if s.targetCheck == nil {
+		return next
+	}
+	return roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		// Opaque URLs, as in the malformed error page probe, do not parse back,
+		// so they are checked by their scheme and host
+		target := req.URL
+		if target.Opaque != "" {
+			target = &url.URL{Scheme: target.Scheme, Host: target.Host, Path: "/"}
+		}
+		if err := s.checkTarget(req.Context(), target.String()); err != nil {
+			return nil, err
+		}
+		return next.RoundTrip(req)
+	})
+}
+
+// dialControl returns the control function of the dialers of the instance,
+// refusing connections to the addresses the address check refuses. It runs
+// on the address being dialed, once resolved, so a host cannot resolve to an
+// allowed address when checked and to a refused one when connected to.
+func (s *Wappalyze) dialControl() func(ctx context.Context, network, address string, c syscall.RawConn) error {
+	if s.addressCheck == nil {
+		return nil
+	}
+	return func(ctx context.Context, network, address string, c syscall.RawConn) error {
+		addrPort, err := netip.ParseAddrPort(address)
+		if err != nil {
+			return fmt.Errorf("%w: invalid address %q", ErrTargetNotAllowed, address)
+		}
+		if err := s.addressCheck(ctx, addrPort.Addr().Unmap()); err != nil {
+			return fmt.Errorf("%w: %w", ErrTargetNotAllowed, err)
+		}
+		return nil
+	}
+}
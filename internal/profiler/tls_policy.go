@@ -0,0 +1,107 @@
+package profiler
+
+import (
+	"bytes"
+	"cmp"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// TLSPolicy decides how analyses treat targets whose certificate does not
+// verify against the system roots
+type TLSPolicy string
+
+const (
+	// TLSPolicyLog accepts invalid certificates, so their sites can still be
+	// fingerprinted, and reports the verification failure on the result. It
+	// is the default.
+	TLSPolicyLog TLSPolicy = "log"
+	// TLSPolicyStrict refuses invalid certificates: the fetch fails with
+	// ErrTLSHandshake
+	TLSPolicyStrict TLSPolicy = "strict"
+	// TLSPolicyInsecure accepts any certificate without verifying it, and
+	// reports nothing
+	TLSPolicyInsecure TLSPolicy = "insecure"
+)
+
+// TLSPolicies lists the available TLS policies
+var TLSPolicies = []TLSPolicy{TLSPolicyLog, TLSPolicyStrict, TLSPolicyInsecure}
+
+// ParseTLSPolicy returns the TLS policy with the given name. An empty name is
+// the log policy.
+func ParseTLSPolicy(name string) (TLSPolicy, error) {
+	if name == "" {
+		return TLSPolicyLog, nil
+	}
+	for _, policy := range TLSPolicies {
+		if string(policy) == name {
+			return policy, nil
+		}
+	}
+	return "", fmt.Errorf("unknown TLS policy %q, expected log, strict or insecure", name)
+}
+
+// tlsConfig returns the client TLS configuration of the policy. Only the
+// strict policy verifies during the handshake; the log policy verifies the
+// certificate of the page afterwards, with verifyCertificate.
+func (p TLSPolicy) tlsConfig() *tls.Config {
+	if p == TLSPolicyStrict {
+		return &tls.Config{}
+	}
+	return &tls.Config{InsecureSkipVerify: true}
+}
+
+// TLSValidation is the outcome of verifying the certificate of the page
+// against the system roots and the host name
+type TLSValidation struct {
+	// Valid is set when the certificate chains to a trusted root, matches the
+	// host and is within its validity period
+	Valid bool `json:"valid"`
+	// Expired is set when the certificate is past its NotAfter date, or not
+	// yet valid
+	Expired bool `json:"expired,omitempty"`
+	// SelfSigned is set when the certificate is signed by its own key
+	SelfSigned bool `json:"self_signed,omitempty"`
+	// HostnameMismatch is set when the certificate is not valid for the host
+	HostnameMismatch bool `json:"hostname_mismatch,omitempty"`
+	// Error is the verification error, if any
+	Error string `json:"error,omitempty"`
+}
+
+// verifyCertificate verifies the certificate a response was served with, as
+// of now, or returns nil if it was not served over TLS
+func verifyCertificate(resp *http.Response, now time.Time) *TLSValidation {
+	if resp == nil || resp.TLS == nil || len(resp.TLS.PeerCertificates) == 0 {
+		return nil
+	}
+	var host string
+	if resp.Request != nil && resp.Request.URL != nil {
+		host = resp.Request.URL.Hostname()
+	}
+	host = cmp.Or(resp.TLS.ServerName, host)
+
+	leaf := resp.TLS.PeerCertificates[0]
+	options := x509.VerifyOptions{
+		DNSName:       host,
+		Intermediates: x509.NewCertPool(),
+		CurrentTime:   now,
+	}
+	for _, cert := range resp.TLS.PeerCertificates[1:] {
+		options.Intermediates.AddCert(cert)
+	}
+
+	validation := &TLSValidation{
+		Expired:          now.After(leaf.NotAfter) || now.Before(leaf.NotBefore),
+		SelfSigned:       bytes.Equal(leaf.RawIssuer, leaf.RawSubject) && leaf.CheckSignatureFrom(leaf) == nil,
+		HostnameMismatch: host != "" && leaf.VerifyHostname(host) != nil,
+	}
+	if _, err := leaf.Verify(options); err != nil {
+		validation.Error = err.Error()
+	} else {
+		validation.Valid = true
+	}
+	return validation
+}
@@ -0,0 +1,101 @@
+package profiler
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseTLSPolicy(t *testing.T) {
+	tests := []struct {
+		name     string
+		expected TLSPolicy
+		wantErr  bool
+	}{
+		{name: "", expected: TLSPolicyLog},
+		{name: "log", expected: TLSPolicyLog},
+		{name: "strict", expected: TLSPolicyStrict},
+		{name: "insecure", expected: TLSPolicyInsecure},
+		{name: "lax", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		policy, err := ParseTLSPolicy(tt.name)
+		if tt.wantErr {
+			require.Error(t, err, "invalid policy %q accepted", tt.name)
+			continue
+		}
+		require.NoError(t, err, "could not parse %q", tt.name)
+		require.Equal(t, tt.expected, policy, "wrong policy for %q", tt.name)
+	}
+}
+
+func TestTLSPolicy(t *testing.T) {
+	// The test server presents a self-signed certificate for 127.0.0.1
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Server", "nginx")
+		w.Write([]byte("<html></html>"))
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		policy     TLSPolicy
+		wantErr    bool
+		validation *TLSValidation
+	}{
+		{policy: TLSPolicyLog, validation: &TLSValidation{SelfSigned: true}},
+		{policy: TLSPolicyStrict, wantErr: true},
+		{policy: TLSPolicyInsecure},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.policy), func(t *testing.T) {
+			wappalyzer, err := New(WithTLSPolicy(tt.policy), WithProfile(ProfileFast))
+			require.NoError(t, err, "could not create wappalyzer")
+
+			result, err := wappalyzer.FingerprintURL(context.Background(), server.URL)
+			if tt.wantErr {
+				require.ErrorIs(t, err, ErrTLSHandshake, "invalid certificate accepted")
+				return
+			}
+			require.NoError(t, err, "could not fingerprint")
+			require.Contains(t, result.GetTechnologies(), "Nginx", "could not detect technologies")
+
+			validation := result.GetProtocol().TLSValidation
+			if tt.validation == nil {
+				require.Nil(t, validation, "validation reported")
+				return
+			}
+			require.NotNil(t, validation, "validation not reported")
+			require.NotEmpty(t, validation.Error, "no verification error")
+			validation.Error = ""
+			require.Equal(t, tt.validation, validation, "wrong validation")
+		})
+	}
+}
+
+func TestVerifyCertificate(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	resp, err := server.Client().Get(server.URL)
+	require.NoError(t, err, "could not fetch")
+	resp.Body.Close()
+
+	validation := verifyCertificate(resp, time.Now())
+	require.False(t, validation.Expired, "valid certificate reported as expired")
+	require.False(t, validation.HostnameMismatch, "matching host reported as a mismatch")
+
+	validation = verifyCertificate(resp, resp.TLS.PeerCertificates[0].NotAfter.Add(time.Hour))
+	require.True(t, validation.Expired, "expired certificate not reported")
+
+	resp.Request.URL.Host = "kitsune.invalid"
+	validation = verifyCertificate(resp, time.Now())
+	require.True(t, validation.HostnameMismatch, "hostname mismatch not reported")
+
+	require.Nil(t, verifyCertificate(&http.Response{}, time.Now()), "validation of a plain HTTP response")
+}
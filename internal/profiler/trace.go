@@ -0,0 +1,178 @@
+package profiler
+
+import (
+	"context"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+)
+
+// TraceEventType is the kind of a TraceEvent
+type TraceEventType string
+
+const (
+	// TraceFetchStart is recorded when an HTTP request is sent, retries
+	// included, with its URL
+	TraceFetchStart TraceEventType = "fetch_start"
+	// TraceFetchFinish is recorded when the response to an HTTP request
+	// arrives, with its URL, status and duration, or its error
+	TraceFetchFinish TraceEventType = "fetch_finish"
+	// TraceCacheHit is recorded when the result is served from the result cache
+	TraceCacheHit TraceEventType = "cache_hit"
+	// TraceParse is recorded when the page is parsed, with the number of
+	// elements and the duration
+	TraceParse TraceEventType = "parse"
+	// TraceInlineScripts is recorded with the number of inline scripts scanned
+	TraceInlineScripts TraceEventType = "inline_scripts"
+	// TraceLimit is recorded when a cap of the AssetPolicy or ParseLimits is
+	// reached, named by the detail
+	TraceLimit TraceEventType = "limit"
+	// TraceMatch is recorded when a detection vector finishes matching, with
+	// the number of its matches and the duration
+	TraceMatch TraceEventType = "match"
+	// TraceDetection is recorded the first time a technology is detected,
+	// with the vector that detected it
+	TraceDetection TraceEventType = "detection"
+	// TraceStage is recorded when a stage completes, with its error
+	TraceStage TraceEventType = "stage"
+	// TraceRegexTimeouts is recorded with the number of regex evaluations
+	// dropped at the regex timeout, counted as in AnalysisStats
+	TraceRegexTimeouts TraceEventType = "regex_timeouts"
+)
+
+// TraceEvent is an event of the trace of an analysis. Only the fields that
+// apply to its type are set.
+type TraceEvent struct {
+	Type TraceEventType `json:"type"`
+	// Elapsed is the time since the analysis started
+	Elapsed    time.Duration `json:"elapsed"`
+	URL        string        `json:"url,omitempty"`
+	Status     int           `json:"status,omitempty"`
+	Stage      Stage         `json:"stage,omitempty"`
+	Vector     string        `json:"vector,omitempty"`
+	Technology string        `json:"technology,omitempty"`
+	Count      int           `json:"count,omitempty"`
+	Duration   time.Duration `json:"duration,omitempty"`
+	Detail     string        `json:"detail,omitempty"`
+	Error      string        `json:"error,omitempty"`
+}
+
+// Trace is the ordered events of an analysis, for reports of missed
+// detections to carry what the analysis fetched, parsed and matched
+type Trace struct {
+	Events []TraceEvent `json:"events"`
+	// Truncated is the number of events left out past maxTraceEvents
+	Truncated int `json:"truncated,omitempty"`
+}
+
+// maxTraceEvents bounds the events of a trace, so pages with many assets do
+// not grow it without limit
+const maxTraceEvents = 1000
+
+// WithTrace traces every analysis of the instance, as TraceContext does for
+// a single one
+func WithTrace(enabled bool) Option {
+	return func(s *Wappalyze) {
+		s.trace = enabled
+	}
+}
+
+// traceKey is the context key of traced analyses
+type traceKey struct{}
+
+// tracerKey is the context key of the tracer of a running analysis
+type tracerKey struct{}
+
+// TraceContext returns a context whose analyses, e.g. through FingerprintURL,
+// record a trace of at most a thousand events, returned by the GetTrace of
+// their result: the HTTP requests sent, the parsing of the page, the matches
+// of each vector, the detections and stages as they happen, and the regex
+// evaluations dropped at the timeout.
+func TraceContext(ctx context.Context) context.Context {
+	return context.WithValue(ctx, traceKey{}, true)
+}
+
+// GetTrace returns the trace of the analysis, for analyses run with
+// TraceContext or WithTrace, or nil
+func (r richResult) GetTrace() *Trace {
+	return r.trace
+}
+
+// tracer records the events of an analysis. All methods are safe to call on
+// a nil tracer, which records nothing.
+type tracer struct {
+	mutex     sync.Mutex
+	start     time.Time
+	events    []TraceEvent
+	truncated int
+}
+
+// startTrace returns ctx with a new tracer if the analyses run with it are
+// traced and none is running yet, along with that tracer. Analyses within a
+// traced one, such as those of the host aliases, share its tracer.
+func (s *Wappalyze) startTrace(ctx context.Context) (context.Context, *tracer) {
+	if tracerOf(ctx) != nil {
+		return ctx, nil
+	}
+	if traced, _ := ctx.Value(traceKey{}).(bool); !traced && !s.trace {
+		return ctx, nil
+	}
+	t := &tracer{start: time.Now()}
+	return context.WithValue(ctx, tracerKey{}, t), t
+}
+
+// tracerOf returns the tracer of the analysis run with ctx, if any
+func tracerOf(ctx context.Context) *tracer {
+	if ctx == nil {
+		return nil
+	}
+	t, _ := ctx.Value(tracerKey{}).(*tracer)
+	return t
+}
+
+// add records event, timed from the start of the analysis
+func (t *tracer) add(event TraceEvent) {
+	if t == nil {
+		return
+	}
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	if len(t.events) >= maxTraceEvents {
+		t.truncated++
+		return
+	}
+	event.Elapsed = time.Since(t.start)
+	t.events = append(t.events, event)
+}
+
+// trace returns the events recorded so far
+func (t *tracer) trace() *Trace {
+	if t == nil {
+		return nil
+	}
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	return &Trace{Events: slices.Clone(t.events), Truncated: t.truncated}
+}
+
+// tracedDo sends req with client, recording the request and its response in
+// the trace of the analysis it is sent for. Redirects are followed within it.
+func tracedDo(client *http.Client, req *http.Request) (*http.Response, error) {
+	t := tracerOf(req.Context())
+	if t == nil {
+		return client.Do(req)
+	}
+	url := req.URL.String()
+	t.add(TraceEvent{Type: TraceFetchStart, URL: url})
+	start := time.Now()
+	resp, err := client.Do(req)
+	event := TraceEvent{Type: TraceFetchFinish, URL: url, Duration: time.Since(start)}
+	if err != nil {
+		event.Error = err.Error()
+	} else {
+		event.Status = resp.StatusCode
+	}
+	t.add(event)
+	return resp, err
+}
@@ -0,0 +1,64 @@
+package profiler
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTrace(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(`<html><head><meta name="generator" content="WordPress 6.4"></head><body></body></html>`))
+	}))
+	defer server.Close()
+
+	wappalyzer, err := New(WithProfile(ProfileFast))
+	require.NoError(t, err, "could not create wappalyzer")
+
+	result, err := wappalyzer.FingerprintURL(context.Background(), server.URL)
+	require.NoError(t, err, "could not fingerprint url")
+	require.Nil(t, result.GetTrace(), "analyses should only be traced when asked")
+
+	result, err = wappalyzer.FingerprintURL(TraceContext(context.Background()), server.URL)
+	require.NoError(t, err, "could not fingerprint url")
+	trace := result.GetTrace()
+	require.NotNil(t, trace, "missing trace")
+	require.Zero(t, trace.Truncated, "the trace should not be truncated")
+
+	seen := make(map[TraceEventType]TraceEvent)
+	for i, event := range trace.Events {
+		if i > 0 {
+			require.GreaterOrEqual(t, event.Elapsed, trace.Events[i-1].Elapsed, "events should be ordered")
+		}
+		if _, ok := seen[event.Type]; !ok {
+			seen[event.Type] = event
+		}
+	}
+	require.Equal(t, server.URL, seen[TraceFetchStart].URL, "the page fetch should be traced first")
+	require.Equal(t, http.StatusOK, seen[TraceFetchFinish].Status, "wrong status")
+	require.Equal(t, StageMain, seen[TraceStage].Stage, "the main stage should be traced")
+	require.Positive(t, seen[TraceParse].Count, "the parsed elements should be counted")
+	require.Contains(t, seen, TraceMatch, "the matchers should be traced")
+
+	var detected bool
+	for _, event := range trace.Events {
+		if event.Type == TraceDetection && event.Technology == "WordPress" {
+			detected = event.Vector == "meta"
+		}
+	}
+	require.True(t, detected, "the detection should be traced with its vector")
+}
+
+func TestTraceBounded(t *testing.T) {
+	tracer := &tracer{}
+	for range maxTraceEvents + 5 {
+		tracer.add(TraceEvent{Type: TraceMatch})
+	}
+	trace := tracer.trace()
+	require.Len(t, trace.Events, maxTraceEvents, "the trace should be bounded")
+	require.Equal(t, 5, trace.Truncated, "wrong number of events left out")
+}
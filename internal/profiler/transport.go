@@ -0,0 +1,25 @@
+package profiler
+
+import "net/http"
+
+// TransportMiddleware wraps the transport of outbound requests, returning
+// the RoundTripper they are sent with instead
+type TransportMiddleware func(next http.RoundTripper) http.RoundTripper
+
+// roundTripperFunc adapts a function to http.RoundTripper
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+// RoundTrip implements http.RoundTripper
+func (f roundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+// wrapTransport applies the transport middleware of the instance to base,
+// the first middleware ending up outermost
+func (s *Wappalyze) wrapTransport(base http.RoundTripper) http.RoundTripper {
+	transport := base
+	for i := len(s.transportMiddleware) - 1; i >= 0; i-- {
+		transport = s.transportMiddleware[i](transport)
+	}
+	return transport
+}
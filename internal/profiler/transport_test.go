@@ -0,0 +1,70 @@
+package profiler
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTransportMiddleware(t *testing.T) {
+	var mu sync.Mutex
+	var signed, seen []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		if r.Header.Get("X-Signature") == "outer,inner" {
+			signed = append(signed, r.URL.Path)
+		}
+		mu.Unlock()
+		switch r.URL.Path {
+		case "/robots.txt":
+			w.Write([]byte("User-agent: *\nDisallow:\n"))
+		case "/app.js":
+			w.Header().Set("Content-Type", "application/javascript")
+			w.Write([]byte("console.log('app')"))
+		default:
+			w.Header().Set("Content-Type", "text/html")
+			w.Write([]byte(`<html><head><script src="/app.js"></script></head><body>Welcome</body></html>`))
+		}
+	}))
+	defer server.Close()
+
+	sign := func(name string) TransportMiddleware {
+		return func(next http.RoundTripper) http.RoundTripper {
+			return roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+				req = req.Clone(req.Context())
+				signature := name
+				if outer := req.Header.Get("X-Signature"); outer != "" {
+					signature = outer + "," + name
+				}
+				req.Header.Set("X-Signature", signature)
+				return next.RoundTrip(req)
+			})
+		}
+	}
+	record := func(next http.RoundTripper) http.RoundTripper {
+		return roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			mu.Lock()
+			seen = append(seen, req.URL.Path)
+			mu.Unlock()
+			return next.RoundTrip(req)
+		})
+	}
+
+	wappalyzer, err := New(WithTransportMiddleware(sign("outer"), sign("inner")), WithTransportMiddleware(record))
+	require.NoError(t, err, "could not create wappalyzer")
+
+	_, err = wappalyzer.FingerprintURL(context.Background(), server.URL+"/")
+	require.NoError(t, err, "could not fingerprint")
+
+	mu.Lock()
+	defer mu.Unlock()
+	for _, path := range []string{"/", "/robots.txt", "/app.js"} {
+		require.True(t, slices.Contains(seen, path), "request to %s not seen by the middleware", path)
+		require.True(t, slices.Contains(signed, path), "request to %s not signed in order", path)
+	}
+}
@@ -0,0 +1,197 @@
+package profiler
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// UserAgent is a browser or crawler that analyses can impersonate
+type UserAgent struct {
+	// Name identifies the user agent in comparisons and cache keys
+	Name string `json:"name"`
+	// Header is the value of the User-Agent header of the requests
+	Header string `json:"header"`
+}
+
+var (
+	// UserAgentDesktop is a desktop Chrome, the user agent of all analyses by default
+	UserAgentDesktop = UserAgent{
+		Name:   "desktop",
+		Header: "Mozilla/5.0 (Windows NT 6.3; WOW64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/117.0.5931.0 Safari/537.36",
+	}
+	// UserAgentMobile is Safari on an iPhone
+	UserAgentMobile = UserAgent{
+		Name:   "mobile",
+		Header: "Mozilla/5.0 (iPhone; CPU iPhone OS 17_0 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.0 Mobile/15E148 Safari/604.1",
+	}
+	// UserAgentBot is the Googlebot crawler
+	UserAgentBot = UserAgent{
+		Name:   "bot",
+		Header: "Mozilla/5.0 (compatible; Googlebot/2.1; +http://www.google.com/bot.html)",
+	}
+)
+
+// UserAgents lists the predefined user agents
+var UserAgents = []UserAgent{UserAgentDesktop, UserAgentMobile, UserAgentBot}
+
+// ScannerUserAgent returns a user agent that identifies the requests as those
+// of a Kitsune scan, pointing to url, a page explaining the scans and how to
+// opt out of them, as in "Mozilla/5.0 (compatible; Kitsune; +https://example.com/scans)".
+// Its product token is kitsune, so robots.txt groups for kitsune apply to it.
+func ScannerUserAgent(url string) UserAgent {
+	return UserAgent{
+		Name:   "scanner",
+		Header: fmt.Sprintf("Mozilla/5.0 (compatible; Kitsune; +%s)", url),
+	}
+}
+
+// WithUserAgent sets the user agent of the analyses of the instance, instead
+// of UserAgentDesktop, such as one made with ScannerUserAgent for scans that
+// identify themselves. UserAgentContext overrides it for a single analysis.
+func WithUserAgent(userAgent UserAgent) Option {
+	return func(s *Wappalyze) {
+		s.userAgent = userAgent
+	}
+}
+
+// ParseUserAgents returns the predefined user agents of a comma separated list
+// of names, such as "desktop,mobile". An empty list has no user agents.
+func ParseUserAgents(list string) ([]UserAgent, error) {
+	var userAgents []UserAgent
+	for _, name := range strings.Split(list, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		index := slices.IndexFunc(UserAgents, func(userAgent UserAgent) bool { return userAgent.Name == name })
+		if index < 0 {
+			return nil, fmt.Errorf("unknown user agent %q, expected desktop, mobile or bot", name)
+		}
+		userAgents = append(userAgents, UserAgents[index])
+	}
+	return userAgents, nil
+}
+
+// userAgentKey is the context key of the user agent of an analysis
+type userAgentKey struct{}
+
+// UserAgentContext returns a context that runs the analyses started with it,
+// e.g. through FingerprintURL, with userAgent instead of UserAgentDesktop.
+// Every request of the analysis sends it, assets and probes included.
+func UserAgentContext(ctx context.Context, userAgent UserAgent) context.Context {
+	return context.WithValue(ctx, userAgentKey{}, userAgent)
+}
+
+// withUserAgent returns ctx with the user agent of the instance, if it has
+// one and ctx sets none
+func (s *Wappalyze) withUserAgent(ctx context.Context) context.Context {
+	if s.userAgent.Header == "" {
+		return ctx
+	}
+	if _, ok := ctx.Value(userAgentKey{}).(UserAgent); ok {
+		return ctx
+	}
+	return UserAgentContext(ctx, s.userAgent)
+}
+
+// userAgentOf returns the user agent of an analysis run with ctx
+func userAgentOf(ctx context.Context) UserAgent {
+	if userAgent, ok := ctx.Value(userAgentKey{}).(UserAgent); ok {
+		return userAgent
+	}
+	return UserAgentDesktop
+}
+
+// UserAgentVariant is the analysis of a target with one user agent
+type UserAgentVariant struct {
+	UserAgent string `json:"user_agent"`
+	// URL is the URL the analyzed response was fetched from, after redirects
+	URL        string               `json:"url,omitempty"`
+	Detections map[string]Detection `json:"detections,omitempty"`
+	// Error is why the variant could not be fetched or was only partly analyzed
+	Error string `json:"error,omitempty"`
+}
+
+// UserAgentComparison compares the analyses of a target with several user agents
+type UserAgentComparison struct {
+	// Variants are the analyses, in the order of the user agents
+	Variants []UserAgentVariant `json:"variants"`
+	// Common lists the technologies detected with every analyzed user agent
+	Common []string `json:"common"`
+	// Unique lists, per user agent, the technologies detected with it only
+	Unique map[string][]string `json:"unique"`
+}
+
+// CompareUserAgents analyzes targetURL once per user agent, concurrently, and
+// reports the technologies detected with every user agent and those unique to
+// one. Sites that adapt to the client serve mobile frameworks, AMP or
+// prerendered pages that an analysis with a single user agent misses. Without
+// user agents, the desktop and mobile ones are compared.
+//
+// Variants that cannot be analyzed are reported with their error and left out
+// of the comparison. CompareUserAgents fails only if no variant was analyzed,
+// returning the error of the first.
+func (s *Wappalyze) CompareUserAgents(ctx context.Context, targetURL string, userAgents ...UserAgent) (UserAgentComparison, error) {
+	if len(userAgents) == 0 {
+		userAgents = []UserAgent{UserAgentDesktop, UserAgentMobile}
+	}
+
+	results := make([]richResult, len(userAgents))
+	errs := make([]error, len(userAgents))
+	var wg sync.WaitGroup
+	for i, userAgent := range userAgents {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i], errs[i] = s.FingerprintURL(UserAgentContext(ctx, userAgent), targetURL)
+		}()
+	}
+	wg.Wait()
+
+	comparison := UserAgentComparison{
+		Variants: make([]UserAgentVariant, len(userAgents)),
+		Unique:   make(map[string][]string),
+	}
+	// found counts the analyzed variants each technology was detected with
+	found := make(map[string]int)
+	var analyzed int
+	for i, userAgent := range userAgents {
+		variant := UserAgentVariant{UserAgent: userAgent.Name, URL: results[i].url}
+		if errs[i] != nil {
+			variant.Error = errs[i].Error()
+		}
+		if results[i].url != "" {
+			variant.Detections = results[i].GetDetections()
+			for name := range variant.Detections {
+				found[name]++
+			}
+			analyzed++
+		}
+		comparison.Variants[i] = variant
+	}
+	if analyzed == 0 {
+		return comparison, errs[0]
+	}
+
+	for name, count := range found {
+		if count == analyzed {
+			comparison.Common = append(comparison.Common, name)
+		}
+	}
+	slices.Sort(comparison.Common)
+	// Only a technology some analyzed variant lacks is unique
+	if analyzed > 1 {
+		for _, variant := range comparison.Variants {
+			for name := range variant.Detections {
+				if found[name] == 1 {
+					comparison.Unique[variant.UserAgent] = append(comparison.Unique[variant.UserAgent], name)
+				}
+			}
+			slices.Sort(comparison.Unique[variant.UserAgent])
+		}
+	}
+	return comparison, nil
+}
@@ -0,0 +1,81 @@
+package profiler
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompareUserAgents(t *testing.T) {
+	var mutex sync.Mutex
+	var userAgents []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mutex.Lock()
+		userAgents = append(userAgents, r.UserAgent())
+		mutex.Unlock()
+
+		w.Header().Set("Server", "nginx")
+		switch {
+		case strings.Contains(r.UserAgent(), "iPhone"):
+			w.Header().Set("X-Powered-By", "Next.js")
+		case strings.Contains(r.UserAgent(), "Googlebot"):
+			w.WriteHeader(http.StatusForbidden)
+		default:
+			w.Header().Set("X-Powered-By", "PHP/8.2.0")
+		}
+		w.Write([]byte("<html><head><title>Example</title></head></html>"))
+	}))
+	defer server.Close()
+
+	wappalyzer, err := New(WithProfile(ProfileFast), WithResultCache(NewLRUCache(10), time.Minute, 0))
+	require.NoError(t, err, "could not create wappalyzer")
+
+	t.Run("default", func(t *testing.T) {
+		comparison, err := wappalyzer.CompareUserAgents(context.Background(), server.URL)
+		require.NoError(t, err, "could not compare user agents")
+
+		require.Len(t, comparison.Variants, 2, "wrong variants")
+		require.Equal(t, "desktop", comparison.Variants[0].UserAgent, "wrong variant order")
+		require.Equal(t, "mobile", comparison.Variants[1].UserAgent, "wrong variant order")
+		require.Contains(t, comparison.Common, "Nginx", "missing common technology")
+		require.Equal(t, []string{"PHP"}, comparison.Unique["desktop"], "wrong desktop technologies")
+		require.Contains(t, comparison.Unique["mobile"], "Next.js", "wrong mobile technologies")
+
+		require.Len(t, userAgents, 2, "each variant should be fetched")
+		require.ElementsMatch(t, []string{UserAgentDesktop.Header, UserAgentMobile.Header}, userAgents, "wrong user agents sent")
+	})
+
+	t.Run("cached-apart", func(t *testing.T) {
+		result, err := wappalyzer.FingerprintURL(UserAgentContext(context.Background(), UserAgentMobile), server.URL)
+		require.NoError(t, err, "could not fingerprint url")
+		require.Contains(t, result.GetDetections(), "Next.js", "mobile result should be cached apart")
+
+		result, err = wappalyzer.FingerprintURL(context.Background(), server.URL)
+		require.NoError(t, err, "could not fingerprint url")
+		require.NotContains(t, result.GetDetections(), "Next.js", "desktop result should be cached apart")
+		require.Len(t, userAgents, 2, "results should be served from the cache")
+	})
+
+	t.Run("blocked", func(t *testing.T) {
+		comparison, err := wappalyzer.CompareUserAgents(context.Background(), server.URL, UserAgentDesktop, UserAgentBot)
+		require.NoError(t, err, "blocked variant should not fail the comparison")
+		require.Contains(t, comparison.Variants[1].Error, "403", "blocked variant should report its error")
+		require.NotContains(t, comparison.Common, "PHP", "technology missing from a variant is not common")
+		require.Contains(t, comparison.Unique["desktop"], "PHP", "wrong desktop technologies")
+	})
+}
+
+func TestParseUserAgents(t *testing.T) {
+	userAgents, err := ParseUserAgents(" mobile, bot,")
+	require.NoError(t, err, "could not parse user agents")
+	require.Equal(t, []UserAgent{UserAgentMobile, UserAgentBot}, userAgents, "wrong user agents")
+
+	_, err = ParseUserAgents("tablet")
+	require.Error(t, err, "unknown user agent should be refused")
+}
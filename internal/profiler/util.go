@@ -2,27 +2,117 @@ package profiler
 
 import (
 	"regexp"
+	"sync"
+	"sync/atomic"
 	"time"
 )
 
+// inlineMatchSize is the input size below which regexes are matched on the
+// calling goroutine. Matching is linear in the input, so short inputs such as
+// header values and URLs cannot get anywhere near the timeout, and skipping the
+// goroutine and timer is most of the cost of matching them.
+const inlineMatchSize = 256
+
+// regexTimeoutCount counts the regex evaluations of the process that hit their
+// timeout. It only tells whether a pooled buffer may still be read by a timed
+// out regex goroutine; analyses count their own timeouts.
+var regexTimeoutCount atomic.Int64
+
+// matchResults recycles the result channels of matchWithTimeout
+var matchResults = sync.Pool{
+	New: func() any { return make(chan []string, 1) },
+}
+
+// matchTimers recycles the timers of matchWithTimeout
+var matchTimers = sync.Pool{
+	New: func() any {
+		timer := time.NewTimer(time.Hour)
+		timer.Stop()
+		return timer
+	},
+}
+
 // matchWithTimeout executes a regex match within a specified duration.
 // It protects against catastrophic backtracking (ReDoS) by terminating slow-running patterns.
-// It returns the submatch slice on success, or nil if the match fails or times out.
-func matchWithTimeout(re *regexp.Regexp, body []byte, timeout time.Duration) []string {
+// It returns the submatch slice on success, or nil if the match fails or times out,
+// along with false if it timed out.
+//
+// The input is not copied, so it may be backed by a pooled buffer. Such a
+// buffer must not be reused if the match timed out, since the regex goroutine
+// still reads it; see matchBuffer.
+func matchWithTimeout(re *regexp.Regexp, input string, timeout time.Duration) ([]string, bool) {
+	if len(input) < inlineMatchSize {
+		return re.FindStringSubmatch(input), true
+	}
+
 	// A channel to communicate the result from the regex goroutine.
-	resultChan := make(chan []string, 1)
+	resultChan := matchResults.Get().(chan []string)
 
 	go func() {
 		// This might be slow if the regex is inefficient.
-		resultChan <- re.FindStringSubmatch(string(body))
+		resultChan <- re.FindStringSubmatch(input)
+	}()
+
+	timer := matchTimers.Get().(*time.Timer)
+	timer.Reset(timeout)
+	defer func() {
+		timer.Stop()
+		matchTimers.Put(timer)
 	}()
 
 	select {
 	case result := <-resultChan:
-		return result
-	case <-time.After(timeout):
-		// The regex took too long. Log this for debugging if needed.
-		// log.Printf("Regex timeout on pattern: %s", re.String())
-		return nil
+		matchResults.Put(resultChan)
+		return result, true
+	case <-timer.C:
+		// The regex took too long, the caller logs it. The goroutine still
+		// owns the channel, so it is left to the garbage collector.
+		regexTimeoutCount.Add(1)
+		return nil, false
+	}
+}
+
+// maxPooledBuffer is the capacity above which buffers are not pooled, so a
+// single huge page does not pin its memory
+const maxPooledBuffer = 1024 * 1024
+
+// matchBuffers recycles the buffers of matchBuffer
+var matchBuffers = sync.Pool{
+	New: func() any { return new(matchBuffer) },
+}
+
+// matchBuffer is a pooled copy of an input prepared for matching, such as the
+// lowercase copy of a page body matched by the html vector
+type matchBuffer struct {
+	data     []byte
+	timeouts int64 // regexTimeoutCount when the buffer was taken
+}
+
+// lowerBuffer returns a pooled ASCII lowercase copy of data. Patterns are
+// compiled case insensitive, so non-ASCII characters are left untouched.
+func lowerBuffer(data []byte) *matchBuffer {
+	buffer := matchBuffers.Get().(*matchBuffer)
+	buffer.timeouts = regexTimeoutCount.Load()
+	buffer.data = append(buffer.data[:0], data...)
+	for i, c := range buffer.data {
+		if 'A' <= c && c <= 'Z' {
+			buffer.data[i] = c + 'a' - 'A'
+		}
+	}
+	return buffer
+}
+
+// String returns the content of the buffer without copying it. The string is
+// only valid until the buffer is released.
+func (b *matchBuffer) String() string {
+	return unsafeToString(b.data)
+}
+
+// release returns the buffer to the pool. Buffers that may still be read by a
+// timed out regex goroutine, and oversized ones, are left to the garbage collector.
+func (b *matchBuffer) release() {
+	if regexTimeoutCount.Load() != b.timeouts || cap(b.data) > maxPooledBuffer {
+		return
 	}
-}
\ No newline at end of file
+	matchBuffers.Put(b)
+}
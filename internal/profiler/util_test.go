@@ -0,0 +1,44 @@
+package profiler
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMatchWithTimeout(t *testing.T) {
+	re := regexp.MustCompile(`(?i)wordpress ([\d.]+)`)
+
+	tests := []struct {
+		name     string
+		input    string
+		expected []string
+	}{
+		{name: "inline", input: "WordPress 6.4", expected: []string{"WordPress 6.4", "6.4"}},
+		{name: "inline no match", input: "Drupal 10"},
+		{name: "goroutine", input: strings.Repeat("x", inlineMatchSize) + "WordPress 6.4", expected: []string{"WordPress 6.4", "6.4"}},
+		{name: "goroutine no match", input: strings.Repeat("x", inlineMatchSize)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			submatches, ok := matchWithTimeout(re, tt.input, time.Second)
+			require.True(t, ok, "match should not time out")
+			require.Equal(t, tt.expected, submatches, "wrong submatches")
+		})
+	}
+}
+
+func TestLowerBuffer(t *testing.T) {
+	buffer := lowerBuffer([]byte("<HTML lang=\"EN\">Ünïcode</HTML>"))
+	require.Equal(t, "<html lang=\"en\">Ünïcode</html>", buffer.String(), "only ASCII should be lowered")
+	buffer.release()
+
+	// A recycled buffer holds the new content only
+	buffer = lowerBuffer([]byte("AB"))
+	require.Equal(t, "ab", buffer.String(), "recycled buffer should be overwritten")
+	buffer.release()
+}
@@ -0,0 +1,315 @@
+package profiler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// FingerprintIssue is a problem found by ValidateFingerprints
+type FingerprintIssue struct {
+	// Path is the JSON pointer of the offending value, such as
+	// /apps/WordPress/html/0, or empty for a file that does not parse
+	Path    string `json:"path"`
+	Message string `json:"message"`
+	// Warning is set for patterns that work but are slow to match, which
+	// ValidateFingerprints does not count as errors
+	Warning bool `json:"warning,omitempty"`
+}
+
+func (i FingerprintIssue) String() string {
+	level := "error"
+	if i.Warning {
+		level = "warning"
+	}
+	if i.Path == "" {
+		return fmt.Sprintf("%s: %s", level, i.Message)
+	}
+	return fmt.Sprintf("%s: %s: %s", i.Path, level, i.Message)
+}
+
+// fingerprintFields are the JSON fields of a Fingerprint
+var fingerprintFields = sync.OnceValue(func() map[string]struct{} {
+	fields := make(map[string]struct{})
+	kind := reflect.TypeOf(Fingerprint{})
+	for i := 0; i < kind.NumField(); i++ {
+		name, _, _ := strings.Cut(kind.Field(i).Tag.Get("json"), ",")
+		fields[name] = struct{}{}
+	}
+	return fields
+})
+
+// ValidateFingerprints checks a user-authored fingerprints or overlay file
+// before it is loaded, which would otherwise silently drop what it cannot use.
+// It reports unknown fields, values of the wrong type, patterns that do not
+// compile, unknown or conflicting categories, and implies, extend or disable entries naming
+// technologies that are neither in the file nor in the embedded data. Patterns
+// that are slow to match, as reported by LintPattern, are warnings. Issues are
+// sorted by path.
+func ValidateFingerprints(data []byte) []FingerprintIssue {
+	v := &validator{known: make(map[string]struct{}), categories: maps.Clone(categoriesMapping)}
+
+	var document map[string]json.RawMessage
+	if err := json.Unmarshal(data, &document); err != nil {
+		return []FingerprintIssue{{Message: describeJSONError(data, err)}}
+	}
+
+	apps := v.decodeApps(document, "apps")
+	extend := v.decodeApps(document, "extend")
+	var disable []string
+	for key, raw := range document {
+		switch key {
+		case "apps", "extend", "metadata":
+		case "categories":
+			v.checkCategories(raw)
+		case "disable":
+			if err := json.Unmarshal(raw, &disable); err != nil {
+				v.errorf("/disable", "must be a list of technology names")
+			}
+		default:
+			v.errorf(pointer("", key), "unknown field %q, expected categories, apps, extend, disable or metadata", key)
+		}
+	}
+
+	// References may name technologies of the file or of the embedded data
+	for appName := range apps {
+		v.known[appName] = struct{}{}
+	}
+	if embedded, err := embeddedFingerprints(); err == nil {
+		for appName := range embedded.Apps {
+			v.known[appName] = struct{}{}
+		}
+	}
+
+	for _, group := range []struct {
+		key  string
+		apps map[string]json.RawMessage
+	}{{"apps", apps}, {"extend", extend}} {
+		for appName, raw := range group.apps {
+			path := pointer(pointer("", group.key), appName)
+			if group.key == "extend" {
+				v.checkReference(path, appName)
+			}
+			v.checkFingerprint(path, raw)
+		}
+	}
+	for i, appName := range disable {
+		v.checkReference(pointer("/disable", strconv.Itoa(i)), appName)
+	}
+
+	sort.SliceStable(v.issues, func(i, j int) bool { return v.issues[i].Path < v.issues[j].Path })
+	return v.issues
+}
+
+// validator accumulates the issues of a fingerprints file
+type validator struct {
+	issues []FingerprintIssue
+	// known are the technologies references may name
+	known map[string]struct{}
+	// categories are the embedded categories and those of the file
+	categories map[int]categoryItem
+}
+
+func (v *validator) errorf(path, format string, args ...interface{}) {
+	v.issues = append(v.issues, FingerprintIssue{Path: path, Message: fmt.Sprintf(format, args...)})
+}
+
+func (v *validator) warnf(path, format string, args ...interface{}) {
+	v.issues = append(v.issues, FingerprintIssue{Path: path, Message: fmt.Sprintf(format, args...), Warning: true})
+}
+
+// checkCategories checks the custom categories of an overlay and adds them to
+// the known categories
+func (v *validator) checkCategories(raw json.RawMessage) {
+	var categories map[int]OverlayCategory
+	if err := json.Unmarshal(raw, &categories); err != nil {
+		v.errorf("/categories", "must be an object of categories keyed by ID")
+		return
+	}
+	for id, category := range categories {
+		path := pointer("/categories", strconv.Itoa(id))
+		if _, ok := v.categories[id]; ok {
+			v.errorf(path, "category %d already exists", id)
+			continue
+		}
+		if category.Name == "" {
+			v.errorf(pointer(path, "name"), "category has no name")
+		}
+		if category.Priority < 0 || category.Priority > 10 {
+			v.errorf(pointer(path, "priority"), "priority must be between 1 and 10")
+		}
+		v.categories[id] = categoryItem{Name: category.Name, Priority: category.Priority}
+	}
+}
+
+// decodeApps decodes the fingerprints of a top-level field, keeping them raw
+func (v *validator) decodeApps(document map[string]json.RawMessage, key string) map[string]json.RawMessage {
+	raw, ok := document[key]
+	if !ok {
+		return nil
+	}
+	var apps map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &apps); err != nil {
+		v.errorf(pointer("", key), "must be an object of technologies")
+	}
+	return apps
+}
+
+// checkReference reports a technology name that is not known
+func (v *validator) checkReference(path, appName string) {
+	if _, ok := v.known[appName]; !ok {
+		v.errorf(path, "unknown technology %q", appName)
+	}
+}
+
+// checkFingerprint checks the fields, types and patterns of a fingerprint
+func (v *validator) checkFingerprint(path string, raw json.RawMessage) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &fields); err != nil {
+		v.errorf(path, "must be an object")
+		return
+	}
+	for field := range fields {
+		if _, ok := fingerprintFields()[field]; !ok {
+			v.errorf(pointer(path, field), "unknown field %q", field)
+		}
+	}
+
+	var fingerprint Fingerprint
+	if err := json.Unmarshal(raw, &fingerprint); err != nil {
+		var typeErr *json.UnmarshalTypeError
+		if errors.As(err, &typeErr) {
+			fieldPath := path
+			for _, token := range strings.Split(typeErr.Field, ".") {
+				fieldPath = pointer(fieldPath, token)
+			}
+			v.errorf(fieldPath, "expected %s, got %s", typeErr.Type, typeErr.Value)
+		} else {
+			v.errorf(path, "%v", err)
+		}
+		return
+	}
+
+	for _, cat := range fingerprint.Cats {
+		if _, ok := v.categories[cat]; !ok {
+			v.errorf(pointer(path, "cats"), "unknown category %d", cat)
+		}
+	}
+	for i, implied := range fingerprint.Implies {
+		v.checkReference(pointer(pointer(path, "implies"), strconv.Itoa(i)), impliedName(implied))
+	}
+	for i, excluded := range fingerprint.Excludes {
+		v.checkReference(pointer(pointer(path, "excludes"), strconv.Itoa(i)), excluded)
+	}
+
+	lists := map[string][]string{
+		"css": fingerprint.CSS, "html": fingerprint.HTML, "scripts": fingerprint.Script,
+		"scriptSrc": fingerprint.ScriptSrc, "robots": fingerprint.Robots, "certIssuer": fingerprint.CertIssuer,
+		"xhr": fingerprint.XHR, "iframe": fingerprint.Iframe, "linkHref": fingerprint.LinkHref,
+	}
+	for vector, patterns := range lists {
+		for i, pattern := range patterns {
+			v.checkPattern(pointer(pointer(path, vector), strconv.Itoa(i)), pattern)
+		}
+	}
+	for vector, patterns := range map[string]map[string]string{
+		"cookies": fingerprint.Cookies, "js": fingerprint.JS, "headers": fingerprint.Headers,
+	} {
+		for key, pattern := range patterns {
+			v.checkPattern(pointer(pointer(path, vector), key), pattern)
+		}
+	}
+	for vector, keyed := range map[string]map[string][]string{
+		"meta": fingerprint.Meta, "dns": fingerprint.DNS, "jsonld": fingerprint.JSONLD,
+	} {
+		for key, patterns := range keyed {
+			for i, pattern := range patterns {
+				v.checkPattern(pointer(pointer(pointer(path, vector), key), strconv.Itoa(i)), pattern)
+			}
+		}
+	}
+
+	for selector, rule := range fingerprint.Dom {
+		rulePath := pointer(pointer(path, "dom"), selector)
+		for key, value := range rule {
+			switch key {
+			case "exists":
+			case "attributes":
+				attributes, ok := value.(map[string]interface{})
+				if !ok {
+					v.errorf(pointer(rulePath, key), "must be an object of attribute patterns")
+					continue
+				}
+				for name, value := range attributes {
+					v.checkPatternValue(pointer(pointer(rulePath, key), name), value)
+				}
+			case "properties":
+				v.warnf(pointer(rulePath, key), "DOM properties are not matched")
+			default:
+				// Other keys are text or direct attribute patterns
+				v.checkPatternValue(pointer(rulePath, key), value)
+			}
+		}
+	}
+}
+
+// checkPatternValue checks a pattern of a DOM rule, which may have any type
+func (v *validator) checkPatternValue(path string, value interface{}) {
+	pattern, ok := value.(string)
+	if !ok {
+		v.errorf(path, "expected a pattern string, got %T", value)
+		return
+	}
+	v.checkPattern(path, pattern)
+}
+
+// checkPattern compiles a pattern and lints it for slow constructs
+func (v *validator) checkPattern(path, pattern string) {
+	if _, err := ParsePattern(pattern); err != nil {
+		v.errorf(path, "pattern does not compile: %v", err)
+		return
+	}
+	lint := LintPattern(pattern)
+	for _, issue := range lint.Issues {
+		v.warnf(path, "%s in %q", issue.Kind, issue.Construct)
+	}
+}
+
+// pointer appends a token to a JSON pointer, escaping it
+func pointer(path, token string) string {
+	token = strings.ReplaceAll(token, "~", "~0")
+	token = strings.ReplaceAll(token, "/", "~1")
+	return path + "/" + token
+}
+
+// describeJSONError describes a JSON error with its line and column, if known
+func describeJSONError(data []byte, err error) string {
+	var offset int64
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	switch {
+	case errors.As(err, &syntaxErr):
+		offset = syntaxErr.Offset
+	case errors.As(err, &typeErr):
+		offset = typeErr.Offset
+	default:
+		return err.Error()
+	}
+
+	// The offset is right after the offending character
+	line, column := 1, 1
+	for _, c := range data[:min(max(int(offset)-1, 0), len(data))] {
+		if c == '\n' {
+			line, column = line+1, 1
+		} else {
+			column++
+		}
+	}
+	return fmt.Sprintf("line %d, column %d: %v", line, column, err)
+}
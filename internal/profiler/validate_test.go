@@ -0,0 +1,76 @@
+package profiler
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateFingerprints(t *testing.T) {
+	tests := []struct {
+		name     string
+		data     string
+		expected []FingerprintIssue
+	}{
+		{
+			name: "valid",
+			data: `{"apps": {"Internal CMS": {"cats": [1], "headers": {"X-CMS": "^internal ([\\d.]+)\\;version:\\1"}, "implies": ["PHP\\;confidence:50"]}}}`,
+		},
+		{
+			name:     "syntax error",
+			data:     "{\n  \"apps\": {,}\n}",
+			expected: []FingerprintIssue{{Message: "line 2, column 12: invalid character ',' looking for beginning of object key string"}},
+		},
+		{
+			name: "unknown fields",
+			data: `{"aps": {}, "apps": {"Internal CMS": {"heders": {"x-cms": ""}}}}`,
+			expected: []FingerprintIssue{
+				{Path: "/apps/Internal CMS/heders", Message: `unknown field "heders"`},
+				{Path: "/aps", Message: `unknown field "aps", expected categories, apps, extend, disable or metadata`},
+			},
+		},
+		{
+			name:     "wrong type",
+			data:     `{"apps": {"Internal CMS": {"html": "internal-cms"}}}`,
+			expected: []FingerprintIssue{{Path: "/apps/Internal CMS/html", Message: "expected []string, got string"}},
+		},
+		{
+			name: "invalid patterns",
+			data: `{"apps": {"Internal CMS": {"html": ["ok", "(unclosed"], "dom": {"a[href*='/cms/']": {"attributes": {"href": "[z-a]"}}}}}}`,
+			expected: []FingerprintIssue{
+				{Path: "/apps/Internal CMS/dom/a[href*='~1cms~1']/attributes/href", Message: "pattern does not compile: error parsing regexp: invalid character class range: `z-a`"},
+				{Path: "/apps/Internal CMS/html/1", Message: "pattern does not compile: error parsing regexp: missing closing ): `(?i)(unclosed`"},
+			},
+		},
+		{
+			name:     "slow pattern",
+			data:     `{"apps": {"Internal CMS": {"scriptSrc": ["(?:cms|app)+\\.js"]}}}`,
+			expected: []FingerprintIssue{{Path: "/apps/Internal CMS/scriptSrc/0", Message: `quantified-alternation in "(?:cms|app)+"`, Warning: true}},
+		},
+		{
+			name: "unknown references",
+			data: `{"apps": {"Internal CMS": {"cats": [9999], "implies": ["Nginx", "Internal Framework"]}}, "extend": {"Wordpress": {}}, "disable": ["PHP", "Unknown"]}`,
+			expected: []FingerprintIssue{
+				{Path: "/apps/Internal CMS/cats", Message: "unknown category 9999"},
+				{Path: "/apps/Internal CMS/implies/1", Message: `unknown technology "Internal Framework"`},
+				{Path: "/disable/1", Message: `unknown technology "Unknown"`},
+				{Path: "/extend/Wordpress", Message: `unknown technology "Wordpress"`},
+			},
+		},
+		{
+			name: "custom categories",
+			data: `{"categories": {"1000": {"name": "Payments"}, "1001": {"priority": 11}, "1": {"name": "CMS"}}, "apps": {"Internal Checkout": {"cats": [1000], "tags": ["payment"]}}}`,
+			expected: []FingerprintIssue{
+				{Path: "/categories/1", Message: "category 1 already exists"},
+				{Path: "/categories/1001/name", Message: "category has no name"},
+				{Path: "/categories/1001/priority", Message: "priority must be between 1 and 10"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.expected, ValidateFingerprints([]byte(tt.data)), "wrong issues")
+		})
+	}
+}
@@ -96,5 +96,19 @@ func TestUniqueFingerprints(t *testing.T) {
 		f.SetIfNotExists("test", "2.36.4", 100)
 		require.Equal(t, map[string]struct{}{"test:2.36.4": {}}, f.GetValues(), "could not get correct values")
 	})
+
+	t.Run("sorted vectors", func(t *testing.T) {
+		f := NewUniqueFingerprints()
+		f.SetWithVector("test", "", 50, "scriptSrc")
+		f.SetWithVector("test", "", 50, "headers")
+		f.SetWithVector("test", "", 50, "html")
+		require.Equal(t, []string{"headers", "html", "scriptSrc"}, f.GetDetections()["test"].DetectedBy, "vectors should be sorted")
+	})
+}
+
+func TestAppInfoFromFingerprintSortsCategories(t *testing.T) {
+	// 22 is Web servers, 1 is CMS
+	info := AppInfoFromFingerprint(&CompiledFingerprint{cats: []int{22, 1}})
+	require.Equal(t, []string{"CMS", "Web servers"}, info.Categories, "categories should be sorted")
 }
 
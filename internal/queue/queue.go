@@ -0,0 +1,81 @@
+// Package queue bounds the number of analyses running at once. Requests over
+// the bound wait in a bounded queue, and are turned away once it is full, so
+// a burst of slow targets cannot exhaust the file descriptors and memory of
+// the server.
+package queue
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+)
+
+// ErrFull is returned when every slot is taken and the queue is full
+var ErrFull = errors.New("analysis queue is full")
+
+// Queue hands out a bounded number of slots, in the order they are requested
+type Queue struct {
+	// slots holds a token per running analysis, nil without a bound
+	slots chan struct{}
+	// waiting holds a token per queued request
+	waiting chan struct{}
+	queued  atomic.Int64
+}
+
+// New creates a queue running at most slots analyses at once, with at most
+// size requests waiting for a slot. Zero slots means no bound.
+func New(slots, size int) *Queue {
+	q := &Queue{waiting: make(chan struct{}, max(size, 0))}
+	if slots > 0 {
+		q.slots = make(chan struct{}, slots)
+	}
+	return q
+}
+
+// Acquire takes a slot, waiting in the queue until one is free or ctx is
+// done. It returns ErrFull without waiting when the queue is full, and the
+// error of ctx when it is done first. The returned function releases the
+// slot.
+func (q *Queue) Acquire(ctx context.Context) (release func(), err error) {
+	if q.slots == nil {
+		return func() {}, nil
+	}
+	select {
+	case q.slots <- struct{}{}:
+		return q.release, nil
+	default:
+	}
+
+	select {
+	case q.waiting <- struct{}{}:
+	default:
+		return nil, ErrFull
+	}
+	q.queued.Add(1)
+	defer func() {
+		q.queued.Add(-1)
+		<-q.waiting
+	}()
+
+	select {
+	case q.slots <- struct{}{}:
+		return q.release, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// release frees a slot
+func (q *Queue) release() {
+	<-q.slots
+}
+
+// Running returns the number of slots taken
+func (q *Queue) Running() int {
+	return len(q.slots)
+}
+
+// Queued returns the number of requests waiting for a slot
+func (q *Queue) Queued() int {
+	return int(q.queued.Load())
+}
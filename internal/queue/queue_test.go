@@ -0,0 +1,54 @@
+package queue
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestAcquire(t *testing.T) {
+	q := New(1, 1)
+	release, err := q.Acquire(context.Background())
+	require.NoError(t, err, "a free slot should be taken at once")
+	require.Equal(t, 1, q.Running(), "wrong running analyses")
+
+	// The second request waits for the slot, the third finds the queue full
+	acquired := make(chan func())
+	go func() {
+		release, err := q.Acquire(context.Background())
+		require.NoError(t, err, "a queued request should get the released slot")
+		acquired <- release
+	}()
+	require.Eventually(t, func() bool { return q.Queued() == 1 }, time.Second, time.Millisecond, "the request should be queued")
+	_, err = q.Acquire(context.Background())
+	require.ErrorIs(t, err, ErrFull, "requests over the queue size should be turned away")
+
+	release()
+	secondRelease := <-acquired
+	require.Equal(t, 0, q.Queued(), "the queue should be empty")
+	require.Equal(t, 1, q.Running(), "wrong running analyses")
+	secondRelease()
+	require.Equal(t, 0, q.Running(), "the slot should be released")
+}
+
+func TestAcquireTimeout(t *testing.T) {
+	q := New(1, 1)
+	_, err := q.Acquire(context.Background())
+	require.NoError(t, err, "a free slot should be taken at once")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err = q.Acquire(ctx)
+	require.ErrorIs(t, err, context.DeadlineExceeded, "waits should end with the context")
+	require.Equal(t, 0, q.Queued(), "requests that gave up should leave the queue")
+}
+
+func TestAcquireUnbounded(t *testing.T) {
+	q := New(0, 0)
+	for range 100 {
+		_, err := q.Acquire(context.Background())
+		require.NoError(t, err, "queues without slots should not bound analyses")
+	}
+}
@@ -0,0 +1,195 @@
+// Package reqlog assigns each HTTP request an ID, propagated through the
+// X-Request-ID header and the request context, and writes a structured access
+// log entry per request. Logs written with the request context through a
+// handler wrapped by NewHandler carry the request ID, which correlates the
+// debug logs of an analysis with the request that started it.
+package reqlog
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Header is the header request IDs are read from and written to
+const Header = "X-Request-ID"
+
+// maxIDLength bounds the length of request IDs accepted from clients
+const maxIDLength = 128
+
+// requestKey is the context key of the request state
+type requestKey struct{}
+
+// request is the state of a request, shared by the middleware and handlers
+type request struct {
+	id     string
+	mutex  sync.Mutex
+	target string
+}
+
+// ID returns the ID of the request ctx belongs to, or an empty string
+func ID(ctx context.Context) string {
+	if req, ok := ctx.Value(requestKey{}).(*request); ok {
+		return req.id
+	}
+	return ""
+}
+
+// SetTarget records the URL a request analyzes, for its access log entry
+func SetTarget(ctx context.Context, target string) {
+	if req, ok := ctx.Value(requestKey{}).(*request); ok {
+		req.mutex.Lock()
+		req.target = target
+		req.mutex.Unlock()
+	}
+}
+
+// Error replies with an error message that includes the request ID, so users
+// can reference the failure in support requests
+func Error(w http.ResponseWriter, r *http.Request, message string, code int) {
+	if id := ID(r.Context()); id != "" {
+		message = fmt.Sprintf("%s (request ID: %s)", message, id)
+	}
+	http.Error(w, message, code)
+}
+
+// validID reports whether a client supplied request ID can be reused: it must
+// be short and made of printable ASCII, so it is safe to log and echo back
+func validID(id string) bool {
+	if id == "" || len(id) > maxIDLength {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] < 0x21 || id[i] > 0x7e {
+			return false
+		}
+	}
+	return true
+}
+
+// newID generates a random request ID
+func newID() string {
+	var buf [16]byte
+	rand.Read(buf[:])
+	return hex.EncodeToString(buf[:])
+}
+
+// statusWriter records the status code and size of a response
+type statusWriter struct {
+	http.ResponseWriter
+	status int
+	bytes  int64
+}
+
+func (w *statusWriter) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *statusWriter) Write(p []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	n, err := w.ResponseWriter.Write(p)
+	w.bytes += int64(n)
+	return n, err
+}
+
+// Flush keeps streaming responses working through the middleware
+func (w *statusWriter) Flush() {
+	if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// Unwrap exposes the underlying writer to http.ResponseController
+func (w *statusWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// outcome summarizes a status code for the access log
+func outcome(status int) string {
+	switch {
+	case status >= 500:
+		return "server_error"
+	case status >= 400:
+		return "client_error"
+	default:
+		return "ok"
+	}
+}
+
+// Middleware assigns every request an ID, reusing a valid X-Request-ID sent by
+// the client, returns it in the X-Request-ID response header, and logs the
+// method, path, target URL, status, outcome, size and duration of every
+// request to logger once it completes.
+func Middleware(next http.Handler, logger *slog.Logger) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+
+		id := r.Header.Get(Header)
+		if !validID(id) {
+			id = newID()
+		}
+		req := &request{id: id}
+		ctx := context.WithValue(r.Context(), requestKey{}, req)
+		w.Header().Set(Header, id)
+
+		writer := &statusWriter{ResponseWriter: w}
+		next.ServeHTTP(writer, r.WithContext(ctx))
+
+		status := writer.status
+		if status == 0 {
+			status = http.StatusOK
+		}
+		req.mutex.Lock()
+		target := req.target
+		req.mutex.Unlock()
+
+		attrs := []slog.Attr{
+			slog.String("method", r.Method),
+			slog.String("path", r.URL.Path),
+			slog.Int("status", status),
+			slog.String("outcome", outcome(status)),
+			slog.Int64("bytes", writer.bytes),
+			slog.Duration("duration", time.Since(start)),
+		}
+		if target != "" {
+			attrs = append(attrs, slog.String("target", target))
+		}
+		logger.LogAttrs(ctx, slog.LevelInfo, "request", attrs...)
+	})
+}
+
+// handler adds the request ID of the context to every record
+type handler struct {
+	slog.Handler
+}
+
+// NewHandler wraps h so records logged with a request context carry its ID
+// as the request_id attribute
+func NewHandler(h slog.Handler) slog.Handler {
+	return handler{Handler: h}
+}
+
+func (h handler) Handle(ctx context.Context, record slog.Record) error {
+	if id := ID(ctx); id != "" {
+		record.AddAttrs(slog.String("request_id", id))
+	}
+	return h.Handler.Handle(ctx, record)
+}
+
+func (h handler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return handler{Handler: h.Handler.WithAttrs(attrs)}
+}
+
+func (h handler) WithGroup(name string) slog.Handler {
+	return handler{Handler: h.Handler.WithGroup(name)}
+}
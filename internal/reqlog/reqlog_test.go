@@ -0,0 +1,68 @@
+package reqlog
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMiddleware(t *testing.T) {
+	var logs bytes.Buffer
+	logger := slog.New(NewHandler(slog.NewJSONHandler(&logs, nil)))
+
+	handler := Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		SetTarget(r.Context(), "https://example.com")
+		logger.InfoContext(r.Context(), "analyzing")
+		Error(w, r, "failed", http.StatusBadGateway)
+	}), logger)
+
+	tests := []struct {
+		name     string
+		header   string
+		expected string
+	}{
+		{name: "propagated", header: "abc-123", expected: "abc-123"},
+		{name: "generated"},
+		{name: "invalid", header: "bad id\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logs.Reset()
+			req := httptest.NewRequest("GET", "/analyze/stream", nil)
+			if tt.header != "" {
+				req.Header.Set(Header, tt.header)
+			}
+			recorder := httptest.NewRecorder()
+			handler.ServeHTTP(recorder, req)
+
+			id := recorder.Header().Get(Header)
+			if tt.expected != "" {
+				require.Equal(t, tt.expected, id, "request ID should be propagated")
+			} else {
+				require.Len(t, id, 32, "request ID should be generated")
+			}
+			require.Equal(t, "failed (request ID: "+id+")\n", recorder.Body.String(), "request ID should be in context")
+
+			decoder := json.NewDecoder(&logs)
+			var handlerEntry, accessEntry map[string]interface{}
+			require.NoError(t, decoder.Decode(&handlerEntry), "handler log missing")
+			require.NoError(t, decoder.Decode(&accessEntry), "access log missing")
+
+			require.Equal(t, id, handlerEntry["request_id"], "handler log should carry the request ID")
+			require.Equal(t, "request", accessEntry["msg"], "wrong access log message")
+			require.Equal(t, id, accessEntry["request_id"], "access log should carry the request ID")
+			require.Equal(t, "GET", accessEntry["method"], "wrong method")
+			require.Equal(t, "/analyze/stream", accessEntry["path"], "wrong path")
+			require.Equal(t, "https://example.com", accessEntry["target"], "wrong target")
+			require.Equal(t, float64(http.StatusBadGateway), accessEntry["status"], "wrong status")
+			require.Equal(t, "server_error", accessEntry["outcome"], "wrong outcome")
+			require.Contains(t, accessEntry, "duration", "duration missing")
+		})
+	}
+}
@@ -0,0 +1,260 @@
+// Package resolve looks up DNS records through public resolvers, falling back
+// from one to the next when a resolver fails. Answers are cached for the TTL
+// of their records, so analyses of many hosts of a domain look its records up
+// once.
+package resolve
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/netip"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/miekg/dns"
+	"github.com/weppos/publicsuffix-go/publicsuffix"
+)
+
+// DefaultServers are the public resolvers queried, in order of preference
+var DefaultServers = []string{
+	"8.8.8.8:53",        // Google
+	"1.1.1.1:53",        // Cloudflare
+	"9.9.9.9:53",        // Quad9
+	"208.67.222.222:53", // OpenDNS
+}
+
+// DefaultCacheSize is the number of answers a resolver caches by default
+const DefaultCacheSize = 10000
+
+const (
+	// maxTTL caps how long an answer is cached, whatever its records say
+	maxTTL = time.Hour
+	// negativeTTL is how long a name with no records is cached when the
+	// answer carries no SOA record telling for how long
+	negativeTTL = time.Minute
+)
+
+// ErrNoAnswer is returned when no server answered a query
+var ErrNoAnswer = errors.New("no resolver answered")
+
+// Client exchanges DNS messages with a server, as *dns.Client does
+type Client interface {
+	ExchangeContext(ctx context.Context, msg *dns.Msg, address string) (*dns.Msg, time.Duration, error)
+}
+
+// DefaultClient is a client that times out after 2 seconds
+var DefaultClient Client = &dns.Client{Timeout: 2 * time.Second}
+
+// Resolver queries DNS servers in order of preference and caches their
+// answers. It is safe for concurrent use.
+type Resolver struct {
+	client  Client
+	servers []string
+	cache   *cache
+}
+
+// New returns a resolver querying servers through client, which caches up to
+// cacheSize answers. A cacheSize of zero disables the cache.
+func New(client Client, servers []string, cacheSize int) *Resolver {
+	resolver := &Resolver{client: client, servers: servers}
+	if cacheSize > 0 {
+		resolver.cache = newCache(cacheSize)
+	}
+	return resolver
+}
+
+// Lookup returns the answer of the first server that answers the query of
+// the records of type qtype of name. Servers that fail, or answer SERVFAIL or
+// REFUSED, are skipped. Callers must not modify the answer, which the cache
+// shares.
+func (r *Resolver) Lookup(ctx context.Context, name string, qtype uint16) (*dns.Msg, error) {
+	if r.cache == nil {
+		return r.exchange(ctx, name, qtype)
+	}
+	key := cacheKey{name: strings.ToLower(dns.Fqdn(name)), qtype: qtype}
+	return r.cache.get(ctx, key, func() (*dns.Msg, error) {
+		return r.exchange(ctx, name, qtype)
+	})
+}
+
+// exchange queries the servers in turn until one answers
+func (r *Resolver) exchange(ctx context.Context, name string, qtype uint16) (*dns.Msg, error) {
+	msg := new(dns.Msg)
+	msg.SetQuestion(dns.Fqdn(name), qtype)
+	msg.RecursionDesired = true
+
+	err := ErrNoAnswer
+	for _, server := range r.servers {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		reply, _, exchangeErr := r.client.ExchangeContext(ctx, msg, server)
+		switch {
+		case exchangeErr != nil:
+			err = fmt.Errorf("%w: %s: %w", ErrNoAnswer, server, exchangeErr)
+		case reply == nil:
+		case reply.Rcode == dns.RcodeServerFailure || reply.Rcode == dns.RcodeRefused:
+			err = fmt.Errorf("%w: %s: %s", ErrNoAnswer, server, dns.RcodeToString[reply.Rcode])
+		default:
+			return reply, nil
+		}
+	}
+	return nil, err
+}
+
+// TXT returns the TXT records of name and the targets of the CNAME records
+// leading to them. The strings of each TXT record are joined.
+func (r *Resolver) TXT(ctx context.Context, name string) (txt, cnames []string, err error) {
+	reply, err := r.Lookup(ctx, name, dns.TypeTXT)
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, answer := range reply.Answer {
+		switch record := answer.(type) {
+		case *dns.TXT:
+			txt = append(txt, strings.Join(record.Txt, ""))
+		case *dns.CNAME:
+			cnames = append(cnames, strings.ToLower(strings.TrimSuffix(record.Target, ".")))
+		}
+	}
+	return txt, cnames, nil
+}
+
+// RegistrableDomain returns the registrable domain of host, such as
+// example.co.uk for www.example.co.uk, or an empty string for addresses and
+// hosts without one
+func RegistrableDomain(host string) string {
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+	if _, err := netip.ParseAddr(host); err == nil {
+		return ""
+	}
+	domain, err := publicsuffix.Domain(host)
+	if err != nil {
+		return ""
+	}
+	return domain
+}
+
+// cacheKey identifies the answers of a cache
+type cacheKey struct {
+	name  string
+	qtype uint16
+}
+
+// cacheEntry is an answer of a cache, or a lookup in flight until ready is
+// closed
+type cacheEntry struct {
+	ready   chan struct{}
+	reply   *dns.Msg
+	err     error
+	expires time.Time
+}
+
+// cache holds the answers of a resolver until their TTL expires. Concurrent
+// lookups of a name wait for the first one rather than repeat it.
+type cache struct {
+	mu      sync.Mutex
+	size    int
+	entries map[cacheKey]*cacheEntry
+	now     func() time.Time
+}
+
+// newCache returns a cache of up to size answers
+func newCache(size int) *cache {
+	return &cache{size: size, entries: make(map[cacheKey]*cacheEntry), now: time.Now}
+}
+
+// get returns the answer of key, looking it up with lookup unless it is
+// cached or in flight. Failed lookups are not cached.
+func (c *cache) get(ctx context.Context, key cacheKey, lookup func() (*dns.Msg, error)) (*dns.Msg, error) {
+	c.mu.Lock()
+	entry, ok := c.entries[key]
+	if ok {
+		c.mu.Unlock()
+		select {
+		case <-entry.ready:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+		if entry.err == nil && c.now().Before(entry.expires) {
+			return entry.reply, nil
+		}
+		c.mu.Lock()
+		// Another lookup may have replaced the stale entry meanwhile
+		if c.entries[key] == entry {
+			delete(c.entries, key)
+		}
+		c.mu.Unlock()
+		return c.get(ctx, key, lookup)
+	}
+	entry = &cacheEntry{ready: make(chan struct{})}
+	c.evict()
+	c.entries[key] = entry
+	c.mu.Unlock()
+
+	entry.reply, entry.err = lookup()
+	if entry.err == nil {
+		entry.expires = c.now().Add(ttl(entry.reply))
+	}
+	close(entry.ready)
+	if entry.err != nil {
+		c.mu.Lock()
+		if c.entries[key] == entry {
+			delete(c.entries, key)
+		}
+		c.mu.Unlock()
+	}
+	return entry.reply, entry.err
+}
+
+// evict makes room for an entry by removing the expired ones, or an arbitrary
+// one if none expired. The caller holds the lock.
+func (c *cache) evict() {
+	if len(c.entries) < c.size {
+		return
+	}
+	now := c.now()
+	for key, entry := range c.entries {
+		select {
+		case <-entry.ready:
+			if !now.Before(entry.expires) {
+				delete(c.entries, key)
+			}
+		default:
+		}
+	}
+	for key := range c.entries {
+		if len(c.entries) < c.size {
+			break
+		}
+		delete(c.entries, key)
+	}
+}
+
+// ttl returns how long reply may be cached: the lowest TTL of its answers or,
+// for names without records, the negative TTL of the SOA record of the zone,
+// capped at maxTTL
+func ttl(reply *dns.Msg) time.Duration {
+	var seconds uint32
+	var found bool
+	for _, answer := range reply.Answer {
+		if !found || answer.Header().Ttl < seconds {
+			seconds = answer.Header().Ttl
+			found = true
+		}
+	}
+	if !found {
+		for _, record := range reply.Ns {
+			if soa, ok := record.(*dns.SOA); ok {
+				seconds = min(soa.Hdr.Ttl, soa.Minttl)
+				found = true
+			}
+		}
+	}
+	if !found {
+		return negativeTTL
+	}
+	return min(time.Duration(seconds)*time.Second, maxTTL)
+}
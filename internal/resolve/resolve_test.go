@@ -0,0 +1,195 @@
+package resolve
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/miekg/dns"
+	"github.com/stretchr/testify/require"
+)
+
+// clientFunc adapts a function to Client
+type clientFunc func(ctx context.Context, msg *dns.Msg, address string) (*dns.Msg, time.Duration, error)
+
+func (f clientFunc) ExchangeContext(ctx context.Context, msg *dns.Msg, address string) (*dns.Msg, time.Duration, error) {
+	return f(ctx, msg, address)
+}
+
+// zoneClient answers queries from records in zone file format, counting the
+// exchanges
+func zoneClient(t *testing.T, exchanges *atomic.Int32, records ...string) Client {
+	zone := make(map[string][]dns.RR)
+	for _, record := range records {
+		rr, err := dns.NewRR(record)
+		require.NoError(t, err, "could not parse record")
+		zone[rr.Header().Name] = append(zone[rr.Header().Name], rr)
+	}
+	return clientFunc(func(ctx context.Context, msg *dns.Msg, address string) (*dns.Msg, time.Duration, error) {
+		exchanges.Add(1)
+		reply := new(dns.Msg)
+		reply.SetReply(msg)
+		for _, rr := range zone[msg.Question[0].Name] {
+			if rr.Header().Rrtype == msg.Question[0].Qtype {
+				reply.Answer = append(reply.Answer, rr)
+			}
+		}
+		if len(reply.Answer) == 0 {
+			reply.Rcode = dns.RcodeNameError
+			reply.Ns = zone["example.com."]
+		}
+		return reply, 0, nil
+	})
+}
+
+func TestLookupFallback(t *testing.T) {
+	var queried []string
+	client := clientFunc(func(ctx context.Context, msg *dns.Msg, address string) (*dns.Msg, time.Duration, error) {
+		queried = append(queried, address)
+		reply := new(dns.Msg)
+		reply.SetReply(msg)
+		switch address {
+		case "failing:53":
+			return nil, 0, errors.New("i/o timeout")
+		case "refusing:53":
+			reply.Rcode = dns.RcodeRefused
+		case "missing:53":
+			reply.Rcode = dns.RcodeNameError
+		}
+		return reply, 0, nil
+	})
+
+	resolver := New(client, []string{"failing:53", "refusing:53", "missing:53", "unused:53"}, 0)
+	reply, err := resolver.Lookup(context.Background(), "example.com", dns.TypeMX)
+	require.NoError(t, err, "could not look up")
+	require.Equal(t, dns.RcodeNameError, reply.Rcode, "the first answer is trusted, even without records")
+	require.Equal(t, []string{"failing:53", "refusing:53", "missing:53"}, queried, "wrong servers queried")
+
+	resolver = New(client, []string{"failing:53", "refusing:53"}, 0)
+	_, err = resolver.Lookup(context.Background(), "example.com", dns.TypeMX)
+	require.ErrorIs(t, err, ErrNoAnswer, "lookups no server answers should fail")
+}
+
+func TestTXT(t *testing.T) {
+	var exchanges atomic.Int32
+	client := zoneClient(t, &exchanges,
+		`k1._domainkey.example.com. 300 IN CNAME dkim.mcsv.net.`,
+		`k1._domainkey.example.com. 300 IN TXT "v=DKIM1; " "p=MIIB"`,
+	)
+	txt, cnames, err := New(client, DefaultServers, 0).TXT(context.Background(), "k1._domainkey.example.com")
+	require.NoError(t, err, "could not look up")
+	require.Equal(t, []string{"v=DKIM1; p=MIIB"}, txt, "wrong txt records")
+	require.Empty(t, cnames, "the client only answers the queried type")
+}
+
+func TestCache(t *testing.T) {
+	var exchanges atomic.Int32
+	client := zoneClient(t, &exchanges,
+		`example.com. 300 IN MX 10 mx1.example.com.`,
+		`example.com. 60 IN MX 20 mx2.example.com.`,
+		`example.com. 3600 IN SOA ns.example.com. admin.example.com. 1 7200 3600 1209600 120`,
+		`example.com. 86400 IN NS ns.example.com.`,
+	)
+	resolver := New(client, DefaultServers, 10)
+	now := time.Now()
+	resolver.cache.now = func() time.Time { return now }
+	lookup := func(name string, qtype uint16) *dns.Msg {
+		reply, err := resolver.Lookup(context.Background(), name, qtype)
+		require.NoError(t, err, "could not look up")
+		return reply
+	}
+
+	require.Len(t, lookup("example.com", dns.TypeMX).Answer, 2, "wrong answer")
+	require.Len(t, lookup("EXAMPLE.com.", dns.TypeMX).Answer, 2, "wrong cached answer")
+	require.EqualValues(t, 1, exchanges.Load(), "names should be cached case insensitively")
+
+	// Answers expire with their record of lowest TTL
+	now = now.Add(61 * time.Second)
+	lookup("example.com", dns.TypeMX)
+	require.EqualValues(t, 2, exchanges.Load(), "expired answers should be looked up again")
+
+	// Names without records expire with the negative TTL of the zone
+	lookup("missing.example.com", dns.TypeTXT)
+	now = now.Add(100 * time.Second)
+	lookup("missing.example.com", dns.TypeTXT)
+	require.EqualValues(t, 3, exchanges.Load(), "negative answers should be cached")
+	now = now.Add(21 * time.Second)
+	lookup("missing.example.com", dns.TypeTXT)
+	require.EqualValues(t, 4, exchanges.Load(), "negative answers should expire")
+
+	// TTLs are capped
+	lookup("example.com", dns.TypeNS)
+	now = now.Add(maxTTL)
+	lookup("example.com", dns.TypeNS)
+	require.EqualValues(t, 6, exchanges.Load(), "answers should expire after maxTTL")
+}
+
+func TestCacheConcurrentLookups(t *testing.T) {
+	var exchanges atomic.Int32
+	release := make(chan struct{})
+	zone := zoneClient(t, &exchanges, `example.com. 300 IN TXT "v=spf1 -all"`)
+	client := clientFunc(func(ctx context.Context, msg *dns.Msg, address string) (*dns.Msg, time.Duration, error) {
+		<-release
+		return zone.ExchangeContext(ctx, msg, address)
+	})
+	resolver := New(client, DefaultServers, 10)
+
+	var wg sync.WaitGroup
+	for range 10 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			txt, _, err := resolver.TXT(context.Background(), "example.com")
+			require.NoError(t, err, "could not look up")
+			require.Equal(t, []string{"v=spf1 -all"}, txt, "wrong txt records")
+		}()
+	}
+	time.Sleep(10 * time.Millisecond)
+	close(release)
+	wg.Wait()
+	require.EqualValues(t, 1, exchanges.Load(), "concurrent lookups of a name should share one exchange")
+}
+
+func TestCacheFailures(t *testing.T) {
+	var exchanges atomic.Int32
+	client := clientFunc(func(ctx context.Context, msg *dns.Msg, address string) (*dns.Msg, time.Duration, error) {
+		exchanges.Add(1)
+		return nil, 0, errors.New("i/o timeout")
+	})
+	resolver := New(client, []string{"failing:53"}, 10)
+	for range 2 {
+		_, err := resolver.Lookup(context.Background(), "example.com", dns.TypeMX)
+		require.ErrorIs(t, err, ErrNoAnswer, "lookups should fail")
+	}
+	require.EqualValues(t, 2, exchanges.Load(), "failures should not be cached")
+}
+
+func TestCacheSize(t *testing.T) {
+	var exchanges atomic.Int32
+	resolver := New(zoneClient(t, &exchanges), DefaultServers, 2)
+	for _, name := range []string{"a.example.com", "b.example.com", "c.example.com"} {
+		_, err := resolver.Lookup(context.Background(), name, dns.TypeA)
+		require.NoError(t, err, "could not look up")
+	}
+	require.Len(t, resolver.cache.entries, 2, "the cache should hold at most its size")
+}
+
+func TestRegistrableDomain(t *testing.T) {
+	tests := []struct {
+		host string
+		want string
+	}{
+		{host: "www.example.com", want: "example.com"},
+		{host: "WWW.Example.co.uk.", want: "example.co.uk"},
+		{host: "example.com", want: "example.com"},
+		{host: "127.0.0.1", want: ""},
+		{host: "::1", want: ""},
+		{host: "localhost", want: ""},
+	}
+	for _, test := range tests {
+		require.Equal(t, test.want, RegistrableDomain(test.host), "wrong registrable domain of %s", test.host)
+	}
+}
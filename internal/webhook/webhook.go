@@ -0,0 +1,188 @@
+// Package webhook posts JSON events to HTTP endpoints. Posts are signed with
+// an HMAC of their body when the endpoint has a secret, and failed posts are
+// retried with exponential backoff.
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Headers of every post. The delivery ID is the same across the attempts of
+// a post, so receivers can ignore duplicates.
+const (
+	EventHeader     = "X-Kitsune-Event"
+	DeliveryHeader  = "X-Kitsune-Delivery"
+	TimestampHeader = "X-Kitsune-Timestamp"
+	// SignatureHeader holds "sha256=" followed by the hex HMAC-SHA256 of the
+	// timestamp, a dot and the body, keyed by the secret
+	SignatureHeader = "X-Kitsune-Signature"
+)
+
+// RetryPolicy configures how failed posts are retried. Network errors, 408,
+// 429 and 5xx responses are retried; other responses are final.
+type RetryPolicy struct {
+	// MaxAttempts is the total number of attempts, including the first one.
+	// Values below 2 disable retries.
+	MaxAttempts int
+	// InitialBackoff is the delay before the first retry. It doubles on every
+	// subsequent retry.
+	InitialBackoff time.Duration
+	// MaxBackoff caps the delay between attempts, including delays requested
+	// by a Retry-After header
+	MaxBackoff time.Duration
+}
+
+// DefaultRetryPolicy returns a retry policy riding out short outages of the
+// endpoint: five attempts over about half a minute
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{MaxAttempts: 5, InitialBackoff: 2 * time.Second, MaxBackoff: 30 * time.Second}
+}
+
+// backoff returns the delay before the given retry (1-based)
+func (p RetryPolicy) backoff(retry int) time.Duration {
+	delay := p.InitialBackoff << (retry - 1)
+	if delay <= 0 || (p.MaxBackoff > 0 && delay > p.MaxBackoff) {
+		delay = p.MaxBackoff
+	}
+	return max(delay, 0)
+}
+
+// Target is an endpoint events are posted to
+type Target struct {
+	URL string
+	// Secret keys the signature of the posts, which are unsigned without one
+	Secret string
+	Retry  RetryPolicy
+}
+
+// Sender posts events to a target
+type Sender struct {
+	target Target
+	client *http.Client
+	// sleep waits between attempts, replaced in tests
+	sleep func(ctx context.Context, delay time.Duration) error
+}
+
+// New creates a sender posting to target with client, or with a client
+// timing out each attempt after 10 seconds if nil
+func New(target Target, client *http.Client) *Sender {
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+	return &Sender{target: target, client: client, sleep: sleep}
+}
+
+// Send posts payload as JSON, as an event of the given type, retrying
+// failures according to the retry policy of the target. It returns the error
+// of the last attempt.
+func (s *Sender) Send(ctx context.Context, event string, payload interface{}) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	delivery := newDeliveryID()
+
+	for attempt := 1; ; attempt++ {
+		delay, err := s.post(ctx, event, delivery, body)
+		if err == nil {
+			return nil
+		}
+		if delay < 0 || attempt >= s.target.Retry.MaxAttempts || ctx.Err() != nil {
+			return err
+		}
+		if delay == 0 {
+			delay = s.target.Retry.backoff(attempt)
+		}
+		if err := s.sleep(ctx, delay); err != nil {
+			return err
+		}
+	}
+}
+
+// post makes a single attempt. When it fails, the delay is negative if the
+// failure is final, or the delay requested by the endpoint, 0 if none.
+func (s *Sender) post(ctx context.Context, event, delivery string, body []byte) (time.Duration, error) {
+	req, err := http.NewRequestWithContext(ctx, "POST", s.target.URL, bytes.NewReader(body))
+	if err != nil {
+		return -1, err
+	}
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "kitsune-webhook")
+	req.Header.Set(EventHeader, event)
+	req.Header.Set(DeliveryHeader, delivery)
+	req.Header.Set(TimestampHeader, timestamp)
+	if s.target.Secret != "" {
+		req.Header.Set(SignatureHeader, Sign(s.target.Secret, timestamp, body))
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	// Drain a little of the body so the connection can be reused
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 4096))
+	resp.Body.Close()
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return 0, nil
+	}
+	err = fmt.Errorf("webhook responded with %s", resp.Status)
+	if resp.StatusCode != http.StatusRequestTimeout && resp.StatusCode != http.StatusTooManyRequests && resp.StatusCode < 500 {
+		return -1, err
+	}
+	if seconds, parseErr := strconv.Atoi(resp.Header.Get("Retry-After")); parseErr == nil && seconds > 0 {
+		delay := time.Duration(seconds) * time.Second
+		if s.target.Retry.MaxBackoff > 0 {
+			delay = min(delay, s.target.Retry.MaxBackoff)
+		}
+		return delay, err
+	}
+	return 0, err
+}
+
+// Sign returns the signature of a post, as sent in SignatureHeader
+func Sign(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify reports whether signature is the signature of a post, for receivers
+// to authenticate posts. Receivers should also reject stale timestamps, so
+// recorded posts cannot be replayed.
+func Verify(secret, timestamp, signature string, body []byte) bool {
+	return hmac.Equal([]byte(signature), []byte(Sign(secret, timestamp, body)))
+}
+
+// newDeliveryID returns a random delivery ID
+func newDeliveryID() string {
+	id := make([]byte, 16)
+	rand.Read(id)
+	return hex.EncodeToString(id)
+}
+
+// sleep waits for delay, or until ctx is done
+func sleep(ctx context.Context, delay time.Duration) error {
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
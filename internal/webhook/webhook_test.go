@@ -0,0 +1,86 @@
+package webhook
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSend(t *testing.T) {
+	var requests []*http.Request
+	var bodies [][]byte
+	statuses := []int{http.StatusServiceUnavailable, http.StatusTooManyRequests, http.StatusOK}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		requests = append(requests, r)
+		bodies = append(bodies, body)
+		if len(requests) == 2 {
+			w.Header().Set("Retry-After", "120")
+		}
+		w.WriteHeader(statuses[len(requests)-1])
+	}))
+	defer server.Close()
+
+	sender := New(Target{URL: server.URL, Secret: "s3cret", Retry: RetryPolicy{MaxAttempts: 3, InitialBackoff: time.Second, MaxBackoff: time.Minute}}, nil)
+	var delays []time.Duration
+	sender.sleep = func(ctx context.Context, delay time.Duration) error {
+		delays = append(delays, delay)
+		return nil
+	}
+	require.NoError(t, sender.Send(context.Background(), "analysis.completed", map[string]string{"url": "https://example.com"}), "could not send")
+
+	require.Len(t, requests, 3, "failures should be retried")
+	require.Equal(t, []time.Duration{time.Second, time.Minute}, delays, "wrong delays, Retry-After capped by the max backoff")
+	require.JSONEq(t, `{"url": "https://example.com"}`, string(bodies[0]), "wrong body")
+	for _, req := range requests {
+		require.Equal(t, "analysis.completed", req.Header.Get(EventHeader), "wrong event")
+		require.Equal(t, requests[0].Header.Get(DeliveryHeader), req.Header.Get(DeliveryHeader), "attempts should share the delivery ID")
+	}
+	last := requests[2]
+	require.True(t, Verify("s3cret", last.Header.Get(TimestampHeader), last.Header.Get(SignatureHeader), bodies[2]), "invalid signature")
+	require.False(t, Verify("other", last.Header.Get(TimestampHeader), last.Header.Get(SignatureHeader), bodies[2]), "signature should depend on the secret")
+}
+
+func TestSendFailures(t *testing.T) {
+	tests := []struct {
+		name         string
+		status       int
+		wantAttempts int
+	}{
+		{name: "client error", status: http.StatusBadRequest, wantAttempts: 1},
+		{name: "server error", status: http.StatusInternalServerError, wantAttempts: 3},
+		{name: "timeout", status: http.StatusRequestTimeout, wantAttempts: 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			attempts := 0
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				attempts++
+				w.WriteHeader(tt.status)
+			}))
+			defer server.Close()
+
+			sender := New(Target{URL: server.URL, Retry: RetryPolicy{MaxAttempts: 3}}, nil)
+			err := sender.Send(context.Background(), "analysis.completed", nil)
+			require.ErrorContains(t, err, http.StatusText(tt.status), "wrong error")
+			require.Equal(t, tt.wantAttempts, attempts, "wrong attempts")
+		})
+	}
+}
+
+func TestSendUnsigned(t *testing.T) {
+	var header http.Header
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header = r.Header
+	}))
+	defer server.Close()
+
+	require.NoError(t, New(Target{URL: server.URL}, nil).Send(context.Background(), "monitor.change", nil), "could not send")
+	require.Empty(t, header.Get(SignatureHeader), "posts without a secret should not be signed")
+	require.NotEmpty(t, header.Get(TimestampHeader), "missing timestamp")
+}